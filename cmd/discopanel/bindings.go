@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/pkg/logger"
+)
+
+// How often container port bindings are compared with the servers, the first check runs right after startup
+const portBindingCheckInterval = time.Minute
+
+// Flags servers whose container publishes other ports than DiscoPanel expects, or shares a
+// host port with another server's container so only one of them can start. The flag is
+// cleared once the bindings match again, e.g. after the container is recreated.
+func checkPortBindings(ctx context.Context, store *storage.Store, dockerClient *docker.Client, log *logger.Logger) {
+	servers, err := store.ListServers(ctx)
+	if err != nil {
+		log.Error("Failed to list servers for the port binding check: %v", err)
+		return
+	}
+
+	warnings := make(map[string]string)
+	owners := make(map[string][]*storage.Server) // host port/protocol -> servers publishing it
+	for _, server := range servers {
+		if server.ContainerID == "" {
+			warnings[server.ID] = ""
+			continue
+		}
+		bindings, err := dockerClient.ContainerPortBindings(ctx, server.ContainerID)
+		if err != nil {
+			// Missing containers are rebuilt on the next start, keep the current flag until then
+			continue
+		}
+
+		// Imported containers and host networking keep whatever ports they were set up with
+		hostNetwork := server.DockerOverrides != nil && server.DockerOverrides.NetworkMode == "host"
+		warnings[server.ID] = ""
+		if !server.Imported && !hostNetwork {
+			warnings[server.ID] = docker.PortBindingMismatch(bindings, server)
+		}
+
+		for key, published := range bindings {
+			for _, binding := range published {
+				if binding.HostPort == "" {
+					continue
+				}
+				hostPort := fmt.Sprintf("%s/%s", binding.HostPort, key.Proto())
+				if !slices.Contains(owners[hostPort], server) {
+					owners[hostPort] = append(owners[hostPort], server)
+				}
+			}
+		}
+	}
+
+	for hostPort, shared := range owners {
+		if len(shared) < 2 {
+			continue
+		}
+		for _, server := range shared {
+			if warnings[server.ID] != "" {
+				continue
+			}
+			var others []string
+			for _, other := range shared {
+				if other != server {
+					others = append(others, other.Name)
+				}
+			}
+			slices.Sort(others)
+			warnings[server.ID] = fmt.Sprintf("host port %s is also published by %s", hostPort, strings.Join(others, ", "))
+		}
+	}
+
+	for _, server := range servers {
+		warning, checked := warnings[server.ID]
+		if !checked || warning == server.PortBindingWarning {
+			continue
+		}
+		if warning != "" {
+			log.Warn("Port bindings of server %s don't match: %s, recreate its container to fix them", server.Name, warning)
+		} else {
+			log.Info("Port bindings of server %s match again", server.Name)
+		}
+		if err := store.SetServerPortBindingWarning(ctx, server.ID, warning); err != nil {
+			log.Error("Failed to save port binding warning for server %s: %v", server.Name, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/pkg/logger"
+)
+
+// Log lines kept in a crash looping server's last error
+const crashLoopLogTail = 20
+
+// Counts the restarts of each server's container done by its restart policy and stops
+// servers that keep exiting before the restarts go on forever
+type crashLoopDetector struct {
+	exits  int
+	window time.Duration
+
+	containers map[string]*containerRestarts
+}
+
+type containerRestarts struct {
+	restartCount int
+	restarts     []time.Time // When restarts were seen, pruned to the window
+}
+
+func newCrashLoopDetector(exits int, window time.Duration) *crashLoopDetector {
+	return &crashLoopDetector{
+		exits:      exits,
+		window:     window,
+		containers: make(map[string]*containerRestarts),
+	}
+}
+
+// Records the restarts since the last check and returns true when it stopped the server
+// for crash looping. The server is saved with StatusError and its exit code and last log
+// lines in LastError.
+func (d *crashLoopDetector) check(ctx context.Context, server *storage.Server, dockerClient *docker.Client, store *storage.Store, log *logger.Logger) bool {
+	if d.exits <= 0 {
+		return false
+	}
+	exits, err := dockerClient.GetContainerExits(ctx, server.ContainerID)
+	if err != nil {
+		return false
+	}
+
+	history, seen := d.containers[server.ContainerID]
+	if !seen {
+		d.containers[server.ContainerID] = &containerRestarts{restartCount: exits.RestartCount}
+		return false
+	}
+
+	// Docker resets the count when the container is started by hand, which adds nothing
+	now := time.Now()
+	for range exits.RestartCount - history.restartCount {
+		history.restarts = append(history.restarts, now)
+	}
+	history.restartCount = exits.RestartCount
+	for len(history.restarts) > 0 && now.Sub(history.restarts[0]) > d.window {
+		history.restarts = history.restarts[1:]
+	}
+	if len(history.restarts) < d.exits {
+		return false
+	}
+	delete(d.containers, server.ContainerID)
+
+	lastError := fmt.Sprintf("Crash loop: the container exited %d times within %s, last exit code %d. DiscoPanel stopped it, fix the cause and start the server again.",
+		len(history.restarts), d.window, exits.ExitCode)
+	if lines := dockerClient.TailContainerLogs(ctx, server.ContainerID, crashLoopLogTail); len(lines) > 0 {
+		lastError += "\n\n" + strings.Join(lines, "\n")
+	}
+	log.Warn("Server %s is crash looping (exit code %d), stopping its container", server.Name, exits.ExitCode)
+
+	if _, err := dockerClient.StopContainer(ctx, server.ContainerID); err != nil {
+		log.Error("Failed to stop crash looping server %s: %v", server.Name, err)
+	}
+	server.Status = storage.StatusError
+	server.LastError = lastError
+	if err := store.UpdateServer(ctx, server); err != nil {
+		log.Error("Failed to update server status: %v", err)
+	}
+	return true
+}
+
+// Drops the history of containers no server uses anymore
+func (d *crashLoopDetector) prune(servers []*storage.Server) {
+	current := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		current[server.ContainerID] = true
+	}
+	for containerID := range d.containers {
+		if !current[containerID] {
+			delete(d.containers, containerID)
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 func main() {
 	var configPath = flag.String("config", "./config.yaml", "Path to configuration file")
+	var relocateData = flag.String("relocate-data", "", "Move the data directory to this path, update stored paths and exit")
 	flag.Parse()
 
 	// Load configuration
@@ -61,7 +62,7 @@ func main() {
 	}
 
 	// Initialize storage w/ migrations and seeding
-	store, err := storage.NewSQLiteStore(cfg)
+	store, err := storage.NewStore(cfg)
 	if err != nil {
 		log.Fatal("Failed to initialize storage: %v", err)
 	}
@@ -71,20 +72,35 @@ func main() {
 
 	// Initialize Docker client with configuration
 	dockerClient, err := docker.NewClient(cfg.Docker.Host, log, docker.ClientConfig{
-		APIVersion:  cfg.Docker.Version,
-		NetworkName: cfg.Docker.NetworkName,
-		RegistryURL: cfg.Docker.RegistryURL,
-		DNS:         cfg.Docker.DNS,
-		Labels:      cfg.Docker.Labels,
+		APIVersion:     cfg.Docker.Version,
+		NetworkName:    cfg.Docker.NetworkName,
+		RegistryURL:    cfg.Docker.RegistryURL,
+		DNS:            cfg.Docker.DNS,
+		Labels:         cfg.Docker.Labels,
+		TLSCACert:      cfg.Docker.TLSCACert,
+		TLSCert:        cfg.Docker.TLSCert,
+		TLSKey:         cfg.Docker.TLSKey,
+		Runtime:        cfg.Docker.Runtime,
+		StartupTimeout: time.Duration(cfg.Docker.StartupTimeout) * time.Second,
+		VolumesDir:     cfg.Docker.VolumesDir,
 	})
 	if err != nil {
 		log.Fatal("Failed to initialize Docker client: %v", err)
 	}
 	defer dockerClient.Close()
 
-	// Ensure Docker network exists
-	if err := dockerClient.EnsureNetwork(); err != nil {
-		log.Error("Failed to ensure Docker network: %v", err)
+	// Relocate the data directory instead of starting the panel
+	if *relocateData != "" {
+		if err := relocateDataDir(ctx, cfg, store, dockerClient, log, *relocateData); err != nil {
+			log.Fatal("Failed to relocate data directory: %v", err)
+		}
+		return
+	}
+
+	// Ensure Docker network exists, container creation is blocked until it does
+	if err := dockerClient.EnsureNetworkWithRetry(ctx, 5, 2*time.Second); err != nil {
+		log.Error("%v", err)
+		log.Error("DiscoPanel is running in a degraded state, see /api/v1/health")
 	}
 
 	// Clean up orphaned containers on startup
@@ -152,6 +168,12 @@ func main() {
 			cfg.Proxy.Enabled, cfg.Proxy.BaseURL, len(cfg.Proxy.ListenPorts))
 	}
 
+	// The proxy dials servers by container IP, which a remote daemon keeps on its own network
+	if cfg.Proxy.Enabled && dockerClient.IsRemote() {
+		log.Error("The proxy can't reach servers on a remote Docker host, it stays off, use direct ports")
+		cfg.Proxy.Enabled = false
+	}
+
 	// Initialize proxy manager
 	proxyManager := proxy.NewManager(store, cfg, log)
 
@@ -171,7 +193,7 @@ func main() {
 	metricsCollector := metrics.NewCollector(store, dockerClient, sender, cfg, eventBus, log)
 
 	// Initialize task scheduler
-	taskScheduler := scheduler.NewScheduler(store, dockerClient, sender, cfg, metricsCollector, log, scheduler.Config{
+	taskScheduler := scheduler.NewScheduler(store, dockerClient, sender, cfg, metricsCollector, eventBus, log, scheduler.Config{
 		CheckInterval: time.Duration(cfg.Docker.SyncInterval) * time.Second, // Use same interval as container status monitor
 	})
 
@@ -313,32 +335,54 @@ func main() {
 		ticker := time.NewTicker(time.Duration(cfg.Docker.SyncInterval) * time.Second)
 		defer ticker.Stop()
 
+		var lastBindingCheck time.Time
+		crashLoops := newCrashLoopDetector(cfg.Docker.CrashLoopExits, time.Duration(cfg.Docker.CrashLoopWindow)*time.Second)
+		unhealthyRestarts := newUnhealthyRestarter(rpcServer.RestartServer)
 		for {
 			select {
 			case <-ticker.C:
 				// Update status for all servers with containers
 				ctx := context.Background()
+				if time.Since(lastBindingCheck) >= portBindingCheckInterval {
+					checkPortBindings(ctx, store, dockerClient, log)
+					lastBindingCheck = time.Now()
+				}
 				servers, err := store.ListServers(ctx)
 				if err != nil {
 					continue
 				}
 
+				crashLoops.prune(servers)
+				unhealthyRestarts.prune(servers)
 				for _, server := range servers {
 					if server.ContainerID != "" {
+						if crashLoops.check(ctx, server, dockerClient, store, log) {
+							if err := proxyManager.UpdateServerRoute(server); err != nil {
+								log.Error("Failed to update proxy route for %s: %v", server.Name, err)
+							}
+							continue
+						}
 						status, err := dockerClient.GetContainerStatus(ctx, server.ContainerID)
+						// A crash loop's error stays on the stopped container until the server is started again
+						if status == storage.StatusStopped && server.Status == storage.StatusError && server.LastError != "" {
+							continue
+						}
 						if err == nil && server.Status != status {
 							oldStatus := server.Status
 							server.Status = status
 							if err := store.UpdateServer(ctx, server); err != nil {
 								log.Error("Failed to update server status: %v", err)
 							}
-							// Update proxy route if status changed and server has proxy configured
-							if server.ProxyHostname != "" && oldStatus != status {
+							// Update proxy routes if status changed (also refreshes Bedrock listeners)
+							if oldStatus != status {
 								if err := proxyManager.UpdateServerRoute(server); err != nil {
 									log.Error("Failed to update proxy route for %s: %v", server.Name, err)
 								}
 							}
 						}
+						if err == nil {
+							unhealthyRestarts.check(ctx, server, status, store, eventBus, log)
+						}
 					}
 				}
 			case <-stopMonitor:
@@ -397,6 +441,7 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown: %v", err)
 	}
+	rpcServer.Close()
 
 	log.Info("Server stopped\n")
 }
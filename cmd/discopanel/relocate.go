@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nickheyer/discopanel/internal/config"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/pkg/files"
+	"github.com/nickheyer/discopanel/pkg/logger"
+)
+
+// Moves the data directory to target and repoints the stored paths at it. Containers
+// mounting the old location are removed first and get recreated on their next start.
+func relocateDataDir(ctx context.Context, cfg *config.Config, store *storage.Store, dockerClient *docker.Client, log *logger.Logger, target string) error {
+	oldDir := cfg.Storage.DataDir
+	newDir, err := filepath.Abs(target)
+	if err != nil {
+		return fmt.Errorf("invalid target directory: %w", err)
+	}
+	if isWithin(newDir, oldDir) || isWithin(oldDir, newDir) {
+		return fmt.Errorf("%s and %s overlap", oldDir, newDir)
+	}
+	if entries, err := os.ReadDir(newDir); err == nil {
+		if len(entries) > 0 {
+			return fmt.Errorf("%s is not empty", newDir)
+		}
+		if err := os.Remove(newDir); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	servers, err := store.ListServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list servers: %w", err)
+	}
+	modules, err := store.ListModules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	moved := make(map[string]bool)
+	var affected []*storage.Server
+	for _, server := range servers {
+		if !isWithin(server.DataPath, oldDir) {
+			continue
+		}
+		// Their containers aren't ours to recreate, or don't mount DataPath at all
+		if server.Imported || server.DataVolume != "" {
+			return fmt.Errorf("server %s is imported or keeps its data in a volume, move its data out of %s first", server.Name, oldDir)
+		}
+		moved[server.ID] = true
+		affected = append(affected, server)
+	}
+
+	for _, server := range affected {
+		if server.ContainerID == "" {
+			continue
+		}
+		log.Info("Removing container of server %s", server.Name)
+		if err := removeContainer(ctx, dockerClient, server.ContainerID); err != nil {
+			return fmt.Errorf("failed to remove container of server %s: %w", server.Name, err)
+		}
+		server.ContainerID = ""
+		server.Status = storage.StatusStopped
+		if err := store.UpdateServer(ctx, server); err != nil {
+			return fmt.Errorf("failed to update server %s: %w", server.Name, err)
+		}
+	}
+	for _, module := range modules {
+		if module.ContainerID == "" || (!moved[module.ServerID] && !isWithin(module.DataPath, oldDir)) {
+			continue
+		}
+		log.Info("Removing container of module %s", module.Name)
+		if err := removeContainer(ctx, dockerClient, module.ContainerID); err != nil {
+			return fmt.Errorf("failed to remove container of module %s: %w", module.Name, err)
+		}
+		module.ContainerID = ""
+		module.Status = storage.ModuleStatusStopped
+		if err := store.UpdateModule(ctx, module); err != nil {
+			return fmt.Errorf("failed to update module %s: %w", module.Name, err)
+		}
+	}
+
+	// An SQLite database inside the data directory moves along with it
+	movesDatabase := cfg.Database.Driver != "postgres" && isWithin(cfg.Database.Path, oldDir)
+	if err := store.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	log.Info("Moving %s to %s", oldDir, newDir)
+	if err := files.MoveDir(oldDir, newDir); err != nil {
+		return err
+	}
+
+	cfg.Storage.DataDir = newDir
+	if movesDatabase {
+		rel, _ := filepath.Rel(oldDir, cfg.Database.Path)
+		cfg.Database.Path = filepath.Join(newDir, rel)
+	}
+	relocated, err := storage.NewStore(cfg)
+	if err != nil {
+		return fmt.Errorf("data moved but the database failed to open: %w", err)
+	}
+	defer relocated.Close()
+	if err := relocated.RelocateDataPaths(ctx, oldDir, newDir); err != nil {
+		return fmt.Errorf("data moved but updating stored paths failed: %w", err)
+	}
+
+	log.Info("Data directory moved to %s, update the configuration before starting DiscoPanel:", newDir)
+	log.Info("  storage.data_dir: %s", newDir)
+	if movesDatabase {
+		log.Info("  database.path: %s", cfg.Database.Path)
+	}
+	for key, dir := range map[string]string{"storage.backup_dir": cfg.Storage.BackupDir, "storage.temp_dir": cfg.Storage.TempDir} {
+		if isWithin(dir, oldDir) {
+			rel, _ := filepath.Rel(oldDir, dir)
+			log.Info("  %s: %s", key, filepath.Join(newDir, rel))
+		}
+	}
+	return nil
+}
+
+func removeContainer(ctx context.Context, dockerClient *docker.Client, containerID string) error {
+	found, err := dockerClient.StopContainer(ctx, containerID)
+	if err != nil || !found {
+		return err
+	}
+	return dockerClient.RemoveContainer(ctx, containerID)
+}
+
+// Whether path is dir or lies inside it
+func isWithin(path, dir string) bool {
+	if path == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/events"
+	"github.com/nickheyer/discopanel/pkg/logger"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// Used when a server restarting on unhealthy leaves its own settings at zero
+const (
+	defaultUnhealthyRestartAfter = 5 * time.Minute
+	defaultMaxUnhealthyRestarts  = 3
+)
+
+// Restarts servers that opted in once Docker has reported them unhealthy for long
+// enough, a few times an hour at most so a server that never recovers isn't restarted forever
+type unhealthyRestarter struct {
+	restart func(ctx context.Context, serverID string) error
+
+	servers map[string]*unhealthyHistory
+}
+
+type unhealthyHistory struct {
+	since    time.Time   // When the current unhealthy stretch began, zero while healthy
+	restarts []time.Time // Restarts done for being unhealthy, pruned to the last hour
+	capped   bool        // The hourly cap was hit in this stretch and has been reported
+}
+
+func newUnhealthyRestarter(restart func(ctx context.Context, serverID string) error) *unhealthyRestarter {
+	return &unhealthyRestarter{
+		restart: restart,
+		servers: make(map[string]*unhealthyHistory),
+	}
+}
+
+// Tracks how long the server has had the unhealthy status and restarts it once that's
+// past its limit. After the hourly cap is reached the server is left alone and the reason
+// is saved in LastError. Restarts count down through shutdown warnings like the API's.
+func (r *unhealthyRestarter) check(ctx context.Context, server *storage.Server, status storage.ServerStatus, store *storage.Store, bus *events.Bus, log *logger.Logger) {
+	if !server.RestartOnUnhealthy {
+		delete(r.servers, server.ID)
+		return
+	}
+
+	history, ok := r.servers[server.ID]
+	if !ok {
+		history = &unhealthyHistory{}
+		r.servers[server.ID] = history
+	}
+	now := time.Now()
+	for len(history.restarts) > 0 && now.Sub(history.restarts[0]) > time.Hour {
+		history.restarts = history.restarts[1:]
+	}
+
+	if status != storage.StatusUnhealthy {
+		history.since = time.Time{}
+		history.capped = false
+		return
+	}
+	if history.since.IsZero() {
+		history.since = now
+		return
+	}
+
+	after := time.Duration(server.UnhealthyRestartAfter) * time.Second
+	if after <= 0 {
+		after = defaultUnhealthyRestartAfter
+	}
+	unhealthyFor := now.Sub(history.since)
+	if unhealthyFor < after {
+		return
+	}
+
+	limit := server.MaxUnhealthyRestarts
+	if limit <= 0 {
+		limit = defaultMaxUnhealthyRestarts
+	}
+	if len(history.restarts) >= limit {
+		if history.capped {
+			return
+		}
+		history.capped = true
+		log.Warn("Server %s is still unhealthy after %d restarts within an hour, leaving it running", server.Name, len(history.restarts))
+		server.LastError = fmt.Sprintf("Unhealthy: the server was restarted %d times within an hour and is still failing its health check. DiscoPanel stopped restarting it, look into the cause and restart it by hand.",
+			len(history.restarts))
+		if err := store.UpdateServer(ctx, server); err != nil {
+			log.Error("Failed to update server status: %v", err)
+		}
+		return
+	}
+
+	log.Warn("Server %s has been unhealthy for %s, restarting it", server.Name, unhealthyFor.Round(time.Second))
+	// Wait a full stretch again either way, a countdown or a slow boot is still unhealthy
+	history.since = now
+	if err := r.restart(ctx, server.ID); err != nil {
+		log.Error("Failed to restart unhealthy server %s: %v", server.Name, err)
+		return
+	}
+	history.restarts = append(history.restarts, now)
+	history.capped = false
+
+	if bus != nil {
+		bus.Emit(ctx, events.Event{
+			Type:     v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_SERVER_UNHEALTHY_RESTART,
+			ServerID: server.ID,
+			Data: map[string]any{
+				"unhealthy_for": unhealthyFor.Round(time.Second).String(),
+				"restarts":      len(history.restarts),
+			},
+		})
+	}
+}
+
+// Drops the history of servers that no longer exist
+func (r *unhealthyRestarter) prune(servers []*storage.Server) {
+	current := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		current[server.ID] = true
+	}
+	for serverID := range r.servers {
+		if !current[serverID] {
+			delete(r.servers, serverID)
+		}
+	}
+}
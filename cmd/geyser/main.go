@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -48,6 +49,17 @@ func main() {
 		}
 	}
 
+	// Floodgate auth fails for every Bedrock player when the key is missing, say so up front
+	if getEnv("REMOTE_AUTH_TYPE", "online") == "floodgate" {
+		keyFile := getEnv("GEYSER_FLOODGATE_KEY_FILE", "key.pem")
+		if !filepath.IsAbs(keyFile) {
+			keyFile = filepath.Join(dataDir, keyFile)
+		}
+		if info, err := os.Stat(keyFile); err != nil || info.Size() == 0 {
+			fatal("floodgate auth needs the server's floodgate key at %s, mount the server's floodgate key.pem there", keyFile)
+		}
+	}
+
 	// Chown data directory recursively
 	if err := chownRecursive(dataDir, puid, pgid); err != nil {
 		fatal("failed to chown data dir: %v", err)
@@ -226,7 +238,11 @@ func chownRecursive(path string, uid, gid int) error {
 		if err != nil {
 			return err
 		}
-		return os.Chown(name, uid, gid)
+		// Read-only mounts like the shared floodgate key keep their owner
+		if err := os.Chown(name, uid, gid); err != nil && !errors.Is(err, syscall.EROFS) {
+			return err
+		}
+		return nil
 	})
 }
 
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"os"
 	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,8 +36,9 @@ var templateFuncs = template.FuncMap{
 var templateFS embed.FS
 
 func main() {
-	serverID := os.Getenv("DISCOPANEL_SERVER_ID")
-	if serverID == "" {
+	// One ID, a comma separated list or "all"
+	serverIDs := os.Getenv("DISCOPANEL_SERVER_ID")
+	if serverIDs == "" {
 		fmt.Fprintln(os.Stderr, "DISCOPANEL_SERVER_ID required")
 		os.Exit(1)
 	}
@@ -43,7 +47,7 @@ func main() {
 	port := envInt("PORT", 8181)
 	poll := envDuration("POLL_INTERVAL", 10*time.Second)
 
-	fmt.Printf("Status Panel: server=%s api=%s poll=%s port=%d\n", serverID, apiURL, poll, port)
+	fmt.Printf("Status Panel: servers=%s api=%s poll=%s port=%d\n", serverIDs, apiURL, poll, port)
 
 	tmpl, err := template.New("").Funcs(templateFuncs).ParseFS(templateFS, "templates/*.tmpl")
 	if err != nil {
@@ -51,30 +55,47 @@ func main() {
 		os.Exit(1)
 	}
 
-	apiToken := os.Getenv("DISCOPANEL_API_TOKEN")
+	p := &panel{
+		poll:   poll,
+		tmpl:   tmpl,
+		title:  os.Getenv("PANEL_TITLE"),
+		states: make(map[string]*serverState),
+	}
+	if serverIDs == "all" {
+		p.all = true
+	} else {
+		for id := range strings.SplitSeq(serverIDs, ",") {
+			if id = strings.TrimSpace(id); id != "" && !slices.Contains(p.ids, id) {
+				p.ids = append(p.ids, id)
+			}
+		}
+	}
+	p.multi = p.all || len(p.ids) > 1
+
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 
+	// The panel token is scoped to this module's server and outlives the user who added the
+	// module. Showing other servers takes an API token that can read them.
+	panelToken, apiToken := os.Getenv("DISCOPANEL_PANEL_TOKEN"), os.Getenv("DISCOPANEL_API_TOKEN")
 	var clientOpts []connect.ClientOption
-	if apiToken != "" {
+	if panelToken != "" && (!p.multi || apiToken == "") {
+		fmt.Println("Using DISCOPANEL_PANEL_TOKEN for authentication")
+		clientOpts = append(clientOpts, connect.WithInterceptors(authInterceptor(panelToken)))
+	} else if apiToken != "" {
 		fmt.Println("Using DISCOPANEL_API_TOKEN for authentication")
 		clientOpts = append(clientOpts, connect.WithInterceptors(authInterceptor(apiToken)))
 	}
 
-	p := &panel{
-		serverClient:    discopanelv1connect.NewServerServiceClient(httpClient, apiURL, clientOpts...),
-		minecraftClient: discopanelv1connect.NewMinecraftServiceClient(httpClient, apiURL, clientOpts...),
-		configClient:    discopanelv1connect.NewConfigServiceClient(httpClient, apiURL, clientOpts...),
-		modpackClient:   discopanelv1connect.NewModpackServiceClient(httpClient, apiURL, clientOpts...),
-		serverID:        serverID,
-		poll:            poll,
-		tmpl:            tmpl,
-		title:           os.Getenv("PANEL_TITLE"),
-	}
+	p.serverClient = discopanelv1connect.NewServerServiceClient(httpClient, apiURL, clientOpts...)
+	p.minecraftClient = discopanelv1connect.NewMinecraftServiceClient(httpClient, apiURL, clientOpts...)
+	p.configClient = discopanelv1connect.NewConfigServiceClient(httpClient, apiURL, clientOpts...)
+	p.modpackClient = discopanelv1connect.NewModpackServiceClient(httpClient, apiURL, clientOpts...)
 
 	go p.loop()
 
 	http.HandleFunc("/", p.handleIndex)
 	http.HandleFunc("/api/status", p.handleAPI)
+	http.HandleFunc("/events", p.handleEvents)
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
@@ -89,16 +110,28 @@ type panel struct {
 	minecraftClient discopanelv1connect.MinecraftServiceClient
 	configClient    discopanelv1connect.ConfigServiceClient
 	modpackClient   discopanelv1connect.ModpackServiceClient
-	serverID        string
 	poll            time.Duration
 	tmpl            *template.Template
 	title           string
+	all             bool // Show every server the token can read
+	multi           bool // Render a grid instead of a single card
+
+	mu         sync.RWMutex
+	ids        []string
+	states     map[string]*serverState
+	modLoaders map[string]*v1.ModLoaderInfo
+	listErr    error // Listing servers failed, only in "all" mode
+
+	subMu       sync.Mutex
+	subscribers map[chan struct{}]struct{} // Open /events streams, signalled after each fetch
+}
 
-	mu             sync.RWMutex
+// What the panel knows about one server, each fails independently of the others
+type serverState struct {
 	server         *v1.Server
-	modLoaders     map[string]*v1.ModLoaderInfo
 	modpack        *v1.IndexedModpack
 	modpackVersion string
+	staticFetched  bool
 	err            error
 }
 
@@ -113,34 +146,120 @@ func (p *panel) fetch() {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	// Fetch static data once (mod loaders, modpack info)
-	p.fetchStaticOnce(ctx)
+	// Fetch static data once (mod loaders)
+	p.fetchModLoadersOnce(ctx)
 
-	// Fetch server status (this changes frequently)
-	resp, err := p.serverClient.GetServer(ctx, connect.NewRequest(&v1.GetServerRequest{Id: p.serverID}))
+	if p.all {
+		p.refreshServerList(ctx)
+	}
+
+	p.mu.RLock()
+	ids := slices.Clone(p.ids)
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.fetchServer(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	p.notify()
+}
+
+// Tells every open /events stream a new snapshot is ready
+func (p *panel) notify() {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	for ch := range p.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // Already pending, the stream sends the latest snapshot anyway
+		}
+	}
+}
+
+func (p *panel) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	p.subMu.Lock()
+	if p.subscribers == nil {
+		p.subscribers = make(map[chan struct{}]struct{})
+	}
+	p.subscribers[ch] = struct{}{}
+	p.subMu.Unlock()
+	return ch
+}
+
+func (p *panel) unsubscribe(ch chan struct{}) {
+	p.subMu.Lock()
+	delete(p.subscribers, ch)
+	p.subMu.Unlock()
+}
+
+// Picks up servers created or deleted since the last poll
+func (p *panel) refreshServerList(ctx context.Context) {
+	resp, err := p.serverClient.ListServers(ctx, connect.NewRequest(&v1.ListServersRequest{}))
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if err != nil {
-		p.err = err
-		fmt.Printf("fetch error: %v\n", err)
+		p.listErr = err
+		fmt.Printf("list error: %v\n", err)
 		return
 	}
+	p.listErr = nil
+
+	p.ids = p.ids[:0]
+	for _, server := range resp.Msg.GetServers() {
+		p.ids = append(p.ids, server.GetId())
+	}
+	for id := range p.states {
+		if !slices.Contains(p.ids, id) {
+			delete(p.states, id)
+		}
+	}
+}
 
-	p.server = resp.Msg.GetServer()
-	p.err = nil
+func (p *panel) fetchServer(ctx context.Context, serverID string) {
+	// Fetch modpack info once per server
+	p.fetchStaticOnce(ctx, serverID)
 
-	if p.title == "" && p.server.GetName() != "" {
-		p.title = p.server.GetName()
+	// Fetch server status (this changes frequently)
+	resp, err := p.serverClient.GetServer(ctx, connect.NewRequest(&v1.GetServerRequest{Id: serverID}))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.state(serverID)
+	if err != nil {
+		state.err = err
+		fmt.Printf("fetch error: server=%s: %v\n", serverID, err)
+		return
 	}
 
+	state.server = resp.Msg.GetServer()
+	state.err = nil
+
 	fmt.Printf("fetched: %s status=%s players=%d tps=%.1f\n",
-		p.server.GetName(), p.server.GetStatus(), p.server.GetPlayersOnline(), p.server.GetTps())
+		state.server.GetName(), state.server.GetStatus(), state.server.GetPlayersOnline(), state.server.GetTps())
+}
+
+// Returns the server's state, creating it on first use. Callers hold p.mu.
+func (p *panel) state(serverID string) *serverState {
+	state, ok := p.states[serverID]
+	if !ok {
+		state = &serverState{}
+		p.states[serverID] = state
+	}
+	return state
 }
 
-// Fetches data that doesn't change during runtime
-func (p *panel) fetchStaticOnce(ctx context.Context) {
+// Fetches the mod loaders once, they don't change during runtime
+func (p *panel) fetchModLoadersOnce(ctx context.Context) {
 	p.mu.RLock()
 	alreadyFetched := p.modLoaders != nil
 	p.mu.RUnlock()
@@ -162,13 +281,25 @@ func (p *panel) fetchStaticOnce(ctx context.Context) {
 		p.modLoaders[info.GetName()] = info
 	}
 	p.mu.Unlock()
+}
+
+// Fetches a server's modpack info, which doesn't change during runtime
+func (p *panel) fetchStaticOnce(ctx context.Context, serverID string) {
+	p.mu.RLock()
+	state, ok := p.states[serverID]
+	alreadyFetched := ok && state.staticFetched
+	p.mu.RUnlock()
+
+	if alreadyFetched {
+		return
+	}
 
 	// Fetch server config to find modpack URL and version
 	indexerKeys := []string{"cfPageUrl", "modrinthModpack"}
 	versionKeys := []string{"cfFileId", "modrinthVersion"}
-	configResp, err := p.configClient.GetServerConfig(ctx, connect.NewRequest(&v1.GetServerConfigRequest{ServerId: p.serverID}))
+	configResp, err := p.configClient.GetServerConfig(ctx, connect.NewRequest(&v1.GetServerConfigRequest{ServerId: serverID}))
 	if err != nil {
-		fmt.Printf("failed to fetch server config: %v\n", err)
+		fmt.Printf("failed to fetch server config: server=%s: %v\n", serverID, err)
 		return
 	}
 
@@ -185,16 +316,23 @@ func (p *panel) fetchStaticOnce(ctx context.Context) {
 		}
 	}
 
+	var modpack *v1.IndexedModpack
 	if modpackURL != "" {
 		modpackResp, _ := p.modpackClient.GetModpackByURL(ctx, connect.NewRequest(&v1.GetModpackByURLRequest{Url: modpackURL}))
 		if modpackResp != nil && modpackResp.Msg.GetModpack() != nil {
-			p.mu.Lock()
-			p.modpack = modpackResp.Msg.GetModpack()
-			p.modpackVersion = modpackVersion
-			p.mu.Unlock()
-			fmt.Printf("loaded modpack: %s (version: %s)\n", p.modpack.GetName(), modpackVersion)
+			modpack = modpackResp.Msg.GetModpack()
+			fmt.Printf("loaded modpack: %s (version: %s)\n", modpack.GetName(), modpackVersion)
 		}
 	}
+
+	p.mu.Lock()
+	state = p.state(serverID)
+	state.modpack = modpack
+	if modpack != nil {
+		state.modpackVersion = modpackVersion
+	}
+	state.staticFetched = true
+	p.mu.Unlock()
 }
 
 func (p *panel) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -203,22 +341,121 @@ func (p *panel) handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := p.tmpl.ExecuteTemplate(w, "index.tmpl", p.pageData()); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// Streams the rendered panel content after every fetch, so the page updates in place
+func (p *panel) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	updates := p.subscribe()
+	defer p.unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from holding events back
+
+	// Comments keep idle proxies from closing the stream between polls
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	if err := p.writeSnapshot(w); err != nil {
+		fmt.Printf("event stream error: %v\n", err)
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updates:
+			if err := p.writeSnapshot(w); err != nil {
+				fmt.Printf("event stream error: %v\n", err)
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Writes the current panel content as one event
+func (p *panel) writeSnapshot(w io.Writer) error {
+	data := p.pageData()
+	var html bytes.Buffer
+	if err := p.tmpl.ExecuteTemplate(&html, "content.tmpl", data); err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(map[string]string{"title": data["Title"].(string), "html": html.String()})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", snapshot)
+	return err
+}
+
+// Template data for the whole page
+func (p *panel) pageData() map[string]any {
 	p.mu.RLock()
-	server := p.server
-	modpack := p.modpack
-	modpackVersion := p.modpackVersion
-	var modLoaderInfo *v1.ModLoaderInfo
-	if server != nil && p.modLoaders != nil {
-		modLoaderInfo = p.modLoaders[server.GetModLoader().String()]
+	cards := make([]map[string]any, 0, len(p.ids))
+	for _, id := range p.ids {
+		cards = append(cards, p.cardData(id))
 	}
-	err := p.err
-	title := p.title
+	listErr := p.listErr
 	p.mu.RUnlock()
 
-	if title == "" {
+	title := p.title
+	if !p.multi && len(cards) == 1 {
+		title = cards[0]["Title"].(string)
+	} else if title == "" {
 		title = "Server Status"
 	}
 
+	return map[string]any{
+		"Multi":   p.multi,
+		"Title":   title,
+		"Cards":   cards,
+		"Error":   listErr,
+		"Refresh": max(int(p.poll.Seconds()), 1),
+	}
+}
+
+// Template data for one server's card. Callers hold p.mu.
+func (p *panel) cardData(serverID string) map[string]any {
+	state, ok := p.states[serverID]
+	if !ok {
+		state = &serverState{}
+	}
+	server := state.server
+
+	// A single card carries the panel title, grid cards their server's name
+	title := server.GetName()
+	if !p.multi {
+		if p.title != "" {
+			title = p.title
+		} else if title == "" {
+			title = "Server Status"
+		}
+	} else if title == "" {
+		title = serverID
+	}
+
+	var modLoaderInfo *v1.ModLoaderInfo
+	if server != nil && p.modLoaders != nil {
+		modLoaderInfo = p.modLoaders[server.GetModLoader().String()]
+	}
+
 	// Compute derived values for display
 	var memoryUsedMB int64
 	var memoryPercent float64
@@ -247,23 +484,18 @@ func (p *panel) handleIndex(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	data := map[string]any{
+	return map[string]any{
 		"Server":            server,
-		"Modpack":           modpack,
-		"ModpackVersion":    modpackVersion,
+		"Modpack":           state.modpack,
+		"ModpackVersion":    state.modpackVersion,
 		"ModLoaderInfo":     modLoaderInfo,
 		"Title":             title,
-		"Error":             err,
+		"Error":             state.err,
 		"MemoryUsedMB":      memoryUsedMB,
 		"MemoryPercent":     fmt.Sprintf("%.0f", memoryPercent),
 		"DiskUsedFormatted": diskUsedFormatted,
 		"DiskPercent":       fmt.Sprintf("%.0f", diskPercent),
 	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := p.tmpl.ExecuteTemplate(w, "index.tmpl", data); err != nil {
-		http.Error(w, err.Error(), 500)
-	}
 }
 
 func formatBytes(bytes int64) string {
@@ -281,20 +513,33 @@ func formatBytes(bytes int64) string {
 
 func (p *panel) handleAPI(w http.ResponseWriter, r *http.Request) {
 	p.mu.RLock()
-	server := p.server
-	var modLoaderInfo *v1.ModLoaderInfo
-	if server != nil && p.modLoaders != nil {
-		modLoaderInfo = p.modLoaders[server.GetModLoader().String()]
+	statuses := make([]map[string]any, 0, len(p.ids))
+	for _, id := range p.ids {
+		status := map[string]any{"server_id": id, "server": nil, "mod_loader_info": nil}
+		if state, ok := p.states[id]; ok {
+			status["server"] = state.server
+			if state.server != nil && p.modLoaders != nil {
+				status["mod_loader_info"] = p.modLoaders[state.server.GetModLoader().String()]
+			}
+			if state.err != nil {
+				status["error"] = state.err.Error()
+			}
+		}
+		statuses = append(statuses, status)
 	}
-	err := p.err
+	listErr := p.listErr
 	p.mu.RUnlock()
 
-	resp := map[string]any{
-		"server":          server,
-		"mod_loader_info": modLoaderInfo,
-	}
-	if err != nil {
-		resp["error"] = err.Error()
+	// Single server mode keeps its original shape
+	var resp map[string]any
+	if !p.multi && len(statuses) == 1 {
+		resp = statuses[0]
+		delete(resp, "server_id")
+	} else {
+		resp = map[string]any{"servers": statuses}
+		if listErr != nil {
+			resp["error"] = listErr.Error()
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
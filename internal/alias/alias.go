@@ -10,6 +10,7 @@ import (
 
 	"github.com/nickheyer/discopanel/internal/config"
 	models "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/minecraft"
 )
 
 // Category groups aliases by their source type
@@ -38,10 +39,26 @@ type Host struct {
 	Hostname string `json:"hostname"`
 }
 
+// Network holds where modules reach the server on the docker network
+type Network struct {
+	Host string `json:"host"` // Container name of the server
+	Port int    `json:"port"` // Java port inside the server container
+}
+
+// Floodgate holds the server's shared Floodgate key
+type Floodgate struct {
+	KeyPath string `json:"key_path"` // Host path of key.pem, empty for loaders without plugin or mod support
+}
+
+// FloodgateKeyAlias makes the module manager generate the key before the module is created
+const FloodgateKeyAlias = "{{server.floodgate.key_path}}"
+
 // Context holds the objects available for alias resolution
 type Context struct {
 	Server       *models.Server
 	ServerConfig *models.ServerConfig
+	Network      *Network   // Derived from Server when unset
+	Floodgate    *Floodgate // Derived from Server when unset
 	Module       *models.Module
 	Modules      map[string]*models.Module // Sibling modules by name (for inter-module references)
 	Host         *Host
@@ -58,8 +75,17 @@ func NewContext() *Context {
 	}
 }
 
-// Derived from host fields
+// Derived from host and server fields
 func (ctx *Context) populateComputed() {
+	if ctx.Network == nil && ctx.Server != nil {
+		ctx.Network = &Network{
+			Host: "discopanel-server-" + ctx.Server.ID,
+			Port: ctx.Server.ContainerPort(),
+		}
+	}
+	if ctx.Floodgate == nil && ctx.Server != nil {
+		ctx.Floodgate = &Floodgate{KeyPath: minecraft.FloodgateKeyPath(ctx.Server.DataPath, ctx.Server.ModLoader)}
+	}
 	if ctx.Host == nil {
 		ctx.Host = &Host{UID: os.Getuid(), GID: os.Getgid()}
 	}
@@ -92,6 +118,8 @@ func GetAvailableAliases(ctx *Context) []Info {
 		{"config", CategorySpecial, reflect.ValueOf(config.Config{})},
 		{"server", CategoryServer, reflect.ValueOf(models.Server{})},
 		{"server.config", CategoryServer, reflect.ValueOf(models.ServerConfig{})},
+		{"server.network", CategoryServer, reflect.ValueOf(Network{})},
+		{"server.floodgate", CategoryServer, reflect.ValueOf(Floodgate{})},
 		{"module", CategoryModule, reflect.ValueOf(models.Module{})},
 	}
 
@@ -105,6 +133,8 @@ func GetAvailableAliases(ctx *Context) []Info {
 		{"config", CategorySpecial, ctx.Config},
 		{"server", CategoryServer, ctx.Server},
 		{"server.config", CategoryServer, ctx.ServerConfig},
+		{"server.network", CategoryServer, ctx.Network},
+		{"server.floodgate", CategoryServer, ctx.Floodgate},
 		{"module", CategoryModule, ctx.Module},
 	}
 
@@ -331,6 +361,8 @@ func Substitute(input string, ctx *Context) string {
 		{"host", ctx.Host},
 		{"config", ctx.Config},
 		{"server.config", ctx.ServerConfig},
+		{"server.network", ctx.Network},
+		{"server.floodgate", ctx.Floodgate},
 		{"server", ctx.Server},
 		{"module", ctx.Module},
 	}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/logger"
+)
+
+const (
+	bufferSize    = 1024
+	batchSize     = 100
+	flushInterval = time.Second
+)
+
+// Recorder persists audit entries in the background so callers never block on the database
+type Recorder struct {
+	store   *storage.Store
+	log     *logger.Logger
+	entries chan *storage.AuditLog
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// Creates a recorder and starts its writer
+func NewRecorder(store *storage.Store, log *logger.Logger) *Recorder {
+	r := &Recorder{
+		store:   store,
+		log:     log,
+		entries: make(chan *storage.AuditLog, bufferSize),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+// Queues an entry, dropping it if the buffer is full. Safe on a nil recorder.
+func (r *Recorder) Record(entry *storage.AuditLog) {
+	if r == nil || entry == nil {
+		return
+	}
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	select {
+	case r.entries <- entry:
+	default:
+		r.log.Warn("Audit buffer full, dropping entry for %s", entry.Action)
+	}
+}
+
+// Flushes queued entries and stops the writer
+func (r *Recorder) Close() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		close(r.entries)
+		r.wg.Wait()
+	})
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*storage.AuditLog, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := r.store.CreateAuditLogs(ctx, batch); err != nil {
+			r.log.Error("Failed to write %d audit entries: %v", len(batch), err)
+		}
+		cancel()
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry, ok := <-r.entries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Encodes detail fields as a JSON object, ignoring empty maps
+func Detail(fields map[string]any) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Strips the port from a remote address
+func ClientIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
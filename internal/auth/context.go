@@ -1,6 +1,10 @@
 package auth
 
-import "context"
+import (
+	"context"
+
+	"github.com/nickheyer/discopanel/internal/rbac"
+)
 
 type contextKey string
 
@@ -8,11 +12,38 @@ const UserContextKey contextKey = "authenticated_user"
 
 // AuthenticatedUser represents a validated user in context
 type AuthenticatedUser struct {
-	ID       string
-	Username string
-	Email    string
-	Roles    []string
-	Provider string // "local" or "oidc"
+	ID        string
+	Username  string
+	Email     string
+	Roles     []string
+	Provider  string   // "local" or "oidc"
+	Scopes    []string // API token scopes, empty when not restricted
+	SessionID string   // Set when authenticated with a session token
+	PanelFor  string   // Server ID when authenticated with that server's panel token
+}
+
+// ClientInfo identifies where a request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+const clientInfoContextKey contextKey = "client_info"
+
+// WithClientInfo adds request origin details to context
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoContextKey, info)
+}
+
+// GetClientInfo retrieves request origin details from context
+func GetClientInfo(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoContextKey).(ClientInfo)
+	return info
+}
+
+// Allows reports whether the user's token scopes permit an action on a resource
+func (u *AuthenticatedUser) Allows(resource, action string) bool {
+	return rbac.ScopeAllows(u.Scopes, resource, action)
 }
 
 // GetUserFromContext retrieves the authenticated user from context
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const forwardedHTTPSContextKey contextKey = "forwarded_https"
+
+// WithForwardedHTTPS marks a request that a trusted proxy received over HTTPS
+func WithForwardedHTTPS(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forwardedHTTPSContextKey, true)
+}
+
+// Whether the client reached us over HTTPS, directly or through a trusted proxy
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || forwardedHTTPS(r.Context())
+}
+
+func forwardedHTTPS(ctx context.Context) bool {
+	forwarded, _ := ctx.Value(forwardedHTTPSContextKey).(bool)
+	return forwarded
+}
+
+// SetCookie sets a cookie with the configured Secure and SameSite attributes
+func (m *Manager) SetCookie(w http.ResponseWriter, r *http.Request, cookie *http.Cookie) {
+	m.applyCookieAttributes(cookie, isHTTPS(r))
+	http.SetCookie(w, cookie)
+}
+
+// CookieHeader returns a Set-Cookie value with the configured attributes, for responses
+// written without an http.ResponseWriter such as RPC handlers
+func (m *Manager) CookieHeader(ctx context.Context, cookie *http.Cookie) string {
+	m.applyCookieAttributes(cookie, forwardedHTTPS(ctx))
+	return cookie.String()
+}
+
+func (m *Manager) applyCookieAttributes(cookie *http.Cookie, https bool) {
+	switch strings.ToLower(m.config.Cookie.Secure) {
+	case "always":
+		cookie.Secure = true
+	case "never":
+		cookie.Secure = false
+	default:
+		cookie.Secure = https
+	}
+
+	switch strings.ToLower(m.config.Cookie.SameSite) {
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "none":
+		// Browsers drop SameSite=None cookies that aren't secure
+		cookie.SameSite = http.SameSiteNoneMode
+		cookie.Secure = true
+	default:
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+}
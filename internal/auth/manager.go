@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -47,6 +48,9 @@ type Manager struct {
 	config      *config.AuthConfig
 	jwtSecret   []byte
 	recoveryKey string
+	throttle    *throttle
+	passkeys    *passkeyCeremonies
+	totpKey     []byte // Encrypts stored TOTP secrets
 }
 
 const jwtSecretSettingKey = "jwt_secret"
@@ -79,6 +83,11 @@ func NewManager(store *db.Store, enforcer *rbac.Enforcer, cfg *config.AuthConfig
 		}
 	}
 
+	totpKey, err := loadTOTPKey(ctx, store, cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate recovery key
 	recoveryBytes := make([]byte, 32)
 	if _, err := rand.Read(recoveryBytes); err != nil {
@@ -91,6 +100,9 @@ func NewManager(store *db.Store, enforcer *rbac.Enforcer, cfg *config.AuthConfig
 		config:      cfg,
 		jwtSecret:   secret,
 		recoveryKey: hex.EncodeToString(recoveryBytes),
+		throttle:    newThrottle(cfg.Throttle),
+		passkeys:    newPasskeyCeremonies(),
+		totpKey:     totpKey,
 	}
 
 	m.loadSettingOverrides(ctx)
@@ -98,46 +110,73 @@ func NewManager(store *db.Store, enforcer *rbac.Enforcer, cfg *config.AuthConfig
 	return m, nil
 }
 
-func (m *Manager) Login(ctx context.Context, username, password string) (*db.User, []string, string, time.Time, error) {
+// Login checks a local password and, for users with two-factor on, totpCode, which may
+// also be a recovery code. ErrTOTPRequired means the password was right but no code was given.
+func (m *Manager) Login(ctx context.Context, username, password, totpCode string) (*db.User, []string, string, time.Time, error) {
 	if !m.config.Local.Enabled {
 		return nil, nil, "", time.Time{}, ErrLocalAuthDisabled
 	}
 
+	// Locked out keys are rejected before the password is checked
+	clientIP := GetClientInfo(ctx).IP
+	userKey, ipKey := "user:"+strings.ToLower(username), "ip:"+clientIP
+	if err := m.throttle.check(userKey, ipKey); err != nil {
+		return nil, nil, "", time.Time{}, err
+	}
+	failed := func(err error) (*db.User, []string, string, time.Time, error) {
+		m.throttle.fail(userKey, m.config.Throttle.MaxAttempts)
+		if clientIP != "" {
+			m.throttle.fail(ipKey, m.config.Throttle.MaxAttemptsPerIP)
+		}
+		return nil, nil, "", time.Time{}, err
+	}
+
 	user, err := m.store.GetUserByUsernameAndProvider(ctx, username, "local")
 	if err != nil {
-		return nil, nil, "", time.Time{}, ErrInvalidCredentials
+		return failed(ErrInvalidCredentials)
 	}
 
 	if !checkPassword(user.PasswordHash, password) {
-		return nil, nil, "", time.Time{}, ErrInvalidCredentials
+		return failed(ErrInvalidCredentials)
 	}
 
 	if !user.IsActive {
-		return nil, nil, "", time.Time{}, ErrUserNotActive
+		return failed(ErrUserNotActive)
 	}
 
+	if err := m.checkSecondFactor(ctx, user.ID, totpCode); err != nil {
+		if errors.Is(err, ErrTOTPRequired) {
+			return nil, nil, "", time.Time{}, err
+		}
+		return failed(err)
+	}
+	m.throttle.reset(userKey, ipKey)
+
+	roleNames, token, expiresAt, err := m.issueSession(ctx, user)
+	if err != nil {
+		return nil, nil, "", time.Time{}, err
+	}
+	return user, roleNames, token, expiresAt, nil
+}
+
+// Signs a session token for an authenticated user and records the login
+func (m *Manager) issueSession(ctx context.Context, user *db.User) ([]string, string, time.Time, error) {
 	// Get user roles
 	roleNames, err := m.store.GetUserRoleNames(ctx, user.ID)
 	if err != nil {
-		return nil, nil, "", time.Time{}, fmt.Errorf("failed to get user roles: %w", err)
+		return nil, "", time.Time{}, fmt.Errorf("failed to get user roles: %w", err)
 	}
 
 	// Generate token
 	expiresAt := time.Now().Add(time.Duration(m.config.SessionTimeout) * time.Second)
 	token, err := m.generateJWT(user.ID, user.Username, roleNames, expiresAt)
 	if err != nil {
-		return nil, nil, "", time.Time{}, err
+		return nil, "", time.Time{}, err
 	}
 
 	// Create session
-	session := &db.Session{
-		ID:        uuid.New().String(),
-		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: expiresAt,
-	}
-	if err := m.store.CreateSession(ctx, session); err != nil {
-		return nil, nil, "", time.Time{}, err
+	if _, err := m.createSession(ctx, user.ID, token, expiresAt, ""); err != nil {
+		return nil, "", time.Time{}, err
 	}
 
 	// Update last login
@@ -145,7 +184,30 @@ func (m *Manager) Login(ctx context.Context, username, password string) (*db.Use
 	user.LastLogin = &now
 	_ = m.store.UpdateUser(ctx, user)
 
-	return user, roleNames, token, expiresAt, nil
+	return roleNames, token, expiresAt, nil
+}
+
+// Persists a session, tagging it with the client info carried in ctx. idToken is
+// only set for OIDC logins.
+func (m *Manager) createSession(ctx context.Context, userID, token string, expiresAt time.Time, idToken string) (*db.Session, error) {
+	client := GetClientInfo(ctx)
+	userAgent := client.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	session := &db.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		IPAddress: client.IP,
+		UserAgent: userAgent,
+		IDToken:   idToken,
+	}
+	if err := m.store.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
 }
 
 func (m *Manager) ValidateSession(ctx context.Context, token string) (*AuthenticatedUser, error) {
@@ -187,10 +249,11 @@ func (m *Manager) ValidateSession(ctx context.Context, token string) (*Authentic
 	}
 
 	authUser := &AuthenticatedUser{
-		ID:       user.ID,
-		Username: user.Username,
-		Roles:    roleNames,
-		Provider: user.AuthProvider,
+		ID:        user.ID,
+		Username:  user.Username,
+		Roles:     roleNames,
+		Provider:  user.AuthProvider,
+		SessionID: session.ID,
 	}
 	if user.Email != nil {
 		authUser.Email = *user.Email
@@ -199,8 +262,13 @@ func (m *Manager) ValidateSession(ctx context.Context, token string) (*Authentic
 	return authUser, nil
 }
 
-func (m *Manager) Logout(ctx context.Context, token string) error {
-	return m.store.DeleteSession(ctx, token)
+// Logout ends the session for token and returns it, nil when it had already ended
+func (m *Manager) Logout(ctx context.Context, token string) (*db.Session, error) {
+	session, err := m.store.GetSession(ctx, token)
+	if err != nil {
+		session = nil
+	}
+	return session, m.store.DeleteSession(ctx, token)
 }
 
 func (m *Manager) CreateLocalUser(ctx context.Context, username, email, password string) (*db.User, error) {
@@ -253,6 +321,18 @@ func (m *Manager) ChangePassword(ctx context.Context, userID, oldPassword, newPa
 	return m.store.UpdateUser(ctx, user)
 }
 
+// VerifyPassword checks a local user's current password, e.g. before disabling two-factor
+func (m *Manager) VerifyPassword(ctx context.Context, userID, password string) error {
+	user, err := m.store.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.AuthProvider != "local" || !checkPassword(user.PasswordHash, password) {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
 func (m *Manager) AnonymousUser() *AuthenticatedUser {
 	return &AuthenticatedUser{
 		ID:       "anonymous",
@@ -285,6 +365,9 @@ func (m *Manager) AuthenticateFromHeader(ctx context.Context, authHeader string)
 	}
 
 	if token != "" {
+		if strings.HasPrefix(token, panelTokenPrefix) {
+			return m.ValidatePanelToken(ctx, token)
+		}
 		if strings.HasPrefix(token, "dp_") {
 			return m.ValidateAPIToken(ctx, token)
 		}
@@ -375,7 +458,13 @@ func (m *Manager) UpdateSettings(ctx context.Context, localEnabled, allowReg, an
 }
 
 // Creates a new API token for a user. Plaintext is returned, SHA-256 hash is stored
-func (m *Manager) GenerateAPIToken(ctx context.Context, userID, name string, expiresInDays *int32) (string, *db.APIToken, error) {
+func (m *Manager) GenerateAPIToken(ctx context.Context, userID, name string, expiresInDays *int32, scopes []string) (string, *db.APIToken, error) {
+	for _, scope := range scopes {
+		if err := rbac.ValidateScope(scope); err != nil {
+			return "", nil, err
+		}
+	}
+
 	// Generate 32 random bytes
 	raw := make([]byte, 32)
 	if _, err := rand.Read(raw); err != nil {
@@ -400,6 +489,7 @@ func (m *Manager) GenerateAPIToken(ctx context.Context, userID, name string, exp
 		Name:      name,
 		TokenHash: hashHex,
 		ExpiresAt: expiresAt,
+		Scopes:    scopes,
 	}
 
 	if err := m.store.CreateAPIToken(ctx, token); err != nil {
@@ -412,7 +502,7 @@ func (m *Manager) GenerateAPIToken(ctx context.Context, userID, name string, exp
 // Creates API token for a module, tied to the creating user's identity
 func (m *Manager) GenerateModuleToken(ctx context.Context, userID, moduleName, moduleID string) (string, *db.APIToken, error) {
 	tokenName := fmt.Sprintf("module:%s:%s", moduleName, moduleID)
-	plaintext, token, err := m.GenerateAPIToken(ctx, userID, tokenName, nil)
+	plaintext, token, err := m.GenerateAPIToken(ctx, userID, tokenName, nil, nil)
 	if err != nil {
 		return "", nil, err
 	}
@@ -426,6 +516,53 @@ func (m *Manager) GenerateModuleToken(ctx context.Context, userID, moduleName, m
 	return plaintext, token, nil
 }
 
+const panelTokenPrefix = "dpp_"
+
+// RotatePanelToken mints a new panel token for the module's server, invalidating the one
+// the module had before. Only its hash is stored, the plaintext is returned and kept on
+// module.PanelToken for the container being built. Recreating a module rotates its token.
+func RotatePanelToken(ctx context.Context, store *db.Store, module *db.Module) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate panel token: %w", err)
+	}
+	token := panelTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	hash := sha256.Sum256([]byte(token))
+	hashHex := hex.EncodeToString(hash[:])
+	if err := store.SetModulePanelTokenHash(ctx, module.ID, hashHex); err != nil {
+		return "", fmt.Errorf("failed to store panel token: %w", err)
+	}
+	module.PanelTokenHash = hashHex
+	module.PanelToken = token
+	return token, nil
+}
+
+// Validates a raw panel token (dpp_...). The caller has no roles, the interceptor only
+// lets it through to rbac.PanelProcedures for its own server.
+func (m *Manager) ValidatePanelToken(ctx context.Context, rawToken string) (*AuthenticatedUser, error) {
+	if !strings.HasPrefix(rawToken, panelTokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	hash := sha256.Sum256([]byte(rawToken))
+	hashHex := hex.EncodeToString(hash[:])
+	module, err := m.store.GetModuleByPanelTokenHash(ctx, hashHex)
+	if err != nil || subtle.ConstantTimeCompare([]byte(module.PanelTokenHash), []byte(hashHex)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	server, err := m.store.GetServer(ctx, module.ServerID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &AuthenticatedUser{
+		ID:       "panel:" + server.ID,
+		Username: "panel:" + server.Name,
+		Provider: "panel",
+		PanelFor: server.ID,
+	}, nil
+}
+
 // Validates a raw API token (dp_...) and returns the authenticated user.
 func (m *Manager) ValidateAPIToken(ctx context.Context, rawToken string) (*AuthenticatedUser, error) {
 	if !strings.HasPrefix(rawToken, "dp_") {
@@ -473,6 +610,7 @@ func (m *Manager) ValidateAPIToken(ctx context.Context, rawToken string) (*Authe
 		Username: user.Username,
 		Roles:    roleNames,
 		Provider: user.AuthProvider,
+		Scopes:   apiToken.Scopes,
 	}
 	if user.Email != nil {
 		authUser.Email = *user.Email
@@ -486,9 +624,16 @@ func (m *Manager) GetRecoveryKey() string {
 }
 
 func (m *Manager) UseRecoveryKey(ctx context.Context, key string) error {
-	if m.recoveryKey == "" || key != m.recoveryKey {
+	// Recovery attempts share the per-IP limit but are counted separately from logins
+	ipKey := "recovery:" + GetClientInfo(ctx).IP
+	if err := m.throttle.check(ipKey); err != nil {
+		return err
+	}
+	if m.recoveryKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(m.recoveryKey)) != 1 {
+		m.throttle.fail(ipKey, m.config.Throttle.MaxAttemptsPerIP)
 		return ErrInvalidRecoveryKey
 	}
+	m.throttle.reset(ipKey)
 	if err := m.store.ResetAllUsers(ctx); err != nil {
 		return fmt.Errorf("failed to reset users: %w", err)
 	}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickheyer/discopanel/internal/config"
+	"github.com/nickheyer/discopanel/internal/db"
+)
+
+func newTestStore(t *testing.T) *db.Store {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "discopanel.db")
+	cfg.Database.AutoMigrate = true
+	store, err := db.NewStore(cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRecoveryKeyFailuresLockTheIP(t *testing.T) {
+	store := newTestStore(t)
+	authConfig := &config.AuthConfig{}
+	authConfig.Local.Enabled = true
+	authConfig.Throttle = config.ThrottleConfig{Enabled: true, MaxAttempts: 2, MaxAttemptsPerIP: 5, Window: 60, Lockout: 60, MaxLockout: 600}
+	manager, err := NewManager(store, nil, authConfig)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	attacker := WithClientInfo(context.Background(), ClientInfo{IP: "203.0.113.7"})
+	// The per-username limit is lower, recovery keys aren't tied to a username
+	for i := 1; i <= authConfig.Throttle.MaxAttemptsPerIP; i++ {
+		if err := manager.UseRecoveryKey(attacker, "wrong"); !errors.Is(err, ErrInvalidRecoveryKey) {
+			t.Fatalf("attempt %d: err = %v, want %v", i, err, ErrInvalidRecoveryKey)
+		}
+	}
+
+	// Locked out now, even with the right key
+	key := manager.GetRecoveryKey()
+	if err := manager.UseRecoveryKey(attacker, key); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("after %d failures: err = %v, want %v", authConfig.Throttle.MaxAttemptsPerIP, err, ErrTooManyAttempts)
+	}
+
+	// Other clients aren't affected
+	other := WithClientInfo(context.Background(), ClientInfo{IP: "198.51.100.2"})
+	if err := manager.UseRecoveryKey(other, key); err != nil {
+		t.Errorf("recovery from another IP: %v", err)
+	}
+}
+
+func TestRotatePanelTokenInvalidatesTheOldOne(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	authConfig := &config.AuthConfig{}
+	authConfig.Local.Enabled = true
+	manager, err := NewManager(store, nil, authConfig)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	server := &db.Server{ID: "panel-test", Name: "Panel Test", ModLoader: db.ModLoaderVanilla, MCVersion: "1.21.1", Port: 25565, DataPath: t.TempDir()}
+	if err := store.CreateServer(ctx, server); err != nil {
+		t.Fatalf("CreateServer: %v", err)
+	}
+	template := &db.ModuleTemplate{Name: "Status Panel", DockerImage: "busybox"}
+	if err := store.CreateModuleTemplate(ctx, template); err != nil {
+		t.Fatalf("CreateModuleTemplate: %v", err)
+	}
+	module := &db.Module{Name: "status", ServerID: server.ID, TemplateID: template.ID}
+	if err := store.CreateModule(ctx, module); err != nil {
+		t.Fatalf("CreateModule: %v", err)
+	}
+
+	first, err := RotatePanelToken(ctx, store, module)
+	if err != nil {
+		t.Fatalf("RotatePanelToken: %v", err)
+	}
+	stored, err := store.GetModule(ctx, module.ID)
+	if err != nil {
+		t.Fatalf("GetModule: %v", err)
+	}
+	if hash := sha256.Sum256([]byte(first)); stored.PanelTokenHash != hex.EncodeToString(hash[:]) {
+		t.Errorf("stored panel token hash = %q, want the token's SHA-256", stored.PanelTokenHash)
+	}
+	user, err := manager.ValidatePanelToken(ctx, first)
+	if err != nil || user.PanelFor != server.ID {
+		t.Fatalf("ValidatePanelToken = %+v, %v, want panel user for %s", user, err, server.ID)
+	}
+
+	second, err := RotatePanelToken(ctx, store, module)
+	if err != nil {
+		t.Fatalf("RotatePanelToken: %v", err)
+	}
+	if second == first {
+		t.Fatal("rotation returned the same token")
+	}
+	if _, err := manager.ValidatePanelToken(ctx, first); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("old token after rotation: err = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := manager.ValidatePanelToken(ctx, second); err != nil {
+		t.Errorf("new token: %v", err)
+	}
+	if _, err := manager.ValidatePanelToken(ctx, stored.PanelTokenHash); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("stored hash accepted as a token: err = %v", err)
+	}
+}
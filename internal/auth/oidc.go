@@ -10,36 +10,45 @@ import (
 	"io"
 	"maps"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/audit"
 	"github.com/nickheyer/discopanel/internal/config"
 	"github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/pkg/logger"
 	"github.com/tidwall/gjson"
-	"golang.org/x/oauth2"
 )
 
 type OIDCHandler struct {
-	manager      *Manager
-	store        *db.Store
-	config       *config.OIDCConfig
-	provider     *oidc.Provider
-	verifier     *oidc.IDTokenVerifier
-	oauth2Config *oauth2.Config
-	httpClient   *http.Client
-	log          *logger.Logger
+	manager    *Manager
+	store      *db.Store
+	config     *config.OIDCConfig
+	httpClient *http.Client
+	audit      *audit.Recorder
+	basePath   string // Subpath DiscoPanel is hosted under, empty at the root
+	links      oidcLinks
+	log        *logger.Logger
+
+	discoveryMu sync.RWMutex
+	cached      *oidcDiscovery // Last successful discovery, nil until the provider answers
+	lastAttempt time.Time
+	stop        chan struct{}
+	stopOnce    sync.Once
 }
 
-func NewOIDCHandler(manager *Manager, store *db.Store, cfg *config.OIDCConfig, log *logger.Logger) (*OIDCHandler, error) {
+func NewOIDCHandler(manager *Manager, store *db.Store, cfg *config.OIDCConfig, basePath string, log *logger.Logger) (*OIDCHandler, error) {
 	if !cfg.Enabled {
 		return &OIDCHandler{
-			manager: manager,
-			store:   store,
-			config:  cfg,
-			log:     log,
+			manager:  manager,
+			store:    store,
+			config:   cfg,
+			basePath: basePath,
+			log:      log,
 		}, nil
 	}
 
@@ -53,44 +62,89 @@ func NewOIDCHandler(manager *Manager, store *db.Store, cfg *config.OIDCConfig, l
 		log.Warn("OIDC: TLS verification disabled")
 	}
 
-	ctx := context.Background()
-	if httpClient != nil {
-		ctx = oidc.ClientContext(ctx, httpClient)
-	}
-	provider, err := oidc.NewProvider(ctx, cfg.IssuerURI)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
+	h := &OIDCHandler{
+		manager:    manager,
+		store:      store,
+		config:     cfg,
+		httpClient: httpClient,
+		basePath:   basePath,
+		stop:       make(chan struct{}),
+		log:        log,
 	}
 
-	verifier := provider.Verifier(&oidc.Config{ClientID: cfg.ClientID})
+	// A provider that is down now doesn't disable OIDC, logins retry discovery until it answers
+	h.lastAttempt = time.Now()
+	if d, err := h.discover(context.Background()); err != nil {
+		log.Warn("OIDC: provider discovery failed, retrying on login: %v", err)
+	} else {
+		h.storeDiscovery(d)
+	}
 
-	scopes := cfg.Scopes
-	if len(scopes) == 0 {
-		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	ttl := time.Duration(cfg.DiscoveryTTL) * time.Second
+	if ttl <= 0 {
+		ttl = time.Hour
 	}
+	go h.refreshDiscovery(ttl)
 
-	oauth2Config := &oauth2.Config{
-		ClientID:     cfg.ClientID,
-		ClientSecret: cfg.ClientSecret,
-		RedirectURL:  cfg.RedirectURL,
-		Endpoint:     provider.Endpoint(),
-		Scopes:       scopes,
+	return h, nil
+}
+
+// Records OIDC login outcomes in the audit log
+func (h *OIDCHandler) SetAuditRecorder(recorder *audit.Recorder) {
+	h.audit = recorder
+}
+
+// Records a login attempt from the callback
+func (h *OIDCHandler) auditLogin(r *http.Request, user *db.User, username string, reason string) {
+	entry := &db.AuditLog{
+		Action:   "oidc/login",
+		Username: username,
+		IP:       audit.ClientIP(r.RemoteAddr),
+		Success:  reason == "",
+	}
+	if user != nil {
+		entry.UserID = user.ID
+		entry.Username = user.Username
+		entry.TargetType = "users"
+		entry.TargetID = user.ID
+	}
+	if reason != "" {
+		entry.Detail = audit.Detail(map[string]any{"error": reason})
 	}
+	h.audit.Record(entry)
+}
 
-	return &OIDCHandler{
-		manager:      manager,
-		store:        store,
-		config:       cfg,
-		provider:     provider,
-		verifier:     verifier,
-		oauth2Config: oauth2Config,
-		httpClient:   httpClient,
-		log:          log,
-	}, nil
+// LoginURL is where the frontend sends users to start an OIDC login
+func (h *OIDCHandler) LoginURL() string {
+	return h.basePath + "/api/v1/auth/oidc/login"
+}
+
+// EndSessionURL is where to send a user logging out of an OIDC session so the provider
+// ends its session too, empty when provider logout is off or unsupported
+func (h *OIDCHandler) EndSessionURL(idToken string) string {
+	if !h.IsEnabled() || !h.config.LogoutFromProvider || idToken == "" {
+		return ""
+	}
+	d := h.cachedDiscovery()
+	if d == nil || d.endSession == "" {
+		return ""
+	}
+	endSession, err := url.Parse(d.endSession)
+	if err != nil {
+		return ""
+	}
+	query := endSession.Query()
+	query.Set("id_token_hint", idToken)
+	query.Set("client_id", h.config.ClientID)
+	if h.config.PostLogoutRedirectURL != "" {
+		query.Set("post_logout_redirect_uri", h.config.PostLogoutRedirectURL)
+	}
+	endSession.RawQuery = query.Encode()
+	return endSession.String()
 }
 
 func (h *OIDCHandler) IsEnabled() bool {
-	return h.config.Enabled && h.provider != nil
+	return h.config.Enabled
 }
 
 func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -99,6 +153,13 @@ func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	d, err := h.discovery(r.Context())
+	if err != nil {
+		h.log.Error("OIDC: %v", err)
+		http.Error(w, "OIDC provider is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
 	state, err := generateState()
 	if err != nil {
 		http.Error(w, "Failed to generate state", http.StatusInternalServerError)
@@ -106,16 +167,26 @@ func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Store state in cookie
-	http.SetCookie(w, &http.Cookie{
+	h.manager.SetCookie(w, r, &http.Cookie{
 		Name:     "oidc_state",
 		Value:    state,
-		Path:     "/",
+		Path:     h.basePath + "/",
 		MaxAge:   300,
 		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
 	})
 
-	http.Redirect(w, r, h.oauth2Config.AuthCodeURL(state), http.StatusFound)
+	// A link keeps the cookie BeginLink set, any other login clears it so an abandoned link
+	// doesn't turn a later login into one. The link itself never comes from the URL.
+	if !r.URL.Query().Has("link") {
+		h.manager.SetCookie(w, r, &http.Cookie{
+			Name:     "oidc_link",
+			Path:     h.basePath + "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	}
+
+	http.Redirect(w, r, d.oauth2Config.AuthCodeURL(state), http.StatusFound)
 }
 
 func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
@@ -132,22 +203,42 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear state cookie
-	http.SetCookie(w, &http.Cookie{
+	h.manager.SetCookie(w, r, &http.Cookie{
 		Name:     "oidc_state",
 		Value:    "",
-		Path:     "/",
+		Path:     h.basePath + "/",
 		MaxAge:   -1,
 		HttpOnly: true,
 	})
 
+	var linkID string
+	if linkCookie, err := r.Cookie("oidc_link"); err == nil {
+		linkID = linkCookie.Value
+		h.manager.SetCookie(w, r, &http.Cookie{
+			Name:     "oidc_link",
+			Value:    "",
+			Path:     h.basePath + "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	}
+
+	d, err := h.discovery(r.Context())
+	if err != nil {
+		h.log.Error("OIDC: %v", err)
+		http.Error(w, "OIDC provider is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
 	// Exchange code for token
 	ctx := r.Context()
 	if h.httpClient != nil {
 		ctx = oidc.ClientContext(ctx, h.httpClient)
 	}
-	oauth2Token, err := h.oauth2Config.Exchange(ctx, r.URL.Query().Get("code"))
+	oauth2Token, err := d.oauth2Config.Exchange(ctx, r.URL.Query().Get("code"))
 	if err != nil {
 		h.log.Error("OIDC: failed to exchange code for token: %v", err)
+		h.auditLogin(r, nil, "", "failed to exchange code for token")
 		http.Error(w, "Failed to exchange code for token", http.StatusInternalServerError)
 		return
 	}
@@ -161,9 +252,10 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify ID token
-	idToken, err := h.verifier.Verify(ctx, rawIDToken)
+	idToken, err := d.verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		h.log.Error("OIDC: failed to verify ID token: %v", err)
+		h.auditLogin(r, nil, "", "failed to verify ID token")
 		http.Error(w, "Failed to verify ID token", http.StatusInternalServerError)
 		return
 	}
@@ -177,8 +269,8 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch UserInfo - some oidc sets role/groups here
-	tokenSource := h.oauth2Config.TokenSource(ctx, oauth2Token)
-	userInfo, err := h.provider.UserInfo(ctx, tokenSource)
+	tokenSource := d.oauth2Config.TokenSource(ctx, oauth2Token)
+	userInfo, err := d.provider.UserInfo(ctx, tokenSource)
 	if err == nil {
 		var uiClaims map[string]any
 		if err := userInfo.Claims(&uiClaims); err == nil {
@@ -195,7 +287,7 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 		extra, err := h.fetchExtraClaims(ctx, oauth2Token.AccessToken)
 		if err != nil {
 			h.log.Error("OIDC: extra claims request failed (%s): %v", h.config.ExtraClaimsURL, err)
-			http.Redirect(w, r, "/login?error=membership_check_failed", http.StatusFound)
+			http.Redirect(w, r, h.basePath+"/login?error=membership_check_failed", http.StatusFound)
 			return
 		}
 		maps.Copy(claims, extra)
@@ -205,15 +297,16 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	if h.config.RequiredClaim != "" && len(h.config.RequiredValues) > 0 {
 		if !h.checkRequiredClaim(claims) {
 			h.log.Warn("OIDC: login rejected — required claim %q not satisfied", h.config.RequiredClaim)
-			http.Redirect(w, r, "/login?error=access_denied", http.StatusFound)
+			h.auditLogin(r, nil, idToken.Subject, "required claim not satisfied")
+			http.Redirect(w, r, h.basePath+"/login?error=access_denied", http.StatusFound)
 			return
 		}
 	}
 
 	// Extract user info from claims
 	sub := idToken.Subject
-	email, _ := claims["email"].(string)
-	username, _ := claims["preferred_username"].(string)
+	email := claimString(claims, h.config.EmailClaim, "email")
+	username := claimString(claims, h.config.UsernameClaim, "preferred_username")
 	if username == "" {
 		username, _ = claims["name"].(string)
 	}
@@ -224,24 +317,43 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 		username = sub
 	}
 
+	// A signed in user linking this identity to their account rather than signing in
+	if linkID != "" {
+		h.completeLink(w, r, linkID, sub, username)
+		return
+	}
+
 	// Resolve roles before creating user to avoid orphaned records on rejection
 	resolvedRoles := h.resolveClaimRoles(claims)
 	if len(resolvedRoles) == 0 && h.config.RejectUnmapped {
 		h.log.Warn("OIDC: login rejected — no mapped roles for user %s", username)
-		http.Redirect(w, r, "/login?error=no_mapped_roles", http.StatusFound)
+		h.auditLogin(r, nil, username, "no mapped roles")
+		http.Redirect(w, r, h.basePath+"/login?error=no_mapped_roles", http.StatusFound)
 		return
 	}
 
 	user, err := h.findOrCreateOIDCUser(ctx, sub, username, email)
 	if err != nil {
 		h.log.Error("OIDC: failed to find or create user (sub=%s, username=%s): %v", sub, username, err)
+		h.auditLogin(r, nil, username, "failed to find or create user")
 		http.Error(w, "Failed to authenticate user", http.StatusInternalServerError)
 		return
 	}
 
-	// Assign resolved roles to user
-	for _, roleName := range resolvedRoles {
-		_ = h.store.AssignRole(ctx, user.ID, roleName, "oidc")
+	// A linked local account keeps the roles it was given here and signs in with its
+	// own second factor, the provider login can't stand in for it
+	if user.AuthProvider == "local" {
+		if enabled, _ := h.manager.TOTPStatus(ctx, user.ID); enabled {
+			h.log.Warn("OIDC: login rejected — user %s has two-factor authentication enabled", user.Username)
+			h.auditLogin(r, user, user.Username, "two-factor authentication required")
+			http.Redirect(w, r, h.basePath+"/login?error=two_factor_required", http.StatusFound)
+			return
+		}
+	} else {
+		// Assign resolved roles to user
+		for _, roleName := range resolvedRoles {
+			_ = h.store.AssignRole(ctx, user.ID, roleName, "oidc")
+		}
 	}
 
 	// Get user roles
@@ -260,22 +372,18 @@ func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create session
-	session := &db.Session{
-		ID:        uuid.New().String(),
-		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: expiresAt,
-	}
-	if err := h.store.CreateSession(ctx, session); err != nil {
+	ctx = WithClientInfo(ctx, ClientInfo{IP: audit.ClientIP(r.RemoteAddr), UserAgent: r.UserAgent()})
+	if _, err := h.manager.createSession(ctx, user.ID, token, expiresAt, rawIDToken); err != nil {
 		h.log.Error("OIDC: failed to create session: %v", err)
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
 
 	h.log.Info("OIDC: user %s authenticated successfully", user.Username)
+	h.auditLogin(r, user, user.Username, "")
 
 	// Redirect to frontend with token in query param
-	http.Redirect(w, r, fmt.Sprintf("/login?token=%s", token), http.StatusFound)
+	http.Redirect(w, r, fmt.Sprintf("%s/login?token=%s", h.basePath, token), http.StatusFound)
 }
 
 // findOrCreateOIDCUser looks up a user by OIDC subject (returning user),
@@ -452,6 +560,15 @@ func gjsonToAny(r gjson.Result) any {
 	return r.Value()
 }
 
+// Reads a string claim by its configured name, or fallback when none is configured
+func claimString(claims map[string]any, name, fallback string) string {
+	if name == "" {
+		name = fallback
+	}
+	value, _ := claims[name].(string)
+	return value
+}
+
 // Returns true if the claims contain the required claim == value match
 func (h *OIDCHandler) checkRequiredClaim(claims map[string]any) bool {
 	value, ok := claims[h.config.RequiredClaim]
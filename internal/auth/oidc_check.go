@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Severity of an OIDCCheck
+type OIDCCheckLevel int
+
+const (
+	OIDCCheckOK OIDCCheckLevel = iota
+	OIDCCheckWarning
+	OIDCCheckError
+)
+
+// OIDCCheck is the outcome of one configuration check
+type OIDCCheck struct {
+	Name    string
+	Level   OIDCCheckLevel
+	Message string
+}
+
+// CheckConfig validates the OIDC configuration against the provider so problems show up
+// before a user hits them mid-login. A successful discovery also refreshes the cache.
+func (h *OIDCHandler) CheckConfig(ctx context.Context) []OIDCCheck {
+	var checks []OIDCCheck
+	add := func(name string, level OIDCCheckLevel, format string, args ...any) {
+		checks = append(checks, OIDCCheck{Name: name, Level: level, Message: fmt.Sprintf(format, args...)})
+	}
+
+	issuerOK := false
+	issuer, err := url.Parse(h.config.IssuerURI)
+	switch {
+	case h.config.IssuerURI == "":
+		add("issuer_uri", OIDCCheckError, "issuer_uri is not set")
+	case err != nil || issuer.Host == "" || (issuer.Scheme != "https" && issuer.Scheme != "http"):
+		add("issuer_uri", OIDCCheckError, "%q is not an http(s) URL", h.config.IssuerURI)
+	case strings.HasSuffix(strings.TrimSuffix(issuer.Path, "/"), "/.well-known/openid-configuration"):
+		add("issuer_uri", OIDCCheckError, "issuer_uri must be the issuer itself, drop the /.well-known/openid-configuration suffix")
+	case issuer.Scheme == "http" && !isLoopbackHost(issuer.Hostname()):
+		issuerOK = true
+		add("issuer_uri", OIDCCheckWarning, "issuer is not served over HTTPS")
+	default:
+		issuerOK = true
+		add("issuer_uri", OIDCCheckOK, "%s", h.config.IssuerURI)
+	}
+
+	var d *oidcDiscovery
+	if issuerOK {
+		if d, err = h.discover(ctx); err != nil {
+			add("discovery", OIDCCheckError, "%v", err)
+		} else {
+			h.discoveryMu.Lock()
+			h.storeDiscovery(d)
+			h.discoveryMu.Unlock()
+			add("discovery", OIDCCheckOK, "Reached %s/.well-known/openid-configuration", strings.TrimSuffix(h.config.IssuerURI, "/"))
+		}
+	}
+
+	h.checkScopes(d, add)
+
+	clientIDOK := false
+	switch {
+	case h.config.ClientID == "":
+		add("client_id", OIDCCheckError, "client_id is not set")
+	case strings.TrimSpace(h.config.ClientID) != h.config.ClientID:
+		add("client_id", OIDCCheckError, "client_id has leading or trailing whitespace")
+	default:
+		clientIDOK = true
+		add("client_id", OIDCCheckOK, "%s", h.config.ClientID)
+	}
+
+	switch {
+	case h.config.ClientSecret == "":
+		add("client_secret", OIDCCheckWarning, "client_secret is empty, only public clients can log in without one")
+	case strings.TrimSpace(h.config.ClientSecret) != h.config.ClientSecret:
+		add("client_secret", OIDCCheckError, "client_secret has leading or trailing whitespace")
+	default:
+		add("client_secret", OIDCCheckOK, "Set")
+	}
+
+	callbackPath := h.basePath + "/api/v1/auth/oidc/callback"
+	redirect, err := url.Parse(h.config.RedirectURL)
+	switch {
+	case h.config.RedirectURL == "":
+		add("redirect_url", OIDCCheckError, "redirect_url is not set")
+	case err != nil || redirect.Host == "" || (redirect.Scheme != "https" && redirect.Scheme != "http"):
+		add("redirect_url", OIDCCheckError, "%q is not an absolute http(s) URL", h.config.RedirectURL)
+	case strings.TrimSuffix(redirect.Path, "/") != callbackPath:
+		add("redirect_url", OIDCCheckError, "redirect_url path is %q, DiscoPanel handles the callback at %s", redirect.Path, callbackPath)
+	case redirect.Scheme == "http" && !isLoopbackHost(redirect.Hostname()):
+		add("redirect_url", OIDCCheckWarning, "redirect_url is not HTTPS, most providers reject plain HTTP redirects outside localhost")
+	default:
+		add("redirect_url", OIDCCheckOK, "%s", h.config.RedirectURL)
+	}
+
+	if d != nil && clientIDOK {
+		h.checkClientCredentials(ctx, d, add)
+	}
+
+	if len(h.config.RoleMapping) > 0 && h.config.RoleClaim == "" {
+		add("role_claim", OIDCCheckWarning, "role_mapping is set but role_claim is empty, so the mapping is never applied")
+	}
+
+	return checks
+}
+
+// Checks the configured scopes, and against the provider's scopes_supported when it lists them
+func (h *OIDCHandler) checkScopes(d *oidcDiscovery, add func(string, OIDCCheckLevel, string, ...any)) {
+	scopes := h.config.Scopes
+	if len(scopes) == 0 {
+		add("scopes", OIDCCheckOK, "Using the default scopes openid, profile and email")
+		return
+	}
+	if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		add("scopes", OIDCCheckError, "scopes must include openid, without it the provider returns no ID token")
+		return
+	}
+
+	var supported struct {
+		ScopesSupported []string `json:"scopes_supported"`
+	}
+	if d != nil && d.document != nil {
+		_ = json.Unmarshal(d.document, &supported)
+	}
+	var unknown []string
+	if len(supported.ScopesSupported) > 0 {
+		for _, scope := range scopes {
+			if !slices.Contains(supported.ScopesSupported, scope) {
+				unknown = append(unknown, scope)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		add("scopes", OIDCCheckWarning, "The provider doesn't advertise %s", strings.Join(unknown, ", "))
+		return
+	}
+	add("scopes", OIDCCheckOK, "%s", strings.Join(scopes, ", "))
+}
+
+// Redeems a made up authorization code. Providers check the client before the code, so an
+// invalid_grant answer means the client ID and secret were accepted.
+func (h *OIDCHandler) checkClientCredentials(ctx context.Context, d *oidcDiscovery, add func(string, OIDCCheckLevel, string, ...any)) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	if h.httpClient != nil {
+		ctx = oidc.ClientContext(ctx, h.httpClient)
+	}
+
+	_, err := d.oauth2Config.Exchange(ctx, "discopanel-config-check")
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case err == nil:
+		add("client_credentials", OIDCCheckWarning, "The provider accepted a made up authorization code")
+	case errors.As(err, &retrieveErr):
+		switch retrieveErr.ErrorCode {
+		case "invalid_grant":
+			add("client_credentials", OIDCCheckOK, "The provider accepted the client ID and secret")
+		case "invalid_client", "unauthorized_client":
+			add("client_credentials", OIDCCheckError, "The provider rejected the client ID or secret: %s", retrieveErr.ErrorCode)
+		default:
+			code := retrieveErr.ErrorCode
+			if code == "" && retrieveErr.Response != nil {
+				code = retrieveErr.Response.Status
+			}
+			add("client_credentials", OIDCCheckWarning, "Couldn't confirm the client credentials, the token endpoint answered %s", code)
+		}
+	default:
+		add("client_credentials", OIDCCheckError, "Failed to reach the token endpoint: %v", err)
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
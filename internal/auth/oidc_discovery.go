@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Bounds a single discovery request so a slow provider can't stall a login
+	discoveryTimeout = 10 * time.Second
+	// Minimum spacing between discovery attempts while nothing is cached
+	discoveryRetry = 30 * time.Second
+)
+
+var errProviderUnavailable = errors.New("OIDC provider discovery has not succeeded yet")
+
+// Discovered provider metadata and the clients built from it
+type oidcDiscovery struct {
+	provider     *oidc.Provider
+	verifier     *oidc.IDTokenVerifier
+	oauth2Config *oauth2.Config
+	endSession   string // end_session_endpoint, empty when the provider has none
+	document     json.RawMessage
+}
+
+// Fetches the provider's discovery document and builds the clients from it
+func (h *OIDCHandler) discover(ctx context.Context) (*oidcDiscovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	if h.httpClient != nil {
+		ctx = oidc.ClientContext(ctx, h.httpClient)
+	}
+
+	provider, err := oidc.NewProvider(ctx, h.config.IssuerURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
+	}
+
+	scopes := h.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	d := &oidcDiscovery{
+		provider: provider,
+		verifier: provider.Verifier(&oidc.Config{ClientID: h.config.ClientID}),
+		oauth2Config: &oauth2.Config{
+			ClientID:     h.config.ClientID,
+			ClientSecret: h.config.ClientSecret,
+			RedirectURL:  h.config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+	}
+
+	// Optional discovery field, only providers supporting RP-initiated logout have it
+	var extra struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&extra); err != nil {
+		h.log.Debug("OIDC: failed to read discovery document: %v", err)
+	}
+	d.endSession = extra.EndSessionEndpoint
+	_ = provider.Claims(&d.document)
+	return d, nil
+}
+
+// Returns the cached discovery, running it now when nothing is cached yet (e.g. the
+// provider was down at startup). Attempts are spaced out so logins can't hammer it.
+func (h *OIDCHandler) discovery(ctx context.Context) (*oidcDiscovery, error) {
+	if d := h.cachedDiscovery(); d != nil {
+		return d, nil
+	}
+
+	h.discoveryMu.Lock()
+	defer h.discoveryMu.Unlock()
+	if h.cached != nil {
+		return h.cached, nil
+	}
+	if time.Since(h.lastAttempt) < discoveryRetry {
+		return nil, errProviderUnavailable
+	}
+	h.lastAttempt = time.Now()
+
+	d, err := h.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.storeDiscovery(d)
+	return d, nil
+}
+
+func (h *OIDCHandler) cachedDiscovery() *oidcDiscovery {
+	h.discoveryMu.RLock()
+	defer h.discoveryMu.RUnlock()
+	return h.cached
+}
+
+// Caches a discovery. An unchanged document keeps the current verifier and the
+// signing keys it has already fetched.
+func (h *OIDCHandler) storeDiscovery(d *oidcDiscovery) {
+	if h.cached != nil && d.document != nil && bytes.Equal(h.cached.document, d.document) {
+		return
+	}
+	if h.config.LogoutFromProvider && d.endSession == "" {
+		h.log.Warn("OIDC: logout_from_provider is set but the provider has no end_session_endpoint")
+	}
+	h.cached = d
+}
+
+// Re-runs discovery every discovery_ttl, keeping the cached configuration whenever the
+// provider can't be reached so logins keep working through an outage
+func (h *OIDCHandler) refreshDiscovery(ttl time.Duration) {
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		d, err := h.discover(context.Background())
+		if err != nil {
+			h.log.Warn("OIDC: failed to refresh provider discovery, keeping the cached configuration: %v", err)
+			continue
+		}
+		h.discoveryMu.Lock()
+		h.storeDiscovery(d)
+		h.discoveryMu.Unlock()
+	}
+}
+
+// Close stops the background discovery refresh
+func (h *OIDCHandler) Close() {
+	if h.stop != nil {
+		h.stopOnce.Do(func() { close(h.stop) })
+	}
+}
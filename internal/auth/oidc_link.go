@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/audit"
+	"github.com/nickheyer/discopanel/internal/db"
+)
+
+var (
+	ErrOIDCLinkLocalOnly = errors.New("only local accounts can link an OIDC identity")
+	ErrOIDCAlreadyLinked = errors.New("account already has a linked OIDC identity")
+	ErrOIDCNotLinked     = errors.New("account has no linked OIDC identity")
+	ErrOIDCOnlyLogin     = errors.New("account signs in only through OIDC, its identity can't be unlinked")
+	ErrOIDCLinkTOTP      = errors.New("accounts with two-factor authentication can't link an OIDC identity")
+)
+
+// How long a link started from the profile page stays usable, the same as the state cookie
+const oidcLinkTTL = 5 * time.Minute
+
+// Links started by signed in users, keyed by a single use ID
+type oidcLinks struct {
+	mu    sync.Mutex
+	byID  map[string]oidcLink
+	sweep time.Time
+}
+
+type oidcLink struct {
+	userID    string
+	expiresAt time.Time
+}
+
+func (l *oidcLinks) start(userID string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.byID == nil {
+		l.byID = make(map[string]oidcLink)
+	}
+	if now.Sub(l.sweep) > oidcLinkTTL {
+		for id, pending := range l.byID {
+			if now.After(pending.expiresAt) {
+				delete(l.byID, id)
+			}
+		}
+		l.sweep = now
+	}
+
+	id := uuid.New().String()
+	l.byID[id] = oidcLink{userID: userID, expiresAt: now.Add(oidcLinkTTL)}
+	return id
+}
+
+// Removes a link and returns the user who started it, empty when unknown or expired
+func (l *oidcLinks) finish(id string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pending, ok := l.byID[id]
+	delete(l.byID, id)
+	if !ok || time.Now().After(pending.expiresAt) {
+		return ""
+	}
+	return pending.userID
+}
+
+// BeginLink starts linking an OIDC identity to a local account. It returns the login URL
+// to send the user to and the Set-Cookie value carrying the link, which the caller's own
+// authenticated response must set. The link never travels in a URL, so a crafted login
+// link can't tie someone else's provider login to an account. The login then runs as
+// usual and its identity is linked instead of signed in.
+func (h *OIDCHandler) BeginLink(ctx context.Context, userID string) (string, string, error) {
+	user, err := h.store.GetUser(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.AuthProvider != "local" {
+		return "", "", ErrOIDCLinkLocalOnly
+	}
+	if user.OIDCSubject != "" {
+		return "", "", ErrOIDCAlreadyLinked
+	}
+	// The provider login would skip the second factor
+	if enabled, _ := h.manager.TOTPStatus(ctx, user.ID); enabled {
+		return "", "", ErrOIDCLinkTOTP
+	}
+
+	cookie := h.manager.CookieHeader(ctx, &http.Cookie{
+		Name:     "oidc_link",
+		Value:    h.links.start(userID),
+		Path:     h.basePath + "/",
+		MaxAge:   int(oidcLinkTTL.Seconds()),
+		HttpOnly: true,
+	})
+	return h.LoginURL() + "?link=1", cookie, nil
+}
+
+// Finishes a link from the callback, the identity must not belong to another account
+func (h *OIDCHandler) completeLink(w http.ResponseWriter, r *http.Request, linkID, sub, username string) {
+	userID := h.links.finish(linkID)
+	if userID == "" {
+		http.Redirect(w, r, h.basePath+"/profile?link_error=expired", http.StatusFound)
+		return
+	}
+
+	ctx := r.Context()
+	if existing, err := h.store.GetUserByOIDCSubject(ctx, sub); err == nil && existing.ID != userID {
+		h.log.Warn("OIDC: refused to link %s, the identity belongs to user %s", username, existing.Username)
+		http.Redirect(w, r, h.basePath+"/profile?link_error=in_use", http.StatusFound)
+		return
+	}
+
+	user, err := h.store.GetUser(ctx, userID)
+	if err != nil || user.AuthProvider != "local" {
+		http.Redirect(w, r, h.basePath+"/profile?link_error=failed", http.StatusFound)
+		return
+	}
+	if enabled, _ := h.manager.TOTPStatus(ctx, user.ID); enabled {
+		http.Redirect(w, r, h.basePath+"/profile?link_error=two_factor", http.StatusFound)
+		return
+	}
+	user.OIDCSubject = sub
+	user.OIDCIssuer = h.config.IssuerURI
+	if err := h.store.UpdateUser(ctx, user); err != nil {
+		h.log.Error("OIDC: failed to link identity to user %s: %v", user.Username, err)
+		http.Redirect(w, r, h.basePath+"/profile?link_error=failed", http.StatusFound)
+		return
+	}
+
+	h.log.Info("OIDC: linked identity %s to user %s", username, user.Username)
+	h.audit.Record(&db.AuditLog{
+		Action:     "oidc/link",
+		UserID:     user.ID,
+		Username:   user.Username,
+		TargetType: "users",
+		TargetID:   user.ID,
+		IP:         audit.ClientIP(r.RemoteAddr),
+		Success:    true,
+	})
+	http.Redirect(w, r, h.basePath+"/profile?linked=1", http.StatusFound)
+}
+
+// UnlinkIdentity removes a local account's linked OIDC identity
+func (h *OIDCHandler) UnlinkIdentity(ctx context.Context, userID string) error {
+	user, err := h.store.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.AuthProvider != "local" {
+		return ErrOIDCOnlyLogin
+	}
+	if user.OIDCSubject == "" {
+		return ErrOIDCNotLinked
+	}
+	user.OIDCSubject = ""
+	user.OIDCIssuer = ""
+	return h.store.UpdateUser(ctx, user)
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/webauthn"
+)
+
+var (
+	ErrPasskeysDisabled = errors.New("passkey sign-in is disabled")
+	ErrPasskeyCeremony  = errors.New("passkey request expired or not found")
+)
+
+// How long the browser has to answer a registration or login challenge
+const passkeyCeremonyTTL = 5 * time.Minute
+
+// PasskeyOptions is what the browser needs to call navigator.credentials
+type PasskeyOptions struct {
+	CeremonyID       string
+	Challenge        []byte
+	RPID             string
+	RPName           string
+	UserHandle       []byte   // Registration only
+	CredentialIDs    [][]byte // Registration: exclude list, login: allow list
+	UserVerification string   // "required" or "preferred"
+	Timeout          time.Duration
+}
+
+type passkeyCeremony struct {
+	challenge []byte
+	userID    string          // Registering user
+	allowed   map[string]bool // Credential IDs a login was started for, nil allows any
+	rp        *webauthn.RelyingParty
+	expiresAt time.Time
+}
+
+// Outstanding challenges, each usable once
+type passkeyCeremonies struct {
+	mu    sync.Mutex
+	byID  map[string]*passkeyCeremony
+	sweep time.Time
+}
+
+func newPasskeyCeremonies() *passkeyCeremonies {
+	return &passkeyCeremonies{byID: make(map[string]*passkeyCeremony), sweep: time.Now()}
+}
+
+func (c *passkeyCeremonies) start(ceremony *passkeyCeremony) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.sweep) > passkeyCeremonyTTL {
+		for id, pending := range c.byID {
+			if now.After(pending.expiresAt) {
+				delete(c.byID, id)
+			}
+		}
+		c.sweep = now
+	}
+
+	id := uuid.New().String()
+	ceremony.expiresAt = now.Add(passkeyCeremonyTTL)
+	c.byID[id] = ceremony
+	return id
+}
+
+// Removes and returns a ceremony, nil when unknown or expired
+func (c *passkeyCeremonies) finish(id string) *passkeyCeremony {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ceremony, ok := c.byID[id]
+	delete(c.byID, id)
+	if !ok || time.Now().After(ceremony.expiresAt) {
+		return nil
+	}
+	return ceremony
+}
+
+// IsPasskeyEnabled reports whether users can register and sign in with passkeys
+func (m *Manager) IsPasskeyEnabled() bool {
+	return m.config.WebAuthn.Enabled
+}
+
+// Resolves the relying party for a request from the UI at origin. Configured values win,
+// otherwise passkeys are bound to the host the UI is served from.
+func (m *Manager) relyingParty(origin string) (*webauthn.RelyingParty, error) {
+	cfg := m.config.WebAuthn
+	if !cfg.Enabled {
+		return nil, ErrPasskeysDisabled
+	}
+
+	rp := &webauthn.RelyingParty{
+		ID:                      cfg.RPID,
+		Name:                    cfg.RPName,
+		Origins:                 cfg.Origins,
+		RequireUserVerification: cfg.RequireUserVerification,
+	}
+	if rp.Name == "" {
+		rp.Name = "DiscoPanel"
+	}
+	if rp.ID == "" || len(rp.Origins) == 0 {
+		u, err := url.Parse(origin)
+		if err != nil || u.Hostname() == "" {
+			return nil, errors.New("request origin is required for passkeys")
+		}
+		if rp.ID == "" {
+			rp.ID = u.Hostname()
+		}
+		if len(rp.Origins) == 0 {
+			rp.Origins = []string{strings.TrimSuffix(origin, "/")}
+		}
+	}
+	return rp, nil
+}
+
+func (m *Manager) passkeyOptions(rp *webauthn.RelyingParty, ceremony *passkeyCeremony) (*PasskeyOptions, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	opts := &PasskeyOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		RPName:           rp.Name,
+		UserVerification: "preferred",
+		Timeout:          passkeyCeremonyTTL,
+	}
+	if rp.RequireUserVerification {
+		opts.UserVerification = "required"
+	}
+	ceremony.challenge = challenge
+	ceremony.rp = rp
+	opts.CeremonyID = m.passkeys.start(ceremony)
+	return opts, nil
+}
+
+// BeginPasskeyRegistration starts adding a passkey to the user's account
+func (m *Manager) BeginPasskeyRegistration(ctx context.Context, userID, origin string) (*PasskeyOptions, error) {
+	rp, err := m.relyingParty(origin)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := m.store.ListPasskeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := m.passkeyOptions(rp, &passkeyCeremony{userID: userID})
+	if err != nil {
+		return nil, err
+	}
+	opts.UserHandle = []byte(userID)
+	for _, passkey := range existing {
+		if id, err := base64.RawURLEncoding.DecodeString(passkey.CredentialID); err == nil {
+			opts.CredentialIDs = append(opts.CredentialIDs, id)
+		}
+	}
+	return opts, nil
+}
+
+// FinishPasskeyRegistration verifies the authenticator's response and stores the passkey
+func (m *Manager) FinishPasskeyRegistration(ctx context.Context, userID, ceremonyID, name string, clientDataJSON, attestationObject []byte) (*db.Passkey, error) {
+	ceremony := m.passkeys.finish(ceremonyID)
+	if ceremony == nil || ceremony.userID == "" || ceremony.userID != userID {
+		return nil, ErrPasskeyCeremony
+	}
+
+	cred, err := ceremony.rp.VerifyRegistration(ceremony.challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("passkey registration failed: %w", err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(cred.ID)
+	if _, err := m.store.GetPasskeyByCredentialID(ctx, credentialID); err == nil {
+		return nil, errors.New("passkey is already registered")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey := &db.Passkey{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+		AAGUID:       hex.EncodeToString(cred.AAGUID),
+	}
+	if err := m.store.CreatePasskey(ctx, passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// BeginPasskeyLogin starts a passkey sign-in. With a username the browser is offered that
+// user's passkeys, without one it lets the user pick any passkey saved for this site.
+func (m *Manager) BeginPasskeyLogin(ctx context.Context, username, origin string) (*PasskeyOptions, error) {
+	rp, err := m.relyingParty(origin)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown usernames get an empty allow list rather than an error, so the
+	// response doesn't reveal which accounts exist
+	ceremony := &passkeyCeremony{}
+	var credentialIDs [][]byte
+	if username != "" {
+		ceremony.allowed = make(map[string]bool)
+		users, err := m.store.ListUsersByUsername(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			passkeys, err := m.store.ListPasskeysByUser(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, passkey := range passkeys {
+				if id, err := base64.RawURLEncoding.DecodeString(passkey.CredentialID); err == nil {
+					credentialIDs = append(credentialIDs, id)
+					ceremony.allowed[passkey.CredentialID] = true
+				}
+			}
+		}
+	}
+
+	opts, err := m.passkeyOptions(rp, ceremony)
+	if err != nil {
+		return nil, err
+	}
+	opts.CredentialIDs = credentialIDs
+	return opts, nil
+}
+
+// FinishPasskeyLogin verifies a passkey assertion and issues a session like Login
+func (m *Manager) FinishPasskeyLogin(ctx context.Context, ceremonyID string, credentialID, clientDataJSON, authenticatorData, signature []byte) (*db.User, []string, string, time.Time, error) {
+	clientIP := GetClientInfo(ctx).IP
+	ipKey := "ip:" + clientIP
+	if err := m.throttle.check(ipKey); err != nil {
+		return nil, nil, "", time.Time{}, err
+	}
+	failed := func(err error) (*db.User, []string, string, time.Time, error) {
+		if clientIP != "" {
+			m.throttle.fail(ipKey, m.config.Throttle.MaxAttemptsPerIP)
+		}
+		return nil, nil, "", time.Time{}, err
+	}
+
+	ceremony := m.passkeys.finish(ceremonyID)
+	if ceremony == nil {
+		return nil, nil, "", time.Time{}, ErrPasskeyCeremony
+	}
+
+	passkey, err := m.store.GetPasskeyByCredentialID(ctx, base64.RawURLEncoding.EncodeToString(credentialID))
+	if err != nil {
+		return failed(ErrInvalidCredentials)
+	}
+	if ceremony.userID != "" || (ceremony.allowed != nil && !ceremony.allowed[passkey.CredentialID]) {
+		return failed(ErrInvalidCredentials)
+	}
+
+	signCount, err := ceremony.rp.VerifyAssertion(ceremony.challenge, &webauthn.Credential{
+		ID:        credentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	}, clientDataJSON, authenticatorData, signature)
+	if err != nil {
+		return failed(fmt.Errorf("%w: %v", ErrInvalidCredentials, err))
+	}
+
+	user, err := m.store.GetUser(ctx, passkey.UserID)
+	if err != nil {
+		return failed(ErrInvalidCredentials)
+	}
+	if !user.IsActive {
+		return failed(ErrUserNotActive)
+	}
+	m.throttle.reset(ipKey)
+
+	if err := m.store.UpdatePasskeyUsage(ctx, passkey.ID, signCount); err != nil {
+		return nil, nil, "", time.Time{}, err
+	}
+
+	roleNames, token, expiresAt, err := m.issueSession(ctx, user)
+	if err != nil {
+		return nil, nil, "", time.Time{}, err
+	}
+	return user, roleNames, token, expiresAt, nil
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nickheyer/discopanel/internal/config"
+)
+
+// ErrTooManyAttempts matches any ThrottleError
+var ErrTooManyAttempts = errors.New("too many attempts")
+
+// ThrottleError is returned while a username or client IP is locked out
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("too many attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottleError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// How often stale attempt records are swept
+const throttleSweepInterval = 5 * time.Minute
+
+// Failed attempts for a single key
+type attemptRecord struct {
+	failures    int
+	windowStart time.Time
+	lastFailure time.Time
+	lockouts    int
+	lockedUntil time.Time
+}
+
+// Counts failed attempts per key in memory and locks keys out with exponential backoff
+type throttle struct {
+	mu        sync.Mutex
+	records   map[string]*attemptRecord
+	cfg       config.ThrottleConfig
+	lastSweep time.Time
+}
+
+func newThrottle(cfg config.ThrottleConfig) *throttle {
+	return &throttle{
+		records:   make(map[string]*attemptRecord),
+		cfg:       cfg,
+		lastSweep: time.Now(),
+	}
+}
+
+// Returns a ThrottleError when any of the keys is currently locked out
+func (t *throttle) check(keys ...string) error {
+	if !t.cfg.Enabled {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.sweep(now)
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		if rec, ok := t.records[key]; ok && rec.lockedUntil.After(now) {
+			retryAfter = max(retryAfter, rec.lockedUntil.Sub(now))
+		}
+	}
+	if retryAfter > 0 {
+		return &ThrottleError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Records a failed attempt against a key, locking it once its limit is reached
+func (t *throttle) fail(key string, limit int) {
+	if !t.cfg.Enabled || limit <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	window := time.Duration(t.cfg.Window) * time.Second
+	rec, ok := t.records[key]
+	if !ok {
+		rec = &attemptRecord{windowStart: now}
+		t.records[key] = rec
+	}
+	if now.Sub(rec.windowStart) > window {
+		rec.failures = 0
+		rec.windowStart = now
+	}
+	rec.failures++
+	rec.lastFailure = now
+
+	if rec.failures >= limit {
+		rec.lockouts++
+		rec.lockedUntil = now.Add(t.lockoutDuration(rec.lockouts))
+		rec.failures = 0
+		rec.windowStart = now
+	}
+}
+
+// Clears the record for each key after a successful attempt
+func (t *throttle) reset(keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		delete(t.records, key)
+	}
+}
+
+// Doubles the base lockout for every consecutive lockout, capped at the configured maximum
+func (t *throttle) lockoutDuration(lockouts int) time.Duration {
+	base := time.Duration(t.cfg.Lockout) * time.Second
+	maxLockout := time.Duration(t.cfg.MaxLockout) * time.Second
+	d := base
+	for i := 1; i < lockouts && (maxLockout <= 0 || d < maxLockout); i++ {
+		d *= 2
+	}
+	if maxLockout > 0 && d > maxLockout {
+		d = maxLockout
+	}
+	return d
+}
+
+// Drops records that are no longer locked and have been quiet for a full window
+// NOTE: Caller must hold t.mu
+func (t *throttle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < throttleSweepInterval {
+		return
+	}
+	t.lastSweep = now
+	window := time.Duration(t.cfg.Window) * time.Second
+	for key, rec := range t.records {
+		if rec.lockedUntil.Before(now) && now.Sub(rec.lastFailure) > window {
+			delete(t.records, key)
+		}
+	}
+}
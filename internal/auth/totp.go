@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nickheyer/discopanel/internal/config"
+	"github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/qrcode"
+	"github.com/nickheyer/discopanel/pkg/totp"
+)
+
+var (
+	ErrTOTPRequired       = errors.New("totp_required")
+	ErrInvalidTOTP        = errors.New("invalid two-factor code")
+	ErrTOTPDisabled       = errors.New("two-factor authentication is disabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPLocalOnly      = errors.New("two-factor authentication is only available for local accounts")
+)
+
+const (
+	totpKeySettingKey     = "totp_encryption_key"
+	totpRecoveryCodeCount = 10
+)
+
+// TOTPEnrollment is what a user needs to add the account to an authenticator app
+type TOTPEnrollment struct {
+	Secret string // Base32, for manual entry
+	URI    string // otpauth:// provisioning URI
+	QRCode []byte // PNG of URI
+}
+
+// Priority: config value → DB-stored value → generate + persist to DB
+func loadTOTPKey(ctx context.Context, store *db.Store, cfg *config.AuthConfig) ([]byte, error) {
+	if cfg.TOTP.EncryptionKey != "" {
+		key := sha256.Sum256([]byte(cfg.TOTP.EncryptionKey))
+		return key[:], nil
+	}
+
+	stored, err := store.GetSystemSetting(ctx, totpKeySettingKey)
+	if err == nil && stored != "" {
+		key, err := hex.DecodeString(stored)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("failed to decode stored TOTP encryption key")
+		}
+		return key, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP encryption key: %w", err)
+	}
+	if err := store.SetSystemSetting(ctx, totpKeySettingKey, hex.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to persist TOTP encryption key: %w", err)
+	}
+	return key, nil
+}
+
+// IsTOTPEnabled reports whether users can enroll an authenticator app
+func (m *Manager) IsTOTPEnabled() bool {
+	return m.config.TOTP.Enabled && m.config.Local.Enabled
+}
+
+func (m *Manager) encryptTOTPSecret(secret string) (string, error) {
+	block, err := aes.NewCipher(m.totpKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (m *Manager) decryptTOTPSecret(stored string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(m.totpKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("stored totp secret is too short")
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt totp secret, was the encryption key changed? %w", err)
+	}
+	return string(secret), nil
+}
+
+// BeginTOTPEnrollment generates a new secret for the user. It only takes effect once
+// ConfirmTOTPEnrollment verifies a code from it, until then it can be restarted.
+func (m *Manager) BeginTOTPEnrollment(ctx context.Context, user *db.User) (*TOTPEnrollment, error) {
+	if !m.IsTOTPEnabled() {
+		return nil, ErrTOTPDisabled
+	}
+	if user.AuthProvider != "local" {
+		return nil, ErrTOTPLocalOnly
+	}
+	if existing, err := m.store.GetUserTOTP(ctx, user.ID); err == nil && existing.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := m.encryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.SaveUserTOTP(ctx, &db.UserTOTP{UserID: user.ID, Secret: encrypted}); err != nil {
+		return nil, err
+	}
+
+	issuer := m.config.TOTP.Issuer
+	if issuer == "" {
+		issuer = "DiscoPanel"
+	}
+	enrollment := &TOTPEnrollment{Secret: secret, URI: totp.URI(issuer, user.Username, secret)}
+	code, err := qrcode.Encode(enrollment.URI)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.QRCode, err = code.PNG(6); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmTOTPEnrollment turns on two-factor once the user proves their app produces
+// valid codes, returning the recovery codes to show them once
+func (m *Manager) ConfirmTOTPEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	enrollment, err := m.store.GetUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if enrollment.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if err := m.verifyTOTPCode(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	enrollment, err = m.store.GetUserTOTP(ctx, userID) // Reload, verifying consumed a step
+	if err != nil {
+		return nil, err
+	}
+	enrollment.Enabled = true
+	enrollment.EnabledAt = &now
+	enrollment.RecoveryCodes = hashes
+	if err := m.store.SaveUserTOTP(ctx, enrollment); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateTOTPRecoveryCodes replaces the user's recovery codes, code must be a current TOTP code
+func (m *Manager) RegenerateTOTPRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	enrollment, err := m.store.GetUserTOTP(ctx, userID)
+	if err != nil || !enrollment.Enabled {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err := m.verifyTOTPCode(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enrollment, err = m.store.GetUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	enrollment.RecoveryCodes = hashes
+	if err := m.store.SaveUserTOTP(ctx, enrollment); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the user's second factor, including a pending enrollment
+func (m *Manager) DisableTOTP(ctx context.Context, userID string) error {
+	return m.store.DeleteUserTOTP(ctx, userID)
+}
+
+// TOTPStatus reports whether the user has two-factor on and how many recovery codes are left
+func (m *Manager) TOTPStatus(ctx context.Context, userID string) (bool, int) {
+	enrollment, err := m.store.GetUserTOTP(ctx, userID)
+	if err != nil || !enrollment.Enabled {
+		return false, 0
+	}
+	return true, len(enrollment.RecoveryCodes)
+}
+
+// Second step of a password login. Users without two-factor pass, otherwise code must be
+// a current TOTP code or one of their recovery codes.
+func (m *Manager) checkSecondFactor(ctx context.Context, userID, code string) error {
+	enrollment, err := m.store.GetUserTOTP(ctx, userID)
+	if err != nil || !enrollment.Enabled {
+		return nil
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrTOTPRequired
+	}
+
+	if len(normalizeRecoveryCode(code)) == recoveryCodeLength {
+		consumed, err := m.store.ConsumeTOTPRecoveryCode(ctx, userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return ErrInvalidTOTP
+		}
+		return nil
+	}
+	return m.verifyTOTPCode(ctx, enrollment, code)
+}
+
+// Checks a TOTP code and marks its time step used
+func (m *Manager) verifyTOTPCode(ctx context.Context, enrollment *db.UserTOTP, code string) error {
+	secret, err := m.decryptTOTPSecret(enrollment.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok, err := totp.Validate(secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTOTP
+	}
+	fresh, err := m.store.ConsumeTOTPStep(ctx, enrollment.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTOTP // Replayed
+	}
+	return nil
+}
+
+// Recovery codes are 10 base32 characters, shown as xxxxx-xxxxx
+const recoveryCodeLength = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Returns the codes to show the user and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, totpRecoveryCodeCount)
+	hashes := make([]string, 0, totpRecoveryCodeCount)
+	for i := 0; i < totpRecoveryCodeCount; i++ {
+		raw := make([]byte, 8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:recoveryCodeLength]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickheyer/discopanel/internal/config"
+	"github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/logger"
+	"github.com/nickheyer/discopanel/pkg/totp"
+)
+
+// A manager on a fresh database with a local user who has two-factor turned on. Returns
+// the user's secret, the step enrollment was confirmed with and the recovery codes.
+func newTOTPTestManager(t *testing.T) (*Manager, *db.User, string, int64, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "discopanel.db")
+	cfg.Database.AutoMigrate = true
+	store, err := db.NewStore(cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	authConfig := &config.AuthConfig{}
+	authConfig.Local.Enabled = true
+	authConfig.TOTP.Enabled = true
+	manager, err := NewManager(store, nil, authConfig)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	user := &db.User{Username: "alex", AuthProvider: "local", IsActive: true}
+	if err := store.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	enrollment, err := manager.BeginTOTPEnrollment(ctx, user)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment: %v", err)
+	}
+	step := totp.Step(time.Now())
+	code, err := totp.Code(enrollment.Secret, step)
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	recoveryCodes, err := manager.ConfirmTOTPEnrollment(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment: %v", err)
+	}
+	return manager, user, enrollment.Secret, step, recoveryCodes
+}
+
+func TestSecondFactorRejectsReusedStep(t *testing.T) {
+	ctx := context.Background()
+	manager, user, secret, step, _ := newTOTPTestManager(t)
+
+	// Confirming the enrollment used up its step
+	confirmed, _ := totp.Code(secret, step)
+	if err := manager.checkSecondFactor(ctx, user.ID, confirmed); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("code from the enrollment step: err = %v, want %v", err, ErrInvalidTOTP)
+	}
+
+	// The next step is accepted once, within the clock skew allowance
+	next, _ := totp.Code(secret, step+1)
+	if err := manager.checkSecondFactor(ctx, user.ID, next); err != nil {
+		t.Fatalf("code for the next step: %v", err)
+	}
+	if err := manager.checkSecondFactor(ctx, user.ID, next); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("replayed code: err = %v, want %v", err, ErrInvalidTOTP)
+	}
+
+	// Earlier steps stay refused too, a captured older code is no use
+	previous, _ := totp.Code(secret, step-1)
+	if err := manager.checkSecondFactor(ctx, user.ID, previous); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("code for an earlier step: err = %v, want %v", err, ErrInvalidTOTP)
+	}
+
+	if err := manager.checkSecondFactor(ctx, user.ID, ""); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("no code: err = %v, want %v", err, ErrTOTPRequired)
+	}
+}
+
+func TestSecondFactorRejectsReusedRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	manager, user, _, _, recoveryCodes := newTOTPTestManager(t)
+	if len(recoveryCodes) != totpRecoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recoveryCodes), totpRecoveryCodeCount)
+	}
+
+	code := recoveryCodes[0]
+	if err := manager.checkSecondFactor(ctx, user.ID, code); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	for _, reused := range []string{code, strings.ToUpper(code), strings.ReplaceAll(code, "-", "")} {
+		if err := manager.checkSecondFactor(ctx, user.ID, reused); !errors.Is(err, ErrInvalidTOTP) {
+			t.Errorf("reused recovery code %q: err = %v, want %v", reused, err, ErrInvalidTOTP)
+		}
+	}
+	if enabled, remaining := manager.TOTPStatus(ctx, user.ID); !enabled || remaining != totpRecoveryCodeCount-1 {
+		t.Errorf("TOTPStatus = %v, %d, want true, %d", enabled, remaining, totpRecoveryCodeCount-1)
+	}
+
+	// The others still work, typed however the user likes
+	if err := manager.checkSecondFactor(ctx, user.ID, " "+strings.ToUpper(strings.ReplaceAll(recoveryCodes[1], "-", ""))+" "); err != nil {
+		t.Errorf("second recovery code: %v", err)
+	}
+	if err := manager.checkSecondFactor(ctx, user.ID, "aaaaa-aaaaa"); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("made up recovery code: err = %v, want %v", err, ErrInvalidTOTP)
+	}
+}
+
+func TestBeginLinkRefusesTwoFactorAccounts(t *testing.T) {
+	ctx := context.Background()
+	manager, user, _, _, _ := newTOTPTestManager(t)
+	handler, err := NewOIDCHandler(manager, manager.store, &config.OIDCConfig{}, "", logger.New())
+	if err != nil {
+		t.Fatalf("NewOIDCHandler: %v", err)
+	}
+
+	if _, _, err := handler.BeginLink(ctx, user.ID); !errors.Is(err, ErrOIDCLinkTOTP) {
+		t.Fatalf("BeginLink with two-factor on: err = %v, want %v", err, ErrOIDCLinkTOTP)
+	}
+
+	if err := manager.DisableTOTP(ctx, user.ID); err != nil {
+		t.Fatalf("DisableTOTP: %v", err)
+	}
+	if _, cookie, err := handler.BeginLink(ctx, user.ID); err != nil || cookie == "" {
+		t.Errorf("BeginLink with two-factor off = %q, %v, want a link cookie", cookie, err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 type DockerExecutor interface {
 	ExecCommand(ctx context.Context, containerID string, command string) (string, error)
+	IsRemote() bool
 }
 
 type Sender struct {
@@ -40,6 +41,11 @@ func (s *Sender) SendCommand(ctx context.Context, serverID string, command strin
 		return "", fmt.Errorf("server container not found")
 	}
 
+	// RCON is only published on the remote host's loopback, exec is the way in
+	if s.docker.IsRemote() {
+		return s.docker.ExecCommand(ctx, server.ContainerID, command)
+	}
+
 	// old docker exec command
 	dockerExec := func(cause error) (string, error) {
 		output, err := s.docker.ExecCommand(ctx, server.ContainerID, command)
@@ -49,13 +55,36 @@ func (s *Sender) SendCommand(ctx context.Context, serverID string, command strin
 		return output, nil
 	}
 
-	serverCfg, err := s.store.GetServerConfig(ctx, serverID)
+	host, rconPort, rconPassword, err := s.ResolveRCON(ctx, server)
+	if err != nil {
+		return dockerExec(err)
+	}
+
+	// run comamand in dedicated context with timeout
+	rconCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	output, err := rcon.SendCommand(rconCtx, host, rconPort, rconPassword, command)
+
 	if err != nil {
-		return dockerExec(fmt.Errorf("failed to load server config: %w", err))
+		return dockerExec(fmt.Errorf("rcon command failed: %w", err))
+	}
+
+	return output, nil
+}
+
+// ResolveRCON returns the address and credentials used to reach a server's RCON listener
+func (s *Sender) ResolveRCON(ctx context.Context, server *storage.Server) (string, int, string, error) {
+	if s.docker.IsRemote() {
+		return "", 0, "", fmt.Errorf("rcon is not reachable on a remote Docker host, commands are sent with docker exec")
+	}
+
+	serverCfg, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("failed to load server config: %w", err)
 	}
 
 	if serverCfg.EnableRCON != nil && *serverCfg.EnableRCON == false {
-		return dockerExec(fmt.Errorf("rcon is disabled for this server"))
+		return "", 0, "", fmt.Errorf("rcon is disabled for this server")
 	}
 
 	var rconPort int
@@ -91,17 +120,8 @@ func (s *Sender) SendCommand(ctx context.Context, serverID string, command strin
 
 	ip, err := proxy.GetContainerIP(server.ContainerID, s.config.Docker.NetworkName)
 	if err != nil {
-		return dockerExec(fmt.Errorf("failed to resolve container ip: %w", err))
+		return "", 0, "", fmt.Errorf("failed to resolve container ip: %w", err)
 	}
 
-	// run comamand in dedicated context with timeout
-	rconCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
-	output, err := rcon.SendCommand(rconCtx, ip, rconPort, rconPassword, command)
-
-	if err != nil {
-		return dockerExec(fmt.Errorf("rcon command failed: %w", err))
-	}
-
-	return output, nil
+	return ip, rconPort, rconPassword, nil
 }
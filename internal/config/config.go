@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/netip"
 	"path/filepath"
 	"reflect"
 	"slices"
@@ -19,6 +20,7 @@ type Config struct {
 	Storage   StorageConfig   `mapstructure:"storage" json:"storage"`
 	Proxy     ProxyConfig     `mapstructure:"proxy" json:"proxy"`
 	Module    ModuleConfig    `mapstructure:"module" json:"module"`
+	Tasks     TasksConfig     `mapstructure:"tasks" json:"tasks"`
 	Minecraft MinecraftConfig `mapstructure:"minecraft" json:"minecraft"`
 	Logging   LoggingConfig   `mapstructure:"logging" json:"logging"`
 	Upload    UploadConfig    `mapstructure:"upload" json:"upload"`
@@ -26,11 +28,37 @@ type Config struct {
 }
 
 type AuthConfig struct {
-	SessionTimeout  int         `mapstructure:"session_timeout" json:"session_timeout"`
-	AnonymousAccess bool        `mapstructure:"anonymous_access" json:"anonymous_access"`
-	JWTSecret       string      `mapstructure:"jwt_secret" json:"jwt_secret"`
-	OIDC            OIDCConfig  `mapstructure:"oidc" json:"oidc"`
-	Local           LocalConfig `mapstructure:"local" json:"local"`
+	SessionTimeout  int            `mapstructure:"session_timeout" json:"session_timeout"`
+	AnonymousAccess bool           `mapstructure:"anonymous_access" json:"anonymous_access"`
+	JWTSecret       string         `mapstructure:"jwt_secret" json:"jwt_secret"`
+	OIDC            OIDCConfig     `mapstructure:"oidc" json:"oidc"`
+	Local           LocalConfig    `mapstructure:"local" json:"local"`
+	Throttle        ThrottleConfig `mapstructure:"throttle" json:"throttle"`
+	WebAuthn        WebAuthnConfig `mapstructure:"webauthn" json:"webauthn"`
+	TOTP            TOTPConfig     `mapstructure:"totp" json:"totp"`
+	Cookie          CookieConfig   `mapstructure:"cookie" json:"cookie"`
+}
+
+// Attributes for cookies DiscoPanel sets, such as the OIDC login state
+type CookieConfig struct {
+	Secure   string `mapstructure:"secure" json:"secure"`       // auto (HTTPS directly or via a trusted proxy), always or never
+	SameSite string `mapstructure:"same_site" json:"same_site"` // lax, strict or none
+}
+
+// Authenticator app two-factor for local password logins
+type TOTPConfig struct {
+	Enabled       bool   `mapstructure:"enabled" json:"enabled"`
+	Issuer        string `mapstructure:"issuer" json:"issuer"`                 // Account name prefix shown in authenticator apps
+	EncryptionKey string `mapstructure:"encryption_key" json:"encryption_key"` // Encrypts stored secrets, generated and kept in the database when empty
+}
+
+// Passkey sign-in
+type WebAuthnConfig struct {
+	Enabled                 bool     `mapstructure:"enabled" json:"enabled"`
+	RPID                    string   `mapstructure:"rp_id" json:"rp_id"`                                         // Domain passkeys are bound to, defaults to the requesting page's host
+	RPName                  string   `mapstructure:"rp_name" json:"rp_name"`                                     // Name shown by the authenticator
+	Origins                 []string `mapstructure:"origins" json:"origins"`                                     // Allowed page origins, defaults to the requesting page's origin
+	RequireUserVerification bool     `mapstructure:"require_user_verification" json:"require_user_verification"` // Require PIN/biometric, not just a touch
 }
 
 type OIDCConfig struct {
@@ -41,6 +69,8 @@ type OIDCConfig struct {
 	RedirectURL     string            `mapstructure:"redirect_url" json:"redirect_url"`
 	Scopes          []string          `mapstructure:"scopes" json:"scopes"`
 	RoleClaim       string            `mapstructure:"role_claim" json:"role_claim"`
+	UsernameClaim   string            `mapstructure:"username_claim" json:"username_claim"`
+	EmailClaim      string            `mapstructure:"email_claim" json:"email_claim"`
 	RoleMapping     map[string]string `mapstructure:"role_mapping" json:"role_mapping"`
 	RejectUnmapped  bool              `mapstructure:"reject_unmapped" json:"reject_unmapped"`
 	SkipTLSVerify   bool              `mapstructure:"skip_tls_verify" json:"skip_tls_verify"`
@@ -49,6 +79,12 @@ type OIDCConfig struct {
 	ExtraClaimsName string            `mapstructure:"extra_claims_name" json:"extra_claims_name"`
 	RequiredClaim   string            `mapstructure:"required_claim" json:"required_claim"`
 	RequiredValues  []string          `mapstructure:"required_values" json:"required_values"`
+
+	DiscoveryTTL int `mapstructure:"discovery_ttl" json:"discovery_ttl"` // Seconds between provider discovery refreshes
+
+	// RP-initiated logout, ends the provider session too when it advertises an end_session_endpoint
+	LogoutFromProvider    bool   `mapstructure:"logout_from_provider" json:"logout_from_provider"`
+	PostLogoutRedirectURL string `mapstructure:"post_logout_redirect_url" json:"post_logout_redirect_url"` // Must be registered with the provider
 }
 
 type LocalConfig struct {
@@ -56,6 +92,16 @@ type LocalConfig struct {
 	AllowRegistration bool `mapstructure:"allow_registration" json:"allow_registration"`
 }
 
+// Failed login and recovery key attempt limits
+type ThrottleConfig struct {
+	Enabled          bool `mapstructure:"enabled" json:"enabled"`
+	MaxAttempts      int  `mapstructure:"max_attempts" json:"max_attempts"`               // Failures per username within the window
+	MaxAttemptsPerIP int  `mapstructure:"max_attempts_per_ip" json:"max_attempts_per_ip"` // Failures per client IP within the window
+	Window           int  `mapstructure:"window" json:"window"`                           // Seconds
+	Lockout          int  `mapstructure:"lockout" json:"lockout"`                         // Seconds for the first lockout, doubled on each repeat
+	MaxLockout       int  `mapstructure:"max_lockout" json:"max_lockout"`                 // Seconds
+}
+
 type ServerConfig struct {
 	Port         string `mapstructure:"port" json:"port"`
 	Host         string `mapstructure:"host" json:"host"`
@@ -63,6 +109,12 @@ type ServerConfig struct {
 	WriteTimeout int    `mapstructure:"write_timeout" json:"write_timeout"`
 	IdleTimeout  int    `mapstructure:"idle_timeout" json:"idle_timeout"`
 	UserAgent    string `mapstructure:"user_agent" json:"user_agent"`
+
+	// Reverse proxies (IPs or CIDRs) whose X-Forwarded-For / X-Real-IP headers name the client
+	TrustedProxies []string `mapstructure:"trusted_proxies" json:"trusted_proxies"`
+
+	// Subpath DiscoPanel is hosted under behind a reverse proxy (ie: /discopanel), empty for the root
+	BasePath string `mapstructure:"base_path" json:"base_path"`
 }
 
 type DockerConfig struct {
@@ -73,13 +125,45 @@ type DockerConfig struct {
 	RegistryURL  string            `mapstructure:"registry_url" json:"registry_url"`
 	DNS          string            `mapstructure:"dns" json:"dns"`
 	Labels       map[string]string `mapstructure:"labels" json:"labels"`
+	TLSCACert    string            `mapstructure:"tls_ca_cert" json:"tls_ca_cert"` // CA used to verify a remote daemon
+	TLSCert      string            `mapstructure:"tls_cert" json:"tls_cert"`       // Client certificate for a remote daemon
+	TLSKey       string            `mapstructure:"tls_key" json:"tls_key"`         // Client key for a remote daemon
+	Runtime      string            `mapstructure:"runtime" json:"runtime"`         // docker or podman
+	// Seconds a server may boot without a ready log line before it is reported running
+	StartupTimeout int `mapstructure:"startup_timeout" json:"startup_timeout"`
+	// A server whose container exits crash_loop_exits times within crash_loop_window seconds
+	// is stopped and flagged with its exit code and last log lines, 0 exits disables the check
+	CrashLoopExits  int `mapstructure:"crash_loop_exits" json:"crash_loop_exits"`
+	CrashLoopWindow int `mapstructure:"crash_loop_window" json:"crash_loop_window"`
+
+	// Back new servers' /data with a named Docker volume instead of a host directory
+	DataVolumes bool   `mapstructure:"data_volumes" json:"data_volumes"`
+	VolumesDir  string `mapstructure:"volumes_dir" json:"volumes_dir"` // Docker's volume directory as mounted into DiscoPanel, optional, empty reaches volumes through the Docker API only
+
+	// Rebuild a server's container as soon as an update changes it. When off the change is
+	// saved and applied by the next start, restart or recreate.
+	AutoRecreate bool `mapstructure:"auto_recreate" json:"auto_recreate"`
 }
 
 type StorageConfig struct {
-	DataDir       string `mapstructure:"data_dir" json:"data_dir"`
-	BackupDir     string `mapstructure:"backup_dir" json:"backup_dir"`
-	TempDir       string `mapstructure:"temp_dir" json:"temp_dir"`
-	MaxUploadSize int64  `mapstructure:"max_upload_size" json:"max_upload_size"`
+	DataDir       string             `mapstructure:"data_dir" json:"data_dir"`
+	BackupDir     string             `mapstructure:"backup_dir" json:"backup_dir"`
+	TempDir       string             `mapstructure:"temp_dir" json:"temp_dir"`
+	MaxUploadSize int64              `mapstructure:"max_upload_size" json:"max_upload_size"`
+	DirNaming     string             `mapstructure:"dir_naming" json:"dir_naming"` // Server directory names: name (<name>_<id>) or id
+	Remote        RemoteBackupConfig `mapstructure:"remote" json:"remote"`
+}
+
+// Off-site copy of backups in an S3-compatible bucket
+type RemoteBackupConfig struct {
+	Enabled   bool   `mapstructure:"enabled" json:"enabled"`
+	Endpoint  string `mapstructure:"endpoint" json:"endpoint"` // Empty uses AWS S3 for the region
+	Region    string `mapstructure:"region" json:"region"`
+	Bucket    string `mapstructure:"bucket" json:"bucket"`
+	Prefix    string `mapstructure:"prefix" json:"prefix"` // Key prefix, backups go under <prefix>/<server dir>/
+	AccessKey string `mapstructure:"access_key" json:"access_key"`
+	SecretKey string `mapstructure:"secret_key" json:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style" json:"path_style"` // Required by MinIO and most self-hosted stores
 }
 
 type ProxyConfig struct {
@@ -89,16 +173,31 @@ type ProxyConfig struct {
 	ListenPorts  []int  `mapstructure:"listen_ports" json:"listen_ports"` // Multiple listen ports
 	PortRangeMin int    `mapstructure:"port_range_min" json:"port_range_min"`
 	PortRangeMax int    `mapstructure:"port_range_max" json:"port_range_max"`
+	// ACME settings for HTTP listeners using tls_mode "acme"
+	ACMEEmail     string `mapstructure:"acme_email" json:"acme_email"`
+	ACMEDirectory string `mapstructure:"acme_directory" json:"acme_directory"` // Empty uses Let's Encrypt production
+	// Per connection log lines: off, errors (rejected and failed connections) or all
+	ConnectionLogging string `mapstructure:"connection_logging" json:"connection_logging"`
+}
+
+// Scheduled task settings
+type TasksConfig struct {
+	// Results of tasks that notify but set no URL of their own are posted here
+	NotifyURL    string `mapstructure:"notify_url" json:"notify_url"`
+	NotifySecret string `mapstructure:"notify_secret" json:"notify_secret"` // Signs posts to notify_url with X-DiscoPanel-Signature
 }
 
 type ModuleConfig struct {
-	Enabled      bool `mapstructure:"enabled" json:"enabled"`
-	PortRangeMin int  `mapstructure:"port_range_min" json:"port_range_min"`
-	PortRangeMax int  `mapstructure:"port_range_max" json:"port_range_max"`
+	Enabled         bool `mapstructure:"enabled" json:"enabled"`
+	PortRangeMin    int  `mapstructure:"port_range_min" json:"port_range_min"`
+	PortRangeMax    int  `mapstructure:"port_range_max" json:"port_range_max"`
+	StrictPortRange bool `mapstructure:"strict_port_range" json:"strict_port_range"` // Reject explicit host ports outside the range instead of warning
 }
 
 type DatabaseConfig struct {
-	Path            string `mapstructure:"path" json:"path"`
+	Driver          string `mapstructure:"driver" json:"driver"` // sqlite or postgres
+	Path            string `mapstructure:"path" json:"path"`     // SQLite database file
+	DSN             string `mapstructure:"dsn" json:"dsn"`       // Postgres connection string
 	MaxConnections  int    `mapstructure:"max_connections" json:"max_connections"`
 	MaxIdleConns    int    `mapstructure:"max_idle_conns" json:"max_idle_conns"`
 	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime" json:"conn_max_lifetime"`
@@ -106,8 +205,12 @@ type DatabaseConfig struct {
 }
 
 type MinecraftConfig struct {
-	ResetGlobal  bool           `mapstructure:"reset_global" json:"reset_global"`
-	GlobalConfig map[string]any `mapstructure:"global_config" json:"global_config"`
+	ResetGlobal       bool           `mapstructure:"reset_global" json:"reset_global"`
+	GlobalConfig      map[string]any `mapstructure:"global_config" json:"global_config"`
+	DefaultMemory     int            `mapstructure:"default_memory" json:"default_memory"`           // MB for new servers that don't set one
+	DefaultMaxPlayers int            `mapstructure:"default_max_players" json:"default_max_players"` // Player limit for new servers that don't set one
+	PortRangeMin      int            `mapstructure:"port_range_min" json:"port_range_min"`           // Host ports auto-assigned to non-proxied servers
+	PortRangeMax      int            `mapstructure:"port_range_max" json:"port_range_max"`
 }
 
 type LoggingConfig struct {
@@ -124,6 +227,7 @@ type UploadConfig struct {
 	DefaultChunkSize int   `mapstructure:"default_chunk_size" json:"default_chunk_size"` // Bytes, default 5MB, client overriden
 	MaxChunkSize     int   `mapstructure:"max_chunk_size" json:"max_chunk_size"`         // Bytes, default 10MB, server overriden
 	MaxUploadSize    int64 `mapstructure:"max_upload_size" json:"max_upload_size"`       // Bytes, default 0 (unlimited)
+	MaxJarSize       int64 `mapstructure:"max_jar_size" json:"max_jar_size"`             // Bytes, default 512MB, custom server jars
 }
 
 func Load(configPath string) (*Config, error) {
@@ -188,9 +292,13 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.write_timeout", 15)
 	v.SetDefault("server.idle_timeout", 60)
 	v.SetDefault("server.user_agent", "DiscoPanel/1.0 (github.com/nickheyer/discopanel)")
+	v.SetDefault("server.trusted_proxies", []string{})
+	v.SetDefault("server.base_path", "")
 
 	// Database defaults
+	v.SetDefault("database.driver", "sqlite")
 	v.SetDefault("database.path", "./data/discopanel.db")
+	v.SetDefault("database.dsn", "")
 	v.SetDefault("database.max_connections", 25)
 	v.SetDefault("database.max_idle_conns", 5)
 	v.SetDefault("database.conn_max_lifetime", 300)
@@ -204,6 +312,16 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("docker.registry_url", "")
 	v.SetDefault("docker.dns", "")
 	v.SetDefault("docker.labels", map[string]string{})
+	v.SetDefault("docker.tls_ca_cert", "")
+	v.SetDefault("docker.tls_cert", "")
+	v.SetDefault("docker.tls_key", "")
+	v.SetDefault("docker.runtime", "docker")
+	v.SetDefault("docker.startup_timeout", 600)
+	v.SetDefault("docker.crash_loop_exits", 3)
+	v.SetDefault("docker.crash_loop_window", 300)
+	v.SetDefault("docker.data_volumes", false)
+	v.SetDefault("docker.volumes_dir", "")
+	v.SetDefault("docker.auto_recreate", true)
 
 	// Storage defaults
 	dataDir, err := filepath.Abs("./data")
@@ -214,6 +332,15 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("storage.backup_dir", "./backups")
 	v.SetDefault("storage.temp_dir", "./tmp")
 	v.SetDefault("storage.max_upload_size", 500*1024*1024) // 500MB
+	v.SetDefault("storage.dir_naming", "name")
+	v.SetDefault("storage.remote.enabled", false)
+	v.SetDefault("storage.remote.endpoint", "")
+	v.SetDefault("storage.remote.region", "us-east-1")
+	v.SetDefault("storage.remote.bucket", "")
+	v.SetDefault("storage.remote.prefix", "discopanel")
+	v.SetDefault("storage.remote.access_key", "")
+	v.SetDefault("storage.remote.secret_key", "")
+	v.SetDefault("storage.remote.path_style", false)
 
 	// Proxy defaults
 	v.SetDefault("proxy.enabled", false)
@@ -222,13 +349,24 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("proxy.listen_ports", []int{25565})
 	v.SetDefault("proxy.port_range_min", 25565)
 	v.SetDefault("proxy.port_range_max", 25665)
+	v.SetDefault("proxy.acme_email", "")
+	v.SetDefault("proxy.acme_directory", "")
+	v.SetDefault("proxy.connection_logging", "off")
 
 	// Module defaults
 	v.SetDefault("module.enabled", true)
 	v.SetDefault("module.port_range_min", 8100)
 	v.SetDefault("module.port_range_max", 8199)
+	v.SetDefault("module.strict_port_range", false)
+
+	v.SetDefault("tasks.notify_url", "")
+	v.SetDefault("tasks.notify_secret", "")
 
 	v.SetDefault("minecraft.reset_global", false)
+	v.SetDefault("minecraft.default_memory", 4096)
+	v.SetDefault("minecraft.default_max_players", 20)
+	v.SetDefault("minecraft.port_range_min", 25565)
+	v.SetDefault("minecraft.port_range_max", 26565)
 
 	// Logging defaults
 	v.SetDefault("logging.enabled", true)
@@ -249,6 +387,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("auth.oidc.redirect_url", "")
 	v.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email"})
 	v.SetDefault("auth.oidc.role_claim", "groups")
+	v.SetDefault("auth.oidc.username_claim", "preferred_username")
+	v.SetDefault("auth.oidc.email_claim", "email")
 	v.SetDefault("auth.oidc.role_mapping", map[string]string{})
 	v.SetDefault("auth.oidc.reject_unmapped", false)
 	v.SetDefault("auth.oidc.skip_tls_verify", false)
@@ -257,14 +397,34 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("auth.oidc.extra_claims_name", "")
 	v.SetDefault("auth.oidc.required_claim", "")
 	v.SetDefault("auth.oidc.required_values", []string{})
+	v.SetDefault("auth.oidc.discovery_ttl", 3600)
+	v.SetDefault("auth.oidc.logout_from_provider", false)
+	v.SetDefault("auth.oidc.post_logout_redirect_url", "")
 	v.SetDefault("auth.local.enabled", true)
 	v.SetDefault("auth.local.allow_registration", false)
+	v.SetDefault("auth.throttle.enabled", true)
+	v.SetDefault("auth.throttle.max_attempts", 5)
+	v.SetDefault("auth.throttle.max_attempts_per_ip", 20)
+	v.SetDefault("auth.throttle.window", 900)
+	v.SetDefault("auth.throttle.lockout", 60)
+	v.SetDefault("auth.throttle.max_lockout", 3600)
+	v.SetDefault("auth.webauthn.enabled", true)
+	v.SetDefault("auth.webauthn.rp_id", "")
+	v.SetDefault("auth.webauthn.rp_name", "DiscoPanel")
+	v.SetDefault("auth.webauthn.origins", []string{})
+	v.SetDefault("auth.webauthn.require_user_verification", false)
+	v.SetDefault("auth.totp.enabled", true)
+	v.SetDefault("auth.totp.issuer", "DiscoPanel")
+	v.SetDefault("auth.totp.encryption_key", "")
+	v.SetDefault("auth.cookie.secure", "auto")
+	v.SetDefault("auth.cookie.same_site", "lax")
 
 	// Upload defaults
 	v.SetDefault("upload.session_ttl", 240)                // 4 hours (in minutes)
 	v.SetDefault("upload.default_chunk_size", 5*1024*1024) // 5MB
 	v.SetDefault("upload.max_chunk_size", 10*1024*1024)    // 10MB
 	v.SetDefault("upload.max_upload_size", 0)              // unlimited
+	v.SetDefault("upload.max_jar_size", 512*1024*1024)     // 512MB
 }
 
 func validateConfig(cfg *Config) error {
@@ -275,6 +435,16 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("invalid database path: %w", err)
 	}
 
+	switch cfg.Database.Driver {
+	case "sqlite":
+	case "postgres":
+		if cfg.Database.DSN == "" {
+			return fmt.Errorf("database driver postgres requires a dsn")
+		}
+	default:
+		return fmt.Errorf("database driver must be sqlite or postgres")
+	}
+
 	cfg.Storage.DataDir, err = filepath.Abs(cfg.Storage.DataDir)
 	if err != nil {
 		return fmt.Errorf("invalid data directory: %w", err)
@@ -290,6 +460,58 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("invalid temp directory: %w", err)
 	}
 
+	switch cfg.Storage.DirNaming {
+	case "name", "id":
+	default:
+		return fmt.Errorf("storage dir_naming must be name or id")
+	}
+
+	if cfg.Storage.Remote.Enabled && (cfg.Storage.Remote.Bucket == "" || cfg.Storage.Remote.AccessKey == "" || cfg.Storage.Remote.SecretKey == "") {
+		return fmt.Errorf("storage remote requires bucket, access_key and secret_key")
+	}
+
+	cfg.Server.BasePath = NormalizeBasePath(cfg.Server.BasePath)
+
+	if _, err := ParseTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(cfg.Auth.Cookie.Secure) {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("auth cookie secure must be auto, always or never")
+	}
+	switch strings.ToLower(cfg.Auth.Cookie.SameSite) {
+	case "lax", "strict":
+	case "none":
+		if strings.EqualFold(cfg.Auth.Cookie.Secure, "never") {
+			return fmt.Errorf("auth cookie same_site none requires secure cookies")
+		}
+	default:
+		return fmt.Errorf("auth cookie same_site must be lax, strict or none")
+	}
+
+	if cfg.Docker.Runtime != "docker" && cfg.Docker.Runtime != "podman" {
+		return fmt.Errorf("docker runtime must be docker or podman")
+	}
+
+	// Client cert and key only make sense together
+	if (cfg.Docker.TLSCert == "") != (cfg.Docker.TLSKey == "") {
+		return fmt.Errorf("docker tls_cert and tls_key must be set together")
+	}
+	if cfg.Docker.CrashLoopExits < 0 {
+		return fmt.Errorf("docker crash_loop_exits must not be negative")
+	}
+	if cfg.Docker.CrashLoopExits > 0 && cfg.Docker.CrashLoopWindow <= 0 {
+		return fmt.Errorf("docker crash_loop_window must be positive when crash_loop_exits is set")
+	}
+
+	switch cfg.Proxy.ConnectionLogging {
+	case "off", "errors", "all":
+	default:
+		return fmt.Errorf("proxy connection_logging must be off, errors or all")
+	}
+
 	// Validate port ranges
 	if cfg.Proxy.PortRangeMin >= cfg.Proxy.PortRangeMax {
 		return fmt.Errorf("proxy port range min must be less than max")
@@ -299,6 +521,26 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("module port range min must be less than max")
 	}
 
+	if cfg.Module.PortRangeMin < 1 || cfg.Module.PortRangeMax > 65535 {
+		return fmt.Errorf("module port range must be within 1-65535")
+	}
+
+	if cfg.Minecraft.PortRangeMin >= cfg.Minecraft.PortRangeMax {
+		return fmt.Errorf("minecraft port range min must be less than max")
+	}
+
+	// Privileged ports would need the container runtime to bind as root
+	if cfg.Minecraft.PortRangeMin < 1024 || cfg.Minecraft.PortRangeMax > 65535 {
+		return fmt.Errorf("minecraft port range must be within 1024-65535")
+	}
+
+	if cfg.Minecraft.DefaultMemory < 512 {
+		return fmt.Errorf("minecraft default_memory must be at least 512 MB")
+	}
+	if cfg.Minecraft.DefaultMaxPlayers < 1 {
+		return fmt.Errorf("minecraft default_max_players must be at least 1")
+	}
+
 	// Ensure ListenPorts includes Primary ListenPort
 	if cfg.Proxy.Enabled {
 		if len(cfg.Proxy.ListenPorts) == 0 {
@@ -322,6 +564,37 @@ func validateConfig(cfg *Config) error {
 	return nil
 }
 
+// Normalizes a base path to a leading slash and no trailing slash, "" for the root
+func NormalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// Parses trusted proxy entries, a bare IP trusts just that address
+func ParseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server trusted_proxies entry %q, expected an IP or CIDR", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
 // Decodes JSON object strings into map types
 func jsonStringToMapHook() mapstructure.DecodeHookFuncType {
 	return func(from, to reflect.Type, data any) (any, error) {
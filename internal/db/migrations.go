@@ -1,6 +1,8 @@
 package db
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -13,6 +15,7 @@ func allModels() []any {
 	return []any{
 		&Server{},
 		&ServerConfig{},
+		&ConfigPreset{},
 		&Mod{},
 		&IndexedModpack{},
 		&IndexedModpackFile{},
@@ -24,12 +27,17 @@ func allModels() []any {
 		&UserRole{},
 		&Session{},
 		&APIToken{},
+		&Passkey{},
+		&UserTOTP{},
 		&RegistrationInvite{},
 		&ScheduledTask{},
 		&TaskExecution{},
 		&ModuleTemplate{},
 		&Module{},
 		&SystemSetting{},
+		&ConsoleHistory{},
+		&ServerACL{},
+		&AuditLog{},
 	}
 }
 
@@ -67,6 +75,7 @@ func seeds(s *Store) error {
 	for _, seed := range []func() error{
 		s.SeedSystemRoles,
 		s.SeedGlobalSettings,
+		s.SeedConfigPresets,
 	} {
 		if err := seed(); err != nil {
 			return err
@@ -118,10 +127,41 @@ func migrations() []*gormigrate.Migration {
 				return tx.Where("source = ?", "migration").Delete(&UserRole{}).Error
 			},
 		},
+		{
+			ID: "20261014_001_hash_panel_tokens",
+			Migrate: func(tx *gorm.DB) error {
+				if !tx.Migrator().HasColumn(&Server{}, "panel_token") {
+					return nil
+				}
+
+				// Running modules still carry their server's plaintext token, keep it valid
+				// through its hash until they are recreated with their own
+				var servers []struct {
+					ID         string
+					PanelToken string
+				}
+				if err := tx.Table("servers").Select("id, panel_token").Where("panel_token <> ''").Scan(&servers).Error; err != nil {
+					return err
+				}
+				for _, server := range servers {
+					hash := sha256.Sum256([]byte(server.PanelToken))
+					if err := tx.Model(&Module{}).Where("server_id = ? AND (panel_token_hash IS NULL OR panel_token_hash = '')", server.ID).
+						Update("panel_token_hash", hex.EncodeToString(hash[:])).Error; err != nil {
+						return fmt.Errorf("failed to carry over panel token of server %s: %w", server.ID, err)
+					}
+				}
+
+				return tx.Migrator().DropColumn(&Server{}, "panel_token")
+			},
+		},
 	}
 }
 
+// Copies the SQLite database aside before migrating, Postgres backups are left to its own tooling
 func (s *Store) backupDB() error {
+	if s.db.Dialector.Name() != "sqlite" {
+		return nil
+	}
 	if s.cfg.Database.Path == "" || s.cfg.Database.Path == ":memory:" {
 		return nil
 	}
@@ -18,6 +18,17 @@ const (
 	StatusCreating  ServerStatus = "creating" // Container is being created/image pulled
 )
 
+// StartupPhase is the boot step of a starting server, derived from its logs
+type StartupPhase string
+
+const (
+	PhasePullingImage StartupPhase = "pulling_image"
+	PhaseInstalling   StartupPhase = "installing" // Downloading server files, mods or a modpack
+	PhaseLaunching    StartupPhase = "launching"  // Server jar starting up
+	PhaseLoadingWorld StartupPhase = "loading_world"
+	PhaseReady        StartupPhase = "ready" // "Done" line seen, players can join
+)
+
 type ModLoader string
 
 const (
@@ -80,9 +91,12 @@ type Server struct {
 	Port            int                  `json:"port"`
 	ProxyPort       int                  `json:"proxy_port" gorm:"column:proxy_port"`
 	ProxyHostname   string               `json:"proxy_hostname" gorm:"column:proxy_hostname;uniqueIndex:idx_proxy_hostname_listener,where:proxy_hostname != ''"`
+	ProxySubdomain  string               `json:"proxy_subdomain" gorm:"column:proxy_subdomain"`                                                                           // Part of ProxyHostname before the proxy base URL, empty for custom hostnames
 	ProxyListenerID string               `json:"proxy_listener_id" gorm:"column:proxy_listener_id;uniqueIndex:idx_proxy_hostname_listener,where:proxy_listener_id != ''"` // Which listener this server uses
 	MaxPlayers      int                  `json:"max_players" gorm:"default:20;column:max_players"`
-	Memory          int                  `json:"memory" gorm:"default:4096"` // in MB (allocated) - IMPORTANT: This applies to the container's memory allocation first, then used to calc the JVM min/max for mc server proc inside w/ overhead
+	Memory          int                  `json:"memory" gorm:"default:4096"`          // in MB (allocated) - IMPORTANT: This applies to the container's memory allocation first, then used to calc the JVM min/max for mc server proc inside w/ overhead
+	CPULimit        float64              `json:"cpu_limit" gorm:"column:cpu_limit"`   // Cores the container may use, 0 for no limit
+	SwapLimit       int                  `json:"swap_limit" gorm:"column:swap_limit"` // MB of swap on top of Memory, 0 for none
 	CreatedAt       time.Time            `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
 	LastStarted     *time.Time           `json:"last_started" gorm:"column:last_started"`
@@ -95,14 +109,48 @@ type Server struct {
 	AdditionalPorts []*v1.AdditionalPort `json:"additional_ports" gorm:"column:additional_ports;serializer:json"`           // Additional port configurations
 	DockerOverrides *v1.DockerOverrides  `json:"docker_overrides" gorm:"column:docker_overrides;type:text;serializer:json"` // Docker container overrides
 
+	// Named Docker volume holding /data, empty for a host bind mount of DataPath.
+	// DataPath then points at the volume's contents as DiscoPanel sees them.
+	DataVolume string `json:"data_volume" gorm:"column:data_volume"`
+
+	// User who owns the server. Owners get editor access to it and it counts against their quota.
+	OwnerID string `json:"owner_id" gorm:"column:owner_id;index"`
+
+	// Adopted from a container DiscoPanel did not create. That container is only
+	// replaced on explicit confirmation and deleting the server leaves it and its data alone.
+	Imported bool `json:"imported" gorm:"column:imported;default:false"`
+
+	// Set by the periodic check when the container's published ports don't match the server
+	PortBindingWarning string `json:"port_binding_warning" gorm:"column:port_binding_warning"`
+
+	// Why the server last ended up in the error state, e.g. a crash loop's exit code and
+	// final log lines. Cleared when the server is started again.
+	LastError string `json:"last_error" gorm:"column:last_error"`
+
+	// An update changed the container but docker.auto_recreate is off, the next start,
+	// restart or recreate rebuilds it
+	RestartRequired bool `json:"restart_required" gorm:"column:restart_required;default:false"`
+
+	// Restart the server once Docker has reported it unhealthy for UnhealthyRestartAfter
+	// seconds, at most MaxUnhealthyRestarts times an hour. Zero picks the defaults.
+	RestartOnUnhealthy    bool `json:"restart_on_unhealthy" gorm:"column:restart_on_unhealthy;default:false"`
+	UnhealthyRestartAfter int  `json:"unhealthy_restart_after" gorm:"column:unhealthy_restart_after"`
+	MaxUnhealthyRestarts  int  `json:"max_unhealthy_restarts" gorm:"column:max_unhealthy_restarts"`
+
+	// Modpack the server was built from, for update checks
+	ModpackID        string `json:"modpack_id" gorm:"column:modpack_id"`                 // Indexed modpack ID
+	ModpackVersionID string `json:"modpack_version_id" gorm:"column:modpack_version_id"` // Installed modpack file/version ID
+
 	// Runtime stats (not persisted to DB)
-	MemoryUsage   float64 `json:"memory_usage" gorm:"-"`   // Current memory usage in MB
-	CPUPercent    float64 `json:"cpu_percent" gorm:"-"`    // Current CPU usage percentage
-	DiskUsage     int64   `json:"disk_usage" gorm:"-"`     // Total server data size in bytes
-	DiskTotal     int64   `json:"disk_total" gorm:"-"`     // Total disk space available in bytes
-	WorldSize     int64   `json:"world_size" gorm:"-"`     // World directory size in bytes
-	PlayersOnline int     `json:"players_online" gorm:"-"` // Current players online
-	TPS           float64 `json:"tps" gorm:"-"`            // Current TPS (20 is optimal)
+	MemoryUsage   float64      `json:"memory_usage" gorm:"-"`   // Current memory usage in MB
+	CPUPercent    float64      `json:"cpu_percent" gorm:"-"`    // Current CPU usage percentage
+	DiskUsage     int64        `json:"disk_usage" gorm:"-"`     // Total server data size in bytes
+	DiskTotal     int64        `json:"disk_total" gorm:"-"`     // Total disk space available in bytes
+	WorldSize     int64        `json:"world_size" gorm:"-"`     // World directory size in bytes
+	CrashDumps    int          `json:"crash_dumps" gorm:"-"`    // JVM error logs and heap dumps present
+	PlayersOnline int          `json:"players_online" gorm:"-"` // Current players online
+	TPS           float64      `json:"tps" gorm:"-"`            // Current TPS (20 is optimal)
+	StartupPhase  StartupPhase `json:"startup_phase" gorm:"-"`  // Boot progress while starting
 
 	// SLP runtime stats (not persisted to DB)
 	SLPAvailable    bool     `json:"slp_available" gorm:"-"`
@@ -113,6 +161,24 @@ type Server struct {
 	PlayerSample    []string `json:"player_sample" gorm:"-"`
 	MaxPlayersSLP   int      `json:"max_players_slp" gorm:"-"` // Actual from SLP (MaxPlayers field is config)
 	Favicon         string   `json:"favicon" gorm:"-"`         // Base64 PNG from SLP
+
+	// Memory trend runtime stats (not persisted to DB)
+	MemoryLimit       float64       `json:"memory_limit" gorm:"-"`        // Container memory limit in MB
+	MemoryTrend       float64       `json:"memory_trend" gorm:"-"`        // MB per hour over the recent window
+	MemoryFullIn      time.Duration `json:"memory_full_in" gorm:"-"`      // Projected time until the limit, 0 when not climbing
+	MemoryLeakWarning bool          `json:"memory_leak_warning" gorm:"-"` // Steady climb toward the limit
+}
+
+// Default Java port inside server containers
+const DefaultJavaPort = 25565
+
+// ContainerPort is the port the server listens on inside its container. Proxied servers
+// always use the default, others listen on their host port.
+func (s *Server) ContainerPort() int {
+	if s.ProxyHostname != "" {
+		return DefaultJavaPort
+	}
+	return s.Port
 }
 
 type ServerConfig struct {
@@ -131,12 +197,13 @@ type ServerConfig struct {
 	EnableRollingLogs      *bool   `json:"enableRollingLogs" env:"ENABLE_ROLLING_LOGS" default:"false" desc:"Enable rolling log files strategy" input:"checkbox" label:"Enable Rolling Logs"`
 	EnableJMX              *bool   `json:"enableJmx" env:"ENABLE_JMX" default:"false" desc:"Enable remote JMX for profiling" input:"checkbox" label:"Enable JMX"`
 	JMXHost                *string `json:"jmxHost" env:"JMX_HOST" default:"" desc:"IP/host running the Docker container for JMX" input:"text" label:"JMX Host"`
-	UseAikarFlags          *bool   `json:"useAikarFlags" env:"USE_AIKAR_FLAGS" default:"false" desc:"Use Aikar's optimized JVM flags for GC tuning" input:"checkbox" label:"Use Aikar Flags"`
+	UseAikarFlags          *bool   `json:"useAikarFlags" env:"USE_AIKAR_FLAGS" default:"false" desc:"Use Aikar's optimized JVM flags for GC tuning, sized to the server's max memory" input:"checkbox" label:"Use Aikar Flags"`
 	UseMeowiceFlags        *bool   `json:"useMeowiceFlags" env:"USE_MEOWICE_FLAGS" default:"false" desc:"Use MeowIce's JVM flags optimized for Java 17+" input:"checkbox" label:"Use MeowIce Flags"`
 	UseMeowiceGraalVMFlags *bool   `json:"useMeowiceGraalvmFlags" env:"USE_MEOWICE_GRAALVM_FLAGS" default:"true" desc:"Enable MeowIce's flags for GraalVM" input:"checkbox" label:"Use MeowIce GraalVM Flags"`
 	JVMOpts                *string `json:"jvmOpts" env:"JVM_OPTS" default:"" desc:"General JVM options" input:"text" label:"JVM Options"`
 	JVMXXOpts              *string `json:"jvmXxOpts" env:"JVM_XX_OPTS" default:"" desc:"JVM -XX options" input:"text" label:"JVM XX Options"`
 	JVMDDOpts              *string `json:"jvmDdOpts" env:"JVM_DD_OPTS" default:"" desc:"Comma separated list of system properties as name=value pairs" input:"text" label:"JVM DD Options"`
+	HeapDumpOnOOM          *bool   `json:"heapDumpOnOom" env:"-" default:"false" desc:"Write heap dumps and fatal error logs to /data/dumps when the JVM crashes" input:"checkbox" label:"Collect Crash Dumps"`
 	ExtraArgs              *string `json:"extraArgs" env:"EXTRA_ARGS" default:"" desc:"Arguments passed to the jar file" input:"text" label:"Extra Arguments"`
 	LogTimestamp           *bool   `json:"logTimestamp" env:"LOG_TIMESTAMP" default:"false" desc:"Include timestamp with each log" input:"checkbox" label:"Log Timestamp"`
 
@@ -203,6 +270,9 @@ type ServerConfig struct {
 	StatusHeartbeatInterval        *int    `json:"statusHeartbeatInterval" env:"STATUS_HEARTBEAT_INTERVAL" default:"0" desc:"Status heartbeat interval (ms)" input:"number" label:"Status Heartbeat Interval"`
 	ExecDirectly                   *bool   `json:"execDirectly" env:"EXEC_DIRECTLY" default:"false" desc:"Enable docker attach with color and interactive capabilities" input:"checkbox" label:"Execute Directly"`
 	StopServerAnnounceDelay        *int    `json:"stopServerAnnounceDelay" env:"STOP_SERVER_ANNOUNCE_DELAY" default:"0" desc:"Delay in seconds after shutdown announcement" input:"number" label:"Stop Server Announce Delay"`
+	StopWarnings                   *string `json:"stopWarnings" env:"-" default:"" desc:"Seconds before a stop or restart from DiscoPanel to warn online players, comma separated (e.g. 60,30,10)" input:"text" label:"Shutdown Warnings"`
+	StopWarningMessage             *string `json:"stopWarningMessage" env:"-" default:"Server {action} in {seconds} seconds" desc:"Shutdown warning, {action} and {seconds} are filled in" input:"text" label:"Shutdown Warning Message"`
+	StopWarningTitle               *bool   `json:"stopWarningTitle" env:"-" default:"true" desc:"Also show shutdown warnings on screen" input:"checkbox" label:"Shutdown Warning Titles"`
 	Proxy                          *string `json:"proxy" env:"PROXY" default:"" desc:"HTTP/HTTPS proxy URL" input:"text" label:"Proxy URL"`
 	Console                        *bool   `json:"console" env:"CONSOLE" default:"true" desc:"Console setting for older Spigot versions" input:"checkbox" label:"Enable Console"`
 	GUI                            *bool   `json:"gui" env:"GUI" default:"true" desc:"GUI interface setting for older servers" input:"checkbox" label:"Enable GUI"`
@@ -305,6 +375,31 @@ type ServerConfig struct {
 	ModrinthDownloadDependencies       *string `json:"modrinthDownloadDependencies" env:"MODRINTH_DOWNLOAD_DEPENDENCIES" default:"none" desc:"Dependency download mode (none, required, optional)" input:"select" label:"Modrinth Download Dependencies"`
 	ModrinthProjectsDefaultVersionType *string `json:"modrinthProjectsDefaultVersionType" env:"MODRINTH_PROJECTS_DEFAULT_VERSION_TYPE" default:"release" desc:"Default version type to select (release, beta, alpha)" input:"select" label:"Modrinth Default Version Type"`
 	VersionFromModrinthProjects        *bool   `json:"versionFromModrinthProjects" env:"VERSION_FROM_MODRINTH_PROJECTS" default:"false" desc:"Automatically set VERSION from Modrinth project compatibility" input:"checkbox" label:"Version From Modrinth Projects"`
+
+	// Feed The Beast
+	FTBModpackID        *string `json:"ftbModpackId" env:"FTB_MODPACK_ID" default:"" desc:"Numerical FTB modpack ID" input:"text" label:"FTB Modpack ID"`
+	FTBModpackVersionID *string `json:"ftbModpackVersionId" env:"FTB_MODPACK_VERSION_ID" default:"" desc:"Numerical FTB modpack version ID, latest when empty" input:"text" label:"FTB Modpack Version ID"`
+	FTBForceReinstall   *bool   `json:"ftbForceReinstall" env:"FTB_FORCE_REINSTALL" default:"false" desc:"Reinstall the modpack files (cleared after start)" input:"checkbox" label:"FTB Force Reinstall" ephemeral:"true"`
+}
+
+// ConfigPresetType defines whether a config preset is built-in or custom
+type ConfigPresetType string
+
+const (
+	ConfigPresetTypeBuiltin ConfigPresetType = "builtin"
+	ConfigPresetTypeCustom  ConfigPresetType = "custom"
+)
+
+// ConfigPreset is a named set of ServerConfig values that can be applied to servers
+type ConfigPreset struct {
+	ID          string            `json:"id" gorm:"primaryKey"`
+	Name        string            `json:"name" gorm:"not null;uniqueIndex"`
+	Description string            `json:"description"`
+	Type        ConfigPresetType  `json:"type" gorm:"not null;default:custom"`
+	Values      map[string]string `json:"values" gorm:"column:preset_values;serializer:json"` // ServerConfig json key to value
+	CreatedBy   string            `json:"created_by" gorm:"column:created_by"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 type Mod struct {
@@ -378,16 +473,45 @@ type ProxyConfig struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+// ListenerProtocol is the transport a proxy listener accepts
+type ListenerProtocol string
+
+const (
+	ListenerProtocolTCP  ListenerProtocol = "tcp"  // Java edition, routed by handshake hostname
+	ListenerProtocolUDP  ListenerProtocol = "udp"  // Bedrock edition, forwarded to a single target server
+	ListenerProtocolHTTP ListenerProtocol = "http" // Web routes (module HTTP ports) by Host header, optionally TLS
+)
+
+// ListenerTLSMode selects how an HTTP listener obtains certificates
+type ListenerTLSMode string
+
+const (
+	ListenerTLSNone  ListenerTLSMode = ""      // Plain HTTP
+	ListenerTLSFiles ListenerTLSMode = "files" // PEM cert/key pair on disk, chosen by SNI
+	ListenerTLSACME  ListenerTLSMode = "acme"  // Let's Encrypt certs issued per server ProxyHostname
+)
+
+// Default Bedrock port exposed by Geyser and native Bedrock servers
+const DefaultBedrockPort = 19132
+
 // ProxyListener represents an individual proxy listening port configuration
 type ProxyListener struct {
-	ID          string    `json:"id" gorm:"primaryKey"`
-	Port        int       `json:"port" gorm:"not null;uniqueIndex"`
-	Name        string    `json:"name"` // e.g., "Primary", "Secondary", "Development"
-	Description string    `json:"description"`
-	Enabled     bool      `json:"enabled" gorm:"not null;default:true"`
-	IsDefault   bool      `json:"is_default" gorm:"not null;default:false"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID          string           `json:"id" gorm:"primaryKey"`
+	Port        int              `json:"port" gorm:"not null;uniqueIndex"`
+	Name        string           `json:"name"` // e.g., "Primary", "Secondary", "Development"
+	Description string           `json:"description"`
+	Enabled     bool             `json:"enabled" gorm:"not null;default:true"`
+	IsDefault   bool             `json:"is_default" gorm:"not null;default:false"`
+	Protocol    ListenerProtocol `json:"protocol" gorm:"not null;default:tcp"`
+	// UDP listeners can't multiplex by hostname, so each forwards to one server's Bedrock port
+	TargetServerID string `json:"target_server_id" gorm:"column:target_server_id;index"`
+	TargetPort     int    `json:"target_port" gorm:"column:target_port;default:0"`
+	// TLS termination, only honored on HTTP listeners; game traffic stays raw TCP/UDP
+	TLSMode     ListenerTLSMode `json:"tls_mode" gorm:"column:tls_mode"`
+	TLSCertPath string          `json:"tls_cert_path" gorm:"column:tls_cert_path"`
+	TLSKeyPath  string          `json:"tls_key_path" gorm:"column:tls_key_path"`
+	CreatedAt   time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // RegistrationInvite represents a shareable invite link for controlled registration
@@ -417,6 +541,11 @@ type User struct {
 	LastLogin    *time.Time `json:"last_login" gorm:"column:last_login"`
 	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Quota overrides, nil inherits from the user's roles and 0 is unlimited
+	MaxServers *int `json:"max_servers" gorm:"column:max_servers"`
+	MaxMemory  *int `json:"max_memory" gorm:"column:max_memory"` // MB
+	MaxModules *int `json:"max_modules" gorm:"column:max_modules"`
 }
 
 // Role represents a role in the RBAC system
@@ -428,6 +557,11 @@ type Role struct {
 	IsDefault   bool      `json:"is_default" gorm:"not null;default:false"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Quotas for members of this role, 0 is unlimited
+	MaxServers int `json:"max_servers" gorm:"column:max_servers;default:0"`
+	MaxMemory  int `json:"max_memory" gorm:"column:max_memory;default:0"` // MB, across servers and modules
+	MaxModules int `json:"max_modules" gorm:"column:max_modules;default:0"`
 }
 
 // UserRole links users to roles
@@ -454,7 +588,35 @@ type APIToken struct {
 	ExpiresAt     *time.Time `json:"expires_at" gorm:"column:expires_at"`
 	LastUsedAt    *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
 	IsModuleToken bool       `json:"is_module_token" gorm:"default:false;column:is_module_token"`
+	Scopes        []string   `json:"scopes" gorm:"column:scopes;serializer:json"` // resource:action pairs, empty inherits the user's full roles
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	User          *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// Passkey is a WebAuthn credential a user can sign in with
+type Passkey struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	UserID       string     `json:"user_id" gorm:"not null;index;column:user_id"`
+	Name         string     `json:"name" gorm:"not null"`
+	CredentialID string     `json:"credential_id" gorm:"not null;uniqueIndex;column:credential_id"` // base64url
+	PublicKey    []byte     `json:"-" gorm:"not null;column:public_key"`                            // COSE_Key
+	SignCount    uint32     `json:"sign_count" gorm:"column:sign_count"`
+	AAGUID       string     `json:"aaguid" gorm:"column:aaguid"` // Authenticator model, hex
+	LastUsedAt   *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	User         *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// UserTOTP is a user's authenticator app second factor for password logins
+type UserTOTP struct {
+	UserID        string     `json:"user_id" gorm:"primaryKey;column:user_id"`
+	Secret        string     `json:"-" gorm:"not null"`                              // Base32 secret, AES-GCM encrypted
+	Enabled       bool       `json:"enabled" gorm:"default:false"`                   // Set once enrollment is confirmed with a code
+	LastStep      int64      `json:"-" gorm:"column:last_step"`                      // Last accepted time step, codes can't be replayed
+	RecoveryCodes []string   `json:"-" gorm:"column:recovery_codes;serializer:json"` // SHA-256 hashes of unused recovery codes
+	EnabledAt     *time.Time `json:"enabled_at" gorm:"column:enabled_at"`
 	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	User          *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
 
@@ -465,6 +627,9 @@ type Session struct {
 	Token     string    `json:"-" gorm:"not null;uniqueIndex"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	IPAddress string    `json:"ip_address" gorm:"column:ip_address"`
+	UserAgent string    `json:"user_agent" gorm:"column:user_agent"`
+	IDToken   string    `json:"-" gorm:"column:id_token;type:text"` // OIDC ID token, the hint for ending the provider session
 	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
 
@@ -472,13 +637,17 @@ type Session struct {
 type TaskType string
 
 const (
-	TaskTypeCommand TaskType = "command" // Execute an RCON command
-	TaskTypeBackup  TaskType = "backup"  // Create a backup
-	TaskTypeRestart TaskType = "restart" // Restart the server
-	TaskTypeStart   TaskType = "start"   // Start the server
-	TaskTypeStop    TaskType = "stop"    // Stop the server
-	TaskTypeScript  TaskType = "script"  // Run a custom script
-	TaskTypeWebhook TaskType = "webhook" // Send an HTTP webhook
+	TaskTypeCommand      TaskType = "command"       // Execute an RCON command
+	TaskTypeBackup       TaskType = "backup"        // Create a backup
+	TaskTypeRestart      TaskType = "restart"       // Restart the server
+	TaskTypeStart        TaskType = "start"         // Start the server
+	TaskTypeStop         TaskType = "stop"          // Stop the server
+	TaskTypeScript       TaskType = "script"        // Run a custom script
+	TaskTypeWebhook      TaskType = "webhook"       // Send an HTTP webhook
+	TaskTypeImageUpdate  TaskType = "image_update"  // Recreate on a newer image digest
+	TaskTypeBackupVerify TaskType = "backup_verify" // Check recent backups are restorable
+	TaskTypeBroadcast    TaskType = "broadcast"     // Announce a message in chat with say
+	TaskTypePregenerate  TaskType = "pregenerate"   // Pre-generate chunks with Chunky
 )
 
 // TaskStatus defines the status of a scheduled task
@@ -527,7 +696,11 @@ type ScheduledTask struct {
 	RetryCount    int  `json:"retry_count" gorm:"default:0"`        // Number of retries on failure
 	RetryDelay    int  `json:"retry_delay" gorm:"default:60"`       // Delay between retries in seconds
 	RequireOnline bool `json:"require_online" gorm:"default:true"`  // Only run if server is online
-	FailureNotify bool `json:"failure_notify" gorm:"default:false"` // Notify on failure (future feature)
+	FailureNotify bool `json:"failure_notify" gorm:"default:false"` // Post failed and skipped runs to the notify webhook
+	SuccessNotify bool `json:"success_notify" gorm:"default:false"` // Post completed runs to the notify webhook
+
+	// Result webhook, empty falls back to tasks.notify_url
+	NotifyURL string `json:"notify_url" gorm:"column:notify_url"`
 
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
@@ -578,12 +751,28 @@ const (
 type ModuleStatus string
 
 const (
-	ModuleStatusStopped  ModuleStatus = "stopped"
-	ModuleStatusStarting ModuleStatus = "starting"
-	ModuleStatusRunning  ModuleStatus = "running"
-	ModuleStatusStopping ModuleStatus = "stopping"
-	ModuleStatusError    ModuleStatus = "error"
-	ModuleStatusCreating ModuleStatus = "creating"
+	ModuleStatusStopped   ModuleStatus = "stopped"
+	ModuleStatusStarting  ModuleStatus = "starting"
+	ModuleStatusRunning   ModuleStatus = "running"
+	ModuleStatusStopping  ModuleStatus = "stopping"
+	ModuleStatusError     ModuleStatus = "error"
+	ModuleStatusCreating  ModuleStatus = "creating"
+	ModuleStatusUnhealthy ModuleStatus = "unhealthy"
+)
+
+// IsUp reports whether the module's container is running, healthy or not
+func (s ModuleStatus) IsUp() bool {
+	return s == ModuleStatusRunning || s == ModuleStatusUnhealthy
+}
+
+// Module restart policies
+const (
+	// Never restarted, neither by Docker nor after failed health checks
+	ModuleRestartNo = "no"
+	// Restarted when the container exits with an error or keeps failing health checks
+	ModuleRestartOnFailure = "on-failure"
+	// Restarted whenever the container exits and when it keeps failing health checks
+	ModuleRestartAlways = "always"
 )
 
 // ModuleTemplate represents a blueprint for creating modules
@@ -593,7 +782,8 @@ type ModuleTemplate struct {
 	Description     string             `json:"description"`
 	Type            ModuleTemplateType `json:"type" gorm:"not null;default:custom"`
 	DockerImage     string             `json:"docker_image" gorm:"not null;column:docker_image"`
-	DefaultEnv      string             `json:"default_env" gorm:"type:text;column:default_env"` // JSON map of default env vars
+	ConfigSchema    string             `json:"config_schema" gorm:"type:text;column:config_schema"` // JSON Schema describing the env vars
+	DefaultEnv      string             `json:"default_env" gorm:"type:text;column:default_env"`     // JSON map of default env vars
 	DefaultVolumes  string             `json:"default_volumes" gorm:"type:text;column:default_volumes"`
 	HealthCheckPath string             `json:"health_check_path" gorm:"column:health_check_path"`
 	HealthCheckPort int                `json:"health_check_port" gorm:"column:health_check_port"`
@@ -680,6 +870,10 @@ type Module struct {
 	HealthCheckTimeout  int `json:"health_check_timeout" gorm:"column:health_check_timeout;default:5"`
 	HealthCheckRetries  int `json:"health_check_retries" gorm:"column:health_check_retries;default:3"`
 
+	// Restart policy, one of the ModuleRestart values. Empty keeps Docker's unless-stopped
+	// policy and never restarts on failed health checks.
+	RestartPolicy string `json:"restart_policy" gorm:"column:restart_policy;default:''"`
+
 	// Event hooks for server lifecycle integration
 	EventHooks []*v1.ModuleEventHook `json:"event_hooks" gorm:"column:event_hooks;serializer:json"`
 
@@ -702,6 +896,11 @@ type Module struct {
 	TokenID        string `json:"token_id" gorm:"column:token_id"`
 	TokenPlaintext string `json:"-" gorm:"column:token_plaintext"`
 
+	// Hash of the read-only panel token for the module's server, e.g. for the status panel.
+	// Every new container gets a fresh token, the plaintext is only held while building it.
+	PanelTokenHash string `json:"-" gorm:"column:panel_token_hash;index"`
+	PanelToken     string `json:"-" gorm:"-"`
+
 	// Relationships
 	Server   *Server         `json:"-" gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE"`
 	Template *ModuleTemplate `json:"-" gorm:"foreignKey:TemplateID;constraint:OnDelete:RESTRICT"`
@@ -710,3 +909,45 @@ type Module struct {
 	MemoryUsage float64 `json:"memory_usage" gorm:"-"`
 	CPUPercent  float64 `json:"cpu_percent" gorm:"-"`
 }
+
+// ConsoleHistory records a command sent from the interactive RCON console
+type ConsoleHistory struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	ServerID  string    `json:"server_id" gorm:"not null;index;column:server_id"`
+	UserID    string    `json:"user_id" gorm:"column:user_id"`
+	Username  string    `json:"username"`
+	Command   string    `json:"command" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	Server *Server `json:"-" gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE"`
+}
+
+// ServerACL grants a single user access to a single server on top of their roles
+type ServerACL struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	ServerID  string    `json:"server_id" gorm:"not null;uniqueIndex:idx_server_acl_user;column:server_id"`
+	UserID    string    `json:"user_id" gorm:"not null;uniqueIndex:idx_server_acl_user;index;column:user_id"`
+	Level     string    `json:"level" gorm:"not null"` // viewer, operator or editor
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Server *Server `json:"-" gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE"`
+	User   *User   `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// AuditLog records an administrative or lifecycle action
+type AuditLog struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	UserID     string    `json:"user_id" gorm:"index;column:user_id"`
+	Username   string    `json:"username"`
+	Action     string    `json:"action" gorm:"not null;index"` // ie: ServerService/CreateServer, oidc/login
+	TargetType string    `json:"target_type" gorm:"column:target_type"`
+	TargetID   string    `json:"target_id" gorm:"column:target_id;index"`
+	IP         string    `json:"ip" gorm:"column:ip"`
+	Success    bool      `json:"success"`
+	Detail     string    `json:"detail" gorm:"type:text"` // JSON object
+
+	// Owner of the target server at the time, when the action was on a server
+	OwnerID string `json:"owner_id" gorm:"column:owner_id;index"`
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Built-in presets, refreshed on every start so changes here reach existing installs
+var builtinConfigPresets = []ConfigPreset{
+	{
+		ID:          "builtin-aikar-paper",
+		Name:        "Aikar-tuned Paper",
+		Description: "Aikar's G1GC flags with trimmed view and simulation distances for steady TPS on Paper servers",
+		Values: map[string]string{
+			"useAikarFlags":      "true",
+			"viewDistance":       "8",
+			"simulationDistance": "6",
+			"syncChunkWrites":    "false",
+		},
+	},
+	{
+		ID:          "builtin-large-modpack",
+		Name:        "Large modpack",
+		Description: "Aikar's flags, shorter distances, more parallel downloads and a longer shutdown window for heavy modpacks",
+		Values: map[string]string{
+			"useAikarFlags":       "true",
+			"viewDistance":        "8",
+			"simulationDistance":  "6",
+			"cfParallelDownloads": "8",
+			"stopDuration":        "180",
+			"enableRollingLogs":   "true",
+		},
+	},
+}
+
+// SeedConfigPresets creates/updates the built-in config presets
+func (s *Store) SeedConfigPresets() error {
+	ctx := context.Background()
+	for _, preset := range builtinConfigPresets {
+		preset.Type = ConfigPresetTypeBuiltin
+		if err := s.UpsertConfigPreset(ctx, &preset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfigPreset operations
+func (s *Store) CreateConfigPreset(ctx context.Context, preset *ConfigPreset) error {
+	if preset.ID == "" {
+		preset.ID = uuid.New().String()
+	}
+	return s.db.WithContext(ctx).Create(preset).Error
+}
+
+func (s *Store) GetConfigPreset(ctx context.Context, id string) (*ConfigPreset, error) {
+	var preset ConfigPreset
+	err := s.db.WithContext(ctx).First(&preset, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("config preset not found")
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+func (s *Store) ListConfigPresets(ctx context.Context) ([]*ConfigPreset, error) {
+	var presets []*ConfigPreset
+	err := s.db.WithContext(ctx).Order("type ASC, name ASC").Find(&presets).Error
+	return presets, err
+}
+
+func (s *Store) DeleteConfigPreset(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&ConfigPreset{}, "id = ?", id).Error
+}
+
+// UpsertConfigPreset creates or updates a config preset by ID
+func (s *Store) UpsertConfigPreset(ctx context.Context, preset *ConfigPreset) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing ConfigPreset
+		err := tx.Where("id = ?", preset.ID).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			return tx.Create(preset).Error
+		}
+		if err != nil {
+			return err
+		}
+		preset.CreatedAt = existing.CreatedAt
+		return tx.Model(&existing).Select("*").Omit("created_at").Updates(preset).Error
+	})
+}
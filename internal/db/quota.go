@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+)
+
+// Quota limits what a user can provision, 0 means unlimited
+type Quota struct {
+	MaxServers int
+	MaxMemory  int // MB, across servers and modules
+	MaxModules int
+}
+
+// QuotaUsage is what a user has provisioned so far
+type QuotaUsage struct {
+	Servers int
+	Memory  int // MB
+	Modules int
+}
+
+// GetUserQuota resolves the effective quota for a user holding roleNames. Per-user
+// overrides win, otherwise each limit is the most generous of the roles, where any
+// unlimited role wins. Users without a record (e.g. anonymous) only get role limits.
+func (s *Store) GetUserQuota(ctx context.Context, userID string, roleNames []string) (*Quota, error) {
+	var roles []*Role
+	if len(roleNames) > 0 {
+		if err := s.db.WithContext(ctx).Where("name IN ?", roleNames).Find(&roles).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	quota := &Quota{
+		MaxServers: mostGenerousLimit(roles, func(r *Role) int { return r.MaxServers }),
+		MaxMemory:  mostGenerousLimit(roles, func(r *Role) int { return r.MaxMemory }),
+		MaxModules: mostGenerousLimit(roles, func(r *Role) int { return r.MaxModules }),
+	}
+
+	var user User
+	if err := s.db.WithContext(ctx).Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	if user.MaxServers != nil {
+		quota.MaxServers = *user.MaxServers
+	}
+	if user.MaxMemory != nil {
+		quota.MaxMemory = *user.MaxMemory
+	}
+	if user.MaxModules != nil {
+		quota.MaxModules = *user.MaxModules
+	}
+	return quota, nil
+}
+
+// GetUserQuotaUsage totals the servers a user owns and the modules they created
+func (s *Store) GetUserQuotaUsage(ctx context.Context, userID string) (*QuotaUsage, error) {
+	var servers []*Server
+	if err := s.db.WithContext(ctx).Select("memory").Where("owner_id = ?", userID).Find(&servers).Error; err != nil {
+		return nil, err
+	}
+	var modules []*Module
+	if err := s.db.WithContext(ctx).Select("memory").Where("created_by = ?", userID).Find(&modules).Error; err != nil {
+		return nil, err
+	}
+
+	usage := &QuotaUsage{Servers: len(servers), Modules: len(modules)}
+	for _, server := range servers {
+		usage.Memory += server.Memory
+	}
+	for _, module := range modules {
+		usage.Memory += module.Memory
+	}
+	return usage, nil
+}
+
+// A user without roles is unlimited, as is a user with any unlimited role
+func mostGenerousLimit(roles []*Role, limit func(*Role) int) int {
+	most := 0
+	for i, role := range roles {
+		l := limit(role)
+		if l <= 0 {
+			return 0
+		}
+		if i == 0 || l > most {
+			most = l
+		}
+	}
+	return most
+}
@@ -3,13 +3,16 @@ package db
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/google/uuid"
 	"github.com/nickheyer/discopanel/internal/config"
 	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -20,8 +23,17 @@ type Store struct {
 	cfg *config.Config
 }
 
-func NewSQLiteStore(cfg *config.Config) (*Store, error) {
-	db, err := gorm.Open(sqlite.Open(cfg.Database.Path), &gorm.Config{
+// NewStore opens the database picked by cfg.Database.Driver, SQLite unless configured otherwise
+func NewStore(cfg *config.Config) (*Store, error) {
+	var dialector gorm.Dialector
+	switch cfg.Database.Driver {
+	case "postgres":
+		dialector = postgres.Open(cfg.Database.DSN)
+	default:
+		dialector = sqlite.Open(cfg.Database.Path)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
@@ -119,11 +131,25 @@ func (s *Store) DeleteServer(ctx context.Context, id string) error {
 			return err
 		}
 
+		// Delete console history
+		if err := tx.Where("server_id = ?", id).Delete(&ConsoleHistory{}).Error; err != nil {
+			return err
+		}
+
+		// Delete access grants
+		if err := tx.Where("server_id = ?", id).Delete(&ServerACL{}).Error; err != nil {
+			return err
+		}
+
 		// Delete server
 		return tx.Delete(&Server{}, "id = ?", id).Error
 	})
 }
 
+func (s *Store) SetServerPortBindingWarning(ctx context.Context, serverID, warning string) error {
+	return s.db.WithContext(ctx).Model(&Server{}).Where("id = ?", serverID).Update("port_binding_warning", warning).Error
+}
+
 func (s *Store) GetServerByPort(ctx context.Context, port int) (*Server, error) {
 	var server Server
 	// Only check servers that don't have a proxy hostname (i.e., servers that actually bind to the port)
@@ -358,7 +384,8 @@ func (s *Store) SearchIndexedModpacks(ctx context.Context, query string, gameVer
 	db := s.db.WithContext(ctx).Model(&IndexedModpack{})
 
 	if query != "" {
-		db = db.Where("name LIKE ? OR summary LIKE ?", "%"+query+"%", "%"+query+"%")
+		// LIKE is case-sensitive on Postgres, lower both sides to match SQLite
+		db = db.Where("LOWER(name) LIKE LOWER(?) OR LOWER(summary) LIKE LOWER(?)", "%"+query+"%", "%"+query+"%")
 	}
 
 	if gameVersion != "" {
@@ -366,7 +393,7 @@ func (s *Store) SearchIndexedModpacks(ctx context.Context, query string, gameVer
 	}
 
 	if modLoader != "" {
-		db = db.Where("mod_loaders LIKE ?", "%"+modLoader+"%")
+		db = db.Where("LOWER(mod_loaders) LIKE LOWER(?)", "%"+modLoader+"%")
 	}
 
 	if indexer != "" {
@@ -720,6 +747,13 @@ func (s *Store) GetUserByUsernameAndProvider(ctx context.Context, username, prov
 	return &user, nil
 }
 
+// Usernames are only unique per auth provider
+func (s *Store) ListUsersByUsername(ctx context.Context, username string) ([]*User, error) {
+	var users []*User
+	err := s.db.WithContext(ctx).Where("username = ?", username).Find(&users).Error
+	return users, err
+}
+
 func (s *Store) GetUserByOIDCSubject(ctx context.Context, subject string) (*User, error) {
 	var user User
 	err := s.db.WithContext(ctx).First(&user, "oidc_subject = ?", subject).Error
@@ -750,6 +784,12 @@ func (s *Store) DeleteUser(ctx context.Context, id string) error {
 		if err := tx.Where("user_id = ?", id).Delete(&APIToken{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("user_id = ?", id).Delete(&Passkey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&UserTOTP{}).Error; err != nil {
+			return err
+		}
 		if err := tx.Where("user_id = ?", id).Delete(&UserRole{}).Error; err != nil {
 			return err
 		}
@@ -891,6 +931,36 @@ func (s *Store) DeleteSession(ctx context.Context, token string) error {
 	return s.db.WithContext(ctx).Where("token = ?", token).Delete(&Session{}).Error
 }
 
+// ListUserSessions returns a user's unexpired sessions, newest first
+func (s *Store) ListUserSessions(ctx context.Context, userID string) ([]*Session, error) {
+	var sessions []*Session
+	err := s.db.WithContext(ctx).Preload("User").
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (s *Store) GetSessionByID(ctx context.Context, id string) (*Session, error) {
+	var session Session
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *Store) DeleteSessionByID(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Where("id = ?", id).Delete(&Session{}).Error
+}
+
+// DeleteUserSessionsExcept removes every session of a user other than keepID, returning the count
+func (s *Store) DeleteUserSessionsExcept(ctx context.Context, userID, keepID string) (int64, error) {
+	result := s.db.WithContext(ctx).Where("user_id = ? AND id != ?", userID, keepID).Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
 func (s *Store) CleanExpiredSessions(ctx context.Context) error {
 	return s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&Session{}).Error
 }
@@ -909,6 +979,12 @@ func (s *Store) ResetAllUsers(ctx context.Context) error {
 		if err := tx.Where("1 = 1").Delete(&APIToken{}).Error; err != nil {
 			return fmt.Errorf("failed to delete api tokens: %w", err)
 		}
+		if err := tx.Where("1 = 1").Delete(&Passkey{}).Error; err != nil {
+			return fmt.Errorf("failed to delete passkeys: %w", err)
+		}
+		if err := tx.Where("1 = 1").Delete(&UserTOTP{}).Error; err != nil {
+			return fmt.Errorf("failed to delete totp enrollments: %w", err)
+		}
 		if err := tx.Where("1 = 1").Delete(&UserRole{}).Error; err != nil {
 			return fmt.Errorf("failed to delete user roles: %w", err)
 		}
@@ -967,6 +1043,105 @@ func (s *Store) UpdateAPITokenLastUsed(ctx context.Context, id string) error {
 	return s.db.WithContext(ctx).Model(&APIToken{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error
 }
 
+// Passkey operations
+func (s *Store) CreatePasskey(ctx context.Context, passkey *Passkey) error {
+	if passkey.ID == "" {
+		passkey.ID = uuid.New().String()
+	}
+	return s.db.WithContext(ctx).Create(passkey).Error
+}
+
+func (s *Store) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*Passkey, error) {
+	var passkey Passkey
+	err := s.db.WithContext(ctx).Where("credential_id = ?", credentialID).First(&passkey).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("passkey not found")
+		}
+		return nil, err
+	}
+	return &passkey, nil
+}
+
+func (s *Store) ListPasskeysByUser(ctx context.Context, userID string) ([]Passkey, error) {
+	var passkeys []Passkey
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&passkeys).Error
+	return passkeys, err
+}
+
+func (s *Store) DeletePasskey(ctx context.Context, id, userID string) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&Passkey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("passkey not found")
+	}
+	return nil
+}
+
+// Records a successful sign-in with the authenticator's new signature counter
+func (s *Store) UpdatePasskeyUsage(ctx context.Context, id string, signCount uint32) error {
+	return s.db.WithContext(ctx).Model(&Passkey{}).Where("id = ?", id).Updates(map[string]any{
+		"sign_count":   signCount,
+		"last_used_at": time.Now(),
+	}).Error
+}
+
+// TOTP operations
+func (s *Store) GetUserTOTP(ctx context.Context, userID string) (*UserTOTP, error) {
+	var totp UserTOTP
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&totp).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("totp not found")
+		}
+		return nil, err
+	}
+	return &totp, nil
+}
+
+func (s *Store) SaveUserTOTP(ctx context.Context, totp *UserTOTP) error {
+	return s.db.WithContext(ctx).Save(totp).Error
+}
+
+func (s *Store) DeleteUserTOTP(ctx context.Context, userID string) error {
+	return s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&UserTOTP{}).Error
+}
+
+// Records an accepted time step, false when that step or a later one was already used
+func (s *Store) ConsumeTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Removes a recovery code by hash, false when it isn't one of the user's unused codes
+func (s *Store) ConsumeTOTPRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	consumed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var totp UserTOTP
+		if err := tx.Where("user_id = ?", userID).First(&totp).Error; err != nil {
+			return err
+		}
+		remaining := make([]string, 0, len(totp.RecoveryCodes))
+		for _, hash := range totp.RecoveryCodes {
+			if hash == codeHash && !consumed {
+				consumed = true
+				continue
+			}
+			remaining = append(remaining, hash)
+		}
+		if !consumed {
+			return nil
+		}
+		totp.RecoveryCodes = remaining
+		return tx.Save(&totp).Error
+	})
+	return consumed, err
+}
+
 // RegistrationInvite operations
 func (s *Store) CreateRegistrationInvite(ctx context.Context, invite *RegistrationInvite) error {
 	if invite.ID == "" {
@@ -1277,6 +1452,22 @@ func (s *Store) GetModule(ctx context.Context, id string) (*Module, error) {
 	return &module, nil
 }
 
+func (s *Store) GetModuleByPanelTokenHash(ctx context.Context, hash string) (*Module, error) {
+	var module Module
+	err := s.db.WithContext(ctx).First(&module, "panel_token_hash = ?", hash).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("module not found")
+		}
+		return nil, err
+	}
+	return &module, nil
+}
+
+func (s *Store) SetModulePanelTokenHash(ctx context.Context, moduleID, hash string) error {
+	return s.db.WithContext(ctx).Model(&Module{}).Where("id = ?", moduleID).Update("panel_token_hash", hash).Error
+}
+
 func (s *Store) ListModules(ctx context.Context) ([]*Module, error) {
 	var modules []*Module
 	err := s.db.WithContext(ctx).Order("created_at DESC").Find(&modules).Error
@@ -1289,6 +1480,68 @@ func (s *Store) ListServerModules(ctx context.Context, serverID string) ([]*Modu
 	return modules, err
 }
 
+// RelocateDataPaths repoints every stored path under oldDir at the same place under newDir,
+// after the data directory was moved. Paths outside oldDir are left alone.
+func (s *Store) RelocateDataPaths(ctx context.Context, oldDir, newDir string) error {
+	rebase := func(path string) (string, bool) {
+		if path == "" {
+			return "", false
+		}
+		rel, err := filepath.Rel(oldDir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", false
+		}
+		return filepath.Join(newDir, rel), true
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var servers []*Server
+		if err := tx.Find(&servers).Error; err != nil {
+			return err
+		}
+		for _, server := range servers {
+			if path, ok := rebase(server.DataPath); ok {
+				if err := tx.Model(&Server{}).Where("id = ?", server.ID).Update("data_path", path).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var modules []*Module
+		if err := tx.Find(&modules).Error; err != nil {
+			return err
+		}
+		for _, module := range modules {
+			if path, ok := rebase(module.DataPath); ok {
+				if err := tx.Model(&Module{}).Where("id = ?", module.ID).Update("data_path", path).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var listeners []*ProxyListener
+		if err := tx.Find(&listeners).Error; err != nil {
+			return err
+		}
+		for _, listener := range listeners {
+			updates := map[string]any{}
+			if path, ok := rebase(listener.TLSCertPath); ok {
+				updates["tls_cert_path"] = path
+			}
+			if path, ok := rebase(listener.TLSKeyPath); ok {
+				updates["tls_key_path"] = path
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if err := tx.Model(&ProxyListener{}).Where("id = ?", listener.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (s *Store) ListModulesByTemplate(ctx context.Context, templateID string) ([]*Module, error) {
 	var modules []*Module
 	err := s.db.WithContext(ctx).Where("template_id = ?", templateID).Order("created_at DESC").Find(&modules).Error
@@ -1324,6 +1577,8 @@ func (s *Store) GetModuleByHostPort(ctx context.Context, port int) (*Module, err
 // PortConflict represents a port conflict with details
 type PortConflict struct {
 	Module   *Module
+	Server   *Server
+	Listener *ProxyListener
 	Port     int
 	Protocol string
 	Reason   string
@@ -1389,6 +1644,76 @@ func (s *Store) CheckPortAvailability(ctx context.Context, hostPort int, protoco
 	return nil, nil
 }
 
+// CheckServerPortAvailability checks if a host port can be published by a server container.
+// Server ports always bind directly, so they collide with any module, listener or other
+// server claiming the same port and protocol. excludeServerID skips the server being updated.
+func (s *Store) CheckServerPortAvailability(ctx context.Context, hostPort int, protocol string, excludeServerID string) (*PortConflict, error) {
+	conflict, err := s.CheckPortAvailability(ctx, hostPort, protocol, false, "", "")
+	if err != nil || conflict != nil {
+		return conflict, err
+	}
+
+	var servers []*Server
+	if err := s.db.WithContext(ctx).Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	for _, server := range servers {
+		if server.ID == excludeServerID {
+			continue
+		}
+
+		if protocol == "tcp" && server.ProxyHostname == "" && server.Port == hostPort {
+			return &PortConflict{
+				Server:   server,
+				Port:     hostPort,
+				Protocol: protocol,
+				Reason:   "port is the game port of another server",
+			}, nil
+		}
+
+		for _, p := range server.AdditionalPorts {
+			if p == nil || int(p.HostPort) != hostPort {
+				continue
+			}
+			existingProtocol := p.Protocol
+			if existingProtocol == "" {
+				existingProtocol = "tcp"
+			}
+			if existingProtocol == protocol {
+				return &PortConflict{
+					Server:   server,
+					Port:     hostPort,
+					Protocol: protocol,
+					Reason:   "port is published by another server",
+				}, nil
+			}
+		}
+	}
+
+	var listeners []*ProxyListener
+	if err := s.db.WithContext(ctx).Find(&listeners).Error; err != nil {
+		return nil, err
+	}
+
+	for _, listener := range listeners {
+		listenerProtocol := string(listener.Protocol)
+		if listenerProtocol == "" {
+			listenerProtocol = "tcp"
+		}
+		if listener.Port == hostPort && listenerProtocol == protocol {
+			return &PortConflict{
+				Listener: listener,
+				Port:     hostPort,
+				Protocol: protocol,
+				Reason:   "port is used by a proxy listener",
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
 func (s *Store) GetModuleByContainerID(ctx context.Context, containerID string) (*Module, error) {
 	var module Module
 	err := s.db.WithContext(ctx).Where("container_id = ?", containerID).First(&module).Error
@@ -1435,3 +1760,160 @@ func (s *Store) ListEventTriggeredTasks(ctx context.Context, serverID string, ev
 	}
 	return matching, nil
 }
+
+// ConsoleHistory operations
+func (s *Store) AddConsoleHistory(ctx context.Context, entry *ConsoleHistory, keep int) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		if keep <= 0 {
+			return nil
+		}
+		// Trim anything older than the newest `keep` entries for this server
+		stale := tx.Model(&ConsoleHistory{}).Select("id").
+			Where("server_id = ?", entry.ServerID).
+			Order("created_at DESC").Offset(keep).Limit(-1)
+		return tx.Where("id IN (?)", stale).Delete(&ConsoleHistory{}).Error
+	})
+}
+
+// ListConsoleHistory returns the most recent console commands for a server, oldest first
+func (s *Store) ListConsoleHistory(ctx context.Context, serverID string, limit int) ([]*ConsoleHistory, error) {
+	var entries []*ConsoleHistory
+	query := s.db.WithContext(ctx).Where("server_id = ?", serverID).Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// ServerACL operations
+func (s *Store) SetServerACL(ctx context.Context, serverID, userID, level string) (*ServerACL, error) {
+	var acl ServerACL
+	err := s.db.WithContext(ctx).Where("server_id = ? AND user_id = ?", serverID, userID).First(&acl).Error
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		acl = ServerACL{ID: uuid.New().String(), ServerID: serverID, UserID: userID}
+	}
+	acl.Level = level
+	if err := s.db.WithContext(ctx).Save(&acl).Error; err != nil {
+		return nil, err
+	}
+	return &acl, nil
+}
+
+func (s *Store) DeleteServerACL(ctx context.Context, serverID, userID string) error {
+	result := s.db.WithContext(ctx).Where("server_id = ? AND user_id = ?", serverID, userID).Delete(&ServerACL{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("server acl not found")
+	}
+	return nil
+}
+
+// ListServerACLs returns all grants on a server with their users preloaded
+func (s *Store) ListServerACLs(ctx context.Context, serverID string) ([]*ServerACL, error) {
+	var acls []*ServerACL
+	err := s.db.WithContext(ctx).Preload("User").Where("server_id = ?", serverID).Order("created_at ASC").Find(&acls).Error
+	return acls, err
+}
+
+// GetUserServerACLs maps server IDs to the access level granted to a user.
+// Servers the user owns are granted editor access.
+func (s *Store) GetUserServerACLs(ctx context.Context, userID string) (map[string]string, error) {
+	var acls []*ServerACL
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&acls).Error; err != nil {
+		return nil, err
+	}
+	var owned []string
+	if err := s.db.WithContext(ctx).Model(&Server{}).Where("owner_id = ?", userID).Pluck("id", &owned).Error; err != nil {
+		return nil, err
+	}
+	levels := make(map[string]string, len(acls)+len(owned))
+	for _, acl := range acls {
+		levels[acl.ServerID] = acl.Level
+	}
+	for _, serverID := range owned {
+		levels[serverID] = "editor"
+	}
+	return levels, nil
+}
+
+// AuditLog operations
+func (s *Store) CreateAuditLogs(ctx context.Context, entries []*AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).CreateInBatches(entries, 100).Error
+}
+
+// AuditLogFilter narrows an audit log query; zero values match everything
+type AuditLogFilter struct {
+	UserID        string
+	ExcludeUserID string // Skip entries by this user, e.g. an owner's own actions
+	OwnerID       string
+	TargetID      string
+	Action        string // Substring match
+	From          *time.Time
+	To            *time.Time
+	Limit         int
+	Offset        int
+}
+
+// ListAuditLogs returns matching entries newest first, with the total match count
+func (s *Store) ListAuditLogs(ctx context.Context, filter AuditLogFilter) ([]*AuditLog, int64, error) {
+	query := s.db.WithContext(ctx).Model(&AuditLog{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ExcludeUserID != "" {
+		query = query.Where("user_id <> ?", filter.ExcludeUserID)
+	}
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("LOWER(action) LIKE LOWER(?)", "%"+filter.Action+"%")
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []*AuditLog
+	query = query.Order("created_at DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -8,9 +8,12 @@ import (
 	"io"
 	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +28,7 @@ import (
 	"github.com/docker/go-connections/nat"
 	models "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/minecraft"
+	"github.com/nickheyer/discopanel/pkg/files"
 	"github.com/nickheyer/discopanel/pkg/logger"
 	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
 )
@@ -37,13 +41,16 @@ const (
 	dockerImagesCacheDuration = time.Hour
 
 	// Default Minecraft server port inside containers
-	DefaultMinecraftPort = 25565
+	DefaultMinecraftPort = models.DefaultJavaPort
 
 	// Default RCON port inside containers
 	DefaultRCONPort = 25575
 
 	// Offset added to game port for RCON host binding
 	RCONPortOffset = 10
+
+	// Minecraft server image repository
+	MinecraftImageRepo = "itzg/minecraft-server"
 )
 
 type ContainerStats struct {
@@ -181,6 +188,14 @@ type ClientConfig struct {
 	RegistryURL string
 	DNS         string
 	Labels      map[string]string
+	TLSCACert   string
+	TLSCert     string
+	TLSKey      string
+	Runtime     string
+	// How long a boot may go without a ready log line before it counts as running
+	StartupTimeout time.Duration
+	// Docker's volume directory as mounted into DiscoPanel, empty when running on the host
+	VolumesDir string
 }
 
 type ContainerLogStreamer interface {
@@ -193,7 +208,16 @@ type Client struct {
 	docker      *client.Client
 	config      ClientConfig
 	logStreamer ContainerLogStreamer
+	startup     *StartupTracker
 	log         *logger.Logger
+	podman      bool
+	rootless    bool
+
+	networkMu  sync.RWMutex
+	networkErr error
+
+	digestMu sync.Mutex
+	digests  map[string]registryDigest // Registry lookups by image reference
 }
 
 // Auto manage streams at the client level when set
@@ -201,6 +225,16 @@ func (c *Client) SetLogStreamer(ls ContainerLogStreamer) {
 	c.logStreamer = ls
 }
 
+// Returns the tracker fed by server log lines
+func (c *Client) Startup() *StartupTracker {
+	return c.startup
+}
+
+// Returns the log-derived boot phase for a server
+func (c *Client) StartupPhase(serverID, containerID string) models.StartupPhase {
+	return c.startup.ServerPhase(serverID, containerID)
+}
+
 func NewClient(host string, log *logger.Logger, config ...ClientConfig) (*Client, error) {
 	opts := []client.Opt{
 		client.FromEnv,
@@ -212,16 +246,34 @@ func NewClient(host string, log *logger.Logger, config ...ClientConfig) (*Client
 		opts = append(opts, client.WithVersion(config[0].APIVersion))
 	}
 
-	if host != "" && host != "unix:///var/run/docker.sock" {
+	// Podman serves the Docker API on its own socket
+	if len(config) > 0 && config[0].Runtime == RuntimePodman && os.Getenv("DOCKER_HOST") == "" &&
+		(host == "" || host == "unix:///var/run/docker.sock") {
+		host = PodmanSocketPath()
+		opts = append(opts, client.WithHost(host))
+	} else if host != "" && host != "unix:///var/run/docker.sock" {
 		opts = append(opts, client.WithHost(host))
 	}
 
+	// Mutual TLS for remote daemons (tcp://host:2376)
+	if len(config) > 0 && (config[0].TLSCACert != "" || config[0].TLSCert != "") {
+		opts = append(opts, client.WithTLSClientConfig(config[0].TLSCACert, config[0].TLSCert, config[0].TLSKey))
+	}
+
 	docker, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
-	c := &Client{docker: docker, log: log}
+	var startupTimeout time.Duration
+	if len(config) > 0 {
+		startupTimeout = config[0].StartupTimeout
+	}
+
+	c := &Client{docker: docker, startup: NewStartupTracker(startupTimeout), log: log, digests: make(map[string]registryDigest)}
+	if c.IsRemote() {
+		log.Info("Using remote Docker daemon at %s", docker.DaemonHost())
+	}
 	if len(config) > 0 {
 		c.config = config[0]
 	} else {
@@ -230,6 +282,7 @@ func NewClient(host string, log *logger.Logger, config ...ClientConfig) (*Client
 			NetworkName: "discopanel-network",
 		}
 	}
+	c.detectRuntime()
 
 	return c, nil
 }
@@ -238,6 +291,24 @@ func (c *Client) Close() error {
 	return c.docker.Close()
 }
 
+// Reports whether the daemon is reached over the network rather than a local socket
+func (c *Client) IsRemote() bool {
+	host := c.docker.DaemonHost()
+	return !strings.HasPrefix(host, "unix://") && !strings.HasPrefix(host, "npipe://")
+}
+
+// Host name of a remote daemon, where the ports it publishes are reached. Empty for a local socket.
+func (c *Client) RemoteHost() string {
+	if !c.IsRemote() {
+		return ""
+	}
+	u, err := url.Parse(c.docker.DaemonHost())
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
 // Get the docker client instance from the client object
 func (c *Client) GetDockerClient() *client.Client {
 	return c.docker
@@ -361,15 +432,22 @@ func ApplyOverrides(overrides *v1.DockerOverrides, config *container.Config, hos
 
 func (c *Client) CreateContainer(ctx context.Context, server *models.Server, serverConfig *models.ServerConfig) (string, error) {
 	// Use server's DockerImage if specified, otherwise determine based on version and loader
-	var imageName string
-	if server.DockerImage != "" {
-		imageName = "itzg/minecraft-server:" + server.DockerImage
-	} else {
-		imageName = getDockerImage(server.ModLoader, server.MCVersion)
+	imageName := ServerImage(server)
+
+	if err := c.requireNetwork(); err != nil {
+		return "", err
+	}
+
+	// Fail fast on tags that no longer exist instead of surfacing a generic pull error
+	if err := c.ValidateImage(ctx, imageName); err != nil {
+		return "", err
 	}
 
 	// Try pulling latest
-	if err := c.pullImage(ctx, imageName); err != nil {
+	c.startup.setPulling(server.ID, true)
+	err := c.pullImage(ctx, imageName)
+	c.startup.setPulling(server.ID, false)
+	if err != nil {
 		return "", fmt.Errorf("failed to pull image: %w", err)
 	}
 
@@ -378,9 +456,8 @@ func (c *Client) CreateContainer(ctx context.Context, server *models.Server, ser
 
 	// Determine container port - proxy servers always use default port internally
 	useProxy := server.ProxyHostname != ""
-	containerPort := server.Port
+	containerPort := server.ContainerPort()
 	if useProxy {
-		containerPort = DefaultMinecraftPort
 		// Override SERVER_PORT env var for proxy servers
 		filtered := make([]string, 0, len(env))
 		for _, e := range env {
@@ -434,8 +511,23 @@ func (c *Client) CreateContainer(ctx context.Context, server *models.Server, ser
 	// Handle path translation when DiscoPanel runs in a container
 	dataPath := TranslateToHostPath(server.DataPath)
 
-	if err := os.MkdirAll(server.DataPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create server data directory: %w", err)
+	if server.DataVolume == "" {
+		if err := os.MkdirAll(server.DataPath, 0755); err != nil {
+			return "", fmt.Errorf("failed to create server data directory: %w", err)
+		}
+	}
+
+	// The JVM treats a missing HeapDumpPath directory as a file name
+	if serverConfig.HeapDumpOnOOM != nil && *serverConfig.HeapDumpOnOOM {
+		var err error
+		if server.DataVolume != "" {
+			_, err = c.RunInVolume(ctx, server, `mkdir -p "/data/$1"`, files.CrashDumpDir)
+		} else {
+			err = os.MkdirAll(filepath.Join(server.DataPath, files.CrashDumpDir), 0755)
+		}
+		if err != nil {
+			c.log.Warn("Failed to create crash dump directory: %v", err)
+		}
 	}
 
 	config := &container.Config{
@@ -454,15 +546,19 @@ func (c *Client) CreateContainer(ctx context.Context, server *models.Server, ser
 		},
 	}
 
+	dataMount := mount.Mount{Type: mount.TypeBind, Source: dataPath, Target: "/data", BindOptions: &mount.BindOptions{CreateMountpoint: true}}
+	if server.DataVolume != "" {
+		dataMount = mount.Mount{Type: mount.TypeVolume, Source: server.DataVolume, Target: "/data"}
+	}
+
 	hostConfig := &container.HostConfig{
-		PortBindings: portBindings,
-		Mounts: []mount.Mount{
-			{Type: mount.TypeBind, Source: dataPath, Target: "/data", BindOptions: &mount.BindOptions{CreateMountpoint: true}},
-		},
+		PortBindings:  portBindings,
+		Mounts:        []mount.Mount{dataMount},
 		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
 		Resources: container.Resources{
 			Memory:     int64(server.Memory) * 1024 * 1024,
-			MemorySwap: int64(server.Memory) * 1024 * 1024,
+			MemorySwap: int64(server.Memory+server.SwapLimit) * 1024 * 1024,
+			NanoCPUs:   int64(server.CPULimit * 1e9),
 		},
 		LogConfig: container.LogConfig{
 			Type:   "json-file",
@@ -480,6 +576,16 @@ func (c *Client) CreateContainer(ctx context.Context, server *models.Server, ser
 		maps.Copy(config.Labels, c.config.Labels)
 	}
 
+	// Rootless Podman user namespace and port handling (overrides may still replace it)
+	uid, gid := 1000, 1000
+	if serverConfig.UID != nil {
+		uid = *serverConfig.UID
+	}
+	if serverConfig.GID != nil {
+		gid = *serverConfig.GID
+	}
+	c.applyRootlessHostConfig(hostConfig, uid, gid)
+
 	// Apply docker overrides
 	ApplyOverrides(server.DockerOverrides, config, hostConfig)
 
@@ -503,6 +609,7 @@ func (c *Client) CreateContainer(ctx context.Context, server *models.Server, ser
 }
 
 func (c *Client) StartContainer(ctx context.Context, containerID string) error {
+	c.startup.Reset(containerID)
 	if err := c.docker.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
 		return err
 	}
@@ -553,6 +660,7 @@ func (c *Client) StopContainer(ctx context.Context, containerID string) (bool, e
 }
 
 func (c *Client) RemoveContainer(ctx context.Context, containerID string) error {
+	c.startup.Reset(containerID)
 	return c.docker.ContainerRemove(ctx, containerID, container.RemoveOptions{
 		Force: true,
 	})
@@ -581,8 +689,9 @@ func (c *Client) RestartContainer(ctx context.Context, containerID string, delay
 
 // Result of a container recreation operation
 type RecreateContainerResult struct {
-	NewContainerID string
-	WasRunning     bool
+	NewContainerID     string
+	WasRunning         bool
+	PortBindingWarning string // How the new container's ports differ from the server's, empty when they match
 }
 
 // Stops, removes, and creates a new container - Returns new container ID and whether it was running before
@@ -615,6 +724,7 @@ func (c *Client) RecreateContainer(ctx context.Context, oldContainerID string, s
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
 	result.NewContainerID = newContainerID
+	result.PortBindingWarning = c.portBindingWarning(ctx, newContainerID, server)
 
 	// Migrate log subscribers from old to new container
 	if c.logStreamer != nil && oldContainerID != "" {
@@ -631,6 +741,98 @@ func (c *Client) RecreateContainer(ctx context.Context, oldContainerID string, s
 	return result, nil
 }
 
+// HasStaleGamePortBinding reports whether a container's published ports no longer
+// match the server, see PortBindingMismatch
+func (c *Client) HasStaleGamePortBinding(ctx context.Context, containerID string, server *models.Server) (bool, error) {
+	bindings, err := c.ContainerPortBindings(ctx, containerID)
+	if err != nil {
+		return false, err
+	}
+	return PortBindingMismatch(bindings, server) != "", nil
+}
+
+// Compares a container's published ports with the server, so a recreate that still binds
+// a proxied server's game port is caught right away. Host networking is never flagged.
+func (c *Client) portBindingWarning(ctx context.Context, containerID string, server *models.Server) string {
+	if server.DockerOverrides != nil && server.DockerOverrides.NetworkMode == "host" {
+		return ""
+	}
+	bindings, err := c.ContainerPortBindings(ctx, containerID)
+	if err != nil {
+		c.log.Debug("Failed to inspect port bindings of container %s: %v", containerID, err)
+		return ""
+	}
+	warning := PortBindingMismatch(bindings, server)
+	if warning != "" {
+		c.log.Warn("Port bindings of the new container for server %s don't match: %s", server.Name, warning)
+	}
+	return warning
+}
+
+// ContainerPortBindings returns the host port bindings a container was created with
+func (c *Client) ContainerPortBindings(ctx context.Context, containerID string) (nat.PortMap, error) {
+	inspect, err := c.docker.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	if inspect.HostConfig == nil {
+		return nat.PortMap{}, nil
+	}
+	return inspect.HostConfig.PortBindings, nil
+}
+
+// PortBindingMismatch describes how published ports differ from what the server expects,
+// empty when they match. Proxied servers publish only their additional ports, as players
+// would otherwise bypass the proxy. Direct servers publish their game port exactly once.
+func PortBindingMismatch(bindings nat.PortMap, server *models.Server) string {
+	// Container ports the user deliberately publishes as additional ports
+	extra := make(map[nat.Port]bool)
+	for _, port := range server.AdditionalPorts {
+		protocol := port.GetProtocol()
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		extra[nat.Port(fmt.Sprintf("%d/%s", port.GetContainerPort(), protocol))] = true
+	}
+
+	direct := server.ProxyHostname == ""
+	gameKey := nat.Port(fmt.Sprintf("%d/tcp", server.ContainerPort()))
+	rconKey := nat.Port(fmt.Sprintf("%d/tcp", DefaultRCONPort))
+
+	var unexpected []string
+	for key, published := range bindings {
+		if len(published) == 0 || extra[key] || (direct && (key == gameKey || key == rconKey)) {
+			continue
+		}
+		for _, binding := range published {
+			unexpected = append(unexpected, fmt.Sprintf("%s->%s", binding.HostPort, key))
+		}
+	}
+	slices.Sort(unexpected)
+
+	if !direct {
+		if len(unexpected) > 0 {
+			return fmt.Sprintf("proxied server publishes %s on the host", strings.Join(unexpected, ", "))
+		}
+		return ""
+	}
+
+	game := bindings[gameKey]
+	switch {
+	case len(game) == 0:
+		return fmt.Sprintf("game port %d is not published on the host", server.Port)
+	case len(game) > 1 || game[0].HostPort != strconv.Itoa(server.Port):
+		var hostPorts []string
+		for _, binding := range game {
+			hostPorts = append(hostPorts, binding.HostPort)
+		}
+		return fmt.Sprintf("game port is published on host port %s, expected %d", strings.Join(hostPorts, ", "), server.Port)
+	case len(unexpected) > 0:
+		return fmt.Sprintf("container publishes %s, which the server doesn't configure", strings.Join(unexpected, ", "))
+	}
+	return ""
+}
+
 func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (models.ServerStatus, error) {
 	inspect, err := c.docker.ContainerInspect(ctx, containerID)
 	if err != nil {
@@ -643,6 +845,9 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (mo
 		if inspect.State.Health != nil {
 			switch inspect.State.Health.Status {
 			case "healthy":
+				if c.serverBooting(containerID, inspect) {
+					return models.StatusStarting, nil
+				}
 				return models.StatusRunning, nil
 			case "starting":
 				return models.StatusStarting, nil
@@ -651,9 +856,15 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (mo
 				return models.StatusUnhealthy, nil
 			default:
 				// No health status or unknown, assume running
+				if c.serverBooting(containerID, inspect) {
+					return models.StatusStarting, nil
+				}
 				return models.StatusRunning, nil
 			}
 		}
+		if c.serverBooting(containerID, inspect) {
+			return models.StatusStarting, nil
+		}
 		return models.StatusRunning, nil
 	case "restarting":
 		return models.StatusStarting, nil
@@ -666,6 +877,40 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (mo
 	}
 }
 
+// ContainerExits is how often and how a container last exited
+type ContainerExits struct {
+	RestartCount int       // Restarts done by the container's restart policy
+	ExitCode     int       // Exit code of the last run
+	FinishedAt   time.Time // Zero when the container never exited
+	Running      bool
+}
+
+// GetContainerExits reads a container's restart count and last exit
+func (c *Client) GetContainerExits(ctx context.Context, containerID string) (*ContainerExits, error) {
+	inspect, err := c.docker.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	exits := &ContainerExits{RestartCount: inspect.RestartCount}
+	if inspect.State != nil {
+		exits.ExitCode = inspect.State.ExitCode
+		exits.Running = inspect.State.Running
+		// Docker reports 0001-01-01T00:00:00Z for containers that never exited
+		if finished, err := time.Parse(time.RFC3339Nano, inspect.State.FinishedAt); err == nil && finished.Year() > 1 {
+			exits.FinishedAt = finished
+		}
+	}
+	return exits, nil
+}
+
+// Server containers only count as running once their logs show the world finished loading
+func (c *Client) serverBooting(containerID string, inspect container.InspectResponse) bool {
+	if inspect.Config == nil || inspect.Config.Labels["discopanel.server.id"] == "" {
+		return false
+	}
+	return c.startup.booting(containerID)
+}
+
 func (c *Client) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
 	// Get real-time stats
 	statsResponse, err := c.docker.ContainerStats(ctx, containerID, false)
@@ -796,12 +1041,60 @@ func getDockerImage(loader models.ModLoader, mcVersion string) string {
 	_ = loader
 	// itzg/minecraft-server supports all mod loaders through environment variables
 	// We use Java version specific tags for better compatibility
-	return "itzg/minecraft-server:" + GetOptimalDockerTag(mcVersion, loader, false)
+	return MinecraftImageRepo + ":" + GetOptimalDockerTag(mcVersion, loader, false)
+}
+
+// ValidateImageTag checks that a Minecraft server image tag exists
+func (c *Client) ValidateImageTag(ctx context.Context, tag string) error {
+	if tag == "" {
+		return fmt.Errorf("docker image tag is empty")
+	}
+	return c.ValidateImage(ctx, MinecraftImageRepo+":"+tag)
+}
+
+// ValidateImage checks that an image reference resolves in its registry.
+// Images already present locally are accepted when the registry cannot confirm them,
+// so offline hosts keep working with previously pulled images.
+func (c *Client) ValidateImage(ctx context.Context, imageName string) error {
+	checkCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	_, distErr := c.docker.DistributionInspect(checkCtx, imageName, "")
+	if distErr == nil {
+		return nil
+	}
+
+	if _, err := c.docker.ImageInspect(ctx, imageName); err == nil {
+		c.log.Debug("Registry lookup for %s failed, using local image: %v", imageName, distErr)
+		return nil
+	}
+
+	if isManifestNotFound(distErr) {
+		return fmt.Errorf("docker image %s does not exist in the registry, choose a different image tag", imageName)
+	}
+
+	// Registry unreachable and no local copy, let the pull report the real failure
+	c.log.Warn("Could not verify docker image %s: %v", imageName, distErr)
+	return nil
+}
+
+func isManifestNotFound(err error) bool {
+	if errdefs.IsNotFound(err) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "manifest unknown")
 }
 
 // Creates the Docker network if it doesn't exist - attaches itself to that network when applicable
 func (c *Client) EnsureNetwork() error {
-	ctx := context.Background()
+	err := c.ensureNetwork(context.Background())
+	c.networkMu.Lock()
+	c.networkErr = err
+	c.networkMu.Unlock()
+	return err
+}
+
+func (c *Client) ensureNetwork(ctx context.Context) error {
 
 	// List existing networks
 	networks, err := c.docker.NetworkList(ctx, network.ListOptions{})
@@ -836,10 +1129,57 @@ func (c *Client) EnsureNetwork() error {
 	return nil
 }
 
+// Retries network setup with backoff, recording the final state for health checks
+func (c *Client) EnsureNetworkWithRetry(ctx context.Context, attempts int, delay time.Duration) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = c.EnsureNetwork(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		c.log.Warn("Docker network %s not ready (attempt %d/%d): %v", c.config.NetworkName, attempt, attempts, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("docker network %q is unavailable: %w; check that the Docker daemon is reachable and that network_name is valid and not conflicting with an existing subnet", c.config.NetworkName, err)
+}
+
+// Returns the last network setup error, nil once the network is ready
+func (c *Client) NetworkError() error {
+	c.networkMu.RLock()
+	defer c.networkMu.RUnlock()
+	return c.networkErr
+}
+
+// Ensures the network is ready before creating containers, retrying setup once if it previously failed
+func (c *Client) requireNetwork() error {
+	if c.config.NetworkName == "" || c.NetworkError() == nil {
+		return nil
+	}
+	if err := c.EnsureNetwork(); err != nil {
+		return fmt.Errorf("docker network %q is unavailable, containers cannot be created until it is fixed: %w", c.config.NetworkName, err)
+	}
+	return nil
+}
+
 // Connects discopanel to its own bridge network if running as container
 // NOTE: Only really needed for bridge mode though
 func (c *Client) attachSelfToNetwork(ctx context.Context) {
+	// Docker creates /.dockerenv, Podman creates /run/.containerenv
 	if _, err := os.Stat("/.dockerenv"); err != nil {
+		if _, err := os.Stat("/run/.containerenv"); err != nil {
+			return
+		}
+	}
+
+	// Our own container lives on a different daemon
+	if c.IsRemote() {
 		return
 	}
 
@@ -916,5 +1256,98 @@ func buildEnvFromConfig(config *models.ServerConfig) []string {
 		}
 	}
 
+	env = applyAikarFlags(env, config)
+	if config.HeapDumpOnOOM != nil && *config.HeapDumpOnOOM {
+		env = appendJVMXXOpts(env, crashDumpJVMFlags)
+	}
+
+	return env
+}
+
+// Heap size from which Aikar's flags switch to their large heap values
+const aikarLargeHeapMB = 12 * 1024
+
+// Aikar's G1 flags sized for a heap of heapMB, see https://docs.papermc.io/paper/aikars-flags
+func aikarFlags(heapMB int) []string {
+	newSize, maxNewSize, regionSize, reserve, occupancy := 30, 40, "8M", 20, 15
+	if heapMB >= aikarLargeHeapMB {
+		newSize, maxNewSize, regionSize, reserve, occupancy = 40, 50, "16M", 15, 20
+	}
+	return []string{
+		"-XX:+UseG1GC",
+		"-XX:+ParallelRefProcEnabled",
+		"-XX:MaxGCPauseMillis=200",
+		"-XX:+UnlockExperimentalVMOptions",
+		"-XX:+DisableExplicitGC",
+		"-XX:+AlwaysPreTouch",
+		fmt.Sprintf("-XX:G1NewSizePercent=%d", newSize),
+		fmt.Sprintf("-XX:G1MaxNewSizePercent=%d", maxNewSize),
+		"-XX:G1HeapRegionSize=" + regionSize,
+		fmt.Sprintf("-XX:G1ReservePercent=%d", reserve),
+		"-XX:G1HeapWastePercent=5",
+		"-XX:G1MixedGCCountTarget=4",
+		fmt.Sprintf("-XX:InitiatingHeapOccupancyPercent=%d", occupancy),
+		"-XX:G1MixedGCLiveThresholdPercent=90",
+		"-XX:G1RSetUpdatingPauseTimePercent=5",
+		"-XX:SurvivorRatio=32",
+		"-XX:+PerfDisableSharedMem",
+		"-XX:MaxTenuringThreshold=1",
+		"-Dusing.aikars.flags=https://mcflags.emc.gs",
+		"-Daikars.new.flags=true",
+	}
+}
+
+// Replaces the image's USE_AIKAR_FLAGS with flags sized from the configured heap, so a
+// memory change retunes them whenever the container is recreated. User JVM_XX_OPTS come
+// last and still win. MeowIce's flags depend on the Java runtime and stay with the image.
+func applyAikarFlags(env []string, config *models.ServerConfig) []string {
+	if config.UseAikarFlags == nil || !*config.UseAikarFlags || (config.UseMeowiceFlags != nil && *config.UseMeowiceFlags) {
+		return env
+	}
+	heap := ""
+	switch {
+	case config.MaxMemory != nil && *config.MaxMemory != "":
+		heap = *config.MaxMemory
+	case config.Memory != nil:
+		heap = *config.Memory
+	}
+	heapMB := ParseMemoryMB(heap)
+	if heapMB == 0 {
+		// Leave the flags to the image when the heap can't be read
+		return env
+	}
+
+	flags := strings.Join(aikarFlags(heapMB), " ")
+	found := false
+	for i, e := range env {
+		if e == "USE_AIKAR_FLAGS=true" {
+			env[i] = "USE_AIKAR_FLAGS=false"
+		} else if existing, ok := strings.CutPrefix(e, "JVM_XX_OPTS="); ok {
+			env[i] = strings.TrimSpace("JVM_XX_OPTS=" + flags + " " + existing)
+			found = true
+		}
+	}
+	if !found {
+		env = append(env, "JVM_XX_OPTS="+flags)
+	}
 	return env
 }
+
+// JVM flags that route heap dumps and fatal error logs into the data dir
+var crashDumpJVMFlags = []string{
+	"-XX:+HeapDumpOnOutOfMemoryError",
+	"-XX:HeapDumpPath=/data/" + files.CrashDumpDir,
+	"-XX:ErrorFile=/data/" + files.CrashDumpDir + "/hs_err_pid%p.log",
+}
+
+// Appends flags to JVM_XX_OPTS, creating the variable when unset
+func appendJVMXXOpts(env []string, flags []string) []string {
+	joined := strings.Join(flags, " ")
+	for i, e := range env {
+		if existing, ok := strings.CutPrefix(e, "JVM_XX_OPTS="); ok {
+			env[i] = strings.TrimSpace("JVM_XX_OPTS=" + existing + " " + joined)
+			return env
+		}
+	}
+	return append(env, "JVM_XX_OPTS="+joined)
+}
@@ -0,0 +1,218 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	models "github.com/nickheyer/discopanel/internal/db"
+)
+
+// PrunableImage is a local image DiscoPanel pulled that nothing currently uses
+type PrunableImage struct {
+	ID       string
+	Tags     []string
+	Size     int64
+	Dangling bool
+}
+
+// ImageUpdate compares the image a container runs with the registry's current digest for its tag
+type ImageUpdate struct {
+	Image         string
+	CurrentDigest string
+	LatestDigest  string
+	Available     bool
+	CheckedAt     time.Time // When the registry was asked, older than now for a cached answer
+}
+
+// How long a registry digest is reused, registries like Docker Hub rate limit manifest requests
+const registryDigestTTL = 10 * time.Minute
+
+type registryDigest struct {
+	digest    string
+	checkedAt time.Time
+}
+
+// Returns the full image reference a server container is created from
+func ServerImage(server *models.Server) string {
+	if server.DockerImage != "" {
+		return MinecraftImageRepo + ":" + server.DockerImage
+	}
+	return getDockerImage(server.ModLoader, server.MCVersion)
+}
+
+// Lists images from the managed repositories that no container uses and no
+// server or module is configured to run. Sizes are per image and may count
+// layers shared with other images, so the total is an upper bound.
+func (c *Client) ListPrunableImages(ctx context.Context, managedRepos []string, inUse []string) ([]PrunableImage, error) {
+	containers, err := c.docker.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	usedIDs := make(map[string]bool, len(containers))
+	for _, ctr := range containers {
+		usedIDs[ctr.ImageID] = true
+	}
+
+	usedRefs := make(map[string]bool, len(inUse))
+	for _, ref := range inUse {
+		usedRefs[normalizeImageRef(ref)] = true
+	}
+
+	repos := make([]string, 0, len(managedRepos)+1)
+	repos = append(repos, MinecraftImageRepo)
+	for _, repo := range managedRepos {
+		repos = append(repos, imageRepo(normalizeImageRef(repo)))
+	}
+
+	images, err := c.docker.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	var prunable []PrunableImage
+	for _, img := range images {
+		if usedIDs[img.ID] || img.Containers > 0 {
+			continue
+		}
+
+		tags := slices.DeleteFunc(slices.Clone(img.RepoTags), func(tag string) bool { return tag == "<none>:<none>" })
+		if slices.ContainsFunc(tags, func(tag string) bool { return usedRefs[normalizeImageRef(tag)] }) {
+			continue
+		}
+
+		managed := false
+		for _, ref := range append(slices.Clone(tags), img.RepoDigests...) {
+			if slices.Contains(repos, imageRepo(ref)) {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			continue
+		}
+
+		prunable = append(prunable, PrunableImage{
+			ID:       img.ID,
+			Tags:     tags,
+			Size:     img.Size,
+			Dangling: len(tags) == 0,
+		})
+	}
+
+	return prunable, nil
+}
+
+// Removes images without forcing, so anything that became in use is kept
+func (c *Client) RemoveImages(ctx context.Context, images []PrunableImage) (removed int, reclaimed int64, errs []string) {
+	for _, img := range images {
+		if _, err := c.docker.ImageRemove(ctx, img.ID, image.RemoveOptions{PruneChildren: true}); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", shortImageID(img.ID), err))
+			continue
+		}
+		removed++
+		reclaimed += img.Size
+	}
+	return removed, reclaimed, errs
+}
+
+// Adds the implicit latest tag to untagged references
+func normalizeImageRef(ref string) string {
+	if strings.Contains(ref, "@") {
+		return ref
+	}
+	if !strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") {
+		return ref + ":latest"
+	}
+	return ref
+}
+
+// Strips the tag or digest from a reference
+func imageRepo(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i]
+	}
+	slash := strings.LastIndex(ref, "/")
+	if i := strings.LastIndex(ref, ":"); i > slash {
+		return ref[:i]
+	}
+	return ref
+}
+
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// Checks whether the registry holds a newer digest for imageName than the one
+// containerID was created from. Without a container the local tag is compared.
+// Registry answers are cached for registryDigestTTL unless refresh is set.
+func (c *Client) CheckImageUpdate(ctx context.Context, containerID, imageName string, refresh bool) (*ImageUpdate, error) {
+	latest, err := c.registryDigest(ctx, imageName, refresh)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImageUpdate{Image: imageName, LatestDigest: latest.digest, CheckedAt: latest.checkedAt}
+
+	imageRef := imageName
+	if containerID != "" {
+		inspect, err := c.docker.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container: %w", err)
+		}
+		imageRef = inspect.Image
+	}
+
+	local, err := c.docker.ImageInspect(ctx, imageRef)
+	if err != nil {
+		// Nothing local to compare against, the next create pulls the latest anyway
+		result.Available = true
+		return result, nil
+	}
+
+	for _, repoDigest := range local.RepoDigests {
+		_, digest, ok := strings.Cut(repoDigest, "@")
+		if !ok {
+			continue
+		}
+		if digest == result.LatestDigest {
+			result.CurrentDigest = digest
+			return result, nil
+		}
+		if result.CurrentDigest == "" {
+			result.CurrentDigest = digest
+		}
+	}
+	result.Available = true
+	return result, nil
+}
+
+// Returns the registry's current digest for imageName, from the cache when it's recent enough
+func (c *Client) registryDigest(ctx context.Context, imageName string, refresh bool) (registryDigest, error) {
+	c.digestMu.Lock()
+	cached, ok := c.digests[imageName]
+	c.digestMu.Unlock()
+	if ok && !refresh && time.Since(cached.checkedAt) < registryDigestTTL {
+		return cached, nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	dist, err := c.docker.DistributionInspect(checkCtx, imageName, "")
+	if err != nil {
+		return registryDigest{}, fmt.Errorf("failed to query registry for %s: %w", imageName, err)
+	}
+
+	latest := registryDigest{digest: dist.Descriptor.Digest.String(), checkedAt: time.Now()}
+	c.digestMu.Lock()
+	c.digests[imageName] = latest
+	c.digestMu.Unlock()
+	return latest, nil
+}
@@ -0,0 +1,226 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/mount"
+	models "github.com/nickheyer/discopanel/internal/db"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// ExistingContainer is what an unmanaged Minecraft container reveals about itself on inspect
+type ExistingContainer struct {
+	ID              string
+	Name            string
+	ImageTag        string // Tag of the itzg/minecraft-server image
+	Env             map[string]string
+	Running         bool
+	HostPort        int // Published game port
+	AdditionalPorts []*v1.AdditionalPort
+	Memory          int    // Container memory limit in MB, 0 when unlimited
+	DataPath        string // /data as DiscoPanel sees it
+	DataVolume      string // Named volume holding /data, empty for a bind mount
+	AutoRestart     bool
+}
+
+// InspectExistingContainer reads an itzg/minecraft-server container DiscoPanel did not
+// create so it can be adopted. It must keep its world in a /data mount, anything in
+// the container's own filesystem would not survive the container being recreated.
+func (c *Client) InspectExistingContainer(ctx context.Context, nameOrID string) (*ExistingContainer, error) {
+	inspect, err := c.docker.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("container %s not found", nameOrID)
+		}
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if inspect.Config == nil || inspect.HostConfig == nil {
+		return nil, fmt.Errorf("failed to inspect container %s", nameOrID)
+	}
+	if inspect.Config.Labels["discopanel.managed"] == "true" {
+		return nil, fmt.Errorf("container %s is already managed by DiscoPanel", nameOrID)
+	}
+
+	ref := normalizeImageRef(strings.TrimPrefix(inspect.Config.Image, "docker.io/"))
+	if imageRepo(ref) != MinecraftImageRepo {
+		return nil, fmt.Errorf("container runs %s, only %s containers can be imported", inspect.Config.Image, MinecraftImageRepo)
+	}
+
+	existing := &ExistingContainer{
+		ID:          inspect.ID,
+		Name:        strings.TrimPrefix(inspect.Name, "/"),
+		ImageTag:    ref[len(MinecraftImageRepo)+1:],
+		Env:         make(map[string]string, len(inspect.Config.Env)),
+		Running:     inspect.State != nil && inspect.State.Running,
+		Memory:      int(inspect.HostConfig.Memory / (1024 * 1024)),
+		AutoRestart: inspect.HostConfig.RestartPolicy.Name != "" && inspect.HostConfig.RestartPolicy.Name != "no",
+	}
+	for _, e := range inspect.Config.Env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			existing.Env[k] = v
+		}
+	}
+
+	// SERVER_PORT moves the game port inside the container
+	gamePort := DefaultMinecraftPort
+	if p, err := strconv.Atoi(existing.Env["SERVER_PORT"]); err == nil && p > 0 {
+		gamePort = p
+	}
+	for port, bindings := range inspect.HostConfig.PortBindings {
+		if len(bindings) == 0 {
+			continue
+		}
+		hostPort, err := strconv.Atoi(bindings[0].HostPort)
+		if err != nil || hostPort == 0 {
+			continue
+		}
+		switch {
+		case port.Int() == gamePort && port.Proto() == "tcp":
+			existing.HostPort = hostPort
+		case port.Int() == DefaultRCONPort && port.Proto() == "tcp":
+			// DiscoPanel publishes RCON itself
+		default:
+			existing.AdditionalPorts = append(existing.AdditionalPorts, &v1.AdditionalPort{
+				Name:          fmt.Sprintf("Imported %d/%s", port.Int(), port.Proto()),
+				ContainerPort: int32(port.Int()),
+				HostPort:      int32(hostPort),
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+	if existing.HostPort == 0 {
+		return nil, fmt.Errorf("container does not publish the game port %d/tcp", gamePort)
+	}
+
+	for _, m := range inspect.Mounts {
+		if m.Destination != "/data" {
+			continue
+		}
+		switch m.Type {
+		case mount.TypeBind:
+			existing.DataPath = TranslateFromHostPath(m.Source)
+		case mount.TypeVolume:
+			vol, err := c.docker.VolumeInspect(ctx, m.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inspect data volume %s: %w", m.Name, err)
+			}
+			existing.DataVolume = vol.Name
+			existing.DataPath = c.VolumeDataPath(vol.Name)
+		}
+	}
+	if existing.DataPath == "" && existing.DataVolume == "" {
+		return nil, fmt.Errorf("container has no /data mount, its world would be lost when the container is recreated")
+	}
+	// Volumes are reached through the API when DiscoPanel has no view of them
+	if existing.DataPath == "" {
+		return existing, nil
+	}
+	if info, err := os.Stat(existing.DataPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("data directory %s is not reachable from DiscoPanel, mount it into DiscoPanel first", existing.DataPath)
+	}
+
+	return existing, nil
+}
+
+// Converts a host path under DISCOPANEL_HOST_DATA_PATH back to the path inside
+// DiscoPanel's container, the reverse of TranslateToHostPath
+func TranslateFromHostPath(path string) string {
+	hostDataPath := os.Getenv("DISCOPANEL_HOST_DATA_PATH")
+	if hostDataPath == "" {
+		return path
+	}
+	containerDataDir := os.Getenv("DISCOPANEL_DATA_DIR")
+	if containerDataDir == "" {
+		containerDataDir = "/app/data"
+	}
+	relPath, err := filepath.Rel(hostDataPath, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+	return filepath.Join(containerDataDir, relPath)
+}
+
+// Fills ServerConfig fields from container environment variables, the reverse of
+// buildEnvFromConfig. Values that don't parse for their field are skipped.
+func ApplyEnvToConfig(config *models.ServerConfig, env map[string]string) {
+	configValue := reflect.ValueOf(config).Elem()
+	configType := configValue.Type()
+
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		envTag := field.Tag.Get("env")
+		if envTag == "" || envTag == "-" {
+			continue
+		}
+		raw, ok := env[envTag]
+		if !ok {
+			continue
+		}
+
+		fieldValue := configValue.Field(i)
+		elemType := field.Type
+		if elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+
+		value := reflect.New(elemType).Elem()
+		switch elemType.Kind() {
+		case reflect.String:
+			value.SetString(raw)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+			if err != nil {
+				continue
+			}
+			value.SetInt(n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				continue
+			}
+			value.SetBool(b)
+		default:
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Pointer {
+			ptr := reflect.New(elemType)
+			ptr.Elem().Set(value)
+			fieldValue.Set(ptr)
+		} else {
+			fieldValue.Set(value)
+		}
+	}
+}
+
+// Parses a JVM memory size such as 4G or 2048M into MB, 0 when it can't be read
+func ParseMemoryMB(size string) int {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" {
+		return 0
+	}
+	multiplier := 1.0 / (1024 * 1024)
+	switch size[len(size)-1] {
+	case 'G':
+		multiplier = 1024
+		size = size[:len(size)-1]
+	case 'M':
+		multiplier = 1
+		size = size[:len(size)-1]
+	case 'K':
+		multiplier = 1.0 / 1024
+		size = size[:len(size)-1]
+	}
+	n, err := strconv.ParseFloat(size, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return int(n * multiplier)
+}
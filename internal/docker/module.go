@@ -33,6 +33,10 @@ func (c *Client) CreateModuleContainer(ctx context.Context, module *models.Modul
 		return "", fmt.Errorf("module template has no Docker image configured")
 	}
 
+	if err := c.requireNetwork(); err != nil {
+		return "", err
+	}
+
 	// Try pulling the image
 	if err := c.pullImage(ctx, imageName); err != nil {
 		c.log.Warn("Failed to pull image %s: %v, attempting to use local", imageName, err)
@@ -144,11 +148,9 @@ func (c *Client) CreateModuleContainer(ctx context.Context, module *models.Modul
 	}
 
 	hostConfig := &container.HostConfig{
-		PortBindings: portBindings,
-		Mounts:       mounts,
-		RestartPolicy: container.RestartPolicy{
-			Name: "unless-stopped",
-		},
+		PortBindings:  portBindings,
+		Mounts:        mounts,
+		RestartPolicy: moduleRestartPolicy(module.RestartPolicy),
 		Resources: container.Resources{
 			Memory:     memory * 1024 * 1024,
 			MemorySwap: memory * 1024 * 1024,
@@ -160,6 +162,9 @@ func (c *Client) CreateModuleContainer(ctx context.Context, module *models.Modul
 		ExtraHosts: []string{"host.docker.internal:host-gateway"},
 	}
 
+	// Map the host user into the container under rootless Podman
+	c.applyRootlessHostConfig(hostConfig, 0, 0)
+
 	// Apply CPU limit if specified
 	if module.CPULimit > 0 {
 		hostConfig.Resources.NanoCPUs = int64(module.CPULimit * 1e9)
@@ -185,6 +190,19 @@ func (c *Client) CreateModuleContainer(ctx context.Context, module *models.Modul
 	return resp.ID, nil
 }
 
+// Docker's counterpart of a module restart policy. Always maps to unless-stopped so a
+// module stopped from the panel stays stopped across Docker restarts.
+func moduleRestartPolicy(policy string) container.RestartPolicy {
+	switch policy {
+	case models.ModuleRestartNo:
+		return container.RestartPolicy{Name: container.RestartPolicyDisabled}
+	case models.ModuleRestartOnFailure:
+		return container.RestartPolicy{Name: container.RestartPolicyOnFailure}
+	default:
+		return container.RestartPolicy{Name: container.RestartPolicyUnlessStopped}
+	}
+}
+
 // buildModuleEnv builds environment variables for a module container
 func (c *Client) buildModuleEnv(module *models.Module, server *models.Server, aliasCtx *alias.Context) []string {
 	env := make([]string, 0)
@@ -194,7 +212,7 @@ func (c *Client) buildModuleEnv(module *models.Module, server *models.Server, al
 		fmt.Sprintf("DISCOPANEL_SERVER_ID=%s", server.ID),
 		fmt.Sprintf("DISCOPANEL_SERVER_NAME=%s", server.Name),
 		fmt.Sprintf("DISCOPANEL_SERVER_HOST=discopanel-server-%s", server.ID),
-		fmt.Sprintf("DISCOPANEL_SERVER_PORT=%d", DefaultMinecraftPort),
+		fmt.Sprintf("DISCOPANEL_SERVER_PORT=%d", server.ContainerPort()),
 		fmt.Sprintf("DISCOPANEL_MODULE_ID=%s", module.ID),
 		fmt.Sprintf("DISCOPANEL_MODULE_NAME=%s", module.Name),
 	)
@@ -203,6 +221,9 @@ func (c *Client) buildModuleEnv(module *models.Module, server *models.Server, al
 	if module.TokenPlaintext != "" {
 		env = append(env, fmt.Sprintf("DISCOPANEL_API_TOKEN=%s", module.TokenPlaintext))
 	}
+	if module.PanelToken != "" {
+		env = append(env, fmt.Sprintf("DISCOPANEL_PANEL_TOKEN=%s", module.PanelToken))
+	}
 
 	// Add module environment variables (frontend sends complete config with alias substitution)
 	if module.EnvOverrides != "" {
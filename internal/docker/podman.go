@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// Supported container runtimes, both spoken to over the Docker Engine API
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Returns the Podman API socket for the current user, preferring the rootless socket
+func PodmanSocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" && os.Getuid() != 0 {
+		return "unix://" + filepath.Join(runtimeDir, "podman", "podman.sock")
+	}
+	if os.Getuid() != 0 {
+		return fmt.Sprintf("unix:///run/user/%d/podman/podman.sock", os.Getuid())
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// Probes the daemon to find out whether it is Podman and running rootless
+func (c *Client) detectRuntime() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if version, err := c.docker.ServerVersion(ctx); err == nil {
+		for _, component := range version.Components {
+			if strings.Contains(strings.ToLower(component.Name), "podman") {
+				c.podman = true
+				break
+			}
+		}
+	} else {
+		c.log.Debug("Could not query container runtime version: %v", err)
+	}
+
+	if info, err := c.docker.Info(ctx); err == nil {
+		for _, opt := range info.SecurityOptions {
+			if strings.Contains(opt, "name=rootless") {
+				c.rootless = true
+				break
+			}
+		}
+	} else {
+		c.log.Debug("Could not query container runtime info: %v", err)
+	}
+
+	if c.config.Runtime == RuntimePodman && !c.podman {
+		c.log.Warn("Runtime is set to podman but the daemon at %s does not identify as Podman", c.docker.DaemonHost())
+	}
+	if c.podman {
+		c.log.Info("Using Podman container runtime (rootless: %t)", c.rootless)
+	}
+}
+
+// Reports whether the daemon is Podman
+func (c *Client) IsPodman() bool {
+	return c.podman
+}
+
+// Reports whether the daemon runs rootless (user namespaces remap container UIDs)
+func (c *Client) IsRootless() bool {
+	return c.rootless
+}
+
+// Adjusts host config for rootless Podman.
+// Rootless Podman maps container UIDs into the user's subuid range, so files the
+// server writes as UID/GID would not be owned by the panel's user on the host.
+// keep-id maps the host user onto the container user instead.
+func (c *Client) applyRootlessHostConfig(hostConfig *container.HostConfig, uid, gid int) {
+	if !c.podman || !c.rootless {
+		return
+	}
+
+	if hostConfig.UsernsMode == "" {
+		if uid > 0 {
+			hostConfig.UsernsMode = container.UsernsMode(fmt.Sprintf("keep-id:uid=%d,gid=%d", uid, gid))
+		} else {
+			hostConfig.UsernsMode = "keep-id"
+		}
+	}
+
+	// Rootless runtimes cannot bind privileged ports unless the sysctl allows it
+	for _, bindings := range hostConfig.PortBindings {
+		for _, binding := range bindings {
+			if port, err := nat.ParsePort(binding.HostPort); err == nil && port > 0 && port < unprivilegedPortStart() {
+				c.log.Warn("Host port %d is privileged and cannot be bound by rootless Podman; lower net.ipv4.ip_unprivileged_port_start or use a port >= %d", port, unprivilegedPortStart())
+			}
+		}
+	}
+}
+
+// Lowest port an unprivileged user may bind on this host
+func unprivilegedPortStart() int {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_unprivileged_port_start")
+	if err != nil {
+		return 1024
+	}
+	var port int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%d", &port); err != nil {
+		return 1024
+	}
+	return port
+}
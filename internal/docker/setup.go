@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	models "github.com/nickheyer/discopanel/internal/db"
+)
+
+// Number of trailing log lines captured from a setup run
+const setupLogTail = 200
+
+// Log fragments that indicate the image's setup step failed
+var setupFailureMarkers = []string{
+	"[init] ERROR",
+	"ERROR:",
+	"[mc-image-helper] ERROR",
+	"Exception in thread",
+}
+
+// SetupResult is the outcome of a setup-only container run
+type SetupResult struct {
+	Success  bool
+	ExitCode int64
+	Error    string
+	Logs     []string
+	Duration time.Duration
+}
+
+// Runs a server container with SETUP_ONLY enabled and waits for it to exit.
+// The container is removed afterwards; the caller recreates the real one.
+func (c *Client) RunSetup(ctx context.Context, server *models.Server, serverConfig *models.ServerConfig) (*SetupResult, error) {
+	setupConfig := *serverConfig
+	setupOnly := true
+	setupConfig.SetupOnly = &setupOnly
+
+	containerID, err := c.CreateContainer(ctx, server, &setupConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := c.RemoveContainer(context.Background(), containerID); err != nil {
+			c.log.Warn("Failed to remove setup container %s: %v", containerID, err)
+		}
+	}()
+
+	// A clean exit must not trigger the unless-stopped restart policy
+	if _, err := c.docker.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyDisabled},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to disable restart policy: %w", err)
+	}
+
+	statusCh, errCh := c.docker.ContainerWait(ctx, containerID, container.WaitConditionNextExit)
+
+	started := time.Now()
+	if err := c.StartContainer(ctx, containerID); err != nil {
+		return nil, fmt.Errorf("failed to start setup container: %w", err)
+	}
+
+	result := &SetupResult{}
+	select {
+	case status := <-statusCh:
+		result.ExitCode = status.StatusCode
+		if status.Error != nil {
+			result.Error = status.Error.Message
+		}
+	case err := <-errCh:
+		c.StopContainer(context.Background(), containerID)
+		if ctx.Err() != nil {
+			result.ExitCode = -1
+			result.Error = "setup timed out before completing"
+		} else {
+			return nil, fmt.Errorf("failed waiting for setup container: %w", err)
+		}
+	}
+	result.Duration = time.Since(started)
+
+	result.Logs = c.TailContainerLogs(context.Background(), containerID, setupLogTail)
+
+	failureLine := ""
+	for _, line := range result.Logs {
+		for _, marker := range setupFailureMarkers {
+			if strings.Contains(line, marker) {
+				failureLine = line
+			}
+		}
+	}
+
+	result.Success = result.ExitCode == 0 && result.Error == "" && failureLine == ""
+	if !result.Success && result.Error == "" {
+		if failureLine != "" {
+			result.Error = failureLine
+		} else {
+			result.Error = fmt.Sprintf("setup exited with code %d", result.ExitCode)
+		}
+	}
+
+	return result, nil
+}
+
+// TailContainerLogs reads the last n log lines of a container (TTY containers, no stream multiplexing)
+func (c *Client) TailContainerLogs(ctx context.Context, containerID string, n int) []string {
+	reader, err := c.docker.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       fmt.Sprintf("%d", n),
+	})
+	if err != nil {
+		c.log.Warn("Failed to read logs for container %s: %v", containerID, err)
+		return nil
+	}
+	defer reader.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/nickheyer/discopanel/internal/db"
+)
+
+// First line the itzg entrypoint prints on every container start
+const startupResetMarker = "[init] Running as uid="
+
+// Lines printed once players can join: vanilla, Forge/Fabric/NeoForge and Paper forks
+// ("Done (12.345s)! For help, type "help""), older Forge builds without a timing,
+// Velocity ("Done (1.23s)!") and BungeeCord/Waterfall listeners
+var startupReadyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Done \(\d+(?:[.,]\d+)?m?s\)!`),
+	regexp.MustCompile(`\]: Done!? For help, type`),
+	regexp.MustCompile(`Listening on /[\d.:\[\]a-fA-F]+:\d+`),
+}
+
+// Assume a boot finished when no ready line shows up, e.g. heavily customised log output
+const DefaultStartupTimeout = 10 * time.Minute
+
+// Log markers for each in-progress phase, checked from the latest phase backwards
+var startupPhaseMarkers = []struct {
+	phase   models.StartupPhase
+	markers []string
+}{
+	{models.PhaseLoadingWorld, []string{"Preparing level", "Preparing start region", "Preparing spawn area"}},
+	{models.PhaseLaunching, []string{"[init] Starting the Minecraft server", "Starting minecraft server version", "ModLauncher running", "Loading Minecraft "}},
+	{models.PhaseInstalling, []string{"[init]", "[mc-image-helper]"}},
+}
+
+// Orders phases so log replays and noisy output never move a boot backwards
+var startupPhaseRank = map[models.StartupPhase]int{
+	models.PhaseInstalling:   1,
+	models.PhaseLaunching:    2,
+	models.PhaseLoadingWorld: 3,
+	models.PhaseReady:        4,
+}
+
+// Follows container logs to derive how far each server boot has progressed
+type StartupTracker struct {
+	mu      sync.RWMutex
+	phases  map[string]models.StartupPhase // containerID -> phase
+	started map[string]time.Time           // containerID -> first boot line
+	pulling map[string]bool                // serverID -> image pull in progress
+	timeout time.Duration
+}
+
+func NewStartupTracker(timeout time.Duration) *StartupTracker {
+	if timeout <= 0 {
+		timeout = DefaultStartupTimeout
+	}
+	return &StartupTracker{
+		phases:  make(map[string]models.StartupPhase),
+		started: make(map[string]time.Time),
+		pulling: make(map[string]bool),
+		timeout: timeout,
+	}
+}
+
+// Advances a container's phase from a single log line
+func (t *StartupTracker) Observe(containerID, line string) {
+	if strings.Contains(line, startupResetMarker) {
+		t.mu.Lock()
+		t.phases[containerID] = models.PhaseInstalling
+		t.started[containerID] = time.Now()
+		t.mu.Unlock()
+		return
+	}
+
+	t.mu.RLock()
+	current := t.phases[containerID]
+	t.mu.RUnlock()
+	if current == models.PhaseReady {
+		return
+	}
+
+	phase, ok := DetectStartupPhase(line)
+	if !ok || startupPhaseRank[phase] <= startupPhaseRank[current] {
+		return
+	}
+
+	t.mu.Lock()
+	if startupPhaseRank[phase] > startupPhaseRank[t.phases[containerID]] {
+		if _, ok := t.started[containerID]; !ok {
+			t.started[containerID] = time.Now()
+		}
+		t.phases[containerID] = phase
+	}
+	t.mu.Unlock()
+}
+
+// Forgets a container's progress ahead of a fresh start or after removal
+func (t *StartupTracker) Reset(containerID string) {
+	t.mu.Lock()
+	delete(t.phases, containerID)
+	delete(t.started, containerID)
+	t.mu.Unlock()
+}
+
+// Returns the phase observed for a container, empty when nothing is known
+func (t *StartupTracker) Phase(containerID string) models.StartupPhase {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.phases[containerID]
+}
+
+// Returns the phase for a server, reporting image pulls that precede its container
+func (t *StartupTracker) ServerPhase(serverID, containerID string) models.StartupPhase {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.pulling[serverID] {
+		return models.PhasePullingImage
+	}
+	if containerID == "" {
+		return ""
+	}
+	return t.phases[containerID]
+}
+
+// Whether a container is known to still be booting, giving up after the timeout
+func (t *StartupTracker) booting(containerID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phase := t.phases[containerID]
+	if phase == "" || phase == models.PhaseReady {
+		return false
+	}
+	if started, ok := t.started[containerID]; ok && time.Since(started) > t.timeout {
+		t.phases[containerID] = models.PhaseReady
+		return false
+	}
+	return true
+}
+
+func (t *StartupTracker) setPulling(serverID string, pulling bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pulling {
+		t.pulling[serverID] = true
+	} else {
+		delete(t.pulling, serverID)
+	}
+}
+
+// Maps a log line to the startup phase it indicates
+func DetectStartupPhase(line string) (models.StartupPhase, bool) {
+	for _, pattern := range startupReadyPatterns {
+		if pattern.MatchString(line) {
+			return models.PhaseReady, true
+		}
+	}
+	for _, group := range startupPhaseMarkers {
+		for _, marker := range group.markers {
+			if strings.Contains(line, marker) {
+				return group.phase, true
+			}
+		}
+	}
+	return "", false
+}
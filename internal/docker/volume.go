@@ -0,0 +1,195 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+	models "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/files"
+)
+
+// DataVolumeName is the named volume backing a server's /data
+func DataVolumeName(serverID string) string {
+	return "discopanel-data-" + serverID
+}
+
+// CreateDataVolume creates the named volume for a server and returns its name. The volume
+// lives in Docker's own storage and is never touched through the host, the server image
+// hands /data to its user on start and files are copied in and out through the API.
+func (c *Client) CreateDataVolume(ctx context.Context, server *models.Server) (string, error) {
+	name := DataVolumeName(server.ID)
+
+	labels := map[string]string{
+		"discopanel.server.id":   server.ID,
+		"discopanel.server.name": server.Name,
+		"discopanel.managed":     "true",
+	}
+	maps.Copy(labels, c.config.Labels)
+
+	if _, err := c.docker.VolumeCreate(ctx, volume.CreateOptions{
+		Name:   name,
+		Driver: "local",
+		Labels: labels,
+	}); err != nil {
+		return "", fmt.Errorf("failed to create data volume: %w", err)
+	}
+	return name, nil
+}
+
+// RemoveDataVolume deletes a server's data volume and everything in it
+func (c *Client) RemoveDataVolume(ctx context.Context, name string) error {
+	if err := c.docker.VolumeRemove(ctx, name, true); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to remove data volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// VolumeDataPath is where DiscoPanel sees a volume's files directly, through Docker's
+// volume directory mounted in at docker.volumes_dir. Empty when that isn't set, the
+// server's files are then only reachable with CopyFromVolume, CopyToVolume and RunInVolume.
+func (c *Client) VolumeDataPath(name string) string {
+	if c.config.VolumesDir == "" {
+		return ""
+	}
+	return filepath.Join(c.config.VolumesDir, name, "_data")
+}
+
+// CopyFromVolume copies src, relative to /data and empty for all of it, out of the
+// server's data volume into destDir. Returns the number of files copied.
+func (c *Client) CopyFromVolume(ctx context.Context, server *models.Server, src, destDir string) (int, error) {
+	helperID, err := c.createVolumeHelper(ctx, server, []string{"true"})
+	if err != nil {
+		return 0, err
+	}
+	defer c.removeVolumeHelper(helperID)
+
+	reader, _, err := c.docker.CopyFromContainer(ctx, helperID, path.Join("/data", src))
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return 0, fmt.Errorf("%s not found in data volume: %w", src, err)
+		}
+		return 0, fmt.Errorf("failed to copy from data volume: %w", err)
+	}
+	defer reader.Close()
+
+	// Entries start with the base name of src, drop it so destDir gets what's inside
+	return files.ExtractTar(reader, destDir, 1)
+}
+
+// CopyToVolume copies the contents of srcDir into dest, relative to /data, in the server's
+// data volume. Copied files are owned by uid and gid, the user the server runs as.
+func (c *Client) CopyToVolume(ctx context.Context, server *models.Server, srcDir, dest string, uid, gid int) error {
+	helperID, err := c.createVolumeHelper(ctx, server, []string{"true"})
+	if err != nil {
+		return err
+	}
+	defer c.removeVolumeHelper(helperID)
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(files.WriteTar(pw, srcDir, dest, uid, gid))
+	}()
+	defer pr.Close()
+
+	if err := c.docker.CopyToContainer(ctx, helperID, "/data", pr, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy into data volume: %w", err)
+	}
+	return nil
+}
+
+// RunInVolume runs a shell script against the server's data volume mounted at /data and
+// returns its output. args are the script's positional parameters, so names never need quoting.
+func (c *Client) RunInVolume(ctx context.Context, server *models.Server, script string, args ...string) (string, error) {
+	helperID, err := c.createVolumeHelper(ctx, server, append([]string{"sh", "-c", script, "sh"}, args...))
+	if err != nil {
+		return "", err
+	}
+	defer c.removeVolumeHelper(helperID)
+
+	if err := c.docker.ContainerStart(ctx, helperID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("failed to start volume helper: %w", err)
+	}
+
+	var exitCode int64
+	statusCh, errCh := c.docker.ContainerWait(ctx, helperID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return "", fmt.Errorf("failed to wait for volume helper: %w", err)
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	var stdout, stderr bytes.Buffer
+	logs, err := c.docker.ContainerLogs(ctx, helperID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to read volume helper output: %w", err)
+	}
+	defer logs.Close()
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return "", fmt.Errorf("failed to read volume helper output: %w", err)
+	}
+
+	if exitCode != 0 {
+		return stdout.String(), fmt.Errorf("volume helper exited with code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Creates a container that only exists to reach a server's data volume, from the server's
+// own image since it's pulled anyway and has a shell. Runs as root to read any file in /data.
+func (c *Client) createVolumeHelper(ctx context.Context, server *models.Server, entrypoint []string) (string, error) {
+	if server.DataVolume == "" {
+		return "", fmt.Errorf("server %s has no data volume", server.Name)
+	}
+
+	imageName := ServerImage(server)
+	if _, err := c.docker.ImageInspect(ctx, imageName); err != nil {
+		if err := c.pullImage(ctx, imageName); err != nil {
+			return "", err
+		}
+	}
+
+	config := &container.Config{
+		Image:      imageName,
+		Entrypoint: entrypoint,
+		User:       "0",
+		WorkingDir: "/data",
+		Labels: map[string]string{
+			"discopanel.server.id":     server.ID,
+			"discopanel.volume_helper": "true",
+			"discopanel.managed":       "true",
+		},
+	}
+	hostConfig := &container.HostConfig{
+		Mounts: []mount.Mount{{
+			Type:   mount.TypeVolume,
+			Source: server.DataVolume,
+			Target: "/data",
+		}},
+		NetworkMode: "none",
+	}
+
+	resp, err := c.docker.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume helper: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// Removes a volume helper, on its own context so a cancelled request doesn't leave it behind
+func (c *Client) removeVolumeHelper(containerID string) {
+	if err := c.docker.ContainerRemove(context.Background(), containerID, container.RemoveOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+		c.log.Warn("Failed to remove volume helper %s: %v", containerID, err)
+	}
+}
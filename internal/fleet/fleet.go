@@ -0,0 +1,159 @@
+package fleet
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+)
+
+// Snapshot format version, bumped on incompatible layout changes
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Manifest describes a fleet snapshot archive
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	ServerCount   int       `json:"server_count"`
+	Scrubbed      []string  `json:"scrubbed_fields"` // Secret config fields removed from the export
+}
+
+// Entry is a single server definition and its config
+type Entry struct {
+	Server *storage.Server       `json:"server"`
+	Config *storage.ServerConfig `json:"config"`
+}
+
+// Snapshot is the decoded content of a fleet archive
+type Snapshot struct {
+	Manifest Manifest
+	Entries  []*Entry
+}
+
+// Returns the names of ServerConfig fields that hold secrets
+func SecretFields() []string {
+	var fields []string
+	t := reflect.TypeOf(storage.ServerConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("input") == "password" {
+			fields = append(fields, t.Field(i).Name)
+		}
+	}
+	return fields
+}
+
+// Clears secret fields from a config in place
+func ScrubSecrets(cfg *storage.ServerConfig) {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range SecretFields() {
+		field := v.FieldByName(name)
+		if field.IsValid() && field.CanSet() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+// Writes entries as a zip archive, scrubbing secrets and host specific state
+func Write(w io.Writer, entries []*Entry) error {
+	zw := zip.NewWriter(w)
+
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		ServerCount:   len(entries),
+		Scrubbed:      SecretFields(),
+	}
+	if err := writeJSON(zw, manifestName, manifest); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		server := *entry.Server
+		server.ContainerID = ""
+		server.Status = storage.StatusStopped
+		server.LastStarted = nil
+
+		var cfg *storage.ServerConfig
+		if entry.Config != nil {
+			c := *entry.Config
+			ScrubSecrets(&c)
+			cfg = &c
+		}
+
+		if err := writeJSON(zw, path.Join("servers", server.ID+".json"), &Entry{Server: &server, Config: cfg}); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// Reads a fleet archive from disk
+func Read(archivePath string) (*Snapshot, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid fleet archive: %w", err)
+	}
+	defer zr.Close()
+
+	snapshot := &Snapshot{}
+	foundManifest := false
+	for _, f := range zr.File {
+		switch {
+		case f.Name == manifestName:
+			if err := readJSON(f, &snapshot.Manifest); err != nil {
+				return nil, err
+			}
+			foundManifest = true
+		case path.Dir(f.Name) == "servers" && path.Ext(f.Name) == ".json":
+			entry := &Entry{}
+			if err := readJSON(f, entry); err != nil {
+				return nil, err
+			}
+			if entry.Server == nil {
+				return nil, fmt.Errorf("%s is missing a server definition", f.Name)
+			}
+			snapshot.Entries = append(snapshot.Entries, entry)
+		}
+	}
+
+	if !foundManifest {
+		return nil, fmt.Errorf("fleet archive is missing %s", manifestName)
+	}
+	if snapshot.Manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("fleet archive format %d is newer than supported version %d", snapshot.Manifest.FormatVersion, FormatVersion)
+	}
+	return snapshot, nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return nil
+}
+
+func readJSON(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(io.LimitReader(rc, 16<<20)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", f.Name, err)
+	}
+	return nil
+}
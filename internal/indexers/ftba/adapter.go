@@ -0,0 +1,295 @@
+package ftba
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickheyer/discopanel/internal/config"
+	"github.com/nickheyer/discopanel/internal/indexers"
+	"github.com/nickheyer/discopanel/internal/minecraft"
+)
+
+func init() {
+	indexers.RegisterIndexer("ftba", func(_ string, cfg *config.Config) indexers.ModpackIndexer {
+		return NewIndexer(cfg)
+	})
+}
+
+// Implements ModpackIndexer
+var _ indexers.ModpackIndexer = (*FTBIndexer)(nil)
+
+// Pack details fetched in parallel per search, the search API only returns IDs
+const detailWorkers = 8
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Adapts the FTB modpacks API to the ModpackIndexer interface
+type FTBIndexer struct {
+	client *Client
+}
+
+// Creates a new FTB indexer
+// Note: the FTB public modpack API does not require an API key
+func NewIndexer(cfg *config.Config) *FTBIndexer {
+	return &FTBIndexer{
+		client: NewClient(cfg),
+	}
+}
+
+// Get the name of this indexer
+func (f *FTBIndexer) GetIndexerName() string {
+	return "ftba"
+}
+
+// Search for modpacks
+func (f *FTBIndexer) SearchModpacks(ctx context.Context, query string, gameVersion string, modLoader string, offset, limit int) (*indexers.SearchResult, error) {
+	// Neither endpoint pages, so ask for enough IDs to cover the offset
+	var resp *SearchResponse
+	var err error
+	if strings.TrimSpace(query) == "" {
+		resp, err = f.client.PopularModpacks(ctx, offset+limit)
+	} else {
+		resp, err = f.client.SearchModpacks(ctx, query, offset+limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := resp.Packs
+	if offset >= len(ids) {
+		ids = nil
+	} else {
+		ids = ids[offset:min(offset+limit, len(ids))]
+	}
+
+	packs, err := f.getPacks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Game version and loader filters are applied to the fetched details
+	modLoader = strings.ToLower(modLoader)
+	modpacks := make([]indexers.Modpack, 0, len(packs))
+	for _, pack := range packs {
+		modpack := f.convertPack(*pack)
+		if gameVersion != "" && !slices.Contains(modpack.GameVersions, gameVersion) {
+			continue
+		}
+		if modLoader != "" && !slices.Contains(modpack.ModLoaders, modLoader) {
+			continue
+		}
+		modpacks = append(modpacks, modpack)
+	}
+
+	total := resp.Total
+	if total == 0 {
+		total = len(resp.Packs)
+	}
+
+	return &indexers.SearchResult{
+		Modpacks:   modpacks,
+		TotalCount: total,
+		PageSize:   limit,
+		Offset:     offset,
+	}, nil
+}
+
+// Get a specific modpack
+func (f *FTBIndexer) GetModpack(ctx context.Context, modpackID string) (*indexers.Modpack, error) {
+	pack, err := f.client.GetModpack(ctx, modpackID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := f.convertPack(*pack)
+	return &result, nil
+}
+
+// Get files for a modpack
+func (f *FTBIndexer) GetModpackFiles(ctx context.Context, modpackID string) ([]indexers.ModpackFile, error) {
+	pack, err := f.client.GetModpack(ctx, modpackID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first, matching the other indexers' SortIndex ordering
+	versions := publicVersions(pack.Versions)
+	slices.SortStableFunc(versions, func(a, b Version) int {
+		return cmp.Compare(b.Updated, a.Updated)
+	})
+
+	result := make([]indexers.ModpackFile, 0, len(versions))
+	for versionIndex, version := range versions {
+		file := f.convertVersionToFile(*pack, version)
+		file.SortIndex = versionIndex
+		result = append(result, file)
+	}
+
+	return result, nil
+}
+
+// Fetches pack details for IDs in parallel, keeping the search order and skipping private or missing packs
+func (f *FTBIndexer) getPacks(ctx context.Context, ids []int) ([]*Pack, error) {
+	packs := make([]*Pack, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, detailWorkers)
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			packs[i], errs[i] = f.client.GetModpack(ctx, strconv.Itoa(id))
+		}()
+	}
+	wg.Wait()
+
+	result := make([]*Pack, 0, len(packs))
+	for i, pack := range packs {
+		if errs[i] != nil {
+			// A single delisted pack shouldn't fail the search, but auth and rate limits should
+			if indexers.IsNotFound(errs[i]) {
+				continue
+			}
+			return nil, errs[i]
+		}
+		if pack.Private {
+			continue
+		}
+		result = append(result, pack)
+	}
+	return result, nil
+}
+
+// Converts an FTB pack to a generic modpack
+func (f *FTBIndexer) convertPack(pack Pack) indexers.Modpack {
+	versions := publicVersions(pack.Versions)
+
+	var gameVersions, modLoaders []string
+	var latest *Version
+	for i, version := range versions {
+		for _, target := range version.Targets {
+			switch target.Type {
+			case "game":
+				if target.Name == "minecraft" && !slices.Contains(gameVersions, target.Version) {
+					gameVersions = append(gameVersions, target.Version)
+				}
+			case "modloader":
+				loader := strings.ToLower(target.Name)
+				if !slices.Contains(modLoaders, loader) {
+					modLoaders = append(modLoaders, loader)
+				}
+			}
+		}
+		if latest == nil || version.Updated > latest.Updated {
+			latest = &versions[i]
+		}
+	}
+
+	categories := make([]string, 0, len(pack.Tags))
+	for _, tag := range pack.Tags {
+		// Loader names are also used as tags
+		if _, isLoader := minecraft.DetectModpackLoader(tag.Name); !isLoader {
+			categories = append(categories, tag.Name)
+		}
+	}
+
+	latestFileID := ""
+	recommendedRAM := 0
+	if latest != nil {
+		latestFileID = strconv.Itoa(latest.ID)
+		recommendedRAM = latest.Specs.Recommended
+	}
+
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(pack.Name), "-"), "-")
+	released := time.Unix(pack.Released, 0)
+
+	return indexers.Modpack{
+		ID:             fmt.Sprintf("ftba-%d", pack.ID),
+		IndexerID:      strconv.Itoa(pack.ID),
+		Indexer:        "ftba",
+		Name:           pack.Name,
+		Slug:           slug,
+		Summary:        pack.Synopsis,
+		Description:    pack.Description,
+		LogoURL:        packArt(pack.Art),
+		WebsiteURL:     fmt.Sprintf("https://www.feed-the-beast.com/modpacks/%d-%s", pack.ID, slug),
+		DownloadCount:  pack.Installs,
+		Categories:     categories,
+		GameVersions:   gameVersions,
+		ModLoaders:     modLoaders,
+		LatestFileID:   latestFileID,
+		DateCreated:    released,
+		DateModified:   time.Unix(pack.Updated, 0),
+		DateReleased:   released,
+		RecommendedRAM: recommendedRAM,
+	}
+}
+
+// Converts an FTB pack version to a generic modpack file. The download is the
+// server installer, which fetches the server pack for that version.
+func (f *FTBIndexer) convertVersionToFile(pack Pack, version Version) indexers.ModpackFile {
+	releaseType := "release"
+	switch strings.ToLower(version.Type) {
+	case "beta":
+		releaseType = "beta"
+	case "alpha":
+		releaseType = "alpha"
+	}
+
+	var gameVersions []string
+	modLoader := ""
+	for _, target := range version.Targets {
+		switch target.Type {
+		case "game":
+			gameVersions = append(gameVersions, target.Version)
+		case "modloader":
+			modLoader = strings.ToLower(target.Name)
+		}
+	}
+
+	return indexers.ModpackFile{
+		ID:            strconv.Itoa(version.ID),
+		ModpackID:     strconv.Itoa(pack.ID),
+		DisplayName:   fmt.Sprintf("%s %s", pack.Name, version.Name),
+		FileName:      fmt.Sprintf("serverinstall_%d_%d", pack.ID, version.ID),
+		FileDate:      time.Unix(version.Updated, 0),
+		ReleaseType:   releaseType,
+		DownloadURL:   ServerInstallerURL(pack.ID, version.ID),
+		GameVersions:  gameVersions,
+		ModLoader:     modLoader,
+		VersionNumber: version.Name,
+	}
+}
+
+func publicVersions(versions []Version) []Version {
+	result := make([]Version, 0, len(versions))
+	for _, version := range versions {
+		if !version.Private {
+			result = append(result, version)
+		}
+	}
+	return result
+}
+
+// Prefers the square pack icon, falling back to any art
+func packArt(art []Art) string {
+	for _, a := range art {
+		if a.Type == "square" {
+			return a.URL
+		}
+	}
+	if len(art) > 0 {
+		return art[0].URL
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package ftba
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nickheyer/discopanel/internal/config"
+	"github.com/nickheyer/discopanel/internal/indexers"
+)
+
+const (
+	BaseURL = "https://api.feed-the-beast.com/v1/modpacks/public/modpack"
+)
+
+type Client struct {
+	http *indexers.HTTPClient
+}
+
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		http: indexers.NewHTTPClient("ftba", cfg.Server.UserAgent, nil),
+	}
+}
+
+// SearchResponse represents the FTB search and popular-list responses, which only carry pack IDs
+type SearchResponse struct {
+	Packs  []int  `json:"packs"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Status string `json:"status"`
+}
+
+// Pack represents an FTB modpack from GET /{id}
+type Pack struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Synopsis    string    `json:"synopsis"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Art         []Art     `json:"art"`
+	Authors     []Author  `json:"authors"`
+	Tags        []Tag     `json:"tags"`
+	Versions    []Version `json:"versions"`
+	Installs    int64     `json:"installs"`
+	Plays       int64     `json:"plays"`
+	Released    int64     `json:"released"` // Unix seconds
+	Updated     int64     `json:"updated"`  // Unix seconds
+	Private     bool      `json:"private"`
+	Status      string    `json:"status"`
+	Message     string    `json:"message"`
+}
+
+type Art struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"` // "square", "splash", "logo", ...
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type Author struct {
+	Name    string `json:"name"`
+	Website string `json:"website"`
+}
+
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Version represents a pack release
+type Version struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"` // "Release", "Beta", "Alpha"
+	Updated int64    `json:"updated"`
+	Private bool     `json:"private"`
+	Specs   Specs    `json:"specs"`
+	Targets []Target `json:"targets"`
+}
+
+// Specs holds the pack author's memory requirements in megabytes
+type Specs struct {
+	Minimum     int `json:"minimum"`
+	Recommended int `json:"recommended"`
+}
+
+// Target is a component a version runs on, e.g. {name: "minecraft", type: "game"} or {name: "forge", type: "modloader"}
+type Target struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
+}
+
+// SearchModpacks searches FTB modpacks by name, returning matching pack IDs
+func (c *Client) SearchModpacks(ctx context.Context, query string, limit int) (*SearchResponse, error) {
+	params := url.Values{}
+	params.Set("term", query)
+
+	var searchResp SearchResponse
+	if err := c.http.DoJSON(ctx, fmt.Sprintf("%s/search/%d?%s", BaseURL, limit, params.Encode()), &searchResp); err != nil {
+		return nil, err
+	}
+
+	return &searchResp, nil
+}
+
+// PopularModpacks lists the most installed FTB modpacks, used when there is no search term
+func (c *Client) PopularModpacks(ctx context.Context, limit int) (*SearchResponse, error) {
+	var searchResp SearchResponse
+	if err := c.http.DoJSON(ctx, fmt.Sprintf("%s/popular/installs/%d", BaseURL, limit), &searchResp); err != nil {
+		return nil, err
+	}
+
+	return &searchResp, nil
+}
+
+// GetModpack retrieves a modpack with its versions
+func (c *Client) GetModpack(ctx context.Context, modpackID string) (*Pack, error) {
+	reqURL := fmt.Sprintf("%s/%s", BaseURL, url.PathEscape(modpackID))
+
+	var pack Pack
+	if err := c.http.DoJSON(ctx, reqURL, &pack); err != nil {
+		return nil, err
+	}
+
+	// The API reports unknown packs as a 200 with an error status
+	if pack.Status == "error" {
+		return nil, indexers.NewAPIError("ftba", http.StatusNotFound, reqURL, pack.Message)
+	}
+
+	return &pack, nil
+}
+
+// ServerInstallerURL is the Linux server installer for a pack version
+func ServerInstallerURL(modpackID, versionID int) string {
+	return fmt.Sprintf("%s/%d/%d/server/linux", BaseURL, modpackID, versionID)
+}
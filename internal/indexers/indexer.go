@@ -51,6 +51,9 @@ type Modpack struct {
 	DateCreated   time.Time `json:"date_created"`
 	DateModified  time.Time `json:"date_modified"`
 	DateReleased  time.Time `json:"date_released"`
+
+	// Memory the pack author recommends in MB, 0 when the indexer doesn't publish one
+	RecommendedRAM int `json:"recommended_ram,omitempty"`
 }
 
 // ModpackFile represents a downloadable file for a modpack
@@ -23,9 +23,11 @@ type ServerMetrics struct {
 	ServerID      string
 	CPUPercent    float64
 	MemoryUsage   float64 // MB
+	MemoryLimit   float64 // MB (container limit)
 	DiskUsage     int64   // bytes (total server data)
 	DiskTotal     int64   // bytes
 	WorldSize     int64   // bytes (world directory only)
+	CrashDumps    int     // JVM error logs and heap dumps in the data dir
 	PlayersOnline int
 	TPS           float64
 	LastUpdated   time.Time
@@ -40,6 +42,11 @@ type ServerMetrics struct {
 	MaxPlayers      int
 	Favicon         string // Base64 PNG (data:image/png;base64,...)
 	SLPLastUpdated  time.Time
+
+	// Memory trend over the rolling window
+	MemoryTrend         float64       // MB per hour
+	MemoryFullIn        time.Duration // projected time until MemoryLimit, 0 when not climbing
+	MemoryLeakSuspected bool          // steady climb toward the limit
 }
 
 // Configuration for metrics collector
@@ -50,6 +57,10 @@ type CollectorConfig struct {
 	SLPInterval   time.Duration // default 15s
 	SLPTimeout    time.Duration // default 5s
 	SLPEnabled    bool          // default true
+
+	// Memory leak detection
+	MemoryWindow      time.Duration // default 30m, history kept per server
+	MemoryLeakHorizon time.Duration // default 6h, warn when the limit is projected within this
 }
 
 // Get default collector configuration
@@ -61,6 +72,9 @@ func DefaultConfig() CollectorConfig {
 		SLPInterval:   15 * time.Second,
 		SLPTimeout:    5 * time.Second,
 		SLPEnabled:    true,
+
+		MemoryWindow:      30 * time.Minute,
+		MemoryLeakHorizon: 6 * time.Hour,
 	}
 }
 
@@ -86,6 +100,10 @@ type Collector struct {
 	lifecycle   map[string]lifecycleState
 	lifecycleMu sync.Mutex
 
+	// Per-server memory samples for trend detection
+	memory   map[string]*memoryHistory
+	memoryMu sync.Mutex
+
 	running  bool
 	stopChan chan struct{}
 	wg       sync.WaitGroup
@@ -109,6 +127,7 @@ func NewCollector(store *storage.Store, docker *docker.Client, sender *command.S
 		log:             log,
 		metrics:         make(map[string]*ServerMetrics),
 		lifecycle:       make(map[string]lifecycleState),
+		memory:          make(map[string]*memoryHistory),
 		collectorConfig: cc,
 	}
 }
@@ -254,6 +273,7 @@ func (c *Collector) collectDockerStats() {
 		// Check if server is running
 		status, err := c.docker.GetContainerStatus(ctx, server.ContainerID)
 		if err != nil || (status != storage.StatusRunning && status != storage.StatusUnhealthy) {
+			c.clearMemory(server.ID)
 			continue
 		}
 
@@ -269,6 +289,7 @@ func (c *Collector) collectDockerStats() {
 			m.MemoryUsage = stats.MemoryUsage
 			m.LastUpdated = time.Now()
 		})
+		c.recordMemory(server.ID, stats.MemoryUsage, stats.MemoryLimit)
 	}
 }
 
@@ -378,10 +399,13 @@ func (c *Collector) collectDiskUsage() {
 			totalWorldSize += size
 		}
 
+		dumps, _ := files.FindCrashDumps(server.DataPath)
+
 		c.updateMetrics(server.ID, func(m *ServerMetrics) {
 			m.DiskUsage = totalSize
 			m.DiskTotal = diskTotal
 			m.WorldSize = totalWorldSize
+			m.CrashDumps = len(dumps)
 			m.LastUpdated = time.Now()
 		})
 	}
@@ -406,6 +430,10 @@ func (c *Collector) RemoveMetrics(serverID string) {
 	delete(c.metrics, serverID)
 	c.mu.Unlock()
 	c.clearLifecycle(serverID)
+
+	c.memoryMu.Lock()
+	delete(c.memory, serverID)
+	c.memoryMu.Unlock()
 }
 
 // Collects SLP data
@@ -455,22 +483,24 @@ func (c *Collector) collectSLPData() {
 			continue
 		}
 
-		// Get container IP
-		containerIP, err := proxy.GetContainerIP(server.ContainerID, c.config.Docker.NetworkName)
-		if err != nil {
-			c.log.Debug("Metrics collector SLP: failed to get container IP for %s: %v", server.ID, err)
-			c.updateMetrics(server.ID, func(m *ServerMetrics) {
-				m.SLPAvailable = false
-			})
-			continue
+		// A remote daemon's containers are only reached on the ports it publishes
+		containerIP, port := c.docker.RemoteHost(), server.Port
+		if containerIP == "" {
+			containerIP, err = proxy.GetContainerIP(server.ContainerID, c.config.Docker.NetworkName)
+			if err != nil {
+				c.log.Debug("Metrics collector SLP: failed to get container IP for %s: %v", server.ID, err)
+				c.updateMetrics(server.ID, func(m *ServerMetrics) {
+					m.SLPAvailable = false
+				})
+				continue
+			}
+			if server.ProxyHostname != "" || port == 0 {
+				port = docker.DefaultMinecraftPort // Proxy listens on default port (inside container)
+			}
 		}
 
 		// SLP ping w/ server version for protocol
 		slpCtx, slpCancel := context.WithTimeout(ctx, c.collectorConfig.SLPTimeout)
-		port := server.Port
-		if server.ProxyHostname != "" || port == 0 {
-			port = docker.DefaultMinecraftPort // Proxy listens on default port (inside container)
-		}
 		result, err := slpClient.Ping(slpCtx, containerIP, port, server.MCVersion)
 		slpCancel()
 
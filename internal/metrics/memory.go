@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Minimum fit before a climb counts as steady rather than GC sawtooth
+const memoryLeakMinR2 = 0.8
+
+type memorySample struct {
+	at    time.Time
+	usage float64 // MB
+}
+
+// Rolling memory window for one server
+type memoryHistory struct {
+	samples []memorySample
+	leaking bool // last verdict, so the warning is logged once per episode
+}
+
+// Memory trend derived from a servers recent samples
+type memoryTrend struct {
+	perHour float64       // MB per hour, least squares slope over the window
+	fullIn  time.Duration // projected time until the limit is reached, 0 when not climbing
+	leaking bool          // steady climb that reaches the limit within the leak horizon
+}
+
+// Adds a memory sample, drops samples older than the window and updates the servers trend
+func (c *Collector) recordMemory(serverID string, usage, limit float64) {
+	now := time.Now()
+	window := c.collectorConfig.MemoryWindow
+
+	c.memoryMu.Lock()
+	history, ok := c.memory[serverID]
+	if !ok {
+		history = &memoryHistory{}
+		c.memory[serverID] = history
+	}
+	history.samples = append(history.samples, memorySample{at: now, usage: usage})
+	cutoff := 0
+	for cutoff < len(history.samples) && now.Sub(history.samples[cutoff].at) > window {
+		cutoff++
+	}
+	history.samples = history.samples[cutoff:]
+
+	trend := c.memoryTrend(history.samples, usage, limit)
+	wasLeaking := history.leaking
+	history.leaking = trend.leaking
+	c.memoryMu.Unlock()
+
+	if trend.leaking && !wasLeaking {
+		c.log.Warn("Server %s memory is climbing %.0f MB/h and will reach its %.0f MB limit in about %s, possible memory leak",
+			serverID, trend.perHour, limit, trend.fullIn.Round(time.Minute))
+	}
+
+	c.updateMetrics(serverID, func(m *ServerMetrics) {
+		m.MemoryLimit = limit
+		m.MemoryTrend = trend.perHour
+		m.MemoryFullIn = trend.fullIn
+		m.MemoryLeakSuspected = trend.leaking
+	})
+}
+
+// Forgets a servers memory window, e.g. when it stops, so a restart starts from a clean baseline
+func (c *Collector) clearMemory(serverID string) {
+	c.memoryMu.Lock()
+	_, ok := c.memory[serverID]
+	delete(c.memory, serverID)
+	c.memoryMu.Unlock()
+
+	if ok {
+		c.updateMetrics(serverID, func(m *ServerMetrics) {
+			m.MemoryTrend = 0
+			m.MemoryFullIn = 0
+			m.MemoryLeakSuspected = false
+		})
+	}
+}
+
+// Fits a line through the samples. No trend is reported until the samples
+// cover half the window, which also keeps JVM warmup from looking like a leak.
+func (c *Collector) memoryTrend(samples []memorySample, usage, limit float64) memoryTrend {
+	if len(samples) < 3 || samples[len(samples)-1].at.Sub(samples[0].at) < c.collectorConfig.MemoryWindow/2 {
+		return memoryTrend{}
+	}
+
+	slope, r2 := linearFit(samples)
+	trend := memoryTrend{perHour: slope}
+	if slope <= 0 || limit <= 0 {
+		return trend
+	}
+
+	headroom := max(limit-usage, 0)
+	trend.fullIn = time.Duration(headroom / slope * float64(time.Hour))
+	trend.leaking = r2 >= memoryLeakMinR2 && trend.fullIn <= c.collectorConfig.MemoryLeakHorizon
+	return trend
+}
+
+// Least squares slope in MB per hour and its coefficient of determination
+func linearFit(samples []memorySample) (float64, float64) {
+	n := float64(len(samples))
+	start := samples[0].at
+
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.at.Sub(start).Hours()
+		sumY += s.usage
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy, syy float64
+	for _, s := range samples {
+		dx := s.at.Sub(start).Hours() - meanX
+		dy := s.usage - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, 0
+	}
+
+	slope := sxy / sxx
+	if syy == 0 {
+		return slope, 0
+	}
+	return slope, (sxy * sxy) / (sxx * syy)
+}
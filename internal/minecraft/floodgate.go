@@ -0,0 +1,55 @@
+package minecraft
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	models "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/files"
+)
+
+// Floodgate's key is a raw AES-128 key, despite the .pem name
+const floodgateKeySize = 16
+
+// FloodgateKeyPath is where Floodgate keeps its key inside a server's data dir, e.g.
+// plugins/floodgate/key.pem on Paper. Empty for loaders Floodgate can't run on.
+func FloodgateKeyPath(serverDataPath string, loader models.ModLoader) string {
+	configDir := GetModLoaderInfo(loader).ConfigDirectory
+	if serverDataPath == "" || configDir == "" {
+		return ""
+	}
+	return filepath.Join(serverDataPath, configDir, "floodgate", "key.pem")
+}
+
+// EnsureFloodgateKey returns the server's Floodgate key, generating one when Floodgate
+// hasn't made its own yet. An existing key is kept so players already linked stay valid.
+func EnsureFloodgateKey(serverDataPath string, loader models.ModLoader) (string, error) {
+	keyPath := FloodgateKeyPath(serverDataPath, loader)
+	if keyPath == "" {
+		return "", fmt.Errorf("floodgate doesn't run on %s servers", loader)
+	}
+	if info, err := os.Stat(keyPath); err == nil && info.Size() > 0 {
+		return keyPath, nil
+	}
+
+	// Floodgate writes its config next to the key, so the server user must own the dirs
+	for _, dir := range []string{filepath.Dir(filepath.Dir(keyPath)), filepath.Dir(keyPath)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		files.ChownLike(dir, serverDataPath)
+	}
+
+	key := make([]byte, floodgateKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate floodgate key: %w", err)
+	}
+	// Readable by the sidecar, which runs as a different user than the server
+	if err := os.WriteFile(keyPath, key, 0644); err != nil {
+		return "", fmt.Errorf("failed to write floodgate key: %w", err)
+	}
+	files.ChownLike(keyPath, serverDataPath)
+	return keyPath, nil
+}
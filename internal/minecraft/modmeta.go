@@ -0,0 +1,248 @@
+package minecraft
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata files are small, anything bigger is not worth reading
+const maxModMetadataSize = 1 << 20
+
+// ModMetadata is what a mod or plugin jar says about itself
+type ModMetadata struct {
+	ModID       string
+	Name        string
+	Version     string
+	Description string
+	Authors     []string
+	Website     string
+}
+
+// ReadModMetadata reads the loader descriptor inside a mod jar: fabric.mod.json,
+// quilt.mod.json, META-INF/(neoforge.)mods.toml or a Bukkit plugin.yml.
+// Returns nil when the jar has none of them or can't be opened.
+func ReadModMetadata(jarPath string) *ModMetadata {
+	zr, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	parsers := []struct {
+		name  string
+		parse func([]byte) *ModMetadata
+	}{
+		{"fabric.mod.json", parseFabricModJSON},
+		{"quilt.mod.json", parseQuiltModJSON},
+		{"META-INF/neoforge.mods.toml", parseForgeModsTOML},
+		{"META-INF/mods.toml", parseForgeModsTOML},
+		{"plugin.yml", parsePluginYML},
+		{"paper-plugin.yml", parsePluginYML},
+	}
+
+	for _, p := range parsers {
+		f, ok := entries[p.name]
+		if !ok {
+			continue
+		}
+		data, err := readZipEntry(f)
+		if err != nil {
+			continue
+		}
+		meta := p.parse(data)
+		if meta == nil {
+			continue
+		}
+
+		// Forge jars usually take their version from the jar manifest at build time
+		if strings.Contains(meta.Version, "${") {
+			meta.Version = ""
+			if manifest, ok := entries["META-INF/MANIFEST.MF"]; ok {
+				if data, err := readZipEntry(manifest); err == nil {
+					meta.Version = manifestValue(data, "Implementation-Version")
+				}
+			}
+		}
+		return meta
+	}
+
+	return nil
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxModMetadataSize))
+}
+
+// fabric.mod.json lists authors as plain strings or {"name": ...} objects
+type fabricPerson struct {
+	Name string
+}
+
+func (p *fabricPerson) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.Name); err == nil {
+		return nil
+	}
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	p.Name = obj.Name
+	return nil
+}
+
+func parseFabricModJSON(data []byte) *ModMetadata {
+	var mod struct {
+		ID          string         `json:"id"`
+		Name        string         `json:"name"`
+		Version     string         `json:"version"`
+		Description string         `json:"description"`
+		Authors     []fabricPerson `json:"authors"`
+		Contact     struct {
+			Homepage string `json:"homepage"`
+		} `json:"contact"`
+	}
+	if err := json.Unmarshal(data, &mod); err != nil || mod.ID == "" {
+		return nil
+	}
+
+	meta := &ModMetadata{
+		ModID:       mod.ID,
+		Name:        mod.Name,
+		Version:     mod.Version,
+		Description: mod.Description,
+		Website:     mod.Contact.Homepage,
+	}
+	for _, author := range mod.Authors {
+		if author.Name != "" {
+			meta.Authors = append(meta.Authors, author.Name)
+		}
+	}
+	return meta
+}
+
+func parseQuiltModJSON(data []byte) *ModMetadata {
+	var mod struct {
+		QuiltLoader struct {
+			ID       string `json:"id"`
+			Version  string `json:"version"`
+			Metadata struct {
+				Name         string            `json:"name"`
+				Description  string            `json:"description"`
+				Contributors map[string]string `json:"contributors"`
+				Contact      struct {
+					Homepage string `json:"homepage"`
+				} `json:"contact"`
+			} `json:"metadata"`
+		} `json:"quilt_loader"`
+	}
+	if err := json.Unmarshal(data, &mod); err != nil || mod.QuiltLoader.ID == "" {
+		return nil
+	}
+
+	loader := mod.QuiltLoader
+	meta := &ModMetadata{
+		ModID:       loader.ID,
+		Name:        loader.Metadata.Name,
+		Version:     loader.Version,
+		Description: loader.Metadata.Description,
+		Website:     loader.Metadata.Contact.Homepage,
+	}
+	for name := range loader.Metadata.Contributors {
+		meta.Authors = append(meta.Authors, name)
+	}
+	return meta
+}
+
+func parseForgeModsTOML(data []byte) *ModMetadata {
+	var file struct {
+		DisplayURL string `toml:"displayURL"`
+		Mods       []struct {
+			ModID       string `toml:"modId"`
+			Version     string `toml:"version"`
+			DisplayName string `toml:"displayName"`
+			Description string `toml:"description"`
+			Authors     string `toml:"authors"`
+			DisplayURL  string `toml:"displayURL"`
+		} `toml:"mods"`
+	}
+	if err := toml.Unmarshal(data, &file); err != nil || len(file.Mods) == 0 || file.Mods[0].ModID == "" {
+		return nil
+	}
+
+	// A jar can bundle several mods, the first entry is the jar's own
+	mod := file.Mods[0]
+	meta := &ModMetadata{
+		ModID:       mod.ModID,
+		Name:        mod.DisplayName,
+		Version:     mod.Version,
+		Description: strings.TrimSpace(mod.Description),
+		Website:     mod.DisplayURL,
+	}
+	if meta.Website == "" {
+		meta.Website = file.DisplayURL
+	}
+	if mod.Authors != "" {
+		meta.Authors = []string{mod.Authors}
+	}
+	return meta
+}
+
+func parsePluginYML(data []byte) *ModMetadata {
+	var plugin struct {
+		Name        string   `yaml:"name"`
+		Version     string   `yaml:"version"`
+		Description string   `yaml:"description"`
+		Author      string   `yaml:"author"`
+		Authors     []string `yaml:"authors"`
+		Website     string   `yaml:"website"`
+	}
+	if err := yaml.Unmarshal(data, &plugin); err != nil || plugin.Name == "" {
+		return nil
+	}
+
+	meta := &ModMetadata{
+		ModID:       strings.ToLower(plugin.Name),
+		Name:        plugin.Name,
+		Version:     plugin.Version,
+		Description: plugin.Description,
+		Website:     plugin.Website,
+		Authors:     plugin.Authors,
+	}
+	if plugin.Author != "" {
+		meta.Authors = append([]string{plugin.Author}, meta.Authors...)
+	}
+	return meta
+}
+
+// Reads a main-section attribute from a jar MANIFEST.MF
+func manifestValue(data []byte, key string) string {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break // End of the main section
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(k, key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -3,10 +3,15 @@ package minecraft
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+
+	models "github.com/nickheyer/discopanel/internal/db"
 )
 
 // ServerProperties represents the Minecraft server.properties file
@@ -22,8 +27,13 @@ func LoadServerProperties(serverDataPath string) (ServerProperties, error) {
 	}
 	defer file.Close()
 
+	return ParseServerProperties(file)
+}
+
+// ParseServerProperties reads key=value lines, skipping blanks and comments
+func ParseServerProperties(r io.Reader) (ServerProperties, error) {
 	properties := make(ServerProperties)
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -157,3 +167,234 @@ func (p ServerProperties) SetBool(key string, value bool) {
 		p[key] = "false"
 	}
 }
+
+// server.properties keys backed by a ServerConfig field, by the field's env tag.
+// Anything else round-trips through CustomServerProperties.
+var serverPropertyEnvs = map[string]string{
+	"accepts-transfers":                       "ACCEPTS_TRANSFERS",
+	"allow-flight":                            "ALLOW_FLIGHT",
+	"allow-nether":                            "ALLOW_NETHER",
+	"announce-player-achievements":            "ANNOUNCE_PLAYER_ACHIEVEMENTS",
+	"broadcast-console-to-ops":                "BROADCAST_CONSOLE_TO_OPS",
+	"broadcast-rcon-to-ops":                   "BROADCAST_RCON_TO_OPS",
+	"bug-report-link":                         "BUG_REPORT_LINK",
+	"difficulty":                              "DIFFICULTY",
+	"enable-command-block":                    "ENABLE_COMMAND_BLOCK",
+	"enable-query":                            "ENABLE_QUERY",
+	"enable-rcon":                             "ENABLE_RCON",
+	"enable-status":                           "ENABLE_STATUS",
+	"enforce-secure-profile":                  "ENFORCE_SECURE_PROFILE",
+	"enforce-whitelist":                       "ENFORCE_WHITELIST",
+	"entity-broadcast-range-percentage":       "ENTITY_BROADCAST_RANGE_PERCENTAGE",
+	"force-gamemode":                          "FORCE_GAMEMODE",
+	"function-permission-level":               "FUNCTION_PERMISSION_LEVEL",
+	"gamemode":                                "MODE",
+	"generate-structures":                     "GENERATE_STRUCTURES",
+	"generator-settings":                      "GENERATOR_SETTINGS",
+	"hardcore":                                "HARDCORE",
+	"hide-online-players":                     "HIDE_ONLINE_PLAYERS",
+	"level-name":                              "LEVEL",
+	"level-seed":                              "SEED",
+	"level-type":                              "LEVEL_TYPE",
+	"log-ips":                                 "LOG_IPS",
+	"management-server-enabled":               "MANAGEMENT_SERVER_ENABLED",
+	"management-server-host":                  "MANAGEMENT_SERVER_HOST",
+	"management-server-port":                  "MANAGEMENT_SERVER_PORT",
+	"management-server-secret":                "MANAGEMENT_SERVER_SECRET",
+	"management-server-tls-enabled":           "MANAGEMENT_SERVER_TLS_ENABLED",
+	"management-server-tls-keystore":          "MANAGEMENT_SERVER_TLS_KEYSTORE",
+	"management-server-tls-keystore-password": "MANAGEMENT_SERVER_TLS_KEYSTORE_PASSWORD",
+	"max-build-height":                        "MAX_BUILD_HEIGHT",
+	"max-chained-neighbor-updates":            "MAX_CHAINED_NEIGHBOR_UPDATES",
+	"max-players":                             "MAX_PLAYERS",
+	"max-world-size":                          "MAX_WORLD_SIZE",
+	"motd":                                    "MOTD",
+	"network-compression-threshold":           "NETWORK_COMPRESSION_THRESHOLD",
+	"online-mode":                             "ONLINE_MODE",
+	"op-permission-level":                     "OP_PERMISSION_LEVEL",
+	"pause-when-empty-seconds":                "PAUSE_WHEN_EMPTY_SECONDS",
+	"player-idle-timeout":                     "PLAYER_IDLE_TIMEOUT",
+	"prevent-proxy-connections":               "PREVENT_PROXY_CONNECTIONS",
+	"pvp":                                     "PVP",
+	"query.port":                              "QUERY_PORT",
+	"rate-limit":                              "RATE_LIMIT",
+	"rcon.password":                           "RCON_PASSWORD",
+	"rcon.port":                               "RCON_PORT",
+	"region-file-compression":                 "REGION_FILE_COMPRESSION",
+	"require-resource-pack":                   "RESOURCE_PACK_ENFORCE",
+	"resource-pack":                           "RESOURCE_PACK",
+	"resource-pack-id":                        "RESOURCE_PACK_ID",
+	"resource-pack-prompt":                    "RESOURCE_PACK_PROMPT",
+	"resource-pack-sha1":                      "RESOURCE_PACK_SHA1",
+	"server-name":                             "SERVER_NAME",
+	"server-port":                             "SERVER_PORT",
+	"simulation-distance":                     "SIMULATION_DISTANCE",
+	"snooper-enabled":                         "SNOOPER_ENABLED",
+	"spawn-animals":                           "SPAWN_ANIMALS",
+	"spawn-monsters":                          "SPAWN_MONSTERS",
+	"spawn-npcs":                              "SPAWN_NPCS",
+	"spawn-protection":                        "SPAWN_PROTECTION",
+	"status-heartbeat-interval":               "STATUS_HEARTBEAT_INTERVAL",
+	"sync-chunk-writes":                       "SYNC_CHUNK_WRITES",
+	"use-native-transport":                    "USE_NATIVE_TRANSPORT",
+	"view-distance":                           "VIEW_DISTANCE",
+	"white-list":                              "ENABLE_WHITELIST",
+}
+
+// PropertiesImport reports where each imported key ended up
+type PropertiesImport struct {
+	Mapped  []string // Set on their ServerConfig field
+	Custom  []string // Kept in CustomServerProperties
+	Ignored []string // Managed by DiscoPanel, e.g. server-port
+}
+
+// ImportServerProperties applies server.properties values onto config. Keys without a
+// field, or whose value the field can't hold exactly, are merged into CustomServerProperties
+// so an export renders the same file back. Keys missing from props are left untouched.
+func ImportServerProperties(config *models.ServerConfig, props ServerProperties) *PropertiesImport {
+	fields := serverPropertyFields(config)
+	custom := customServerProperties(config)
+	result := &PropertiesImport{}
+
+	for _, key := range sortedPropertyKeys(props) {
+		value := props[key]
+		if field, ok := fields[key]; ok {
+			if field.system {
+				result.Ignored = append(result.Ignored, key)
+				continue
+			}
+			if setPropertyValue(field.value, value) {
+				delete(custom, key)
+				result.Mapped = append(result.Mapped, key)
+				continue
+			}
+		}
+		custom[key] = value
+		result.Custom = append(result.Custom, key)
+	}
+
+	if len(custom) == 0 {
+		config.CustomServerProperties = nil
+	} else {
+		var lines []string
+		for _, key := range sortedPropertyKeys(custom) {
+			lines = append(lines, key+"="+custom[key])
+		}
+		rendered := strings.Join(lines, "\n")
+		config.CustomServerProperties = &rendered
+	}
+	return result
+}
+
+// ExportServerProperties renders config as a server.properties file, mapped fields first
+// overlaid by CustomServerProperties the same way the container applies them
+func ExportServerProperties(config *models.ServerConfig) string {
+	props := make(ServerProperties)
+	for key, field := range serverPropertyFields(config) {
+		if value, ok := propertyValue(field.value); ok {
+			props[key] = value
+		}
+	}
+	for key, value := range customServerProperties(config) {
+		props[key] = value
+	}
+
+	var b strings.Builder
+	b.WriteString("#Minecraft server properties\n")
+	for _, key := range sortedPropertyKeys(props) {
+		fmt.Fprintf(&b, "%s=%s\n", key, props[key])
+	}
+	return b.String()
+}
+
+type serverPropertyField struct {
+	value  reflect.Value
+	system bool // Synced from the server record, not user editable
+}
+
+// Resolves serverPropertyEnvs to the settable fields of config
+func serverPropertyFields(config *models.ServerConfig) map[string]serverPropertyField {
+	byEnv := make(map[string]serverPropertyField)
+	configValue := reflect.ValueOf(config).Elem()
+	configType := configValue.Type()
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if env := field.Tag.Get("env"); env != "" && env != "-" {
+			byEnv[env] = serverPropertyField{value: configValue.Field(i), system: field.Tag.Get("system") == "true"}
+		}
+	}
+
+	fields := make(map[string]serverPropertyField, len(serverPropertyEnvs))
+	for key, env := range serverPropertyEnvs {
+		if field, ok := byEnv[env]; ok {
+			fields[key] = field
+		}
+	}
+	return fields
+}
+
+func customServerProperties(config *models.ServerConfig) ServerProperties {
+	if config.CustomServerProperties == nil {
+		return make(ServerProperties)
+	}
+	props, err := ParseServerProperties(strings.NewReader(*config.CustomServerProperties))
+	if err != nil {
+		return make(ServerProperties)
+	}
+	return props
+}
+
+// Formats a set pointer field, false when unset
+func propertyValue(field reflect.Value) (string, bool) {
+	if field.Kind() != reflect.Pointer || field.IsNil() {
+		return "", false
+	}
+	elem := field.Elem()
+	switch elem.Kind() {
+	case reflect.String:
+		return elem.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(elem.Bool()), true
+	case reflect.Int:
+		return strconv.FormatInt(elem.Int(), 10), true
+	}
+	return "", false
+}
+
+// Sets a pointer field from a property value. Only values that format back to the
+// exact same text are accepted, so "TRUE" or "08" stay custom rather than being rewritten.
+func setPropertyValue(field reflect.Value, value string) bool {
+	if field.Kind() != reflect.Pointer || !field.CanSet() {
+		return false
+	}
+	ptr := reflect.New(field.Type().Elem())
+	switch ptr.Elem().Kind() {
+	case reflect.String:
+		ptr.Elem().SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil || strconv.FormatBool(b) != value {
+			return false
+		}
+		ptr.Elem().SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil || strconv.Itoa(i) != value {
+			return false
+		}
+		ptr.Elem().SetInt(int64(i))
+	default:
+		return false
+	}
+	field.Set(ptr)
+	return true
+}
+
+func sortedPropertyKeys(props ServerProperties) []string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package minecraft
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	models "github.com/nickheyer/discopanel/internal/db"
+)
+
+const roundTripProperties = `#Minecraft server properties
+#Mon Oct 12 18:04:55 UTC 2026
+# a comment with key=value in it
+difficulty=hard
+gamemode=survival
+max-players=40
+spawn-protection=16
+motd=§aWelcome\: to the \\ server \#1
+level-seed=-4172144997902289642
+generator-settings={"biome"\:"minecraft\:plains","layers"\=[]}
+resource-pack=https\://example.com/pack.zip?a=1&b=2
+pvp = true
+online-mode=TRUE
+view-distance=08
+white-list=false
+server-port=25570
+custom-plugin.setting=some value
+another-unknown-key=
+`
+
+func importProperties(t *testing.T, config *models.ServerConfig, text string) *PropertiesImport {
+	t.Helper()
+	props, err := ParseServerProperties(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseServerProperties: %v", err)
+	}
+	return ImportServerProperties(config, props)
+}
+
+func TestServerPropertiesRoundTrip(t *testing.T) {
+	original, err := ParseServerProperties(strings.NewReader(roundTripProperties))
+	if err != nil {
+		t.Fatalf("ParseServerProperties: %v", err)
+	}
+	for key := range original {
+		if strings.HasPrefix(key, "#") {
+			t.Fatalf("comment parsed as property %q", key)
+		}
+	}
+
+	config := &models.ServerConfig{}
+	result := importProperties(t, config, roundTripProperties)
+	if !slices.Equal(result.Ignored, []string{"max-players", "server-port"}) {
+		t.Errorf("Ignored = %v, want [max-players server-port]", result.Ignored)
+	}
+	for _, key := range []string{"custom-plugin.setting", "another-unknown-key", "online-mode", "view-distance"} {
+		if !slices.Contains(result.Custom, key) {
+			t.Errorf("%s not kept as a custom property, Custom = %v", key, result.Custom)
+		}
+	}
+	for _, key := range []string{"difficulty", "spawn-protection", "motd", "pvp", "white-list"} {
+		if !slices.Contains(result.Mapped, key) {
+			t.Errorf("%s not mapped to its field, Mapped = %v", key, result.Mapped)
+		}
+	}
+
+	exported := ExportServerProperties(config)
+	if !strings.HasPrefix(exported, "#Minecraft server properties\n") {
+		t.Errorf("export doesn't start with the header comment:\n%s", exported)
+	}
+	roundTripped, err := ParseServerProperties(strings.NewReader(exported))
+	if err != nil {
+		t.Fatalf("ParseServerProperties of export: %v", err)
+	}
+
+	// Everything comes back byte for byte, escapes included, except what DiscoPanel manages
+	want := maps.Clone(original)
+	delete(want, "max-players")
+	delete(want, "server-port")
+	if !maps.Equal(roundTripped, want) {
+		for key, value := range want {
+			if got, ok := roundTripped[key]; !ok || got != value {
+				t.Errorf("%s = %q (present %v), want %q", key, got, ok, value)
+			}
+		}
+		for key, value := range roundTripped {
+			if _, ok := want[key]; !ok {
+				t.Errorf("unexpected %s = %q in export", key, value)
+			}
+		}
+	}
+
+	// Importing the export again changes nothing
+	again := &models.ServerConfig{}
+	importProperties(t, again, exported)
+	if second := ExportServerProperties(again); second != exported {
+		t.Errorf("second export differs:\n%s\nwant:\n%s", second, exported)
+	}
+}
+
+func TestImportServerPropertiesMovesCustomKeyToField(t *testing.T) {
+	custom := "difficulty=Hard\nunknown=1"
+	config := &models.ServerConfig{CustomServerProperties: &custom}
+
+	importProperties(t, config, "difficulty=hard\n")
+	if config.Difficulty == nil || *config.Difficulty != "hard" {
+		t.Fatalf("Difficulty = %v, want hard", config.Difficulty)
+	}
+	if config.CustomServerProperties == nil || *config.CustomServerProperties != "unknown=1" {
+		t.Errorf("CustomServerProperties = %v, want unknown=1", config.CustomServerProperties)
+	}
+	if exported := ExportServerProperties(config); !strings.Contains(exported, "\ndifficulty=hard\n") || strings.Contains(exported, "Hard") {
+		t.Errorf("export has the stale custom value:\n%s", exported)
+	}
+}
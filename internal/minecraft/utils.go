@@ -145,6 +145,31 @@ func ParsePlayerListFromOutput(output string) (int, []string) {
 	return 0, []string{}
 }
 
+// Java names are 3-16 word characters, Floodgate prefixes Bedrock names with "."
+var playerNameRe = regexp.MustCompile(`^\.?[A-Za-z0-9_]{1,16}$`)
+
+// IsValidPlayerName reports whether name is safe to pass as a player argument to a command
+func IsValidPlayerName(name string) bool {
+	return playerNameRe.MatchString(name)
+}
+
+// IsPlayerNotFoundOutput reports whether command output indicates the target player was not found
+func IsPlayerNotFoundOutput(output string) bool {
+	output = strings.ToLower(stripMinecraftColors(output))
+	for _, marker := range []string{
+		"no player was found",
+		"that player does not exist",
+		"player not found",
+		"unknown player",
+		"is not online",
+	} {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 func stripMinecraftColors(text string) string {
 	// Remove Minecraft color codes (§ followed by a character)
 	re := regexp.MustCompile(`§.`)
@@ -0,0 +1,105 @@
+package minecraft
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	models "github.com/nickheyer/discopanel/internal/db"
+)
+
+const (
+	// VoiceChatModID is the mod/plugin ID of Simple Voice Chat
+	VoiceChatModID = "voicechat"
+	// DefaultVoiceChatPort is the UDP port Simple Voice Chat listens on out of the box
+	DefaultVoiceChatPort = 24454
+)
+
+// VoiceChatInfo describes a Simple Voice Chat install found in a server's data dir
+type VoiceChatInfo struct {
+	JarName        string // Mod or plugin jar, empty when only the config was found
+	ConfigPath     string // voicechat-server.properties, empty before first start
+	Port           int    // UDP port voice chat listens on inside the container
+	SharesGamePort bool   // port=-1, voice chat rides on the Minecraft port over UDP
+	VoiceHost      string // voice_host override advertised to clients, if set
+}
+
+// Config locations for the mod (Forge/Fabric/NeoForge/Quilt) and the plugin (Bukkit/Paper)
+var voiceChatConfigPaths = []string{
+	filepath.Join("config", "voicechat", "voicechat-server.properties"),
+	filepath.Join("plugins", "voicechat", "voicechat-server.properties"),
+}
+
+// DetectVoiceChat looks for Simple Voice Chat by its jar in the mods/plugins directory
+// or by its server config. Returns nil when it isn't installed.
+func DetectVoiceChat(serverDataPath string, loader models.ModLoader) *VoiceChatInfo {
+	if serverDataPath == "" {
+		return nil
+	}
+	info := &VoiceChatInfo{Port: DefaultVoiceChatPort}
+
+	if modsPath := GetModsPath(serverDataPath, loader); modsPath != "" {
+		if entries, err := os.ReadDir(modsPath); err == nil {
+			for _, entry := range entries {
+				name := strings.ToLower(entry.Name())
+				if !entry.IsDir() && strings.HasSuffix(name, ".jar") && strings.HasPrefix(name, VoiceChatModID) {
+					info.JarName = entry.Name()
+					break
+				}
+			}
+		}
+	}
+
+	for _, rel := range voiceChatConfigPaths {
+		path := filepath.Join(serverDataPath, rel)
+		if _, err := os.Stat(path); err == nil {
+			info.ConfigPath = path
+			break
+		}
+	}
+
+	if info.JarName == "" && info.ConfigPath == "" {
+		return nil
+	}
+
+	if info.ConfigPath != "" {
+		props, err := readVoiceChatConfig(info.ConfigPath)
+		if err == nil {
+			if port, err := strconv.Atoi(props["port"]); err == nil {
+				if port == -1 {
+					info.SharesGamePort = true
+				} else if port > 0 && port <= 65535 {
+					info.Port = port
+				}
+			}
+			info.VoiceHost = props["voice_host"]
+		}
+	}
+
+	return info
+}
+
+// voicechat-server.properties is a flat key=value file, no escaping or sections
+func readVoiceChatConfig(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return props, scanner.Err()
+}
@@ -24,7 +24,7 @@ func InitBuiltinTemplates(store *storage.Store) error {
 			RequiresServer: true,
 			Icon:           "users",
 			Ports: []*v1.ModulePort{
-				{Name: "Bedrock", ContainerPort: 19132, HostPort: 0, Protocol: "udp", ProxyEnabled: true},
+				{Name: "Bedrock", ContainerPort: 19132, HostPort: 0, Protocol: "udp", ProxyEnabled: false},
 			},
 			DefaultAccessUrls: []string{"http://{{host.hostname}}:{{module.ports.Bedrock.host_port}}"},
 			DefaultEnv: `{
@@ -36,12 +36,16 @@ func InitBuiltinTemplates(store *storage.Store) error {
 				"BEDROCK_MOTD1": "GeyserMC",
 				"BEDROCK_MOTD2": "Minecraft Server",
 				"BEDROCK_SERVERNAME": "Geyser",
-				"REMOTE_ADDRESS": "discopanel-server-{{server.id}}",
-				"REMOTE_PORT": "25565",
-				"REMOTE_AUTH_TYPE": "offline"
+				"REMOTE_ADDRESS": "{{server.network.host}}",
+				"REMOTE_PORT": "{{server.network.port}}",
+				"REMOTE_AUTH_TYPE": "floodgate",
+				"GEYSER_FLOODGATE_KEY_FILE": "key.pem"
 			}`,
-			DefaultVolumes:  `[{"source": "{{server.data_path}}/modules/geyser", "target": "/data", "read_only": false}]`,
-			Documentation:   "Geyser acts as a proxy, translating Bedrock packets to Java packets.",
+			DefaultVolumes: `[
+				{"source": "{{server.data_path}}/modules/geyser", "target": "/data", "read_only": false},
+				{"source": "{{server.floodgate.key_path}}", "target": "/data/key.pem", "read_only": true}
+				]`,
+			Documentation:   "Geyser acts as a proxy, translating Bedrock packets to Java packets. It connects to the attached server on the docker network and starts and stops along with it. Bedrock players join on the allocated Bedrock port, or through a UDP proxy listener targeting the server. DiscoPanel keeps one Floodgate key per server in the server's floodgate config dir and mounts it into Geyser, so both sides always match.",
 			HealthCheckPort: 19132,
 			DefaultMemory:   1024,
 		},
@@ -57,7 +61,7 @@ func InitBuiltinTemplates(store *storage.Store) error {
 			Icon:           "archive",
 			Ports:          []*v1.ModulePort{},
 			DefaultEnv: `{
-				"RCON_HOST": "discopanel-server-{{server.id}}",
+				"RCON_HOST": "{{server.network.host}}",
 				"RCON_PORT": "{{server.config.rconPort}}",
 				"RCON_PASSWORD": "{{server.config.rconPassword}}",
 				"SRC_DIR": "/data",
@@ -97,7 +101,7 @@ func InitBuiltinTemplates(store *storage.Store) error {
 			DefaultEnv: `{
 				"RWA_ADMIN": "true",
 				"RWA_PASSWORD": "admin",
-				"RWA_RCON_HOST": "discopanel-server-{{server.id}}",
+				"RWA_RCON_HOST": "{{server.network.host}}",
 				"RWA_RCON_PORT": "{{server.config.rconPort}}",
 				"RWA_RCON_PASSWORD": "{{server.config.rconPassword}}",
 				"RWA_WEBSOCKET_URL": "ws://{{server.proxy_hostname}}:{{module.ports.WS.host_port}}"
@@ -124,7 +128,7 @@ func InitBuiltinTemplates(store *storage.Store) error {
 			},
 			DefaultAccessUrls: []string{"http://{{host.hostname}}:{{module.ports.Metrics.host_port}}/metrics"},
 			DefaultEnv: `{
-				"EXPORT_SERVERS": "discopanel-server-{{server.id}}:25565",
+				"EXPORT_SERVERS": "{{server.network.host}}:{{server.network.port}}",
 				"EXPORT_PORT": "{{module.ports.Metrics.container_port}}"
 			}`,
 			DefaultVolumes:  `[]`,
@@ -187,7 +191,7 @@ func InitBuiltinTemplates(store *storage.Store) error {
 			DefaultVolumes:  `[]`,
 			HealthCheckPath: "/health",
 			HealthCheckPort: 8181,
-			Documentation:   "Displays a real-time status dashboard for the attached Minecraft server. Fetches status via the DiscoPanel API including player count, TPS, CPU/memory usage, and server configuration. Automatically refreshes every 10 seconds.",
+			Documentation:   "Displays a real-time status dashboard for the attached Minecraft server. Fetches status via the DiscoPanel API including player count, TPS, CPU/memory usage, and server configuration. The page updates in place every POLL_INTERVAL (10 seconds by default). Set DISCOPANEL_SERVER_ID to a comma separated list of server IDs, or to all, to show a grid of servers; other servers need a DISCOPANEL_API_TOKEN that can read them.",
 			DefaultMemory:   512,
 		},
 	}
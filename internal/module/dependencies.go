@@ -0,0 +1,108 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+)
+
+// ErrDependencyCycle is returned when module dependencies loop back on themselves
+var ErrDependencyCycle = errors.New("module dependencies form a cycle")
+
+// Default time a dependency gets to turn healthy when its edge doesn't set one
+const defaultDependencyTimeout = 60
+
+// dependencyOrder returns the given modules along with everything they depend on, directly
+// or not, with every module placed after its dependencies. The given modules are used as
+// they are, so unsaved dependency changes can be checked before they're stored.
+func (m *Manager) dependencyOrder(ctx context.Context, modules []*storage.Module) ([]*storage.Module, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	byID := make(map[string]*storage.Module, len(modules))
+	for _, module := range modules {
+		byID[module.ID] = module
+	}
+
+	state := make(map[string]int)
+	var order, path []*storage.Module
+
+	var visit func(module *storage.Module) error
+	visit = func(module *storage.Module) error {
+		switch state[module.ID] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.IndexFunc(path, func(m *storage.Module) bool { return m.ID == module.ID })
+			names := make([]string, 0, len(path)-start+1)
+			for _, m := range path[start:] {
+				names = append(names, m.Name)
+			}
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(names, module.Name), " -> "))
+		}
+
+		state[module.ID] = visiting
+		path = append(path, module)
+		for _, dep := range module.Dependencies {
+			if dep == nil || dep.ModuleId == "" {
+				continue
+			}
+			depModule, ok := byID[dep.ModuleId]
+			if !ok {
+				var err error
+				if depModule, err = m.store.GetModule(ctx, dep.ModuleId); err != nil {
+					return fmt.Errorf("dependency module %s of %s not found: %w", dep.ModuleId, module.Name, err)
+				}
+				byID[depModule.ID] = depModule
+			}
+			if err := visit(depModule); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[module.ID] = visited
+		order = append(order, module)
+		return nil
+	}
+
+	for _, module := range modules {
+		if err := visit(module); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// CheckDependencies validates a module's dependencies before they're saved: every
+// dependency must exist and none may lead back to the module
+func (m *Manager) CheckDependencies(ctx context.Context, module *storage.Module) error {
+	_, err := m.dependencyOrder(ctx, []*storage.Module{module})
+	return err
+}
+
+// Names of running modules on the same server that depend on the module
+func (m *Manager) runningDependents(ctx context.Context, module *storage.Module) []string {
+	siblings, err := m.store.ListServerModules(ctx, module.ServerID)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, sibling := range siblings {
+		if !sibling.Status.IsUp() {
+			continue
+		}
+		for _, dep := range sibling.Dependencies {
+			if dep != nil && dep.ModuleId == module.ID {
+				names = append(names, sibling.Name)
+				break
+			}
+		}
+	}
+	return names
+}
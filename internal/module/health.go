@@ -0,0 +1,178 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+)
+
+// How often the health monitor looks for modules due a check, each module is still
+// only probed once per its own health_check_interval
+const healthMonitorTick = 5 * time.Second
+
+// HealthResult is the outcome of a module's background health checks
+type HealthResult struct {
+	Healthy   bool
+	Message   string
+	CheckedAt time.Time
+	Failures  int // Consecutive failed checks
+}
+
+// ModuleHealth returns the latest health result of a module, false when none has run
+func (m *Manager) ModuleHealth(moduleID string) (HealthResult, bool) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	result, ok := m.health[moduleID]
+	if !ok {
+		return HealthResult{}, false
+	}
+	return *result, true
+}
+
+// Forgets a module's health so a fresh start isn't judged by its previous run
+func (m *Manager) resetHealth(moduleID string) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	delete(m.health, moduleID)
+}
+
+// Whether a module has failed enough consecutive checks to count as unhealthy
+func (m *Manager) failingHealth(module *storage.Module) bool {
+	result, ok := m.ModuleHealth(module.ID)
+	return ok && result.Failures >= healthRetries(module)
+}
+
+func healthRetries(module *storage.Module) int {
+	if module.HealthCheckRetries > 0 {
+		return module.HealthCheckRetries
+	}
+	return 3
+}
+
+// Probes running modules whose template defines an HTTP health check until stop is closed
+func (m *Manager) monitorHealth(stop <-chan struct{}) {
+	ticker := time.NewTicker(healthMonitorTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		m.probeDueModules(context.Background())
+	}
+}
+
+func (m *Manager) probeDueModules(ctx context.Context) {
+	modules, err := m.store.ListModules(ctx)
+	if err != nil {
+		m.logger.Error("Health monitor: failed to list modules: %v", err)
+		return
+	}
+
+	templates := make(map[string]*storage.ModuleTemplate)
+	for _, module := range modules {
+		if module.ContainerID == "" || !module.Status.IsUp() {
+			continue
+		}
+		template, ok := templates[module.TemplateID]
+		if !ok {
+			template, err = m.store.GetModuleTemplate(ctx, module.TemplateID)
+			if err != nil {
+				continue
+			}
+			templates[module.TemplateID] = template
+		}
+		if template.HealthCheckPath == "" && template.HealthCheckPort == 0 {
+			continue
+		}
+
+		interval := time.Duration(module.HealthCheckInterval) * time.Second
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+
+		m.healthMu.Lock()
+		last, checked := m.health[module.ID]
+		due := !m.probing[module.ID] && (!checked || time.Since(last.CheckedAt) >= interval)
+		if due {
+			m.probing[module.ID] = true
+		}
+		m.healthMu.Unlock()
+
+		if due {
+			go m.probeModule(ctx, module, template)
+		}
+	}
+}
+
+// Runs one health check and acts on the result, restarting the module once it has
+// failed health_check_retries checks in a row and its restart policy allows it
+func (m *Manager) probeModule(ctx context.Context, module *storage.Module, template *storage.ModuleTemplate) {
+	defer func() {
+		m.healthMu.Lock()
+		delete(m.probing, module.ID)
+		m.healthMu.Unlock()
+	}()
+
+	message := ""
+	containerIP, err := m.docker.GetModuleContainerIP(ctx, module.ContainerID)
+	if err != nil {
+		message = fmt.Sprintf("failed to get container IP: %v", err)
+	} else if err := m.checkHealth(fmt.Sprintf("http://%s:%d%s", containerIP, template.HealthCheckPort, template.HealthCheckPath), module.HealthCheckTimeout); err != nil {
+		message = err.Error()
+	}
+	healthy := message == ""
+
+	m.healthMu.Lock()
+	result, ok := m.health[module.ID]
+	if !ok {
+		result = &HealthResult{}
+		m.health[module.ID] = result
+	}
+	result.Healthy = healthy
+	result.Message = message
+	result.CheckedAt = time.Now()
+	if healthy {
+		result.Failures = 0
+	} else {
+		result.Failures++
+	}
+	failures := result.Failures
+	m.healthMu.Unlock()
+
+	retries := healthRetries(module)
+	switch {
+	case healthy && module.Status == storage.ModuleStatusUnhealthy:
+		m.logger.Info("Module %s is healthy again", module.Name)
+		m.setModuleStatus(ctx, module.ID, storage.ModuleStatusUnhealthy, storage.ModuleStatusRunning)
+	case !healthy && failures < retries:
+		m.logger.Debug("Health check failed for %s (attempt %d/%d): %s", module.Name, failures, retries, message)
+	case !healthy && failures == retries:
+		m.logger.Warn("Module %s is unhealthy after %d failed health checks: %s", module.Name, failures, message)
+		m.setModuleStatus(ctx, module.ID, storage.ModuleStatusRunning, storage.ModuleStatusUnhealthy)
+
+		if module.RestartPolicy != storage.ModuleRestartOnFailure && module.RestartPolicy != storage.ModuleRestartAlways {
+			return
+		}
+		m.logger.Info("Restarting unhealthy module %s", module.Name)
+		if err := m.RestartModule(ctx, module.ID); err != nil {
+			m.logger.Error("Failed to restart unhealthy module %s: %v", module.Name, err)
+		}
+	}
+}
+
+// Moves a module from one status to another, leaving it alone if something else
+// changed its status in the meantime
+func (m *Manager) setModuleStatus(ctx context.Context, moduleID string, from, to storage.ModuleStatus) {
+	module, err := m.store.GetModule(ctx, moduleID)
+	if err != nil || module.Status != from {
+		return
+	}
+	module.Status = to
+	if err := m.store.UpdateModule(ctx, module); err != nil {
+		m.logger.Error("Failed to update status of module %s: %v", module.Name, err)
+	}
+}
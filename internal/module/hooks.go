@@ -2,6 +2,7 @@ package module
 
 import (
 	"context"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -33,19 +34,39 @@ func (m *Manager) autoStartModules(ctx context.Context, serverID string) {
 		return
 	}
 
+	var autoStart []*storage.Module
 	for _, module := range modules {
 		if module.AutoStart && !module.Detached {
-			go func(mod *storage.Module) {
-				// Small delay to let the server settle before starting modules
-				time.Sleep(2 * time.Second)
-				if err := m.StartModule(context.Background(), mod.ID); err != nil {
-					m.logger.Error("Failed to start module %s on server start: %v", mod.Name, err)
-				} else {
-					m.logger.Info("Started module %s with server", mod.Name)
-				}
-			}(module)
+			autoStart = append(autoStart, module)
 		}
 	}
+	if len(autoStart) == 0 {
+		return
+	}
+
+	// Modules caught in a cycle fail to start on their own, the rest still start
+	order, err := m.dependencyOrder(ctx, autoStart)
+	if err != nil {
+		m.logger.Warn("Starting modules unordered: %v", err)
+		order = autoStart
+	}
+
+	go func() {
+		// Small delay to let the server settle before starting modules
+		time.Sleep(2 * time.Second)
+
+		// Started one at a time so dependencies shared by several modules start once
+		for _, mod := range order {
+			if !slices.Contains(autoStart, mod) {
+				continue // Started by its dependents
+			}
+			if err := m.StartModule(context.Background(), mod.ID); err != nil {
+				m.logger.Error("Failed to start module %s on server start: %v", mod.Name, err)
+			} else {
+				m.logger.Info("Started module %s with server", mod.Name)
+			}
+		}
+	}()
 }
 
 // Stops modules following server lifecycle when the parent server stops
@@ -56,8 +77,19 @@ func (m *Manager) stopLifecycleModules(ctx context.Context, serverID string) {
 		return
 	}
 
-	for _, module := range modules {
-		if module.Status == storage.ModuleStatusRunning && !module.Detached {
+	// Dependents stop before the modules they depend on
+	order, err := m.dependencyOrder(ctx, modules)
+	if err != nil {
+		m.logger.Warn("Stopping lifecycle modules unordered: %v", err)
+		order = modules
+	}
+	slices.Reverse(order)
+
+	for _, module := range order {
+		if !slices.Contains(modules, module) {
+			continue
+		}
+		if module.Status.IsUp() && !module.Detached {
 			if err := m.StopModule(ctx, module.ID); err != nil {
 				m.logger.Error("Failed to stop module %s on server stop: %v", module.Name, err)
 			} else {
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/nickheyer/discopanel/internal/alias"
+	"github.com/nickheyer/discopanel/internal/auth"
 	"github.com/nickheyer/discopanel/internal/command"
 	"github.com/nickheyer/discopanel/internal/config"
 	storage "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/minecraft"
 	"github.com/nickheyer/discopanel/internal/proxy"
 	"github.com/nickheyer/discopanel/pkg/logger"
 )
@@ -26,6 +30,12 @@ type Manager struct {
 	logStreamer  *logger.LogStreamer
 	mu           sync.Mutex
 	running      bool
+	stopHealth   chan struct{}
+
+	// Background health check results, keyed by module ID
+	healthMu sync.Mutex
+	health   map[string]*HealthResult
+	probing  map[string]bool
 }
 
 // NewManager creates a new module manager
@@ -37,6 +47,8 @@ func NewManager(store *storage.Store, docker *docker.Client, sender *command.Sen
 		config:       cfg,
 		proxyManager: proxyManager,
 		logger:       log,
+		health:       make(map[string]*HealthResult),
+		probing:      make(map[string]bool),
 	}
 }
 
@@ -65,6 +77,8 @@ func (m *Manager) Start() error {
 	}
 
 	m.running = true
+	m.stopHealth = make(chan struct{})
+	go m.monitorHealth(m.stopHealth)
 	m.logger.Info("Module manager started")
 	return nil
 }
@@ -77,6 +91,7 @@ func (m *Manager) Stop() error {
 	if !m.running {
 		return nil
 	}
+	close(m.stopHealth)
 
 	ctx := context.Background()
 	modules, err := m.store.ListModules(ctx)
@@ -89,7 +104,7 @@ func (m *Manager) Stop() error {
 				continue
 			}
 
-			if module.Status == storage.ModuleStatusRunning {
+			if module.Status.IsUp() {
 				m.logger.Info("Stopping module: %s", module.Name)
 				if err := m.StopModule(ctx, module.ID); err != nil {
 					m.logger.Error("Failed to stop module %s: %v", module.Name, err)
@@ -129,6 +144,20 @@ func (m *Manager) CreateAndStartModule(ctx context.Context, moduleID string, sta
 	// Fetch server config for alias resolution
 	serverConfig, _ := m.store.GetServerConfig(ctx, server.ID)
 
+	// Lets the module read its server through the API whoever created it
+	if _, err := auth.RotatePanelToken(ctx, m.store, module); err != nil {
+		m.logger.Warn("Failed to create panel token for module %s: %v", module.ID, err)
+	}
+
+	// Modules like Geyser share the server's Floodgate key, it has to exist before it's mounted
+	if strings.Contains(module.EnvOverrides, alias.FloodgateKeyAlias) || strings.Contains(module.VolumeOverrides, alias.FloodgateKeyAlias) {
+		if server.DataPath == "" {
+			m.logger.Warn("Floodgate key of server %s is in a Docker volume DiscoPanel can't see, it can't be shared with modules", server.ID)
+		} else if _, err := minecraft.EnsureFloodgateKey(server.DataPath, server.ModLoader); err != nil {
+			m.logger.Warn("Failed to prepare floodgate key for server %s: %v", server.ID, err)
+		}
+	}
+
 	// Fetch sibling modules for inter-module alias resolution
 	siblingModules := make(map[string]*storage.Module)
 	serverModules, err := m.store.ListServerModules(ctx, module.ServerID)
@@ -180,11 +209,16 @@ func (m *Manager) StartModule(ctx context.Context, moduleID string) error {
 	if err != nil {
 		// Container doesn't exist, recreate it
 		m.logger.Info("Container for module %s no longer exists, recreating", module.Name)
+		oldContainerID := module.ContainerID
 		module.ContainerID = ""
 		if err := m.store.UpdateModule(ctx, module); err != nil {
 			return fmt.Errorf("failed to clear module container ID: %w", err)
 		}
-		return m.CreateAndStartModule(ctx, moduleID, true)
+		if err := m.CreateAndStartModule(ctx, moduleID, false); err != nil {
+			return err
+		}
+		m.migrateLogSubscribers(ctx, moduleID, oldContainerID)
+		return m.StartModule(ctx, moduleID)
 	}
 
 	// Start dependencies first
@@ -214,6 +248,7 @@ func (m *Manager) StartModule(ctx context.Context, moduleID string) error {
 
 	// Update status and timestamps
 	now := time.Now()
+	m.resetHealth(moduleID)
 	module.Status = storage.ModuleStatusRunning
 	module.LastStarted = &now
 	if err := m.store.UpdateModule(ctx, module); err != nil {
@@ -228,6 +263,8 @@ func (m *Manager) StartModule(ctx context.Context, moduleID string) error {
 				m.logger.Error("Failed to add proxy route for module %s: %v", module.Name, err)
 			}
 		}
+		// Bedrock listeners prefer a running sidecar over the server container
+		m.proxyManager.RefreshServerListeners(module.ServerID)
 	}
 
 	m.logger.Info("Started module: %s", module.Name)
@@ -257,7 +294,7 @@ func (m *Manager) runInitCommand(moduleID string) {
 
 	// Verify container is still running
 	status, err := m.GetModuleStatus(ctx, moduleID)
-	if err != nil || status != storage.ModuleStatusRunning {
+	if err != nil || !status.IsUp() {
 		m.logger.Warn("Init command: module %s is no longer running, skipping", module.Name)
 		return
 	}
@@ -280,45 +317,54 @@ func (m *Manager) runInitCommand(moduleID string) {
 	}
 }
 
-// startDependencies starts and waits for module dependencies
+// startDependencies starts and waits for module dependencies, transitive ones included,
+// each after the modules it depends on
 func (m *Manager) startDependencies(ctx context.Context, module *storage.Module) error {
 	if len(module.Dependencies) == 0 {
 		return nil
 	}
 
-	for _, dep := range module.Dependencies {
-		if dep == nil || dep.ModuleId == "" {
-			continue
-		}
+	order, err := m.dependencyOrder(ctx, []*storage.Module{module})
+	if err != nil {
+		return err
+	}
 
-		depModule, err := m.store.GetModule(ctx, dep.ModuleId)
-		if err != nil {
-			return fmt.Errorf("dependency module %s not found: %w", dep.ModuleId, err)
+	// Longest health wait any dependent asks of each dependency
+	waits := make(map[string]int)
+	for _, dependent := range order {
+		for _, dep := range dependent.Dependencies {
+			if dep == nil || !dep.WaitForHealthy {
+				continue
+			}
+			timeout := int(dep.TimeoutSeconds)
+			if timeout == 0 {
+				timeout = defaultDependencyTimeout
+			}
+			waits[dep.ModuleId] = max(waits[dep.ModuleId], timeout)
 		}
+	}
 
+	// The module itself comes last
+	for _, depModule := range order[:len(order)-1] {
 		// Start dependency if not running
-		if depModule.Status != storage.ModuleStatusRunning {
+		if !depModule.Status.IsUp() {
 			m.logger.Info("Starting dependency %s for module %s", depModule.Name, module.Name)
 
 			// Create container if needed
 			if depModule.ContainerID == "" {
-				if err := m.CreateAndStartModule(ctx, dep.ModuleId, true); err != nil {
+				if err := m.CreateAndStartModule(ctx, depModule.ID, true); err != nil {
 					return fmt.Errorf("failed to create and start dependency %s: %w", depModule.Name, err)
 				}
 			} else {
-				if err := m.StartModule(ctx, dep.ModuleId); err != nil {
+				if err := m.StartModule(ctx, depModule.ID); err != nil {
 					return fmt.Errorf("failed to start dependency %s: %w", depModule.Name, err)
 				}
 			}
 		}
 
 		// Wait for dependency to be healthy if configured
-		if dep.WaitForHealthy {
-			timeout := int(dep.TimeoutSeconds)
-			if timeout == 0 {
-				timeout = 60 // Default 60 seconds
-			}
-			if err := m.waitForHealthy(ctx, dep.ModuleId, timeout); err != nil {
+		if timeout, ok := waits[depModule.ID]; ok {
+			if err := m.waitForHealthy(ctx, depModule.ID, timeout); err != nil {
 				return fmt.Errorf("dependency %s not healthy: %w", depModule.Name, err)
 			}
 		}
@@ -378,7 +424,7 @@ func (m *Manager) waitForHealthy(ctx context.Context, moduleID string, timeoutSe
 
 			// Perform health check
 			healthURL := fmt.Sprintf("http://%s:%d%s", containerIP, template.HealthCheckPort, template.HealthCheckPath)
-			if m.checkHealth(healthURL, module.HealthCheckTimeout) {
+			if m.checkHealth(healthURL, module.HealthCheckTimeout) == nil {
 				m.logger.Info("Module %s is healthy", module.Name)
 				return nil
 			}
@@ -407,15 +453,15 @@ func (m *Manager) waitForRunning(ctx context.Context, moduleID string, timeoutSe
 			if err != nil {
 				continue
 			}
-			if status == storage.ModuleStatusRunning {
+			if status.IsUp() {
 				return nil
 			}
 		}
 	}
 }
 
-// checkHealth performs an HTTP health check
-func (m *Manager) checkHealth(url string, timeoutSeconds int) bool {
+// checkHealth performs an HTTP health check, any 2xx or 3xx answer passes
+func (m *Manager) checkHealth(url string, timeoutSeconds int) error {
 	if timeoutSeconds == 0 {
 		timeoutSeconds = 5
 	}
@@ -426,11 +472,14 @@ func (m *Manager) checkHealth(url string, timeoutSeconds int) bool {
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return false
+		return err
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode >= 200 && resp.StatusCode < 400
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return nil
 }
 
 // StopModule stops a running module
@@ -444,6 +493,10 @@ func (m *Manager) StopModule(ctx context.Context, moduleID string) error {
 		return fmt.Errorf("module has no container")
 	}
 
+	if dependents := m.runningDependents(ctx, module); len(dependents) > 0 {
+		m.logger.Warn("Stopping module %s while %s still depend on it", module.Name, strings.Join(dependents, ", "))
+	}
+
 	// Update status
 	module.Status = storage.ModuleStatusStopping
 	if err := m.store.UpdateModule(ctx, module); err != nil {
@@ -472,11 +525,16 @@ func (m *Manager) StopModule(ctx context.Context, moduleID string) error {
 	}
 
 	// Update status
+	m.resetHealth(moduleID)
 	module.Status = storage.ModuleStatusStopped
 	if err := m.store.UpdateModule(ctx, module); err != nil {
 		return fmt.Errorf("failed to update module status: %w", err)
 	}
 
+	if m.proxyManager != nil {
+		m.proxyManager.RefreshServerListeners(module.ServerID)
+	}
+
 	m.logger.Info("Stopped module: %s", module.Name)
 	return nil
 }
@@ -499,7 +557,7 @@ func (m *Manager) RecreateModule(ctx context.Context, moduleID string) error {
 		return fmt.Errorf("failed to get module: %w", err)
 	}
 
-	wasRunning := module.Status == storage.ModuleStatusRunning
+	wasRunning := module.Status.IsUp()
 
 	// Stop if running
 	if wasRunning {
@@ -509,6 +567,7 @@ func (m *Manager) RecreateModule(ctx context.Context, moduleID string) error {
 	}
 
 	// Remove old container
+	oldContainerID := module.ContainerID
 	if module.ContainerID != "" {
 		if err := m.docker.RemoveContainer(ctx, module.ContainerID); err != nil {
 			m.logger.Error("Failed to remove old module container: %v", err)
@@ -518,13 +577,29 @@ func (m *Manager) RecreateModule(ctx context.Context, moduleID string) error {
 	}
 
 	// Create new container
-	if err := m.CreateAndStartModule(ctx, moduleID, wasRunning); err != nil {
+	if err := m.CreateAndStartModule(ctx, moduleID, false); err != nil {
 		return fmt.Errorf("failed to recreate module: %w", err)
 	}
+	m.migrateLogSubscribers(ctx, moduleID, oldContainerID)
 
+	if wasRunning {
+		return m.StartModule(ctx, moduleID)
+	}
 	return nil
 }
 
+// Keeps clients following a module's logs attached when its container is replaced
+func (m *Manager) migrateLogSubscribers(ctx context.Context, moduleID, oldContainerID string) {
+	if m.logStreamer == nil || oldContainerID == "" {
+		return
+	}
+	module, err := m.store.GetModule(ctx, moduleID)
+	if err != nil || module.ContainerID == "" {
+		return
+	}
+	m.logStreamer.MigrateSubscribers(oldContainerID, module.ContainerID)
+}
+
 // DeleteModule stops and removes a module and its container
 func (m *Manager) DeleteModule(ctx context.Context, moduleID string) error {
 	module, err := m.store.GetModule(ctx, moduleID)
@@ -533,7 +608,7 @@ func (m *Manager) DeleteModule(ctx context.Context, moduleID string) error {
 	}
 
 	// Stop if running
-	if module.Status == storage.ModuleStatusRunning {
+	if module.Status.IsUp() {
 		if err := m.StopModule(ctx, moduleID); err != nil {
 			m.logger.Error("Failed to stop module for deletion: %v", err)
 		}
@@ -581,7 +656,12 @@ func (m *Manager) GetModuleStatus(ctx context.Context, moduleID string) (storage
 	// Map ServerStatus to ModuleStatus
 	switch status {
 	case storage.StatusRunning:
+		if m.failingHealth(module) {
+			return storage.ModuleStatusUnhealthy, nil
+		}
 		return storage.ModuleStatusRunning, nil
+	case storage.StatusUnhealthy:
+		return storage.ModuleStatusUnhealthy, nil
 	case storage.StatusStarting:
 		return storage.ModuleStatusStarting, nil
 	case storage.StatusStopping:
@@ -616,6 +696,32 @@ func (m *Manager) AllocateModulePortExcluding(ctx context.Context, exclude map[i
 		}
 	}
 
+	// Skip host ports bound by directly exposed servers
+	servers, err := m.store.ListServers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, server := range servers {
+		if server.ProxyHostname == "" && server.Port > 0 {
+			usedPorts[server.Port] = true
+			usedPorts[server.Port+docker.RCONPortOffset] = true
+		}
+		for _, port := range server.AdditionalPorts {
+			if port != nil && port.HostPort > 0 {
+				usedPorts[int(port.HostPort)] = true
+			}
+		}
+	}
+
+	// Skip proxy listener ports
+	listeners, err := m.store.GetProxyListeners(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, listener := range listeners {
+		usedPorts[listener.Port] = true
+	}
+
 	// Also exclude ports passed in (allocated in same request)
 	for port := range exclude {
 		usedPorts[port] = true
@@ -631,6 +737,20 @@ func (m *Manager) AllocateModulePortExcluding(ctx context.Context, exclude map[i
 	return 0, fmt.Errorf("no available module ports in range %d-%d", m.config.Module.PortRangeMin, m.config.Module.PortRangeMax)
 }
 
+// CheckModulePortRange validates an explicitly requested host port against the configured module range.
+// Out of range ports are rejected when strict_port_range is set, otherwise only logged.
+func (m *Manager) CheckModulePortRange(port int) error {
+	rangeMin, rangeMax := m.config.Module.PortRangeMin, m.config.Module.PortRangeMax
+	if port >= rangeMin && port <= rangeMax {
+		return nil
+	}
+	if m.config.Module.StrictPortRange {
+		return fmt.Errorf("host port %d is outside the module port range %d-%d", port, rangeMin, rangeMax)
+	}
+	m.logger.Warn("Module host port %d is outside the configured module port range %d-%d", port, rangeMin, rangeMax)
+	return nil
+}
+
 // GetUsedModulePorts returns all ports currently in use by modules
 func (m *Manager) GetUsedModulePorts(ctx context.Context) ([]int, error) {
 	modules, err := m.store.ListModules(ctx)
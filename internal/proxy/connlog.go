@@ -0,0 +1,349 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nickheyer/discopanel/pkg/logger"
+)
+
+const (
+	// DefaultConnectionLogSize is how many recent connections the manager keeps
+	DefaultConnectionLogSize = 500
+	// Client IPs tracked for per-IP metrics before the least recently seen is evicted
+	maxTrackedClients = 1024
+	// Minimum gap between repeated unmatched-hostname warnings for the same hostname
+	unmatchedWarnInterval = time.Minute
+)
+
+// Connection outcomes recorded by the proxies
+const (
+	OutcomeActive         = "active"          // Still proxying
+	OutcomeClosed         = "closed"          // Proxied and closed normally
+	OutcomeNoRoute        = "no_route"        // Hostname matched no active route
+	OutcomeBackendError   = "backend_error"   // Route found but backend unreachable
+	OutcomeHandshakeError = "handshake_error" // Client never sent a valid handshake
+)
+
+// Connection logging verbosities, how many finished connections get a log line
+const (
+	ConnectionLoggingOff    = "off"
+	ConnectionLoggingErrors = "errors" // Rejected connections and unreachable backends
+	ConnectionLoggingAll    = "all"    // Every connection, forwarded ones included
+)
+
+// ConnectionRecord is one accepted proxy connection
+type ConnectionRecord struct {
+	ID           uint64
+	StartedAt    time.Time
+	EndedAt      time.Time
+	ListenerPort int
+	Hostname     string // Empty for raw TCP listeners
+	ServerID     string // Routed server, empty when unrouted
+	ClientIP     string
+	Intent       string // "status" (server list ping) or "login", empty for raw TCP
+	Backend      string // Backend address the connection was routed to
+	BytesIn      int64  // Client -> backend
+	BytesOut     int64  // Backend -> client
+	Outcome      string
+}
+
+// ConnectionStats are cumulative counters since the proxy manager started
+type ConnectionStats struct {
+	Total          int64
+	Active         int64
+	Routed         int64
+	NoRoute        int64
+	BackendErrors  int64
+	HandshakeFails int64
+	BytesIn        int64
+	BytesOut       int64
+}
+
+// ClientStats are per-IP counters
+type ClientStats struct {
+	IP          string
+	Connections int64
+	NoRoute     int64
+	BytesIn     int64
+	BytesOut    int64
+	LastSeen    time.Time
+}
+
+// ConnectionLog keeps a ring buffer of recent connections and counters, shared by all listeners
+type ConnectionLog struct {
+	mu       sync.Mutex
+	records  []*ConnectionRecord
+	next     int
+	nextID   uint64
+	stats    ConnectionStats
+	clients  map[string]*ClientStats
+	lastWarn map[string]time.Time
+
+	verbosity string
+	logger    *logger.Logger
+}
+
+// NewConnectionLog creates a connection log holding up to size recent connections
+func NewConnectionLog(size int) *ConnectionLog {
+	if size <= 0 {
+		size = DefaultConnectionLogSize
+	}
+	return &ConnectionLog{
+		records:  make([]*ConnectionRecord, size),
+		clients:  make(map[string]*ClientStats),
+		lastWarn: make(map[string]time.Time),
+	}
+}
+
+// Begin records a newly accepted connection. Nil-safe so proxies built without a log still work.
+func (l *ConnectionLog) Begin(listenerPort int, remote net.Addr) *ConnectionRecord {
+	if l == nil {
+		return nil
+	}
+
+	clientIP := remote.String()
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	rec := &ConnectionRecord{
+		ID:           l.nextID,
+		StartedAt:    time.Now(),
+		ListenerPort: listenerPort,
+		ClientIP:     clientIP,
+		Outcome:      OutcomeActive,
+	}
+	l.records[l.next] = rec
+	l.next = (l.next + 1) % len(l.records)
+
+	l.stats.Total++
+	l.stats.Active++
+
+	client := l.clientLocked(clientIP)
+	client.Connections++
+	client.LastSeen = rec.StartedAt
+
+	return rec
+}
+
+// SetLogging sets which finished connections are written to log, see ConnectionLoggingOff
+func (l *ConnectionLog) SetLogging(verbosity string, log *logger.Logger) error {
+	switch verbosity {
+	case ConnectionLoggingOff, ConnectionLoggingErrors, ConnectionLoggingAll:
+	case "":
+		verbosity = ConnectionLoggingOff
+	default:
+		return fmt.Errorf("connection logging must be off, errors or all")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.verbosity = verbosity
+	l.logger = log
+	return nil
+}
+
+// Logging returns the current connection logging verbosity
+func (l *ConnectionLog) Logging() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.verbosity == "" {
+		return ConnectionLoggingOff
+	}
+	return l.verbosity
+}
+
+// Route fills in what the handshake resolved to
+func (l *ConnectionLog) Route(rec *ConnectionRecord, hostname, serverID, backend, intent string) {
+	if l == nil || rec == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec.Hostname = hostname
+	rec.ServerID = serverID
+	rec.Backend = backend
+	rec.Intent = intent
+}
+
+// Finish closes out a connection with its outcome and byte counts
+func (l *ConnectionLog) Finish(rec *ConnectionRecord, outcome string, bytesIn, bytesOut int64) {
+	if l == nil || rec == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.logFinishedLocked(rec)
+
+	rec.EndedAt = time.Now()
+	rec.Outcome = outcome
+	rec.BytesIn = bytesIn
+	rec.BytesOut = bytesOut
+
+	l.stats.Active--
+	l.stats.BytesIn += bytesIn
+	l.stats.BytesOut += bytesOut
+	switch outcome {
+	case OutcomeClosed:
+		l.stats.Routed++
+	case OutcomeNoRoute:
+		l.stats.NoRoute++
+	case OutcomeBackendError:
+		l.stats.BackendErrors++
+	case OutcomeHandshakeError:
+		l.stats.HandshakeFails++
+	}
+
+	if client, ok := l.clients[rec.ClientIP]; ok {
+		client.BytesIn += bytesIn
+		client.BytesOut += bytesOut
+		if outcome == OutcomeNoRoute {
+			client.NoRoute++
+		}
+	}
+}
+
+// Writes a finished connection to log when the verbosity asks for it
+func (l *ConnectionLog) logFinishedLocked(rec *ConnectionRecord) {
+	if l.logger == nil || l.verbosity == "" || l.verbosity == ConnectionLoggingOff {
+		return
+	}
+
+	var result string
+	switch rec.Outcome {
+	case OutcomeClosed:
+		if l.verbosity != ConnectionLoggingAll {
+			return
+		}
+		result = fmt.Sprintf("forwarded, %d bytes in, %d bytes out over %s", rec.BytesIn, rec.BytesOut, rec.EndedAt.Sub(rec.StartedAt).Round(time.Second))
+	case OutcomeNoRoute:
+		result = "rejected, no active route"
+	case OutcomeHandshakeError:
+		result = "rejected, invalid handshake"
+	case OutcomeBackendError:
+		result = "backend down"
+	default:
+		result = rec.Outcome
+	}
+
+	hostname := rec.Hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+	backend := rec.Backend
+	if backend == "" {
+		backend = "-"
+	}
+	intent := ""
+	if rec.Intent != "" {
+		intent = " (" + rec.Intent + ")"
+	}
+	l.logger.Info("Proxy connection %d from %s on port %d: hostname %s%s, backend %s, %s",
+		rec.ID, rec.ClientIP, rec.ListenerPort, hostname, intent, backend, result)
+}
+
+// ShouldWarnUnmatched rate limits unmatched-hostname warnings per hostname
+func (l *ConnectionLog) ShouldWarnUnmatched(hostname string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := l.lastWarn[hostname]; ok && now.Sub(last) < unmatchedWarnInterval {
+		return false
+	}
+	if len(l.lastWarn) >= maxTrackedClients {
+		for host, last := range l.lastWarn {
+			if now.Sub(last) >= unmatchedWarnInterval {
+				delete(l.lastWarn, host)
+			}
+		}
+	}
+	l.lastWarn[hostname] = now
+	return true
+}
+
+// Snapshot returns recent connections newest first, plus counters and per-IP stats
+func (l *ConnectionLog) Snapshot() ([]ConnectionRecord, ConnectionStats, []ClientStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]ConnectionRecord, 0, len(l.records))
+	for i := 1; i <= len(l.records); i++ {
+		rec := l.records[(l.next-i+len(l.records))%len(l.records)]
+		if rec == nil {
+			break
+		}
+		records = append(records, *rec)
+	}
+
+	clients := make([]ClientStats, 0, len(l.clients))
+	for _, client := range l.clients {
+		clients = append(clients, *client)
+	}
+
+	return records, l.stats, clients
+}
+
+func (l *ConnectionLog) clientLocked(ip string) *ClientStats {
+	if client, ok := l.clients[ip]; ok {
+		return client
+	}
+
+	if len(l.clients) >= maxTrackedClients {
+		var oldest *ClientStats
+		for _, client := range l.clients {
+			if oldest == nil || client.LastSeen.Before(oldest.LastSeen) {
+				oldest = client
+			}
+		}
+		delete(l.clients, oldest.IP)
+	}
+
+	client := &ClientStats{IP: ip}
+	l.clients[ip] = client
+	return client
+}
+
+// pipeConns copies both directions until either side closes, returning bytes sent each way
+func pipeConns(clientConn, backendConn net.Conn) (bytesIn, bytesOut int64) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		bytesIn, _ = io.Copy(backendConn, clientConn)
+		backendConn.Close()
+	}()
+
+	go func() {
+		defer wg.Done()
+		bytesOut, _ = io.Copy(clientConn, backendConn)
+		clientConn.Close()
+	}()
+
+	wg.Wait()
+	return bytesIn, bytesOut
+}
+
+// listenerPort is the local port a proxy accepted on, 0 if unknown
+func listenerPort(listener net.Listener) int {
+	if listener == nil {
+		return 0
+	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Edge proxy config formats supported by RenderExport
+const (
+	ExportFormatHAProxy = "haproxy"
+	ExportFormatNginx   = "nginx"
+)
+
+// ExportListener is a listener port together with the server routes it carries
+type ExportListener struct {
+	Name   string
+	Port   int
+	Routes []ExportRoute
+}
+
+// ExportRoute maps a hostname to the backend a running server is reachable on
+type ExportRoute struct {
+	ServerID    string
+	ServerName  string
+	Hostname    string
+	BackendHost string
+	BackendPort int
+}
+
+var exportNameSanitizer = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Turns a display name into an identifier usable in haproxy/nginx sections
+func exportIdentifier(prefix, name string) string {
+	id := exportNameSanitizer.ReplaceAllString(strings.ToLower(name), "_")
+	return prefix + strings.Trim(id, "_")
+}
+
+// RenderExport renders listeners and their routes as an edge proxy stream config
+func RenderExport(format string, listeners []ExportListener) (string, error) {
+	// Longest hostnames first so substring matches never shadow a more specific host
+	for i := range listeners {
+		sort.SliceStable(listeners[i].Routes, func(a, b int) bool {
+			ra, rb := listeners[i].Routes[a], listeners[i].Routes[b]
+			if len(ra.Hostname) != len(rb.Hostname) {
+				return len(ra.Hostname) > len(rb.Hostname)
+			}
+			return ra.Hostname < rb.Hostname
+		})
+	}
+	sort.SliceStable(listeners, func(a, b int) bool { return listeners[a].Port < listeners[b].Port })
+
+	switch format {
+	case ExportFormatHAProxy:
+		return renderHAProxy(listeners), nil
+	case ExportFormatNginx:
+		return renderNginx(listeners), nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func writeExportHeader(b *strings.Builder, lines ...string) {
+	fmt.Fprintf(b, "# Generated by DiscoPanel at %s\n", time.Now().UTC().Format(time.RFC3339))
+	for _, line := range lines {
+		fmt.Fprintf(b, "# %s\n", line)
+	}
+	b.WriteString("\n")
+}
+
+// HAProxy reads the Minecraft handshake from the buffered payload and matches the hostname in it
+func renderHAProxy(listeners []ExportListener) string {
+	var b strings.Builder
+	writeExportHeader(&b,
+		"Minecraft has no TLS/SNI, so routing matches the hostname inside the handshake packet.",
+		"Paste into haproxy.cfg; backends must be reachable from HAProxy (e.g. on the DiscoPanel Docker network).",
+	)
+
+	backends := make(map[string]ExportRoute)
+	var backendOrder []string
+
+	for _, listener := range listeners {
+		fmt.Fprintf(&b, "frontend %s\n", exportIdentifier("discopanel_", fmt.Sprintf("%s_%d", listener.Name, listener.Port)))
+		b.WriteString("    mode tcp\n")
+		fmt.Fprintf(&b, "    bind :%d\n", listener.Port)
+		b.WriteString("    tcp-request inspect-delay 5s\n")
+		b.WriteString("    tcp-request content accept if { req.len gt 0 }\n")
+		for _, route := range listener.Routes {
+			name := exportIdentifier("mc_", route.ServerName+"_"+shortID(route.ServerID))
+			fmt.Fprintf(&b, "    use_backend %s if { req.payload(0,0),lower -m sub %s }\n", name, strings.ToLower(route.Hostname))
+			if _, ok := backends[name]; !ok {
+				backends[name] = route
+				backendOrder = append(backendOrder, name)
+			}
+		}
+		if len(listener.Routes) == 0 {
+			b.WriteString("    # No running servers are routed through this listener\n")
+		}
+		b.WriteString("\n")
+	}
+
+	for _, name := range backendOrder {
+		route := backends[name]
+		fmt.Fprintf(&b, "backend %s\n", name)
+		b.WriteString("    mode tcp\n")
+		fmt.Fprintf(&b, "    server %s %s:%d check\n\n", exportIdentifier("", route.ServerName), route.BackendHost, route.BackendPort)
+	}
+
+	return b.String()
+}
+
+// Nginx's stream module can't inspect the Minecraft handshake, so each server gets its own port
+func renderNginx(listeners []ExportListener) string {
+	var b strings.Builder
+	writeExportHeader(&b,
+		"nginx stream can only preread TLS SNI, which Minecraft doesn't send, so it can't route by hostname.",
+		"The first server on each listener keeps the listener port; others get the following ports.",
+		"Place inside the top-level stream { } block of nginx.conf.",
+	)
+
+	for _, listener := range listeners {
+		fmt.Fprintf(&b, "# Listener %q (port %d)\n", listener.Name, listener.Port)
+		if len(listener.Routes) == 0 {
+			b.WriteString("# No running servers are routed through this listener\n\n")
+			continue
+		}
+
+		for i, route := range listener.Routes {
+			upstream := exportIdentifier("mc_", route.ServerName+"_"+shortID(route.ServerID))
+			fmt.Fprintf(&b, "upstream %s {\n", upstream)
+			fmt.Fprintf(&b, "    server %s:%d;\n", route.BackendHost, route.BackendPort)
+			b.WriteString("}\n\n")
+
+			fmt.Fprintf(&b, "# %s\n", route.Hostname)
+			b.WriteString("server {\n")
+			fmt.Fprintf(&b, "    listen %d;\n", listener.Port+i)
+			fmt.Fprintf(&b, "    proxy_pass %s;\n", upstream)
+			b.WriteString("}\n\n")
+		}
+	}
+
+	return b.String()
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
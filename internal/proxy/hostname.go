@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DNS limits on a whole name and on each dot separated label
+const (
+	maxHostnameLength = 253
+	maxLabelLength    = 63
+)
+
+// NormalizeHostname lowercases a proxy hostname and checks it is a plain DNS name. The
+// usual mistakes, a URL with a scheme, a port or a path, get an error saying what to drop.
+func NormalizeHostname(hostname string) (string, error) {
+	h := strings.ToLower(strings.TrimSpace(hostname))
+	if h == "" {
+		return "", fmt.Errorf("hostname is empty")
+	}
+
+	if scheme, rest, ok := strings.Cut(h, "://"); ok {
+		return "", fmt.Errorf("hostname %q must not include %s://, use %s", hostname, scheme, bareHost(rest))
+	}
+	if strings.ContainsAny(h, "/?#") {
+		return "", fmt.Errorf("hostname %q must not include a path, use %s", hostname, bareHost(h))
+	}
+	if strings.Contains(h, ":") {
+		return "", fmt.Errorf("hostname %q must not include a port, players connect on the listener's port, use %s", hostname, bareHost(h))
+	}
+
+	// A fully qualified name's trailing dot routes the same as without it
+	h = strings.TrimSuffix(h, ".")
+	if len(h) > maxHostnameLength {
+		return "", fmt.Errorf("hostname is %d characters long, the limit is %d", len(h), maxHostnameLength)
+	}
+	for _, label := range strings.Split(h, ".") {
+		if err := checkLabel(label); err != nil {
+			return "", fmt.Errorf("hostname %q is invalid: %w", hostname, err)
+		}
+	}
+	return h, nil
+}
+
+// ResolveHostname normalizes a proxy hostname against the base URL. With suffix set, a name
+// not already under the base URL gets it appended. Returns the full hostname and, when it sits
+// under the base URL, the part before it so the base URL can later be swapped out.
+func ResolveHostname(hostname, baseURL string, suffix bool) (string, string, error) {
+	fqdn, err := NormalizeHostname(hostname)
+	if err != nil {
+		return "", "", err
+	}
+	base := strings.Trim(strings.ToLower(strings.TrimSpace(baseURL)), ".")
+	if base == "" {
+		return fqdn, "", nil
+	}
+	if suffix && fqdn != base && !strings.HasSuffix(fqdn, "."+base) {
+		if fqdn, err = NormalizeHostname(fqdn + "." + base); err != nil {
+			return "", "", err
+		}
+	}
+	subdomain, _ := strings.CutSuffix(fqdn, "."+base)
+	if subdomain == fqdn {
+		return fqdn, "", nil
+	}
+	return fqdn, subdomain, nil
+}
+
+func checkLabel(label string) error {
+	switch {
+	case label == "":
+		return fmt.Errorf("it has an empty label, check for doubled, leading or trailing dots")
+	case len(label) > maxLabelLength:
+		return fmt.Errorf("label %q is longer than %d characters", label, maxLabelLength)
+	case label[0] == '-' || label[len(label)-1] == '-':
+		return fmt.Errorf("label %q must not start or end with a hyphen", label)
+	}
+	for _, c := range label {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return fmt.Errorf("%q is not allowed, use only letters, digits, hyphens and dots", c)
+		}
+	}
+	return nil
+}
+
+// What's left of a mistyped hostname after dropping any scheme, credentials, path and port
+func bareHost(s string) string {
+	if _, rest, ok := strings.Cut(s, "://"); ok {
+		s = rest
+	}
+	if i := strings.IndexAny(s, "/?#"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		s = s[i+1:]
+	}
+	if host, _, ok := strings.Cut(s, ":"); ok {
+		s = host
+	}
+	return s
+}
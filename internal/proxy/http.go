@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -22,6 +23,7 @@ type HTTPProxy struct {
 	routesMutex  sync.RWMutex
 	logger       *logger.Logger
 	listenAddr   string
+	tlsConfig    *tls.Config
 	running      bool
 	runningMutex sync.RWMutex
 }
@@ -32,6 +34,7 @@ func NewHTTPProxy(cfg *Config) *HTTPProxy {
 		routes:     make(map[string]*Route),
 		logger:     cfg.Logger,
 		listenAddr: cfg.ListenAddr,
+		tlsConfig:  cfg.TLSConfig,
 	}
 
 	p.server = &http.Server{
@@ -81,6 +84,9 @@ func (p *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	proxy.Director = func(req *http.Request) {
 		originalDirector(req)
 		req.Host = r.Host
+		if r.TLS != nil {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
 	}
 
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
@@ -215,6 +221,13 @@ func (p *HTTPProxy) Start() error {
 		return fmt.Errorf("failed to listen on %s: %w", p.listenAddr, err)
 	}
 
+	// Terminate TLS here so backends keep speaking plain HTTP
+	scheme := "HTTP"
+	if p.tlsConfig != nil {
+		listener = tls.NewListener(listener, p.tlsConfig)
+		scheme = "HTTPS"
+	}
+
 	p.running = true
 
 	go func() {
@@ -223,7 +236,7 @@ func (p *HTTPProxy) Start() error {
 		}
 	}()
 
-	p.logger.Info("HTTP proxy started on %s", p.listenAddr)
+	p.logger.Info("%s proxy started on %s", scheme, p.listenAddr)
 	return nil
 }
 
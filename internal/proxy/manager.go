@@ -11,6 +11,7 @@ import (
 	"github.com/nickheyer/discopanel/internal/config"
 	db "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/pkg/logger"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // Manager handles the lifecycle of the proxy and manages routes
@@ -21,16 +22,28 @@ type Manager struct {
 	logger      *logger.Logger
 	mu          sync.Mutex
 	networkName string
+	dataDir     string
+
+	acme     *autocert.Manager
+	acmeOnce sync.Once
+
+	connections *ConnectionLog // Shared by every listener and module port proxy
 }
 
 // NewManager creates a new proxy manager
 func NewManager(store *db.Store, cfg *config.Config, logger *logger.Logger) *Manager {
+	connections := NewConnectionLog(DefaultConnectionLogSize)
+	if err := connections.SetLogging(cfg.Proxy.ConnectionLogging, logger); err != nil {
+		logger.Warn("Proxy connection logging disabled: %v", err)
+	}
 	return &Manager{
 		proxies:     make(map[int]Proxier),
 		store:       store,
 		config:      &cfg.Proxy,
 		logger:      logger,
 		networkName: cfg.Docker.NetworkName,
+		dataDir:     cfg.Storage.DataDir,
+		connections: connections,
 	}
 }
 
@@ -61,14 +74,12 @@ func (m *Manager) Start() error {
 			continue
 		}
 
-		listenAddr := fmt.Sprintf(":%d", listener.Port)
-		proxy := NewMinecraftProxy(&Config{
-			ListenAddr: listenAddr,
-			Logger:     m.logger,
-		})
-
+		proxy, err := m.newListenerProxy(listener)
+		if err != nil {
+			m.logger.Error("Failed to create proxy for listener %s: %v", listener.Name, err)
+			continue
+		}
 		m.proxies[listener.Port] = proxy
-		m.logger.Info("Created Minecraft proxy for listener %s on port %d", listener.Name, listener.Port)
 	}
 
 	// Load existing server routes
@@ -88,7 +99,7 @@ func (m *Manager) Start() error {
 		if server.ProxyHostname != "" && server.ContainerID != "" && server.ProxyListenerID != "" {
 			// Find which listener this server uses
 			listener, ok := listenerMap[server.ProxyListenerID]
-			if !ok || !listener.Enabled {
+			if !ok || !listener.Enabled || !routesByHandshake(listener) {
 				m.logger.Error("Server %s has invalid or disabled listener %s", server.Name, server.ProxyListenerID)
 				continue
 			}
@@ -118,6 +129,13 @@ func (m *Manager) Start() error {
 		}
 	}
 
+	// Point Bedrock listeners at their target servers
+	for _, listener := range listeners {
+		if listener.Enabled && listener.Protocol == db.ListenerProtocolUDP {
+			m.refreshUDPListenerUnlocked(listener)
+		}
+	}
+
 	// Start all proxy instances
 	for port, proxy := range m.proxies {
 		if err := proxy.Start(); err != nil {
@@ -160,6 +178,9 @@ func (m *Manager) UpdateServerRoute(server *db.Server) error {
 		return nil
 	}
 
+	// Bedrock listeners follow the server regardless of its Java routing
+	m.refreshServerUDPListenersUnlocked(server.ID)
+
 	// Get the listener for this server
 	if server.ProxyListenerID == "" {
 		return nil // No listener assigned
@@ -170,8 +191,8 @@ func (m *Manager) UpdateServerRoute(server *db.Server) error {
 		return fmt.Errorf("failed to get proxy listener: %w", err)
 	}
 
-	if !listener.Enabled {
-		return nil // Listener is disabled
+	if !listener.Enabled || !routesByHandshake(listener) {
+		return nil // Listener is disabled or doesn't carry Java traffic
 	}
 
 	// Get the proxy instance for this listener's port
@@ -296,6 +317,26 @@ func (m *Manager) GetRoutes() map[string]*Route {
 	return allRoutes
 }
 
+// Connections returns recent proxy connections newest first, plus cumulative and per-IP counters
+func (m *Manager) Connections() ([]ConnectionRecord, ConnectionStats, []ClientStats) {
+	return m.connections.Snapshot()
+}
+
+// SetConnectionLogging changes which proxy connections are logged until the next restart,
+// which goes back to proxy.connection_logging
+func (m *Manager) SetConnectionLogging(verbosity string) error {
+	if err := m.connections.SetLogging(verbosity, m.logger); err != nil {
+		return err
+	}
+	m.logger.Info("Proxy connection logging set to %s", verbosity)
+	return nil
+}
+
+// ConnectionLogging returns the current proxy connection logging verbosity
+func (m *Manager) ConnectionLogging() string {
+	return m.connections.Logging()
+}
+
 // IsRunning returns whether any proxy is running
 func (m *Manager) IsRunning() bool {
 	m.mu.Lock()
@@ -325,11 +366,10 @@ func (m *Manager) AddListener(listener *db.ProxyListener) error {
 	}
 
 	// Create new proxy instance
-	listenAddr := fmt.Sprintf(":%d", listener.Port)
-	proxy := NewMinecraftProxy(&Config{
-		ListenAddr: listenAddr,
-		Logger:     m.logger,
-	})
+	proxy, err := m.newListenerProxy(listener)
+	if err != nil {
+		return err
+	}
 
 	// Start the proxy
 	if err := proxy.Start(); err != nil {
@@ -337,11 +377,134 @@ func (m *Manager) AddListener(listener *db.ProxyListener) error {
 	}
 
 	m.proxies[listener.Port] = proxy
-	m.logger.Info("Added and started Minecraft proxy for listener %s on port %d", listener.Name, listener.Port)
+	m.logger.Info("Started proxy for listener %s on port %d", listener.Name, listener.Port)
+
+	if listener.Protocol == db.ListenerProtocolUDP {
+		m.refreshUDPListenerUnlocked(listener)
+	}
 
 	return nil
 }
 
+// newListenerProxy creates the proxy type matching a listener's protocol
+func (m *Manager) newListenerProxy(listener *db.ProxyListener) (Proxier, error) {
+	cfg := &Config{
+		ListenAddr:  fmt.Sprintf(":%d", listener.Port),
+		Logger:      m.logger,
+		Connections: m.connections,
+	}
+
+	switch listener.Protocol {
+	case db.ListenerProtocolUDP:
+		m.logger.Info("Created UDP proxy for listener %s on port %d", listener.Name, listener.Port)
+		return NewUDPProxy(cfg), nil
+	case db.ListenerProtocolHTTP:
+		tlsConfig, err := m.listenerTLSConfig(listener)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure tls for listener %s: %w", listener.Name, err)
+		}
+		cfg.TLSConfig = tlsConfig
+		m.logger.Info("Created HTTP proxy for listener %s on port %d (tls: %q)", listener.Name, listener.Port, listener.TLSMode)
+		return NewHTTPProxy(cfg), nil
+	default:
+		m.logger.Info("Created Minecraft proxy for listener %s on port %d", listener.Name, listener.Port)
+		return NewMinecraftProxy(cfg), nil
+	}
+}
+
+// routesByHandshake reports whether a listener carries Java connections routed by hostname
+func routesByHandshake(listener *db.ProxyListener) bool {
+	return listener.Protocol == "" || listener.Protocol == db.ListenerProtocolTCP
+}
+
+// RefreshServerListeners re-resolves the backend of every UDP listener targeting a server
+func (m *Manager) RefreshServerListeners(serverID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.proxies) == 0 || !m.config.Enabled {
+		return
+	}
+
+	m.refreshServerUDPListenersUnlocked(serverID)
+}
+
+// refreshServerUDPListenersUnlocked refreshes UDP listeners targeting a server (must be called with lock held)
+func (m *Manager) refreshServerUDPListenersUnlocked(serverID string) {
+	listeners, err := m.store.GetProxyListeners(context.Background())
+	if err != nil {
+		m.logger.Error("Failed to load proxy listeners: %v", err)
+		return
+	}
+
+	for _, listener := range listeners {
+		if listener.Enabled && listener.Protocol == db.ListenerProtocolUDP && listener.TargetServerID == serverID {
+			m.refreshUDPListenerUnlocked(listener)
+		}
+	}
+}
+
+// refreshUDPListenerUnlocked points a UDP listener at its target server's Bedrock endpoint.
+// A running module exposing the target port over UDP (the Geyser sidecar) takes precedence,
+// otherwise datagrams go straight to the server container for native Bedrock images.
+func (m *Manager) refreshUDPListenerUnlocked(listener *db.ProxyListener) {
+	proxy, ok := m.proxies[listener.Port]
+	if !ok {
+		return
+	}
+
+	targetPort := listener.TargetPort
+	if targetPort == 0 {
+		targetPort = db.DefaultBedrockPort
+	}
+
+	backendHost, err := m.resolveBedrockBackend(listener.TargetServerID, targetPort)
+	if err != nil || backendHost == "" {
+		if err != nil {
+			m.logger.Debug("No Bedrock backend for listener %s: %v", listener.Name, err)
+		}
+		proxy.RemoveRoute("udp")
+		return
+	}
+
+	proxy.AddRoute(listener.TargetServerID, "udp", backendHost, targetPort)
+}
+
+// resolveBedrockBackend finds the container IP serving a server's Bedrock port, or "" if none is up
+func (m *Manager) resolveBedrockBackend(serverID string, targetPort int) (string, error) {
+	if serverID == "" {
+		return "", nil
+	}
+
+	ctx := context.Background()
+	modules, err := m.store.ListServerModules(ctx, serverID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	for _, module := range modules {
+		if module.ContainerID == "" || !module.Status.IsUp() {
+			continue
+		}
+		for _, port := range module.Ports {
+			if port != nil && port.Protocol == "udp" && int(port.ContainerPort) == targetPort {
+				return GetContainerIP(module.ContainerID, m.networkName)
+			}
+		}
+	}
+
+	server, err := m.store.GetServer(ctx, serverID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get server: %w", err)
+	}
+
+	if server.ContainerID == "" || (server.Status != db.StatusRunning && server.Status != db.StatusStarting) {
+		return "", nil
+	}
+
+	return GetContainerIP(server.ContainerID, m.networkName)
+}
+
 // RemoveListener stops and removes a proxy instance for a listener
 func (m *Manager) RemoveListener(port int) error {
 	m.mu.Lock()
@@ -440,8 +603,9 @@ func (m *Manager) addPortRouteUnlocked(routeID, hostname, containerIP string, ho
 	if !exists {
 		listenAddr := fmt.Sprintf(":%d", hostPort)
 		cfg := &Config{
-			ListenAddr: listenAddr,
-			Logger:     m.logger,
+			ListenAddr:  listenAddr,
+			Logger:      m.logger,
+			Connections: m.connections,
 		}
 
 		// Create appropriate proxy type based on protocol
@@ -521,6 +685,11 @@ func (m *Manager) removePortRouteUnlocked(hostPort int, hostname, moduleName, po
 		m.logger.Info("Removed module route: %s:%d (module: %s, port: %s)", hostname, hostPort, moduleName, portName)
 	}
 
+	// Listener-owned proxies (e.g. HTTPS listeners shared by module web UIs) outlive their routes
+	if listener, _ := m.store.GetProxyListenerByPort(context.Background(), hostPort); listener != nil && listener.Enabled {
+		return
+	}
+
 	// Check if this proxy has any remaining routes
 	routes := proxy.GetRoutes()
 	if len(routes) == 0 {
@@ -635,6 +804,7 @@ func (m *Manager) ensureDefaultListenerLocked() (*db.ProxyListener, error) {
 		Name:      "Primary",
 		IsDefault: true,
 		Enabled:   true,
+		Protocol:  db.ListenerProtocolTCP,
 	}
 
 	if err := m.store.CreateProxyListener(ctx, defaultListener); err != nil {
@@ -3,7 +3,6 @@ package proxy
 import (
 	"context"
 	"fmt"
-	"io"
 	"net"
 	"strings"
 	"sync"
@@ -23,17 +22,19 @@ type MinecraftProxy struct {
 	runningMutex sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
+	connections  *ConnectionLog
 }
 
 // NewMinecraftProxy creates a new Minecraft proxy instance
 func NewMinecraftProxy(cfg *Config) *MinecraftProxy {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &MinecraftProxy{
-		routes:     make(map[string]*Route),
-		logger:     cfg.Logger,
-		listenAddr: cfg.ListenAddr,
-		ctx:        ctx,
-		cancel:     cancel,
+		routes:      make(map[string]*Route),
+		logger:      cfg.Logger,
+		listenAddr:  cfg.ListenAddr,
+		ctx:         ctx,
+		cancel:      cancel,
+		connections: cfg.Connections,
 	}
 }
 
@@ -160,6 +161,8 @@ func (p *MinecraftProxy) handleConnection(clientConn net.Conn) {
 
 	p.logger.Debug("Attempting to route incoming Minecraft connection!")
 
+	rec := p.connections.Begin(listenerPort(p.listener), clientConn.RemoteAddr())
+
 	// Set initial timeout for handshake
 	clientConn.SetReadDeadline(time.Now().Add(10 * time.Second))
 
@@ -167,6 +170,7 @@ func (p *MinecraftProxy) handleConnection(clientConn net.Conn) {
 	handshake, err := ReadHandshakePacket(clientConn)
 	if err != nil {
 		p.logger.Debug("Failed to read handshake from %s: %v", clientConn.RemoteAddr(), err)
+		p.connections.Finish(rec, OutcomeHandshakeError, 0, 0)
 		return
 	}
 
@@ -183,8 +187,18 @@ func (p *MinecraftProxy) handleConnection(clientConn net.Conn) {
 	route, exists := p.routes[hostname]
 	p.routesMutex.RUnlock()
 
+	intent := "login"
+	if handshake.NextState == 1 {
+		intent = "status"
+	}
+
 	if !exists || !route.Active {
-		p.logger.Debug("No active route found for hostname: %s", hostname)
+		p.connections.Route(rec, hostname, "", "", intent)
+		p.connections.Finish(rec, OutcomeNoRoute, 0, 0)
+		if p.connections.ShouldWarnUnmatched(hostname) {
+			p.logger.Warn("No server route for hostname %q (from %s on %s); check the DNS record and the server's proxy hostname",
+				hostname, clientConn.RemoteAddr(), p.listenAddr)
+		}
 		p.routesMutex.RLock()
 		p.logger.Debug("Available routes:")
 		for r := range p.routes {
@@ -193,12 +207,13 @@ func (p *MinecraftProxy) handleConnection(clientConn net.Conn) {
 		p.routesMutex.RUnlock()
 		return
 	}
-
 	// Connect to backend
 	backendAddr := net.JoinHostPort(route.BackendHost, fmt.Sprintf("%d", route.BackendPort))
+	p.connections.Route(rec, hostname, route.ServerID, backendAddr, intent)
 	backendConn, err := net.DialTimeout("tcp", backendAddr, 5*time.Second)
 	if err != nil {
 		p.logger.Error("Failed to connect to backend %s: %v", backendAddr, err)
+		p.connections.Finish(rec, OutcomeBackendError, 0, 0)
 		return
 	}
 	defer backendConn.Close()
@@ -229,6 +244,7 @@ func (p *MinecraftProxy) handleConnection(clientConn net.Conn) {
 	// Forward the modified handshake to the backend
 	if err := WriteHandshakePacket(backendConn, handshake); err != nil {
 		p.logger.Error("Failed to write handshake to backend: %v", err)
+		p.connections.Finish(rec, OutcomeBackendError, 0, 0)
 		return
 	}
 
@@ -237,22 +253,8 @@ func (p *MinecraftProxy) handleConnection(clientConn net.Conn) {
 	backendConn.SetReadDeadline(time.Time{})
 
 	// Start bidirectional proxying
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		io.Copy(backendConn, clientConn)
-		backendConn.Close()
-	}()
-
-	go func() {
-		defer wg.Done()
-		io.Copy(clientConn, backendConn)
-		clientConn.Close()
-	}()
-
-	wg.Wait()
+	bytesIn, bytesOut := pipeConns(clientConn, backendConn)
+	p.connections.Finish(rec, OutcomeClosed, bytesIn, bytesOut)
 }
 
 // GetRoutes returns a copy of all current routes
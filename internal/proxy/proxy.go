@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"crypto/tls"
+
 	"github.com/nickheyer/discopanel/pkg/logger"
 )
 
@@ -28,4 +30,7 @@ type Route struct {
 type Config struct {
 	ListenAddr string // Address to listen on (e.g., ":25565" or ":8080")
 	Logger     *logger.Logger
+	TLSConfig  *tls.Config // Terminates TLS when set (HTTP proxies only)
+
+	Connections *ConnectionLog // Records accepted connections (Minecraft and TCP proxies)
 }
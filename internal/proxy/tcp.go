@@ -3,7 +3,6 @@ package proxy
 import (
 	"context"
 	"fmt"
-	"io"
 	"net"
 	"sync"
 	"time"
@@ -23,16 +22,18 @@ type TCPProxy struct {
 	runningMutex sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
+	connections  *ConnectionLog
 }
 
 // NewTCPProxy creates a new raw TCP proxy instance
 func NewTCPProxy(cfg *Config) *TCPProxy {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &TCPProxy{
-		logger:     cfg.Logger,
-		listenAddr: cfg.ListenAddr,
-		ctx:        ctx,
-		cancel:     cancel,
+		logger:      cfg.Logger,
+		listenAddr:  cfg.ListenAddr,
+		ctx:         ctx,
+		cancel:      cancel,
+		connections: cfg.Connections,
 	}
 }
 
@@ -162,18 +163,23 @@ func (p *TCPProxy) handleConnection(clientConn net.Conn) {
 	p.runningMutex.RLock()
 	backendHost := p.backendHost
 	backendPort := p.backendPort
+	serverID := p.serverID
 	p.runningMutex.RUnlock()
 
+	rec := p.connections.Begin(listenerPort(p.listener), clientConn.RemoteAddr())
+
 	if backendHost == "" || backendPort == 0 {
 		p.logger.Debug("No backend configured for TCP proxy")
+		p.connections.Finish(rec, OutcomeNoRoute, 0, 0)
 		return
 	}
-
 	// Connect to backend
 	backendAddr := net.JoinHostPort(backendHost, fmt.Sprintf("%d", backendPort))
+	p.connections.Route(rec, "", serverID, backendAddr, "")
 	backendConn, err := net.DialTimeout("tcp", backendAddr, 5*time.Second)
 	if err != nil {
 		p.logger.Error("Failed to connect to backend %s: %v", backendAddr, err)
+		p.connections.Finish(rec, OutcomeBackendError, 0, 0)
 		return
 	}
 	defer backendConn.Close()
@@ -181,20 +187,6 @@ func (p *TCPProxy) handleConnection(clientConn net.Conn) {
 	p.logger.Debug("TCP connection established: %s -> %s", clientConn.RemoteAddr(), backendAddr)
 
 	// Start bidirectional proxying
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		io.Copy(backendConn, clientConn)
-		backendConn.Close()
-	}()
-
-	go func() {
-		defer wg.Done()
-		io.Copy(clientConn, backendConn)
-		clientConn.Close()
-	}()
-
-	wg.Wait()
+	bytesIn, bytesOut := pipeConns(clientConn, backendConn)
+	p.connections.Finish(rec, OutcomeClosed, bytesIn, bytesOut)
 }
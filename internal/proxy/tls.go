@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/nickheyer/discopanel/internal/db"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// listenerTLSConfig builds the TLS config for an HTTP listener, nil for plain HTTP
+func (m *Manager) listenerTLSConfig(listener *db.ProxyListener) (*tls.Config, error) {
+	if listener.Protocol != db.ListenerProtocolHTTP {
+		return nil, nil
+	}
+
+	switch listener.TLSMode {
+	case db.ListenerTLSNone:
+		return nil, nil
+	case db.ListenerTLSFiles:
+		certs, err := newCertStore(listener.TLSCertPath, listener.TLSKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     []string{"h2", "http/1.1"},
+			GetCertificate: certs.getCertificate,
+		}, nil
+	case db.ListenerTLSACME:
+		return m.acmeManager().TLSConfig(), nil
+	default:
+		return nil, fmt.Errorf("unknown tls mode %q", listener.TLSMode)
+	}
+}
+
+// acmeManager lazily creates the shared autocert manager. Certificates are only
+// issued for hostnames assigned to a server, and are validated with TLS-ALPN-01,
+// so the listener must be reachable from the internet on port 443.
+func (m *Manager) acmeManager() *autocert.Manager {
+	m.acmeOnce.Do(func() {
+		m.acme = &autocert.Manager{
+			Prompt: autocert.AcceptTOS,
+			Cache:  autocert.DirCache(filepath.Join(m.dataDir, "acme")),
+			Email:  m.config.ACMEEmail,
+			HostPolicy: func(ctx context.Context, host string) error {
+				servers, err := m.store.ListServers(ctx)
+				if err != nil {
+					return err
+				}
+				for _, server := range servers {
+					if server.ProxyHostname != "" && strings.EqualFold(server.ProxyHostname, host) {
+						return nil
+					}
+				}
+				return fmt.Errorf("acme: host %q is not assigned to any server", host)
+			},
+		}
+		if m.config.ACMEDirectory != "" {
+			m.acme.Client = &acme.Client{DirectoryURL: m.config.ACMEDirectory}
+		}
+	})
+	return m.acme
+}
+
+// certStore serves PEM certificates from disk, picking one by SNI and
+// reloading it when the files change so external renewals are picked up.
+//
+// certPath is either a single certificate (with keyPath as its key) or a
+// directory of <hostname>.crt/<hostname>.key pairs, where "_.example.com"
+// covers "*.example.com".
+type certStore struct {
+	certPath string
+	keyPath  string
+	dir      bool
+
+	mu     sync.Mutex
+	loaded map[string]*loadedCert // cert file -> parsed cert
+}
+
+type loadedCert struct {
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertStore(certPath, keyPath string) (*certStore, error) {
+	if certPath == "" {
+		return nil, fmt.Errorf("tls certificate path is required")
+	}
+
+	info, err := os.Stat(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tls certificate path: %w", err)
+	}
+
+	store := &certStore{
+		certPath: certPath,
+		keyPath:  keyPath,
+		dir:      info.IsDir(),
+		loaded:   make(map[string]*loadedCert),
+	}
+
+	if !store.dir {
+		if keyPath == "" {
+			return nil, fmt.Errorf("tls key path is required")
+		}
+		if _, err := store.load(certPath, keyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+func (s *certStore) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !s.dir {
+		return s.load(s.certPath, s.keyPath)
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name == "" {
+		return nil, fmt.Errorf("tls: client did not send a server name")
+	}
+
+	candidates := []string{name}
+	if i := strings.Index(name, "."); i > 0 {
+		candidates = append(candidates, "_"+name[i:])
+	}
+
+	for _, candidate := range candidates {
+		certFile := filepath.Join(s.certPath, candidate+".crt")
+		if _, err := os.Stat(certFile); err != nil {
+			continue
+		}
+		return s.load(certFile, filepath.Join(s.certPath, candidate+".key"))
+	}
+
+	return nil, fmt.Errorf("tls: no certificate for %q", name)
+}
+
+func (s *certStore) load(certFile, keyFile string) (*tls.Certificate, error) {
+	info, err := os.Stat(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat certificate: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.loaded[certFile]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %w", certFile, err)
+	}
+
+	s.loaded[certFile] = &loadedCert{cert: &cert, modTime: info.ModTime()}
+	return &cert, nil
+}
@@ -9,29 +9,93 @@ type ProcedurePermission struct {
 
 // PublicProcedures lists RPC procedures that require no authentication.
 var PublicProcedures = map[string]bool{
-	"/discopanel.v1.AuthService/GetAuthStatus":   true,
-	"/discopanel.v1.AuthService/Login":           true,
-	"/discopanel.v1.AuthService/Register":        true,
-	"/discopanel.v1.AuthService/GetOIDCLoginURL": true,
-	"/discopanel.v1.AuthService/ValidateInvite":  true,
-	"/discopanel.v1.AuthService/UseRecoveryKey":  true,
+	"/discopanel.v1.AuthService/GetAuthStatus":      true,
+	"/discopanel.v1.AuthService/Login":              true,
+	"/discopanel.v1.AuthService/Register":           true,
+	"/discopanel.v1.AuthService/GetOIDCLoginURL":    true,
+	"/discopanel.v1.AuthService/ValidateInvite":     true,
+	"/discopanel.v1.AuthService/UseRecoveryKey":     true,
+	"/discopanel.v1.AuthService/BeginPasskeyLogin":  true,
+	"/discopanel.v1.AuthService/FinishPasskeyLogin": true,
+	"/discopanel.v1.HealthService/Check":            true,
+}
+
+// PanelProcedures lists the read-only calls a server's panel token may make. The value
+// is the request field naming the server, empty for reference data.
+var PanelProcedures = map[string]string{
+	"/discopanel.v1.ServerService/GetServer":        "id",
+	"/discopanel.v1.ConfigService/GetServerConfig":  "server_id",
+	"/discopanel.v1.MinecraftService/GetModLoaders": "",
+	"/discopanel.v1.ModpackService/GetModpackByURL": "",
 }
 
 // AuthenticatedOnlyProcedures lists RPC procedures that require authentication
 // but no specific resource permission.
 var AuthenticatedOnlyProcedures = map[string]bool{
 	// AuthService - authenticated user operations
-	"/discopanel.v1.AuthService/GetCurrentUser": true,
-	"/discopanel.v1.AuthService/Logout":         true,
-	"/discopanel.v1.AuthService/ChangePassword": true,
-	"/discopanel.v1.AuthService/CreateAPIToken": true,
-	"/discopanel.v1.AuthService/ListAPITokens":  true,
-	"/discopanel.v1.AuthService/DeleteAPIToken": true,
+	"/discopanel.v1.AuthService/GetCurrentUser":              true,
+	"/discopanel.v1.AuthService/Logout":                      true,
+	"/discopanel.v1.AuthService/ChangePassword":              true,
+	"/discopanel.v1.AuthService/CreateAPIToken":              true,
+	"/discopanel.v1.AuthService/ListAPITokens":               true,
+	"/discopanel.v1.AuthService/DeleteAPIToken":              true,
+	"/discopanel.v1.AuthService/ListSessions":                true,
+	"/discopanel.v1.AuthService/RevokeSession":               true,
+	"/discopanel.v1.AuthService/RevokeOtherSessions":         true,
+	"/discopanel.v1.AuthService/BeginPasskeyRegistration":    true,
+	"/discopanel.v1.AuthService/FinishPasskeyRegistration":   true,
+	"/discopanel.v1.AuthService/ListPasskeys":                true,
+	"/discopanel.v1.AuthService/DeletePasskey":               true,
+	"/discopanel.v1.AuthService/GetTOTPStatus":               true,
+	"/discopanel.v1.AuthService/BeginTOTPEnrollment":         true,
+	"/discopanel.v1.AuthService/ConfirmTOTPEnrollment":       true,
+	"/discopanel.v1.AuthService/DisableTOTP":                 true,
+	"/discopanel.v1.AuthService/RegenerateTOTPRecoveryCodes": true,
+	"/discopanel.v1.AuthService/BeginOIDCLink":               true,
+	"/discopanel.v1.AuthService/UnlinkOIDCIdentity":          true,
+
+	// HealthService - build information
+	"/discopanel.v1.HealthService/GetVersion": true,
+
+	// AuditService - scoped to the caller's own servers
+	"/discopanel.v1.AuditService/ListServerActivity": true,
 
 	// MinecraftService - reference data, no resource ownership
 	"/discopanel.v1.MinecraftService/GetMinecraftVersions": true,
 	"/discopanel.v1.MinecraftService/GetModLoaders":        true,
 	"/discopanel.v1.MinecraftService/GetDockerImages":      true,
+	"/discopanel.v1.MinecraftService/ResolveDockerImage":   true,
+}
+
+// ServerCollectionProcedures are the reads on every server ("*") whose handlers narrow the
+// result to what the caller may see, with ServerFilter or a check per server, or to what the
+// caller started. Users with only per-server grants may call them, other "*" reads need the
+// permission on all servers.
+var ServerCollectionProcedures = map[string]bool{
+	"/discopanel.v1.ServerService/ListServers":        true,
+	"/discopanel.v1.ServerService/StartBulkOperation": true,
+	"/discopanel.v1.ServerService/GetBulkOperation":   true,
+	"/discopanel.v1.FileService/GetExtractionStatus":  true,
+}
+
+// CredentialProcedures are the authenticated-only calls that change how the account signs
+// in or what it can do. Scoped API tokens may not make them, or a token limited to reading
+// servers could mint an unrestricted one or take over the account.
+var CredentialProcedures = map[string]bool{
+	"/discopanel.v1.AuthService/ChangePassword":              true,
+	"/discopanel.v1.AuthService/CreateAPIToken":              true,
+	"/discopanel.v1.AuthService/DeleteAPIToken":              true,
+	"/discopanel.v1.AuthService/RevokeSession":               true,
+	"/discopanel.v1.AuthService/RevokeOtherSessions":         true,
+	"/discopanel.v1.AuthService/BeginPasskeyRegistration":    true,
+	"/discopanel.v1.AuthService/FinishPasskeyRegistration":   true,
+	"/discopanel.v1.AuthService/DeletePasskey":               true,
+	"/discopanel.v1.AuthService/BeginTOTPEnrollment":         true,
+	"/discopanel.v1.AuthService/ConfirmTOTPEnrollment":       true,
+	"/discopanel.v1.AuthService/DisableTOTP":                 true,
+	"/discopanel.v1.AuthService/RegenerateTOTPRecoveryCodes": true,
+	"/discopanel.v1.AuthService/BeginOIDCLink":               true,
+	"/discopanel.v1.AuthService/UnlinkOIDCIdentity":          true,
 }
 
 // ProcedurePermissions maps each RPC procedure path to the resource and action
@@ -44,27 +108,64 @@ var ProcedurePermissions = map[string]ProcedurePermission{
 	"/discopanel.v1.ServerService/ClearServerLogs":      {Resource: ResourceServers, Action: ActionUpdate, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/GetNextAvailablePort": {Resource: ResourceServers, Action: ActionRead},
 	"/discopanel.v1.ServerService/CreateServer":         {Resource: ResourceServers, Action: ActionCreate},
+	"/discopanel.v1.ServerService/CloneServer":          {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"}, // Handler also requires servers:create
+	"/discopanel.v1.ServerService/PreviewServerDataDir": {Resource: ResourceServers, Action: ActionCreate},
+	"/discopanel.v1.ServerService/GetServerDefaults":    {Resource: ResourceServers, Action: ActionCreate},
+	"/discopanel.v1.ServerService/ImportServer":         {Resource: ResourceServers, Action: ActionCreate},
 	"/discopanel.v1.ServerService/UpdateServer":         {Resource: ResourceServers, Action: ActionUpdate, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/DeleteServer":         {Resource: ResourceServers, Action: ActionDelete, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/StartServer":          {Resource: ResourceServers, Action: ActionStart, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/StopServer":           {Resource: ResourceServers, Action: ActionStop, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/RestartServer":        {Resource: ResourceServers, Action: ActionRestart, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/RecreateServer":       {Resource: ResourceServers, Action: ActionRestart, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/PrepareServer":        {Resource: ResourceServers, Action: ActionStart, ObjectIDField: "id"},
 	"/discopanel.v1.ServerService/SendCommand":          {Resource: ResourceServers, Action: ActionCommand, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/ListPlayers":          {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/ManagePlayer":         {Resource: ResourceServers, Action: ActionCommand, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/ListCrashDumps":       {Resource: ResourceFiles, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/ListServerACL":        {Resource: ResourceUsers, Action: ActionRead},
+	"/discopanel.v1.ServerService/GrantServerACL":       {Resource: ResourceUsers, Action: ActionUpdate},
+	"/discopanel.v1.ServerService/RevokeServerACL":      {Resource: ResourceUsers, Action: ActionUpdate},
+	"/discopanel.v1.ServerService/ListBackups":          {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/RestoreBackup":        {Resource: ResourceServers, Action: ActionUpdate, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/VerifyBackup":         {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/GetModpackUpdate":     {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/UpdateModpack":        {Resource: ResourceServers, Action: ActionUpdate, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/GetServerImageStatus": {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/UpdateServerImage":    {Resource: ResourceServers, Action: ActionUpdate, ObjectIDField: "id"},
+	// Each server in a bulk operation is checked against the action it runs
+	"/discopanel.v1.ServerService/StartBulkOperation": {Resource: ResourceServers, Action: ActionRead},
+	"/discopanel.v1.ServerService/GetBulkOperation":   {Resource: ResourceServers, Action: ActionRead},
+	"/discopanel.v1.ServerService/ListWorlds":         {Resource: ResourceServers, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ServerService/SetActiveWorld":     {Resource: ResourceServers, Action: ActionUpdate, ObjectIDField: "id"},
 
 	// ── AuthService (admin) ───────────────────────────────────────────
 	"/discopanel.v1.AuthService/GetAuthConfig":      {Resource: ResourceSettings, Action: ActionRead},
 	"/discopanel.v1.AuthService/UpdateAuthSettings": {Resource: ResourceSettings, Action: ActionUpdate},
+	"/discopanel.v1.AuthService/TestOIDCConfig":     {Resource: ResourceSettings, Action: ActionRead},
 	"/discopanel.v1.AuthService/CreateInvite":       {Resource: ResourceUsers, Action: ActionCreate},
 	"/discopanel.v1.AuthService/ListInvites":        {Resource: ResourceUsers, Action: ActionRead},
 	"/discopanel.v1.AuthService/GetInvite":          {Resource: ResourceUsers, Action: ActionRead},
 	"/discopanel.v1.AuthService/DeleteInvite":       {Resource: ResourceUsers, Action: ActionDelete},
 
+	// ── AuditService ───────────────────────────────────────────────────
+	"/discopanel.v1.AuditService/ListAuditLogs": {Resource: ResourceAudit, Action: ActionRead},
+
 	// ── ConfigService ──────────────────────────────────────────────────
-	"/discopanel.v1.ConfigService/GetServerConfig":      {Resource: ResourceServerConfig, Action: ActionRead, ObjectIDField: "server_id"},
-	"/discopanel.v1.ConfigService/UpdateServerConfig":   {Resource: ResourceServerConfig, Action: ActionUpdate, ObjectIDField: "server_id"},
-	"/discopanel.v1.ConfigService/GetGlobalSettings":    {Resource: ResourceSettings, Action: ActionRead},
-	"/discopanel.v1.ConfigService/UpdateGlobalSettings": {Resource: ResourceSettings, Action: ActionUpdate},
+	"/discopanel.v1.ConfigService/GetServerConfig":        {Resource: ResourceServerConfig, Action: ActionRead, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/GetServerConfigDiff":    {Resource: ResourceServerConfig, Action: ActionRead, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/UpdateServerConfig":     {Resource: ResourceServerConfig, Action: ActionUpdate, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/ImportCustomServerJar":  {Resource: ResourceServerConfig, Action: ActionUpdate, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/ImportServerProperties": {Resource: ResourceServerConfig, Action: ActionUpdate, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/ExportServerProperties": {Resource: ResourceServerConfig, Action: ActionRead, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/ListConfigPresets":      {Resource: ResourceServerConfig, Action: ActionRead},
+	"/discopanel.v1.ConfigService/CreateConfigPreset":     {Resource: ResourceSettings, Action: ActionUpdate},
+	"/discopanel.v1.ConfigService/DeleteConfigPreset":     {Resource: ResourceSettings, Action: ActionUpdate},
+	"/discopanel.v1.ConfigService/ApplyConfigPreset":      {Resource: ResourceServerConfig, Action: ActionUpdate, ObjectIDField: "server_id"},
+	"/discopanel.v1.ConfigService/GetGlobalSettings":      {Resource: ResourceSettings, Action: ActionRead},
+	"/discopanel.v1.ConfigService/UpdateGlobalSettings":   {Resource: ResourceSettings, Action: ActionUpdate},
+	"/discopanel.v1.ConfigService/ExportFleet":            {Resource: ResourceSettings, Action: ActionRead},
+	"/discopanel.v1.ConfigService/ImportFleet":            {Resource: ResourceSettings, Action: ActionUpdate},
 
 	// ── FileService ────────────────────────────────────────────────────
 	"/discopanel.v1.FileService/ListFiles":           {Resource: ResourceFiles, Action: ActionRead, ObjectIDField: "server_id"},
@@ -81,6 +182,8 @@ var ProcedurePermissions = map[string]ProcedurePermission{
 	"/discopanel.v1.FileService/DownloadArchive":     {Resource: ResourceFiles, Action: ActionRead, ObjectIDField: "server_id"},
 	"/discopanel.v1.FileService/InitFileDownload":    {Resource: ResourceFiles, Action: ActionRead, ObjectIDField: "server_id"},
 	"/discopanel.v1.FileService/GetExtractionStatus": {Resource: ResourceFiles, Action: ActionRead},
+	"/discopanel.v1.FileService/DownloadWorld":       {Resource: ResourceFiles, Action: ActionRead, ObjectIDField: "server_id"},
+	"/discopanel.v1.FileService/ReplaceWorld":        {Resource: ResourceFiles, Action: ActionUpdate, ObjectIDField: "server_id"},
 
 	// ── ModService ─────────────────────────────────────────────────────
 	"/discopanel.v1.ModService/ListMods":          {Resource: ResourceMods, Action: ActionRead, ObjectIDField: "server_id"},
@@ -120,6 +223,8 @@ var ProcedurePermissions = map[string]ProcedurePermission{
 	"/discopanel.v1.ModuleService/StopModule":                 {Resource: ResourceModules, Action: ActionStop, ObjectIDField: "id"},
 	"/discopanel.v1.ModuleService/RestartModule":              {Resource: ResourceModules, Action: ActionRestart, ObjectIDField: "id"},
 	"/discopanel.v1.ModuleService/RecreateModule":             {Resource: ResourceModules, Action: ActionRestart, ObjectIDField: "id"},
+	"/discopanel.v1.ModuleService/GetModuleImageStatus":       {Resource: ResourceModules, Action: ActionRead, ObjectIDField: "id"},
+	"/discopanel.v1.ModuleService/UpdateModuleImage":          {Resource: ResourceModules, Action: ActionUpdate, ObjectIDField: "id"},
 	"/discopanel.v1.ModuleService/GetModuleLogs":              {Resource: ResourceModules, Action: ActionRead, ObjectIDField: "id"},
 	"/discopanel.v1.ModuleService/GetNextAvailableModulePort": {Resource: ResourceModules, Action: ActionRead},
 	"/discopanel.v1.ModuleService/GetAvailableAliases":        {Resource: ResourceModules, Action: ActionRead},
@@ -135,6 +240,9 @@ var ProcedurePermissions = map[string]ProcedurePermission{
 	"/discopanel.v1.ProxyService/DeleteProxyListener": {Resource: ResourceProxy, Action: ActionDelete, ObjectIDField: "id"},
 	"/discopanel.v1.ProxyService/GetServerRouting":    {Resource: ResourceProxy, Action: ActionRead, ObjectIDField: "server_id"},
 	"/discopanel.v1.ProxyService/UpdateServerRouting": {Resource: ResourceProxy, Action: ActionUpdate, ObjectIDField: "server_id"},
+	"/discopanel.v1.ProxyService/MoveServerListener":  {Resource: ResourceProxy, Action: ActionUpdate, ObjectIDField: "server_id"},
+	"/discopanel.v1.ProxyService/ExportProxyConfig":   {Resource: ResourceProxy, Action: ActionRead},
+	"/discopanel.v1.ProxyService/GetProxyConnections": {Resource: ResourceProxy, Action: ActionRead},
 
 	// ── TaskService ────────────────────────────────────────────────────
 	"/discopanel.v1.TaskService/ListTasks":            {Resource: ResourceTasks, Action: ActionRead, ObjectIDField: "server_id"},
@@ -149,6 +257,8 @@ var ProcedurePermissions = map[string]ProcedurePermission{
 	"/discopanel.v1.TaskService/GetTaskExecution":     {Resource: ResourceTasks, Action: ActionRead, ObjectIDField: "id"},
 	"/discopanel.v1.TaskService/CancelExecution":      {Resource: ResourceTasks, Action: ActionUpdate, ObjectIDField: "id"},
 	"/discopanel.v1.TaskService/GetSchedulerStatus":   {Resource: ResourceTasks, Action: ActionRead},
+	"/discopanel.v1.TaskService/EstimateBackupSize":   {Resource: ResourceTasks, Action: ActionRead, ObjectIDField: "server_id"},
+	"/discopanel.v1.TaskService/PregenerateChunks":    {Resource: ResourceTasks, Action: ActionCreate, ObjectIDField: "server_id"},
 
 	// ── UserService ────────────────────────────────────────────────────
 	"/discopanel.v1.UserService/ListUsers":  {Resource: ResourceUsers, Action: ActionRead},
@@ -174,6 +284,8 @@ var ProcedurePermissions = map[string]ProcedurePermission{
 	"/discopanel.v1.SupportService/DownloadSupportBundle": {Resource: ResourceSupport, Action: ActionRead},
 	"/discopanel.v1.SupportService/UploadSupportBundle":   {Resource: ResourceSupport, Action: ActionCreate},
 	"/discopanel.v1.SupportService/GetApplicationLogs":    {Resource: ResourceSupport, Action: ActionRead},
+	"/discopanel.v1.SupportService/PruneImages":           {Resource: ResourceSettings, Action: ActionUpdate},
+	"/discopanel.v1.SupportService/GetDiagnostics":        {Resource: ResourceSettings, Action: ActionRead},
 
 	// ── UploadService ──────────────────────────────────────────────────
 	"/discopanel.v1.UploadService/GetUploadStatus": {Resource: ResourceUploads, Action: ActionRead},
@@ -19,7 +19,8 @@ type Permission struct {
 
 // Enforcer wraps a Casbin enforcer with convenience methods for RBAC.
 type Enforcer struct {
-	enforcer *casbin.Enforcer
+	enforcer  *casbin.Enforcer
+	aclLookup ACLLookup
 }
 
 // NewEnforcer creates a new Casbin RBAC enforcer backed by the given GORM database.
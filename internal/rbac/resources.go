@@ -1,5 +1,12 @@
 package rbac
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
 // Resource constants
 const (
 	ResourceServers         = "servers"
@@ -16,6 +23,7 @@ const (
 	ResourceSettings        = "settings"
 	ResourceSupport         = "support"
 	ResourceUploads         = "uploads"
+	ResourceAudit           = "audit"
 )
 
 // Action constants
@@ -48,7 +56,7 @@ var AllResources = []string{
 	ResourceModpacks, ResourceModules, ResourceModuleTemplates,
 	ResourceFiles, ResourceTasks, ResourceProxy,
 	ResourceUsers, ResourceRoles, ResourceSettings,
-	ResourceSupport, ResourceUploads,
+	ResourceSupport, ResourceUploads, ResourceAudit,
 }
 
 // ResourceScopeSource maps each scopeable resource to the resource that
@@ -95,3 +103,39 @@ func ResourceActionsFromProcedures() []ResourceActionEntry {
 	}
 	return entries
 }
+
+// ScopeAllows reports whether a token scope list permits an action on a
+// resource. Scopes take the form "resource:action", "resource:*" or "*".
+// An empty list places no restriction beyond the owning user's roles.
+func ScopeAllows(scopes []string, resource, action string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if scope == "*" || scope == resource+":*" || scope == resource+":"+action {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidScope is returned for malformed or unknown token scopes.
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ValidateScope checks that a scope names a known resource and action.
+func ValidateScope(scope string) error {
+	if scope == "*" {
+		return nil
+	}
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok {
+		return fmt.Errorf("%w %q: expected resource:action", ErrInvalidScope, scope)
+	}
+	if !slices.Contains(AllResources, resource) {
+		return fmt.Errorf("%w %q: unknown resource %q", ErrInvalidScope, scope, resource)
+	}
+	if action != "*" && !slices.Contains(AllActions, action) {
+		return fmt.Errorf("%w %q: unknown action %q", ErrInvalidScope, scope, action)
+	}
+	return nil
+}
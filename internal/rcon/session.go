@@ -0,0 +1,123 @@
+package rcon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jltobler/go-rcon"
+)
+
+// Session holds a persistent authenticated RCON connection and
+// transparently redials when the server drops it (e.g. on restart)
+type Session struct {
+	addr     string
+	password string
+
+	mu   sync.Mutex
+	conn *rcon.Conn
+}
+
+// NewSession creates a session for the given host and port, the connection is opened lazily
+func NewSession(RCONHost string, RCONPort int, RCONPassword string) *Session {
+	return &Session{
+		addr:     fmt.Sprintf("rcon://%s:%d", RCONHost, RCONPort),
+		password: RCONPassword,
+	}
+}
+
+// Connect dials and authenticates if there is no live connection
+func (s *Session) Connect(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connectLocked(ctx)
+}
+
+func (s *Session) connectLocked(ctx context.Context) error {
+	if s.conn != nil && !s.conn.IsClosed() {
+		return nil
+	}
+	s.conn = nil
+
+	type dialResult struct {
+		conn *rcon.Conn
+		err  error
+	}
+	resultCh := make(chan dialResult, 1)
+	go func() {
+		conn, err := rcon.Dial(s.addr, s.password)
+		resultCh <- dialResult{conn: conn, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// Close the connection if the dial completes after we gave up
+		go func() {
+			if r := <-resultCh; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return ctx.Err()
+	case r := <-resultCh:
+		if r.err != nil {
+			return r.err
+		}
+		s.conn = r.conn
+		return nil
+	}
+}
+
+// Connected reports whether the session currently holds a live connection
+func (s *Session) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn != nil && !s.conn.IsClosed()
+}
+
+// Send runs a command, reconnecting once if the existing connection has gone stale
+func (s *Session) Send(ctx context.Context, command string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := s.connectLocked(ctx); err != nil {
+			return "", err
+		}
+
+		conn := s.conn
+		resultCh := make(chan rconResult, 1)
+		go func() {
+			output, err := conn.SendCommand(command)
+			resultCh <- rconResult{output: output, err: err}
+		}()
+
+		select {
+		case <-ctx.Done():
+			// Drop the connection so the pending read does not poison the next command
+			conn.Close()
+			s.conn = nil
+			return "", ctx.Err()
+		case result := <-resultCh:
+			if result.err == nil {
+				return result.output, nil
+			}
+			lastErr = result.err
+			conn.Close()
+			s.conn = nil
+		}
+	}
+	return "", lastErr
+}
+
+// Close closes the underlying connection if open
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Serves next under basePath, so DiscoPanel can sit behind a reverse proxy at a subpath.
+// The bare base path redirects to its trailing slash form and anything outside it is 404.
+func withBasePath(next http.Handler, basePath string) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == basePath:
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, basePath+"/"):
+			stripped.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
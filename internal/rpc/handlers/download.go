@@ -47,6 +47,10 @@ func NewDownloadStreamHandler(downloadManager *download.Manager, authManager *au
 		}
 
 		// Check RBAC permission (files:read)
+		if !user.Allows(rbac.ResourceFiles, rbac.ActionRead) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
 		if enforcer != nil {
 			allowed, rbacErr := enforcer.Enforce(user.Roles, rbac.ResourceFiles, rbac.ActionRead, "*")
 			if rbacErr != nil || !allowed {
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nickheyer/discopanel/internal/docker"
+)
+
+type healthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks"`
+}
+
+// NewHealthHandler returns an http.HandlerFunc reporting panel readiness.
+//
+//	GET /api/v1/health
+//	200 {"status":"ok", ...} or 503 {"status":"degraded", ...}
+func NewHealthHandler(dockerClient *docker.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := healthResponse{
+			Status: "ok",
+			Checks: map[string]healthCheck{},
+		}
+
+		network := healthCheck{Status: "ok"}
+		if err := dockerClient.NetworkError(); err != nil {
+			network = healthCheck{Status: "degraded", Error: err.Error()}
+			resp.Status = "degraded"
+		}
+		resp.Checks["docker_network"] = network
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if resp.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
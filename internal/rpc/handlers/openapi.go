@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"io/fs"
 	"net/http"
 	"strings"
@@ -13,13 +14,16 @@ import (
 )
 
 // NewOpenAPIHandler returns an http.HandlerFunc that serves the OpenAPI spec.
-// Strips Connect protocol noise and injects per-operation security overrides.
-// When isAuthEnabled returns false, security schemes are removed entirely.
+// Strips Connect protocol noise, adds the plain HTTP endpoints and injects
+// per-operation security overrides. When isAuthEnabled returns false, security
+// schemes are removed entirely. Paths ending in .json get the spec as JSON.
 func NewOpenAPIHandler(log *logger.Logger, isAuthEnabled func() bool) http.HandlerFunc {
 	var (
-		once         sync.Once
-		authEnabled  []byte
-		authDisabled []byte
+		once             sync.Once
+		authEnabled      []byte
+		authDisabled     []byte
+		authEnabledJSON  []byte
+		authDisabledJSON []byte
 	)
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -94,6 +98,8 @@ func NewOpenAPIHandler(log *logger.Logger, isAuthEnabled func() bool) http.Handl
 				}
 			}
 
+			addHTTPEndpoints(doc)
+
 			enabled, err := yaml.Marshal(doc)
 			if err != nil {
 				log.Error("Failed to marshal auth-enabled OpenAPI spec: %v", err)
@@ -101,6 +107,9 @@ func NewOpenAPIHandler(log *logger.Logger, isAuthEnabled func() bool) http.Handl
 			} else {
 				authEnabled = enabled
 			}
+			if authEnabledJSON, err = json.Marshal(doc); err != nil {
+				log.Error("Failed to marshal auth-enabled OpenAPI spec as JSON: %v", err)
+			}
 
 			// Build auth-disabled variant: strip all security fields
 			delete(doc, "security")
@@ -128,12 +137,21 @@ func NewOpenAPIHandler(log *logger.Logger, isAuthEnabled func() bool) http.Handl
 			} else {
 				authDisabled = stripped
 			}
+			if authDisabledJSON, err = json.Marshal(doc); err != nil {
+				log.Error("Failed to marshal stripped OpenAPI spec as JSON: %v", err)
+			}
 		})
 
+		asJSON := strings.HasSuffix(r.URL.Path, ".json")
 		var spec []byte
-		if isAuthEnabled() {
+		switch {
+		case isAuthEnabled() && asJSON:
+			spec = authEnabledJSON
+		case isAuthEnabled():
 			spec = authEnabled
-		} else {
+		case asJSON:
+			spec = authDisabledJSON
+		default:
 			spec = authDisabled
 		}
 
@@ -142,8 +160,143 @@ func NewOpenAPIHandler(log *logger.Logger, isAuthEnabled func() bool) http.Handl
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/yaml")
+		if asJSON {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "application/yaml")
+		}
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Write(spec)
 	}
 }
+
+// Adds the endpoints served by plain HTTP handlers, which the proto generated spec can't see
+func addHTTPEndpoints(doc map[string]any) {
+	paths, ok := doc["paths"].(map[string]any)
+	if !ok {
+		paths = map[string]any{}
+		doc["paths"] = paths
+	}
+	components, ok := doc["components"].(map[string]any)
+	if !ok {
+		components = map[string]any{}
+		doc["components"] = components
+	}
+	schemas, ok := components["schemas"].(map[string]any)
+	if !ok {
+		schemas = map[string]any{}
+		components["schemas"] = schemas
+	}
+
+	schemas["HealthCheck"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"status": map[string]any{"type": "string", "enum": []any{"ok", "degraded"}},
+			"error":  map[string]any{"type": "string"},
+		},
+	}
+	schemas["HealthResponse"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"status": map[string]any{"type": "string", "enum": []any{"ok", "degraded"}},
+			"checks": map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"$ref": "#/components/schemas/HealthCheck"},
+			},
+		},
+	}
+	schemas["UploadStreamResponse"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"session_id":     map[string]any{"type": "string"},
+			"bytes_received": map[string]any{"type": "integer", "format": "int64"},
+			"completed":      map[string]any{"type": "boolean"},
+			"temp_path":      map[string]any{"type": "string"},
+		},
+	}
+
+	sessionParam := map[string]any{
+		"name":     "sessionId",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]any{"type": "string"},
+	}
+	jsonBody := func(description, schema string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": map[string]any{"$ref": "#/components/schemas/" + schema},
+				},
+			},
+		}
+	}
+
+	paths["/api/v1/health"] = map[string]any{
+		"get": map[string]any{
+			"tags":        []any{"http"},
+			"summary":     "Panel readiness",
+			"description": "Readiness for load balancers and container healthchecks.",
+			"operationId": "health",
+			"security":    []any{},
+			"responses": map[string]any{
+				"200": jsonBody("All checks pass", "HealthResponse"),
+				"503": jsonBody("A check is degraded", "HealthResponse"),
+			},
+		},
+	}
+	paths["/api/v1/upload/{sessionId}"] = map[string]any{
+		"put": map[string]any{
+			"tags":        []any{"http"},
+			"summary":     "Stream bytes into an upload session",
+			"description": "Sessions are created with UploadService/InitUpload. Resume by sending the rest of the file from X-Upload-Offset.",
+			"operationId": "uploadStream",
+			"parameters": []any{
+				sessionParam,
+				map[string]any{
+					"name":   "X-Upload-Offset",
+					"in":     "header",
+					"schema": map[string]any{"type": "integer", "format": "int64", "default": 0},
+				},
+			},
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/octet-stream": map[string]any{
+						"schema": map[string]any{"type": "string", "format": "binary"},
+					},
+				},
+			},
+			"responses": map[string]any{
+				"200": jsonBody("Bytes received so far", "UploadStreamResponse"),
+			},
+		},
+	}
+	paths["/api/v1/download/{sessionId}"] = map[string]any{
+		"get": map[string]any{
+			"tags":        []any{"http"},
+			"summary":     "Download the file of a download session",
+			"description": "Sessions are created by RPCs like FileService/DownloadArchive. Supports Range requests. The token may be passed as ?token= instead of the Authorization header.",
+			"operationId": "downloadStream",
+			"parameters": []any{
+				sessionParam,
+				map[string]any{
+					"name":   "token",
+					"in":     "query",
+					"schema": map[string]any{"type": "string"},
+				},
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "File bytes",
+					"content": map[string]any{
+						"application/octet-stream": map[string]any{
+							"schema": map[string]any{"type": "string", "format": "binary"},
+						},
+					},
+				},
+				"206": map[string]any{"description": "Requested byte range"},
+			},
+		},
+	}
+}
@@ -50,6 +50,10 @@ func NewUploadStreamHandler(uploadManager *upload.Manager, authManager *auth.Man
 		}
 
 		// Check RBAC permission (uploads:create)
+		if !user.Allows(rbac.ResourceUploads, rbac.ActionCreate) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
 		if enforcer != nil {
 			allowed, rbacErr := enforcer.Enforce(user.Roles, rbac.ResourceUploads, rbac.ActionCreate, "*")
 			if rbacErr != nil || !allowed {
@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/nickheyer/discopanel/internal/auth"
+)
+
+// Replaces RemoteAddr with the client address from X-Forwarded-For or X-Real-IP, but
+// only when the request came straight from a trusted proxy. Everything downstream
+// (sessions, login throttling, audit logs, OIDC) then sees the real client, and
+// cookies are marked secure when the proxy says the client connected over HTTPS.
+func trustProxyHeaders(next http.Handler, trusted []netip.Prefix) http.Handler {
+	if len(trusted) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer, ok := parseIP(r.RemoteAddr); ok && isTrusted(peer, trusted) {
+			if strings.EqualFold(forwardedProto(r), "https") {
+				r = r.WithContext(auth.WithForwardedHTTPS(r.Context()))
+			}
+		}
+		if ip, ok := forwardedClientIP(r, trusted); ok {
+			r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Scheme the client used to reach the proxy nearest to us
+func forwardedProto(r *http.Request) string {
+	values := r.Header.Values("X-Forwarded-Proto")
+	if len(values) == 0 {
+		return ""
+	}
+	protos := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(protos[len(protos)-1])
+}
+
+// Walks X-Forwarded-For from the right, skipping our own proxies, so a client can't
+// spoof its address by sending the header itself. X-Real-IP is the fallback.
+func forwardedClientIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok || !isTrusted(peer, trusted) {
+		return netip.Addr{}, false
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) > 0 {
+		var client netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseIP(hops[i])
+			if !ok {
+				break
+			}
+			client = addr
+			if !isTrusted(addr, trusted) {
+				break
+			}
+		}
+		return client, client.IsValid()
+	}
+
+	return parseIP(r.Header.Get("X-Real-IP"))
+}
+
+// Parses an address with or without a port
+func parseIP(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
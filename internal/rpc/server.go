@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"slices"
+	"strings"
 	"time"
 
 	"connectrpc.com/connect"
 	"connectrpc.com/grpcreflect"
+	"github.com/nickheyer/discopanel/internal/audit"
 	"github.com/nickheyer/discopanel/internal/auth"
 	"github.com/nickheyer/discopanel/internal/command"
 	"github.com/nickheyer/discopanel/internal/config"
@@ -25,6 +27,7 @@ import (
 	"github.com/nickheyer/discopanel/internal/ws"
 	"github.com/nickheyer/discopanel/pkg/download"
 	"github.com/nickheyer/discopanel/pkg/logger"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
 	"github.com/nickheyer/discopanel/pkg/proto/discopanel/v1/discopanelv1connect"
 	"github.com/nickheyer/discopanel/pkg/upload"
 	web "github.com/nickheyer/discopanel/web/discopanel"
@@ -54,6 +57,8 @@ type Server struct {
 	uploadManager    *upload.Manager
 	downloadManager  *download.Manager
 	wsHub            *ws.Hub
+	audit            *audit.Recorder
+	serverService    *services.ServerService
 }
 
 // Creates new Connect RPC server
@@ -67,6 +72,7 @@ func NewServer(store *storage.Store, docker *docker.Client, sender *command.Send
 		if err := enforcer.SeedDefaultPolicies(cfg.Auth.AnonymousAccess); err != nil {
 			log.Error("Failed to seed default policies: %v", err)
 		}
+		enforcer.SetACLLookup(store.GetUserServerACLs)
 	}
 
 	// Initialize auth manager
@@ -76,15 +82,20 @@ func NewServer(store *storage.Store, docker *docker.Client, sender *command.Send
 	}
 
 	// Initialize OIDC handler
-	oidcHandler, err := auth.NewOIDCHandler(authManager, store, &cfg.Auth.OIDC, log)
+	oidcHandler, err := auth.NewOIDCHandler(authManager, store, &cfg.Auth.OIDC, cfg.Server.BasePath, log)
 	if err != nil {
 		log.Warn("Failed to initialize OIDC handler: %v", err)
-		oidcHandler, _ = auth.NewOIDCHandler(authManager, store, &config.OIDCConfig{}, log)
+		oidcHandler, _ = auth.NewOIDCHandler(authManager, store, &config.OIDCConfig{}, cfg.Server.BasePath, log)
 	}
 
+	// Initialize audit recorder
+	auditRecorder := audit.NewRecorder(store, log)
+	oidcHandler.SetAuditRecorder(auditRecorder)
+
 	// Initialize log streamer
 	logStreamer := logger.NewLogStreamer(docker.GetDockerClient(), log, 10000)
 	docker.SetLogStreamer(logStreamer)
+	logStreamer.OnLine(docker.Startup().Observe)
 
 	// Initialize upload manager
 	uploadTTL := time.Duration(cfg.Upload.SessionTTL) * time.Minute
@@ -115,6 +126,7 @@ func NewServer(store *storage.Store, docker *docker.Client, sender *command.Send
 		uploadManager:    uploadManager,
 		downloadManager:  downloadManager,
 		wsHub:            wsHub,
+		audit:            auditRecorder,
 	}
 
 	s.setupHandler()
@@ -129,6 +141,7 @@ func (s *Server) setupHandler() {
 	interceptors := []connect.Interceptor{
 		s.loggingInterceptor(),
 		s.authInterceptor(),
+		s.auditInterceptor(),
 	}
 
 	opts := []connect.HandlerOption{
@@ -144,9 +157,11 @@ func (s *Server) setupHandler() {
 
 	// Add reflection for gRPC clients
 	reflector := grpcreflect.NewStaticReflector(
+		discopanelv1connect.AuditServiceName,
 		discopanelv1connect.AuthServiceName,
 		discopanelv1connect.ConfigServiceName,
 		discopanelv1connect.FileServiceName,
+		discopanelv1connect.HealthServiceName,
 		discopanelv1connect.MinecraftServiceName,
 		discopanelv1connect.ModServiceName,
 		discopanelv1connect.ModpackServiceName,
@@ -159,11 +174,13 @@ func (s *Server) setupHandler() {
 		discopanelv1connect.UploadServiceName,
 		discopanelv1connect.UserServiceName,
 	)
-	mux.Handle(grpcreflect.NewHandlerV1(reflector))
-	mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector))
+	reflectOpts := connect.WithInterceptors(s.reflectionAuthInterceptor())
+	mux.Handle(grpcreflect.NewHandlerV1(reflector, reflectOpts))
+	mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector, reflectOpts))
 
 	// Register WebSocket handler
 	mux.Handle("/ws", s.wsHub)
+	mux.HandleFunc("GET /api/v1/servers/{id}/rcon/ws", s.wsHub.ServeConsole)
 
 	// Register OIDC HTTP handlers
 	if s.oidcHandler != nil && s.oidcHandler.IsEnabled() {
@@ -177,33 +194,47 @@ func (s *Server) setupHandler() {
 	// Streaming file download endpoint
 	mux.Handle("/api/v1/download/", handlers.NewDownloadStreamHandler(s.downloadManager, s.authManager, s.enforcer, s.log))
 
-	// Serve dynamic OpenAPI spec
-	mux.HandleFunc("/api/v1/openapi.yaml", handlers.NewOpenAPIHandler(s.log, s.authManager.IsAnyAuthEnabled))
+	// Readiness for load balancers and container healthchecks
+	mux.HandleFunc("/api/v1/health", handlers.NewHealthHandler(s.docker))
+
+	// Serve dynamic OpenAPI spec, as YAML or JSON
+	openAPIHandler := handlers.NewOpenAPIHandler(s.log, s.authManager.IsAnyAuthEnabled)
+	mux.HandleFunc("/api/v1/openapi.yaml", openAPIHandler)
+	mux.HandleFunc("/api/v1/openapi.json", openAPIHandler)
+
+	// Interactive API reference lives in the frontend
+	mux.Handle("GET /api/v1/docs", http.RedirectHandler(s.config.Server.BasePath+"/docs/api", http.StatusFound))
 
 	// Serve frontend for non-RPC routes
 	s.setupFrontend(mux)
 
+	// Validated at config load
+	trusted, _ := config.ParseTrustedProxies(s.config.Server.TrustedProxies)
+
 	// h2c HTTP/2 cleartext
-	s.handler = h2c.NewHandler(mux, &http2.Server{})
+	s.handler = h2c.NewHandler(trustProxyHeaders(withBasePath(mux, s.config.Server.BasePath), trusted), &http2.Server{})
 }
 
 // Registers all Connect RPC service handlers
 func (s *Server) registerServices(mux *http.ServeMux, opts []connect.HandlerOption) {
 	// Create service instances
 	authService := services.NewAuthService(s.store, s.authManager, s.enforcer, s.oidcHandler, s.log)
-	configService := services.NewConfigService(s.store, s.config, s.docker, s.log)
+	configService := services.NewConfigService(s.store, s.config, s.docker, s.uploadManager, s.downloadManager, s.log)
 	fileService := services.NewFileService(s.store, s.docker, s.uploadManager, s.downloadManager, s.log)
 	minecraftService := services.NewMinecraftService(s.store, s.docker, s.log)
 	modService := services.NewModService(s.store, s.docker, s.uploadManager, s.log)
 	modpackService := services.NewModpackService(s.store, s.config, s.uploadManager, s.log)
 	proxyService := services.NewProxyService(s.store, s.docker, s.proxyManager, s.config, s.logStreamer, s.log)
-	serverService := services.NewServerService(s.store, s.docker, s.sender, s.config, s.proxyManager, s.logStreamer, s.metricsCollector, s.moduleManager, s.bus, s.log)
-	supportService := services.NewSupportService(s.store, s.docker, s.config, s.log)
+	serverService := services.NewServerService(s.store, s.docker, s.sender, s.config, s.proxyManager, s.logStreamer, s.metricsCollector, s.moduleManager, s.scheduler, s.bus, s.enforcer, s.log)
+	s.serverService = serverService
+	supportService := services.NewSupportService(s.store, s.docker, s.proxyManager, s.config, s.log)
 	taskService := services.NewTaskService(s.store, s.scheduler, s.log)
 	userService := services.NewUserService(s.store, s.authManager, s.log)
 	roleService := services.NewRoleService(s.store, s.enforcer, s.log)
 	moduleService := services.NewModuleService(s.store, s.docker, s.moduleManager, s.proxyManager, s.authManager, s.config, s.logStreamer, s.log)
 	uploadService := services.NewUploadService(s.uploadManager, s.config, s.log)
+	auditService := services.NewAuditService(s.store, s.log)
+	healthService := services.NewHealthService(s.docker, s.log)
 
 	// Register service handlers
 	authPath, authHandler := discopanelv1connect.NewAuthServiceHandler(authService, opts...)
@@ -247,6 +278,12 @@ func (s *Server) registerServices(mux *http.ServeMux, opts []connect.HandlerOpti
 
 	uploadPath, uploadHandler := discopanelv1connect.NewUploadServiceHandler(uploadService, opts...)
 	mux.Handle(uploadPath, uploadHandler)
+
+	auditPath, auditHandler := discopanelv1connect.NewAuditServiceHandler(auditService, opts...)
+	mux.Handle(auditPath, auditHandler)
+
+	healthPath, healthHandler := discopanelv1connect.NewHealthServiceHandler(healthService, opts...)
+	mux.Handle(healthPath, healthHandler)
 }
 
 // The HTTP handler for the server
@@ -272,6 +309,10 @@ func (s *Server) authInterceptor() connect.UnaryInterceptorFunc {
 	return func(next connect.UnaryFunc) connect.UnaryFunc {
 		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 			procedure := req.Spec().Procedure
+			ctx = auth.WithClientInfo(ctx, auth.ClientInfo{
+				IP:        audit.ClientIP(req.Peer().Addr),
+				UserAgent: req.Header().Get("User-Agent"),
+			})
 
 			// Public procedures - no auth required
 			if rbac.PublicProcedures[procedure] {
@@ -288,19 +329,37 @@ func (s *Server) authInterceptor() connect.UnaryInterceptorFunc {
 			// Set user in context
 			ctx = auth.WithUser(ctx, user)
 
+			// Panel tokens only read their own server
+			if user.PanelFor != "" {
+				field, ok := rbac.PanelProcedures[procedure]
+				if !ok || (field != "" && extractObjectID(req, field) != user.PanelFor) {
+					return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("panel token does not allow %s", procedure))
+				}
+				return next(ctx, req)
+			}
+
 			// Authenticated-only procedures (no specific resource permission needed)
 			if rbac.AuthenticatedOnlyProcedures[procedure] {
+				if len(user.Scopes) > 0 && rbac.CredentialProcedures[procedure] {
+					return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("scoped api tokens can't call %s", procedure))
+				}
 				return next(ctx, req)
 			}
 
 			// Check resource permission
 			if perm, ok := rbac.ProcedurePermissions[procedure]; ok {
+				if !user.Allows(perm.Resource, perm.Action) {
+					return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("api token scope does not allow %s/%s", perm.Resource, perm.Action))
+				}
 				if s.enforcer != nil {
 					objectID := "*"
 					if perm.ObjectIDField != "" {
 						objectID = extractObjectID(req, perm.ObjectIDField)
 					}
-					allowed, err := s.enforcer.Enforce(user.Roles, perm.Resource, perm.Action, objectID)
+					allowed, err := s.enforcer.EnforceUser(ctx, user.ID, user.Roles, perm.Resource, perm.Action, objectID)
+					if err == nil && !allowed && objectID == "*" && rbac.ServerCollectionProcedures[procedure] {
+						allowed, err = s.enforcer.EnforceAnyServer(ctx, user.ID, user.Roles, perm.Action)
+					}
 					if err != nil {
 						s.log.Error("RBAC enforcement error: %v", err)
 						return nil, connect.NewError(connect.CodeInternal, err)
@@ -316,6 +375,117 @@ func (s *Server) authInterceptor() connect.UnaryInterceptorFunc {
 	}
 }
 
+// auditedProcedures lists non-mapped procedures that are recorded in the audit log
+// in addition to every procedure with a mutating RBAC action.
+var auditedProcedures = []string{
+	"/discopanel.v1.AuthService/Login",
+	"/discopanel.v1.AuthService/Logout",
+	"/discopanel.v1.AuthService/Register",
+	"/discopanel.v1.AuthService/ChangePassword",
+	"/discopanel.v1.AuthService/CreateAPIToken",
+	"/discopanel.v1.AuthService/DeleteAPIToken",
+	"/discopanel.v1.AuthService/UseRecoveryKey",
+	"/discopanel.v1.AuthService/RevokeSession",
+	"/discopanel.v1.AuthService/RevokeOtherSessions",
+	"/discopanel.v1.AuthService/FinishPasskeyRegistration",
+	"/discopanel.v1.AuthService/DeletePasskey",
+	"/discopanel.v1.AuthService/FinishPasskeyLogin",
+	"/discopanel.v1.AuthService/ConfirmTOTPEnrollment",
+	"/discopanel.v1.AuthService/DisableTOTP",
+	"/discopanel.v1.AuthService/RegenerateTOTPRecoveryCodes",
+}
+
+// Reflection streams, so the unary auth interceptor never sees it. Any signed in
+// user may describe the services, the calls themselves are still checked.
+type reflectionAuthInterceptor struct {
+	authManager *auth.Manager
+}
+
+func (s *Server) reflectionAuthInterceptor() connect.Interceptor {
+	return &reflectionAuthInterceptor{authManager: s.authManager}
+}
+
+func (i *reflectionAuthInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return next
+}
+
+func (i *reflectionAuthInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *reflectionAuthInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if _, err := i.authManager.AuthenticateFromHeader(ctx, conn.RequestHeader().Get("Authorization")); err != nil {
+			return connect.NewError(connect.CodeUnauthenticated, err)
+		}
+		return next(ctx, conn)
+	}
+}
+
+// Creates a Connect interceptor that records mutating calls in the audit log
+func (s *Server) auditInterceptor() connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			procedure := req.Spec().Procedure
+			perm, mapped := rbac.ProcedurePermissions[procedure]
+			if !slices.Contains(auditedProcedures, procedure) && (!mapped || perm.Action == rbac.ActionRead) {
+				return next(ctx, req)
+			}
+
+			entry := &storage.AuditLog{
+				Action: strings.TrimPrefix(procedure, "/discopanel.v1."),
+				IP:     audit.ClientIP(req.Peer().Addr),
+			}
+			if mapped {
+				entry.TargetType = perm.Resource
+				if perm.ObjectIDField != "" {
+					if id := extractObjectID(req, perm.ObjectIDField); id != "*" {
+						entry.TargetID = id
+					}
+				}
+			}
+
+			// Resolve the owner up front, the call may delete the server
+			if entry.TargetID != "" && rbac.ResourceScopeSource[entry.TargetType] == rbac.ResourceServers {
+				if server, err := s.store.GetServer(ctx, entry.TargetID); err == nil {
+					entry.OwnerID = server.OwnerID
+				}
+			}
+
+			resp, err := next(ctx, req)
+
+			entry.Success = err == nil
+			if user := auth.GetUserFromContext(ctx); user != nil {
+				entry.UserID = user.ID
+				entry.Username = user.Username
+			} else if signedIn := signedInUser(resp, err); signedIn != nil {
+				// Passkey sign-in carries no username, the response says who logged in
+				entry.UserID = signedIn.Id
+				entry.Username = signedIn.Username
+			} else if username := extractObjectID(req, "username"); username != "*" {
+				entry.Username = username
+			}
+			if err != nil {
+				entry.Detail = audit.Detail(map[string]any{"error": err.Error()})
+			}
+			s.audit.Record(entry)
+
+			return resp, err
+		}
+	}
+}
+
+// Returns the user a successful login response was issued for
+func signedInUser(resp connect.AnyResponse, err error) *v1.User {
+	if err != nil || resp == nil {
+		return nil
+	}
+	if login, ok := resp.Any().(interface{ GetUser() *v1.User }); ok {
+		return login.GetUser()
+	}
+	return nil
+}
+
 // pollingProcedures lists endpoints that are called frequently and should be excluded from logging.
 var pollingProcedures = []string{
 	"/discopanel.v1.AuthService/GetAuthStatus",
@@ -327,6 +497,7 @@ var pollingProcedures = []string{
 	"/discopanel.v1.UploadService/UploadChunk",
 	"/discopanel.v1.UploadService/GetUploadStatus",
 	"/discopanel.v1.FileService/GetExtractionStatus",
+	"/discopanel.v1.HealthService/Check",
 }
 
 // Checks if a procedure is a polling endpoint or high-frequency endpoint
@@ -429,11 +600,24 @@ func extractObjectID(req connect.AnyRequest, fieldName string) string {
 	return "*"
 }
 
+// Flushes pending audit entries
+func (s *Server) Close() {
+	s.oidcHandler.Close()
+	s.audit.Close()
+}
+
 // RecoveryKey returns the current recovery key from the auth manager.
 func (s *Server) RecoveryKey() string {
 	return s.authManager.GetRecoveryKey()
 }
 
+// Restarts a server like the API does, counting down through its shutdown warnings
+// first when players are online
+func (s *Server) RestartServer(ctx context.Context, serverID string) error {
+	_, err := s.serverService.RestartServer(ctx, connect.NewRequest(&v1.RestartServerRequest{Id: serverID}))
+	return err
+}
+
 // Starts log streaming for a container
 func (s *Server) StartLogStreaming(containerID string) error {
 	return s.logStreamer.StartStreaming(containerID)
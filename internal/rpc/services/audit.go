@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"connectrpc.com/connect"
+	"github.com/nickheyer/discopanel/internal/auth"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/logger"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"github.com/nickheyer/discopanel/pkg/proto/discopanel/v1/discopanelv1connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var _ discopanelv1connect.AuditServiceHandler = (*AuditService)(nil)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+type AuditService struct {
+	store *storage.Store
+	log   *logger.Logger
+}
+
+func NewAuditService(store *storage.Store, log *logger.Logger) *AuditService {
+	return &AuditService{
+		store: store,
+		log:   log,
+	}
+}
+
+func (s *AuditService) ListAuditLogs(ctx context.Context, req *connect.Request[v1.ListAuditLogsRequest]) (*connect.Response[v1.ListAuditLogsResponse], error) {
+	msg := req.Msg
+
+	page, pageSize := auditPage(msg.Page, msg.PageSize)
+	filter := storage.AuditLogFilter{
+		UserID:  msg.UserId,
+		OwnerID: msg.OwnerId,
+		Action:  msg.Action,
+		Limit:   pageSize,
+		Offset:  (page - 1) * pageSize,
+	}
+	if msg.From != nil {
+		from := msg.From.AsTime()
+		filter.From = &from
+	}
+	if msg.To != nil {
+		to := msg.To.AsTime()
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("'to' must not be before 'from'"))
+	}
+
+	entries, total, err := s.store.ListAuditLogs(ctx, filter)
+	if err != nil {
+		s.log.Error("Failed to list audit logs: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to list audit logs"))
+	}
+
+	return connect.NewResponse(&v1.ListAuditLogsResponse{
+		Entries:  dbAuditLogsToProto(entries),
+		Total:    total,
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	}), nil
+}
+
+// Lists what other users, e.g. admins, did to the servers the caller owns
+func (s *AuditService) ListServerActivity(ctx context.Context, req *connect.Request[v1.ListServerActivityRequest]) (*connect.Response[v1.ListServerActivityResponse], error) {
+	msg := req.Msg
+
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	page, pageSize := auditPage(msg.Page, msg.PageSize)
+	entries, total, err := s.store.ListAuditLogs(ctx, storage.AuditLogFilter{
+		OwnerID:       user.ID,
+		ExcludeUserID: user.ID,
+		TargetID:      msg.ServerId,
+		Limit:         pageSize,
+		Offset:        (page - 1) * pageSize,
+	})
+	if err != nil {
+		s.log.Error("Failed to list server activity: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to list server activity"))
+	}
+
+	return connect.NewResponse(&v1.ListServerActivityResponse{
+		Entries:  dbAuditLogsToProto(entries),
+		Total:    total,
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	}), nil
+}
+
+// Clamps a 1-based page and its size
+func auditPage(page, pageSize int32) (int, int) {
+	p, size := int(page), int(pageSize)
+	if p < 1 {
+		p = 1
+	}
+	if size <= 0 {
+		size = defaultAuditPageSize
+	}
+	if size > maxAuditPageSize {
+		size = maxAuditPageSize
+	}
+	return p, size
+}
+
+func dbAuditLogsToProto(entries []*storage.AuditLog) []*v1.AuditLogEntry {
+	protoEntries := make([]*v1.AuditLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		protoEntries = append(protoEntries, &v1.AuditLogEntry{
+			Id:         entry.ID,
+			Timestamp:  timestamppb.New(entry.CreatedAt),
+			UserId:     entry.UserID,
+			Username:   entry.Username,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetId:   entry.TargetID,
+			Ip:         entry.IP,
+			Success:    entry.Success,
+			Detail:     entry.Detail,
+			OwnerId:    entry.OwnerID,
+			OnBehalf:   entry.OwnerID != "" && entry.UserID != entry.OwnerID,
+		})
+	}
+	return protoEntries
+}
@@ -5,6 +5,9 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/nickheyer/discopanel/pkg/logger"
 	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
 	"github.com/nickheyer/discopanel/pkg/proto/discopanel/v1/discopanelv1connect"
+	"github.com/nickheyer/discopanel/pkg/webauthn"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -55,6 +59,7 @@ func (s *AuthService) GetAuthStatus(ctx context.Context, req *connect.Request[v1
 		AllowRegistration:      s.authManager.IsRegistrationAllowed(),
 		FirstUserSetup:         userCount == 0,
 		AnonymousAccessEnabled: s.authManager.IsAnonymousAccessEnabled(),
+		PasskeyEnabled:         s.authManager.IsPasskeyEnabled(),
 	}), nil
 }
 
@@ -65,8 +70,14 @@ func (s *AuthService) Login(ctx context.Context, req *connect.Request[v1.LoginRe
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("username and password are required"))
 	}
 
-	user, roles, token, expiresAt, err := s.authManager.Login(ctx, msg.Username, msg.Password)
+	user, roles, token, expiresAt, err := s.authManager.Login(ctx, msg.Username, msg.Password, msg.TotpCode)
 	if err != nil {
+		if throttled := throttleError(err); throttled != nil {
+			return nil, throttled
+		}
+		if errors.Is(err, auth.ErrTOTPRequired) || errors.Is(err, auth.ErrInvalidTOTP) {
+			return nil, connect.NewError(connect.CodeUnauthenticated, err)
+		}
 		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrUserNotActive) {
 			return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid credentials"))
 		}
@@ -92,15 +103,20 @@ func (s *AuthService) Logout(ctx context.Context, req *connect.Request[v1.Logout
 		token, _ = strings.CutPrefix(token, "bearer ")
 	}
 
+	resp := &v1.LogoutResponse{
+		Message: "logged out",
+	}
 	if token != "" {
-		if err := s.authManager.Logout(ctx, token); err != nil {
+		session, err := s.authManager.Logout(ctx, token)
+		if err != nil {
 			s.log.Debug("Logout error: %v", err)
 		}
+		if session != nil && s.oidcHandler != nil {
+			resp.LogoutUrl = s.oidcHandler.EndSessionURL(session.IDToken)
+		}
 	}
 
-	return connect.NewResponse(&v1.LogoutResponse{
-		Message: "logged out",
-	}), nil
+	return connect.NewResponse(resp), nil
 }
 
 func (s *AuthService) Register(ctx context.Context, req *connect.Request[v1.RegisterRequest]) (*connect.Response[v1.RegisterResponse], error) {
@@ -273,7 +289,7 @@ func (s *AuthService) GetOIDCLoginURL(ctx context.Context, req *connect.Request[
 	}
 
 	return connect.NewResponse(&v1.GetOIDCLoginURLResponse{
-		LoginUrl: "/api/v1/auth/oidc/login",
+		LoginUrl: s.oidcHandler.LoginURL(),
 	}), nil
 }
 
@@ -307,6 +323,79 @@ func (s *AuthService) GetAuthConfig(ctx context.Context, req *connect.Request[v1
 	return connect.NewResponse(resp), nil
 }
 
+func (s *AuthService) TestOIDCConfig(ctx context.Context, req *connect.Request[v1.TestOIDCConfigRequest]) (*connect.Response[v1.TestOIDCConfigResponse], error) {
+	if s.oidcHandler == nil || !s.oidcHandler.IsEnabled() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("OIDC is not enabled"))
+	}
+
+	resp := &v1.TestOIDCConfigResponse{Ok: true}
+	for _, check := range s.oidcHandler.CheckConfig(ctx) {
+		level := v1.OIDCCheckLevel_OIDC_CHECK_LEVEL_OK
+		switch check.Level {
+		case auth.OIDCCheckWarning:
+			level = v1.OIDCCheckLevel_OIDC_CHECK_LEVEL_WARNING
+		case auth.OIDCCheckError:
+			level = v1.OIDCCheckLevel_OIDC_CHECK_LEVEL_ERROR
+			resp.Ok = false
+		}
+		resp.Checks = append(resp.Checks, &v1.OIDCCheck{
+			Name:    check.Name,
+			Level:   level,
+			Message: check.Message,
+		})
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (s *AuthService) BeginOIDCLink(ctx context.Context, req *connect.Request[v1.BeginOIDCLinkRequest]) (*connect.Response[v1.BeginOIDCLinkResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+	if s.oidcHandler == nil || !s.oidcHandler.IsEnabled() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("OIDC is not enabled"))
+	}
+
+	linkURL, linkCookie, err := s.oidcHandler.BeginLink(ctx, user.ID)
+	if err != nil {
+		return nil, s.oidcLinkError(err, "failed to start linking")
+	}
+
+	// The link rides on this authenticated call's cookie, the login URL carries nothing
+	resp := connect.NewResponse(&v1.BeginOIDCLinkResponse{
+		LinkUrl: linkURL,
+	})
+	resp.Header().Add("Set-Cookie", linkCookie)
+	return resp, nil
+}
+
+func (s *AuthService) UnlinkOIDCIdentity(ctx context.Context, req *connect.Request[v1.UnlinkOIDCIdentityRequest]) (*connect.Response[v1.UnlinkOIDCIdentityResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+	if s.oidcHandler == nil {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("OIDC is not enabled"))
+	}
+
+	// Admins can unlink any account, users confirm with their password
+	userID := user.ID
+	if req.Msg.UserId != nil && *req.Msg.UserId != user.ID {
+		if err := s.requireUsersAction(user, rbac.ActionUpdate); err != nil {
+			return nil, err
+		}
+		userID = *req.Msg.UserId
+	} else if err := s.authManager.VerifyPassword(ctx, user.ID, req.Msg.Password); err != nil {
+		return nil, connect.NewError(connect.CodePermissionDenied, errors.New("incorrect password"))
+	}
+
+	if err := s.oidcHandler.UnlinkIdentity(ctx, userID); err != nil {
+		return nil, s.oidcLinkError(err, "failed to unlink OIDC identity")
+	}
+
+	return connect.NewResponse(&v1.UnlinkOIDCIdentityResponse{}), nil
+}
+
 func (s *AuthService) UpdateAuthSettings(ctx context.Context, req *connect.Request[v1.UpdateAuthSettingsRequest]) (*connect.Response[v1.UpdateAuthSettingsResponse], error) {
 	msg := req.Msg
 
@@ -473,8 +562,11 @@ func (s *AuthService) CreateAPIToken(ctx context.Context, req *connect.Request[v
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("token name is required"))
 	}
 
-	plaintext, apiToken, err := s.authManager.GenerateAPIToken(ctx, user.ID, msg.Name, msg.ExpiresInDays)
+	plaintext, apiToken, err := s.authManager.GenerateAPIToken(ctx, user.ID, msg.Name, msg.ExpiresInDays, msg.Scopes)
 	if err != nil {
+		if errors.Is(err, rbac.ErrInvalidScope) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
 		s.log.Error("Failed to create API token: %v", err)
 		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to create API token"))
 	}
@@ -531,6 +623,9 @@ func (s *AuthService) UseRecoveryKey(ctx context.Context, req *connect.Request[v
 	}
 
 	if err := s.authManager.UseRecoveryKey(ctx, req.Msg.RecoveryKey); err != nil {
+		if throttled := throttleError(err); throttled != nil {
+			return nil, throttled
+		}
 		if errors.Is(err, auth.ErrInvalidRecoveryKey) {
 			return nil, connect.NewError(connect.CodePermissionDenied, errors.New("invalid recovery key"))
 		}
@@ -543,11 +638,428 @@ func (s *AuthService) UseRecoveryKey(ctx context.Context, req *connect.Request[v
 	}), nil
 }
 
+func (s *AuthService) ListSessions(ctx context.Context, req *connect.Request[v1.ListSessionsRequest]) (*connect.Response[v1.ListSessionsResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	userID := user.ID
+	if req.Msg.UserId != nil && *req.Msg.UserId != user.ID {
+		if err := s.requireUsersAction(user, rbac.ActionRead); err != nil {
+			return nil, err
+		}
+		userID = *req.Msg.UserId
+	}
+
+	sessions, err := s.store.ListUserSessions(ctx, userID)
+	if err != nil {
+		s.log.Error("Failed to list sessions: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to list sessions"))
+	}
+
+	protoSessions := make([]*v1.Session, 0, len(sessions))
+	for _, session := range sessions {
+		protoSessions = append(protoSessions, dbSessionToProto(session, user.SessionID))
+	}
+
+	return connect.NewResponse(&v1.ListSessionsResponse{
+		Sessions: protoSessions,
+	}), nil
+}
+
+func (s *AuthService) RevokeSession(ctx context.Context, req *connect.Request[v1.RevokeSessionRequest]) (*connect.Response[v1.RevokeSessionResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	if req.Msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("session ID is required"))
+	}
+
+	session, err := s.store.GetSessionByID(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("session not found"))
+	}
+	if session.UserID != user.ID {
+		if err := s.requireUsersAction(user, rbac.ActionUpdate); err != nil {
+			// Hide other users' sessions from non-admins
+			return nil, connect.NewError(connect.CodeNotFound, errors.New("session not found"))
+		}
+	}
+
+	if err := s.store.DeleteSessionByID(ctx, session.ID); err != nil {
+		s.log.Error("Failed to revoke session: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to revoke session"))
+	}
+
+	return connect.NewResponse(&v1.RevokeSessionResponse{}), nil
+}
+
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, req *connect.Request[v1.RevokeOtherSessionsRequest]) (*connect.Response[v1.RevokeOtherSessionsResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	userID, keepID := user.ID, user.SessionID
+	if req.Msg.UserId != nil && *req.Msg.UserId != user.ID {
+		if err := s.requireUsersAction(user, rbac.ActionUpdate); err != nil {
+			return nil, err
+		}
+		userID, keepID = *req.Msg.UserId, ""
+	}
+
+	revoked, err := s.store.DeleteUserSessionsExcept(ctx, userID, keepID)
+	if err != nil {
+		s.log.Error("Failed to revoke sessions: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to revoke sessions"))
+	}
+
+	return connect.NewResponse(&v1.RevokeOtherSessionsResponse{
+		Revoked: int32(revoked),
+	}), nil
+}
+
+// Passkey registration options, bound to the origin the UI is served from
+func (s *AuthService) BeginPasskeyRegistration(ctx context.Context, req *connect.Request[v1.BeginPasskeyRegistrationRequest]) (*connect.Response[v1.BeginPasskeyRegistrationResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	opts, err := s.authManager.BeginPasskeyRegistration(ctx, user.ID, req.Header().Get("Origin"))
+	if err != nil {
+		return nil, s.passkeyError(err, "failed to start passkey registration")
+	}
+
+	algorithms := make([]int32, 0, len(webauthn.SupportedAlgorithms))
+	for _, alg := range webauthn.SupportedAlgorithms {
+		algorithms = append(algorithms, int32(alg))
+	}
+
+	return connect.NewResponse(&v1.BeginPasskeyRegistrationResponse{
+		ChallengeId:          opts.CeremonyID,
+		Challenge:            opts.Challenge,
+		RpId:                 opts.RPID,
+		RpName:               opts.RPName,
+		UserId:               opts.UserHandle,
+		UserName:             user.Username,
+		ExcludeCredentialIds: opts.CredentialIDs,
+		UserVerification:     opts.UserVerification,
+		TimeoutMs:            int32(opts.Timeout.Milliseconds()),
+		Algorithms:           algorithms,
+	}), nil
+}
+
+func (s *AuthService) FinishPasskeyRegistration(ctx context.Context, req *connect.Request[v1.FinishPasskeyRegistrationRequest]) (*connect.Response[v1.FinishPasskeyRegistrationResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	msg := req.Msg
+	if msg.ChallengeId == "" || len(msg.ClientDataJson) == 0 || len(msg.AttestationObject) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("challenge ID, client data and attestation object are required"))
+	}
+
+	passkey, err := s.authManager.FinishPasskeyRegistration(ctx, user.ID, msg.ChallengeId, msg.Name, msg.ClientDataJson, msg.AttestationObject)
+	if err != nil {
+		return nil, s.passkeyError(err, "failed to register passkey")
+	}
+
+	return connect.NewResponse(&v1.FinishPasskeyRegistrationResponse{
+		Passkey: dbPasskeyToProto(passkey),
+	}), nil
+}
+
+func (s *AuthService) ListPasskeys(ctx context.Context, req *connect.Request[v1.ListPasskeysRequest]) (*connect.Response[v1.ListPasskeysResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	passkeys, err := s.store.ListPasskeysByUser(ctx, user.ID)
+	if err != nil {
+		s.log.Error("Failed to list passkeys: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to list passkeys"))
+	}
+
+	protoPasskeys := make([]*v1.Passkey, 0, len(passkeys))
+	for i := range passkeys {
+		protoPasskeys = append(protoPasskeys, dbPasskeyToProto(&passkeys[i]))
+	}
+
+	return connect.NewResponse(&v1.ListPasskeysResponse{
+		Passkeys: protoPasskeys,
+	}), nil
+}
+
+func (s *AuthService) DeletePasskey(ctx context.Context, req *connect.Request[v1.DeletePasskeyRequest]) (*connect.Response[v1.DeletePasskeyResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	if req.Msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("passkey ID is required"))
+	}
+
+	if err := s.store.DeletePasskey(ctx, req.Msg.Id, user.ID); err != nil {
+		s.log.Error("Failed to delete passkey: %v", err)
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("passkey not found"))
+	}
+
+	return connect.NewResponse(&v1.DeletePasskeyResponse{}), nil
+}
+
+// Passkey sign-in options, username narrows the browser's choice to that account's passkeys
+func (s *AuthService) BeginPasskeyLogin(ctx context.Context, req *connect.Request[v1.BeginPasskeyLoginRequest]) (*connect.Response[v1.BeginPasskeyLoginResponse], error) {
+	opts, err := s.authManager.BeginPasskeyLogin(ctx, strings.TrimSpace(req.Msg.Username), req.Header().Get("Origin"))
+	if err != nil {
+		return nil, s.passkeyError(err, "failed to start passkey sign-in")
+	}
+
+	return connect.NewResponse(&v1.BeginPasskeyLoginResponse{
+		ChallengeId:        opts.CeremonyID,
+		Challenge:          opts.Challenge,
+		RpId:               opts.RPID,
+		AllowCredentialIds: opts.CredentialIDs,
+		UserVerification:   opts.UserVerification,
+		TimeoutMs:          int32(opts.Timeout.Milliseconds()),
+	}), nil
+}
+
+func (s *AuthService) FinishPasskeyLogin(ctx context.Context, req *connect.Request[v1.FinishPasskeyLoginRequest]) (*connect.Response[v1.FinishPasskeyLoginResponse], error) {
+	msg := req.Msg
+	if msg.ChallengeId == "" || len(msg.CredentialId) == 0 || len(msg.ClientDataJson) == 0 || len(msg.AuthenticatorData) == 0 || len(msg.Signature) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("challenge ID, credential ID, client data, authenticator data and signature are required"))
+	}
+
+	user, roles, token, expiresAt, err := s.authManager.FinishPasskeyLogin(ctx, msg.ChallengeId, msg.CredentialId, msg.ClientDataJson, msg.AuthenticatorData, msg.Signature)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrUserNotActive) {
+			s.log.Debug("Passkey login rejected: %v", err)
+			return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid credentials"))
+		}
+		return nil, s.passkeyError(err, "login failed")
+	}
+
+	return connect.NewResponse(&v1.FinishPasskeyLoginResponse{
+		Token:     token,
+		User:      dbUserToProto(user, roles),
+		ExpiresAt: timestamppb.New(expiresAt),
+	}), nil
+}
+
+func (s *AuthService) GetTOTPStatus(ctx context.Context, req *connect.Request[v1.GetTOTPStatusRequest]) (*connect.Response[v1.GetTOTPStatusResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	dbUser, err := s.store.GetUser(ctx, user.ID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("user not found"))
+	}
+
+	enabled, remaining := s.authManager.TOTPStatus(ctx, user.ID)
+	return connect.NewResponse(&v1.GetTOTPStatusResponse{
+		Available:              s.authManager.IsTOTPEnabled() && dbUser.AuthProvider == "local",
+		Enabled:                enabled,
+		RecoveryCodesRemaining: int32(remaining),
+	}), nil
+}
+
+// Starts two-factor enrollment, the secret is only shown here
+func (s *AuthService) BeginTOTPEnrollment(ctx context.Context, req *connect.Request[v1.BeginTOTPEnrollmentRequest]) (*connect.Response[v1.BeginTOTPEnrollmentResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	dbUser, err := s.store.GetUser(ctx, user.ID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("user not found"))
+	}
+
+	enrollment, err := s.authManager.BeginTOTPEnrollment(ctx, dbUser)
+	if err != nil {
+		return nil, s.totpError(err, "failed to start two-factor enrollment")
+	}
+
+	return connect.NewResponse(&v1.BeginTOTPEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OtpauthUrl: enrollment.URI,
+		QrCodePng:  enrollment.QRCode,
+	}), nil
+}
+
+func (s *AuthService) ConfirmTOTPEnrollment(ctx context.Context, req *connect.Request[v1.ConfirmTOTPEnrollmentRequest]) (*connect.Response[v1.ConfirmTOTPEnrollmentResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	if req.Msg.Code == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("code is required"))
+	}
+
+	codes, err := s.authManager.ConfirmTOTPEnrollment(ctx, user.ID, req.Msg.Code)
+	if err != nil {
+		return nil, s.totpError(err, "failed to enable two-factor authentication")
+	}
+
+	return connect.NewResponse(&v1.ConfirmTOTPEnrollmentResponse{
+		RecoveryCodes: codes,
+	}), nil
+}
+
+func (s *AuthService) DisableTOTP(ctx context.Context, req *connect.Request[v1.DisableTOTPRequest]) (*connect.Response[v1.DisableTOTPResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	// Admins can reset a user who lost their authenticator, users confirm with their password
+	userID := user.ID
+	if req.Msg.UserId != nil && *req.Msg.UserId != user.ID {
+		if err := s.requireUsersAction(user, rbac.ActionUpdate); err != nil {
+			return nil, err
+		}
+		userID = *req.Msg.UserId
+	} else if err := s.authManager.VerifyPassword(ctx, user.ID, req.Msg.Password); err != nil {
+		return nil, connect.NewError(connect.CodePermissionDenied, errors.New("incorrect password"))
+	}
+
+	if err := s.authManager.DisableTOTP(ctx, userID); err != nil {
+		s.log.Error("Failed to disable two-factor authentication: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to disable two-factor authentication"))
+	}
+
+	return connect.NewResponse(&v1.DisableTOTPResponse{}), nil
+}
+
+func (s *AuthService) RegenerateTOTPRecoveryCodes(ctx context.Context, req *connect.Request[v1.RegenerateTOTPRecoveryCodesRequest]) (*connect.Response[v1.RegenerateTOTPRecoveryCodesResponse], error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authenticated"))
+	}
+
+	if req.Msg.Code == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("code is required"))
+	}
+
+	codes, err := s.authManager.RegenerateTOTPRecoveryCodes(ctx, user.ID, req.Msg.Code)
+	if err != nil {
+		return nil, s.totpError(err, "failed to regenerate recovery codes")
+	}
+
+	return connect.NewResponse(&v1.RegenerateTOTPRecoveryCodesResponse{
+		RecoveryCodes: codes,
+	}), nil
+}
+
+// Maps two-factor management failures onto connect codes. Bad codes are not
+// Unauthenticated here, that would sign the user out of the UI.
+func (s *AuthService) totpError(err error, msg string) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTOTP):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, auth.ErrTOTPDisabled), errors.Is(err, auth.ErrTOTPLocalOnly),
+		errors.Is(err, auth.ErrTOTPNotEnrolled), errors.Is(err, auth.ErrTOTPAlreadyEnabled):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+	s.log.Error("Two-factor request failed: %v", err)
+	return connect.NewError(connect.CodeInternal, errors.New(msg))
+}
+
+// Maps account linking failures onto connect codes
+func (s *AuthService) oidcLinkError(err error, msg string) error {
+	switch {
+	case errors.Is(err, auth.ErrOIDCLinkLocalOnly), errors.Is(err, auth.ErrOIDCAlreadyLinked),
+		errors.Is(err, auth.ErrOIDCNotLinked), errors.Is(err, auth.ErrOIDCOnlyLogin),
+		errors.Is(err, auth.ErrOIDCLinkTOTP):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+	s.log.Error("OIDC link request failed: %v", err)
+	return connect.NewError(connect.CodeInternal, errors.New(msg))
+}
+
+// Maps passkey ceremony failures onto connect codes
+func (s *AuthService) passkeyError(err error, msg string) error {
+	if throttled := throttleError(err); throttled != nil {
+		return throttled
+	}
+	switch {
+	case errors.Is(err, auth.ErrPasskeysDisabled):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	case errors.Is(err, auth.ErrPasskeyCeremony):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	case errors.Is(err, webauthn.ErrChallengeMismatch), errors.Is(err, webauthn.ErrOriginMismatch),
+		errors.Is(err, webauthn.ErrRPIDMismatch), errors.Is(err, webauthn.ErrUserNotPresent),
+		errors.Is(err, webauthn.ErrUserNotVerified), errors.Is(err, webauthn.ErrInvalidSignature):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	s.log.Error("Passkey request failed: %v", err)
+	return connect.NewError(connect.CodeInternal, errors.New(msg))
+}
+
+// Checks that the caller may act on other users' accounts
+func (s *AuthService) requireUsersAction(user *auth.AuthenticatedUser, action string) error {
+	if !user.Allows(rbac.ResourceUsers, action) {
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("api token scope does not allow %s/%s", rbac.ResourceUsers, action))
+	}
+	// Fail closed without an enforcer
+	if s.enforcer == nil {
+		return connect.NewError(connect.CodePermissionDenied, errors.New("permission denied"))
+	}
+	allowed, err := s.enforcer.Enforce(user.Roles, rbac.ResourceUsers, action, "*")
+	if err != nil {
+		s.log.Error("Failed to check permissions: %v", err)
+		return connect.NewError(connect.CodeInternal, errors.New("failed to check permissions"))
+	}
+	if !allowed {
+		return connect.NewError(connect.CodePermissionDenied, errors.New("permission denied"))
+	}
+	return nil
+}
+
+// Converts a lockout into a resource exhausted error carrying a Retry-After header
+func throttleError(err error) *connect.Error {
+	var throttled *auth.ThrottleError
+	if !errors.As(err, &throttled) {
+		return nil
+	}
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	cerr := connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("too_many_attempts: retry in %d seconds", retryAfter))
+	cerr.Meta().Set("Retry-After", strconv.Itoa(retryAfter))
+	return cerr
+}
+
+func dbSessionToProto(session *storage.Session, currentID string) *v1.Session {
+	ps := &v1.Session{
+		Id:        session.ID,
+		UserId:    session.UserID,
+		IpAddress: session.IPAddress,
+		UserAgent: session.UserAgent,
+		CreatedAt: timestamppb.New(session.CreatedAt),
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+		Current:   currentID != "" && session.ID == currentID,
+	}
+	if session.User != nil {
+		ps.Username = session.User.Username
+	}
+	return ps
+}
+
 func dbAPITokenToProto(t *storage.APIToken) *v1.ApiToken {
 	pt := &v1.ApiToken{
 		Id:        t.ID,
 		Name:      t.Name,
 		CreatedAt: timestamppb.New(t.CreatedAt),
+		Scopes:    t.Scopes,
 	}
 	if t.ExpiresAt != nil {
 		pt.ExpiresAt = timestamppb.New(*t.ExpiresAt)
@@ -558,6 +1070,19 @@ func dbAPITokenToProto(t *storage.APIToken) *v1.ApiToken {
 	return pt
 }
 
+func dbPasskeyToProto(p *storage.Passkey) *v1.Passkey {
+	pp := &v1.Passkey{
+		Id:        p.ID,
+		Name:      p.Name,
+		Aaguid:    p.AAGUID,
+		CreatedAt: timestamppb.New(p.CreatedAt),
+	}
+	if p.LastUsedAt != nil {
+		pp.LastUsedAt = timestamppb.New(*p.LastUsedAt)
+	}
+	return pp
+}
+
 func dbInviteToProto(invite *storage.RegistrationInvite) *v1.RegistrationInvite {
 	pi := &v1.RegistrationInvite{
 		Id:          invite.ID,
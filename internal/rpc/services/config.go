@@ -4,36 +4,56 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/auth"
 	"github.com/nickheyer/discopanel/internal/config"
 	storage "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/fleet"
+	"github.com/nickheyer/discopanel/internal/minecraft"
+	"github.com/nickheyer/discopanel/pkg/download"
+	"github.com/nickheyer/discopanel/pkg/files"
 	"github.com/nickheyer/discopanel/pkg/logger"
 	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
 	"github.com/nickheyer/discopanel/pkg/proto/discopanel/v1/discopanelv1connect"
+	"github.com/nickheyer/discopanel/pkg/upload"
 	"gorm.io/gorm"
 )
 
 var _ discopanelv1connect.ConfigServiceHandler = (*ConfigService)(nil)
 
+// Directory under the server data path holding uploaded custom server jars
+const customJarDir = "custom-server"
+
 type ConfigService struct {
-	store  *storage.Store
-	config *config.Config
-	docker *docker.Client
-	log    *logger.Logger
+	store           *storage.Store
+	config          *config.Config
+	docker          *docker.Client
+	uploadManager   *upload.Manager
+	downloadManager *download.Manager
+	log             *logger.Logger
 }
 
 // Creates new config service
-func NewConfigService(store *storage.Store, cfg *config.Config, docker *docker.Client, log *logger.Logger) *ConfigService {
+func NewConfigService(store *storage.Store, cfg *config.Config, docker *docker.Client, uploadManager *upload.Manager, downloadManager *download.Manager, log *logger.Logger) *ConfigService {
 	return &ConfigService{
-		store:  store,
-		config: cfg,
-		docker: docker,
-		log:    log,
+		store:           store,
+		config:          cfg,
+		docker:          docker,
+		uploadManager:   uploadManager,
+		downloadManager: downloadManager,
+		log:             log,
 	}
 }
 
@@ -96,7 +116,10 @@ func (s *ConfigService) UpdateServerConfig(ctx context.Context, req *connect.Req
 	// Apply updates w/ reflection
 	if err := applyConfigUpdates(config, msg.Updates); err != nil {
 		s.log.Error("Failed to apply config updates: %v", err)
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("failed to apply configuration updates"))
+		return nil, configUpdateError(err)
+	}
+	if err := validateServerConfig(config, slices.Collect(maps.Keys(msg.Updates))); err != nil {
+		return nil, configUpdateError(err)
 	}
 
 	// Save updated config
@@ -124,6 +147,255 @@ func (s *ConfigService) UpdateServerConfig(ctx context.Context, req *connect.Req
 	}), nil
 }
 
+// Compares server config field by field with global settings and struct defaults
+func (s *ConfigService) GetServerConfigDiff(ctx context.Context, req *connect.Request[v1.GetServerConfigDiffRequest]) (*connect.Response[v1.GetServerConfigDiffResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+
+	if err := s.store.SyncServerConfigWithServer(ctx, server); err != nil {
+		s.log.Error("Failed to sync server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to sync server configuration"))
+	}
+
+	config, err := s.store.GetServerConfig(ctx, msg.ServerId)
+	if err != nil {
+		s.log.Error("Failed to get server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get server configuration"))
+	}
+
+	globalSettings, _, err := s.store.GetGlobalSettings(ctx)
+	if err != nil {
+		s.log.Error("Failed to get global settings: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get global settings"))
+	}
+
+	return connect.NewResponse(&v1.GetServerConfigDiffResponse{
+		Fields: diffServerConfig(config, globalSettings, msg.OverridesOnly),
+	}), nil
+}
+
+// Applies a server.properties file onto server config
+func (s *ConfigService) ImportServerProperties(ctx context.Context, req *connect.Request[v1.ImportServerPropertiesRequest]) (*connect.Response[v1.ImportServerPropertiesResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+
+	props, err := minecraft.ParseServerProperties(strings.NewReader(msg.Content))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid server.properties: %w", err))
+	}
+
+	config, err := s.store.GetServerConfig(ctx, msg.ServerId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			config = s.store.CreateDefaultServerConfig(msg.ServerId)
+		} else {
+			s.log.Error("Failed to get server config: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get server configuration"))
+		}
+	}
+
+	before := *config
+	result := minecraft.ImportServerProperties(config, props)
+	if err := validateServerConfig(config, changedConfigKeys(&before, config)); err != nil {
+		return nil, configUpdateError(err)
+	}
+
+	if err := s.store.SaveServerConfig(ctx, config); err != nil {
+		s.log.Error("Failed to save server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to save server configuration"))
+	}
+
+	if server.ContainerID != "" && s.docker != nil {
+		if err := s.recreateContainer(ctx, server, config); err != nil {
+			s.log.Error("Config saved but container recreation failed: %v", err)
+		}
+	}
+
+	categories, err := buildConfigCategories(config)
+	if err != nil {
+		s.log.Error("Failed to build config categories: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to format configuration"))
+	}
+
+	return connect.NewResponse(&v1.ImportServerPropertiesResponse{
+		Categories:  categories,
+		MappedKeys:  result.Mapped,
+		CustomKeys:  result.Custom,
+		IgnoredKeys: result.Ignored,
+	}), nil
+}
+
+// Renders server config as a server.properties file
+func (s *ConfigService) ExportServerProperties(ctx context.Context, req *connect.Request[v1.ExportServerPropertiesRequest]) (*connect.Response[v1.ExportServerPropertiesResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+
+	if err := s.store.SyncServerConfigWithServer(ctx, server); err != nil {
+		s.log.Error("Failed to sync server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to sync server configuration"))
+	}
+
+	config, err := s.store.GetServerConfig(ctx, msg.ServerId)
+	if err != nil {
+		s.log.Error("Failed to get server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get server configuration"))
+	}
+
+	return connect.NewResponse(&v1.ExportServerPropertiesResponse{
+		Content: minecraft.ExportServerProperties(config),
+	}), nil
+}
+
+// Lists built-in and custom config presets
+func (s *ConfigService) ListConfigPresets(ctx context.Context, req *connect.Request[v1.ListConfigPresetsRequest]) (*connect.Response[v1.ListConfigPresetsResponse], error) {
+	presets, err := s.store.ListConfigPresets(ctx)
+	if err != nil {
+		s.log.Error("Failed to list config presets: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to list config presets"))
+	}
+
+	protoPresets := make([]*v1.ConfigPreset, 0, len(presets))
+	for _, preset := range presets {
+		protoPresets = append(protoPresets, dbConfigPresetToProto(preset))
+	}
+
+	return connect.NewResponse(&v1.ListConfigPresetsResponse{
+		Presets: protoPresets,
+	}), nil
+}
+
+// Saves a custom config preset
+func (s *ConfigService) CreateConfigPreset(ctx context.Context, req *connect.Request[v1.CreateConfigPresetRequest]) (*connect.Response[v1.CreateConfigPresetResponse], error) {
+	msg := req.Msg
+
+	name := strings.TrimSpace(msg.Name)
+	if name == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("preset name is required"))
+	}
+	if len(msg.Values) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("preset has no values"))
+	}
+
+	editable := editableConfigFields()
+	for key := range msg.Values {
+		if !editable[key] {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%s can't be set by a preset", key))
+		}
+	}
+	scratch := &storage.ServerConfig{}
+	if err := applyConfigUpdates(scratch, msg.Values); err != nil {
+		return nil, configUpdateError(err)
+	}
+	if err := validateServerConfig(scratch, slices.Collect(maps.Keys(msg.Values))); err != nil {
+		return nil, configUpdateError(err)
+	}
+
+	preset := &storage.ConfigPreset{
+		Name:        name,
+		Description: msg.Description,
+		Type:        storage.ConfigPresetTypeCustom,
+		Values:      msg.Values,
+	}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		preset.CreatedBy = user.ID
+	}
+
+	if err := s.store.CreateConfigPreset(ctx, preset); err != nil {
+		s.log.Error("Failed to create config preset: %v", err)
+		return nil, connect.NewError(connect.CodeAlreadyExists, errors.New("a preset with that name already exists"))
+	}
+
+	return connect.NewResponse(&v1.CreateConfigPresetResponse{
+		Preset: dbConfigPresetToProto(preset),
+	}), nil
+}
+
+// Deletes a custom config preset
+func (s *ConfigService) DeleteConfigPreset(ctx context.Context, req *connect.Request[v1.DeleteConfigPresetRequest]) (*connect.Response[v1.DeleteConfigPresetResponse], error) {
+	preset, err := s.store.GetConfigPreset(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("preset not found"))
+	}
+
+	// Don't allow deleting built-in presets
+	if preset.Type == storage.ConfigPresetTypeBuiltin {
+		return nil, connect.NewError(connect.CodePermissionDenied, errors.New("cannot delete built-in preset"))
+	}
+
+	if err := s.store.DeleteConfigPreset(ctx, preset.ID); err != nil {
+		s.log.Error("Failed to delete config preset: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to delete preset"))
+	}
+
+	return connect.NewResponse(&v1.DeleteConfigPresetResponse{}), nil
+}
+
+// Merges a preset onto server config
+func (s *ConfigService) ApplyConfigPreset(ctx context.Context, req *connect.Request[v1.ApplyConfigPresetRequest]) (*connect.Response[v1.ApplyConfigPresetResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+
+	preset, err := s.store.GetConfigPreset(ctx, msg.PresetId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("preset not found"))
+	}
+
+	config, err := s.store.GetServerConfig(ctx, msg.ServerId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			config = s.store.CreateDefaultServerConfig(msg.ServerId)
+		} else {
+			s.log.Error("Failed to get server config: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get server configuration"))
+		}
+	}
+
+	if err := applyConfigPreset(config, preset); err != nil {
+		s.log.Error("Failed to apply config preset %s: %v", preset.Name, err)
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("failed to apply preset"))
+	}
+	if err := validateServerConfig(config, slices.Collect(maps.Keys(preset.Values))); err != nil {
+		return nil, configUpdateError(err)
+	}
+
+	if err := s.store.SaveServerConfig(ctx, config); err != nil {
+		s.log.Error("Failed to save server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to save server configuration"))
+	}
+
+	if server.ContainerID != "" && s.docker != nil {
+		if err := s.recreateContainer(ctx, server, config); err != nil {
+			s.log.Error("Config saved but container recreation failed: %v", err)
+		}
+	}
+
+	categories, err := buildConfigCategories(config)
+	if err != nil {
+		s.log.Error("Failed to build config categories: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to format configuration"))
+	}
+
+	return connect.NewResponse(&v1.ApplyConfigPresetResponse{
+		Categories: categories,
+	}), nil
+}
+
 // Gets global settings
 func (s *ConfigService) GetGlobalSettings(ctx context.Context, req *connect.Request[v1.GetGlobalSettingsRequest]) (*connect.Response[v1.GetGlobalSettingsResponse], error) {
 	config, _, err := s.store.GetGlobalSettings(ctx)
@@ -154,7 +426,10 @@ func (s *ConfigService) UpdateGlobalSettings(ctx context.Context, req *connect.R
 
 	if err := applyConfigUpdates(config, msg.Updates); err != nil {
 		s.log.Error("Failed to apply config updates: %v", err)
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("failed to apply configuration updates"))
+		return nil, configUpdateError(err)
+	}
+	if err := validateServerConfig(config, slices.Collect(maps.Keys(msg.Updates))); err != nil {
+		return nil, configUpdateError(err)
 	}
 
 	if err := s.store.UpdateGlobalSettings(ctx, config); err != nil {
@@ -173,7 +448,299 @@ func (s *ConfigService) UpdateGlobalSettings(ctx context.Context, req *connect.R
 	}), nil
 }
 
+// Imports an uploaded jar into the server data dir and points CUSTOM_SERVER at it
+func (s *ConfigService) ImportCustomServerJar(ctx context.Context, req *connect.Request[v1.ImportCustomServerJarRequest]) (*connect.Response[v1.ImportCustomServerJarResponse], error) {
+	msg := req.Msg
+
+	if msg.UploadSessionId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("upload_session_id is required"))
+	}
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
+
+	tempPath, originalFilename, err := s.uploadManager.GetTempPath(msg.UploadSessionId)
+	if err != nil {
+		s.log.Error("Failed to get upload session: %v", err)
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("upload session not found or not completed"))
+	}
+
+	filename := filepath.Base(originalFilename)
+	if !strings.EqualFold(filepath.Ext(filename), ".jar") || strings.ContainsAny(filename, "/\\") {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("custom server must be a .jar file"))
+	}
+
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		s.log.Error("Failed to stat uploaded jar: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to read uploaded file"))
+	}
+	if maxSize := s.config.Upload.MaxJarSize; maxSize > 0 && info.Size() > maxSize {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("jar exceeds maximum size of %d bytes", maxSize))
+	}
+
+	if err := files.ValidateJarFile(tempPath); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	jarDir := filepath.Join(server.DataPath, customJarDir)
+	if err := os.MkdirAll(jarDir, 0755); err != nil {
+		s.log.Error("Failed to create custom jar directory: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to create directory"))
+	}
+
+	jarPath := filepath.Join(jarDir, filename)
+	if err := os.Rename(tempPath, jarPath); err != nil {
+		if err := files.CopyFile(tempPath, jarPath); err != nil {
+			s.log.Error("Failed to move custom jar: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, errors.New("failed to save jar"))
+		}
+		os.Remove(tempPath)
+	}
+	s.uploadManager.CleanupSession(msg.UploadSessionId)
+
+	config, err := s.store.GetServerConfig(ctx, msg.ServerId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			config = s.store.CreateDefaultServerConfig(msg.ServerId)
+		} else {
+			s.log.Error("Failed to get server config: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get server configuration"))
+		}
+	}
+
+	// Server data dir is mounted at /data inside the container
+	containerPath := path.Join("/data", customJarDir, filename)
+	config.CustomServer = &containerPath
+
+	if err := s.store.SaveServerConfig(ctx, config); err != nil {
+		s.log.Error("Failed to save server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to save server configuration"))
+	}
+
+	if server.ContainerID != "" && s.docker != nil {
+		if err := s.recreateContainer(ctx, server, config); err != nil {
+			s.log.Error("Config saved but container recreation failed: %v", err)
+		}
+	}
+
+	categories, err := buildConfigCategories(config)
+	if err != nil {
+		s.log.Error("Failed to build config categories: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to format configuration"))
+	}
+
+	return connect.NewResponse(&v1.ImportCustomServerJarResponse{
+		Path:       containerPath,
+		Categories: categories,
+	}), nil
+}
+
+// Exports every server definition and config, with secrets scrubbed, as a download session
+func (s *ConfigService) ExportFleet(ctx context.Context, req *connect.Request[v1.ExportFleetRequest]) (*connect.Response[v1.ExportFleetResponse], error) {
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to list servers"))
+	}
+
+	entries := make([]*fleet.Entry, 0, len(servers))
+	for _, server := range servers {
+		serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+		if err != nil {
+			s.log.Warn("Exporting server %s without config: %v", server.ID, err)
+			serverConfig = nil
+		}
+		entries = append(entries, &fleet.Entry{Server: server, Config: serverConfig})
+	}
+
+	tempPath := filepath.Join(s.downloadManager.TempDir(), fmt.Sprintf("fleet-%s.zip", time.Now().Format("20060102-150405.000")))
+	out, err := os.Create(tempPath)
+	if err != nil {
+		s.log.Error("Failed to create fleet archive: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to create archive"))
+	}
+	if err := fleet.Write(out, entries); err != nil {
+		out.Close()
+		os.Remove(tempPath)
+		s.log.Error("Failed to write fleet archive: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to write archive"))
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tempPath)
+		s.log.Error("Failed to finalize fleet archive: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to write archive"))
+	}
+
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to read archive"))
+	}
+
+	filename := fmt.Sprintf("discopanel-fleet-%s.zip", time.Now().Format("20060102-150405"))
+	session := s.downloadManager.InitSession(tempPath, filename, info.Size(), true)
+	s.log.Info("Exported fleet snapshot with %d servers", len(entries))
+
+	return connect.NewResponse(&v1.ExportFleetResponse{
+		SessionId:      session.ID,
+		Filename:       filename,
+		TotalSize:      info.Size(),
+		ServerCount:    int32(len(entries)),
+		ScrubbedFields: fleet.SecretFields(),
+	}), nil
+}
+
+// Recreates server definitions from an uploaded fleet archive
+func (s *ConfigService) ImportFleet(ctx context.Context, req *connect.Request[v1.ImportFleetRequest]) (*connect.Response[v1.ImportFleetResponse], error) {
+	msg := req.Msg
+
+	if msg.UploadSessionId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("upload_session_id is required"))
+	}
+
+	tempPath, _, err := s.uploadManager.GetTempPath(msg.UploadSessionId)
+	if err != nil {
+		s.log.Error("Failed to get upload session: %v", err)
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("upload session not found or not completed"))
+	}
+	defer s.uploadManager.CleanupSession(msg.UploadSessionId)
+
+	snapshot, err := fleet.Read(tempPath)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	resp := &v1.ImportFleetResponse{}
+	for _, entry := range snapshot.Entries {
+		id, err := s.importFleetEntry(ctx, entry)
+		if err != nil {
+			s.log.Warn("Skipping fleet server %s: %v", entry.Server.Name, err)
+			resp.Skipped = append(resp.Skipped, &v1.FleetImportSkip{Name: entry.Server.Name, Reason: err.Error()})
+			continue
+		}
+		resp.ImportedServerIds = append(resp.ImportedServerIds, id)
+	}
+
+	s.log.Info("Imported %d servers from fleet snapshot (%d skipped)", len(resp.ImportedServerIds), len(resp.Skipped))
+	return connect.NewResponse(resp), nil
+}
+
+// Creates a single server from a fleet entry, returning the new server ID
+func (s *ConfigService) importFleetEntry(ctx context.Context, entry *fleet.Entry) (string, error) {
+	server := entry.Server
+	if server.Name == "" {
+		return "", errors.New("server has no name")
+	}
+
+	// Keep the original ID when it is free on this host
+	if server.ID == "" {
+		server.ID = uuid.New().String()
+	} else if _, err := s.store.GetServer(ctx, server.ID); err == nil {
+		server.ID = uuid.New().String()
+	}
+
+	if server.ProxyListenerID != "" {
+		listener, err := s.store.GetProxyListener(ctx, server.ProxyListenerID)
+		if err != nil || listener == nil {
+			return "", fmt.Errorf("proxy listener %s does not exist", server.ProxyListenerID)
+		}
+		servers, err := s.store.ListServers(ctx)
+		if err != nil {
+			return "", errors.New("failed to check proxy hostnames")
+		}
+		for _, existing := range servers {
+			if existing.ProxyListenerID == server.ProxyListenerID && strings.EqualFold(existing.ProxyHostname, server.ProxyHostname) {
+				return "", fmt.Errorf("proxy hostname %s is already in use", server.ProxyHostname)
+			}
+		}
+		server.ProxyPort = listener.Port
+	} else if server.Port > 0 {
+		if existing, err := s.store.GetServerByPort(ctx, server.Port); err == nil && existing != nil {
+			return "", fmt.Errorf("port %d is already in use by %s", server.Port, existing.Name)
+		}
+	}
+
+	if err := checkCallerQuota(ctx, s.store, storage.QuotaUsage{Servers: 1, Memory: server.Memory}); err != nil {
+		if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+			return "", errors.New(connectErr.Message())
+		}
+		return "", err
+	}
+	server.OwnerID = ""
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		server.OwnerID = user.ID
+	}
+
+	// The server starts over on this host in a fresh data directory of its own
+	server.ContainerID = ""
+	server.Imported = false
+	server.DataVolume = ""
+	server.Status = storage.StatusStopped
+	server.LastStarted = nil
+	server.CreatedAt = time.Time{}
+	server.UpdatedAt = time.Time{}
+	server.DataPath = serverDataPath(s.config, server.Name, server.ID)
+
+	if err := createDataDir(server.DataPath); err != nil {
+		s.log.Error("Failed to create data directory: %v", err)
+		return "", errors.New("failed to create server directory")
+	}
+
+	if err := s.store.CreateServer(ctx, server); err != nil {
+		s.log.Error("Failed to create server: %v", err)
+		return "", errors.New("failed to create server")
+	}
+
+	if entry.Config == nil {
+		return server.ID, nil
+	}
+
+	// Overlay the exported config, keeping freshly generated secrets
+	defaults, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		defaults = s.store.CreateDefaultServerConfig(server.ID)
+	}
+	serverConfig := entry.Config
+	serverConfig.ID = defaults.ID
+	serverConfig.ServerID = server.ID
+	serverConfig.Server = nil
+	defaultValue := reflect.ValueOf(defaults).Elem()
+	configValue := reflect.ValueOf(serverConfig).Elem()
+	for _, name := range fleet.SecretFields() {
+		if field := configValue.FieldByName(name); field.IsValid() && field.IsZero() {
+			field.Set(defaultValue.FieldByName(name))
+		}
+	}
+
+	// A server without its exported config isn't the one in the snapshot, skip it
+	if err := s.store.SaveServerConfig(ctx, serverConfig); err != nil {
+		s.log.Error("Failed to save imported server config: %v", err)
+		if err := s.store.DeleteServer(ctx, server.ID); err != nil {
+			s.log.Error("Failed to remove server %s after its config failed to save: %v", server.ID, err)
+		}
+		os.RemoveAll(server.DataPath)
+		return "", errors.New("failed to save server config")
+	}
+	if err := s.store.SyncServerConfigWithServer(ctx, server); err != nil {
+		s.log.Warn("Failed to sync imported server config: %v", err)
+	}
+
+	return server.ID, nil
+}
+
 func (s *ConfigService) recreateContainer(ctx context.Context, server *storage.Server, config *storage.ServerConfig) error {
+	// Imported containers keep running as they are, the saved config applies once the server is recreated
+	if server.Imported {
+		s.log.Info("Saved config for imported server %s, recreate it to apply", server.Name)
+		return nil
+	}
+
 	oldContainerID := server.ContainerID
 	wasRunning := false
 	if server.Status == storage.StatusRunning {
@@ -211,11 +778,51 @@ func (s *ConfigService) recreateContainer(ctx context.Context, server *storage.S
 	return nil
 }
 
+func dbConfigPresetToProto(preset *storage.ConfigPreset) *v1.ConfigPreset {
+	return &v1.ConfigPreset{
+		Id:          preset.ID,
+		Name:        preset.Name,
+		Description: preset.Description,
+		Builtin:     preset.Type == storage.ConfigPresetTypeBuiltin,
+		Values:      preset.Values,
+	}
+}
+
+// Config keys a preset may set: every env-backed field that isn't system managed
+func editableConfigFields() map[string]bool {
+	configType := reflect.TypeFor[storage.ServerConfig]()
+	fields := make(map[string]bool, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		env := field.Tag.Get("env")
+		if env == "" || env == "-" || field.Tag.Get("system") == "true" {
+			continue
+		}
+		fields[field.Tag.Get("json")] = true
+	}
+	return fields
+}
+
+// Merges the preset's non-empty values onto config, leaving system fields alone
+func applyConfigPreset(config *storage.ServerConfig, preset *storage.ConfigPreset) error {
+	editable := editableConfigFields()
+	updates := make(map[string]string, len(preset.Values))
+	for key, value := range preset.Values {
+		if value != "" && editable[key] {
+			updates[key] = value
+		}
+	}
+	return applyConfigUpdates(config, updates)
+}
+
 // Maps updates w/ reflection
 func applyConfigUpdates(config any, updates map[string]string) error {
 	configValue := reflect.ValueOf(config).Elem()
 	configType := configValue.Type()
 
+	// Values that don't parse are collected so every bad field is reported at once
+	var errs configFieldErrors
+
 	for key, strValue := range updates {
 		// Find the field by json tag
 		fieldIndex := -1
@@ -261,13 +868,15 @@ func applyConfigUpdates(config any, updates map[string]string) error {
 		case reflect.Bool:
 			b, err := strconv.ParseBool(strValue)
 			if err != nil {
-				return fmt.Errorf("invalid boolean for key %s: %v", key, err)
+				errs.add(key, "must be true or false")
+				continue
 			}
 			val = reflect.ValueOf(b)
 		case reflect.Int, reflect.Int32, reflect.Int64:
 			i, err := strconv.ParseInt(strValue, 10, 64)
 			if err != nil {
-				return fmt.Errorf("invalid integer for key %s: %v", key, err)
+				errs.add(key, "must be a whole number")
+				continue
 			}
 			// Convert to specific int type
 			if targetType.Kind() == reflect.Int {
@@ -280,7 +889,8 @@ func applyConfigUpdates(config any, updates map[string]string) error {
 		case reflect.Float32, reflect.Float64:
 			f, err := strconv.ParseFloat(strValue, 64)
 			if err != nil {
-				return fmt.Errorf("invalid float for key %s: %v", key, err)
+				errs.add(key, "must be a number")
+				continue
 			}
 			if targetType.Kind() == reflect.Float32 {
 				val = reflect.ValueOf(float32(f))
@@ -302,24 +912,92 @@ func applyConfigUpdates(config any, updates map[string]string) error {
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
+// Config categories in display order, indexed by getCategoryIndex
+var configCategoryNames = []string{
+	"JVM Configuration",
+	"Server Settings",
+	"Game Settings",
+	"World Generation",
+	"RCON",
+	"Resource Pack",
+	"Management Server",
+	"Ops/Admins",
+	"Whitelist",
+	"Auto-Pause",
+	"Auto-Stop",
+	"CurseForge",
+	"Modrinth",
+	"Feed The Beast",
+}
+
+// Builds the per-field diff of a server config against global settings and struct defaults
+func diffServerConfig(config, globalSettings *storage.ServerConfig, overridesOnly bool) []*v1.ConfigFieldDiff {
+	configValue := reflect.ValueOf(config).Elem()
+	globalValue := reflect.ValueOf(globalSettings).Elem()
+	configType := configValue.Type()
+
+	var fields []*v1.ConfigFieldDiff
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		jsonTag := field.Tag.Get("json")
+		envTag := field.Tag.Get("env")
+		if envTag == "" || envTag == "-" {
+			continue
+		}
+		categoryIndex := getCategoryIndex(jsonTag)
+		if categoryIndex < 0 || categoryIndex >= len(configCategoryNames) {
+			continue
+		}
+
+		diff := &v1.ConfigFieldDiff{
+			Key:         jsonTag,
+			Label:       field.Tag.Get("label"),
+			Category:    configCategoryNames[categoryIndex],
+			EnvVar:      envTag,
+			Value:       configFieldValue(configValue.Field(i)),
+			GlobalValue: configFieldValue(globalValue.Field(i)),
+		}
+		if diff.Label == "" {
+			diff.Label = jsonTag
+		}
+		if defaultTag := field.Tag.Get("default"); defaultTag != "" {
+			diff.DefaultValue = &defaultTag
+		}
+
+		if diff.Value != nil {
+			diff.OverridesGlobal = diff.GlobalValue == nil || *diff.Value != *diff.GlobalValue
+			diff.OverridesDefault = diff.DefaultValue == nil || *diff.Value != *diff.DefaultValue
+		}
+		if overridesOnly && !diff.OverridesGlobal && !diff.OverridesDefault {
+			continue
+		}
+		fields = append(fields, diff)
+	}
+	return fields
+}
+
+// Stringifies a config field, nil when the pointer is unset
+func configFieldValue(fieldValue reflect.Value) *string {
+	if fieldValue.Kind() == reflect.Pointer {
+		if fieldValue.IsNil() {
+			return nil
+		}
+		fieldValue = fieldValue.Elem()
+	}
+	strValue := fmt.Sprintf("%v", fieldValue.Interface())
+	return &strValue
+}
+
 func buildConfigCategories(config any) ([]*v1.ConfigCategory, error) {
-	categories := []*v1.ConfigCategory{
-		{Name: "JVM Configuration", Properties: []*v1.ConfigProperty{}},
-		{Name: "Server Settings", Properties: []*v1.ConfigProperty{}},
-		{Name: "Game Settings", Properties: []*v1.ConfigProperty{}},
-		{Name: "World Generation", Properties: []*v1.ConfigProperty{}},
-		{Name: "RCON", Properties: []*v1.ConfigProperty{}},
-		{Name: "Resource Pack", Properties: []*v1.ConfigProperty{}},
-		{Name: "Management Server", Properties: []*v1.ConfigProperty{}},
-		{Name: "Ops/Admins", Properties: []*v1.ConfigProperty{}},
-		{Name: "Whitelist", Properties: []*v1.ConfigProperty{}},
-		{Name: "Auto-Pause", Properties: []*v1.ConfigProperty{}},
-		{Name: "Auto-Stop", Properties: []*v1.ConfigProperty{}},
-		{Name: "CurseForge", Properties: []*v1.ConfigProperty{}},
-		{Name: "Modrinth", Properties: []*v1.ConfigProperty{}},
+	categories := make([]*v1.ConfigCategory, 0, len(configCategoryNames))
+	for _, name := range configCategoryNames {
+		categories = append(categories, &v1.ConfigCategory{Name: name, Properties: []*v1.ConfigProperty{}})
 	}
 
 	configValue := reflect.ValueOf(config).Elem()
@@ -434,13 +1112,13 @@ func getCategoryIndex(key string) int {
 	// JVM Configuration (0)
 	case "uid", "gid", "memory", "initMemory", "maxMemory", "tz", "enableRollingLogs",
 		"enableJmx", "jmxHost", "useAikarFlags", "useMeowiceFlags", "useMeowiceGraalvmFlags",
-		"jvmOpts", "jvmXxOpts", "jvmDdOpts", "extraArgs", "logTimestamp":
+		"jvmOpts", "jvmXxOpts", "jvmDdOpts", "extraArgs", "logTimestamp", "heapDumpOnOom":
 		return 0
 
 	// Server Settings (1)
 	case "type", "customServer", "customJarExec", "eula", "version", "motd", "icon", "overrideIcon", "serverName",
 		"serverPort", "console", "gui", "stopDuration", "setupOnly", "execDirectly",
-		"stopServerAnnounceDelay", "proxy", "useFlareFlags", "useSimdFlags",
+		"stopServerAnnounceDelay", "stopWarnings", "stopWarningMessage", "stopWarningTitle", "proxy", "useFlareFlags", "useSimdFlags",
 		"serverPropertiesEscapeUnicode", "bugReportLink", "customServerProperties":
 		return 1
 
@@ -509,6 +1187,10 @@ func getCategoryIndex(key string) int {
 		"versionFromModrinthProjects":
 		return 12
 
+	// Feed The Beast (13)
+	case "ftbModpackId", "ftbModpackVersionId", "ftbForceReinstall":
+		return 13
+
 	default:
 		return -1 // Unknown
 	}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"connectrpc.com/connect"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// Inclusive bounds for numeric config fields, vanilla server.properties ranges where they exist
+var configFieldRanges = map[string][2]int{
+	"maxPlayers":                     {0, math.MaxInt32},
+	"maxWorldSize":                   {1, 29999984},
+	"maxBuildHeight":                 {0, 4064},
+	"spawnProtection":                {0, math.MaxInt32},
+	"viewDistance":                   {2, 32},
+	"simulationDistance":             {2, 32},
+	"serverPort":                     {1, 65535},
+	"queryPort":                      {1, 65535},
+	"rconPort":                       {1, 65535},
+	"managementServerPort":           {0, 65535}, // 0 picks a free port
+	"playerIdleTimeout":              {0, math.MaxInt32},
+	"entityBroadcastRangePercentage": {10, 1000},
+	"functionPermissionLevel":        {1, 4},
+	"opPermissionLevel":              {0, 4},
+	"networkCompressionThreshold":    {-1, math.MaxInt32}, // -1 disables compression
+	"maxChainedNeighborUpdates":      {-1, math.MaxInt32},
+	"rateLimit":                      {0, math.MaxInt32},
+	"stopDuration":                   {0, math.MaxInt32},
+}
+
+// Field-level problems with a config update
+type configFieldErrors []*v1.ConfigFieldError
+
+func (e configFieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, field := range e {
+		msgs = append(msgs, field.Key+": "+field.Message)
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+func (e *configFieldErrors) add(key, format string, args ...any) {
+	*e = append(*e, &v1.ConfigFieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// Checks the given fields of config, nil keys checks every field. Select fields must hold
+// one of their options and numbers must be in range. Server configs, unlike global
+// settings and presets, also need an RCON password while RCON is enabled.
+func validateServerConfig(config *storage.ServerConfig, keys []string) error {
+	configValue := reflect.ValueOf(config).Elem()
+	configType := configValue.Type()
+
+	check := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		check[key] = true
+	}
+
+	var errs configFieldErrors
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		key := field.Tag.Get("json")
+		fieldValue := configValue.Field(i)
+		if keys != nil && !check[key] {
+			continue
+		}
+		if fieldValue.Kind() != reflect.Pointer || fieldValue.IsNil() {
+			continue
+		}
+		value := fieldValue.Elem()
+
+		if field.Tag.Get("input") == "select" && value.Kind() == reflect.String {
+			options := getSelectOptions(key)
+			if len(options) > 0 && !containsFold(options, value.String()) {
+				errs.add(key, "must be one of %s", strings.Join(nonEmpty(options), ", "))
+			}
+		}
+
+		if bounds, ok := configFieldRanges[key]; ok && value.CanInt() {
+			if n := value.Int(); n < int64(bounds[0]) || n > int64(bounds[1]) {
+				if bounds[1] == math.MaxInt32 {
+					errs.add(key, "must be at least %d", bounds[0])
+				} else {
+					errs.add(key, "must be between %d and %d", bounds[0], bounds[1])
+				}
+			}
+		}
+	}
+
+	if config.StopWarnings != nil && (keys == nil || check["stopWarnings"]) {
+		if _, err := parseStopWarnings(*config.StopWarnings); err != nil {
+			errs.add("stopWarnings", "%v", err)
+		}
+	}
+
+	isServer := config.ServerID != "" && config.ID != storage.GlobalSettingsID
+	if isServer && (keys == nil || check["enableRcon"] || check["rconPassword"]) {
+		rconEnabled := config.EnableRCON == nil || *config.EnableRCON // The image enables RCON by default
+		if rconEnabled && (config.RCONPassword == nil || *config.RCONPassword == "") {
+			errs.add("rconPassword", "is required while RCON is enabled")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// JSON keys of fields that differ between two configs
+func changedConfigKeys(before, after *storage.ServerConfig) []string {
+	beforeValue := reflect.ValueOf(before).Elem()
+	afterValue := reflect.ValueOf(after).Elem()
+	afterType := afterValue.Type()
+
+	keys := []string{}
+	for i := 0; i < afterType.NumField(); i++ {
+		key := afterType.Field(i).Tag.Get("json")
+		if key == "" || key == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(beforeValue.Field(i).Interface(), afterValue.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Converts config update failures into InvalidArgument errors carrying the offending fields
+func configUpdateError(err error) error {
+	var fieldErrs configFieldErrors
+	if !errors.As(err, &fieldErrs) {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("failed to apply configuration updates"))
+	}
+
+	cerr := connect.NewError(connect.CodeInvalidArgument, fieldErrs)
+	if detail, detailErr := connect.NewErrorDetail(&v1.ConfigValidationError{Fields: fieldErrs}); detailErr == nil {
+		cerr.AddDetail(detail)
+	}
+	return cerr
+}
+
+func containsFold(options []string, value string) bool {
+	for _, option := range options {
+		if strings.EqualFold(option, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, value := range values {
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
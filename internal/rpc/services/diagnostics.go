@@ -0,0 +1,283 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"connectrpc.com/connect"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Diagnostic checks in the order they run
+const (
+	checkProxyListeners   = "proxy_listeners"
+	checkServerListeners  = "server_listeners"
+	checkProxiedPorts     = "proxied_published_ports"
+	checkAuthDefaults     = "auth_defaults"
+	checkCurseForgeAPIKey = "curseforge_api_key"
+	checkContainerStatus  = "container_status"
+)
+
+// Shorter configured JWT secrets are flagged as guessable
+const minRecommendedJWTSecret = 32
+
+// Collects findings for one diagnostics run
+type diagnostics struct {
+	checks   []string
+	findings []*v1.DiagnosticFinding
+}
+
+func (d *diagnostics) run(check string, fn func()) {
+	d.checks = append(d.checks, check)
+	fn()
+}
+
+func (d *diagnostics) add(check string, severity v1.DiagnosticSeverity, server *storage.Server, fix, format string, args ...any) {
+	finding := &v1.DiagnosticFinding{
+		Check:    check,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Fix:      fix,
+	}
+	if server != nil {
+		finding.ServerId = server.ID
+		finding.ServerName = server.Name
+	}
+	d.findings = append(d.findings, finding)
+}
+
+// GetDiagnostics runs a battery of checks for common misconfigurations and returns what
+// they found, most severe first, each with a suggested fix
+func (s *SupportService) GetDiagnostics(ctx context.Context, req *connect.Request[v1.GetDiagnosticsRequest]) (*connect.Response[v1.GetDiagnosticsResponse], error) {
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list servers"))
+	}
+	listeners, err := s.store.GetProxyListeners(ctx)
+	if err != nil {
+		s.log.Error("Failed to load proxy listeners: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to load proxy listeners"))
+	}
+
+	d := &diagnostics{}
+	d.run(checkProxyListeners, func() { s.diagnoseProxyListeners(d, listeners) })
+	d.run(checkServerListeners, func() { s.diagnoseServerListeners(d, servers, listeners) })
+	d.run(checkProxiedPorts, func() { s.diagnoseProxiedPorts(ctx, d, servers) })
+	d.run(checkAuthDefaults, func() { s.diagnoseAuthDefaults(d) })
+	d.run(checkCurseForgeAPIKey, func() { s.diagnoseCurseForgeAPIKey(ctx, d, servers) })
+	d.run(checkContainerStatus, func() { s.diagnoseContainerStatus(ctx, d, servers) })
+
+	slices.SortStableFunc(d.findings, func(a, b *v1.DiagnosticFinding) int {
+		return cmp.Or(cmp.Compare(a.Severity, b.Severity), cmp.Compare(a.ServerName, b.ServerName))
+	})
+
+	return connect.NewResponse(&v1.GetDiagnosticsResponse{
+		Findings:  d.findings,
+		Checks:    d.checks,
+		CheckedAt: timestamppb.Now(),
+	}), nil
+}
+
+func (s *SupportService) diagnoseProxyListeners(d *diagnostics, listeners []*storage.ProxyListener) {
+	if !s.config.Proxy.Enabled {
+		return
+	}
+	enabled := 0
+	for _, listener := range listeners {
+		if listener.Enabled {
+			enabled++
+		}
+	}
+	switch {
+	case len(listeners) == 0:
+		d.add(checkProxyListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, nil,
+			"Add a listener under Settings > Routing, or save the proxy settings to create the default one",
+			"The proxy is enabled but has no listeners, nothing accepts player connections")
+	case enabled == 0:
+		d.add(checkProxyListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, nil,
+			"Enable at least one listener under Settings > Routing",
+			"The proxy is enabled but all %d of its listeners are disabled", len(listeners))
+	case s.proxyManager != nil && !s.proxyManager.IsRunning():
+		d.add(checkProxyListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, nil,
+			"Check the DiscoPanel log for listener errors, usually a port already in use on the host",
+			"The proxy is enabled but none of its listeners is running")
+	}
+}
+
+func (s *SupportService) diagnoseServerListeners(d *diagnostics, servers []*storage.Server, listeners []*storage.ProxyListener) {
+	byID := make(map[string]*storage.ProxyListener, len(listeners))
+	for _, listener := range listeners {
+		byID[listener.ID] = listener
+	}
+
+	for _, server := range servers {
+		if server.ProxyHostname == "" {
+			continue
+		}
+		listener := byID[server.ProxyListenerID]
+		switch {
+		case !s.config.Proxy.Enabled:
+			d.add(checkServerListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, server,
+				"Enable the proxy under Settings > Routing, or clear the server's proxy hostname to publish its port directly",
+				"Server has proxy hostname %s but the proxy is disabled, and proxied servers don't publish their game port", server.ProxyHostname)
+		case server.ProxyListenerID == "":
+			d.add(checkServerListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, server,
+				"Pick a listener in the server's routing settings",
+				"Server has proxy hostname %s but no listener, no route is registered for it", server.ProxyHostname)
+		case listener == nil:
+			d.add(checkServerListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, server,
+				"Move the server to an existing listener in its routing settings",
+				"Server's proxy listener no longer exists, %s doesn't route anywhere", server.ProxyHostname)
+		case !listener.Enabled:
+			d.add(checkServerListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, server,
+				fmt.Sprintf("Enable listener %s or move the server to an enabled one", listener.Name),
+				"Server routes %s through listener %s (port %d), which is disabled", server.ProxyHostname, listener.Name, listener.Port)
+		case !isJavaListener(listener):
+			d.add(checkServerListeners, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, server,
+				"Move the server to a TCP listener in its routing settings",
+				"Server routes %s through %s listener %s, which doesn't carry Java connections", server.ProxyHostname, listener.Protocol, listener.Name)
+		}
+	}
+}
+
+func (s *SupportService) diagnoseProxiedPorts(ctx context.Context, d *diagnostics, servers []*storage.Server) {
+	for _, server := range servers {
+		if server.ProxyHostname == "" || server.ContainerID == "" || server.Imported {
+			continue
+		}
+		if server.DockerOverrides != nil && server.DockerOverrides.NetworkMode == "host" {
+			continue
+		}
+		bindings, err := s.docker.ContainerPortBindings(ctx, server.ContainerID)
+		if err != nil {
+			continue
+		}
+		if mismatch := docker.PortBindingMismatch(bindings, server); mismatch != "" {
+			d.add(checkProxiedPorts, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, server,
+				"Recreate the server's container so it only publishes its additional ports",
+				"Container publishes ports the proxy should own (%s), players can bypass the proxy", mismatch)
+		}
+	}
+}
+
+func (s *SupportService) diagnoseAuthDefaults(d *diagnostics) {
+	auth := s.config.Auth
+	if !auth.Local.Enabled && !auth.OIDC.Enabled {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, nil,
+			"Set auth.local.enabled or auth.oidc.enabled and create an admin account",
+			"Authentication is disabled, anyone who can reach the panel has full admin access")
+		return
+	}
+
+	if auth.AnonymousAccess {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, nil,
+			"Set auth.anonymous_access to false unless the panel is only reachable from a trusted network",
+			"Anonymous access is on, visitors get the anonymous role without signing in")
+	}
+	if auth.Local.Enabled && auth.Local.AllowRegistration {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, nil,
+			"Set auth.local.allow_registration to false and invite users instead",
+			"Open registration is on, anyone who can reach the panel can create an account")
+	}
+	if !auth.Throttle.Enabled {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, nil,
+			"Set auth.throttle.enabled to true",
+			"Login throttling is off, passwords can be guessed without limit")
+	}
+	if auth.JWTSecret != "" && len(auth.JWTSecret) < minRecommendedJWTSecret {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, nil,
+			fmt.Sprintf("Use a random auth.jwt_secret of at least %d characters, or leave it empty to generate one", minRecommendedJWTSecret),
+			"auth.jwt_secret is only %d characters long, session tokens signed with it can be forged", len(auth.JWTSecret))
+	}
+	if auth.Cookie.Secure == "never" {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, nil,
+			"Set auth.cookie.secure to auto and serve the panel over HTTPS",
+			"Cookies are never marked secure, sign-in state can leak over plain HTTP")
+	}
+	if auth.OIDC.Enabled && auth.OIDC.SkipTLSVerify {
+		d.add(checkAuthDefaults, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, nil,
+			"Set auth.oidc.skip_tls_verify to false and trust the provider's CA instead",
+			"OIDC skips TLS verification, a man in the middle can impersonate the identity provider")
+	}
+}
+
+func (s *SupportService) diagnoseCurseForgeAPIKey(ctx context.Context, d *diagnostics, servers []*storage.Server) {
+	globalSettings, _, err := s.store.GetGlobalSettings(ctx)
+	if err != nil {
+		s.log.Error("Failed to get global settings: %v", err)
+		return
+	}
+	if hasCurseForgeAPIKey(globalSettings) {
+		return
+	}
+
+	for _, server := range servers {
+		curseForge := server.ModLoader == storage.ModLoaderAutoCurseForge
+		if !curseForge && server.ModpackID != "" {
+			if modpack, err := s.store.GetIndexedModpack(ctx, server.ModpackID); err == nil {
+				curseForge = modpack.Indexer == "fuego"
+			}
+		}
+		if !curseForge {
+			continue
+		}
+		if serverConfig, err := s.store.GetServerConfig(ctx, server.ID); err == nil && hasCurseForgeAPIKey(serverConfig) {
+			continue
+		}
+		d.add(checkCurseForgeAPIKey, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_CRITICAL, server,
+			"Set a CurseForge API key in the global settings (https://console.curseforge.com/#/api-keys)",
+			"Server installs a CurseForge modpack but no CurseForge API key is configured, the download fails on start")
+	}
+
+	if _, total, err := s.store.SearchIndexedModpacks(ctx, "", "", "", "fuego", 0, 1); err == nil && total > 0 {
+		d.add(checkCurseForgeAPIKey, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_INFO, nil,
+			"Set a CurseForge API key in the global settings to search and sync CurseForge modpacks",
+			"%d CurseForge modpacks are indexed but no CurseForge API key is configured", total)
+	}
+}
+
+func hasCurseForgeAPIKey(cfg *storage.ServerConfig) bool {
+	return cfg != nil && ((cfg.CFAPIKey != nil && *cfg.CFAPIKey != "") || (cfg.CFAPIKeyFile != nil && *cfg.CFAPIKeyFile != ""))
+}
+
+func (s *SupportService) diagnoseContainerStatus(ctx context.Context, d *diagnostics, servers []*storage.Server) {
+	syncInterval := time.Duration(s.config.Docker.SyncInterval) * time.Second
+	for _, server := range servers {
+		if server.ContainerID == "" {
+			continue
+		}
+		status, err := s.docker.GetContainerStatus(ctx, server.ContainerID)
+		if err != nil {
+			d.add(checkContainerStatus, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, server,
+				"Recreate the server to build a new container",
+				"Server's container %s can't be inspected: %v", shortContainerID(server.ContainerID), err)
+			continue
+		}
+
+		// Transitions settle within a sync, a crash loop's error stays on a stopped container
+		switch {
+		case status == server.Status,
+			server.Status == storage.StatusStarting, server.Status == storage.StatusStopping, server.Status == storage.StatusCreating,
+			status == storage.StatusStarting,
+			server.Status == storage.StatusError && server.LastError != "" && status == storage.StatusStopped:
+			continue
+		}
+		d.add(checkContainerStatus, v1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING, server,
+			fmt.Sprintf("The status sync corrects this within %s, if it persists check that DiscoPanel can reach Docker", syncInterval),
+			"Server is %s in the database but its container is %s", server.Status, status)
+	}
+}
+
+func shortContainerID(containerID string) string {
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
@@ -14,6 +14,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/auth"
 	storage "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/docker"
 	"github.com/nickheyer/discopanel/pkg/download"
@@ -27,8 +28,12 @@ import (
 // Compile-time check that FileService implements the interface
 var _ discopanelv1connect.FileServiceHandler = (*FileService)(nil)
 
+// Largest file GetFile returns and UpdateFile writes, bigger files go through the download and upload sessions
+const maxFileContentSize = 16 << 20
+
 // extractionOp tracks an in-progress or completed extraction.
 type extractionOp struct {
+	UserID         string // Caller that started it, the only one who sees its status
 	State          string // "extracting", "completed", "failed"
 	FilesExtracted atomic.Int32
 	Error          string
@@ -67,6 +72,9 @@ func (s *FileService) ListFiles(ctx context.Context, req *connect.Request[v1.Lis
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Get path parameter
 	path := msg.Path
@@ -75,8 +83,8 @@ func (s *FileService) ListFiles(ctx context.Context, req *connect.Request[v1.Lis
 	}
 
 	// Clean and validate path
-	fullPath := filepath.Join(server.DataPath, path)
-	if !strings.HasPrefix(fullPath, server.DataPath) {
+	fullPath, err := files.ResolvePath(server.DataPath, path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -107,10 +115,13 @@ func (s *FileService) GetFile(ctx context.Context, req *connect.Request[v1.GetFi
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Clean and validate path
-	fullPath := filepath.Join(server.DataPath, msg.Path)
-	if !strings.HasPrefix(fullPath, server.DataPath) {
+	fullPath, err := files.ResolvePath(server.DataPath, msg.Path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -128,6 +139,10 @@ func (s *FileService) GetFile(ctx context.Context, req *connect.Request[v1.GetFi
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("path is a directory"))
 	}
 
+	if info.Size() > maxFileContentSize {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("file is larger than %d MB, download it instead", maxFileContentSize>>20))
+	}
+
 	// Read file content
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
@@ -141,6 +156,8 @@ func (s *FileService) GetFile(ctx context.Context, req *connect.Request[v1.GetFi
 	return connect.NewResponse(&v1.GetFileResponse{
 		Content:  content,
 		MimeType: mimeType,
+		IsBinary: !files.IsTextContent(content),
+		Size:     info.Size(),
 	}), nil
 }
 
@@ -158,6 +175,9 @@ func (s *FileService) SaveUploadedFile(ctx context.Context, req *connect.Request
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Get temp file path and original filename from upload manager
 	tempPath, originalFilename, err := s.uploadManager.GetTempPath(msg.UploadSessionId)
@@ -184,8 +204,8 @@ func (s *FileService) SaveUploadedFile(ctx context.Context, req *connect.Request
 	}
 
 	// Clean and validate path
-	fullPath := filepath.Join(server.DataPath, targetPath)
-	if !strings.HasPrefix(fullPath, server.DataPath) {
+	fullPath, err := files.ResolvePath(server.DataPath, targetPath)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -195,8 +215,13 @@ func (s *FileService) SaveUploadedFile(ctx context.Context, req *connect.Request
 		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to create directory"))
 	}
 
+	// The name itself could be ".." or an existing link
+	destFilePath, err := files.ResolvePath(server.DataPath, filepath.Join(targetPath, targetFilename))
+	if err != nil || filepath.Dir(destFilePath) != fullPath {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid filename"))
+	}
+
 	// Move file from temp location to destination
-	destFilePath := filepath.Join(fullPath, targetFilename)
 	if err := os.Rename(tempPath, destFilePath); err != nil {
 		if err := files.CopyFile(tempPath, destFilePath); err != nil {
 			s.log.Error("Failed to move file: %v", err)
@@ -218,18 +243,30 @@ func (s *FileService) SaveUploadedFile(ctx context.Context, req *connect.Request
 func (s *FileService) UpdateFile(ctx context.Context, req *connect.Request[v1.UpdateFileRequest]) (*connect.Response[v1.UpdateFileResponse], error) {
 	msg := req.Msg
 
+	if len(msg.Content) > maxFileContentSize {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("content is larger than %d MB, upload the file instead", maxFileContentSize>>20))
+	}
+
 	// Get server
 	server, err := s.store.GetServer(ctx, msg.ServerId)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Clean and validate path
-	fullPath := filepath.Join(server.DataPath, msg.Path)
-	if !strings.HasPrefix(fullPath, server.DataPath) {
+	fullPath, err := files.ResolvePath(server.DataPath, msg.Path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
+	// Don't overwrite directories
+	if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("path is a directory"))
+	}
+
 	// Create directories if needed
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -258,6 +295,9 @@ func (s *FileService) DeleteFile(ctx context.Context, req *connect.Request[v1.De
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Build list of paths to delete: prefer bulk paths, fall back to single path
 	paths := msg.Paths
@@ -269,11 +309,11 @@ func (s *FileService) DeleteFile(ctx context.Context, req *connect.Request[v1.De
 	}
 
 	for _, p := range paths {
-		fullPath := filepath.Join(server.DataPath, p)
-		if !strings.HasPrefix(fullPath, server.DataPath) {
+		fullPath, err := files.ResolvePath(server.DataPath, p)
+		if err != nil {
 			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid path: %s", p))
 		}
-		if fullPath == server.DataPath {
+		if fullPath == filepath.Clean(server.DataPath) {
 			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("cannot delete server root directory"))
 		}
 
@@ -318,20 +358,23 @@ func (s *FileService) RenameFile(ctx context.Context, req *connect.Request[v1.Re
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Clean and validate old path
-	oldFullPath := filepath.Join(server.DataPath, msg.Path)
-	if !strings.HasPrefix(oldFullPath, server.DataPath) {
+	oldFullPath, err := files.ResolvePath(server.DataPath, msg.Path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
 	// Build new path
 	dir := filepath.Dir(msg.Path)
 	newPath := filepath.Join(dir, msg.NewName)
-	newFullPath := filepath.Join(server.DataPath, newPath)
 
 	// Validate new path
-	if !strings.HasPrefix(newFullPath, server.DataPath) {
+	newFullPath, err := files.ResolvePath(server.DataPath, newPath)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid new path"))
 	}
 
@@ -369,10 +412,13 @@ func (s *FileService) ExtractArchive(ctx context.Context, req *connect.Request[v
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Clean and validate archive path
-	fullArchivePath := filepath.Join(server.DataPath, msg.Path)
-	if !strings.HasPrefix(fullArchivePath, server.DataPath) {
+	fullArchivePath, err := files.ResolvePath(server.DataPath, msg.Path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid archive path"))
 	}
 
@@ -406,6 +452,9 @@ func (s *FileService) ExtractArchive(ctx context.Context, req *connect.Request[v
 	// Start async extraction
 	opID := uuid.New().String()
 	op := &extractionOp{State: "extracting"}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		op.UserID = user.ID
+	}
 	s.extractions.Store(opID, op)
 
 	go func() {
@@ -429,6 +478,9 @@ func (s *FileService) ExtractArchive(ctx context.Context, req *connect.Request[v
 // Get progress of extraction
 func (s *FileService) GetExtractionStatus(ctx context.Context, req *connect.Request[v1.GetExtractionStatusRequest]) (*connect.Response[v1.GetExtractionStatusResponse], error) {
 	val, ok := s.extractions.Load(req.Msg.OperationId)
+	if user := auth.GetUserFromContext(ctx); ok && user != nil && val.(*extractionOp).UserID != user.ID {
+		ok = false
+	}
 	if !ok {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("extraction operation not found"))
 	}
@@ -465,9 +517,12 @@ func (s *FileService) CreateFolder(ctx context.Context, req *connect.Request[v1.
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
-	fullPath := filepath.Join(server.DataPath, msg.Path)
-	if !strings.HasPrefix(fullPath, server.DataPath) {
+	fullPath, err := files.ResolvePath(server.DataPath, msg.Path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -489,11 +544,16 @@ func (s *FileService) MoveFile(ctx context.Context, req *connect.Request[v1.Move
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
-	srcFull := filepath.Join(server.DataPath, msg.SourcePath)
-	dstFull := filepath.Join(server.DataPath, msg.DestinationPath)
-
-	if !strings.HasPrefix(srcFull, server.DataPath) || !strings.HasPrefix(dstFull, server.DataPath) {
+	srcFull, err := files.ResolvePath(server.DataPath, msg.SourcePath)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
+	}
+	dstFull, err := files.ResolvePath(server.DataPath, msg.DestinationPath)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -542,11 +602,16 @@ func (s *FileService) CopyFile(ctx context.Context, req *connect.Request[v1.Copy
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
-	srcFull := filepath.Join(server.DataPath, msg.SourcePath)
-	dstFull := filepath.Join(server.DataPath, msg.DestinationPath)
-
-	if !strings.HasPrefix(srcFull, server.DataPath) || !strings.HasPrefix(dstFull, server.DataPath) {
+	srcFull, err := files.ResolvePath(server.DataPath, msg.SourcePath)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
+	}
+	dstFull, err := files.ResolvePath(server.DataPath, msg.DestinationPath)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -592,6 +657,9 @@ func (s *FileService) CreateArchive(ctx context.Context, req *connect.Request[v1
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	if len(msg.Paths) == 0 {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("no paths specified"))
@@ -599,8 +667,7 @@ func (s *FileService) CreateArchive(ctx context.Context, req *connect.Request[v1
 
 	// Validate all paths
 	for _, p := range msg.Paths {
-		fullPath := filepath.Join(server.DataPath, p)
-		if !strings.HasPrefix(fullPath, server.DataPath) {
+		if _, err := files.ResolvePath(server.DataPath, p); err != nil {
 			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid path: %s", p))
 		}
 	}
@@ -618,8 +685,8 @@ func (s *FileService) CreateArchive(ctx context.Context, req *connect.Request[v1
 	if destDir == "" {
 		destDir = "."
 	}
-	destFull := filepath.Join(server.DataPath, destDir, archiveName)
-	if !strings.HasPrefix(destFull, server.DataPath) {
+	destFull, err := files.ResolvePath(server.DataPath, filepath.Join(destDir, archiveName))
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid destination path"))
 	}
 
@@ -646,14 +713,16 @@ func (s *FileService) DownloadArchive(ctx context.Context, req *connect.Request[
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	if len(msg.Paths) == 0 {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("no paths specified"))
 	}
 
 	for _, p := range msg.Paths {
-		fullPath := filepath.Join(server.DataPath, p)
-		if !strings.HasPrefix(fullPath, server.DataPath) {
+		if _, err := files.ResolvePath(server.DataPath, p); err != nil {
 			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid path: %s", p))
 		}
 	}
@@ -699,9 +768,12 @@ func (s *FileService) InitFileDownload(ctx context.Context, req *connect.Request
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
-	fullPath := filepath.Join(server.DataPath, msg.Path)
-	if !strings.HasPrefix(fullPath, server.DataPath) {
+	fullPath, err := files.ResolvePath(server.DataPath, msg.Path)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid path"))
 	}
 
@@ -773,7 +845,7 @@ func (s *FileService) listDirectory(path, basePath string) ([]*v1.FileInfo, erro
 			IsDir:      entry.IsDir(),
 			Size:       info.Size(),
 			Modified:   info.ModTime().Unix(),
-			IsEditable: !entry.IsDir() && files.IsTextFile(fullPath),
+			IsEditable: !entry.IsDir() && info.Size() <= maxFileContentSize && files.IsTextFile(fullPath),
 		}
 
 		lsFiles = append(lsFiles, fileInfo)
@@ -808,7 +880,7 @@ func (s *FileService) listDirectoryTree(path, basePath string, depth, maxDepth i
 			IsDir:      entry.IsDir(),
 			Size:       info.Size(),
 			Modified:   info.ModTime().Unix(),
-			IsEditable: !entry.IsDir() && files.IsTextFile(fullPath),
+			IsEditable: !entry.IsDir() && info.Size() <= maxFileContentSize && files.IsTextFile(fullPath),
 		}
 
 		// If it's a directory and we haven't reached max depth, get children
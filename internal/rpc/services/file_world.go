@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/files"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// DownloadWorld zips a world directory and returns a download session.
+// The actual bytes are served via GET /api/v1/download/{session_id}.
+func (s *FileService) DownloadWorld(ctx context.Context, req *connect.Request[v1.DownloadWorldRequest]) (*connect.Response[v1.DownloadWorldResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+
+	world, worldPath, err := resolveWorld(ctx, s.store, server, msg.World)
+	if err != nil {
+		return nil, err
+	}
+
+	// A volume DiscoPanel can't see is copied out through Docker and zipped from there
+	dataPath := server.DataPath
+	if dataPath == "" {
+		staging, err := volumeStaging(s.downloadManager.TempDir(), "world")
+		if err != nil {
+			s.log.Error("Failed to create staging directory: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, errors.New("failed to create archive"))
+		}
+		defer os.RemoveAll(staging)
+		if _, err := s.docker.CopyFromVolume(ctx, server, world, filepath.Join(staging, world)); err != nil {
+			s.log.Error("Failed to copy world %s out of server %s: %v", world, server.Name, err)
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("world %s not found", world))
+		}
+		dataPath = staging
+		worldPath = filepath.Join(staging, world)
+	}
+	if _, err := os.Stat(filepath.Join(worldPath, "level.dat")); err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("world %s not found", world))
+	}
+
+	// Entries keep the world directory, so the zip can be uploaded again as is
+	filename := world + ".zip"
+	tempPath := filepath.Join(s.downloadManager.TempDir(), fmt.Sprintf("world-%s.zip", time.Now().Format("20060102-150405.000")))
+	if _, err := files.CreateZipArchive([]string{world}, dataPath, tempPath, true); err != nil {
+		s.log.Error("Failed to create world archive: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to create archive"))
+	}
+
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to stat archive"))
+	}
+
+	session := s.downloadManager.InitSession(tempPath, filename, info.Size(), true)
+
+	return connect.NewResponse(&v1.DownloadWorldResponse{
+		SessionId: session.ID,
+		Filename:  filename,
+		TotalSize: info.Size(),
+		World:     world,
+	}), nil
+}
+
+// ReplaceWorld swaps a world directory for the world in an uploaded zip. The zip's
+// shallowest level.dat marks the world, so both a bare world and its directory work.
+func (s *FileService) ReplaceWorld(ctx context.Context, req *connect.Request[v1.ReplaceWorldRequest]) (*connect.Response[v1.ReplaceWorldResponse], error) {
+	msg := req.Msg
+
+	if msg.UploadSessionId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("upload_session_id is required"))
+	}
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
+	}
+
+	// The server would save its loaded world over the new one
+	if server.ContainerID != "" {
+		if status, err := s.docker.GetContainerStatus(ctx, server.ContainerID); err == nil &&
+			status != storage.StatusStopped && status != storage.StatusError {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("stop the server before replacing its world"))
+		}
+	}
+
+	world, worldPath, err := resolveWorld(ctx, s.store, server, msg.World)
+	if err != nil {
+		return nil, err
+	}
+
+	archivePath, _, err := s.uploadManager.GetTempPath(msg.UploadSessionId)
+	if err != nil {
+		s.log.Error("Failed to get upload session: %v", err)
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("upload session not found or not completed"))
+	}
+	defer s.uploadManager.CleanupSession(msg.UploadSessionId)
+
+	prefix, err := files.FindZipWorld(archivePath)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("upload is not a world zip: %w", err))
+	}
+
+	uid, gid := serverUser(ctx, s.store, server)
+	if server.DataPath == "" {
+		return s.replaceVolumeWorld(ctx, server, world, archivePath, prefix, uid, gid)
+	}
+
+	// Extract next to the world first, a broken upload leaves the old world in place
+	staging := worldPath + ".upload"
+	if err := os.RemoveAll(staging); err != nil {
+		s.log.Error("Failed to clear %s: %v", staging, err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to prepare world directory"))
+	}
+	count, err := files.ExtractZipDir(ctx, archivePath, prefix, staging)
+	if err != nil {
+		os.RemoveAll(staging)
+		s.log.Error("Failed to extract world upload for server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to extract world: %w", err))
+	}
+
+	if err := swapWorld(worldPath, staging); err != nil {
+		os.RemoveAll(staging)
+		s.log.Error("Failed to replace world %s of server %s: %v", world, server.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to replace world"))
+	}
+
+	// Extracted files belong to DiscoPanel's user, the server runs as its own
+	if err := files.ChownTree(worldPath, uid, gid); err != nil {
+		s.log.Warn("Failed to chown world %s of server %s: %v", world, server.Name, err)
+	}
+
+	s.log.Info("Replaced world %s of server %s (%d files)", world, server.Name, count)
+	return connect.NewResponse(&v1.ReplaceWorldResponse{
+		World:          world,
+		FilesExtracted: int32(count),
+	}), nil
+}
+
+// ReplaceWorld for a data volume DiscoPanel can't see. The upload is extracted locally and
+// copied in next to the world, then swapped in by a shell in the volume.
+func (s *FileService) replaceVolumeWorld(ctx context.Context, server *storage.Server, world, archivePath, prefix string, uid, gid int) (*connect.Response[v1.ReplaceWorldResponse], error) {
+	staging, err := volumeStaging(s.downloadManager.TempDir(), "world")
+	if err != nil {
+		s.log.Error("Failed to create staging directory: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to prepare world directory"))
+	}
+	defer os.RemoveAll(staging)
+
+	count, err := files.ExtractZipDir(ctx, archivePath, prefix, staging)
+	if err != nil {
+		s.log.Error("Failed to extract world upload for server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to extract world: %w", err))
+	}
+
+	upload := world + ".upload"
+	if _, err := s.docker.RunInVolume(ctx, server, `rm -rf -- "/data/$1"`, upload); err != nil {
+		s.log.Error("Failed to clear %s in server %s: %v", upload, server.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to prepare world directory"))
+	}
+	if err := s.docker.CopyToVolume(ctx, server, staging, upload, uid, gid); err != nil {
+		s.log.Error("Failed to copy world upload into server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to replace world"))
+	}
+
+	// Same moves as swapWorld, the old world comes back when the new one can't take its place
+	if _, err := s.docker.RunInVolume(ctx, server, `cd /data || exit 1
+rm -rf -- "$1.replaced" || exit 1
+if [ -e "$1" ]; then mv -- "$1" "$1.replaced" || exit 1; fi
+if ! mv -- "$1.upload" "$1"; then
+	mv -- "$1.replaced" "$1"
+	exit 1
+fi
+rm -rf -- "$1.replaced"`, world); err != nil {
+		s.docker.RunInVolume(ctx, server, `rm -rf -- "/data/$1"`, upload)
+		s.log.Error("Failed to replace world %s of server %s: %v", world, server.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to replace world"))
+	}
+
+	s.log.Info("Replaced world %s of server %s (%d files)", world, server.Name, count)
+	return connect.NewResponse(&v1.ReplaceWorldResponse{
+		World:          world,
+		FilesExtracted: int32(count),
+	}), nil
+}
+
+// Resolves a world name to its directory, an empty name is the server's level name. The
+// directory is empty for a data volume DiscoPanel can't see, the name is still checked.
+func resolveWorld(ctx context.Context, store *storage.Store, server *storage.Server, world string) (string, string, error) {
+	world = strings.TrimSpace(world)
+	if world == "" {
+		world = activeWorld(ctx, store, server)
+	}
+	if strings.ContainsAny(world, `/\`) || world == "." || world == ".." {
+		return "", "", connect.NewError(connect.CodeInvalidArgument, errors.New("world must be a directory name"))
+	}
+	if server.DataPath == "" {
+		return world, "", nil
+	}
+
+	worldPath, err := files.ResolvePath(server.DataPath, world)
+	if err != nil {
+		return "", "", connect.NewError(connect.CodeInvalidArgument, errors.New("invalid world"))
+	}
+	return world, worldPath, nil
+}
+
+// The world the server loads, its LEVEL setting
+func activeWorld(ctx context.Context, store *storage.Store, server *storage.Server) string {
+	if serverConfig, err := store.GetServerConfig(ctx, server.ID); err == nil && serverConfig.Level != nil && *serverConfig.Level != "" {
+		return *serverConfig.Level
+	}
+	return "world"
+}
+
+// Moves staging into the world's place, putting the old world back when that fails
+func swapWorld(worldPath, staging string) error {
+	previous := worldPath + ".replaced"
+	if err := os.RemoveAll(previous); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(worldPath); err == nil {
+		if err := os.Rename(worldPath, previous); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(staging, worldPath); err != nil {
+		os.Rename(previous, worldPath)
+		return err
+	}
+	os.RemoveAll(previous)
+	return nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"runtime"
+
+	"connectrpc.com/connect"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/pkg/logger"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"github.com/nickheyer/discopanel/pkg/proto/discopanel/v1/discopanelv1connect"
+)
+
+// Compile-time check that HealthService implements the interface
+var _ discopanelv1connect.HealthServiceHandler = (*HealthService)(nil)
+
+// HealthService implements the Health service
+type HealthService struct {
+	docker *docker.Client
+	log    *logger.Logger
+}
+
+// NewHealthService creates a new health service
+func NewHealthService(docker *docker.Client, log *logger.Logger) *HealthService {
+	return &HealthService{
+		docker: docker,
+		log:    log,
+	}
+}
+
+// Check runs the same readiness checks as GET /api/v1/health
+func (s *HealthService) Check(ctx context.Context, req *connect.Request[v1.CheckRequest]) (*connect.Response[v1.CheckResponse], error) {
+	resp := &v1.CheckResponse{Status: v1.HealthStatus_HEALTH_STATUS_OK}
+
+	network := &v1.HealthCheck{Name: "docker_network", Status: v1.HealthStatus_HEALTH_STATUS_OK}
+	if err := s.docker.NetworkError(); err != nil {
+		network.Status = v1.HealthStatus_HEALTH_STATUS_DEGRADED
+		network.Error = err.Error()
+		resp.Status = v1.HealthStatus_HEALTH_STATUS_DEGRADED
+	}
+	resp.Checks = append(resp.Checks, network)
+
+	return connect.NewResponse(resp), nil
+}
+
+// GetVersion reports the panel build
+func (s *HealthService) GetVersion(ctx context.Context, req *connect.Request[v1.GetVersionRequest]) (*connect.Response[v1.GetVersionResponse], error) {
+	return connect.NewResponse(&v1.GetVersionResponse{
+		Version:    getVersionInfo(),
+		GoVersion:  runtime.Version(),
+		ApiVersion: string(v1.File_discopanel_v1_health_proto.Package()),
+	}), nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/events"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func imageUpdateToProto(update *docker.ImageUpdate) *v1.ImageStatus {
+	return &v1.ImageStatus{
+		Image:           update.Image,
+		CurrentDigest:   update.CurrentDigest,
+		LatestDigest:    update.LatestDigest,
+		UpdateAvailable: update.Available,
+		CheckedAt:       timestamppb.New(update.CheckedAt),
+	}
+}
+
+// GetServerImageStatus compares the image a server runs with its tag's current digest
+func (s *ServerService) GetServerImageStatus(ctx context.Context, req *connect.Request[v1.GetServerImageStatusRequest]) (*connect.Response[v1.GetServerImageStatusResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	update, err := s.docker.CheckImageUpdate(ctx, server.ContainerID, docker.ServerImage(server), req.Msg.Refresh)
+	if err != nil {
+		s.log.Error("Failed to check image of server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeUnavailable, err)
+	}
+
+	return connect.NewResponse(&v1.GetServerImageStatusResponse{
+		Status: imageUpdateToProto(update),
+	}), nil
+}
+
+// UpdateServerImage recreates a server's container when its tag has a newer image, the
+// recreation pulls it. A running server is started again.
+func (s *ServerService) UpdateServerImage(ctx context.Context, req *connect.Request[v1.UpdateServerImageRequest]) (*connect.Response[v1.UpdateServerImageResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if server.Imported {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("server runs an imported container, recreate it from DiscoPanel before updating its image"))
+	}
+	if server.ContainerID == "" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("server has no container, the newest image is pulled when it's next started"))
+	}
+
+	imageName := docker.ServerImage(server)
+	update, err := s.docker.CheckImageUpdate(ctx, server.ContainerID, imageName, true)
+	if err != nil {
+		s.log.Error("Failed to check image of server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeUnavailable, err)
+	}
+	if !update.Available {
+		return connect.NewResponse(&v1.UpdateServerImageResponse{
+			Status: imageUpdateToProto(update),
+		}), nil
+	}
+
+	recreated, err := s.RecreateServer(ctx, connect.NewRequest(&v1.RecreateServerRequest{Id: server.ID}))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.bus != nil {
+		s.bus.Emit(ctx, events.Event{
+			Type:     v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_IMAGE_UPDATED,
+			ServerID: server.ID,
+			Data: map[string]any{
+				"image":           imageName,
+				"previous_digest": update.CurrentDigest,
+				"digest":          update.LatestDigest,
+			},
+		})
+	}
+	s.log.Info("Server %s updated to the newest %s", server.Name, imageName)
+
+	return connect.NewResponse(&v1.UpdateServerImageResponse{
+		Status:  imageUpdateToProto(update),
+		Updated: true,
+		Running: recreated.Msg.Running,
+	}), nil
+}
+
+// GetModuleImageStatus compares the image a module runs with its tag's current digest
+func (s *ModuleService) GetModuleImageStatus(ctx context.Context, req *connect.Request[v1.GetModuleImageStatusRequest]) (*connect.Response[v1.GetModuleImageStatusResponse], error) {
+	msg := req.Msg
+	if msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("module ID is required"))
+	}
+
+	_, update, err := s.checkModuleImage(ctx, msg.Id, msg.Refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&v1.GetModuleImageStatusResponse{
+		Status: imageUpdateToProto(update),
+	}), nil
+}
+
+// UpdateModuleImage recreates a module's container when its tag has a newer image
+func (s *ModuleService) UpdateModuleImage(ctx context.Context, req *connect.Request[v1.UpdateModuleImageRequest]) (*connect.Response[v1.UpdateModuleImageResponse], error) {
+	msg := req.Msg
+	if msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("module ID is required"))
+	}
+
+	module, update, err := s.checkModuleImage(ctx, msg.Id, true)
+	if err != nil {
+		return nil, err
+	}
+	if !update.Available {
+		return connect.NewResponse(&v1.UpdateModuleImageResponse{
+			Status: imageUpdateToProto(update),
+		}), nil
+	}
+
+	if err := s.moduleManager.RecreateModule(ctx, msg.Id); err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to recreate module: %w", err))
+	}
+	s.log.Info("Module %s updated to the newest %s", module.Name, update.Image)
+
+	return connect.NewResponse(&v1.UpdateModuleImageResponse{
+		Status:  imageUpdateToProto(update),
+		Updated: true,
+	}), nil
+}
+
+func (s *ModuleService) checkModuleImage(ctx context.Context, moduleID string, refresh bool) (*storage.Module, *docker.ImageUpdate, error) {
+	module, err := s.store.GetModule(ctx, moduleID)
+	if err != nil {
+		return nil, nil, connect.NewError(connect.CodeNotFound, errors.New("module not found"))
+	}
+	template, err := s.store.GetModuleTemplate(ctx, module.TemplateID)
+	if err != nil {
+		return nil, nil, connect.NewError(connect.CodeNotFound, errors.New("module template not found"))
+	}
+	if template.DockerImage == "" {
+		return nil, nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("module template has no Docker image configured"))
+	}
+
+	update, err := s.docker.CheckImageUpdate(ctx, module.ContainerID, template.DockerImage, refresh)
+	if err != nil {
+		s.log.Error("Failed to check image of module %s: %v", module.Name, err)
+		return nil, nil, connect.NewError(connect.CodeUnavailable, err)
+	}
+	return module, update, nil
+}
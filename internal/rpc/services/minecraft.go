@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"fmt"
 
 	"connectrpc.com/connect"
 	storage "github.com/nickheyer/discopanel/internal/db"
@@ -139,3 +140,27 @@ func (s *MinecraftService) GetDockerImages(ctx context.Context, req *connect.Req
 		Images: protoImages,
 	}), nil
 }
+
+// ResolveDockerImage previews the Docker image tag chosen for a version and loader
+func (s *MinecraftService) ResolveDockerImage(ctx context.Context, req *connect.Request[v1.ResolveDockerImageRequest]) (*connect.Response[v1.ResolveDockerImageResponse], error) {
+	msg := req.Msg
+	if msg.McVersion == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("mc_version is required"))
+	}
+
+	modLoader := protoModLoaderToDB(msg.ModLoader)
+	tag := docker.GetOptimalDockerTag(msg.McVersion, modLoader, msg.PreferGraalvm)
+
+	resp := &v1.ResolveDockerImageResponse{
+		Tag:         tag,
+		Image:       docker.MinecraftImageRepo + ":" + tag,
+		JavaVersion: docker.GetRequiredJavaVersion(msg.McVersion, modLoader),
+		Valid:       true,
+	}
+	if err := s.docker.ValidateImageTag(ctx, tag); err != nil {
+		resp.Valid = false
+		resp.Error = err.Error()
+	}
+
+	return connect.NewResponse(resp), nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
@@ -49,6 +50,9 @@ func (s *ModService) ListMods(ctx context.Context, req *connect.Request[v1.ListM
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Get the mods directory path
 	modsDir := minecraft.GetModsPath(server.DataPath, server.ModLoader)
@@ -71,29 +75,9 @@ func (s *ModService) ListMods(ctx context.Context, req *connect.Request[v1.ListM
 				continue
 			}
 
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-
-			// Extract display name from filename (remove extension)
-			displayName := file.Name()
-			if ext := filepath.Ext(displayName); ext != "" {
-				displayName = displayName[:len(displayName)-len(ext)]
-			}
-
-			// Create mod entry with consistent ID generation
-			mod := &v1.Mod{
-				Id:          uuid.NewSHA1(uuid.NameSpaceURL, []byte(msg.ServerId+file.Name())).String(),
-				ServerId:    msg.ServerId,
-				FileName:    file.Name(),
-				DisplayName: displayName,
-				Enabled:     true,
-				FileSize:    info.Size(),
-				UploadedAt:  timestamppb.New(info.ModTime()),
+			if mod := modFileToProto(msg.ServerId, modsDir, file, true); mod != nil {
+				mods = append(mods, mod)
 			}
-
-			mods = append(mods, mod)
 		}
 	}
 
@@ -109,28 +93,9 @@ func (s *ModService) ListMods(ctx context.Context, req *connect.Request[v1.ListM
 				continue
 			}
 
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-
-			// Extract display name from filename
-			displayName := file.Name()
-			if ext := filepath.Ext(displayName); ext != "" {
-				displayName = displayName[:len(displayName)-len(ext)]
-			}
-
-			mod := &v1.Mod{
-				Id:          uuid.NewSHA1(uuid.NameSpaceURL, []byte(msg.ServerId+file.Name())).String(),
-				ServerId:    msg.ServerId,
-				FileName:    file.Name(),
-				DisplayName: displayName,
-				Enabled:     false,
-				FileSize:    info.Size(),
-				UploadedAt:  timestamppb.New(info.ModTime()),
+			if mod := modFileToProto(msg.ServerId, disabledDir, file, false); mod != nil {
+				mods = append(mods, mod)
 			}
-
-			mods = append(mods, mod)
 		}
 	}
 
@@ -148,6 +113,9 @@ func (s *ModService) GetMod(ctx context.Context, req *connect.Request[v1.GetModR
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Get the mods directory path
 	modsDir := minecraft.GetModsPath(server.DataPath, server.ModLoader)
@@ -162,24 +130,9 @@ func (s *ModService) GetMod(ctx context.Context, req *connect.Request[v1.GetModR
 				// Generate the same ID as in ListMods to match
 				fileID := uuid.NewSHA1(uuid.NameSpaceURL, []byte(msg.ServerId+file.Name())).String()
 				if fileID == msg.ModId {
-					info, _ := file.Info()
-
-					displayName := file.Name()
-					if ext := filepath.Ext(displayName); ext != "" {
-						displayName = displayName[:len(displayName)-len(ext)]
+					if mod := modFileToProto(msg.ServerId, modsDir, file, true); mod != nil {
+						return connect.NewResponse(&v1.GetModResponse{Mod: mod}), nil
 					}
-
-					return connect.NewResponse(&v1.GetModResponse{
-						Mod: &v1.Mod{
-							Id:          fileID,
-							ServerId:    msg.ServerId,
-							FileName:    file.Name(),
-							DisplayName: displayName,
-							Enabled:     true,
-							FileSize:    info.Size(),
-							UploadedAt:  timestamppb.New(info.ModTime()),
-						},
-					}), nil
 				}
 			}
 		}
@@ -192,24 +145,9 @@ func (s *ModService) GetMod(ctx context.Context, req *connect.Request[v1.GetModR
 			if !file.IsDir() && minecraft.IsValidModFile(file.Name(), server.ModLoader) {
 				fileID := uuid.NewSHA1(uuid.NameSpaceURL, []byte(msg.ServerId+file.Name())).String()
 				if fileID == msg.ModId {
-					info, _ := file.Info()
-
-					displayName := file.Name()
-					if ext := filepath.Ext(displayName); ext != "" {
-						displayName = displayName[:len(displayName)-len(ext)]
+					if mod := modFileToProto(msg.ServerId, disabledDir, file, false); mod != nil {
+						return connect.NewResponse(&v1.GetModResponse{Mod: mod}), nil
 					}
-
-					return connect.NewResponse(&v1.GetModResponse{
-						Mod: &v1.Mod{
-							Id:          fileID,
-							ServerId:    msg.ServerId,
-							FileName:    file.Name(),
-							DisplayName: displayName,
-							Enabled:     false,
-							FileSize:    info.Size(),
-							UploadedAt:  timestamppb.New(info.ModTime()),
-						},
-					}), nil
 				}
 			}
 		}
@@ -232,6 +170,9 @@ func (s *ModService) ImportUploadedMod(ctx context.Context, req *connect.Request
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	// Get temp file path and original filename from upload manager
 	tempPath, originalFilename, err := s.uploadManager.GetTempPath(msg.UploadSessionId)
@@ -245,6 +186,14 @@ func (s *ModService) ImportUploadedMod(ctx context.Context, req *connect.Request
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid file type for this mod loader"))
 	}
 
+	// Modpack installs resync the mods directory on every start
+	if minecraft.GetModLoaderInfo(server.ModLoader).Category == "Modpack" && !msg.Force {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("this server's mods are managed by its modpack and are replaced on restart, upload with force to add it anyway"))
+	}
+
+	// Don't let a file name escape the mods directory
+	originalFilename = filepath.Base(originalFilename)
+
 	// Get the correct mods directory based on mod loader
 	modsDir := minecraft.GetModsPath(server.DataPath, server.ModLoader)
 	if modsDir == "" {
@@ -277,25 +226,13 @@ func (s *ModService) ImportUploadedMod(ctx context.Context, req *connect.Request
 		return nil, connect.NewError(connect.CodeInternal, errors.New("failed to get mod info"))
 	}
 
-	// Use provided display name or derive from filename
-	displayName := msg.DisplayName
-	if displayName == "" {
-		displayName = originalFilename
-		if ext := filepath.Ext(displayName); ext != "" {
-			displayName = displayName[:len(displayName)-len(ext)]
-		}
+	// Fill in what the jar says about itself, explicit values win
+	mod := modInfoToProto(msg.ServerId, modPath, info, true)
+	if msg.DisplayName != "" {
+		mod.DisplayName = msg.DisplayName
 	}
-
-	// Create mod record
-	mod := &v1.Mod{
-		Id:          uuid.NewSHA1(uuid.NameSpaceURL, []byte(msg.ServerId+originalFilename)).String(),
-		ServerId:    msg.ServerId,
-		FileName:    originalFilename,
-		DisplayName: displayName,
-		Description: msg.Description,
-		Enabled:     true,
-		FileSize:    info.Size(),
-		UploadedAt:  timestamppb.New(info.ModTime()),
+	if msg.Description != "" {
+		mod.Description = msg.Description
 	}
 
 	return connect.NewResponse(&v1.ImportUploadedModResponse{
@@ -313,6 +250,9 @@ func (s *ModService) UpdateMod(ctx context.Context, req *connect.Request[v1.Upda
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	modsDir := minecraft.GetModsPath(server.DataPath, server.ModLoader)
 	if modsDir == "" {
@@ -389,36 +329,63 @@ func (s *ModService) UpdateMod(ctx context.Context, req *connect.Request[v1.Upda
 	}
 
 	// Build response
-	displayName := modFileName
-	if ext := filepath.Ext(displayName); ext != "" {
-		displayName = displayName[:len(displayName)-len(ext)]
+	dir := disabledDir
+	if finalEnabled {
+		dir = modsDir
 	}
+	mod := modInfoToProto(msg.ServerId, filepath.Join(dir, modFileName), modInfo, finalEnabled)
+	mod.UpdatedAt = timestamppb.Now()
 
 	// Use provided display name if given
 	if msg.DisplayName != nil && *msg.DisplayName != "" {
-		displayName = *msg.DisplayName
+		mod.DisplayName = *msg.DisplayName
 	}
-
-	description := ""
 	if msg.Description != nil {
-		description = *msg.Description
+		mod.Description = *msg.Description
 	}
 
 	return connect.NewResponse(&v1.UpdateModResponse{
-		Mod: &v1.Mod{
-			Id:          msg.ModId,
-			ServerId:    msg.ServerId,
-			FileName:    modFileName,
-			DisplayName: displayName,
-			Description: description,
-			Enabled:     finalEnabled,
-			FileSize:    modInfo.Size(),
-			UploadedAt:  timestamppb.New(modInfo.ModTime()),
-			UpdatedAt:   timestamppb.Now(),
-		},
+		Mod: mod,
 	}), nil
 }
 
+// Builds the mod for a directory entry, nil if it can't be stat'ed
+func modFileToProto(serverID, dir string, entry os.DirEntry, enabled bool) *v1.Mod {
+	info, err := entry.Info()
+	if err != nil {
+		return nil
+	}
+	return modInfoToProto(serverID, filepath.Join(dir, entry.Name()), info, enabled)
+}
+
+// Builds the mod for a jar, using the name, version and ID from its loader metadata
+// and falling back to the file name when the jar has none
+func modInfoToProto(serverID, path string, info os.FileInfo, enabled bool) *v1.Mod {
+	fileName := filepath.Base(path)
+	mod := &v1.Mod{
+		// ID derived from the file name so it stays stable across listings
+		Id:          uuid.NewSHA1(uuid.NameSpaceURL, []byte(serverID+fileName)).String(),
+		ServerId:    serverID,
+		FileName:    fileName,
+		DisplayName: strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		Enabled:     enabled,
+		FileSize:    info.Size(),
+		UploadedAt:  timestamppb.New(info.ModTime()),
+	}
+
+	if meta := minecraft.ReadModMetadata(path); meta != nil {
+		if meta.Name != "" {
+			mod.DisplayName = meta.Name
+		}
+		mod.ModId = meta.ModID
+		mod.Version = meta.Version
+		mod.Description = meta.Description
+		mod.Author = strings.Join(meta.Authors, ", ")
+		mod.Website = meta.Website
+	}
+	return mod
+}
+
 // DeleteMod deletes a mod
 func (s *ModService) DeleteMod(ctx context.Context, req *connect.Request[v1.DeleteModRequest]) (*connect.Response[v1.DeleteModResponse], error) {
 	msg := req.Msg
@@ -428,6 +395,9 @@ func (s *ModService) DeleteMod(ctx context.Context, req *connect.Request[v1.Dele
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	modsDir := minecraft.GetModsPath(server.DataPath, server.ModLoader)
 	if modsDir == "" {
@@ -19,6 +19,7 @@ import (
 	storage "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/docker"
 	"github.com/nickheyer/discopanel/internal/indexers"
+	_ "github.com/nickheyer/discopanel/internal/indexers/ftba"
 	_ "github.com/nickheyer/discopanel/internal/indexers/fuego"
 	_ "github.com/nickheyer/discopanel/internal/indexers/modrinth"
 	"github.com/nickheyer/discopanel/internal/minecraft"
@@ -53,9 +54,14 @@ func NewModpackService(store *storage.Store, cfg *config.Config, uploadManager *
 
 // getIndexer creates an indexer by name, looking up the fuego API key from settings when needed.
 func (s *ModpackService) getIndexer(ctx context.Context, name string) (indexers.ModpackIndexer, error) {
+	return newModpackIndexer(ctx, s.store, s.config, name)
+}
+
+// Builds an indexer client, loading the CurseForge API key from global settings for fuego
+func newModpackIndexer(ctx context.Context, store *storage.Store, cfg *config.Config, name string) (indexers.ModpackIndexer, error) {
 	apiKey := ""
 	if name == "fuego" {
-		globalSettings, _, err := s.store.GetGlobalSettings(ctx)
+		globalSettings, _, err := store.GetGlobalSettings(ctx)
 		if err != nil || globalSettings == nil {
 			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get global settings"))
 		}
@@ -66,7 +72,7 @@ func (s *ModpackService) getIndexer(ctx context.Context, name string) (indexers.
 			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("CurseForge API key not configured"))
 		}
 	}
-	idx, err := indexers.NewIndexer(name, apiKey, s.config)
+	idx, err := indexers.NewIndexer(name, apiKey, cfg)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
@@ -442,6 +448,11 @@ func (s *ModpackService) SyncModpacks(ctx context.Context, req *connect.Request[
 		javaVersion := docker.GetRequiredJavaVersion(mcVersion, modLoader)
 		dockerImage := docker.GetOptimalDockerTag(mcVersion, modLoader, false)
 
+		recommendedRAM := 6144 // 6GB for modpacks
+		if modpack.RecommendedRAM > 0 {
+			recommendedRAM = modpack.RecommendedRAM
+		}
+
 		dbModpack := &storage.IndexedModpack{
 			ID:            modpack.ID,
 			IndexerID:     modpack.IndexerID,
@@ -464,7 +475,7 @@ func (s *ModpackService) SyncModpacks(ctx context.Context, req *connect.Request[
 			MCVersion:      mcVersion,
 			JavaVersion:    javaVersion,
 			DockerImage:    dockerImage,
-			RecommendedRAM: 6144, // 6GB for modpacks
+			RecommendedRAM: recommendedRAM,
 		}
 
 		if err := s.store.UpsertIndexedModpack(ctx, dbModpack); err != nil {
@@ -883,6 +894,7 @@ func (s *ModpackService) GetIndexerStatus(ctx context.Context, req *connect.Requ
 	indexersAvailable := map[string]bool{
 		"fuego":    apiKeyConfigured,
 		"modrinth": true, // Modrinth doesn't require API key
+		"ftba":     true, // Neither does FTB
 		"manual":   true, // Manual uploads always available
 	}
 
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -119,11 +120,35 @@ func dbModuleStatusToProto(s storage.ModuleStatus) v1.ModuleStatus {
 		return v1.ModuleStatus_MODULE_STATUS_ERROR
 	case storage.ModuleStatusCreating:
 		return v1.ModuleStatus_MODULE_STATUS_CREATING
+	case storage.ModuleStatusUnhealthy:
+		return v1.ModuleStatus_MODULE_STATUS_UNHEALTHY
 	default:
 		return v1.ModuleStatus_MODULE_STATUS_UNSPECIFIED
 	}
 }
 
+// Attaches the module manager's latest health check result
+func (s *ModuleService) withHealth(pm *v1.Module) *v1.Module {
+	if result, ok := s.moduleManager.ModuleHealth(pm.Id); ok {
+		pm.Health = &v1.ModuleHealth{
+			Healthy:             result.Healthy,
+			Message:             result.Message,
+			CheckedAt:           timestamppb.New(result.CheckedAt),
+			ConsecutiveFailures: int32(result.Failures),
+		}
+	}
+	return pm
+}
+
+func validateRestartPolicy(policy string) error {
+	switch policy {
+	case "", storage.ModuleRestartNo, storage.ModuleRestartOnFailure, storage.ModuleRestartAlways:
+		return nil
+	default:
+		return fmt.Errorf("invalid restart policy %q, expected no, on-failure or always", policy)
+	}
+}
+
 func dbModuleTemplateToProto(t *storage.ModuleTemplate) *v1.ModuleTemplate {
 	if t == nil {
 		return nil
@@ -134,6 +159,7 @@ func dbModuleTemplateToProto(t *storage.ModuleTemplate) *v1.ModuleTemplate {
 		Description:             t.Description,
 		Type:                    dbModuleTemplateTypeToProto(t.Type),
 		DockerImage:             t.DockerImage,
+		ConfigSchema:            t.ConfigSchema,
 		DefaultEnv:              t.DefaultEnv,
 		DefaultVolumes:          t.DefaultVolumes,
 		HealthCheckPath:         t.HealthCheckPath,
@@ -204,6 +230,7 @@ func dbModuleToProto(m *storage.Module, serverName, templateName, serverProxyHos
 		InitCommand:           m.InitCommand,
 		InitCommandDelay:      int32(m.InitCommandDelay),
 		RestartAfterInit:      m.RestartAfterInit,
+		RestartPolicy:         m.RestartPolicy,
 	}
 
 	if m.LastStarted != nil {
@@ -278,6 +305,9 @@ func (s *ModuleService) CreateModuleTemplate(ctx context.Context, req *connect.R
 	if msg.DockerImage == "" {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("docker_image is required"))
 	}
+	if _, err := parseModuleConfigSchema(msg.ConfigSchema); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 
 	// Check for duplicate name
 	if _, err := s.store.GetModuleTemplateByName(ctx, msg.Name); err == nil {
@@ -290,6 +320,7 @@ func (s *ModuleService) CreateModuleTemplate(ctx context.Context, req *connect.R
 		Description:             msg.Description,
 		Type:                    storage.ModuleTemplateTypeCustom, // User-created templates are always custom
 		DockerImage:             msg.DockerImage,
+		ConfigSchema:            msg.ConfigSchema,
 		DefaultEnv:              msg.DefaultEnv,
 		DefaultVolumes:          msg.DefaultVolumes,
 		HealthCheckPath:         msg.HealthCheckPath,
@@ -347,6 +378,12 @@ func (s *ModuleService) UpdateModuleTemplate(ctx context.Context, req *connect.R
 	if msg.DockerImage != nil {
 		template.DockerImage = *msg.DockerImage
 	}
+	if msg.ConfigSchema != nil {
+		if _, err := parseModuleConfigSchema(*msg.ConfigSchema); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		template.ConfigSchema = *msg.ConfigSchema
+	}
 	if msg.DefaultEnv != nil {
 		template.DefaultEnv = *msg.DefaultEnv
 	}
@@ -493,7 +530,7 @@ func (s *ModuleService) ListModules(ctx context.Context, req *connect.Request[v1
 		}
 
 		createdByUsername := s.resolveCreatedByUsername(ctx, m.CreatedBy)
-		protoModules = append(protoModules, dbModuleToProto(m, serverName, templateName, serverProxyHostname, createdByUsername))
+		protoModules = append(protoModules, s.withHealth(dbModuleToProto(m, serverName, templateName, serverProxyHostname, createdByUsername)))
 	}
 
 	return connect.NewResponse(&v1.ListModulesResponse{
@@ -536,7 +573,7 @@ func (s *ModuleService) GetModule(ctx context.Context, req *connect.Request[v1.G
 
 	createdByUsername := s.resolveCreatedByUsername(ctx, module.CreatedBy)
 	return connect.NewResponse(&v1.GetModuleResponse{
-		Module: dbModuleToProto(module, serverName, templateName, serverProxyHostname, createdByUsername),
+		Module: s.withHealth(dbModuleToProto(module, serverName, templateName, serverProxyHostname, createdByUsername)),
 	}), nil
 }
 
@@ -551,6 +588,9 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 	if msg.TemplateId == "" {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("template_id is required"))
 	}
+	if err := validateRestartPolicy(msg.RestartPolicy); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 
 	// Verify server exists
 	server, err := s.store.GetServer(ctx, msg.ServerId)
@@ -570,6 +610,25 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		ports = template.Ports
 	}
 
+	// Same for env, volumes and hooks, so a request naming only the template gets a working module
+	envOverrides, volumeOverrides, eventHooks := msg.EnvOverrides, msg.VolumeOverrides, msg.EventHooks
+	if envOverrides == "" {
+		envOverrides = template.DefaultEnv
+	}
+	envOverrides, err = applyModuleConfigSchema(template.ConfigSchema, envOverrides)
+	if err != nil {
+		return nil, moduleEnvError(err)
+	}
+	if msg.Config != "" && !json.Valid([]byte(msg.Config)) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("config must be valid JSON"))
+	}
+	if volumeOverrides == "" {
+		volumeOverrides = template.DefaultVolumes
+	}
+	if len(eventHooks) == 0 {
+		eventHooks = template.DefaultHooks
+	}
+
 	// Allocate host ports for any port entries that need it
 	// Track ports allocated in this request to avoid duplicates
 	allocatedInRequest := make(map[int]bool)
@@ -592,6 +651,11 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		if port == nil || port.HostPort == 0 {
 			continue
 		}
+		if !port.ProxyEnabled && !allocatedInRequest[int(port.HostPort)] {
+			if err := s.moduleManager.CheckModulePortRange(int(port.HostPort)); err != nil {
+				return nil, connect.NewError(connect.CodeInvalidArgument, err)
+			}
+		}
 		protocol := port.Protocol
 		if protocol == "" {
 			protocol = "tcp"
@@ -614,8 +678,8 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		TemplateID:            msg.TemplateId,
 		Status:                storage.ModuleStatusStopped,
 		Config:                msg.Config,
-		EnvOverrides:          msg.EnvOverrides,
-		VolumeOverrides:       msg.VolumeOverrides,
+		EnvOverrides:          envOverrides,
+		VolumeOverrides:       volumeOverrides,
 		Memory:                int(msg.Memory),
 		CPULimit:              msg.CpuLimit,
 		AutoStart:             msg.AutoStart,
@@ -626,7 +690,7 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		HealthCheckInterval:   int(msg.HealthCheckInterval),
 		HealthCheckTimeout:    int(msg.HealthCheckTimeout),
 		HealthCheckRetries:    int(msg.HealthCheckRetries),
-		EventHooks:            msg.EventHooks,
+		EventHooks:            eventHooks,
 		Metadata:              msg.Metadata,
 		CmdOverride:           msg.CmdOverride,
 		AccessUrls:            msg.AccessUrls,
@@ -635,20 +699,7 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		InitCommand:           msg.InitCommand,
 		InitCommandDelay:      int(msg.InitCommandDelay),
 		RestartAfterInit:      msg.RestartAfterInit,
-	}
-
-	// Generate module API token tied to the creating user
-	if user := auth.GetUserFromContext(ctx); user != nil {
-		module.CreatedBy = user.ID
-		if s.authManager != nil {
-			plaintext, token, err := s.authManager.GenerateModuleToken(ctx, user.ID, msg.Name, moduleID)
-			if err != nil {
-				s.log.Error("Failed to generate module token: %v", err)
-			} else {
-				module.TokenID = token.ID
-				module.TokenPlaintext = plaintext
-			}
-		}
+		RestartPolicy:         msg.RestartPolicy,
 	}
 
 	// Use template defaults for access URLs if not provided
@@ -656,6 +707,10 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		module.AccessUrls = template.DefaultAccessUrls
 	}
 
+	if err := s.moduleManager.CheckDependencies(ctx, module); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	if module.Memory == 0 {
 		if template.DefaultMemory > 0 {
 			module.Memory = template.DefaultMemory
@@ -680,6 +735,24 @@ func (s *ModuleService) CreateModule(ctx context.Context, req *connect.Request[v
 		module.RestartAfterInit = template.DefaultRestartAfterInit
 	}
 
+	if err := checkCallerQuota(ctx, s.store, storage.QuotaUsage{Modules: 1, Memory: module.Memory}); err != nil {
+		return nil, err
+	}
+
+	// Generate module API token tied to the creating user
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		module.CreatedBy = user.ID
+		if s.authManager != nil {
+			plaintext, token, err := s.authManager.GenerateModuleToken(ctx, user.ID, msg.Name, moduleID)
+			if err != nil {
+				s.log.Error("Failed to generate module token: %v", err)
+			} else {
+				module.TokenID = token.ID
+				module.TokenPlaintext = plaintext
+			}
+		}
+	}
+
 	if err := s.store.CreateModule(ctx, module); err != nil {
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create module: %w", err))
 	}
@@ -716,11 +789,22 @@ func (s *ModuleService) UpdateModule(ctx context.Context, req *connect.Request[v
 		module.Name = *msg.Name
 	}
 	if msg.Config != nil {
+		if *msg.Config != "" && !json.Valid([]byte(*msg.Config)) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("config must be valid JSON"))
+		}
 		module.Config = *msg.Config
 	}
 	if msg.EnvOverrides != nil {
-		if *msg.EnvOverrides != module.EnvOverrides {
-			module.EnvOverrides = *msg.EnvOverrides
+		configSchema := ""
+		if template, err := s.store.GetModuleTemplate(ctx, module.TemplateID); err == nil {
+			configSchema = template.ConfigSchema
+		}
+		envOverrides, err := applyModuleConfigSchema(configSchema, *msg.EnvOverrides)
+		if err != nil {
+			return nil, moduleEnvError(err)
+		}
+		if envOverrides != module.EnvOverrides {
+			module.EnvOverrides = envOverrides
 			needsRecreate = true
 		}
 	}
@@ -732,6 +816,9 @@ func (s *ModuleService) UpdateModule(ctx context.Context, req *connect.Request[v
 	}
 	if msg.Memory != nil {
 		if int(*msg.Memory) != module.Memory {
+			if err := checkCreatorQuota(ctx, s.store, module.CreatedBy, storage.QuotaUsage{Memory: int(*msg.Memory) - module.Memory}); err != nil {
+				return nil, err
+			}
 			module.Memory = int(*msg.Memory)
 			needsRecreate = true
 		}
@@ -760,6 +847,11 @@ func (s *ModuleService) UpdateModule(ctx context.Context, req *connect.Request[v
 			if port == nil || port.HostPort == 0 {
 				continue
 			}
+			if !port.ProxyEnabled {
+				if err := s.moduleManager.CheckModulePortRange(int(port.HostPort)); err != nil {
+					return nil, connect.NewError(connect.CodeInvalidArgument, err)
+				}
+			}
 			protocol := port.Protocol
 			if protocol == "" {
 				protocol = "tcp"
@@ -778,6 +870,9 @@ func (s *ModuleService) UpdateModule(ctx context.Context, req *connect.Request[v
 	}
 	if len(msg.Dependencies) > 0 {
 		module.Dependencies = msg.Dependencies
+		if err := s.moduleManager.CheckDependencies(ctx, module); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
 	}
 	if msg.HealthCheckInterval != nil {
 		module.HealthCheckInterval = int(*msg.HealthCheckInterval)
@@ -824,6 +919,15 @@ func (s *ModuleService) UpdateModule(ctx context.Context, req *connect.Request[v
 	if msg.RestartAfterInit != nil {
 		module.RestartAfterInit = *msg.RestartAfterInit
 	}
+	if msg.RestartPolicy != nil {
+		if err := validateRestartPolicy(*msg.RestartPolicy); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		if *msg.RestartPolicy != module.RestartPolicy {
+			module.RestartPolicy = *msg.RestartPolicy
+			needsRecreate = true
+		}
+	}
 
 	if err := s.store.UpdateModule(ctx, module); err != nil {
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update module: %w", err))
@@ -853,7 +957,7 @@ func (s *ModuleService) UpdateModule(ctx context.Context, req *connect.Request[v
 
 	createdByUsername := s.resolveCreatedByUsername(ctx, module.CreatedBy)
 	return connect.NewResponse(&v1.UpdateModuleResponse{
-		Module: dbModuleToProto(module, serverName, templateName, serverProxyHostname, createdByUsername),
+		Module: s.withHealth(dbModuleToProto(module, serverName, templateName, serverProxyHostname, createdByUsername)),
 	}), nil
 }
 
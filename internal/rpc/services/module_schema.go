@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// The part of JSON Schema a template's config_schema may use to describe its env vars,
+// one property per variable
+type moduleConfigSchema struct {
+	Properties map[string]moduleSchemaProperty `json:"properties"`
+	Required   []string                        `json:"required"`
+}
+
+type moduleSchemaProperty struct {
+	Type    string `json:"type"` // string, integer, number or boolean, empty accepts anything
+	Enum    []any  `json:"enum"`
+	Default any    `json:"default"`
+}
+
+// Parses a template's config_schema, nil when the template has none
+func parseModuleConfigSchema(raw string) (*moduleConfigSchema, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var schema moduleConfigSchema
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		return nil, fmt.Errorf("config_schema is not valid JSON: %w", err)
+	}
+	for key, property := range schema.Properties {
+		switch property.Type {
+		case "", "string", "integer", "number", "boolean":
+		default:
+			return nil, fmt.Errorf("config_schema property %s has unsupported type %q", key, property.Type)
+		}
+	}
+	return &schema, nil
+}
+
+// Fills schema defaults into a module's env overrides and checks required keys, types and
+// enums. Values holding an alias are only resolved at container creation, so their type
+// isn't checked. Returns the env overrides to store.
+func applyModuleConfigSchema(rawSchema, envOverrides string) (string, error) {
+	env := map[string]string{}
+	if strings.TrimSpace(envOverrides) != "" {
+		if err := json.Unmarshal([]byte(envOverrides), &env); err != nil {
+			return "", errors.New("env_overrides must be a JSON object of string values")
+		}
+	}
+
+	schema, err := parseModuleConfigSchema(rawSchema)
+	if err != nil || schema == nil {
+		// A broken schema on an existing template shouldn't block its modules
+		return envOverrides, nil
+	}
+
+	filled := false
+	for key, property := range schema.Properties {
+		if _, ok := env[key]; !ok && property.Default != nil {
+			env[key] = schemaValueString(property.Default)
+			filled = true
+		}
+	}
+
+	var errs configFieldErrors
+	for _, key := range schema.Required {
+		if env[key] == "" {
+			errs.add(key, "is required")
+		}
+	}
+	for key, property := range schema.Properties {
+		value, ok := env[key]
+		if !ok || value == "" || strings.Contains(value, "{{") {
+			continue
+		}
+		if msg := checkSchemaValue(property, value); msg != "" {
+			errs.add(key, "%s", msg)
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+		return "", errs
+	}
+
+	if !filled {
+		return envOverrides, nil
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Why value doesn't satisfy property, empty when it does
+func checkSchemaValue(property moduleSchemaProperty, value string) string {
+	switch property.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number"
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be true or false"
+		}
+	}
+
+	if len(property.Enum) > 0 {
+		options := make([]string, len(property.Enum))
+		for i, option := range property.Enum {
+			options[i] = schemaValueString(option)
+		}
+		if !slices.Contains(options, value) {
+			return "must be one of " + strings.Join(options, ", ")
+		}
+	}
+	return ""
+}
+
+// Renders a JSON schema value the way it appears in an env var
+func schemaValueString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// Converts env override failures into InvalidArgument errors, carrying the offending keys
+func moduleEnvError(err error) error {
+	var fieldErrs configFieldErrors
+	if !errors.As(err, &fieldErrs) {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	msgs := make([]string, 0, len(fieldErrs))
+	for _, field := range fieldErrs {
+		msgs = append(msgs, field.Key+": "+field.Message)
+	}
+	cerr := connect.NewError(connect.CodeInvalidArgument, errors.New("invalid env overrides: "+strings.Join(msgs, "; ")))
+	if detail, detailErr := connect.NewErrorDetail(&v1.ConfigValidationError{Fields: fieldErrs}); detailErr == nil {
+		cerr.AddDetail(detail)
+	}
+	return cerr
+}
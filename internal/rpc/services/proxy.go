@@ -1,8 +1,11 @@
 package services
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
 
 	"connectrpc.com/connect"
@@ -16,6 +19,9 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Minecraft's protocol has no TLS, so only web listeners can terminate it
+var errTLSListenerProtocol = fmt.Errorf("tls is only supported on http listeners, not raw game traffic")
+
 // Compile-time check that ProxyService implements the interface
 var _ discopanelv1connect.ProxyServiceHandler = (*ProxyService)(nil)
 
@@ -90,16 +96,7 @@ func (s *ProxyService) GetProxyStatus(ctx context.Context, req *connect.Request[
 	listenPorts := make([]int32, len(listeners))
 	for i, l := range listeners {
 		listenPorts[i] = int32(l.Port)
-		protoListeners[i] = &v1.ProxyListener{
-			Id:          l.ID,
-			Name:        l.Name,
-			Description: l.Description,
-			Port:        int32(l.Port),
-			Enabled:     l.Enabled,
-			IsDefault:   l.IsDefault,
-			CreatedAt:   timestamppb.New(l.CreatedAt),
-			UpdatedAt:   timestamppb.New(l.UpdatedAt),
-		}
+		protoListeners[i] = dbProxyListenerToProto(l)
 	}
 
 	// Primary port
@@ -111,10 +108,12 @@ func (s *ProxyService) GetProxyStatus(ctx context.Context, req *connect.Request[
 	// Get running status and active routes count
 	running := false
 	activeRoutes := int32(0)
+	connectionLogging := s.config.Proxy.ConnectionLogging
 	if s.proxyManager != nil {
 		running = s.proxyManager.IsRunning()
 		routes := s.proxyManager.GetRoutes()
 		activeRoutes = int32(len(routes))
+		connectionLogging = s.proxyManager.ConnectionLogging()
 	}
 
 	return connect.NewResponse(&v1.GetProxyStatusResponse{
@@ -125,18 +124,53 @@ func (s *ProxyService) GetProxyStatus(ctx context.Context, req *connect.Request[
 		ListenPort:   primaryPort,
 		Running:      running,
 		ActiveRoutes: activeRoutes,
+
+		ConnectionLogging: connectionLogging,
 	}), nil
 }
 
+// Refuses proxy routing on a remote Docker host. The proxy dials servers by container IP,
+// which the remote daemon keeps on a network of its own.
+func remoteProxyError(dockerClient *docker.Client) error {
+	if dockerClient != nil && dockerClient.IsRemote() {
+		return connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("the proxy can't reach servers on a remote Docker host, use direct ports"))
+	}
+	return nil
+}
+
 // UpdateProxyConfig updates proxy configuration
 func (s *ProxyService) UpdateProxyConfig(ctx context.Context, req *connect.Request[v1.UpdateProxyConfigRequest]) (*connect.Response[v1.UpdateProxyConfigResponse], error) {
 	msg := req.Msg
 
+	baseURL := strings.Trim(strings.TrimSpace(msg.BaseUrl), ".")
+	if baseURL != "" {
+		normalized, err := proxy.NormalizeHostname(baseURL)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid base url: %w", err))
+		}
+		baseURL = normalized
+	}
+	oldBaseURL := s.config.Proxy.BaseURL
+
+	if msg.Enabled {
+		if err := remoteProxyError(s.docker); err != nil {
+			return nil, err
+		}
+	}
+
+	if msg.ConnectionLogging != nil {
+		switch msg.GetConnectionLogging() {
+		case proxy.ConnectionLoggingOff, proxy.ConnectionLoggingErrors, proxy.ConnectionLoggingAll:
+		default:
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("connection logging must be off, errors or all"))
+		}
+	}
+
 	// Save to database
 	proxyConfig := &storage.ProxyConfig{
 		ID:      "default",
 		Enabled: msg.Enabled,
-		BaseURL: msg.BaseUrl,
+		BaseURL: baseURL,
 	}
 
 	if err := s.store.SaveProxyConfig(ctx, proxyConfig); err != nil {
@@ -146,9 +180,21 @@ func (s *ProxyService) UpdateProxyConfig(ctx context.Context, req *connect.Reque
 
 	// Update in-memory configuration
 	s.config.Proxy.Enabled = msg.Enabled
-	s.config.Proxy.BaseURL = msg.BaseUrl
+	s.config.Proxy.BaseURL = baseURL
+
+	s.log.Info("Proxy configuration saved to database: enabled=%v, base_url=%v", msg.Enabled, baseURL)
+
+	// Not persisted, a restart goes back to proxy.connection_logging
+	if msg.ConnectionLogging != nil && s.proxyManager != nil {
+		if err := s.proxyManager.SetConnectionLogging(msg.GetConnectionLogging()); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
 
-	s.log.Info("Proxy configuration saved to database: enabled=%v, base_url=%v", msg.Enabled, msg.BaseUrl)
+	resuffixed := 0
+	if baseURL != oldBaseURL {
+		resuffixed = s.rebaseServerHostnames(ctx, oldBaseURL, baseURL, msg.ResuffixServers)
+	}
 
 	// If enabling proxy, ensure a default listener exists and start if not running
 	if msg.Enabled && s.proxyManager != nil {
@@ -178,9 +224,79 @@ func (s *ProxyService) UpdateProxyConfig(ctx context.Context, req *connect.Reque
 		ListenPort:   statusResp.Msg.ListenPort,
 		Running:      statusResp.Msg.Running,
 		ActiveRoutes: statusResp.Msg.ActiveRoutes,
+
+		ResuffixedServers: int32(resuffixed),
+		ConnectionLogging: statusResp.Msg.ConnectionLogging,
 	}), nil
 }
 
+// Re-splits server hostnames against a new base URL. With resuffix set, servers that were
+// under the old base URL move under the new one, keeping their subdomain. Returns how many moved.
+func (s *ProxyService) rebaseServerHostnames(ctx context.Context, oldBaseURL, baseURL string, resuffix bool) int {
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers for base URL change: %v", err)
+		return 0
+	}
+	taken := make(map[string]bool)
+	for _, server := range servers {
+		taken[server.ProxyHostname] = true
+	}
+
+	moved := 0
+	for _, server := range servers {
+		if server.ProxyHostname == "" {
+			continue
+		}
+		oldHostname := server.ProxyHostname
+		hostname := oldHostname
+		// Servers created before subdomains were stored are split against the old base URL here
+		_, oldSubdomain, _ := proxy.ResolveHostname(oldHostname, oldBaseURL, false)
+		if resuffix && oldSubdomain != "" && baseURL != "" {
+			target := oldSubdomain + "." + baseURL
+			if _, err := proxy.NormalizeHostname(target); err != nil {
+				s.log.Warn("Not moving server %s to the new base URL: %v", server.Name, err)
+			} else if taken[target] {
+				s.log.Warn("Not moving server %s to %s, the hostname is already in use", server.Name, target)
+			} else {
+				hostname = target
+			}
+		}
+
+		_, subdomain, err := proxy.ResolveHostname(hostname, baseURL, false)
+		if err != nil {
+			subdomain = ""
+		}
+		if hostname == oldHostname && subdomain == server.ProxySubdomain {
+			continue
+		}
+
+		server.ProxyHostname = hostname
+		server.ProxySubdomain = subdomain
+		if err := s.store.UpdateServer(ctx, server); err != nil {
+			s.log.Error("Failed to update hostname of server %s: %v", server.Name, err)
+			continue
+		}
+		if hostname == oldHostname {
+			continue
+		}
+
+		delete(taken, oldHostname)
+		taken[hostname] = true
+		moved++
+		s.log.Info("Moved server %s from %s to %s", server.Name, oldHostname, hostname)
+		if s.proxyManager != nil {
+			if err := s.proxyManager.RemoveRouteByHostname(oldHostname, server.ProxyListenerID); err != nil {
+				s.log.Error("Failed to remove old proxy route: %v", err)
+			}
+			if err := s.proxyManager.UpdateServerRoute(server); err != nil {
+				s.log.Error("Failed to update proxy route: %v", err)
+			}
+		}
+	}
+	return moved
+}
+
 // GetProxyListeners gets proxy listeners
 func (s *ProxyService) GetProxyListeners(ctx context.Context, req *connect.Request[v1.GetProxyListenersRequest]) (*connect.Response[v1.GetProxyListenersResponse], error) {
 	listeners, err := s.store.GetProxyListeners(ctx)
@@ -195,25 +311,16 @@ func (s *ProxyService) GetProxyListeners(ctx context.Context, req *connect.Reque
 	// Convert to proto format with server count
 	protoListeners := make([]*v1.ProxyListenerWithCount, len(listeners))
 	for i, listener := range listeners {
-		// Count servers using this listener
+		// Count servers using this listener (UDP listeners serve only their target)
 		count := int32(0)
 		for _, server := range servers {
-			if server.ProxyListenerID == listener.ID {
+			if server.ProxyListenerID == listener.ID || (listener.Protocol == storage.ListenerProtocolUDP && server.ID == listener.TargetServerID) {
 				count++
 			}
 		}
 
 		protoListeners[i] = &v1.ProxyListenerWithCount{
-			Listener: &v1.ProxyListener{
-				Id:          listener.ID,
-				Name:        listener.Name,
-				Description: listener.Description,
-				Port:        int32(listener.Port),
-				Enabled:     listener.Enabled,
-				IsDefault:   listener.IsDefault,
-				CreatedAt:   timestamppb.New(listener.CreatedAt),
-				UpdatedAt:   timestamppb.New(listener.UpdatedAt),
-			},
+			Listener:    dbProxyListenerToProto(listener),
 			ServerCount: count,
 		}
 	}
@@ -232,6 +339,14 @@ func (s *ProxyService) CreateProxyListener(ctx context.Context, req *connect.Req
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid port number"))
 	}
 
+	protocol, err := parseListenerProtocol(msg.Protocol)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if msg.TlsMode != "" && protocol != storage.ListenerProtocolHTTP {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errTLSListenerProtocol)
+	}
+
 	// Check if port is already in use
 	existing, _ := s.store.GetProxyListenerByPort(ctx, int(msg.Port))
 	if existing != nil {
@@ -252,6 +367,20 @@ func (s *ProxyService) CreateProxyListener(ctx context.Context, req *connect.Req
 		Port:        int(msg.Port),
 		Enabled:     msg.Enabled,
 		IsDefault:   msg.IsDefault,
+		Protocol:    protocol,
+	}
+	switch protocol {
+	case storage.ListenerProtocolUDP:
+		listener.TargetServerID = msg.TargetServerId
+		listener.TargetPort = int(msg.TargetPort)
+	case storage.ListenerProtocolHTTP:
+		listener.TLSMode = storage.ListenerTLSMode(msg.TlsMode)
+		listener.TLSCertPath = msg.TlsCertPath
+		listener.TLSKeyPath = msg.TlsKeyPath
+	}
+
+	if err := s.validateListener(ctx, listener); err != nil {
+		return nil, err
 	}
 
 	if err := s.store.CreateProxyListener(ctx, listener); err != nil {
@@ -268,16 +397,7 @@ func (s *ProxyService) CreateProxyListener(ctx context.Context, req *connect.Req
 	}
 
 	return connect.NewResponse(&v1.CreateProxyListenerResponse{
-		Listener: &v1.ProxyListener{
-			Id:          listener.ID,
-			Name:        listener.Name,
-			Description: listener.Description,
-			Port:        int32(listener.Port),
-			Enabled:     listener.Enabled,
-			IsDefault:   listener.IsDefault,
-			CreatedAt:   timestamppb.New(listener.CreatedAt),
-			UpdatedAt:   timestamppb.New(listener.UpdatedAt),
-		},
+		Listener: dbProxyListenerToProto(listener),
 	}), nil
 }
 
@@ -290,11 +410,59 @@ func (s *ProxyService) UpdateProxyListener(ctx context.Context, req *connect.Req
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("listener not found"))
 	}
 
+	oldProtocol := listener.Protocol
+	oldTLS := [3]string{string(listener.TLSMode), listener.TLSCertPath, listener.TLSKeyPath}
+	if msg.Protocol != "" {
+		protocol, err := parseListenerProtocol(msg.Protocol)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		listener.Protocol = protocol
+	}
+
 	// Update fields
 	listener.Name = msg.Name
 	listener.Description = msg.Description
 	listener.Enabled = msg.Enabled
 	listener.IsDefault = msg.IsDefault
+	if listener.Protocol == storage.ListenerProtocolUDP {
+		if msg.TargetServerId != "" {
+			listener.TargetServerID = msg.TargetServerId
+		}
+		if msg.TargetPort != 0 {
+			listener.TargetPort = int(msg.TargetPort)
+		}
+	} else {
+		listener.TargetServerID = ""
+		listener.TargetPort = 0
+	}
+	if msg.TlsMode != "" && listener.Protocol != storage.ListenerProtocolHTTP {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errTLSListenerProtocol)
+	}
+	if listener.Protocol == storage.ListenerProtocolHTTP {
+		listener.TLSMode = storage.ListenerTLSMode(msg.TlsMode)
+		listener.TLSCertPath = msg.TlsCertPath
+		listener.TLSKeyPath = msg.TlsKeyPath
+	} else {
+		listener.TLSMode = storage.ListenerTLSNone
+		listener.TLSCertPath = ""
+		listener.TLSKeyPath = ""
+	}
+
+	if err := s.validateListener(ctx, listener); err != nil {
+		return nil, err
+	}
+
+	// Java servers routed through a listener would lose their route if it stopped carrying game traffic
+	if isJavaListener(&storage.ProxyListener{Protocol: oldProtocol}) && !isJavaListener(listener) {
+		servers, _ := s.store.ListServers(ctx)
+		for _, server := range servers {
+			if server.ProxyListenerID == listener.ID {
+				return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("cannot switch listener to %s: servers are using it", listener.Protocol))
+			}
+		}
+	}
+	tlsChanged := oldTLS != [3]string{string(listener.TLSMode), listener.TLSCertPath, listener.TLSKeyPath}
 
 	// If setting as default, unset other defaults
 	if msg.IsDefault {
@@ -319,8 +487,8 @@ func (s *ProxyService) UpdateProxyListener(ctx context.Context, req *connect.Req
 
 	// Handle proxy manager updates if running
 	if s.proxyManager != nil {
-		// If port changed, remove old and add new
-		if oldPort != listener.Port {
+		// If port, protocol or certificates changed, remove old and add new
+		if oldPort != listener.Port || oldProtocol != listener.Protocol || tlsChanged {
 			s.proxyManager.RemoveListener(oldPort)
 			if listener.Enabled {
 				if err := s.proxyManager.AddListener(listener); err != nil {
@@ -333,20 +501,14 @@ func (s *ProxyService) UpdateProxyListener(ctx context.Context, req *connect.Req
 		} else if listener.Enabled {
 			// If enabled and port didn't change, try to add it (in case it wasn't there)
 			s.proxyManager.AddListener(listener)
+			if listener.Protocol == storage.ListenerProtocolUDP {
+				s.proxyManager.RefreshServerListeners(listener.TargetServerID)
+			}
 		}
 	}
 
 	return connect.NewResponse(&v1.UpdateProxyListenerResponse{
-		Listener: &v1.ProxyListener{
-			Id:          listener.ID,
-			Name:        listener.Name,
-			Description: listener.Description,
-			Port:        int32(listener.Port),
-			Enabled:     listener.Enabled,
-			IsDefault:   listener.IsDefault,
-			CreatedAt:   timestamppb.New(listener.CreatedAt),
-			UpdatedAt:   timestamppb.New(listener.UpdatedAt),
-		},
+		Listener: dbProxyListenerToProto(listener),
 	}), nil
 }
 
@@ -424,6 +586,7 @@ func (s *ProxyService) GetServerRouting(ctx context.Context, req *connect.Reques
 		BaseUrl:           s.config.Proxy.BaseURL,
 		ListenPort:        listenPort,
 		CurrentRoute:      currentRoute,
+		ProxySubdomain:    server.ProxySubdomain,
 	}), nil
 }
 
@@ -441,10 +604,14 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 	oldProxyListenerID := server.ProxyListenerID
 
 	// Validate and normalize hostname
-	hostname := strings.TrimSpace(strings.ToLower(msg.ProxyHostname))
+	hostname := strings.TrimSpace(msg.ProxyHostname)
+	subdomain := ""
 	if hostname != "" {
-		if strings.Contains(hostname, " ") || strings.Contains(hostname, "://") {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid hostname format"))
+		if err := remoteProxyError(s.docker); err != nil {
+			return nil, err
+		}
+		if hostname, subdomain, err = proxy.ResolveHostname(hostname, s.config.Proxy.BaseURL, msg.UseBaseUrl); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
 		}
 
 		// Check for conflicts with other servers
@@ -461,6 +628,15 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 
 	// Determine new listener ID
 	listenerID := msg.ProxyListenerId
+	if listenerID != "" {
+		listener, err := s.store.GetProxyListener(ctx, listenerID)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("listener not found"))
+		}
+		if !isJavaListener(listener) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("only tcp listeners can route servers by hostname"))
+		}
+	}
 	if listenerID == "" && hostname != "" {
 		// If enabling proxy but no listener specified, use existing or get default
 		if oldProxyListenerID != "" {
@@ -470,7 +646,7 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 			listeners, err := s.store.GetProxyListeners(ctx)
 			if err == nil {
 				for _, l := range listeners {
-					if l.IsDefault && l.Enabled {
+					if l.IsDefault && l.Enabled && isJavaListener(l) {
 						listenerID = l.ID
 						break
 					}
@@ -478,7 +654,7 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 				// If no default, use first enabled listener
 				if listenerID == "" {
 					for _, l := range listeners {
-						if l.Enabled {
+						if l.Enabled && isJavaListener(l) {
 							listenerID = l.ID
 							break
 						}
@@ -503,6 +679,9 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 
 	// Container recreation needed if proxy mode changes OR listener changes while proxy is enabled
 	needsRecreation := proxyModeChanged || (listenerChanged && hostname != "" && oldProxyHostname != "")
+	if needsRecreation && server.Imported {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("recreate the imported server before changing its proxy mode"))
+	}
 
 	// Removes old route BEFORE updating server in DB and using its hostname
 	if hostnameChanged && oldProxyHostname != "" && s.proxyManager != nil {
@@ -513,8 +692,15 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 
 	// Update server fields
 	server.ProxyHostname = hostname
+	server.ProxySubdomain = subdomain
 	server.ProxyListenerID = listenerID
 
+	// Without auto recreation the container is left alone until the next start, restart or recreate
+	if needsRecreation && server.ContainerID != "" && !s.config.Docker.AutoRecreate {
+		server.RestartRequired = true
+		needsRecreation = false
+	}
+
 	// Handle container recreation if needed
 	if needsRecreation && server.ContainerID != "" && s.docker != nil {
 		serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
@@ -528,6 +714,7 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 			s.log.Error("Failed to recreate container for proxy change: %v", err)
 			if result != nil && result.NewContainerID != "" {
 				server.ContainerID = result.NewContainerID
+				server.PortBindingWarning = result.PortBindingWarning
 				server.Status = storage.StatusError
 			} else {
 				server.Status = storage.StatusError
@@ -539,6 +726,8 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 			}
 		} else {
 			server.ContainerID = result.NewContainerID
+			server.PortBindingWarning = result.PortBindingWarning
+			server.RestartRequired = false
 			if result.WasRunning {
 				server.Status = storage.StatusRunning
 			} else {
@@ -568,3 +757,302 @@ func (s *ProxyService) UpdateServerRouting(ctx context.Context, req *connect.Req
 		ProxyListenerId: listenerID,
 	}), nil
 }
+
+// MoveServerListener moves a proxied server to another listener. Only its route moves, the
+// container keeps listening on its internal port and isn't recreated.
+func (s *ProxyService) MoveServerListener(ctx context.Context, req *connect.Request[v1.MoveServerListenerRequest]) (*connect.Response[v1.MoveServerListenerResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if server.ProxyHostname == "" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server has no proxy hostname, set one before choosing a listener"))
+	}
+
+	listener, err := s.store.GetProxyListener(ctx, msg.ListenerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("listener not found"))
+	}
+	if !isJavaListener(listener) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("only tcp listeners can route servers by hostname"))
+	}
+	if !listener.Enabled {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("listener %s is disabled", listener.Name))
+	}
+
+	if listener.ID != server.ProxyListenerID {
+		servers, err := s.store.ListServers(ctx)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check hostname conflicts"))
+		}
+		for _, srv := range servers {
+			if srv.ID != server.ID && srv.ProxyListenerID == listener.ID && srv.ProxyHostname == server.ProxyHostname {
+				return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("hostname %s is already routed on listener %s", server.ProxyHostname, listener.Name))
+			}
+		}
+
+		oldListenerID := server.ProxyListenerID
+		server.ProxyListenerID = listener.ID
+		server.ProxyPort = listener.Port
+		if err := s.store.UpdateServer(ctx, server); err != nil {
+			s.log.Error("Failed to move server %s to listener %s: %v", server.Name, listener.Name, err)
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update server"))
+		}
+
+		if s.proxyManager != nil {
+			if err := s.proxyManager.RemoveRouteByHostname(server.ProxyHostname, oldListenerID); err != nil {
+				s.log.Error("Failed to remove old proxy route: %v", err)
+			}
+			if err := s.proxyManager.UpdateServerRoute(server); err != nil {
+				s.log.Error("Failed to update proxy route: %v", err)
+			}
+		}
+		s.log.Info("Moved server %s to listener %s (port %d)", server.Name, listener.Name, listener.Port)
+	}
+
+	return connect.NewResponse(&v1.MoveServerListenerResponse{
+		Hostname:        server.ProxyHostname,
+		ProxyListenerId: listener.ID,
+		ProxyPort:       int32(listener.Port),
+	}), nil
+}
+
+// GetProxyConnections returns recently proxied connections and traffic counters
+func (s *ProxyService) GetProxyConnections(ctx context.Context, req *connect.Request[v1.GetProxyConnectionsRequest]) (*connect.Response[v1.GetProxyConnectionsResponse], error) {
+	if s.proxyManager == nil {
+		return connect.NewResponse(&v1.GetProxyConnectionsResponse{Stats: &v1.ProxyConnectionStats{}}), nil
+	}
+
+	records, stats, clients := s.proxyManager.Connections()
+
+	msg := req.Msg
+	connections := make([]*v1.ProxyConnection, 0, len(records))
+	for i := range records {
+		rec := &records[i]
+		if msg.ServerId != "" && rec.ServerID != msg.ServerId {
+			continue
+		}
+		if msg.UnroutedOnly && rec.Outcome != proxy.OutcomeNoRoute {
+			continue
+		}
+		connections = append(connections, dbProxyConnectionToProto(rec))
+		if msg.Limit > 0 && len(connections) >= int(msg.Limit) {
+			break
+		}
+	}
+
+	slices.SortFunc(clients, func(a, b proxy.ClientStats) int {
+		return cmp.Compare(b.Connections, a.Connections)
+	})
+	protoClients := make([]*v1.ProxyClientStats, len(clients))
+	for i, client := range clients {
+		protoClients[i] = &v1.ProxyClientStats{
+			Ip:          client.IP,
+			Connections: client.Connections,
+			NoRoute:     client.NoRoute,
+			BytesIn:     client.BytesIn,
+			BytesOut:    client.BytesOut,
+			LastSeen:    timestamppb.New(client.LastSeen),
+		}
+	}
+
+	return connect.NewResponse(&v1.GetProxyConnectionsResponse{
+		Connections: connections,
+		Stats: &v1.ProxyConnectionStats{
+			Total:             stats.Total,
+			Active:            stats.Active,
+			Routed:            stats.Routed,
+			NoRoute:           stats.NoRoute,
+			BackendErrors:     stats.BackendErrors,
+			HandshakeFailures: stats.HandshakeFails,
+			BytesIn:           stats.BytesIn,
+			BytesOut:          stats.BytesOut,
+		},
+		Clients: protoClients,
+	}), nil
+}
+
+// ExportProxyConfig renders the current routing table as an edge proxy config
+func (s *ProxyService) ExportProxyConfig(ctx context.Context, req *connect.Request[v1.ExportProxyConfigRequest]) (*connect.Response[v1.ExportProxyConfigResponse], error) {
+	var format, filename string
+	switch req.Msg.Format {
+	case v1.ProxyExportFormat_PROXY_EXPORT_FORMAT_HAPROXY:
+		format, filename = proxy.ExportFormatHAProxy, "discopanel-haproxy.cfg"
+	case v1.ProxyExportFormat_PROXY_EXPORT_FORMAT_NGINX:
+		format, filename = proxy.ExportFormatNginx, "discopanel-nginx-stream.conf"
+	default:
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("format must be haproxy or nginx"))
+	}
+
+	listeners, err := s.store.GetProxyListeners(ctx)
+	if err != nil {
+		s.log.Error("Failed to get proxy listeners: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get proxy listeners"))
+	}
+
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list servers"))
+	}
+
+	// Only enabled hostname-routed listeners and running servers with a hostname are exported
+	exportListeners := make([]proxy.ExportListener, 0, len(listeners))
+	routeCount := 0
+	for _, listener := range listeners {
+		if !listener.Enabled || !isJavaListener(listener) {
+			continue
+		}
+
+		exportListener := proxy.ExportListener{Name: listener.Name, Port: listener.Port}
+		for _, server := range servers {
+			if server.ProxyListenerID != listener.ID || server.ProxyHostname == "" || server.ContainerID == "" {
+				continue
+			}
+			if server.Status != storage.StatusRunning && server.Status != storage.StatusStarting {
+				continue
+			}
+
+			// Container names resolve on the Docker network and survive restarts, IPs don't
+			backendHost := fmt.Sprintf("discopanel-server-%s", server.ID)
+			if req.Msg.ResolveIps && s.docker != nil {
+				ip, err := s.docker.GetModuleContainerIP(ctx, server.ContainerID)
+				if err != nil {
+					s.log.Warn("Failed to resolve container IP for server %s: %v", server.Name, err)
+				} else {
+					backendHost = ip
+				}
+			}
+
+			exportListener.Routes = append(exportListener.Routes, proxy.ExportRoute{
+				ServerID:    server.ID,
+				ServerName:  server.Name,
+				Hostname:    server.ProxyHostname,
+				BackendHost: backendHost,
+				BackendPort: docker.DefaultMinecraftPort,
+			})
+			routeCount++
+		}
+		exportListeners = append(exportListeners, exportListener)
+	}
+
+	content, err := proxy.RenderExport(format, exportListeners)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connect.NewResponse(&v1.ExportProxyConfigResponse{
+		Content:    content,
+		Filename:   filename,
+		RouteCount: int32(routeCount),
+	}), nil
+}
+
+// parseListenerProtocol normalizes a listener protocol, defaulting to tcp
+func parseListenerProtocol(protocol string) (storage.ListenerProtocol, error) {
+	switch storage.ListenerProtocol(strings.ToLower(strings.TrimSpace(protocol))) {
+	case "", storage.ListenerProtocolTCP:
+		return storage.ListenerProtocolTCP, nil
+	case storage.ListenerProtocolUDP:
+		return storage.ListenerProtocolUDP, nil
+	case storage.ListenerProtocolHTTP:
+		return storage.ListenerProtocolHTTP, nil
+	default:
+		return "", fmt.Errorf("invalid listener protocol %q: must be tcp, udp or http", protocol)
+	}
+}
+
+// isJavaListener reports whether a listener carries Java connections routed by hostname
+func isJavaListener(listener *storage.ProxyListener) bool {
+	return listener.Protocol == "" || listener.Protocol == storage.ListenerProtocolTCP
+}
+
+// validateListener checks protocol specific settings: the Bedrock target of UDP
+// listeners and the TLS config of HTTP listeners
+func (s *ProxyService) validateListener(ctx context.Context, listener *storage.ProxyListener) error {
+	if !isJavaListener(listener) && listener.IsDefault {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%s listeners cannot be the default listener", listener.Protocol))
+	}
+
+	if listener.Protocol == storage.ListenerProtocolHTTP {
+		switch listener.TLSMode {
+		case storage.ListenerTLSNone, storage.ListenerTLSACME:
+			listener.TLSCertPath, listener.TLSKeyPath = "", ""
+		case storage.ListenerTLSFiles:
+			if listener.TLSCertPath == "" {
+				return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("tls_cert_path is required for tls mode files"))
+			}
+			info, err := os.Stat(listener.TLSCertPath)
+			if err != nil {
+				return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("tls certificate path not readable: %w", err))
+			}
+			if !info.IsDir() && listener.TLSKeyPath == "" {
+				return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("tls_key_path is required when tls_cert_path is a file"))
+			}
+		default:
+			return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid tls mode %q: must be empty, files or acme", listener.TLSMode))
+		}
+		return nil
+	}
+
+	if listener.Protocol != storage.ListenerProtocolUDP {
+		return nil
+	}
+
+	if listener.TargetPort < 0 || listener.TargetPort > 65535 {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid target port"))
+	}
+	if listener.TargetPort == 0 {
+		listener.TargetPort = storage.DefaultBedrockPort
+	}
+	if listener.TargetServerID == "" {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("udp listeners require a target server"))
+	}
+	if _, err := s.store.GetServer(ctx, listener.TargetServerID); err != nil {
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("target server not found"))
+	}
+
+	return nil
+}
+
+func dbProxyListenerToProto(listener *storage.ProxyListener) *v1.ProxyListener {
+	return &v1.ProxyListener{
+		Id:             listener.ID,
+		Name:           listener.Name,
+		Description:    listener.Description,
+		Port:           int32(listener.Port),
+		Enabled:        listener.Enabled,
+		IsDefault:      listener.IsDefault,
+		CreatedAt:      timestamppb.New(listener.CreatedAt),
+		UpdatedAt:      timestamppb.New(listener.UpdatedAt),
+		Protocol:       string(listener.Protocol),
+		TargetServerId: listener.TargetServerID,
+		TargetPort:     int32(listener.TargetPort),
+		TlsMode:        string(listener.TLSMode),
+		TlsCertPath:    listener.TLSCertPath,
+		TlsKeyPath:     listener.TLSKeyPath,
+	}
+}
+
+// dbProxyConnectionToProto converts a connection log record to proto
+func dbProxyConnectionToProto(rec *proxy.ConnectionRecord) *v1.ProxyConnection {
+	conn := &v1.ProxyConnection{
+		Id:           rec.ID,
+		StartedAt:    timestamppb.New(rec.StartedAt),
+		ListenerPort: int32(rec.ListenerPort),
+		Hostname:     rec.Hostname,
+		ServerId:     rec.ServerID,
+		ClientIp:     rec.ClientIP,
+		Intent:       rec.Intent,
+		BytesIn:      rec.BytesIn,
+		BytesOut:     rec.BytesOut,
+		Outcome:      rec.Outcome,
+		Backend:      rec.Backend,
+	}
+	if !rec.EndedAt.IsZero() {
+		conn.EndedAt = timestamppb.New(rec.EndedAt)
+	}
+	return conn
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/nickheyer/discopanel/internal/auth"
+	storage "github.com/nickheyer/discopanel/internal/db"
+)
+
+// Checks that adding servers, memory and modules keeps the calling user within their quota
+func checkCallerQuota(ctx context.Context, store *storage.Store, add storage.QuotaUsage) error {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil
+	}
+	return checkQuota(ctx, store, user.ID, user.Roles, add)
+}
+
+// Checks a change to something the given user created, e.g. raising a server's memory
+func checkCreatorQuota(ctx context.Context, store *storage.Store, userID string, add storage.QuotaUsage) error {
+	if userID == "" {
+		return nil
+	}
+	roles, err := store.GetUserRoleNames(ctx, userID)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check quota"))
+	}
+	return checkQuota(ctx, store, userID, roles, add)
+}
+
+func checkQuota(ctx context.Context, store *storage.Store, userID string, roles []string, add storage.QuotaUsage) error {
+	quota, err := store.GetUserQuota(ctx, userID, roles)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check quota"))
+	}
+	if quota.MaxServers == 0 && quota.MaxMemory == 0 && quota.MaxModules == 0 {
+		return nil
+	}
+
+	usage, err := store.GetUserQuotaUsage(ctx, userID)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check quota"))
+	}
+
+	if add.Servers > 0 && quota.MaxServers > 0 && usage.Servers+add.Servers > quota.MaxServers {
+		return connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("server quota exceeded: limit is %d servers", quota.MaxServers))
+	}
+	if add.Modules > 0 && quota.MaxModules > 0 && usage.Modules+add.Modules > quota.MaxModules {
+		return connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("module quota exceeded: limit is %d modules", quota.MaxModules))
+	}
+	if add.Memory > 0 && quota.MaxMemory > 0 && usage.Memory+add.Memory > quota.MaxMemory {
+		return connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("memory quota exceeded: %d of %d MB in use, %d MB requested", usage.Memory, quota.MaxMemory, add.Memory))
+	}
+	return nil
+}
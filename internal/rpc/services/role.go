@@ -79,6 +79,9 @@ func (s *RoleService) CreateRole(ctx context.Context, req *connect.Request[v1.Cr
 		Description: msg.Description,
 		IsSystem:    false,
 		IsDefault:   msg.IsDefault,
+		MaxServers:  max(int(msg.MaxServers), 0),
+		MaxMemory:   max(int(msg.MaxMemory), 0),
+		MaxModules:  max(int(msg.MaxModules), 0),
 	}
 
 	if err := s.store.CreateRole(ctx, role); err != nil {
@@ -113,7 +116,8 @@ func (s *RoleService) UpdateRole(ctx context.Context, req *connect.Request[v1.Up
 		return nil, connect.NewError(connect.CodeNotFound, errors.New("role not found"))
 	}
 
-	if role.IsSystem {
+	// System roles keep their identity, but admins can still limit them
+	if role.IsSystem && (msg.Name != nil || msg.Description != nil || msg.IsDefault != nil) {
 		return nil, connect.NewError(connect.CodePermissionDenied, errors.New("cannot modify system role"))
 	}
 
@@ -126,6 +130,15 @@ func (s *RoleService) UpdateRole(ctx context.Context, req *connect.Request[v1.Up
 	if msg.IsDefault != nil {
 		role.IsDefault = *msg.IsDefault
 	}
+	if msg.MaxServers != nil {
+		role.MaxServers = max(int(*msg.MaxServers), 0)
+	}
+	if msg.MaxMemory != nil {
+		role.MaxMemory = max(int(*msg.MaxMemory), 0)
+	}
+	if msg.MaxModules != nil {
+		role.MaxModules = max(int(*msg.MaxModules), 0)
+	}
 
 	if err := s.store.UpdateRole(ctx, role); err != nil {
 		s.log.Error("Failed to update role: %v", err)
@@ -404,6 +417,9 @@ func dbRoleToProto(role *storage.Role, perms []rbac.Permission) *v1.Role {
 		Permissions: protoPerms,
 		CreatedAt:   timestamppb.New(role.CreatedAt),
 		UpdatedAt:   timestamppb.New(role.UpdatedAt),
+		MaxServers:  int32(role.MaxServers),
+		MaxMemory:   int32(role.MaxMemory),
+		MaxModules:  int32(role.MaxModules),
 	}
 }
 
@@ -10,21 +10,27 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/auth"
 	"github.com/nickheyer/discopanel/internal/command"
 	"github.com/nickheyer/discopanel/internal/config"
 	storage "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/docker"
 	"github.com/nickheyer/discopanel/internal/events"
+	"github.com/nickheyer/discopanel/internal/indexers"
 	"github.com/nickheyer/discopanel/internal/metrics"
 	"github.com/nickheyer/discopanel/internal/minecraft"
 	"github.com/nickheyer/discopanel/internal/module"
 	"github.com/nickheyer/discopanel/internal/proxy"
+	"github.com/nickheyer/discopanel/internal/rbac"
+	"github.com/nickheyer/discopanel/internal/scheduler"
 	"github.com/nickheyer/discopanel/pkg/files"
 	"github.com/nickheyer/discopanel/pkg/logger"
 	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
@@ -46,11 +52,19 @@ type ServerService struct {
 	logStreamer      *logger.LogStreamer
 	metricsCollector *metrics.Collector
 	moduleManager    *module.Manager
+	scheduler        *scheduler.Scheduler
 	bus              *events.Bus
+	enforcer         *rbac.Enforcer
+
+	countdownMu sync.Mutex
+	countdowns  map[string]*shutdownCountdown // Server ID to its running pre-stop countdown
+
+	bulkMu   sync.Mutex
+	bulkJobs map[string]*bulkOperation // Operation ID to the bulk operation, finished ones kept a while for polling
 }
 
 // NewServerService creates a new server service
-func NewServerService(store *storage.Store, docker *docker.Client, sender *command.Sender, config *config.Config, proxy *proxy.Manager, logStreamer *logger.LogStreamer, metricsCollector *metrics.Collector, moduleManager *module.Manager, bus *events.Bus, log *logger.Logger) *ServerService {
+func NewServerService(store *storage.Store, docker *docker.Client, sender *command.Sender, config *config.Config, proxy *proxy.Manager, logStreamer *logger.LogStreamer, metricsCollector *metrics.Collector, moduleManager *module.Manager, sched *scheduler.Scheduler, bus *events.Bus, enforcer *rbac.Enforcer, log *logger.Logger) *ServerService {
 	return &ServerService{
 		store:            store,
 		docker:           docker,
@@ -61,7 +75,11 @@ func NewServerService(store *storage.Store, docker *docker.Client, sender *comma
 		logStreamer:      logStreamer,
 		metricsCollector: metricsCollector,
 		moduleManager:    moduleManager,
+		scheduler:        sched,
 		bus:              bus,
+		enforcer:         enforcer,
+		countdowns:       make(map[string]*shutdownCountdown),
+		bulkJobs:         make(map[string]*bulkOperation),
 	}
 }
 
@@ -81,10 +99,13 @@ func dbServerToProto(server *storage.Server) *v1.Server {
 		McVersion:       server.MCVersion,
 		Port:            int32(server.Port),
 		ProxyHostname:   server.ProxyHostname,
+		ProxySubdomain:  server.ProxySubdomain,
 		ProxyListenerId: server.ProxyListenerID,
 		ProxyPort:       int32(server.ProxyPort),
 		MaxPlayers:      int32(server.MaxPlayers),
 		Memory:          int32(server.Memory),
+		CpuLimit:        server.CPULimit,
+		SwapLimit:       int32(server.SwapLimit),
 		DataPath:        server.DataPath,
 		ContainerId:     server.ContainerID,
 		JavaVersion:     int32(javaVersion),
@@ -97,6 +118,7 @@ func dbServerToProto(server *storage.Server) *v1.Server {
 		DiskUsage:       server.DiskUsage,
 		DiskTotal:       server.DiskTotal,
 		WorldSize:       server.WorldSize,
+		CrashDumps:      int32(server.CrashDumps),
 		PlayersOnline:   int32(server.PlayersOnline),
 		Tps:             server.TPS,
 		AdditionalPorts: server.AdditionalPorts,
@@ -112,6 +134,26 @@ func dbServerToProto(server *storage.Server) *v1.Server {
 		PlayerSample:    server.PlayerSample,
 		MaxPlayersSlp:   int32(server.MaxPlayersSLP),
 		Favicon:         server.Favicon,
+
+		ModpackId:        server.ModpackID,
+		ModpackVersionId: server.ModpackVersionID,
+
+		DataVolume: server.DataVolume,
+		OwnerId:    server.OwnerID,
+		Imported:   server.Imported,
+
+		PortBindingWarning: server.PortBindingWarning,
+		RestartRequired:    server.RestartRequired,
+		LastError:          server.LastError,
+
+		RestartOnUnhealthy:    server.RestartOnUnhealthy,
+		UnhealthyRestartAfter: int32(server.UnhealthyRestartAfter),
+		MaxUnhealthyRestarts:  int32(server.MaxUnhealthyRestarts),
+
+		MemoryLimit:         int64(server.MemoryLimit),
+		MemoryTrend:         server.MemoryTrend,
+		MemoryFullInSeconds: int64(server.MemoryFullIn.Seconds()),
+		MemoryLeakWarning:   server.MemoryLeakWarning,
 	}
 
 	// Apply overrides
@@ -122,6 +164,7 @@ func dbServerToProto(server *storage.Server) *v1.Server {
 
 	// Map status
 	protoServer.Status = dbStatusToProto(server.Status)
+	protoServer.StartupPhase = dbStartupPhaseToProto(server.StartupPhase)
 
 	// Map optional last started
 	if server.LastStarted != nil {
@@ -131,6 +174,37 @@ func dbServerToProto(server *storage.Server) *v1.Server {
 	return protoServer
 }
 
+// dbStartupPhaseToProto converts a log-derived startup phase to proto
+func dbStartupPhaseToProto(phase storage.StartupPhase) v1.StartupPhase {
+	switch phase {
+	case storage.PhasePullingImage:
+		return v1.StartupPhase_STARTUP_PHASE_PULLING_IMAGE
+	case storage.PhaseInstalling:
+		return v1.StartupPhase_STARTUP_PHASE_INSTALLING
+	case storage.PhaseLaunching:
+		return v1.StartupPhase_STARTUP_PHASE_LAUNCHING
+	case storage.PhaseLoadingWorld:
+		return v1.StartupPhase_STARTUP_PHASE_LOADING_WORLD
+	case storage.PhaseReady:
+		return v1.StartupPhase_STARTUP_PHASE_READY
+	default:
+		return v1.StartupPhase_STARTUP_PHASE_UNSPECIFIED
+	}
+}
+
+// Attaches the log-derived startup phase while a server is booting or pulling its image
+func (s *ServerService) applyStartupPhase(server *storage.Server) {
+	phase := s.docker.StartupPhase(server.ID, server.ContainerID)
+	switch server.Status {
+	case storage.StatusStarting, storage.StatusRunning, storage.StatusUnhealthy:
+	default:
+		if phase != storage.PhasePullingImage {
+			phase = ""
+		}
+	}
+	server.StartupPhase = phase
+}
+
 // dbModLoaderToProto converts database mod loader to proto
 func dbModLoaderToProto(loader storage.ModLoader) v1.ModLoader {
 	switch loader {
@@ -146,6 +220,8 @@ func dbModLoaderToProto(loader storage.ModLoader) v1.ModLoader {
 		return v1.ModLoader_MOD_LOADER_PAPER
 	case storage.ModLoaderFolia:
 		return v1.ModLoader_MOD_LOADER_FOLIA
+	case storage.ModLoaderFTBA:
+		return v1.ModLoader_MOD_LOADER_FTBA
 	case storage.ModLoaderSpigot:
 		return v1.ModLoader_MOD_LOADER_SPIGOT
 	case storage.ModLoaderBukkit:
@@ -186,6 +262,8 @@ func protoModLoaderToDB(loader v1.ModLoader) storage.ModLoader {
 		return storage.ModLoaderPaper
 	case v1.ModLoader_MOD_LOADER_FOLIA:
 		return storage.ModLoaderFolia
+	case v1.ModLoader_MOD_LOADER_FTBA:
+		return storage.ModLoaderFTBA
 	case v1.ModLoader_MOD_LOADER_SPIGOT:
 		return storage.ModLoaderSpigot
 	case v1.ModLoader_MOD_LOADER_BUKKIT:
@@ -241,6 +319,20 @@ func (s *ServerService) ListServers(ctx context.Context, req *connect.Request[v1
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list servers"))
 	}
 
+	// Limit to servers the caller can read when access is scoped
+	if user := auth.GetUserFromContext(ctx); user != nil && s.enforcer != nil {
+		all, ids, err := s.enforcer.ServerFilter(ctx, user.ID, user.Roles, rbac.ActionRead)
+		if err != nil {
+			s.log.Error("Failed to resolve server access: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list servers"))
+		}
+		if !all {
+			servers = slices.DeleteFunc(servers, func(server *storage.Server) bool {
+				return !ids[server.ID]
+			})
+		}
+	}
+
 	// Get all proxy listeners once for efficiency
 	var listeners map[string]*storage.ProxyListener
 	if s.config.Proxy.Enabled {
@@ -267,6 +359,10 @@ func (s *ServerService) ListServers(ctx context.Context, req *connect.Request[v1
 			if err == nil {
 				server.Status = status
 			}
+			// Still up while players are warned, but already on its way down
+			if s.countdownAction(server.ID) != "" {
+				server.Status = storage.StatusStopping
+			}
 
 			// Apply cached metrics from the background collector
 			if s.metricsCollector != nil {
@@ -276,6 +372,7 @@ func (s *ServerService) ListServers(ctx context.Context, req *connect.Request[v1
 					server.DiskUsage = m.DiskUsage
 					server.DiskTotal = m.DiskTotal
 					server.WorldSize = m.WorldSize
+					server.CrashDumps = m.CrashDumps
 					server.PlayersOnline = m.PlayersOnline
 					server.TPS = m.TPS
 
@@ -287,9 +384,15 @@ func (s *ServerService) ListServers(ctx context.Context, req *connect.Request[v1
 					server.ProtocolVersion = m.ProtocolVersion
 					server.PlayerSample = m.PlayerSample
 					server.MaxPlayersSLP = m.MaxPlayers
+
+					server.MemoryLimit = m.MemoryLimit
+					server.MemoryTrend = m.MemoryTrend
+					server.MemoryFullIn = m.MemoryFullIn
+					server.MemoryLeakWarning = m.MemoryLeakSuspected
 				}
 			}
 		}
+		s.applyStartupPhase(server)
 	}
 
 	// Convert to proto
@@ -324,7 +427,11 @@ func (s *ServerService) GetServer(ctx context.Context, req *connect.Request[v1.G
 		if err == nil {
 			server.Status = status
 		}
+		if s.countdownAction(server.ID) != "" {
+			server.Status = storage.StatusStopping
+		}
 	}
+	s.applyStartupPhase(server)
 
 	// Apply cached metrics from the background collector
 	if s.metricsCollector != nil {
@@ -334,6 +441,7 @@ func (s *ServerService) GetServer(ctx context.Context, req *connect.Request[v1.G
 			server.DiskUsage = m.DiskUsage
 			server.DiskTotal = m.DiskTotal
 			server.WorldSize = m.WorldSize
+			server.CrashDumps = m.CrashDumps
 			server.PlayersOnline = m.PlayersOnline
 			server.TPS = m.TPS
 
@@ -346,11 +454,19 @@ func (s *ServerService) GetServer(ctx context.Context, req *connect.Request[v1.G
 			server.PlayerSample = m.PlayerSample
 			server.MaxPlayersSLP = m.MaxPlayers
 			server.Favicon = m.Favicon
+
+			server.MemoryLimit = m.MemoryLimit
+			server.MemoryTrend = m.MemoryTrend
+			server.MemoryFullIn = m.MemoryFullIn
+			server.MemoryLeakWarning = m.MemoryLeakSuspected
 		}
 	}
 
+	protoServer := dbServerToProto(server)
+	protoServer.VoiceChat = voiceChatInfoToProto(server)
+
 	return connect.NewResponse(&v1.GetServerResponse{
-		Server: dbServerToProto(server),
+		Server: protoServer,
 	}), nil
 }
 
@@ -361,13 +477,23 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 	// Convert mod loader from proto
 	modLoader := protoModLoaderToDB(msg.ModLoader)
 
+	// Fall back to the configured defaults, the quota check below covers them too
+	if msg.Memory <= 0 {
+		msg.Memory = int32(s.config.Minecraft.DefaultMemory)
+	}
+	if msg.MaxPlayers <= 0 {
+		msg.MaxPlayers = int32(s.config.Minecraft.DefaultMaxPlayers)
+	}
+
 	// If modpack is selected, load it and derive settings
 	var modpackURL string
+	var selectedModpack *storage.IndexedModpack
 	if msg.ModpackId != "" {
 		modpack, err := s.store.GetIndexedModpack(ctx, msg.ModpackId)
 		if err != nil {
 			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid modpack"))
 		}
+		selectedModpack = modpack
 
 		// Set the modpack URL
 		modpackURL = modpack.WebsiteURL
@@ -378,6 +504,8 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 			modLoader = storage.ModLoaderAutoCurseForge
 		case "modrinth":
 			modLoader = storage.ModLoaderModrinth
+		case "ftba":
+			modLoader = storage.ModLoaderFTBA
 		}
 
 		// Get MC version from modpack if not explicitly set
@@ -398,60 +526,29 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 	if msg.Name == "" || msg.McVersion == "" {
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("name and MC version are required"))
 	}
+	if err := validateResourceLimits(msg.CpuLimit, msg.SwapLimit); err != nil {
+		return nil, err
+	}
 
 	// Handle proxy configuration
 	proxyHostname := msg.ProxyHostname
+	proxySubdomain := ""
 	proxyListenerID := msg.ProxyListenerId
 	port := int(msg.Port)
 
 	if proxyHostname != "" {
-		// If using base URL, append it to the hostname
-		if msg.UseBaseUrl {
-			proxyConfig, _, err := s.store.GetProxyConfig(ctx)
-			if err == nil && proxyConfig.BaseURL != "" {
-				// Only append base URL if hostname doesn't already contain a domain
-				if !strings.Contains(proxyHostname, ".") {
-					proxyHostname = proxyHostname + "." + proxyConfig.BaseURL
-				}
-			}
+		fqdn, subdomain, err := proxy.ResolveHostname(proxyHostname, s.config.Proxy.BaseURL, msg.UseBaseUrl)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
 		}
+		proxyHostname, proxySubdomain = fqdn, subdomain
 
-		// Validate listener selection
-		if proxyListenerID != "" {
-			listener, err := s.store.GetProxyListener(ctx, proxyListenerID)
-			if err != nil || !listener.Enabled {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid or disabled proxy listener"))
-			}
-			port = listener.Port
-		} else {
-			// No listener specified, get the default one
-			listeners, err := s.store.GetProxyListeners(ctx)
-			if err != nil || len(listeners) == 0 {
-				return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("no proxy listeners configured"))
-			}
-
-			// Find default or first enabled listener
-			var defaultListener *storage.ProxyListener
-			for _, l := range listeners {
-				if l.IsDefault && l.Enabled {
-					defaultListener = l
-					break
-				}
-			}
-			if defaultListener == nil {
-				for _, l := range listeners {
-					if l.Enabled {
-						defaultListener = l
-						break
-					}
-				}
-			}
-			if defaultListener == nil {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("no enabled proxy listeners available"))
-			}
-			proxyListenerID = defaultListener.ID
-			port = defaultListener.Port
+		listener, err := s.proxyListener(ctx, proxyListenerID)
+		if err != nil {
+			return nil, err
 		}
+		proxyListenerID = listener.ID
+		port = listener.Port
 	} else {
 		// For non-proxy servers, must have a unique port
 		if port == 0 {
@@ -481,55 +578,26 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 	if dockerImage == "" {
 		dockerImage = docker.GetOptimalDockerTag(msg.McVersion, modLoader, false)
 	}
+	if err := s.docker.ValidateImageTag(ctx, dockerImage); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 
 	// Validate additional ports
-	var additionalPorts []*v1.AdditionalPort
-	usedPorts := make(map[string]bool)
-
-	for _, protoPort := range msg.AdditionalPorts {
-		// Validate port range
-		if protoPort.ContainerPort < 1 || protoPort.ContainerPort > 65535 {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid container port %d", protoPort.ContainerPort))
-		}
-		if protoPort.HostPort < 1 || protoPort.HostPort > 65535 {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid host port %d", protoPort.HostPort))
-		}
-
-		// Default protocol to TCP
-		protocol := protoPort.Protocol
-		if protocol == "" {
-			protocol = "tcp"
-		} else if protocol != "tcp" && protocol != "udp" {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid protocol %s (must be tcp or udp)", protocol))
-		}
-
-		// Check for duplicate ports
-		portKey := fmt.Sprintf("%d/%s", protoPort.HostPort, protocol)
-		if usedPorts[portKey] {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("duplicate host port %d/%s", protoPort.HostPort, protocol))
-		}
-		usedPorts[portKey] = true
+	additionalPorts, err := s.validateAdditionalPorts(ctx, "", port, msg.AdditionalPorts)
+	if err != nil {
+		return nil, err
+	}
 
-		// Check if port conflicts
-		if int(protoPort.HostPort) == port {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("additional port %d conflicts with main server port", protoPort.HostPort))
-		}
-		if s.config.Proxy.Enabled && slices.Contains(s.config.Proxy.ListenPorts, int(protoPort.HostPort)) {
-			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("port %d is already in use by the proxy server", protoPort.HostPort))
+	var preset *storage.ConfigPreset
+	if msg.ConfigPresetId != "" {
+		preset, err = s.store.GetConfigPreset(ctx, msg.ConfigPresetId)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("config preset not found"))
 		}
-
-		additionalPorts = append(additionalPorts, &v1.AdditionalPort{
-			ContainerPort: protoPort.ContainerPort,
-			HostPort:      protoPort.HostPort,
-			Protocol:      protocol,
-			Name:          protoPort.Name,
-		})
 	}
 
 	// Create server object
 	serverUUID := uuid.New().String()
-	serverDataDir := fmt.Sprintf("%s_%s", files.SanitizePathName(msg.Name), serverUUID)
-	serverDataPath := filepath.Join(s.config.Storage.DataDir, "servers", serverDataDir)
 
 	server := &storage.Server{
 		ID:              serverUUID,
@@ -540,10 +608,13 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 		Status:          storage.StatusCreating,
 		Port:            port,
 		ProxyHostname:   proxyHostname,
+		ProxySubdomain:  proxySubdomain,
 		ProxyListenerID: proxyListenerID,
 		MaxPlayers:      int(msg.MaxPlayers),
 		Memory:          int(msg.Memory),
-		DataPath:        serverDataPath,
+		CPULimit:        msg.CpuLimit,
+		SwapLimit:       int(msg.SwapLimit),
+		DataPath:        serverDataPath(s.config, msg.Name, serverUUID),
 		JavaVersion:     docker.GetRequiredJavaVersion(msg.McVersion, modLoader),
 		DockerImage:     dockerImage,
 		AutoStart:       msg.AutoStart,
@@ -554,16 +625,16 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 	}
 
 	// Set defaults
-	if server.MaxPlayers == 0 {
-		server.MaxPlayers = 20
-	}
-	if server.Memory == 0 {
-		server.Memory = 4096
-	}
 	if server.ModLoader == "" {
 		server.ModLoader = storage.ModLoaderVanilla
 	}
 
+	// Remember the installed modpack release for update checks
+	if selectedModpack != nil {
+		server.ModpackID = selectedModpack.ID
+		server.ModpackVersionID = modpackVersionOrLatest(selectedModpack, msg.ModpackVersionId)
+	}
+
 	// When using proxy, set the ports correctly
 	if server.ProxyHostname != "" && proxyListenerID != "" {
 		listener, err := s.store.GetProxyListener(ctx, proxyListenerID)
@@ -573,8 +644,15 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 		}
 	}
 
+	if err := checkCallerQuota(ctx, s.store, storage.QuotaUsage{Servers: 1, Memory: server.Memory}); err != nil {
+		return nil, err
+	}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		server.OwnerID = user.ID
+	}
+
 	// Create data directory
-	if err := os.MkdirAll(server.DataPath, 0755); err != nil {
+	if err := s.createServerData(ctx, server); err != nil {
 		s.log.Error("Failed to create data directory: %v", err)
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server directory"))
 	}
@@ -582,6 +660,7 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 	// Save to database
 	if err := s.store.CreateServer(ctx, server); err != nil {
 		s.log.Error("Failed to create server: %v", err)
+		s.removeServerData(ctx, server)
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server"))
 	}
 
@@ -605,6 +684,12 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 		serverConfig.InitMemory = serverConfig.Memory
 	}
 
+	if preset != nil {
+		if err := applyConfigPreset(serverConfig, preset); err != nil {
+			s.log.Error("Failed to apply config preset %s: %v", preset.Name, err)
+		}
+	}
+
 	if err := s.store.UpdateServerConfig(ctx, serverConfig); err != nil {
 		s.log.Error("Failed to update server config with memory settings: %v", err)
 	}
@@ -617,23 +702,18 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 			modpackFile, err := s.store.GetIndexedModpackFiles(ctx, msg.ModpackId)
 			if err == nil && len(modpackFile) > 0 {
 				sourcePath := modpackFile[0].DownloadURL
-				destPath := filepath.Join(server.DataPath, "modpack.zip")
 
 				// Copy the modpack file
-				if sourceFile, err := os.Open(sourcePath); err == nil {
-					defer sourceFile.Close()
-					if destFile, err := os.Create(destPath); err == nil {
-						defer destFile.Close()
-						io.Copy(destFile, sourceFile)
-
-						// Set CF_MODPACK_ZIP for manual modpack
-						cfModpackZip := "/data/modpack.zip"
-						serverConfig.CFModpackZip = &cfModpackZip
-
-						// Set a dummy slug
-						cfSlug := "manual-" + modpack.ID
-						serverConfig.CFSlug = &cfSlug
-					}
+				if err := s.copyIntoServerData(ctx, server, sourcePath, "modpack.zip"); err == nil {
+					// Set CF_MODPACK_ZIP for manual modpack
+					cfModpackZip := "/data/modpack.zip"
+					serverConfig.CFModpackZip = &cfModpackZip
+
+					// Set a dummy slug
+					cfSlug := "manual-" + modpack.ID
+					serverConfig.CFSlug = &cfSlug
+				} else {
+					s.log.Error("Failed to copy modpack into server %s: %v", server.Name, err)
 				}
 			}
 		} else if modpackURL != "" && server.ModLoader == storage.ModLoaderAutoCurseForge {
@@ -662,6 +742,8 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 				versionType := "release"
 				serverConfig.ModrinthModpackVersionType = &versionType
 			}
+		} else if modpack != nil && modpack.Indexer == "ftba" {
+			setFTBModpackConfig(serverConfig, modpack, msg.ModpackVersionId)
 		}
 
 		// Update config with modpack settings
@@ -671,68 +753,78 @@ func (s *ServerService) CreateServer(ctx context.Context, req *connect.Request[v
 	}
 
 	// Create Docker container asynchronously
-	go func() {
-		bgCtx := context.Background()
-		s.log.Info("Starting async Docker container creation for server %s", server.ID)
+	go s.provisionContainer(server, serverConfig, msg.StartImmediately)
 
-		containerID, err := s.docker.CreateContainer(bgCtx, server, serverConfig)
-		if err != nil {
-			s.log.Error("Failed to create container: %v", err)
-			server.Status = storage.StatusError
-			if updateErr := s.store.UpdateServer(bgCtx, server); updateErr != nil {
-				s.log.Error("Failed to update server status to error: %v", updateErr)
-			}
-			return
-		}
+	var dataDir string
+	if server.DataVolume == "" {
+		dataDir = filepath.Base(server.DataPath)
+	}
 
-		server.ContainerID = containerID
-		s.log.Info("Container created successfully for server %s: %s", server.ID, containerID)
+	// Return immediately with the server in "creating" state
+	return connect.NewResponse(&v1.CreateServerResponse{
+		Server:  dbServerToProto(server),
+		DataDir: dataDir,
+	}), nil
+}
 
-		// Update server with container ID
-		if err := s.store.UpdateServer(bgCtx, server); err != nil {
-			s.log.Error("Failed to update server with container ID: %v", err)
-			return
+// Creates the container of a server in the creating state, then starts it when asked or
+// leaves it stopped. Meant to run in the background, failures put the server in error.
+func (s *ServerService) provisionContainer(server *storage.Server, serverConfig *storage.ServerConfig, start bool) {
+	bgCtx := context.Background()
+	s.log.Info("Starting async Docker container creation for server %s", server.ID)
+
+	containerID, err := s.docker.CreateContainer(bgCtx, server, serverConfig)
+	if err != nil {
+		s.log.Error("Failed to create container: %v", err)
+		server.Status = storage.StatusError
+		if updateErr := s.store.UpdateServer(bgCtx, server); updateErr != nil {
+			s.log.Error("Failed to update server status to error: %v", updateErr)
 		}
+		return
+	}
 
-		// Start the container immediately if requested
-		if msg.StartImmediately {
-			if err := s.docker.StartContainer(bgCtx, containerID); err != nil {
-				s.log.Error("Failed to start container: %v", err)
-				server.Status = storage.StatusError
-			} else {
-				server.Status = storage.StatusStarting
-				// Update last started time
-				now := time.Now()
-				server.LastStarted = &now
-				// Clear ephemeral configuration fields
-				if err := s.store.ClearEphemeralConfigFields(bgCtx, server.ID); err != nil {
-					s.log.Error("Failed to clear ephemeral config fields: %v", err)
-				}
-			}
-			// Update status in database
-			if err := s.store.UpdateServer(bgCtx, server); err != nil {
-				s.log.Error("Failed to update server status: %v", err)
-			}
-			// Update proxy route if enabled
-			if s.proxy != nil && server.ProxyHostname != "" {
-				if err := s.proxy.UpdateServerRoute(server); err != nil {
-					s.log.Error("Failed to update proxy route for newly created server: %v", err)
-				}
-			}
+	server.ContainerID = containerID
+	s.log.Info("Container created successfully for server %s: %s", server.ID, containerID)
+
+	// Update server with container ID
+	if err := s.store.UpdateServer(bgCtx, server); err != nil {
+		s.log.Error("Failed to update server with container ID: %v", err)
+		return
+	}
+
+	// Start the container immediately if requested
+	if start {
+		if err := s.docker.StartContainer(bgCtx, containerID); err != nil {
+			s.log.Error("Failed to start container: %v", err)
+			server.Status = storage.StatusError
 		} else {
-			// Update status to stopped once container is ready
-			server.Status = storage.StatusStopped
-			if err := s.store.UpdateServer(bgCtx, server); err != nil {
-				s.log.Error("Failed to update server status: %v", err)
+			server.Status = storage.StatusStarting
+			// Update last started time
+			now := time.Now()
+			server.LastStarted = &now
+			// Clear ephemeral configuration fields
+			if err := s.store.ClearEphemeralConfigFields(bgCtx, server.ID); err != nil {
+				s.log.Error("Failed to clear ephemeral config fields: %v", err)
 			}
-			s.log.Info("Server %s created but not started immediately", server.ID)
 		}
-	}()
-
-	// Return immediately with the server in "creating" state
-	return connect.NewResponse(&v1.CreateServerResponse{
-		Server: dbServerToProto(server),
-	}), nil
+		// Update status in database
+		if err := s.store.UpdateServer(bgCtx, server); err != nil {
+			s.log.Error("Failed to update server status: %v", err)
+		}
+		// Update proxy route if enabled
+		if s.proxy != nil && server.ProxyHostname != "" {
+			if err := s.proxy.UpdateServerRoute(server); err != nil {
+				s.log.Error("Failed to update proxy route for newly created server: %v", err)
+			}
+		}
+	} else {
+		// Update status to stopped once container is ready
+		server.Status = storage.StatusStopped
+		if err := s.store.UpdateServer(bgCtx, server); err != nil {
+			s.log.Error("Failed to update server status: %v", err)
+		}
+		s.log.Info("Server %s created but not started immediately", server.ID)
+	}
 }
 
 // UpdateServer updates a server
@@ -790,12 +882,32 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 		needsRecreation = true
 	}
 	if msg.Memory > 0 && int(msg.Memory) != originalMemory {
+		if err := checkCreatorQuota(ctx, s.store, server.OwnerID, storage.QuotaUsage{Memory: int(msg.Memory) - originalMemory}); err != nil {
+			return nil, err
+		}
 		server.Memory = int(msg.Memory)
 		needsRecreation = true
 		if err := s.store.UpdateServerConfigMemory(ctx, server.ID, int(msg.Memory)); err != nil {
 			s.log.Error("Failed to update server config memory: %v", err)
 		}
 	}
+	if msg.CpuLimit != nil || msg.SwapLimit != nil {
+		cpuLimit, swapLimit := server.CPULimit, int32(server.SwapLimit)
+		if msg.CpuLimit != nil {
+			cpuLimit = *msg.CpuLimit
+		}
+		if msg.SwapLimit != nil {
+			swapLimit = *msg.SwapLimit
+		}
+		if err := validateResourceLimits(cpuLimit, swapLimit); err != nil {
+			return nil, err
+		}
+		if cpuLimit != server.CPULimit || int(swapLimit) != server.SwapLimit {
+			server.CPULimit = cpuLimit
+			server.SwapLimit = int(swapLimit)
+			needsRecreation = true
+		}
+	}
 	if msg.ModLoader != "" && storage.ModLoader(msg.ModLoader) != originalModLoader {
 		server.ModLoader = storage.ModLoader(msg.ModLoader)
 		server.TPSCommand = minecraft.GetTPSCommand(server.ModLoader)
@@ -806,6 +918,9 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 		needsRecreation = true
 	}
 	if msg.DockerImage != "" && msg.DockerImage != originalDockerImage {
+		if err := s.docker.ValidateImageTag(ctx, msg.DockerImage); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
 		server.DockerImage = msg.DockerImage
 		needsRecreation = true
 	}
@@ -818,51 +933,27 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 	if msg.TpsCommand != nil {
 		server.TPSCommand = *msg.TpsCommand
 	}
+	if msg.RestartOnUnhealthy != nil {
+		server.RestartOnUnhealthy = *msg.RestartOnUnhealthy
+	}
+	if msg.UnhealthyRestartAfter != nil {
+		if *msg.UnhealthyRestartAfter < 0 {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unhealthy_restart_after cannot be negative"))
+		}
+		server.UnhealthyRestartAfter = int(*msg.UnhealthyRestartAfter)
+	}
+	if msg.MaxUnhealthyRestarts != nil {
+		if *msg.MaxUnhealthyRestarts < 0 {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("max_unhealthy_restarts cannot be negative"))
+		}
+		server.MaxUnhealthyRestarts = int(*msg.MaxUnhealthyRestarts)
+	}
 
 	// Handle additional ports update
 	if len(msg.AdditionalPorts) > 0 {
-		// Validate additional ports
-		var additionalPorts []*v1.AdditionalPort
-		usedPorts := make(map[string]bool)
-
-		for _, protoPort := range msg.AdditionalPorts {
-			// Validate port range
-			if protoPort.ContainerPort < 1 || protoPort.ContainerPort > 65535 {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid container port %d", protoPort.ContainerPort))
-			}
-			if protoPort.HostPort < 1 || protoPort.HostPort > 65535 {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid host port %d", protoPort.HostPort))
-			}
-
-			// Default protocol to TCP
-			protocol := protoPort.Protocol
-			if protocol == "" {
-				protocol = "tcp"
-			} else if protocol != "tcp" && protocol != "udp" {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid protocol %s", protocol))
-			}
-
-			// Check for duplicate ports
-			portKey := fmt.Sprintf("%d/%s", protoPort.HostPort, protocol)
-			if usedPorts[portKey] {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("duplicate host port %d/%s", protoPort.HostPort, protocol))
-			}
-			usedPorts[portKey] = true
-
-			// Check if port conflicts
-			if int(protoPort.HostPort) == server.Port {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("additional port %d conflicts with main server port", protoPort.HostPort))
-			}
-			if s.config.Proxy.Enabled && slices.Contains(s.config.Proxy.ListenPorts, int(protoPort.HostPort)) {
-				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("port %d is already in use by the proxy server", protoPort.HostPort))
-			}
-
-			additionalPorts = append(additionalPorts, &v1.AdditionalPort{
-				ContainerPort: protoPort.ContainerPort,
-				HostPort:      protoPort.HostPort,
-				Protocol:      protocol,
-				Name:          protoPort.Name,
-			})
+		additionalPorts, err := s.validateAdditionalPorts(ctx, server.ID, server.Port, msg.AdditionalPorts)
+		if err != nil {
+			return nil, err
 		}
 
 		server.AdditionalPorts = additionalPorts
@@ -882,6 +973,13 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 		needsRecreation = true
 	}
 
+	// Switching modpacks always rebuilds the container
+	if needsRecreation || msg.ModpackId != "" {
+		if err := requireRecreateConfirmed(server, msg.ConfirmRecreate); err != nil {
+			return nil, err
+		}
+	}
+
 	// Handle modpack version update
 	if msg.ModpackId != "" {
 		serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
@@ -893,6 +991,8 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 		modpack, err := s.store.GetIndexedModpack(ctx, msg.ModpackId)
 		if err == nil {
 			modpackURL := modpack.WebsiteURL
+			server.ModpackID = modpack.ID
+			server.ModpackVersionID = modpackVersionOrLatest(modpack, msg.ModpackVersionId)
 
 			switch modpack.Indexer {
 			case "fuego", "manual":
@@ -924,6 +1024,14 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 					versionType := "release"
 					serverConfig.ModrinthModpackVersionType = &versionType
 				}
+			case "ftba":
+				server.ModLoader = storage.ModLoaderFTBA
+				needsRecreation = true
+
+				// Switching releases on an installed pack needs the installer to run again
+				setFTBModpackConfig(serverConfig, modpack, msg.ModpackVersionId)
+				forceReinstall := true
+				serverConfig.FTBForceReinstall = &forceReinstall
 			}
 
 			if err := s.store.UpdateServerConfig(ctx, serverConfig); err != nil {
@@ -932,6 +1040,12 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 		}
 	}
 
+	// Without auto recreation the container is left alone until the next start, restart or recreate
+	if needsRecreation && server.ContainerID != "" && !s.config.Docker.AutoRecreate {
+		server.RestartRequired = true
+		needsRecreation = false
+	}
+
 	// Save server updates first
 	if err := s.store.UpdateServer(ctx, server); err != nil {
 		s.log.Error("Failed to update server: %v", err)
@@ -948,7 +1062,7 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 		}
 
 		// Recreate container
-		result, err := s.docker.RecreateContainer(ctx, server.ContainerID, server, serverConfig)
+		result, err := s.recreateContainer(ctx, server, serverConfig)
 		if err != nil {
 			s.log.Error("Failed to recreate container: %v", err)
 			if result != nil && result.NewContainerID != "" {
@@ -962,6 +1076,8 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 			}
 		} else {
 			server.ContainerID = result.NewContainerID
+			server.Imported = false
+			server.RestartRequired = false
 			if result.WasRunning {
 				server.Status = storage.StatusRunning
 			} else {
@@ -980,69 +1096,413 @@ func (s *ServerService) UpdateServer(ctx context.Context, req *connect.Request[v
 	}), nil
 }
 
-// DeleteServer deletes a server
-func (s *ServerService) DeleteServer(ctx context.Context, req *connect.Request[v1.DeleteServerRequest]) (*connect.Response[v1.DeleteServerResponse], error) {
-	server, err := s.store.GetServer(ctx, req.Msg.Id)
-	if err != nil {
-		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+// Points the FTB installer at the pack, pinning the version unless latest is requested
+func setFTBModpackConfig(serverConfig *storage.ServerConfig, modpack *storage.IndexedModpack, versionID string) {
+	modpackID := modpack.IndexerID
+	serverConfig.FTBModpackID = &modpackID
+	if versionID != "" && versionID != "latest" {
+		serverConfig.FTBModpackVersionID = &versionID
+	} else {
+		serverConfig.FTBModpackVersionID = nil
 	}
+}
 
-	// Remove proxy route if configured
-	if s.proxy != nil && server.ProxyHostname != "" {
-		if err := s.proxy.RemoveServerRoute(server.ID); err != nil {
-			s.log.Error("Failed to remove proxy route: %v", err)
+// Validates extra published ports against each other and every other host port claim
+func (s *ServerService) validateAdditionalPorts(ctx context.Context, serverID string, mainPort int, ports []*v1.AdditionalPort) ([]*v1.AdditionalPort, error) {
+	var additionalPorts []*v1.AdditionalPort
+	usedPorts := make(map[string]bool)
+
+	for _, protoPort := range ports {
+		// Validate port range
+		if protoPort.ContainerPort < 1 || protoPort.ContainerPort > 65535 {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid container port %d", protoPort.ContainerPort))
+		}
+		if protoPort.HostPort < 1 || protoPort.HostPort > 65535 {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid host port %d", protoPort.HostPort))
 		}
-	}
 
-	// Stop and remove module containers before deleting the server
-	// (database cascade will delete module records, but containers need cleanup)
-	if s.moduleManager != nil {
-		modules, err := s.store.ListServerModules(ctx, server.ID)
-		if err == nil {
-			for _, mod := range modules {
-				if mod.ContainerID != "" {
-					if err := s.moduleManager.StopModule(ctx, mod.ID); err != nil {
-						s.log.Error("Failed to stop module %s: %v", mod.ID, err)
-					}
-					if err := s.moduleManager.DeleteModule(ctx, mod.ID); err != nil {
-						s.log.Error("Failed to delete module %s: %v", mod.ID, err)
-					}
-				}
-			}
+		// Default protocol to TCP
+		protocol := protoPort.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		} else if protocol != "tcp" && protocol != "udp" {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid protocol %s (must be tcp or udp)", protocol))
 		}
-	}
 
-	// Stop and remove container
-	if server.ContainerID != "" {
-		if _, err := s.docker.StopContainer(ctx, server.ContainerID); err != nil {
-			s.log.Error("Failed to stop container: %v", err)
+		// Check for duplicate ports
+		portKey := fmt.Sprintf("%d/%s", protoPort.HostPort, protocol)
+		if usedPorts[portKey] {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("duplicate host port %d/%s", protoPort.HostPort, protocol))
 		}
-		if err := s.docker.RemoveContainer(ctx, server.ContainerID); err != nil {
-			s.log.Error("Failed to remove container: %v", err)
+		usedPorts[portKey] = true
+
+		// Check if port conflicts
+		if protocol == "tcp" && int(protoPort.HostPort) == mainPort {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("additional port %d conflicts with main server port", protoPort.HostPort))
+		}
+		if s.config.Proxy.Enabled && slices.Contains(s.config.Proxy.ListenPorts, int(protoPort.HostPort)) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("port %d is already in use by the proxy server", protoPort.HostPort))
 		}
-	}
 
-	// Delete from database
-	if err := s.store.DeleteServer(ctx, req.Msg.Id); err != nil {
-		s.log.Error("Failed to delete server: %v", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to delete server"))
-	}
+		// Check modules, listeners and other servers
+		conflict, err := s.store.CheckServerPortAvailability(ctx, int(protoPort.HostPort), protocol, serverID)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check port availability: %w", err))
+		}
+		if conflict != nil {
+			owner := ""
+			switch {
+			case conflict.Module != nil:
+				owner = "module " + conflict.Module.Name
+			case conflict.Server != nil:
+				owner = "server " + conflict.Server.Name
+			case conflict.Listener != nil:
+				owner = "listener " + conflict.Listener.Name
+			}
+			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("port %d/%s: %s (used by %s)", protoPort.HostPort, protocol, conflict.Reason, owner))
+		}
 
-	// Delete data directory
-	if err := os.RemoveAll(server.DataPath); err != nil {
-		s.log.Error("Failed to delete server data: %v", err)
+		additionalPorts = append(additionalPorts, &v1.AdditionalPort{
+			ContainerPort: protoPort.ContainerPort,
+			HostPort:      protoPort.HostPort,
+			Protocol:      protocol,
+			Name:          protoPort.Name,
+			Description:   protoPort.Description,
+		})
 	}
 
-	return connect.NewResponse(&v1.DeleteServerResponse{}), nil
+	return additionalPorts, nil
 }
 
-// StartServer starts a server
-func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1.StartServerRequest]) (*connect.Response[v1.StartServerResponse], error) {
-	server, err := s.store.GetServer(ctx, req.Msg.Id)
-	if err != nil {
+// Rebuilds the server's container from serverConfig, publishing a voice chat port the
+// server is missing first. The new container's port check is saved on the server, so
+// every recreate reports bindings that still don't match, not just the ones on start.
+func (s *ServerService) recreateContainer(ctx context.Context, server *storage.Server, serverConfig *storage.ServerConfig) (*docker.RecreateContainerResult, error) {
+	s.ensureVoiceChatPort(ctx, server)
+	result, err := s.docker.RecreateContainer(ctx, server.ContainerID, server, serverConfig)
+	if result != nil && result.NewContainerID != "" {
+		server.PortBindingWarning = result.PortBindingWarning
+	}
+	return result, err
+}
+
+// syncContainerPorts rebuilds an existing container whose published ports have drifted
+// from the server: a voice chat port that needs adding, or a game port still bound on
+// the host after the server switched to proxied mode (or vice versa).
+func (s *ServerService) syncContainerPorts(ctx context.Context, server *storage.Server) error {
+	portsChanged := s.ensureVoiceChatPort(ctx, server)
+	if portsChanged {
+		if err := s.store.UpdateServer(ctx, server); err != nil {
+			s.log.Error("Failed to save voice chat port: %v", err)
+			return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update server"))
+		}
+	}
+
+	// Imported containers are only replaced on request, new ports apply then
+	if server.ContainerID == "" || server.Imported {
+		return nil
+	}
+
+	needsRecreation := portsChanged
+	if !needsRecreation {
+		stale, err := s.docker.HasStaleGamePortBinding(ctx, server.ContainerID, server)
+		if err != nil {
+			// Missing containers are rebuilt by the start fallback
+			s.log.Debug("Failed to inspect port bindings for server %s: %v", server.ID, err)
+			return nil
+		}
+		if stale {
+			s.log.Info("Container for server %s has stale port bindings (proxy hostname %q), recreating", server.ID, server.ProxyHostname)
+			needsRecreation = true
+		}
+	}
+	if !needsRecreation {
+		return nil
+	}
+
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		s.log.Error("Failed to get server config: %v", err)
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get server configuration"))
+	}
+	result, err := s.recreateContainer(ctx, server, serverConfig)
+	if err != nil {
+		s.log.Error("Failed to recreate container with updated ports: %v", err)
+	} else {
+		server.RestartRequired = false
+	}
+	if result != nil && result.NewContainerID != "" {
+		server.ContainerID = result.NewContainerID
+	}
+	if err := s.store.UpdateServer(ctx, server); err != nil {
+		s.log.Error("Failed to update server with container ID: %v", err)
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update server"))
+	}
+	return nil
+}
+
+// Rebuilds the container from the stored config to apply changes an update left for the
+// next start or restart. Returns whether the new container is running.
+func (s *ServerService) applyPendingChanges(ctx context.Context, server *storage.Server) (bool, error) {
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		s.log.Error("Failed to get server config: %v", err)
+		return false, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get server configuration"))
+	}
+
+	result, err := s.recreateContainer(ctx, server, serverConfig)
+	if result != nil && result.NewContainerID != "" {
+		server.ContainerID = result.NewContainerID
+		server.Imported = false
+	}
+	if err != nil {
+		s.log.Error("Failed to recreate container with pending changes: %v", err)
+		server.Status = storage.StatusError
+		if updateErr := s.store.UpdateServer(ctx, server); updateErr != nil {
+			s.log.Error("Failed to update server status: %v", updateErr)
+		}
+		return false, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to recreate server container"))
+	}
+
+	server.RestartRequired = false
+	if err := s.store.UpdateServer(ctx, server); err != nil {
+		s.log.Error("Failed to update server with container ID: %v", err)
+		return false, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update server"))
+	}
+	s.log.Info("Applied pending changes to server %s with new container %s", server.Name, result.NewContainerID)
+	return result.WasRunning, nil
+}
+
+// voiceChatAutoPortName names the additional port DiscoPanel publishes for Simple Voice Chat
+const voiceChatAutoPortName = "Simple Voice Chat"
+
+// voiceChatContainerPort resolves the in-container UDP port voice chat listens on
+func voiceChatContainerPort(server *storage.Server, info *minecraft.VoiceChatInfo) int {
+	if !info.SharesGamePort {
+		return info.Port
+	}
+	if server.ProxyHostname != "" {
+		return docker.DefaultMinecraftPort
+	}
+	return server.Port
+}
+
+// findVoiceChatPort returns the additional UDP port publishing voice chat, if any
+func findVoiceChatPort(server *storage.Server, containerPort int) *v1.AdditionalPort {
+	for _, port := range server.AdditionalPorts {
+		if port != nil && port.Protocol == "udp" && int(port.ContainerPort) == containerPort {
+			return port
+		}
+	}
+	return nil
+}
+
+// ensureVoiceChatPort publishes the Simple Voice Chat UDP port when the mod or plugin
+// is installed but no matching binding exists. Returns true if the ports changed and
+// the container needs recreating.
+func (s *ServerService) ensureVoiceChatPort(ctx context.Context, server *storage.Server) bool {
+	info := minecraft.DetectVoiceChat(server.DataPath, server.ModLoader)
+	if info == nil {
+		return false
+	}
+
+	containerPort := voiceChatContainerPort(server, info)
+	if findVoiceChatPort(server, containerPort) != nil {
+		return false
+	}
+
+	// Prefer the same host port so the default client config works, else the next free one
+	wanted := containerPort
+	hostPort := 0
+	for candidate := wanted; candidate <= 65535 && candidate < wanted+100; candidate++ {
+		if s.config.Proxy.Enabled && slices.Contains(s.config.Proxy.ListenPorts, candidate) {
+			continue
+		}
+		if slices.ContainsFunc(server.AdditionalPorts, func(p *v1.AdditionalPort) bool {
+			return p != nil && p.Protocol == "udp" && int(p.HostPort) == candidate
+		}) {
+			continue
+		}
+		conflict, err := s.store.CheckServerPortAvailability(ctx, candidate, "udp", server.ID)
+		if err != nil {
+			s.log.Error("Failed to check voice chat port availability: %v", err)
+			return false
+		}
+		if conflict == nil {
+			hostPort = candidate
+			break
+		}
+	}
+	if hostPort == 0 {
+		s.log.Warn("Simple Voice Chat detected on server %s but no free UDP port near %d", server.ID, wanted)
+		return false
+	}
+
+	// Retarget a previous auto-added binding if the mod's port changed
+	for _, port := range server.AdditionalPorts {
+		if port != nil && port.Protocol == "udp" && port.Name == voiceChatAutoPortName {
+			s.log.Info("Simple Voice Chat port changed for server %s, rebinding UDP %d -> %d", server.ID, hostPort, containerPort)
+			port.ContainerPort = int32(containerPort)
+			port.HostPort = int32(hostPort)
+			return true
+		}
+	}
+
+	s.log.Info("Simple Voice Chat detected on server %s, publishing UDP %d -> %d", server.ID, hostPort, containerPort)
+	server.AdditionalPorts = append(server.AdditionalPorts, &v1.AdditionalPort{
+		Name:          voiceChatAutoPortName,
+		ContainerPort: int32(containerPort),
+		HostPort:      int32(hostPort),
+		Protocol:      "udp",
+		Description:   "Added automatically for Simple Voice Chat",
+	})
+	return true
+}
+
+// voiceChatInfoToProto reports how players reach voice chat, nil when it isn't installed
+func voiceChatInfoToProto(server *storage.Server) *v1.VoiceChatInfo {
+	info := minecraft.DetectVoiceChat(server.DataPath, server.ModLoader)
+	if info == nil {
+		return nil
+	}
+
+	containerPort := voiceChatContainerPort(server, info)
+	result := &v1.VoiceChatInfo{
+		ContainerPort: int32(containerPort),
+		VoiceHost:     info.VoiceHost,
+		Configured:    info.ConfigPath != "",
+	}
+	if port := findVoiceChatPort(server, containerPort); port != nil {
+		result.HostPort = port.HostPort
+	}
+	return result
+}
+
+// DeleteServer deletes a server
+func (s *ServerService) DeleteServer(ctx context.Context, req *connect.Request[v1.DeleteServerRequest]) (*connect.Response[v1.DeleteServerResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	// Remove proxy route if configured
+	if s.proxy != nil && server.ProxyHostname != "" {
+		if err := s.proxy.RemoveServerRoute(server.ID); err != nil {
+			s.log.Error("Failed to remove proxy route: %v", err)
+		}
+	}
+
+	// Stop and remove module containers before deleting the server
+	// (database cascade will delete module records, but containers need cleanup)
+	if s.moduleManager != nil {
+		modules, err := s.store.ListServerModules(ctx, server.ID)
+		if err == nil {
+			for _, mod := range modules {
+				if mod.ContainerID != "" {
+					if err := s.moduleManager.StopModule(ctx, mod.ID); err != nil {
+						s.log.Error("Failed to stop module %s: %v", mod.ID, err)
+					}
+					if err := s.moduleManager.DeleteModule(ctx, mod.ID); err != nil {
+						s.log.Error("Failed to delete module %s: %v", mod.ID, err)
+					}
+				}
+			}
+		}
+	}
+
+	// Stop and remove container, an imported one is handed back untouched
+	if server.ContainerID != "" && !server.Imported {
+		if _, err := s.docker.StopContainer(ctx, server.ContainerID); err != nil {
+			s.log.Error("Failed to stop container: %v", err)
+		}
+		if err := s.docker.RemoveContainer(ctx, server.ContainerID); err != nil {
+			s.log.Error("Failed to remove container: %v", err)
+		}
+	}
+
+	// Delete from database
+	if err := s.store.DeleteServer(ctx, req.Msg.Id); err != nil {
+		s.log.Error("Failed to delete server: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to delete server"))
+	}
+
+	// Delete data directory, unless it predates DiscoPanel
+	if server.Imported {
+		s.log.Info("Kept data of imported server %s at %s", server.Name, server.DataPath)
+	} else if err := s.removeServerData(ctx, server); err != nil {
+		s.log.Error("Failed to delete server data: %v", err)
+	}
+
+	return connect.NewResponse(&v1.DeleteServerResponse{}), nil
+}
+
+// Checks the CPU and swap limits a create or update asks for. Docker refuses NanoCPUs
+// below a hundredth of a core.
+func validateResourceLimits(cpuLimit float64, swapLimit int32) error {
+	if cpuLimit < 0 || (cpuLimit > 0 && cpuLimit < 0.01) {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("cpu limit must be 0 for no limit or at least 0.01 cores"))
+	}
+	if swapLimit < 0 {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("swap limit must be 0 for no swap or a size in MB"))
+	}
+	return nil
+}
+
+// Where a new server's data goes, named by storage.dir_naming
+func serverDataPath(cfg *config.Config, name, id string) string {
+	return filepath.Join(cfg.Storage.DataDir, "servers", files.ServerDirName(cfg.Storage.DirNaming, name, id))
+}
+
+// Creates a new server's data directory. An existing one is refused rather than shared,
+// since a failed create would delete it again.
+func createDataDir(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.Mkdir(path, 0755)
+}
+
+// Creates the server's data directory, or its named volume when docker.data_volumes is on
+// or the daemon is remote, where a directory of ours wouldn't be the one it mounts. A
+// volume's DataPath is only set when DiscoPanel sees Docker's volume directory.
+func (s *ServerService) createServerData(ctx context.Context, server *storage.Server) error {
+	if !s.config.Docker.DataVolumes && !s.docker.IsRemote() {
+		return createDataDir(server.DataPath)
+	}
+
+	name, err := s.docker.CreateDataVolume(ctx, server)
+	if err != nil {
+		return err
+	}
+	server.DataVolume = name
+	server.DataPath = s.docker.VolumeDataPath(name)
+	return nil
+}
+
+// Deletes the server's data directory or named volume
+func (s *ServerService) removeServerData(ctx context.Context, server *storage.Server) error {
+	if server.DataVolume != "" {
+		return s.docker.RemoveDataVolume(ctx, server.DataVolume)
+	}
+	return os.RemoveAll(server.DataPath)
+}
+
+// StartServer starts a server
+func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1.StartServerRequest]) (*connect.Response[v1.StartServerResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
 	}
 
+	if err := s.syncContainerPorts(ctx, server); err != nil {
+		return nil, err
+	}
+	if server.RestartRequired && server.ContainerID != "" {
+		if _, err := s.applyPendingChanges(ctx, server); err != nil {
+			return nil, err
+		}
+	}
+
 	// If container doesn't exist, create it first
 	if server.ContainerID == "" {
 		serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
@@ -1058,6 +1518,7 @@ func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1
 		}
 
 		server.ContainerID = containerID
+		server.RestartRequired = false
 		if err := s.store.UpdateServer(ctx, server); err != nil {
 			s.log.Error("Failed to update server with container ID: %v", err)
 			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update server"))
@@ -1066,6 +1527,10 @@ func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1
 
 	// Start container
 	if err := s.docker.StartContainer(ctx, server.ContainerID); err != nil {
+		if server.Imported {
+			s.log.Error("Failed to start imported container: %v", err)
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("failed to start imported container, recreate the server to rebuild it"))
+		}
 		s.log.Error("Failed to start container, attempting to recreate: %v", err)
 
 		// Get server config for container creation
@@ -1076,7 +1541,7 @@ func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1
 		}
 
 		// Recreate container
-		result, err := s.docker.RecreateContainer(ctx, server.ContainerID, server, serverConfig)
+		result, err := s.recreateContainer(ctx, server, serverConfig)
 		if err != nil {
 			s.log.Error("Failed to recreate container: %v", err)
 			if result != nil && result.NewContainerID != "" {
@@ -1090,6 +1555,7 @@ func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1
 			}
 		} else {
 			server.ContainerID = result.NewContainerID
+			server.RestartRequired = false
 			if result.WasRunning {
 				server.Status = storage.StatusRunning
 			} else {
@@ -1108,6 +1574,7 @@ func (s *ServerService) StartServer(ctx context.Context, req *connect.Request[v1
 	now := time.Now()
 	server.Status = storage.StatusStarting
 	server.LastStarted = &now
+	server.LastError = ""
 
 	if err := s.store.UpdateServer(ctx, server); err != nil {
 		s.log.Error("Failed to update server status: %v", err)
@@ -1156,11 +1623,33 @@ func (s *ServerService) StopServer(ctx context.Context, req *connect.Request[v1.
 		}), nil
 	}
 
-	// Stop container
+	// Warn online players first, the stop then happens once the countdown runs out
+	status, err := s.deferToCountdown(ctx, server, "stopping", req.Msg.Immediate, func(ctx context.Context, server *storage.Server) {
+		if _, err := s.stopServer(ctx, server); err != nil {
+			s.log.Error("Failed to stop server %s after countdown: %v", server.ID, err)
+		}
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+	if status == "" {
+		status, err = s.stopServer(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return connect.NewResponse(&v1.StopServerResponse{
+		Status: status,
+	}), nil
+}
+
+// Stops a server's container and reports whether it is stopping or was already gone
+func (s *ServerService) stopServer(ctx context.Context, server *storage.Server) (string, error) {
 	found, err := s.docker.StopContainer(ctx, server.ContainerID)
 	if err != nil {
 		s.log.Error("Failed to stop container: %v", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to stop server"))
+		return "", connect.NewError(connect.CodeInternal, fmt.Errorf("failed to stop server"))
 	}
 
 	// If container wasn't found, clean up stale reference
@@ -1191,13 +1680,10 @@ func (s *ServerService) StopServer(ctx context.Context, req *connect.Request[v1.
 		})
 	}
 
-	status := "stopping"
 	if !found {
-		status = "stopped"
+		return "stopped", nil
 	}
-	return connect.NewResponse(&v1.StopServerResponse{
-		Status: status,
-	}), nil
+	return "stopping", nil
 }
 
 // RestartServer restarts a server
@@ -1207,6 +1693,10 @@ func (s *ServerService) RestartServer(ctx context.Context, req *connect.Request[
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
 	}
 
+	if err := s.syncContainerPorts(ctx, server); err != nil {
+		return nil, err
+	}
+
 	// If container doesn't exist, create it and start it
 	if server.ContainerID == "" {
 		// Get server config for container creation
@@ -1224,6 +1714,7 @@ func (s *ServerService) RestartServer(ctx context.Context, req *connect.Request[
 		}
 
 		server.ContainerID = containerID
+		server.RestartRequired = false
 		if err := s.store.UpdateServer(ctx, server); err != nil {
 			s.log.Error("Failed to update server with container ID: %v", err)
 			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update server"))
@@ -1239,6 +1730,7 @@ func (s *ServerService) RestartServer(ctx context.Context, req *connect.Request[
 		now := time.Now()
 		server.Status = storage.StatusStarting
 		server.LastStarted = &now
+		server.LastError = ""
 
 		if err := s.store.UpdateServer(ctx, server); err != nil {
 			s.log.Error("Failed to update server status: %v", err)
@@ -1262,16 +1754,50 @@ func (s *ServerService) RestartServer(ctx context.Context, req *connect.Request[
 		}), nil
 	}
 
-	// Restart container
-	if err := s.docker.RestartContainer(ctx, server.ContainerID, 2*time.Second); err != nil {
+	// Warn online players first, the restart then happens once the countdown runs out
+	status, err := s.deferToCountdown(ctx, server, "restarting", req.Msg.Immediate, func(ctx context.Context, server *storage.Server) {
+		if err := s.restartServer(ctx, server); err != nil {
+			s.log.Error("Failed to restart server %s after countdown: %v", server.ID, err)
+		}
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+	if status == "" {
+		if err := s.restartServer(ctx, server); err != nil {
+			return nil, err
+		}
+	}
+
+	return connect.NewResponse(&v1.RestartServerResponse{
+		Status: "restarting",
+	}), nil
+}
+
+// Restarts a server's existing container
+func (s *ServerService) restartServer(ctx context.Context, server *storage.Server) error {
+	if server.RestartRequired {
+		// Recreating restarts a running container, a stopped one still needs starting
+		running, err := s.applyPendingChanges(ctx, server)
+		if err != nil {
+			return err
+		}
+		if !running {
+			if err := s.docker.StartContainer(ctx, server.ContainerID); err != nil {
+				s.log.Error("Failed to start container: %v", err)
+				return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to restart server"))
+			}
+		}
+	} else if err := s.docker.RestartContainer(ctx, server.ContainerID, 2*time.Second); err != nil {
 		s.log.Error("Failed to restart container: %v", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to restart server"))
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to restart server"))
 	}
 
 	// Update server status
 	now := time.Now()
 	server.Status = storage.StatusStarting
 	server.LastStarted = &now
+	server.LastError = ""
 	if err := s.store.UpdateServer(ctx, server); err != nil {
 		s.log.Error("Failed to update server status: %v", err)
 	}
@@ -1288,10 +1814,7 @@ func (s *ServerService) RestartServer(ctx context.Context, req *connect.Request[
 			ServerID: server.ID,
 		})
 	}
-
-	return connect.NewResponse(&v1.RestartServerResponse{
-		Status: "restarting",
-	}), nil
+	return nil
 }
 
 // Destroys and recreates a server container from scratch - brute force reset
@@ -1300,6 +1823,9 @@ func (s *ServerService) RecreateServer(ctx context.Context, req *connect.Request
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
 	}
+	if err := requireRecreateConfirmed(server, req.Msg.ConfirmRecreate); err != nil {
+		return nil, err
+	}
 
 	// Get server config for container creation
 	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
@@ -1308,10 +1834,15 @@ func (s *ServerService) RecreateServer(ctx context.Context, req *connect.Request
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get server configuration"))
 	}
 
-	// Recreate container
-	result, err := s.docker.RecreateContainer(ctx, server.ContainerID, server, serverConfig)
+	// Recreate container, data stays in place and only a running server is started again
+	result, err := s.recreateContainer(ctx, server, serverConfig)
 	if err != nil {
 		s.log.Error("Failed to recreate container: %v", err)
+		// The new container may exist even though it failed to start
+		if result != nil && result.NewContainerID != "" {
+			server.ContainerID = result.NewContainerID
+			server.Imported = false
+		}
 		server.Status = storage.StatusError
 		if updateErr := s.store.UpdateServer(ctx, server); updateErr != nil {
 			s.log.Error("Failed to update server status: %v", updateErr)
@@ -1320,11 +1851,17 @@ func (s *ServerService) RecreateServer(ctx context.Context, req *connect.Request
 	}
 
 	server.ContainerID = result.NewContainerID
+	server.Imported = false
+	server.RestartRequired = false
 
 	// Update server status
-	now := time.Now()
-	server.Status = storage.StatusStarting
-	server.LastStarted = &now
+	if result.WasRunning {
+		now := time.Now()
+		server.Status = storage.StatusStarting
+		server.LastStarted = &now
+	} else {
+		server.Status = storage.StatusStopped
+	}
 
 	if err := s.store.UpdateServer(ctx, server); err != nil {
 		s.log.Error("Failed to update server: %v", err)
@@ -1346,52 +1883,137 @@ func (s *ServerService) RecreateServer(ctx context.Context, req *connect.Request
 	s.log.Info("Server %s recreated successfully with new container %s", server.Name, result.NewContainerID)
 
 	return connect.NewResponse(&v1.RecreateServerResponse{
-		Status: "recreated",
+		Status:  "recreated",
+		Running: result.WasRunning,
 	}), nil
 }
 
-// SendCommand sends a command to a server
-func (s *ServerService) SendCommand(ctx context.Context, req *connect.Request[v1.SendCommandRequest]) (*connect.Response[v1.SendCommandResponse], error) {
-	server, err := s.store.GetServer(ctx, req.Msg.Id)
-
-	silent := false
-	if req.Msg.Silent != nil {
-		silent = *req.Msg.Silent
-	}
+// Default time allowed for a setup-only run
+const defaultPrepareTimeout = 15 * time.Minute
 
+// Runs the container in setup-only mode, reports the outcome and leaves a stopped container ready to start
+func (s *ServerService) PrepareServer(ctx context.Context, req *connect.Request[v1.PrepareServerRequest]) (*connect.Response[v1.PrepareServerResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
 	if err != nil {
-		s.log.Error("Failed to get server: %v", err)
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
 	}
 
-	// Check if server is running
-	if server.ContainerID == "" {
-		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server container not found"))
+	if server.Imported {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("recreate the imported server before preparing it"))
+	}
+	if server.ContainerID != "" {
+		status, err := s.docker.GetContainerStatus(ctx, server.ContainerID)
+		if err == nil && status != storage.StatusStopped {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server must be stopped before preparing"))
+		}
 	}
 
-	status, err := s.docker.GetContainerStatus(ctx, server.ContainerID)
-	if err != nil || status != storage.StatusRunning {
-		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server is not running"))
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		s.log.Error("Failed to get server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get server configuration"))
 	}
 
-	if req.Msg.Command == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("command is required"))
+	timeout := defaultPrepareTimeout
+	if req.Msg.TimeoutSeconds != nil && *req.Msg.TimeoutSeconds > 0 {
+		timeout = time.Duration(*req.Msg.TimeoutSeconds) * time.Second
 	}
 
-	// Add command to log stream if available
-	commandTime := time.Now()
-	if !silent && s.logStreamer != nil {
-		s.logStreamer.AddCommandEntry(server.ContainerID, req.Msg.Command, commandTime)
+	// Free the container name for the setup run
+	if server.ContainerID != "" {
+		if err := s.docker.RemoveContainer(ctx, server.ContainerID); err != nil {
+			s.log.Debug("Could not remove container before prepare (may not exist): %v", err)
+		}
+		server.ContainerID = ""
+	}
+	server.Status = storage.StatusCreating
+	if err := s.store.UpdateServer(ctx, server); err != nil {
+		s.log.Error("Failed to update server status: %v", err)
 	}
 
-	// Send command
-	output, err := s.sender.SendCommand(ctx, server.ID, req.Msg.Command)
-	success := err == nil
+	setupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	// Add command output to log stream if available
-	if !silent && s.logStreamer != nil && (output != "" || !success) {
-		s.logStreamer.AddCommandOutput(server.ContainerID, output, success, commandTime)
-	}
+	s.log.Info("Preparing server %s in setup-only mode", server.Name)
+	result, setupErr := s.docker.RunSetup(setupCtx, server, serverConfig)
+
+	// Leave a regular stopped container behind either way
+	server.Status = storage.StatusStopped
+	if containerID, err := s.docker.CreateContainer(ctx, server, serverConfig); err != nil {
+		s.log.Error("Failed to create container after prepare: %v", err)
+		server.Status = storage.StatusError
+	} else {
+		server.ContainerID = containerID
+	}
+	if setupErr != nil || !result.Success {
+		server.Status = storage.StatusError
+	}
+	if err := s.store.UpdateServer(ctx, server); err != nil {
+		s.log.Error("Failed to update server: %v", err)
+	}
+
+	if setupErr != nil {
+		s.log.Error("Failed to run setup for server %s: %v", server.Name, setupErr)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to run setup: %v", setupErr))
+	}
+
+	if result.Success {
+		s.log.Info("Server %s prepared in %s", server.Name, result.Duration.Round(time.Second))
+	} else {
+		s.log.Warn("Setup for server %s failed: %s", server.Name, result.Error)
+	}
+
+	return connect.NewResponse(&v1.PrepareServerResponse{
+		Success:    result.Success,
+		ExitCode:   result.ExitCode,
+		Error:      result.Error,
+		Logs:       result.Logs,
+		DurationMs: result.Duration.Milliseconds(),
+	}), nil
+}
+
+// SendCommand sends a command to a server
+func (s *ServerService) SendCommand(ctx context.Context, req *connect.Request[v1.SendCommandRequest]) (*connect.Response[v1.SendCommandResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+
+	silent := false
+	if req.Msg.Silent != nil {
+		silent = *req.Msg.Silent
+	}
+
+	if err != nil {
+		s.log.Error("Failed to get server: %v", err)
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	// Check if server is running
+	if server.ContainerID == "" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server container not found"))
+	}
+
+	status, err := s.docker.GetContainerStatus(ctx, server.ContainerID)
+	if err != nil || status != storage.StatusRunning {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server is not running"))
+	}
+
+	if req.Msg.Command == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("command is required"))
+	}
+
+	// Add command to log stream if available
+	commandTime := time.Now()
+	if !silent && s.logStreamer != nil {
+		s.logStreamer.AddCommandEntry(server.ContainerID, req.Msg.Command, commandTime)
+	}
+
+	// Send command
+	output, err := s.sender.SendCommand(ctx, server.ID, req.Msg.Command)
+	success := err == nil
+
+	// Add command output to log stream if available
+	if !silent && s.logStreamer != nil && (output != "" || !success) {
+		s.logStreamer.AddCommandOutput(server.ContainerID, output, success, commandTime)
+	}
 
 	if err != nil {
 		s.log.Error("Failed to execute command: %v", err)
@@ -1413,6 +2035,9 @@ func (s *ServerService) UploadToMCLogs(ctx context.Context, req *connect.Request
 	if err != nil {
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
 	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
 
 	logPath := filepath.Join(server.DataPath, "logs", "latest.log")
 	content, err := os.ReadFile(logPath)
@@ -1528,19 +2153,103 @@ func (s *ServerService) ClearServerLogs(ctx context.Context, req *connect.Reques
 
 // GetNextAvailablePort gets the next available port
 func (s *ServerService) GetNextAvailablePort(ctx context.Context, req *connect.Request[v1.GetNextAvailablePortRequest]) (*connect.Response[v1.GetNextAvailablePortResponse], error) {
-	// Get all servers
-	servers, err := s.store.ListServers(ctx)
+	usedPortsMap, err := s.usedServerPorts(ctx)
 	if err != nil {
 		s.log.Error("Failed to list servers: %v", err)
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get available port"))
 	}
 
+	nextPort, err := s.nextServerPort(usedPortsMap)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert map to proto UsedPort array
+	usedPorts := make([]*v1.UsedPort, 0, len(usedPortsMap))
+	for port, inUse := range usedPortsMap {
+		usedPorts = append(usedPorts, &v1.UsedPort{
+			Port:  port,
+			InUse: inUse,
+		})
+	}
+
+	return connect.NewResponse(&v1.GetNextAvailablePortResponse{
+		Port:      int32(nextPort),
+		UsedPorts: usedPorts,
+		RangeMin:  int32(s.config.Minecraft.PortRangeMin),
+		RangeMax:  int32(s.config.Minecraft.PortRangeMax),
+	}), nil
+}
+
+// GetServerDefaults returns what CreateServer fills in for omitted values, and the caller's memory quota
+func (s *ServerService) GetServerDefaults(ctx context.Context, req *connect.Request[v1.GetServerDefaultsRequest]) (*connect.Response[v1.GetServerDefaultsResponse], error) {
+	resp := &v1.GetServerDefaultsResponse{
+		Memory:     int32(s.config.Minecraft.DefaultMemory),
+		MaxPlayers: int32(s.config.Minecraft.DefaultMaxPlayers),
+	}
+
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		quota, err := s.store.GetUserQuota(ctx, user.ID, user.Roles)
+		if err != nil {
+			s.log.Error("Failed to get quota for user %s: %v", user.Username, err)
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get quota"))
+		}
+		if quota.MaxMemory > 0 {
+			usage, err := s.store.GetUserQuotaUsage(ctx, user.ID)
+			if err != nil {
+				s.log.Error("Failed to get quota usage for user %s: %v", user.Username, err)
+				return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get quota"))
+			}
+			resp.MemoryQuota = int32(quota.MaxMemory)
+			resp.MemoryUsed = int32(usage.Memory)
+		}
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// PreviewServerDataDir shows the data directory name CreateServer would derive from a name
+func (s *ServerService) PreviewServerDataDir(ctx context.Context, req *connect.Request[v1.PreviewServerDataDirRequest]) (*connect.Response[v1.PreviewServerDataDirResponse], error) {
+	const idPlaceholder = "<id>"
+	dataPath := serverDataPath(s.config, req.Msg.Name, idPlaceholder)
+	return connect.NewResponse(&v1.PreviewServerDataDirResponse{
+		DirName:    filepath.Base(dataPath),
+		DataPath:   dataPath,
+		DataVolume: s.config.Docker.DataVolumes,
+	}), nil
+}
+
+// usedServerPorts collects host ports taken by servers, modules, proxy listeners and the panel
+func (s *ServerService) usedServerPorts(ctx context.Context) (map[int32]bool, error) {
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build a map of used ports (only for non-proxied servers)
 	usedPortsMap := make(map[int32]bool)
 	for _, server := range servers {
-		// Only count ports for servers that don't use proxy
+		// Only count ports for servers that don't use proxy, their RCON binding included
 		if server.ProxyHostname == "" && server.Port > 0 {
 			usedPortsMap[int32(server.Port)] = true
+			usedPortsMap[int32(server.Port+docker.RCONPortOffset)] = true
+		}
+		for _, port := range server.AdditionalPorts {
+			if port != nil && port.HostPort > 0 {
+				usedPortsMap[port.HostPort] = true
+			}
+		}
+	}
+
+	modules, err := s.store.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, module := range modules {
+		for _, port := range module.Ports {
+			if port != nil && port.HostPort > 0 {
+				usedPortsMap[port.HostPort] = true
+			}
 		}
 	}
 
@@ -1549,29 +2258,766 @@ func (s *ServerService) GetNextAvailablePort(ctx context.Context, req *connect.R
 		for _, port := range s.config.Proxy.ListenPorts {
 			usedPortsMap[int32(port)] = true
 		}
+		listeners, err := s.store.GetProxyListeners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			usedPortsMap[int32(listener.Port)] = true
+		}
+	}
+
+	if panelPort, err := strconv.Atoi(s.config.Server.Port); err == nil {
+		usedPortsMap[int32(panelPort)] = true
 	}
+	return usedPortsMap, nil
+}
 
-	// Find the next available port starting from 25565
-	var nextPort int32 = 25565
-	for usedPortsMap[nextPort] {
-		nextPort++
-		// Safety check to avoid infinite loop
-		if nextPort > 65535 {
-			return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("no available ports"))
+// nextServerPort picks the first port in the configured range that is free, along with
+// the localhost port its RCON gets bound to
+func (s *ServerService) nextServerPort(used map[int32]bool) (int, error) {
+	rangeMin, rangeMax := s.config.Minecraft.PortRangeMin, s.config.Minecraft.PortRangeMax
+	for port := rangeMin; port <= rangeMax; port++ {
+		if !used[int32(port)] && !used[int32(port+docker.RCONPortOffset)] {
+			return port, nil
 		}
 	}
+	return 0, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("no available ports in range %d-%d", rangeMin, rangeMax))
+}
 
-	// Convert map to proto UsedPort array
-	usedPorts := make([]*v1.UsedPort, 0, len(usedPortsMap))
-	for port, inUse := range usedPortsMap {
-		usedPorts = append(usedPorts, &v1.UsedPort{
-			Port:  port,
-			InUse: inUse,
+// ListPlayers lists players currently online
+func (s *ServerService) ListPlayers(ctx context.Context, req *connect.Request[v1.ListPlayersRequest]) (*connect.Response[v1.ListPlayersResponse], error) {
+	server, err := s.getRunningServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.sender.SendCommand(ctx, server.ID, "list")
+	if err != nil {
+		s.log.Error("Failed to list players: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list players: %w", err))
+	}
+
+	online, players := minecraft.ParsePlayerListFromOutput(output)
+	return connect.NewResponse(&v1.ListPlayersResponse{
+		Online:  int32(online),
+		Players: players,
+	}), nil
+}
+
+// ManagePlayer runs a moderation action against a player
+func (s *ServerService) ManagePlayer(ctx context.Context, req *connect.Request[v1.ManagePlayerRequest]) (*connect.Response[v1.ManagePlayerResponse], error) {
+	msg := req.Msg
+	if !minecraft.IsValidPlayerName(msg.Player) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid player name"))
+	}
+	if strings.ContainsAny(msg.Reason, "\r\n") {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("reason must be a single line"))
+	}
+
+	var cmd string
+	switch msg.Action {
+	case v1.PlayerAction_PLAYER_ACTION_KICK:
+		cmd = strings.TrimSpace("kick " + msg.Player + " " + msg.Reason)
+	case v1.PlayerAction_PLAYER_ACTION_BAN:
+		cmd = strings.TrimSpace("ban " + msg.Player + " " + msg.Reason)
+	case v1.PlayerAction_PLAYER_ACTION_PARDON:
+		cmd = "pardon " + msg.Player
+	case v1.PlayerAction_PLAYER_ACTION_OP:
+		cmd = "op " + msg.Player
+	case v1.PlayerAction_PLAYER_ACTION_DEOP:
+		cmd = "deop " + msg.Player
+	case v1.PlayerAction_PLAYER_ACTION_WHITELIST_ADD:
+		cmd = "whitelist add " + msg.Player
+	case v1.PlayerAction_PLAYER_ACTION_WHITELIST_REMOVE:
+		cmd = "whitelist remove " + msg.Player
+	default:
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unknown player action"))
+	}
+
+	server, err := s.getRunningServer(ctx, msg.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	commandTime := time.Now()
+	if s.logStreamer != nil {
+		s.logStreamer.AddCommandEntry(server.ContainerID, cmd, commandTime)
+	}
+
+	output, err := s.sender.SendCommand(ctx, server.ID, cmd)
+	if s.logStreamer != nil && (output != "" || err != nil) {
+		s.logStreamer.AddCommandOutput(server.ContainerID, output, err == nil, commandTime)
+	}
+	if err != nil {
+		s.log.Error("Failed to execute player command: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to execute command: %w", err))
+	}
+
+	if minecraft.IsPlayerNotFoundOutput(output) {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("player %s not found: %s", msg.Player, strings.TrimSpace(output)))
+	}
+
+	// Mirror ops/whitelist changes into the config so they survive container recreation
+	configUpdated, err := s.syncPlayerLists(ctx, server.ID, msg.Player, msg.Action)
+	if err != nil {
+		s.log.Error("Failed to update player lists for server %s: %v", server.ID, err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("command succeeded but failed to update server config"))
+	}
+
+	return connect.NewResponse(&v1.ManagePlayerResponse{
+		Output:        output,
+		ConfigUpdated: configUpdated,
+	}), nil
+}
+
+// getRunningServer loads a server and ensures its container is running
+func (s *ServerService) getRunningServer(ctx context.Context, id string) (*storage.Server, error) {
+	server, err := s.store.GetServer(ctx, id)
+	if err != nil {
+		s.log.Error("Failed to get server: %v", err)
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	if server.ContainerID == "" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server container not found"))
+	}
+
+	status, err := s.docker.GetContainerStatus(ctx, server.ContainerID)
+	if err != nil || status != storage.StatusRunning {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server is not running"))
+	}
+
+	return server, nil
+}
+
+// syncPlayerLists applies a player action to the OPS and WHITELIST config fields
+func (s *ServerService) syncPlayerLists(ctx context.Context, serverID, player string, action v1.PlayerAction) (bool, error) {
+	serverConfig, err := s.store.GetServerConfig(ctx, serverID)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	switch action {
+	case v1.PlayerAction_PLAYER_ACTION_OP:
+		changed = updatePlayerList(&serverConfig.Ops, player, true)
+	case v1.PlayerAction_PLAYER_ACTION_DEOP:
+		changed = updatePlayerList(&serverConfig.Ops, player, false)
+	case v1.PlayerAction_PLAYER_ACTION_WHITELIST_ADD:
+		changed = updatePlayerList(&serverConfig.Whitelist, player, true)
+	case v1.PlayerAction_PLAYER_ACTION_WHITELIST_REMOVE:
+		changed = updatePlayerList(&serverConfig.Whitelist, player, false)
+	case v1.PlayerAction_PLAYER_ACTION_BAN:
+		// A banned player should not be restored as op or whitelisted on recreation
+		opsChanged := updatePlayerList(&serverConfig.Ops, player, false)
+		whitelistChanged := updatePlayerList(&serverConfig.Whitelist, player, false)
+		changed = opsChanged || whitelistChanged
+	}
+
+	if !changed {
+		return false, nil
+	}
+	return true, s.store.UpdateServerConfig(ctx, serverConfig)
+}
+
+// updatePlayerList adds or removes a name from a comma-separated player list
+func updatePlayerList(field **string, player string, add bool) bool {
+	var names []string
+	if *field != nil {
+		for name := range strings.SplitSeq(**field, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	idx := slices.IndexFunc(names, func(name string) bool {
+		return strings.EqualFold(name, player)
+	})
+	switch {
+	case add && idx == -1:
+		names = append(names, player)
+	case !add && idx != -1:
+		names = slices.Delete(names, idx, idx+1)
+	default:
+		return false
+	}
+
+	joined := strings.Join(names, ",")
+	*field = &joined
+	return true
+}
+
+// ListBackups lists a server's backup archives
+func (s *ServerService) ListBackups(ctx context.Context, req *connect.Request[v1.ListBackupsRequest]) (*connect.Response[v1.ListBackupsResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	backups, err := scheduler.ListServerBackups(s.config.Storage.BackupDir, server)
+	if err != nil {
+		s.log.Error("Failed to list backups: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list backups"))
+	}
+
+	resp := &v1.ListBackupsResponse{RemoteEnabled: s.config.Storage.Remote.Enabled}
+	if resp.RemoteEnabled {
+		// A store outage shouldn't hide the local backups
+		remote, err := scheduler.ListRemoteBackups(ctx, s.config, server)
+		if err != nil {
+			s.log.Warn("Failed to list remote backups for server %s: %v", server.Name, err)
+			resp.RemoteError = err.Error()
+		}
+		backups = scheduler.MergeBackups(backups, remote)
+	}
+
+	resp.Backups = make([]*v1.Backup, 0, len(backups))
+	for _, backup := range backups {
+		resp.Backups = append(resp.Backups, &v1.Backup{
+			Name:      backup.Name,
+			Size:      backup.Size,
+			CreatedAt: timestamppb.New(backup.CreatedAt),
+			Local:     backup.Path != "",
+			Remote:    backup.Remote,
 		})
 	}
 
-	return connect.NewResponse(&v1.GetNextAvailablePortResponse{
-		Port:      nextPort,
-		UsedPorts: usedPorts,
+	return connect.NewResponse(resp), nil
+}
+
+// RestoreBackup extracts a backup over its server, or into a new server cloned from it
+func (s *ServerService) RestoreBackup(ctx context.Context, req *connect.Request[v1.RestoreBackupRequest]) (*connect.Response[v1.RestoreBackupResponse], error) {
+	msg := req.Msg
+	server, err := s.store.GetServer(ctx, msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	backup, err := scheduler.FindServerBackup(s.config.Storage.BackupDir, server, msg.BackupName)
+	if err != nil && s.config.Storage.Remote.Enabled {
+		// Not on disk, restore from the off-site copy
+		backup, err = scheduler.FetchRemoteBackup(ctx, s.config, server, msg.BackupName, s.config.Storage.TempDir)
+		if err == nil {
+			defer os.Remove(backup.Path)
+		}
+	}
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	}
+
+	if msg.ToNewServer {
+		// Creating a server needs its own permission on top of update on the source
+		if user := auth.GetUserFromContext(ctx); user != nil && s.enforcer != nil {
+			allowed, err := s.enforcer.EnforceUser(ctx, user.ID, user.Roles, rbac.ResourceServers, rbac.ActionCreate, "*")
+			if err != nil || !allowed {
+				return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("permission denied: servers:create"))
+			}
+		}
+
+		restored, count, err := s.restoreToNewServer(ctx, server, backup.Path, msg.NewServerName)
+		if err != nil {
+			return nil, err
+		}
+		s.log.Info("Restored backup %s of server %s into new server %s (%d files)", backup.Name, server.ID, restored.ID, count)
+		return connect.NewResponse(&v1.RestoreBackupResponse{
+			ServerId:      restored.ID,
+			FilesRestored: int32(count),
+		}), nil
+	}
+
+	// Overwriting files under a running server would corrupt the world it has loaded
+	if server.ContainerID != "" {
+		if status, err := s.docker.GetContainerStatus(ctx, server.ContainerID); err == nil &&
+			status != storage.StatusStopped && status != storage.StatusError {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("stop the server before restoring a backup over it"))
+		}
+	}
+
+	// Replace whole top-level entries so files deleted since the backup don't linger
+	entries, err := files.ListZipTopLevel(backup.Path)
+	if err != nil {
+		s.log.Error("Failed to read backup %s: %v", backup.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to read backup archive"))
+	}
+	var count int
+	if server.DataPath == "" {
+		count, err = s.restoreIntoVolume(ctx, server, backup.Path, entries)
+	} else {
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(server.DataPath, entry)); err != nil {
+				s.log.Error("Failed to clear %s before restore: %v", entry, err)
+				return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to clear existing files"))
+			}
+		}
+		count, err = files.ExtractArchive(ctx, backup.Path, server.DataPath, nil)
+	}
+	if err != nil {
+		s.log.Error("Failed to restore backup %s: %v", backup.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to restore backup"))
+	}
+
+	s.log.Info("Restored backup %s over server %s (%d files)", backup.Name, server.ID, count)
+	return connect.NewResponse(&v1.RestoreBackupResponse{
+		ServerId:      server.ID,
+		FilesRestored: int32(count),
 	}), nil
 }
+
+// VerifyBackup checks one or all of a server's backups without restoring them
+func (s *ServerService) VerifyBackup(ctx context.Context, req *connect.Request[v1.VerifyBackupRequest]) (*connect.Response[v1.VerifyBackupResponse], error) {
+	msg := req.Msg
+	server, err := s.store.GetServer(ctx, msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	var backups []scheduler.BackupFile
+	if msg.BackupName != "" {
+		backup, err := scheduler.FindServerBackup(s.config.Storage.BackupDir, server, msg.BackupName)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		}
+		backups = append(backups, *backup)
+	} else {
+		backups, err = scheduler.ListServerBackups(s.config.Storage.BackupDir, server)
+		if err != nil {
+			s.log.Error("Failed to list backups: %v", err)
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list backups"))
+		}
+	}
+
+	resp := &v1.VerifyBackupResponse{Results: make([]*v1.BackupVerification, 0, len(backups))}
+	for i := range backups {
+		result := scheduler.VerifyBackup(ctx, &backups[i])
+		if !result.OK {
+			s.log.Warn("Backup %s of server %s failed verification: %s", result.Name, server.Name, strings.Join(result.Problems, "; "))
+		}
+		resp.Results = append(resp.Results, &v1.BackupVerification{
+			BackupName:   result.Name,
+			Ok:           result.OK,
+			FilesChecked: int32(result.FilesChecked),
+			BytesChecked: result.Bytes,
+			Worlds:       result.Worlds,
+			Problems:     result.Problems,
+			VerifiedAt:   timestamppb.New(result.VerifiedAt),
+		})
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// restoreToNewServer provisions a stopped copy of source with its own data dir and config,
+// then extracts the archive into it. The copy is never proxied and gets a free port.
+func (s *ServerService) restoreToNewServer(ctx context.Context, source *storage.Server, archivePath, name string) (*storage.Server, int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = source.Name + " (restored)"
+	}
+
+	usedPorts, err := s.usedServerPorts(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return nil, 0, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get available port"))
+	}
+	port, err := s.nextServerPort(usedPorts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	serverUUID := uuid.New().String()
+
+	restored := &storage.Server{
+		ID:              serverUUID,
+		Name:            name,
+		Description:     source.Description,
+		ModLoader:       source.ModLoader,
+		MCVersion:       source.MCVersion,
+		Status:          storage.StatusStopped,
+		Port:            port,
+		MaxPlayers:      source.MaxPlayers,
+		Memory:          source.Memory,
+		DataPath:        serverDataPath(s.config, name, serverUUID),
+		JavaVersion:     source.JavaVersion,
+		DockerImage:     source.DockerImage,
+		TPSCommand:      source.TPSCommand,
+		DockerOverrides: source.DockerOverrides,
+
+		ModpackID:        source.ModpackID,
+		ModpackVersionID: source.ModpackVersionID,
+	}
+
+	if err := checkCallerQuota(ctx, s.store, storage.QuotaUsage{Servers: 1, Memory: restored.Memory}); err != nil {
+		return nil, 0, err
+	}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		restored.OwnerID = user.ID
+	}
+
+	if err := s.createServerData(ctx, restored); err != nil {
+		s.log.Error("Failed to create data directory: %v", err)
+		return nil, 0, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server directory"))
+	}
+
+	var count int
+	if restored.DataPath == "" {
+		count, err = s.restoreIntoVolume(ctx, restored, archivePath, nil)
+	} else {
+		count, err = files.ExtractArchive(ctx, archivePath, restored.DataPath, nil)
+	}
+	if err != nil {
+		s.removeServerData(ctx, restored)
+		s.log.Error("Failed to extract backup into new server: %v", err)
+		return nil, 0, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to restore backup"))
+	}
+
+	if err := s.store.CreateServer(ctx, restored); err != nil {
+		s.removeServerData(ctx, restored)
+		s.log.Error("Failed to create server: %v", err)
+		return nil, 0, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server"))
+	}
+
+	if _, err := s.copyServerConfig(ctx, source, restored); err != nil {
+		s.log.Error("Failed to copy server config to restored server: %v", err)
+	}
+
+	return restored, count, nil
+}
+
+// Carries the source's settings over to target, keeping target's identity, port and RCON
+// password and minting a new management server secret
+func (s *ServerService) copyServerConfig(ctx context.Context, source, target *storage.Server) (*storage.ServerConfig, error) {
+	sourceConfig, err := s.store.GetServerConfig(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	targetConfig, err := s.store.GetServerConfig(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *sourceConfig
+	copied.ID = targetConfig.ID
+	copied.ServerID = target.ID
+	copied.Server = nil
+	copied.ServerPort = targetConfig.ServerPort
+	copied.RCONPassword = targetConfig.RCONPassword
+	if copied.ManagementServerSecret != nil && *copied.ManagementServerSecret != "" {
+		secret := uuid.New().String()
+		copied.ManagementServerSecret = &secret
+	}
+	if err := s.store.UpdateServerConfig(ctx, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// Resolves an enabled proxy listener, the default one (or first enabled) when listenerID is empty
+func (s *ServerService) proxyListener(ctx context.Context, listenerID string) (*storage.ProxyListener, error) {
+	if err := remoteProxyError(s.docker); err != nil {
+		return nil, err
+	}
+	if listenerID != "" {
+		listener, err := s.store.GetProxyListener(ctx, listenerID)
+		if err != nil || !listener.Enabled {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid or disabled proxy listener"))
+		}
+		return listener, nil
+	}
+
+	listeners, err := s.store.GetProxyListeners(ctx)
+	if err != nil || len(listeners) == 0 {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("no proxy listeners configured"))
+	}
+
+	// Find default or first enabled listener
+	for _, l := range listeners {
+		if l.IsDefault && l.Enabled {
+			return l, nil
+		}
+	}
+	for _, l := range listeners {
+		if l.Enabled {
+			return l, nil
+		}
+	}
+	return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("no enabled proxy listeners available"))
+}
+
+// Release types accepted on each modpack version channel
+var modpackChannelTypes = map[string][]string{
+	"release": {"release"},
+	"beta":    {"release", "beta"},
+	"alpha":   {"release", "beta", "alpha"},
+}
+
+// modpackVersionOrLatest resolves an empty or "latest" version to the newest file known to the index
+func modpackVersionOrLatest(modpack *storage.IndexedModpack, versionID string) string {
+	if versionID == "" || versionID == "latest" {
+		return modpack.LatestFileID
+	}
+	return versionID
+}
+
+// modpackReleases is a server's modpack with its published files, newest first
+type modpackReleases struct {
+	modpack   *storage.IndexedModpack
+	channel   string
+	files     []indexers.ModpackFile
+	installed *indexers.ModpackFile
+	latest    *indexers.ModpackFile // Newest file on the channel
+}
+
+// Fetches the releases of the modpack a server was built from
+func (s *ServerService) getModpackReleases(ctx context.Context, server *storage.Server) (*modpackReleases, error) {
+	if server.ModpackID == "" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("server was not created from a modpack"))
+	}
+
+	modpack, err := s.store.GetIndexedModpack(ctx, server.ModpackID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("modpack is no longer indexed"))
+	}
+	if modpack.Indexer == "manual" {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("uploaded modpacks have no published releases"))
+	}
+
+	indexerClient, err := newModpackIndexer(ctx, s.store, s.config, modpack.Indexer)
+	if err != nil {
+		return nil, err
+	}
+	files, err := indexerClient.GetModpackFiles(ctx, modpack.IndexerID)
+	if err != nil {
+		s.log.Error("Failed to get modpack files from %s: %v", modpack.Indexer, err)
+		return nil, mapIndexerError(err, "failed to get modpack releases")
+	}
+
+	// Lower sort index = newer release, as returned by the indexer
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].SortIndex < files[j].SortIndex
+	})
+
+	releases := &modpackReleases{modpack: modpack, channel: "release", files: files}
+	if serverConfig, err := s.store.GetServerConfig(ctx, server.ID); err == nil && serverConfig.ModrinthModpackVersionType != nil {
+		if _, ok := modpackChannelTypes[*serverConfig.ModrinthModpackVersionType]; ok {
+			releases.channel = *serverConfig.ModrinthModpackVersionType
+		}
+	}
+
+	for i := range files {
+		if files[i].ID == server.ModpackVersionID {
+			releases.installed = &files[i]
+		}
+		if releases.latest == nil && slices.Contains(modpackChannelTypes[releases.channel], files[i].ReleaseType) {
+			releases.latest = &files[i]
+		}
+	}
+
+	return releases, nil
+}
+
+// updateAvailable reports whether the channel's newest release is newer than the installed one
+func (r *modpackReleases) updateAvailable() bool {
+	if r.latest == nil {
+		return false
+	}
+	if r.installed == nil {
+		return true
+	}
+	return r.latest.SortIndex < r.installed.SortIndex
+}
+
+func modpackReleaseToProto(modpack *storage.IndexedModpack, file *indexers.ModpackFile) *v1.ModpackRelease {
+	if file == nil {
+		return nil
+	}
+
+	release := &v1.ModpackRelease{
+		Id:            file.ID,
+		DisplayName:   file.DisplayName,
+		VersionNumber: file.VersionNumber,
+		ReleaseType:   file.ReleaseType,
+		FileDate:      timestamppb.New(file.FileDate),
+	}
+	if modpack.WebsiteURL != "" {
+		switch modpack.Indexer {
+		case "modrinth":
+			release.ChangelogUrl = fmt.Sprintf("%s/version/%s", modpack.WebsiteURL, file.ID)
+		case "fuego":
+			release.ChangelogUrl = fmt.Sprintf("%s/files/%s", modpack.WebsiteURL, file.ID)
+		case "ftba":
+			release.ChangelogUrl = fmt.Sprintf("%s/versions", modpack.WebsiteURL)
+		}
+	}
+	return release
+}
+
+// GetModpackUpdate checks for a newer release of the server's modpack on its version channel
+func (s *ServerService) GetModpackUpdate(ctx context.Context, req *connect.Request[v1.GetModpackUpdateRequest]) (*connect.Response[v1.GetModpackUpdateResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	releases, err := s.getModpackReleases(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&v1.GetModpackUpdateResponse{
+		ModpackId:       releases.modpack.ID,
+		ModpackName:     releases.modpack.Name,
+		Channel:         releases.channel,
+		Installed:       modpackReleaseToProto(releases.modpack, releases.installed),
+		Latest:          modpackReleaseToProto(releases.modpack, releases.latest),
+		UpdateAvailable: releases.updateAvailable(),
+	}), nil
+}
+
+// UpdateModpack moves the server to another release of its modpack and recreates the container
+func (s *ServerService) UpdateModpack(ctx context.Context, req *connect.Request[v1.UpdateModpackRequest]) (*connect.Response[v1.UpdateModpackResponse], error) {
+	msg := req.Msg
+	server, err := s.store.GetServer(ctx, msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	releases, err := s.getModpackReleases(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	target := releases.latest
+	if msg.VersionId != "" {
+		target = nil
+		for i := range releases.files {
+			if releases.files[i].ID == msg.VersionId {
+				target = &releases.files[i]
+				break
+			}
+		}
+	}
+	if target == nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("modpack release not found"))
+	}
+
+	// UpdateServer owns the loader-specific modpack config and the container recreation
+	updated, err := s.UpdateServer(ctx, connect.NewRequest(&v1.UpdateServerRequest{
+		Id:               server.ID,
+		ModpackId:        releases.modpack.ID,
+		ModpackVersionId: target.ID,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Server %s modpack %s updated from %s to %s", server.Name, releases.modpack.Name, server.ModpackVersionID, target.ID)
+	return connect.NewResponse(&v1.UpdateModpackResponse{
+		Server:  updated.Msg.Server,
+		Release: modpackReleaseToProto(releases.modpack, target),
+	}), nil
+}
+
+// Lists JVM fatal error logs and heap dumps left in the server data dir
+func (s *ServerService) ListCrashDumps(ctx context.Context, req *connect.Request[v1.ListCrashDumpsRequest]) (*connect.Response[v1.ListCrashDumpsResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
+
+	dumps, err := files.FindCrashDumps(server.DataPath)
+	if err != nil {
+		s.log.Error("Failed to scan crash dumps: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list crash dumps"))
+	}
+
+	resp := &v1.ListCrashDumpsResponse{Dumps: make([]*v1.CrashDump, 0, len(dumps))}
+	for _, dump := range dumps {
+		resp.Dumps = append(resp.Dumps, &v1.CrashDump{
+			Path:       dump.Path,
+			Type:       dump.Type,
+			Size:       dump.Size,
+			ModifiedAt: timestamppb.New(dump.Modified),
+		})
+	}
+
+	if serverConfig, err := s.store.GetServerConfig(ctx, server.ID); err == nil && serverConfig.HeapDumpOnOOM != nil {
+		resp.CollectionEnabled = *serverConfig.HeapDumpOnOOM
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// Lists per-server access grants
+func (s *ServerService) ListServerACL(ctx context.Context, req *connect.Request[v1.ListServerACLRequest]) (*connect.Response[v1.ListServerACLResponse], error) {
+	if _, err := s.store.GetServer(ctx, req.Msg.Id); err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	acls, err := s.store.ListServerACLs(ctx, req.Msg.Id)
+	if err != nil {
+		s.log.Error("Failed to list server ACLs: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list server access"))
+	}
+
+	entries := make([]*v1.ServerACLEntry, 0, len(acls))
+	for _, acl := range acls {
+		entries = append(entries, dbServerACLToProto(acl))
+	}
+
+	return connect.NewResponse(&v1.ListServerACLResponse{Entries: entries}), nil
+}
+
+// Grants or updates a user's access level on a server
+func (s *ServerService) GrantServerACL(ctx context.Context, req *connect.Request[v1.GrantServerACLRequest]) (*connect.Response[v1.GrantServerACLResponse], error) {
+	msg := req.Msg
+
+	if !rbac.ValidACLLevel(msg.Level) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid access level %q", msg.Level))
+	}
+
+	if _, err := s.store.GetServer(ctx, msg.Id); err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	user, err := s.store.GetUser(ctx, msg.UserId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("user not found"))
+	}
+
+	acl, err := s.store.SetServerACL(ctx, msg.Id, user.ID, msg.Level)
+	if err != nil {
+		s.log.Error("Failed to grant server ACL: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to grant server access"))
+	}
+	acl.User = user
+
+	s.log.Info("Granted %s access on server %s to user %s", msg.Level, msg.Id, user.Username)
+	return connect.NewResponse(&v1.GrantServerACLResponse{Entry: dbServerACLToProto(acl)}), nil
+}
+
+// Removes a user's access grant from a server
+func (s *ServerService) RevokeServerACL(ctx context.Context, req *connect.Request[v1.RevokeServerACLRequest]) (*connect.Response[v1.RevokeServerACLResponse], error) {
+	if err := s.store.DeleteServerACL(ctx, req.Msg.Id, req.Msg.UserId); err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server access grant not found"))
+	}
+
+	s.log.Info("Revoked access on server %s from user %s", req.Msg.Id, req.Msg.UserId)
+	return connect.NewResponse(&v1.RevokeServerACLResponse{}), nil
+}
+
+func dbServerACLToProto(acl *storage.ServerACL) *v1.ServerACLEntry {
+	entry := &v1.ServerACLEntry{
+		UserId:    acl.UserID,
+		Level:     acl.Level,
+		CreatedAt: timestamppb.New(acl.CreatedAt),
+	}
+	if acl.User != nil {
+		entry.Username = acl.User.Username
+	}
+	return entry
+}
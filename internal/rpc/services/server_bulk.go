@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/auth"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/rbac"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Servers a bulk operation works on at once
+const bulkConcurrency = 4
+
+// How long a finished bulk operation can still be polled
+const bulkRetention = time.Hour
+
+// A user-driven action over several servers, results are guarded by the service's bulkMu
+type bulkOperation struct {
+	id         string
+	userID     string
+	action     v1.BulkAction
+	immediate  bool
+	results    []*v1.BulkServerResult
+	tasks      map[string]*storage.ScheduledTask // Server ID to the backup task to run
+	done       bool
+	createdAt  time.Time
+	finishedAt *time.Time
+}
+
+var bulkActionNames = map[v1.BulkAction]string{
+	v1.BulkAction_BULK_ACTION_START:   "start",
+	v1.BulkAction_BULK_ACTION_STOP:    "stop",
+	v1.BulkAction_BULK_ACTION_RESTART: "restart",
+	v1.BulkAction_BULK_ACTION_BACKUP:  "backup",
+}
+
+// Permission each lifecycle action needs on the servers it runs on, backups need update
+// on the backup task instead
+var bulkActionPermissions = map[v1.BulkAction]string{
+	v1.BulkAction_BULK_ACTION_START:   rbac.ActionStart,
+	v1.BulkAction_BULK_ACTION_STOP:    rbac.ActionStop,
+	v1.BulkAction_BULK_ACTION_RESTART: rbac.ActionRestart,
+}
+
+// StartBulkOperation queues an action on several servers and returns right away, the
+// servers are worked through in the background and the operation is polled for results
+func (s *ServerService) StartBulkOperation(ctx context.Context, req *connect.Request[v1.StartBulkOperationRequest]) (*connect.Response[v1.StartBulkOperationResponse], error) {
+	msg := req.Msg
+	if _, ok := bulkActionNames[msg.Action]; !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("action must be start, stop, restart or backup"))
+	}
+	if len(msg.ServerIds) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("at least one server ID is required"))
+	}
+
+	op := &bulkOperation{
+		id:        uuid.New().String(),
+		action:    msg.Action,
+		immediate: msg.Immediate,
+		tasks:     make(map[string]*storage.ScheduledTask),
+		createdAt: time.Now(),
+	}
+	user := auth.GetUserFromContext(ctx)
+	if user != nil {
+		op.userID = user.ID
+	}
+
+	seen := make(map[string]bool, len(msg.ServerIds))
+	var queued []int
+	for _, serverID := range msg.ServerIds {
+		if serverID == "" || seen[serverID] {
+			continue
+		}
+		seen[serverID] = true
+
+		result, task := s.prepareBulkServer(ctx, op, serverID)
+		if task != nil {
+			op.tasks[serverID] = task
+		}
+		if result.Status == v1.BulkResultStatus_BULK_RESULT_STATUS_PENDING {
+			queued = append(queued, len(op.results))
+		}
+		op.results = append(op.results, result)
+	}
+
+	s.bulkMu.Lock()
+	s.pruneBulkOperationsLocked()
+	s.bulkJobs[op.id] = op
+	if len(queued) == 0 {
+		s.finishBulkOperationLocked(op)
+	}
+	response := bulkOperationToProto(op)
+	s.bulkMu.Unlock()
+
+	if len(queued) > 0 {
+		s.log.Info("Bulk %s of %d servers started (operation %s)", bulkActionNames[op.action], len(queued), op.id)
+		// The request is over before the servers are, keep its values but not its deadline
+		go s.runBulkOperation(context.WithoutCancel(ctx), op, queued)
+	}
+
+	return connect.NewResponse(&v1.StartBulkOperationResponse{
+		Operation: response,
+	}), nil
+}
+
+// GetBulkOperation reports the progress of a bulk operation the caller started
+func (s *ServerService) GetBulkOperation(ctx context.Context, req *connect.Request[v1.GetBulkOperationRequest]) (*connect.Response[v1.GetBulkOperationResponse], error) {
+	s.bulkMu.Lock()
+	defer s.bulkMu.Unlock()
+
+	op, ok := s.bulkJobs[req.Msg.Id]
+	if user := auth.GetUserFromContext(ctx); ok && user != nil && op.userID != user.ID {
+		ok = false
+	}
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("bulk operation not found"))
+	}
+
+	return connect.NewResponse(&v1.GetBulkOperationResponse{
+		Operation: bulkOperationToProto(op),
+	}), nil
+}
+
+// Checks a server can take part in the operation. The result is pending when it can, the
+// task is the backup task to run for backups.
+func (s *ServerService) prepareBulkServer(ctx context.Context, op *bulkOperation, serverID string) (*v1.BulkServerResult, *storage.ScheduledTask) {
+	result := &v1.BulkServerResult{ServerId: serverID}
+
+	server, err := s.store.GetServer(ctx, serverID)
+	if err != nil {
+		result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_FAILED
+		result.Message = "server not found"
+		return result, nil
+	}
+	result.ServerName = server.Name
+
+	if op.action == v1.BulkAction_BULK_ACTION_BACKUP {
+		task, reason := s.bulkBackupTask(ctx, server)
+		if task == nil {
+			result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_SKIPPED
+			result.Message = reason
+			return result, nil
+		}
+		if !s.callerAllowed(ctx, rbac.ResourceTasks, rbac.ActionUpdate, task.ID) {
+			result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_SKIPPED
+			result.Message = "permission denied: tasks:update"
+			return result, nil
+		}
+		result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_PENDING
+		return result, task
+	}
+
+	// Detached servers live outside DiscoPanel's lifecycle, like on startup and shutdown
+	if server.Detached {
+		result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_SKIPPED
+		result.Message = "server is detached from DiscoPanel, start and stop it where it runs"
+		return result, nil
+	}
+	action := bulkActionPermissions[op.action]
+	if !s.callerAllowed(ctx, rbac.ResourceServers, action, server.ID) {
+		result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_SKIPPED
+		result.Message = "permission denied: servers:" + action
+		return result, nil
+	}
+
+	result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_PENDING
+	return result, nil
+}
+
+// Reports whether the caller may run the action on an object, for permissions beyond the
+// one the interceptor checked for the call. Both the API token's scope and the caller's
+// roles and grants must allow it, without a user or an enforcer nothing is allowed.
+func (s *ServerService) callerAllowed(ctx context.Context, resource, action, objectID string) bool {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil || s.enforcer == nil || !user.Allows(resource, action) {
+		return false
+	}
+	allowed, err := s.enforcer.EnforceUser(ctx, user.ID, user.Roles, resource, action, objectID)
+	return err == nil && allowed
+}
+
+// The server's backup task, its settings say what gets backed up and how many are kept.
+// An enabled task wins over a disabled one.
+func (s *ServerService) bulkBackupTask(ctx context.Context, server *storage.Server) (*storage.ScheduledTask, string) {
+	if s.scheduler == nil {
+		return nil, "scheduler is not running"
+	}
+	tasks, err := s.store.ListScheduledTasks(ctx, server.ID)
+	if err != nil {
+		s.log.Error("Failed to list tasks of server %s: %v", server.Name, err)
+		return nil, "failed to list the server's tasks"
+	}
+
+	var backup *storage.ScheduledTask
+	for _, task := range tasks {
+		if task.TaskType != storage.TaskTypeBackup {
+			continue
+		}
+		if task.Status == storage.TaskStatusEnabled {
+			return task, ""
+		}
+		if backup == nil {
+			backup = task
+		}
+	}
+	if backup == nil {
+		return nil, "server has no backup task, create one to set what gets backed up"
+	}
+	return backup, ""
+}
+
+// Works through the queued servers, a few at a time
+func (s *ServerService) runBulkOperation(ctx context.Context, op *bulkOperation, queued []int) {
+	sem := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for _, i := range queued {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s.bulkMu.Lock()
+			result := op.results[i]
+			result.Status = v1.BulkResultStatus_BULK_RESULT_STATUS_RUNNING
+			serverID := result.ServerId
+			s.bulkMu.Unlock()
+
+			status, message := s.runBulkAction(ctx, op, serverID)
+
+			s.bulkMu.Lock()
+			result.Status = status
+			result.Message = message
+			s.bulkMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	s.bulkMu.Lock()
+	s.finishBulkOperationLocked(op)
+	failed := 0
+	for _, result := range op.results {
+		if result.Status == v1.BulkResultStatus_BULK_RESULT_STATUS_FAILED {
+			failed++
+		}
+	}
+	s.bulkMu.Unlock()
+	s.log.Info("Bulk %s finished with %d of %d servers failed (operation %s)", bulkActionNames[op.action], failed, len(op.results), op.id)
+}
+
+// Runs the action on one server through the same handlers the single server calls use
+func (s *ServerService) runBulkAction(ctx context.Context, op *bulkOperation, serverID string) (v1.BulkResultStatus, string) {
+	var status string
+	var err error
+	switch op.action {
+	case v1.BulkAction_BULK_ACTION_START:
+		var resp *connect.Response[v1.StartServerResponse]
+		if resp, err = s.StartServer(ctx, connect.NewRequest(&v1.StartServerRequest{Id: serverID})); err == nil {
+			status = resp.Msg.Status
+		}
+	case v1.BulkAction_BULK_ACTION_STOP:
+		var resp *connect.Response[v1.StopServerResponse]
+		if resp, err = s.StopServer(ctx, connect.NewRequest(&v1.StopServerRequest{Id: serverID, Immediate: op.immediate})); err == nil {
+			status = resp.Msg.Status
+		}
+	case v1.BulkAction_BULK_ACTION_RESTART:
+		var resp *connect.Response[v1.RestartServerResponse]
+		if resp, err = s.RestartServer(ctx, connect.NewRequest(&v1.RestartServerRequest{Id: serverID, Immediate: op.immediate})); err == nil {
+			status = resp.Msg.Status
+		}
+	case v1.BulkAction_BULK_ACTION_BACKUP:
+		return s.runBulkBackup(ctx, op.tasks[serverID])
+	}
+
+	if err != nil {
+		if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+			return v1.BulkResultStatus_BULK_RESULT_STATUS_FAILED, connectErr.Message()
+		}
+		return v1.BulkResultStatus_BULK_RESULT_STATUS_FAILED, err.Error()
+	}
+	return v1.BulkResultStatus_BULK_RESULT_STATUS_SUCCEEDED, status
+}
+
+// Runs a backup task like a manual trigger, so it shows up in the task's history
+func (s *ServerService) runBulkBackup(ctx context.Context, task *storage.ScheduledTask) (v1.BulkResultStatus, string) {
+	execution, err := s.scheduler.TriggerTask(ctx, task.ID)
+	if err != nil {
+		s.log.Error("Failed to trigger backup task %s: %v", task.Name, err)
+		return v1.BulkResultStatus_BULK_RESULT_STATUS_FAILED, fmt.Sprintf("failed to trigger backup task %s", task.Name)
+	}
+
+	switch execution.Status {
+	case storage.ExecutionStatusCompleted:
+		return v1.BulkResultStatus_BULK_RESULT_STATUS_SUCCEEDED, fmt.Sprintf("backup task %s completed", task.Name)
+	case storage.ExecutionStatusSkipped:
+		return v1.BulkResultStatus_BULK_RESULT_STATUS_SKIPPED, fmt.Sprintf("backup task %s skipped: %s", task.Name, execution.Error)
+	default:
+		return v1.BulkResultStatus_BULK_RESULT_STATUS_FAILED, fmt.Sprintf("backup task %s failed: %s", task.Name, execution.Error)
+	}
+}
+
+func (s *ServerService) finishBulkOperationLocked(op *bulkOperation) {
+	now := time.Now()
+	op.done = true
+	op.finishedAt = &now
+}
+
+// Forgets finished operations nobody polled within the retention
+func (s *ServerService) pruneBulkOperationsLocked() {
+	for id, op := range s.bulkJobs {
+		if op.done && time.Since(*op.finishedAt) > bulkRetention {
+			delete(s.bulkJobs, id)
+		}
+	}
+}
+
+// Copies the operation so the caller can use it after releasing bulkMu
+func bulkOperationToProto(op *bulkOperation) *v1.BulkOperation {
+	results := make([]*v1.BulkServerResult, len(op.results))
+	for i, result := range op.results {
+		results[i] = &v1.BulkServerResult{
+			ServerId:   result.ServerId,
+			ServerName: result.ServerName,
+			Status:     result.Status,
+			Message:    result.Message,
+		}
+	}
+
+	proto := &v1.BulkOperation{
+		Id:        op.id,
+		Action:    op.action,
+		Results:   results,
+		Done:      op.done,
+		CreatedAt: timestamppb.New(op.createdAt),
+	}
+	if op.finishedAt != nil {
+		proto.FinishedAt = timestamppb.New(*op.finishedAt)
+	}
+	return proto
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/auth"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/proxy"
+	"github.com/nickheyer/discopanel/internal/rbac"
+	"github.com/nickheyer/discopanel/pkg/files"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// Top-level entries of a data directory that belong to one run of the server
+var cloneSkipped = []string{"logs", "crash-reports", files.CrashDumpDir}
+
+// CloneServer creates a new server like the source: its settings, mods and config files,
+// and its worlds when asked. The copy gets its own data directory, port or hostname and
+// RCON password, and is returned in the creating state while its files and container are made.
+func (s *ServerService) CloneServer(ctx context.Context, req *connect.Request[v1.CloneServerRequest]) (*connect.Response[v1.CloneServerResponse], error) {
+	msg := req.Msg
+
+	source, err := s.store.GetServer(ctx, msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+
+	// Creating a server needs its own permission on top of read on the source
+	if !s.callerAllowed(ctx, rbac.ResourceServers, rbac.ActionCreate, "*") {
+		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("permission denied: servers:create"))
+	}
+
+	name := strings.TrimSpace(msg.Name)
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+
+	serverUUID := uuid.New().String()
+	clone := &storage.Server{
+		ID:              serverUUID,
+		Name:            name,
+		Description:     source.Description,
+		ModLoader:       source.ModLoader,
+		MCVersion:       source.MCVersion,
+		Status:          storage.StatusCreating,
+		MaxPlayers:      source.MaxPlayers,
+		Memory:          source.Memory,
+		CPULimit:        source.CPULimit,
+		SwapLimit:       source.SwapLimit,
+		DataPath:        serverDataPath(s.config, name, serverUUID),
+		JavaVersion:     source.JavaVersion,
+		DockerImage:     source.DockerImage,
+		AutoStart:       source.AutoStart,
+		Detached:        source.Detached,
+		TPSCommand:      source.TPSCommand,
+		DockerOverrides: source.DockerOverrides,
+
+		ModpackID:        source.ModpackID,
+		ModpackVersionID: source.ModpackVersionID,
+
+		RestartOnUnhealthy:    source.RestartOnUnhealthy,
+		UnhealthyRestartAfter: source.UnhealthyRestartAfter,
+		MaxUnhealthyRestarts:  source.MaxUnhealthyRestarts,
+	}
+
+	// Additional ports are left out, their host ports belong to the source
+	if msg.ProxyHostname != "" {
+		fqdn, subdomain, err := proxy.ResolveHostname(msg.ProxyHostname, s.config.Proxy.BaseURL, msg.UseBaseUrl)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		listener, err := s.proxyListener(ctx, source.ProxyListenerID)
+		if err != nil {
+			return nil, err
+		}
+		clone.ProxyHostname = fqdn
+		clone.ProxySubdomain = subdomain
+		clone.ProxyListenerID = listener.ID
+		clone.ProxyPort = listener.Port
+		clone.Port = 25565 // Internal container port for proxied servers
+	} else {
+		port, err := s.clonePort(ctx, int(msg.Port))
+		if err != nil {
+			return nil, err
+		}
+		clone.Port = port
+	}
+
+	if err := checkCallerQuota(ctx, s.store, storage.QuotaUsage{Servers: 1, Memory: clone.Memory}); err != nil {
+		return nil, err
+	}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		clone.OwnerID = user.ID
+	}
+
+	if err := s.createServerData(ctx, clone); err != nil {
+		s.log.Error("Failed to create data directory: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server directory"))
+	}
+
+	if err := s.store.CreateServer(ctx, clone); err != nil {
+		s.removeServerData(ctx, clone)
+		s.log.Error("Failed to create server: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server"))
+	}
+
+	serverConfig, err := s.copyServerConfig(ctx, source, clone)
+	if err != nil {
+		s.log.Error("Failed to copy server config to cloned server: %v", err)
+		if serverConfig, err = s.store.GetServerConfig(ctx, clone.ID); err != nil {
+			serverConfig = s.store.CreateDefaultServerConfig(clone.ID)
+		}
+	}
+
+	s.log.Info("Cloning server %s into %s (worlds: %t)", source.Name, clone.ID, msg.CopyWorld)
+
+	// Built before the background work below starts changing the server
+	var dataDir string
+	if clone.DataVolume == "" {
+		dataDir = filepath.Base(clone.DataPath)
+	}
+	resp := connect.NewResponse(&v1.CloneServerResponse{
+		Server:  dbServerToProto(clone),
+		DataDir: dataDir,
+	})
+
+	go func() {
+		bgCtx := context.Background()
+
+		// Flush the source's worlds so the copy isn't caught mid-save
+		resumeSaves := func() {}
+		if msg.CopyWorld && s.scheduler != nil {
+			resumeSaves = s.scheduler.PauseWorldSaves(bgCtx, source)
+		}
+		err := s.copyCloneFiles(bgCtx, source, clone, msg.CopyWorld)
+		resumeSaves()
+		if err != nil {
+			s.log.Error("Failed to copy files of server %s into %s: %v", source.Name, clone.ID, err)
+			clone.Status = storage.StatusError
+			clone.LastError = "failed to copy the source server's files"
+			if err := s.store.UpdateServer(bgCtx, clone); err != nil {
+				s.log.Error("Failed to update server status to error: %v", err)
+			}
+			return
+		}
+
+		if clone.DataPath != "" {
+			uid, gid := configUser(serverConfig)
+			if err := files.ChownTree(clone.DataPath, uid, gid); err != nil {
+				s.log.Warn("Failed to chown data directory of server %s: %v", clone.ID, err)
+			}
+		}
+
+		s.provisionContainer(clone, serverConfig, msg.StartImmediately)
+	}()
+
+	return resp, nil
+}
+
+// Checks a requested port for a clone off the proxy, or picks a free one
+func (s *ServerService) clonePort(ctx context.Context, port int) (int, error) {
+	used, err := s.usedServerPorts(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return 0, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get available port"))
+	}
+	if port == 0 {
+		return s.nextServerPort(used)
+	}
+
+	if port < 1 || port > 65535 {
+		return 0, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid port %d", port))
+	}
+	if used[int32(port)] || used[int32(port+docker.RCONPortOffset)] {
+		return 0, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("port already in use"))
+	}
+	if s.config.Proxy.Enabled && slices.Contains(s.config.Proxy.ListenPorts, port) {
+		return 0, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("port is already in use by the proxy server"))
+	}
+	return port, nil
+}
+
+// Copies the source's files into the clone, staging either side that's in a data volume
+// DiscoPanel can't see in a scratch directory copied through Docker
+func (s *ServerService) copyCloneFiles(ctx context.Context, source, clone *storage.Server, copyWorld bool) error {
+	src := source.DataPath
+	if src == "" {
+		staging, err := volumeStaging(s.config.Storage.TempDir, "clone")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(staging)
+		if _, err := s.docker.CopyFromVolume(ctx, source, "", staging); err != nil {
+			return err
+		}
+		src = staging
+	}
+
+	if clone.DataPath != "" {
+		return copyServerFiles(src, clone.DataPath, copyWorld)
+	}
+
+	staging, err := volumeStaging(s.config.Storage.TempDir, "clone")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := copyServerFiles(src, staging, copyWorld); err != nil {
+		return err
+	}
+	uid, gid := serverUser(ctx, s.store, clone)
+	return s.docker.CopyToVolume(ctx, clone, staging, "", uid, gid)
+}
+
+// Copies a server's data directory into another, leaving out logs, crash reports and dumps,
+// and directories holding a level.dat unless copyWorld is set
+func copyServerFiles(src, dst string, copyWorld bool) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if slices.Contains(cloneSkipped, entry.Name()) {
+			continue
+		}
+		from := filepath.Join(src, entry.Name())
+		to := filepath.Join(dst, entry.Name())
+
+		switch {
+		case entry.IsDir():
+			if !copyWorld {
+				if _, err := os.Stat(filepath.Join(from, "level.dat")); err == nil {
+					continue
+				}
+			}
+			if err := files.CopyDir(from, to); err != nil {
+				return err
+			}
+		case entry.Type().IsRegular():
+			if err := files.CopyFile(from, to); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickheyer/discopanel/pkg/files"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func TestCopyServerFilesSkipsRunFiles(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "server.properties"), "motd=hello\n")
+	writeTestFile(t, filepath.Join(src, "config", "mod.toml"), "enabled = true\n")
+	writeTestFile(t, filepath.Join(src, "world", "level.dat"), "level")
+	writeTestFile(t, filepath.Join(src, "logs", "latest.log"), "log")
+	writeTestFile(t, filepath.Join(src, "crash-reports", "crash-2026-10-14.txt"), "crash")
+	writeTestFile(t, filepath.Join(src, files.CrashDumpDir, "hs_err_pid1.log"), "dump")
+
+	for _, copyWorld := range []bool{false, true} {
+		dst := t.TempDir()
+		if err := copyServerFiles(src, dst, copyWorld); err != nil {
+			t.Fatalf("copyServerFiles(copyWorld %t): %v", copyWorld, err)
+		}
+
+		for _, path := range []string{"server.properties", filepath.Join("config", "mod.toml")} {
+			if _, err := os.Stat(filepath.Join(dst, path)); err != nil {
+				t.Errorf("copyWorld %t: %s not copied: %v", copyWorld, path, err)
+			}
+		}
+		for _, dir := range []string{"logs", "crash-reports", files.CrashDumpDir} {
+			if _, err := os.Stat(filepath.Join(dst, dir)); !os.IsNotExist(err) {
+				t.Errorf("copyWorld %t: %s was copied", copyWorld, dir)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dst, "world", "level.dat")); (err == nil) != copyWorld {
+			t.Errorf("copyWorld %t: world copied = %t", copyWorld, err == nil)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/nickheyer/discopanel/internal/auth"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/minecraft"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// ImportServer adopts an existing itzg/minecraft-server container. The container and its
+// data stay as they are, its image, ports, memory limit and env are read back into the
+// server and its config so DiscoPanel can rebuild an equivalent container later.
+func (s *ServerService) ImportServer(ctx context.Context, req *connect.Request[v1.ImportServerRequest]) (*connect.Response[v1.ImportServerResponse], error) {
+	msg := req.Msg
+
+	containerRef := strings.TrimSpace(msg.Container)
+	if containerRef == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("container is required"))
+	}
+
+	existing, err := s.docker.InspectExistingContainer(ctx, containerRef)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check existing servers"))
+	}
+	for _, server := range servers {
+		if server.ContainerID == existing.ID {
+			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("container is already imported as %s", server.Name))
+		}
+	}
+
+	if conflict, err := s.store.GetServerByPort(ctx, existing.HostPort); err != nil {
+		s.log.Error("Failed to check port: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check port availability"))
+	} else if conflict != nil {
+		return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("port %d is already used by %s", existing.HostPort, conflict.Name))
+	}
+	if s.config.Proxy.Enabled && slices.Contains(s.config.Proxy.ListenPorts, existing.HostPort) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("port %d is already in use by the proxy server", existing.HostPort))
+	}
+	additionalPorts, err := s.validateAdditionalPorts(ctx, "", existing.HostPort, existing.AdditionalPorts)
+	if err != nil {
+		return nil, err
+	}
+
+	env := existing.Env
+	modLoader := storage.ModLoaderVanilla
+	if serverType := strings.ToLower(env["TYPE"]); serverType != "" {
+		known := slices.ContainsFunc(minecraft.GetAllModLoaders(), func(info minecraft.ModLoaderInfo) bool {
+			return info.Name == serverType
+		})
+		if !known {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unsupported server type %s", env["TYPE"]))
+		}
+		modLoader = storage.ModLoader(serverType)
+	}
+
+	mcVersion := env["VERSION"]
+	if mcVersion == "" {
+		mcVersion = "LATEST"
+	}
+
+	// Without a container limit, size it like CreateServer does, heap at 75% of the allocation
+	memory := existing.Memory
+	if memory == 0 {
+		heap := docker.ParseMemoryMB(env["MAX_MEMORY"])
+		if heap == 0 {
+			heap = docker.ParseMemoryMB(env["MEMORY"])
+		}
+		memory = heap * 4 / 3
+	}
+	if memory == 0 {
+		memory = s.config.Minecraft.DefaultMemory
+	}
+
+	maxPlayers, _ := strconv.Atoi(env["MAX_PLAYERS"])
+	if maxPlayers <= 0 {
+		maxPlayers = s.config.Minecraft.DefaultMaxPlayers
+	}
+
+	name := strings.TrimSpace(msg.Name)
+	if name == "" {
+		name = existing.Name
+	}
+
+	status, err := s.docker.GetContainerStatus(ctx, existing.ID)
+	if err != nil {
+		status = storage.StatusStopped
+	}
+
+	server := &storage.Server{
+		ID:              uuid.New().String(),
+		Name:            name,
+		Description:     msg.Description,
+		ModLoader:       modLoader,
+		MCVersion:       mcVersion,
+		ContainerID:     existing.ID,
+		Status:          status,
+		Port:            existing.HostPort,
+		MaxPlayers:      maxPlayers,
+		Memory:          memory,
+		DataPath:        existing.DataPath,
+		DataVolume:      existing.DataVolume,
+		JavaVersion:     docker.GetRequiredJavaVersion(mcVersion, modLoader),
+		DockerImage:     existing.ImageTag,
+		Detached:        true, // It ran without DiscoPanel so far, don't stop it with DiscoPanel
+		TPSCommand:      minecraft.GetTPSCommand(modLoader),
+		AdditionalPorts: additionalPorts,
+		Imported:        true,
+	}
+
+	if err := checkCallerQuota(ctx, s.store, storage.QuotaUsage{Servers: 1, Memory: server.Memory}); err != nil {
+		return nil, err
+	}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		server.OwnerID = user.ID
+	}
+
+	if err := s.store.CreateServer(ctx, server); err != nil {
+		s.log.Error("Failed to create imported server: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create server"))
+	}
+
+	// Layer the container's env over the defaults so a rebuilt container runs the same way
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		serverConfig = s.store.CreateDefaultServerConfig(server.ID)
+	}
+	docker.ApplyEnvToConfig(serverConfig, env)
+	if serverConfig.MaxMemory == nil && serverConfig.Memory == nil && serverConfig.InitMemory == nil {
+		strMax := fmt.Sprintf("%dM", int(float64(server.Memory)*0.75))
+		serverConfig.MaxMemory = &strMax
+		strMin := fmt.Sprintf("%dM", int(float64(server.Memory)*0.45))
+		serverConfig.InitMemory = &strMin
+	}
+	if err := s.store.SaveServerConfig(ctx, serverConfig); err != nil {
+		s.log.Error("Failed to save imported server config: %v", err)
+	} else if err := s.store.SyncServerConfigWithServer(ctx, server); err != nil {
+		s.log.Warn("Failed to sync imported server config: %v", err)
+	}
+
+	s.log.Info("Imported container %s (%s) as server %s", existing.Name, existing.ID[:12], server.ID)
+
+	return connect.NewResponse(&v1.ImportServerResponse{
+		Server: dbServerToProto(server),
+	}), nil
+}
+
+// Imported servers still run their original container, replacing it needs the caller's go-ahead
+func requireRecreateConfirmed(server *storage.Server, confirmed bool) error {
+	if server.Imported && !confirmed {
+		return connect.NewError(connect.CodeFailedPrecondition, errors.New("server runs an imported container, confirm to replace it with one built by DiscoPanel"))
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/nickheyer/discopanel/internal/config"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/pkg/logger"
+)
+
+// Just enough of the Docker API to create, inspect and remove containers
+type fakeDocker struct {
+	mu         sync.Mutex
+	next       int
+	containers map[string]*container.HostConfig
+}
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("API-Version", "1.47")
+	w.Header().Set("Content-Type", "application/json")
+	path := apiVersionPrefix.ReplaceAllString(r.URL.Path, "")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+	case path == "/version":
+		json.NewEncoder(w).Encode(map[string]any{"ApiVersion": "1.47"})
+	case path == "/info":
+		json.NewEncoder(w).Encode(map[string]any{})
+	case parts[0] == "distribution":
+		json.NewEncoder(w).Encode(map[string]any{"Descriptor": map[string]any{"digest": "sha256:" + strings.Repeat("0", 64)}})
+	case path == "/images/create":
+		w.Write([]byte("{}"))
+	case path == "/containers/create":
+		var req container.CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.next++
+		id := fmt.Sprintf("container-%d", f.next)
+		f.containers[id] = req.HostConfig
+		json.NewEncoder(w).Encode(container.CreateResponse{ID: id})
+	case parts[0] == "containers" && len(parts) >= 2:
+		hostConfig, ok := f.containers[parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No such container: " + parts[1]})
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.containers, parts[1])
+			w.WriteHeader(http.StatusNoContent)
+		case len(parts) == 3 && parts[2] == "json":
+			json.NewEncoder(w).Encode(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:         parts[1],
+					State:      &container.State{Status: "exited"},
+					HostConfig: hostConfig,
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "not implemented: " + path})
+	}
+}
+
+func (f *fakeDocker) portBindings(containerID string) nat.PortMap {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if hostConfig := f.containers[containerID]; hostConfig != nil {
+		return hostConfig.PortBindings
+	}
+	return nil
+}
+
+// A server service backed by a fresh database and the fake daemon, with one direct server
+// whose container already exists
+func newPortsTestService(t *testing.T) (*ServerService, *fakeDocker, *storage.Server) {
+	t.Helper()
+	ctx := context.Background()
+
+	daemon := &fakeDocker{containers: make(map[string]*container.HostConfig)}
+	httpServer := httptest.NewServer(daemon)
+	t.Cleanup(httpServer.Close)
+
+	log := logger.New()
+	dockerClient, err := docker.NewClient("tcp://"+httpServer.Listener.Addr().String(), log, docker.ClientConfig{})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { dockerClient.Close() })
+
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "discopanel.db")
+	cfg.Database.AutoMigrate = true
+	store, err := storage.NewStore(cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	server := &storage.Server{
+		ID:          "ports-test",
+		Name:        "Ports Test",
+		ModLoader:   storage.ModLoaderVanilla,
+		MCVersion:   "1.21.1",
+		DockerImage: "java21",
+		Port:        25570,
+		Memory:      2048,
+		DataPath:    t.TempDir(),
+	}
+	if err := store.CreateServer(ctx, server); err != nil {
+		t.Fatalf("CreateServer: %v", err)
+	}
+	serverConfig, err := store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		t.Fatalf("GetServerConfig: %v", err)
+	}
+	server.ContainerID, err = dockerClient.CreateContainer(ctx, server, serverConfig)
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+	if err := store.UpdateServer(ctx, server); err != nil {
+		t.Fatalf("UpdateServer: %v", err)
+	}
+
+	svc := NewServerService(store, dockerClient, nil, cfg, nil, nil, nil, nil, nil, nil, nil, log)
+	return svc, daemon, server
+}
+
+// Fails unless the game port is neither published on all interfaces nor flagged
+func assertProxiedBindings(t *testing.T, daemon *fakeDocker, server *storage.Server) {
+	t.Helper()
+	bindings := daemon.portBindings(server.ContainerID)
+	for key, published := range bindings {
+		for _, binding := range published {
+			if binding.HostIP == "0.0.0.0" && (key.Int() == server.Port || key.Int() == docker.DefaultMinecraftPort) {
+				t.Errorf("proxied server still publishes %s on %s:%s", key, binding.HostIP, binding.HostPort)
+			}
+		}
+	}
+	if mismatch := docker.PortBindingMismatch(bindings, server); mismatch != "" {
+		t.Errorf("recreated container bindings don't match: %s", mismatch)
+	}
+	if server.PortBindingWarning != "" {
+		t.Errorf("PortBindingWarning = %q, want empty", server.PortBindingWarning)
+	}
+}
+
+func TestSyncContainerPortsDropsGamePortWhenProxied(t *testing.T) {
+	ctx := context.Background()
+	svc, daemon, server := newPortsTestService(t)
+
+	direct := daemon.portBindings(server.ContainerID)[nat.Port(fmt.Sprintf("%d/tcp", server.Port))]
+	if len(direct) != 1 || direct[0].HostIP != "0.0.0.0" {
+		t.Fatalf("direct server game port bindings = %v, want one on 0.0.0.0", direct)
+	}
+
+	oldContainerID := server.ContainerID
+	server.ProxyHostname = "survival.example.com"
+	if err := svc.store.UpdateServer(ctx, server); err != nil {
+		t.Fatalf("UpdateServer: %v", err)
+	}
+	stale, err := svc.docker.HasStaleGamePortBinding(ctx, server.ContainerID, server)
+	if err != nil || !stale {
+		t.Fatalf("HasStaleGamePortBinding = %v, %v, want true", stale, err)
+	}
+
+	if err := svc.syncContainerPorts(ctx, server); err != nil {
+		t.Fatalf("syncContainerPorts: %v", err)
+	}
+	if server.ContainerID == oldContainerID {
+		t.Fatal("container was not recreated")
+	}
+	assertProxiedBindings(t, daemon, server)
+
+	stored, err := svc.store.GetServer(ctx, server.ID)
+	if err != nil {
+		t.Fatalf("GetServer: %v", err)
+	}
+	if stored.ContainerID != server.ContainerID {
+		t.Errorf("stored container ID = %q, want %q", stored.ContainerID, server.ContainerID)
+	}
+}
+
+func TestApplyPendingChangesDropsGamePortWhenProxied(t *testing.T) {
+	ctx := context.Background()
+	svc, daemon, server := newPortsTestService(t)
+
+	server.ProxyHostname = "survival.example.com"
+	server.RestartRequired = true
+	server.PortBindingWarning = "proxied server publishes 25570->25570/tcp on the host"
+	if _, err := svc.applyPendingChanges(ctx, server); err != nil {
+		t.Fatalf("applyPendingChanges: %v", err)
+	}
+	if server.RestartRequired {
+		t.Error("RestartRequired still set after applying pending changes")
+	}
+	assertProxiedBindings(t, daemon, server)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/minecraft"
+)
+
+// Used when a server has shutdown warnings but no message of its own
+const defaultStopWarningMessage = "Server {action} in {seconds} seconds"
+
+// A running pre-stop countdown, action is what happens once it runs out
+type shutdownCountdown struct {
+	action string // stopping or restarting
+	cancel context.CancelFunc
+}
+
+// Parses comma separated warning marks in seconds, longest first
+func parseStopWarnings(value string) ([]int, error) {
+	var marks []int
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%q is not a positive number of seconds", part)
+		}
+		marks = append(marks, n)
+	}
+	slices.Sort(marks)
+	marks = slices.Compact(marks)
+	slices.Reverse(marks)
+	return marks, nil
+}
+
+// Warning marks to count down through before the server goes down, nil when it should go
+// down right away because no warnings are configured, it isn't running or nobody is online
+func (s *ServerService) stopWarningMarks(ctx context.Context, server *storage.Server) ([]int, *storage.ServerConfig) {
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil || serverConfig.StopWarnings == nil {
+		return nil, nil
+	}
+	marks, err := parseStopWarnings(*serverConfig.StopWarnings)
+	if err != nil || len(marks) == 0 {
+		return nil, nil
+	}
+	if status, err := s.docker.GetContainerStatus(ctx, server.ContainerID); err != nil || status != storage.StatusRunning {
+		return nil, nil
+	}
+	if s.playersOnline(ctx, server) == 0 {
+		return nil, nil
+	}
+	return marks, serverConfig
+}
+
+// Asks the server who is online, falling back to the collector's last count
+func (s *ServerService) playersOnline(ctx context.Context, server *storage.Server) int {
+	if output, err := s.sender.SendCommand(ctx, server.ID, "list"); err == nil && output != "" {
+		count, _ := minecraft.ParsePlayerListFromOutput(output)
+		return count
+	}
+	if s.metricsCollector != nil {
+		if m := s.metricsCollector.GetMetrics(server.ID); m != nil {
+			return m.PlayersOnline
+		}
+	}
+	return 0
+}
+
+// Registers a countdown for the server, false when one is already running
+func (s *ServerService) beginCountdown(serverID, action string) (context.Context, *shutdownCountdown, bool) {
+	s.countdownMu.Lock()
+	defer s.countdownMu.Unlock()
+	if _, ok := s.countdowns[serverID]; ok {
+		return nil, nil, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	countdown := &shutdownCountdown{action: action, cancel: cancel}
+	s.countdowns[serverID] = countdown
+	return ctx, countdown, true
+}
+
+// Action of the server's running countdown, empty when there is none
+func (s *ServerService) countdownAction(serverID string) string {
+	s.countdownMu.Lock()
+	defer s.countdownMu.Unlock()
+	if countdown, ok := s.countdowns[serverID]; ok {
+		return countdown.action
+	}
+	return ""
+}
+
+// Cancels the server's countdown. A non-nil countdown only ends that one, so a finished
+// countdown doesn't end another that was started after it was cut short.
+func (s *ServerService) endCountdown(serverID string, countdown *shutdownCountdown) {
+	s.countdownMu.Lock()
+	defer s.countdownMu.Unlock()
+	current, ok := s.countdowns[serverID]
+	if !ok || (countdown != nil && current != countdown) {
+		return
+	}
+	current.cancel()
+	delete(s.countdowns, serverID)
+}
+
+// Checks whether a stop or restart should wait for a countdown. It returns the response
+// status to report right away, or empty when the caller should go ahead now.
+func (s *ServerService) deferToCountdown(ctx context.Context, server *storage.Server, action string, immediate bool, then func(context.Context, *storage.Server)) (string, error) {
+	if running := s.countdownAction(server.ID); running != "" {
+		if immediate {
+			s.endCountdown(server.ID, nil)
+			return "", nil
+		}
+		if running != action {
+			return "", fmt.Errorf("server is already %s after a countdown", running)
+		}
+		return action, nil
+	}
+	if immediate {
+		return "", nil
+	}
+
+	marks, serverConfig := s.stopWarningMarks(ctx, server)
+	if marks == nil {
+		return "", nil
+	}
+	countdownCtx, countdown, ok := s.beginCountdown(server.ID, action)
+	if !ok {
+		return action, nil
+	}
+
+	s.log.Info("Server %s %s in %d seconds, warning online players", server.ID, action, marks[0])
+	go func() {
+		defer s.endCountdown(server.ID, countdown)
+		if !s.warnPlayers(countdownCtx, server, serverConfig, action, marks) {
+			return
+		}
+		// The server may have changed while players were being warned
+		current, err := s.store.GetServer(countdownCtx, server.ID)
+		if err != nil {
+			s.log.Error("Failed to load server %s after countdown: %v", server.ID, err)
+			return
+		}
+		then(countdownCtx, current)
+	}()
+	return action, nil
+}
+
+// Warns online players at each mark and saves the world once the countdown runs out.
+// False when the countdown was cut short.
+func (s *ServerService) warnPlayers(ctx context.Context, server *storage.Server, serverConfig *storage.ServerConfig, action string, marks []int) bool {
+	message := defaultStopWarningMessage
+	if serverConfig.StopWarningMessage != nil && strings.TrimSpace(*serverConfig.StopWarningMessage) != "" {
+		message = *serverConfig.StopWarningMessage
+	}
+	showTitle := serverConfig.StopWarningTitle == nil || *serverConfig.StopWarningTitle
+
+	for i, seconds := range marks {
+		text := strings.NewReplacer("{action}", action, "{seconds}", strconv.Itoa(seconds)).Replace(message)
+		s.sendWarning(ctx, server, "say "+text)
+		if showTitle {
+			// A quoted string is a plain text component in both JSON and SNBT
+			quoted, _ := json.Marshal(text)
+			s.sendWarning(ctx, server, "title @a actionbar "+string(quoted))
+		}
+
+		next := 0
+		if i+1 < len(marks) {
+			next = marks[i+1]
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Duration(seconds-next) * time.Second):
+		}
+	}
+
+	s.sendWarning(ctx, server, "save-all")
+	return ctx.Err() == nil
+}
+
+func (s *ServerService) sendWarning(ctx context.Context, server *storage.Server, command string) {
+	if _, err := s.sender.SendCommand(ctx, server.ID, command); err != nil {
+		s.log.Debug("Failed to send %q to server %s: %v", command, server.ID, err)
+	}
+}
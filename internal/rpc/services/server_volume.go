@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/pkg/files"
+)
+
+// Refuses features that work on the server's files directly when they are in a Docker
+// volume DiscoPanel can't see. Worlds, backups and clones go through the Docker API instead.
+func requireLocalData(server *storage.Server) error {
+	if server.DataPath == "" {
+		return connect.NewError(connect.CodeFailedPrecondition, errors.New("server files are in a Docker volume DiscoPanel can't see, set docker.volumes_dir to manage them here"))
+	}
+	return nil
+}
+
+// The UID and GID the server runs as, files DiscoPanel writes for it are handed to them
+func configUser(serverConfig *storage.ServerConfig) (int, int) {
+	uid, gid := 1000, 1000
+	if serverConfig == nil {
+		return uid, gid
+	}
+	if serverConfig.UID != nil {
+		uid = *serverConfig.UID
+	}
+	if serverConfig.GID != nil {
+		gid = *serverConfig.GID
+	}
+	return uid, gid
+}
+
+// The UID and GID of a stored server, the defaults when its config can't be read
+func serverUser(ctx context.Context, store *storage.Store, server *storage.Server) (int, int) {
+	serverConfig, err := store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		return configUser(nil)
+	}
+	return configUser(serverConfig)
+}
+
+// A time-ordered scratch directory for copying volume files through, removed by the caller
+func volumeStaging(tempDir, kind string) (string, error) {
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(tempDir, fmt.Sprintf("%s-%s-", kind, time.Now().Format("20060102-150405")))
+}
+
+// Copies a local file into the top of the server's data, through Docker for a volume
+// DiscoPanel can't see
+func (s *ServerService) copyIntoServerData(ctx context.Context, server *storage.Server, src, name string) error {
+	if server.DataPath != "" {
+		return files.CopyFile(src, filepath.Join(server.DataPath, name))
+	}
+
+	staging, err := volumeStaging(s.config.Storage.TempDir, "copy")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := files.CopyFile(src, filepath.Join(staging, name)); err != nil {
+		return err
+	}
+	uid, gid := serverUser(ctx, s.store, server)
+	return s.docker.CopyToVolume(ctx, server, staging, "", uid, gid)
+}
+
+// Extracts a backup archive into a data volume DiscoPanel can't see, clearing the given
+// top-level entries first. The archive is unpacked in a scratch directory and copied in.
+func (s *ServerService) restoreIntoVolume(ctx context.Context, server *storage.Server, archivePath string, entries []string) (int, error) {
+	staging, err := volumeStaging(s.config.Storage.TempDir, "restore")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(staging)
+
+	count, err := files.ExtractArchive(ctx, archivePath, staging, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) > 0 {
+		if _, err := s.docker.RunInVolume(ctx, server, `cd /data && rm -rf -- "$@"`, entries...); err != nil {
+			return 0, fmt.Errorf("failed to clear existing files: %w", err)
+		}
+	}
+	uid, gid := serverUser(ctx, s.store, server)
+	if err := s.docker.CopyToVolume(ctx, server, staging, "", uid, gid); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// A world in a data volume DiscoPanel can't see
+type volumeWorld struct {
+	size     int64
+	modified time.Time
+}
+
+// Lists the top-level directories of a data volume holding a level.dat
+func listVolumeWorlds(ctx context.Context, dockerClient *docker.Client, server *storage.Server) (map[string]volumeWorld, error) {
+	output, err := dockerClient.RunInVolume(ctx, server, `for d in /data/*/; do
+	[ -f "$d/level.dat" ] || continue
+	printf '%s\t%s\t%s\n' "$(du -sk "$d" | cut -f1)" "$(stat -c %Y "$d/level.dat")" "$(basename "$d")"
+done`)
+	if err != nil {
+		return nil, err
+	}
+
+	worlds := make(map[string]volumeWorld)
+	for line := range strings.Lines(output) {
+		fields := strings.SplitN(strings.TrimRight(line, "\n"), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		kb, _ := strconv.ParseInt(fields[0], 10, 64)
+		modified, _ := strconv.ParseInt(fields[1], 10, 64)
+		worlds[fields[2]] = volumeWorld{size: kb * 1024, modified: time.Unix(modified, 0)}
+	}
+	return worlds, nil
+}
+
+// Reports whether a data volume holds the world directory
+func volumeHasWorld(ctx context.Context, dockerClient *docker.Client, server *storage.Server, world string) (bool, error) {
+	output, err := dockerClient.RunInVolume(ctx, server, `[ ! -f "/data/$1/level.dat" ] || echo yes`, world)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) == "yes", nil
+}
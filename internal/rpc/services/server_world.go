@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/files"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Suffixes of the directories Bukkit servers keep a world's other dimensions in
+var dimensionSuffixes = []string{"_nether", "_the_end"}
+
+// ListWorlds lists the top-level directories of a server's data directory holding a
+// level.dat, leaving out the dimension directories of another world
+func (s *ServerService) ListWorlds(ctx context.Context, req *connect.Request[v1.ListWorldsRequest]) (*connect.Response[v1.ListWorldsResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if server.DataPath == "" {
+		return s.listVolumeWorlds(ctx, server)
+	}
+
+	entries, err := os.ReadDir(server.DataPath)
+	if err != nil && !os.IsNotExist(err) {
+		s.log.Error("Failed to read data directory of server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to read server directory"))
+	}
+
+	levels := make(map[string]time.Time)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(server.DataPath, entry.Name(), "level.dat")); err == nil && !info.IsDir() {
+			levels[entry.Name()] = info.ModTime()
+		}
+	}
+
+	active := activeWorld(ctx, s.store, server)
+	worlds := make([]*v1.World, 0, len(levels))
+	for name, modified := range levels {
+		if isDimensionDir(name, levels) {
+			continue
+		}
+		size, _ := files.CalculateDirSize(filepath.Join(server.DataPath, name))
+		worlds = append(worlds, &v1.World{
+			Name:       name,
+			Size:       size,
+			Active:     name == active,
+			ModifiedAt: timestamppb.New(modified),
+		})
+	}
+	sort.Slice(worlds, func(i, j int) bool {
+		return worlds[i].Name < worlds[j].Name
+	})
+
+	return connect.NewResponse(&v1.ListWorldsResponse{
+		Worlds: worlds,
+		Active: active,
+	}), nil
+}
+
+// SetActiveWorld points the server's LEVEL at another existing world and recreates its
+// container so the new environment applies. A running server is started again on it.
+func (s *ServerService) SetActiveWorld(ctx context.Context, req *connect.Request[v1.SetActiveWorldRequest]) (*connect.Response[v1.SetActiveWorldResponse], error) {
+	msg := req.Msg
+	if strings.TrimSpace(msg.World) == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("world is required"))
+	}
+
+	server, err := s.store.GetServer(ctx, msg.Id)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if server.Imported {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("server runs an imported container, recreate it from DiscoPanel before switching worlds"))
+	}
+
+	world, worldPath, err := resolveWorld(ctx, s.store, server, msg.World)
+	if err != nil {
+		return nil, err
+	}
+	exists := false
+	if worldPath == "" {
+		if exists, err = volumeHasWorld(ctx, s.docker, server, world); err != nil {
+			s.log.Error("Failed to look for world %s in server %s: %v", world, server.Name, err)
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to read server directory"))
+		}
+	} else if _, err := os.Stat(filepath.Join(worldPath, "level.dat")); err == nil {
+		exists = true
+	}
+	if !exists {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("world %s not found, upload it first", world))
+	}
+
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		s.log.Error("Failed to get server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get server configuration"))
+	}
+	previous := activeWorld(ctx, s.store, server)
+	if previous == world {
+		return connect.NewResponse(&v1.SetActiveWorldResponse{World: world}), nil
+	}
+
+	// Recreating the container kicks everyone, make the caller own that
+	if !msg.Confirm && server.ContainerID != "" {
+		if players := s.playersOnline(ctx, server); players > 0 {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("%d players online, confirm to switch worlds and disconnect them", players))
+		}
+	}
+
+	serverConfig.Level = &world
+	if err := s.store.SaveServerConfig(ctx, serverConfig); err != nil {
+		s.log.Error("Failed to save server config: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to save server configuration"))
+	}
+	s.log.Info("Server %s switched from world %s to %s", server.Name, previous, world)
+
+	// Without a container the new world is picked up when it's created
+	if server.ContainerID == "" {
+		return connect.NewResponse(&v1.SetActiveWorldResponse{World: world}), nil
+	}
+
+	running, err := s.applyPendingChanges(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	if running {
+		now := time.Now()
+		server.Status = storage.StatusStarting
+		server.LastStarted = &now
+		server.LastError = ""
+		if err := s.store.UpdateServer(ctx, server); err != nil {
+			s.log.Error("Failed to update server status: %v", err)
+		}
+	}
+
+	return connect.NewResponse(&v1.SetActiveWorldResponse{
+		World:     world,
+		Restarted: running,
+	}), nil
+}
+
+// ListWorlds for a data volume DiscoPanel can't see, read by a shell in the volume
+func (s *ServerService) listVolumeWorlds(ctx context.Context, server *storage.Server) (*connect.Response[v1.ListWorldsResponse], error) {
+	found, err := listVolumeWorlds(ctx, s.docker, server)
+	if err != nil {
+		s.log.Error("Failed to list worlds of server %s: %v", server.Name, err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to read server directory"))
+	}
+
+	levels := make(map[string]time.Time, len(found))
+	for name, world := range found {
+		levels[name] = world.modified
+	}
+
+	active := activeWorld(ctx, s.store, server)
+	worlds := make([]*v1.World, 0, len(found))
+	for name, world := range found {
+		if isDimensionDir(name, levels) {
+			continue
+		}
+		worlds = append(worlds, &v1.World{
+			Name:       name,
+			Size:       world.size,
+			Active:     name == active,
+			ModifiedAt: timestamppb.New(world.modified),
+		})
+	}
+	sort.Slice(worlds, func(i, j int) bool {
+		return worlds[i].Name < worlds[j].Name
+	})
+
+	return connect.NewResponse(&v1.ListWorldsResponse{
+		Worlds: worlds,
+		Active: active,
+	}), nil
+}
+
+// Reports whether name is the nether or end directory of another world in levels
+func isDimensionDir(name string, levels map[string]time.Time) bool {
+	for _, suffix := range dimensionSuffixes {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if _, exists := levels[base]; exists {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"archive/tar"
 	"bytes"
+	"cmp"
 	"compress/gzip"
 	"context"
 	"debug/buildinfo"
@@ -13,6 +14,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,6 +24,8 @@ import (
 	"github.com/nickheyer/discopanel/internal/config"
 	storage "github.com/nickheyer/discopanel/internal/db"
 	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/fleet"
+	"github.com/nickheyer/discopanel/internal/proxy"
 	"github.com/nickheyer/discopanel/pkg/logger"
 	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
 	"github.com/nickheyer/discopanel/pkg/proto/discopanel/v1/discopanelv1connect"
@@ -32,10 +37,11 @@ var _ discopanelv1connect.SupportServiceHandler = (*SupportService)(nil)
 
 // SupportService implements the Support service
 type SupportService struct {
-	store  *storage.Store
-	docker *docker.Client
-	config *config.Config
-	log    *logger.Logger
+	store        *storage.Store
+	docker       *docker.Client
+	proxyManager *proxy.Manager
+	config       *config.Config
+	log          *logger.Logger
 	// Store generated bundles temporarily
 	bundles map[string]*BundleInfo
 }
@@ -59,13 +65,14 @@ type UploadUserInfo struct {
 }
 
 // NewSupportService creates a new support service
-func NewSupportService(store *storage.Store, docker *docker.Client, config *config.Config, log *logger.Logger) *SupportService {
+func NewSupportService(store *storage.Store, docker *docker.Client, proxyManager *proxy.Manager, config *config.Config, log *logger.Logger) *SupportService {
 	return &SupportService{
-		store:   store,
-		docker:  docker,
-		config:  config,
-		log:     log,
-		bundles: make(map[string]*BundleInfo),
+		store:        store,
+		docker:       docker,
+		proxyManager: proxyManager,
+		config:       config,
+		log:          log,
+		bundles:      make(map[string]*BundleInfo),
 	}
 }
 
@@ -73,79 +80,26 @@ func NewSupportService(store *storage.Store, docker *docker.Client, config *conf
 func (s *SupportService) GenerateSupportBundle(ctx context.Context, req *connect.Request[v1.GenerateSupportBundleRequest]) (*connect.Response[v1.GenerateSupportBundleResponse], error) {
 	msg := req.Msg
 
-	// Default all options to true if not specified
-	includeLogs := msg.IncludeLogs
-	includeConfigs := msg.IncludeConfigs
-	includeSystemInfo := msg.IncludeSystemInfo
-
-	s.log.Info("Generating support bundle (logs=%v, configs=%v, system=%v)", includeLogs, includeConfigs, includeSystemInfo)
-
-	// Create temporary directory for bundle
-	tempDir := filepath.Join(s.config.Storage.TempDir, fmt.Sprintf("support-bundle-%d", time.Now().Unix()))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		s.log.Error("Failed to create temp directory: %v", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create temp directory"))
+	opts := bundleOptions{
+		logs:       msg.IncludeLogs,
+		configs:    msg.IncludeConfigs,
+		database:   msg.IncludeDatabase,
+		systemInfo: msg.IncludeSystemInfo,
+		serverIDs:  msg.ServerIds,
 	}
-	defer os.RemoveAll(tempDir) // Clean up temp directory when done
+
+	s.log.Info("Generating support bundle (logs=%v, configs=%v, database=%v, system=%v)", opts.logs, opts.configs, opts.database, opts.systemInfo)
 
 	// Prepare bundle file path
 	bundleID := uuid.New().String()
 	bundleFileName := fmt.Sprintf("discopanel-support-%s.tar.gz", time.Now().Format("20060102-150405"))
 	bundlePath := filepath.Join(s.config.Storage.TempDir, bundleFileName)
 
-	// Create the tar.gz file
-	bundleFile, err := os.Create(bundlePath)
-	if err != nil {
+	if err := s.writeBundle(ctx, bundlePath, opts); err != nil {
 		s.log.Error("Failed to create bundle file: %v", err)
+		os.Remove(bundlePath)
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create bundle file"))
 	}
-	defer bundleFile.Close()
-
-	// Create gzip writer
-	gzipWriter := gzip.NewWriter(bundleFile)
-	defer gzipWriter.Close()
-
-	// Create tar writer
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-
-	// 1. Add logs to bundle if requested
-	if includeLogs {
-		if err := s.addLogsToBundle(ctx, tarWriter, msg.ServerIds); err != nil {
-			s.log.Error("Failed to add logs to bundle: %v", err)
-			// Continue without failing the entire bundle
-			s.log.Warn("Continuing without logs")
-		}
-	}
-
-	// 2. Add database/configs to bundle if requested
-	if includeConfigs {
-		if err := s.addDatabaseToBundle(tarWriter); err != nil {
-			s.log.Error("Failed to add database to bundle: %v", err)
-			// Continue without failing
-			s.log.Warn("Continuing without database")
-		}
-
-		// Add server configurations
-		if err := s.addServerConfigsToBundle(ctx, tarWriter, msg.ServerIds); err != nil {
-			s.log.Error("Failed to add server configs: %v", err)
-			s.log.Warn("Continuing without server configs")
-		}
-	}
-
-	// 3. Add system information if requested
-	if includeSystemInfo {
-		if err := s.addSystemInfoToBundle(ctx, tarWriter); err != nil {
-			s.log.Error("Failed to add system info to bundle: %v", err)
-			// Don't fail the entire bundle if system info fails
-			s.log.Warn("Continuing without system info")
-		}
-	}
-
-	// Close writers to flush all data
-	tarWriter.Close()
-	gzipWriter.Close()
-	bundleFile.Close()
 
 	// Get file size
 	fileInfo, err := os.Stat(bundlePath)
@@ -207,78 +161,25 @@ func (s *SupportService) DownloadSupportBundle(ctx context.Context, req *connect
 func (s *SupportService) UploadSupportBundle(ctx context.Context, req *connect.Request[v1.UploadSupportBundleRequest]) (*connect.Response[v1.UploadSupportBundleResponse], error) {
 	msg := req.Msg
 
-	// Default all options to true if not specified
-	includeLogs := msg.IncludeLogs
-	includeConfigs := msg.IncludeConfigs
-	includeSystemInfo := msg.IncludeSystemInfo
-
-	s.log.Info("Generating support bundle for upload (logs=%v, configs=%v, system=%v)", includeLogs, includeConfigs, includeSystemInfo)
-
-	// Create temporary directory for bundle
-	tempDir := filepath.Join(s.config.Storage.TempDir, fmt.Sprintf("support-bundle-%d", time.Now().Unix()))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		s.log.Error("Failed to create temp directory: %v", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create temp directory"))
+	opts := bundleOptions{
+		logs:       msg.IncludeLogs,
+		configs:    msg.IncludeConfigs,
+		database:   msg.IncludeDatabase,
+		systemInfo: msg.IncludeSystemInfo,
+		serverIDs:  msg.ServerIds,
 	}
-	defer os.RemoveAll(tempDir) // Clean up temp directory when done
+
+	s.log.Info("Generating support bundle for upload (logs=%v, configs=%v, database=%v, system=%v)", opts.logs, opts.configs, opts.database, opts.systemInfo)
 
 	// Prepare bundle file path
 	bundleFileName := fmt.Sprintf("discopanel-support-%s.tar.gz", time.Now().Format("20060102-150405"))
 	bundlePath := filepath.Join(s.config.Storage.TempDir, bundleFileName)
 
-	// Create the tar.gz file
-	bundleFile, err := os.Create(bundlePath)
-	if err != nil {
+	if err := s.writeBundle(ctx, bundlePath, opts); err != nil {
 		s.log.Error("Failed to create bundle file: %v", err)
+		os.Remove(bundlePath)
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create bundle file"))
 	}
-	defer bundleFile.Close()
-
-	// Create gzip writer
-	gzipWriter := gzip.NewWriter(bundleFile)
-	defer gzipWriter.Close()
-
-	// Create tar writer
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-
-	// 1. Add logs to bundle if requested
-	if includeLogs {
-		if err := s.addLogsToBundle(ctx, tarWriter, msg.ServerIds); err != nil {
-			s.log.Error("Failed to add logs to bundle: %v", err)
-			// Continue without failing the entire bundle
-			s.log.Warn("Continuing without logs")
-		}
-	}
-
-	// 2. Add database/configs to bundle if requested
-	if includeConfigs {
-		if err := s.addDatabaseToBundle(tarWriter); err != nil {
-			s.log.Error("Failed to add database to bundle: %v", err)
-			// Continue without failing
-			s.log.Warn("Continuing without database")
-		}
-
-		// Add server configurations
-		if err := s.addServerConfigsToBundle(ctx, tarWriter, msg.ServerIds); err != nil {
-			s.log.Error("Failed to add server configs: %v", err)
-			s.log.Warn("Continuing without server configs")
-		}
-	}
-
-	// 3. Add system information if requested
-	if includeSystemInfo {
-		if err := s.addSystemInfoToBundle(ctx, tarWriter); err != nil {
-			s.log.Error("Failed to add system info to bundle: %v", err)
-			// Don't fail the entire bundle if system info fails
-			s.log.Warn("Continuing without system info")
-		}
-	}
-
-	// Close writers to flush all data
-	tarWriter.Close()
-	gzipWriter.Close()
-	bundleFile.Close()
 
 	// Build user info for upload
 	userInfo := &UploadUserInfo{
@@ -432,8 +333,97 @@ func (s *SupportService) cleanupBundle(bundleID string) {
 	}
 }
 
+// Container log lines included per server
+const supportLogTail = 1000
+
+// What goes into a support bundle
+type bundleOptions struct {
+	logs       bool
+	configs    bool
+	database   bool // Raw SQLite file, holds password hashes and tokens so it is opt-in
+	systemInfo bool
+	serverIDs  []string // Empty for all
+}
+
+// Writes a support bundle to bundlePath. Sections that fail are logged and left out.
+func (s *SupportService) writeBundle(ctx context.Context, bundlePath string, opts bundleOptions) error {
+	bundleFile, err := os.Create(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer bundleFile.Close()
+
+	gzipWriter := gzip.NewWriter(bundleFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	servers, err := s.bundleServers(ctx, opts.serverIDs)
+	if err != nil {
+		s.log.Warn("Failed to list servers for support bundle: %v", err)
+	}
+
+	// 1. Add logs to bundle if requested
+	if opts.logs {
+		if err := s.addLogsToBundle(ctx, tarWriter, servers); err != nil {
+			s.log.Error("Failed to add logs to bundle: %v", err)
+			s.log.Warn("Continuing without logs")
+		}
+	}
+
+	// 2. Add configs, and the database when asked for
+	if opts.configs {
+		if err := s.addServerConfigsToBundle(ctx, tarWriter, servers); err != nil {
+			s.log.Error("Failed to add server configs: %v", err)
+			s.log.Warn("Continuing without server configs")
+		}
+	}
+	if opts.database {
+		if err := s.addDatabaseToBundle(tarWriter); err != nil {
+			s.log.Error("Failed to add database to bundle: %v", err)
+			s.log.Warn("Continuing without database")
+		}
+	}
+
+	// 3. Add system information and proxy state if requested
+	if opts.systemInfo {
+		if err := s.addSystemInfoToBundle(ctx, tarWriter); err != nil {
+			s.log.Error("Failed to add system info to bundle: %v", err)
+			s.log.Warn("Continuing without system info")
+		}
+		if err := s.addProxyStateToBundle(tarWriter); err != nil {
+			s.log.Error("Failed to add proxy state to bundle: %v", err)
+			s.log.Warn("Continuing without proxy state")
+		}
+	}
+
+	// Close writers to flush all data
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	return bundleFile.Close()
+}
+
+// Servers a bundle covers, all of them when no IDs are given. Unknown IDs are skipped.
+func (s *SupportService) bundleServers(ctx context.Context, serverIDs []string) ([]*storage.Server, error) {
+	if len(serverIDs) == 0 {
+		return s.store.ListServers(ctx)
+	}
+	var servers []*storage.Server
+	for _, id := range serverIDs {
+		server, err := s.store.GetServer(ctx, id)
+		if err != nil {
+			s.log.Warn("Failed to get server %s for support bundle: %v", id, err)
+			continue
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
 // addLogsToBundle adds logs to the tar archive
-func (s *SupportService) addLogsToBundle(ctx context.Context, tarWriter *tar.Writer, serverIDs []string) error {
+func (s *SupportService) addLogsToBundle(ctx context.Context, tarWriter *tar.Writer, servers []*storage.Server) error {
 	// Get recent logs from memory buffer
 	recentLogs := s.log.GetRecentLogs()
 	recentLogsContent := strings.Join(recentLogs, "\n")
@@ -478,21 +468,23 @@ func (s *SupportService) addLogsToBundle(ctx context.Context, tarWriter *tar.Wri
 		}
 	}
 
-	// Add server-specific logs if requested
-	if len(serverIDs) > 0 {
-		for _, serverID := range serverIDs {
-			server, err := s.store.GetServer(ctx, serverID)
-			if err != nil {
-				s.log.Warn("Failed to get server %s for logs: %v", serverID, err)
-				continue
+	// Add server-specific logs
+	for _, server := range servers {
+		// Add server's latest.log if it exists
+		latestLogPath := filepath.Join(server.DataPath, "logs", "latest.log")
+		if server.DataPath != "" && fileExists(latestLogPath) {
+			targetPath := fmt.Sprintf("servers/%s/latest.log", server.Name)
+			if err := addFileToTar(tarWriter, latestLogPath, targetPath); err != nil {
+				s.log.Warn("Failed to add server log for %s: %v", server.Name, err)
 			}
+		}
 
-			// Add server's latest.log if it exists
-			latestLogPath := filepath.Join(server.DataPath, "logs", "latest.log")
-			if fileExists(latestLogPath) {
-				targetPath := fmt.Sprintf("servers/%s/latest.log", server.Name)
-				if err := addFileToTar(tarWriter, latestLogPath, targetPath); err != nil {
-					s.log.Warn("Failed to add server log for %s: %v", server.Name, err)
+		// The container's output also covers startup and install steps before latest.log exists
+		if server.ContainerID != "" && s.docker != nil {
+			if lines := s.docker.TailContainerLogs(ctx, server.ContainerID, supportLogTail); len(lines) > 0 {
+				targetPath := fmt.Sprintf("servers/%s/container.log", server.Name)
+				if err := addBytesToTar(tarWriter, targetPath, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+					s.log.Warn("Failed to add container log for %s: %v", server.Name, err)
 				}
 			}
 		}
@@ -503,6 +495,9 @@ func (s *SupportService) addLogsToBundle(ctx context.Context, tarWriter *tar.Wri
 
 // addDatabaseToBundle adds the database to the tar archive
 func (s *SupportService) addDatabaseToBundle(tarWriter *tar.Writer) error {
+	if s.config.Database.Driver == "postgres" {
+		return fmt.Errorf("database runs on postgres, dump it with pg_dump instead")
+	}
 	dbPath := s.config.Database.Path
 
 	if !fileExists(dbPath) {
@@ -513,27 +508,8 @@ func (s *SupportService) addDatabaseToBundle(tarWriter *tar.Writer) error {
 	return addFileToTar(tarWriter, dbPath, "database/discopanel.db")
 }
 
-// addServerConfigsToBundle adds server configuration files to the bundle
-func (s *SupportService) addServerConfigsToBundle(ctx context.Context, tarWriter *tar.Writer, serverIDs []string) error {
-	var servers []*storage.Server
-	var err error
-
-	if len(serverIDs) > 0 {
-		// Get specific servers
-		for _, id := range serverIDs {
-			server, err := s.store.GetServer(ctx, id)
-			if err == nil {
-				servers = append(servers, server)
-			}
-		}
-	} else {
-		// Get all servers
-		servers, err = s.store.ListServers(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to list servers: %w", err)
-		}
-	}
-
+// addServerConfigsToBundle adds server configuration files to the bundle, secrets removed
+func (s *SupportService) addServerConfigsToBundle(ctx context.Context, tarWriter *tar.Writer, servers []*storage.Server) error {
 	// Add each server's configuration
 	for _, server := range servers {
 		// Get server config from database
@@ -542,6 +518,7 @@ func (s *SupportService) addServerConfigsToBundle(ctx context.Context, tarWriter
 			s.log.Warn("Failed to get config for server %s: %v", server.Name, err)
 			continue
 		}
+		fleet.ScrubSecrets(serverConfig)
 
 		// Marshal server config to JSON
 		configData, err := json.MarshalIndent(serverConfig, "", "  ")
@@ -551,26 +528,14 @@ func (s *SupportService) addServerConfigsToBundle(ctx context.Context, tarWriter
 		}
 
 		// Add to tar
-		header := &tar.Header{
-			Name:    fmt.Sprintf("configs/servers/%s_config.json", server.Name),
-			Size:    int64(len(configData)),
-			Mode:    0644,
-			ModTime: time.Now(),
-		}
-
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return fmt.Errorf("failed to write config header for %s: %w", server.Name, err)
-		}
-
-		if _, err := tarWriter.Write(configData); err != nil {
-			return fmt.Errorf("failed to write config content for %s: %w", server.Name, err)
+		if err := addBytesToTar(tarWriter, fmt.Sprintf("configs/servers/%s_config.json", server.Name), configData); err != nil {
+			return fmt.Errorf("failed to write config for %s: %w", server.Name, err)
 		}
 
 		// Also add server.properties if it exists
-		serverPropsPath := filepath.Join(server.DataPath, "server.properties")
-		if fileExists(serverPropsPath) {
+		if props, err := os.ReadFile(filepath.Join(server.DataPath, "server.properties")); server.DataPath != "" && err == nil {
 			targetPath := fmt.Sprintf("configs/servers/%s_server.properties", server.Name)
-			if err := addFileToTar(tarWriter, serverPropsPath, targetPath); err != nil {
+			if err := addBytesToTar(tarWriter, targetPath, scrubProperties(props)); err != nil {
 				s.log.Warn("Failed to add server.properties for %s: %v", server.Name, err)
 			}
 		}
@@ -579,6 +544,66 @@ func (s *SupportService) addServerConfigsToBundle(ctx context.Context, tarWriter
 	return nil
 }
 
+// Blanks properties whose key names a password or secret, e.g. rcon.password
+func scrubProperties(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(strings.TrimSpace(key), "#") || strings.TrimSpace(value) == "" {
+			continue
+		}
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, "password") || strings.Contains(lower, "secret") {
+			lines[i] = key + "=<redacted>"
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Route as written to the support bundle
+type bundleRoute struct {
+	ServerID    string `json:"server_id"`
+	Hostname    string `json:"hostname"`
+	BackendHost string `json:"backend_host"`
+	BackendPort int    `json:"backend_port"`
+	Active      bool   `json:"active"`
+}
+
+// addProxyStateToBundle adds the proxy's live routes and connection counters
+func (s *SupportService) addProxyStateToBundle(tarWriter *tar.Writer) error {
+	if s.proxyManager == nil {
+		return nil
+	}
+
+	routes := make([]bundleRoute, 0)
+	for _, route := range s.proxyManager.GetRoutes() {
+		routes = append(routes, bundleRoute{
+			ServerID:    route.ServerID,
+			Hostname:    route.Hostname,
+			BackendHost: route.BackendHost,
+			BackendPort: route.BackendPort,
+			Active:      route.Active,
+		})
+	}
+	slices.SortFunc(routes, func(a, b bundleRoute) int {
+		return cmp.Or(cmp.Compare(a.Hostname, b.Hostname), cmp.Compare(a.ServerID, b.ServerID))
+	})
+
+	// Per-client counters are left out, they are player IP addresses
+	_, stats, _ := s.proxyManager.Connections()
+	state := map[string]any{
+		"running":     s.proxyManager.IsRunning(),
+		"routes":      routes,
+		"connections": stats,
+	}
+
+	jsonData, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal proxy state: %w", err)
+	}
+	return addBytesToTar(tarWriter, "proxy/routes.json", jsonData)
+}
+
 // addSystemInfoToBundle adds system and configuration information to bundle
 func (s *SupportService) addSystemInfoToBundle(ctx context.Context, tarWriter *tar.Writer) error {
 	servers, _ := s.store.ListServers(ctx)
@@ -635,6 +660,7 @@ func (s *SupportService) addSystemInfoToBundle(ctx context.Context, tarWriter *t
 						Port:      int32(listener.Port),
 						Enabled:   listener.Enabled,
 						IsDefault: listener.IsDefault,
+						Protocol:  string(listener.Protocol),
 					})
 				}
 			}
@@ -659,6 +685,14 @@ func (s *SupportService) addSystemInfoToBundle(ctx context.Context, tarWriter *t
 
 	// Build the complete system info
 	systemInfo := &v1.SystemInfo{
+		Host: &v1.HostInfo{
+			Os:            runtime.GOOS,
+			Arch:          runtime.GOARCH,
+			GoVersion:     runtime.Version(),
+			Cpus:          int32(runtime.NumCPU()),
+			Containerized: fileExists("/.dockerenv") || fileExists("/run/.containerenv"),
+			DockerRuntime: s.config.Docker.Runtime,
+		},
 		Timestamp:   time.Now().Format(time.RFC3339),
 		Version:     getVersionInfo(),
 		ServerCount: int32(len(servers)),
@@ -691,6 +725,21 @@ func (s *SupportService) addSystemInfoToBundle(ctx context.Context, tarWriter *t
 	return nil
 }
 
+// addBytesToTar adds in-memory content to the tar archive
+func addBytesToTar(tw *tar.Writer, destPath string, data []byte) error {
+	header := &tar.Header{
+		Name:    destPath,
+		Size:    int64(len(data)),
+		Mode:    0644,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
 // addFileToTar adds a file to the tar archive
 func addFileToTar(tw *tar.Writer, sourcePath, destPath string) error {
 	file, err := os.Open(sourcePath)
@@ -805,3 +854,57 @@ func (s *SupportService) GetApplicationLogs(ctx context.Context, req *connect.Re
 		Size:     fileInfo.Size(),
 	}), nil
 }
+
+// Reports unused server and module images, removing them unless dry_run is set
+func (s *SupportService) PruneImages(ctx context.Context, req *connect.Request[v1.PruneImagesRequest]) (*connect.Response[v1.PruneImagesResponse], error) {
+	servers, err := s.store.ListServers(ctx)
+	if err != nil {
+		s.log.Error("Failed to list servers: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list servers"))
+	}
+	templates, err := s.store.ListModuleTemplates(ctx)
+	if err != nil {
+		s.log.Error("Failed to list module templates: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list module templates"))
+	}
+
+	var inUse, managedRepos []string
+	for _, server := range servers {
+		inUse = append(inUse, docker.ServerImage(server))
+	}
+	for _, template := range templates {
+		if template.DockerImage != "" {
+			inUse = append(inUse, template.DockerImage)
+			managedRepos = append(managedRepos, template.DockerImage)
+		}
+	}
+
+	images, err := s.docker.ListPrunableImages(ctx, managedRepos, inUse)
+	if err != nil {
+		s.log.Error("Failed to list prunable images: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list images"))
+	}
+
+	resp := &v1.PruneImagesResponse{Images: make([]*v1.PrunableImage, 0, len(images))}
+	for _, img := range images {
+		resp.Images = append(resp.Images, &v1.PrunableImage{
+			Id:       img.ID,
+			Tags:     img.Tags,
+			Size:     img.Size,
+			Dangling: img.Dangling,
+		})
+		resp.ReclaimableBytes += img.Size
+	}
+
+	if req.Msg.DryRun || len(images) == 0 {
+		return connect.NewResponse(resp), nil
+	}
+
+	removed, reclaimed, errs := s.docker.RemoveImages(ctx, images)
+	resp.Removed = int32(removed)
+	resp.ReclaimedBytes = reclaimed
+	resp.Errors = errs
+	s.log.Info("Pruned %d images, reclaimed %d bytes (%d failed)", removed, reclaimed, len(errs))
+
+	return connect.NewResponse(resp), nil
+}
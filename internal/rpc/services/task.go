@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
@@ -52,6 +55,14 @@ func dbTaskTypeToProto(t storage.TaskType) v1.TaskType {
 		return v1.TaskType_TASK_TYPE_SCRIPT
 	case storage.TaskTypeWebhook:
 		return v1.TaskType_TASK_TYPE_WEBHOOK
+	case storage.TaskTypeImageUpdate:
+		return v1.TaskType_TASK_TYPE_IMAGE_UPDATE
+	case storage.TaskTypeBackupVerify:
+		return v1.TaskType_TASK_TYPE_BACKUP_VERIFY
+	case storage.TaskTypeBroadcast:
+		return v1.TaskType_TASK_TYPE_BROADCAST
+	case storage.TaskTypePregenerate:
+		return v1.TaskType_TASK_TYPE_PREGENERATE
 	default:
 		return v1.TaskType_TASK_TYPE_UNSPECIFIED
 	}
@@ -74,6 +85,14 @@ func protoTaskTypeToDB(t v1.TaskType) storage.TaskType {
 		return storage.TaskTypeScript
 	case v1.TaskType_TASK_TYPE_WEBHOOK:
 		return storage.TaskTypeWebhook
+	case v1.TaskType_TASK_TYPE_IMAGE_UPDATE:
+		return storage.TaskTypeImageUpdate
+	case v1.TaskType_TASK_TYPE_BACKUP_VERIFY:
+		return storage.TaskTypeBackupVerify
+	case v1.TaskType_TASK_TYPE_BROADCAST:
+		return storage.TaskTypeBroadcast
+	case v1.TaskType_TASK_TYPE_PREGENERATE:
+		return storage.TaskTypePregenerate
 	default:
 		return storage.TaskTypeCommand
 	}
@@ -187,6 +206,9 @@ func dbTaskToProto(task *storage.ScheduledTask) *v1.ScheduledTask {
 		EventTriggers: task.EventTriggers,
 		CreatedAt:     timestamppb.New(task.CreatedAt),
 		UpdatedAt:     timestamppb.New(task.UpdatedAt),
+
+		SuccessNotify: task.SuccessNotify,
+		NotifyUrl:     task.NotifyURL,
 	}
 
 	if task.RunAt != nil {
@@ -294,6 +316,10 @@ func (s *TaskService) CreateTask(ctx context.Context, req *connect.Request[v1.Cr
 			return nil, connect.NewError(connect.CodeInvalidArgument, err)
 		}
 	}
+	notifyURL := strings.TrimSpace(msg.NotifyUrl)
+	if err := validateNotifyURL(notifyURL); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 
 	// Create task
 	task := &storage.ScheduledTask{
@@ -313,6 +339,9 @@ func (s *TaskService) CreateTask(ctx context.Context, req *connect.Request[v1.Cr
 		RetryDelay:    int(msg.RetryDelay),
 		RequireOnline: msg.RequireOnline,
 		EventTriggers: eventTriggers,
+		FailureNotify: msg.FailureNotify,
+		SuccessNotify: msg.SuccessNotify,
+		NotifyURL:     notifyURL,
 	}
 
 	// Set defaults
@@ -405,6 +434,19 @@ func (s *TaskService) UpdateTask(ctx context.Context, req *connect.Request[v1.Up
 	if msg.RequireOnline != nil {
 		task.RequireOnline = *msg.RequireOnline
 	}
+	if msg.FailureNotify != nil {
+		task.FailureNotify = *msg.FailureNotify
+	}
+	if msg.SuccessNotify != nil {
+		task.SuccessNotify = *msg.SuccessNotify
+	}
+	if msg.NotifyUrl != nil {
+		notifyURL := strings.TrimSpace(*msg.NotifyUrl)
+		if err := validateNotifyURL(notifyURL); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		task.NotifyURL = notifyURL
+	}
 	if msg.ClearEventTriggers {
 		task.EventTriggers = nil
 	}
@@ -593,6 +635,18 @@ func validateWebhookConfig(cfg string) error {
 	return nil
 }
 
+// validateNotifyURL checks a task's result webhook is an absolute http(s) URL, empty is allowed
+func validateNotifyURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notify URL must be an http or https URL")
+	}
+	return nil
+}
+
 // GetSchedulerStatus gets the scheduler status
 func (s *TaskService) GetSchedulerStatus(ctx context.Context, req *connect.Request[v1.GetSchedulerStatusRequest]) (*connect.Response[v1.GetSchedulerStatusResponse], error) {
 	status := s.scheduler.GetStatus()
@@ -612,3 +666,110 @@ func (s *TaskService) GetSchedulerStatus(ctx context.Context, req *connect.Reque
 
 	return connect.NewResponse(response), nil
 }
+
+// EstimateBackupSize sizes what a backup task config would archive
+func (s *TaskService) EstimateBackupSize(ctx context.Context, req *connect.Request[v1.EstimateBackupSizeRequest]) (*connect.Response[v1.EstimateBackupSizeResponse], error) {
+	server, err := s.store.GetServer(ctx, req.Msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
+
+	var config scheduler.BackupTaskConfig
+	if req.Msg.Config != "" {
+		if err := json.Unmarshal([]byte(req.Msg.Config), &config); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid backup config: %w", err))
+		}
+	}
+
+	estimate, err := scheduler.EstimateBackup(server.DataPath, &config)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+
+	return connect.NewResponse(&v1.EstimateBackupSizeResponse{
+		SizeBytes:     estimate.Bytes,
+		FileCount:     int32(estimate.Files),
+		ExcludedBytes: estimate.ExcludedBytes,
+		ExcludedFiles: int32(estimate.ExcludedFiles),
+		Paths:         estimate.Paths,
+		Missing:       estimate.Missing,
+	}), nil
+}
+
+// PregenerateChunks creates a one-off pre-generation task and starts it right away.
+// Its executions keep the latest Chunky progress in their output until it finishes.
+func (s *TaskService) PregenerateChunks(ctx context.Context, req *connect.Request[v1.PregenerateChunksRequest]) (*connect.Response[v1.PregenerateChunksResponse], error) {
+	msg := req.Msg
+
+	server, err := s.store.GetServer(ctx, msg.ServerId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("server not found"))
+	}
+	if msg.Radius <= 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("radius must be greater than zero"))
+	}
+	if err := requireLocalData(server); err != nil {
+		return nil, err
+	}
+	if !scheduler.HasChunky(server.DataPath) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("pre-generation is not supported on this server, install the Chunky mod or plugin first"))
+	}
+	if server.Status != storage.StatusRunning {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("start the server before pre-generating chunks"))
+	}
+
+	world := strings.TrimSpace(msg.World)
+	config, err := json.Marshal(scheduler.PregenerateTaskConfig{
+		World:   world,
+		CenterX: int(msg.CenterX),
+		CenterZ: int(msg.CenterZ),
+		Radius:  int(msg.Radius),
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to encode task config"))
+	}
+
+	name := fmt.Sprintf("Pre-generate radius %d around %d, %d", msg.Radius, msg.CenterX, msg.CenterZ)
+	if world != "" {
+		name += " in " + world
+	}
+
+	// Once tasks without a next run are never picked up by the scheduler loop
+	now := time.Now()
+	task := &storage.ScheduledTask{
+		ID:            uuid.New().String(),
+		ServerID:      server.ID,
+		Name:          name,
+		TaskType:      storage.TaskTypePregenerate,
+		Status:        storage.TaskStatusEnabled,
+		Schedule:      storage.ScheduleTypeOnce,
+		RunAt:         &now,
+		Timezone:      "UTC",
+		Config:        string(config),
+		Timeout:       int(msg.Timeout),
+		RequireOnline: true,
+	}
+	if task.Timeout <= 0 {
+		task.Timeout = 12 * 60 * 60 // 12 hours default
+	}
+
+	if err := s.store.CreateScheduledTask(ctx, task); err != nil {
+		s.log.Error("Failed to create task: %v", err)
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create task"))
+	}
+
+	s.log.Info("Started chunk pre-generation for server %s: %s", server.Name, task.Name)
+
+	go func() {
+		if _, err := s.scheduler.TriggerTask(context.Background(), task.ID); err != nil {
+			s.log.Error("Chunk pre-generation for server %s failed: %v", server.Name, err)
+		}
+	}()
+
+	return connect.NewResponse(&v1.PregenerateChunksResponse{
+		Task: dbTaskToProto(task),
+	}), nil
+}
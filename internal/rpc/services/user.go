@@ -118,6 +118,15 @@ func (s *UserService) UpdateUser(ctx context.Context, req *connect.Request[v1.Up
 	if msg.IsActive != nil {
 		user.IsActive = *msg.IsActive
 	}
+	if msg.MaxServers != nil {
+		user.MaxServers = quotaOverride(*msg.MaxServers)
+	}
+	if msg.MaxMemory != nil {
+		user.MaxMemory = quotaOverride(*msg.MaxMemory)
+	}
+	if msg.MaxModules != nil {
+		user.MaxModules = quotaOverride(*msg.MaxModules)
+	}
 
 	if err := s.store.UpdateUser(ctx, user); err != nil {
 		s.log.Error("Failed to update user: %v", err)
@@ -188,9 +197,31 @@ func dbUserToProto(user *storage.User, roles []string) *v1.User {
 		Roles:        roles,
 		CreatedAt:    timestamppb.New(user.CreatedAt),
 		UpdatedAt:    timestamppb.New(user.UpdatedAt),
+		OidcSubject:  user.OIDCSubject,
+		OidcIssuer:   user.OIDCIssuer,
 	}
 	if user.LastLogin != nil {
 		protoUser.LastLogin = timestamppb.New(*user.LastLogin)
 	}
+	protoUser.MaxServers = quotaOverrideToProto(user.MaxServers)
+	protoUser.MaxMemory = quotaOverrideToProto(user.MaxMemory)
+	protoUser.MaxModules = quotaOverrideToProto(user.MaxModules)
 	return protoUser
 }
+
+func quotaOverrideToProto(limit *int) *int32 {
+	if limit == nil {
+		return nil
+	}
+	v := int32(*limit)
+	return &v
+}
+
+// A negative override clears it so the user falls back to their role limits
+func quotaOverride(limit int32) *int {
+	if limit < 0 {
+		return nil
+	}
+	v := int(limit)
+	return &v
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -16,12 +18,126 @@ import (
 
 // BackupTaskConfig represents configuration for backup tasks
 type BackupTaskConfig struct {
-	BackupName    string   `json:"backup_name"`
-	Paths         []string `json:"paths"`
-	Compress      bool     `json:"compress"`
-	RetentionDays int      `json:"retention_days"`
-	MaxBackups    int      `json:"max_backups"`
-	MinBackups    int      `json:"min_backups"`
+	BackupName        string   `json:"backup_name"`
+	Paths             []string `json:"paths"`
+	Exclude           []string `json:"exclude"`             // Glob patterns left out of the archive
+	IncludeMods       bool     `json:"include_mods"`        // Keep mods/ and plugin jars, excluded by default
+	NoDefaultExcludes bool     `json:"no_default_excludes"` // Only apply Exclude, not defaultBackupExcludes
+	Compress          bool     `json:"compress"`
+	RetentionDays     int      `json:"retention_days"`
+	MaxBackups        int      `json:"max_backups"`
+	MinBackups        int      `json:"min_backups"`
+	Upload            bool     `json:"upload"` // Ship the archive to the remote store after it is created
+}
+
+// Server config files backed up alongside the world when no paths are configured
+var defaultBackupConfigPaths = []string{
+	"config",
+	"defaultconfigs",
+	"plugins",
+	"server.properties",
+	"ops.json",
+	"whitelist.json",
+	"banned-players.json",
+	"banned-ips.json",
+}
+
+// Regenerable or re-downloadable content skipped unless NoDefaultExcludes is set
+var defaultBackupExcludes = []string{
+	"libraries/",
+	"cache/",
+	".cache/",
+	"logs/",
+	"crash-reports/",
+	"/*.jar",
+}
+
+// Mods and plugin jars come from the modpack or loader install, so they're skipped unless IncludeMods
+var modBackupExcludes = []string{
+	"/mods/",
+	"/plugins/*.jar",
+}
+
+// BackupEstimate is what a backup with the given config would currently contain
+type BackupEstimate struct {
+	Paths         []string
+	Missing       []string
+	Files         int
+	Bytes         int64
+	ExcludedFiles int
+	ExcludedBytes int64
+}
+
+// BackupFile is a backup archive on disk, in the remote store, or both
+type BackupFile struct {
+	Name      string
+	Path      string // Empty when the backup only exists remotely
+	Size      int64
+	CreatedAt time.Time
+	Remote    bool // A copy exists in the remote store
+}
+
+// ServerBackupDir is where a server's backups live. Backups are grouped per server
+// using the unique server data directory name, so they survive a server rename.
+func ServerBackupDir(backupRoot string, server *storage.Server) string {
+	return filepath.Join(backupRoot, serverDataName(server))
+}
+
+// Unique name of the server's data, the volume name for volume-backed servers whose
+// DataPath is empty or ends in the volume's "_data" directory
+func serverDataName(server *storage.Server) string {
+	if server.DataVolume != "" {
+		return server.DataVolume
+	}
+	return filepath.Base(server.DataPath)
+}
+
+// ListServerBackups returns a server's backup archives, newest first
+func ListServerBackups(backupRoot string, server *storage.Server) ([]BackupFile, error) {
+	dir := ServerBackupDir(backupRoot, server)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []BackupFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".zip") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupFile{
+			Name:      entry.Name(),
+			Path:      filepath.Join(dir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// FindServerBackup resolves a backup file name to its archive, rejecting anything outside the server's backup dir
+func FindServerBackup(backupRoot string, server *storage.Server, name string) (*BackupFile, error) {
+	if name == "" || name != filepath.Base(name) || !strings.HasSuffix(name, ".zip") {
+		return nil, fmt.Errorf("invalid backup name %q", name)
+	}
+
+	path := filepath.Join(ServerBackupDir(backupRoot, server), name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s not found", name)
+	}
+	return &BackupFile{Name: name, Path: path, Size: info.Size(), CreatedAt: info.ModTime()}, nil
 }
 
 // Archives server data into the configured backup directory.
@@ -38,17 +154,37 @@ func (s *Scheduler) executeBackupTask(ctx context.Context, server *storage.Serve
 	if s.appConfig == nil || s.appConfig.Storage.BackupDir == "" {
 		return "", fmt.Errorf("backup directory is not configured")
 	}
-	if server.DataPath == "" {
+	if server.DataPath == "" && server.DataVolume == "" {
 		return "", fmt.Errorf("server has no data directory")
 	}
 
-	paths, missing, err := resolveBackupPaths(server.DataPath, config.Paths)
+	resumeSaves := s.PauseWorldSaves(ctx, server)
+	defer resumeSaves()
+
+	// A volume DiscoPanel can't see is copied out through Docker once the saves are flushed
+	dataPath := server.DataPath
+	if dataPath == "" {
+		staging, err := os.MkdirTemp(s.appConfig.Storage.TempDir, "backup-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		defer os.RemoveAll(staging)
+		if _, err := s.docker.CopyFromVolume(ctx, server, "", staging); err != nil {
+			return "", fmt.Errorf("failed to copy server files out of its volume: %w", err)
+		}
+		dataPath = staging
+	}
+
+	paths, missing, err := resolveBackupPaths(dataPath, &config)
+	if err != nil {
+		return "", err
+	}
+	filter, err := backupFilter(&config)
 	if err != nil {
 		return "", err
 	}
 
-	// Backups are grouped per server using the unique server data directory name
-	destDir := filepath.Join(s.appConfig.Storage.BackupDir, filepath.Base(server.DataPath))
+	destDir := ServerBackupDir(s.appConfig.Storage.BackupDir, server)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
@@ -60,11 +196,8 @@ func (s *Scheduler) executeBackupTask(ctx context.Context, server *storage.Serve
 	prefix := files.SanitizePathName(backupName)
 	destPath := filepath.Join(destDir, fmt.Sprintf("%s_%s.zip", prefix, time.Now().UTC().Format("20060102-150405")))
 
-	resumeSaves := s.pauseWorldSaves(ctx, server)
-	defer resumeSaves()
-
 	start := time.Now()
-	count, err := files.CreateZipArchive(paths, server.DataPath, destPath, config.Compress)
+	count, err := files.CreateZipArchive(paths, dataPath, destPath, config.Compress, filter)
 	if err != nil {
 		return "", fmt.Errorf("failed to create backup archive: %w", err)
 	}
@@ -87,22 +220,34 @@ func (s *Scheduler) executeBackupTask(ctx context.Context, server *storage.Serve
 	if pruneErr != nil {
 		output += fmt.Sprintf("; prune warning: %v", pruneErr)
 	}
+
+	if config.Upload {
+		uploadOutput, err := s.uploadBackup(ctx, server, destPath, prefix+"_", &config)
+		if err != nil {
+			return output, fmt.Errorf("backup created but upload failed: %w", err)
+		}
+		output += "; " + uploadOutput
+	}
 	return output, nil
 }
 
 // Validates the requested paths against the server data
 // directory and returns the relative paths to archive plus any that were
-// configured but do not exist. An empty request means the server's world directory.
-func resolveBackupPaths(dataPath string, requested []string) (paths []string, missing []string, err error) {
-	cleaned := make([]string, 0, len(requested))
-	for _, p := range requested {
-		rel := filepath.Clean(strings.TrimSpace(p))
-		if rel == "" || rel == "." {
+// configured but do not exist. "." archives the whole directory (minus excludes).
+// An empty request means the server's world directories and config files.
+func resolveBackupPaths(dataPath string, config *BackupTaskConfig) (paths []string, missing []string, err error) {
+	cleaned := make([]string, 0, len(config.Paths))
+	for _, p := range config.Paths {
+		if strings.TrimSpace(p) == "" {
 			continue
 		}
+		rel := filepath.Clean(strings.TrimSpace(p))
 		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
 			return nil, nil, fmt.Errorf("invalid backup path %q: must be relative to the server directory", p)
 		}
+		if rel == "." {
+			return []string{"."}, nil, nil
+		}
 		cleaned = append(cleaned, rel)
 	}
 
@@ -119,6 +264,17 @@ func resolveBackupPaths(dataPath string, requested []string) (paths []string, mi
 			}
 			paths = append(paths, rel)
 		}
+
+		// Plus whichever config files this server has
+		defaults := defaultBackupConfigPaths
+		if config.IncludeMods {
+			defaults = append([]string{"mods"}, defaults...)
+		}
+		for _, rel := range defaults {
+			if _, err := os.Stat(filepath.Join(dataPath, rel)); err == nil {
+				paths = append(paths, rel)
+			}
+		}
 		return paths, nil, nil
 	}
 
@@ -135,20 +291,176 @@ func resolveBackupPaths(dataPath string, requested []string) (paths []string, mi
 	return paths, missing, nil
 }
 
-// Disables auto-saving and flushes pending world writes so the files on disk are consistent while they are archived.
-// The returned function re-enables saving and is safe to call even when the server was offline or the save commands failed.
-func (s *Scheduler) pauseWorldSaves(ctx context.Context, server *storage.Server) func() {
+// Builds the archive filter from the default and configured exclude globs.
+// Patterns follow .gitignore conventions: a trailing "/" only matches directories,
+// a leading "/" anchors to the server directory, patterns without a "/" match a
+// name at any depth, and "**" spans directories.
+func backupFilter(config *BackupTaskConfig) (files.ZipFilter, error) {
+	var patterns []string
+	if !config.NoDefaultExcludes {
+		patterns = append(patterns, defaultBackupExcludes...)
+	}
+	if !config.IncludeMods {
+		patterns = append(patterns, modBackupExcludes...)
+	}
+	patterns = append(patterns, config.Exclude...)
+
+	type matcher struct {
+		re      *regexp.Regexp
+		dirOnly bool
+	}
+	var matchers []matcher
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(filepath.ToSlash(pattern))
+		if pattern == "" {
+			continue
+		}
+		re, dirOnly, err := compileBackupGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		matchers = append(matchers, matcher{re: re, dirOnly: dirOnly})
+	}
+
+	return func(rel string, isDir bool) bool {
+		for _, m := range matchers {
+			if m.dirOnly && !isDir {
+				continue
+			}
+			if m.re.MatchString(rel) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Converts a gitignore-style glob into an anchored regexp over slash-separated paths
+func compileBackupGlob(pattern string) (*regexp.Regexp, bool, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return nil, false, fmt.Errorf("empty pattern")
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	return re, dirOnly, err
+}
+
+// EstimateBackup walks what a backup with config would archive from dataPath right now,
+// without writing anything
+func EstimateBackup(dataPath string, config *BackupTaskConfig) (*BackupEstimate, error) {
+	paths, missing, err := resolveBackupPaths(dataPath, config)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := backupFilter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &BackupEstimate{Paths: paths, Missing: missing}
+	for _, p := range paths {
+		root := filepath.Join(dataPath, p)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(dataPath, path)
+			excluded := path != root && filter(filepath.ToSlash(rel), d.IsDir())
+
+			if d.IsDir() {
+				if excluded {
+					// Count what the skipped directory would have added
+					n, size := dirUsage(path)
+					estimate.ExcludedFiles += n
+					estimate.ExcludedBytes += size
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if excluded {
+				estimate.ExcludedFiles++
+				estimate.ExcludedBytes += info.Size()
+			} else {
+				estimate.Files++
+				estimate.Bytes += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", p, err)
+		}
+	}
+	return estimate, nil
+}
+
+func dirUsage(dir string) (int, int64) {
+	var count int
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			count++
+			size += info.Size()
+		}
+		return nil
+	})
+	return count, size
+}
+
+// PauseWorldSaves disables auto-saving and flushes pending world writes so the files on disk are consistent while they
+// are archived or copied. The returned function re-enables saving and is safe to call even when the server was offline
+// or the save commands failed.
+func (s *Scheduler) PauseWorldSaves(ctx context.Context, server *storage.Server) func() {
 	if server.Status != storage.StatusRunning || server.ContainerID == "" {
 		return func() {}
 	}
 
 	if _, err := s.sender.SendCommand(ctx, server.ID, "save-off"); err != nil {
-		s.log.Warn("Backup: failed to disable world saves on server %s (continuing anyway): %v", server.Name, err)
+		s.log.Warn("Failed to disable world saves on server %s (continuing anyway): %v", server.Name, err)
 		return func() {}
 	}
 
 	if _, err := s.sender.SendCommand(ctx, server.ID, "save-all flush"); err != nil {
-		s.log.Warn("Backup: failed to flush world saves on server %s: %v", server.Name, err)
+		s.log.Warn("Failed to flush world saves on server %s: %v", server.Name, err)
 	} else {
 		// Give the server a moment to finish writing chunks to disk
 		select {
@@ -162,9 +474,40 @@ func (s *Scheduler) pauseWorldSaves(ctx context.Context, server *storage.Server)
 		resumeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 		if _, err := s.sender.SendCommand(resumeCtx, server.ID, "save-on"); err != nil {
-			s.log.Error("Backup: failed to re-enable world saves on server %s: %v", server.Name, err)
+			s.log.Error("Failed to re-enable world saves on server %s: %v", server.Name, err)
+		}
+	}
+}
+
+// Picks the backups retention removes, see pruneBackups
+func expiredBackups(backups []BackupFile, retentionDays, minBackups, maxBackups int) []string {
+	// Newest first
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	toDelete := make(map[string]bool)
+	if maxBackups > 0 {
+		for _, b := range backups[min(maxBackups, len(backups)):] {
+			toDelete[b.Name] = true
 		}
 	}
+	if retentionDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -retentionDays)
+		// Keep the newest minBackups regardless of age (always at least the most recent backup)
+		protected := max(minBackups, 1)
+		for _, b := range backups[min(protected, len(backups)):] {
+			if b.CreatedAt.Before(cutoff) {
+				toDelete[b.Name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(toDelete))
+	for name := range toDelete {
+		names = append(names, name)
+	}
+	return names
 }
 
 // Removes old backups matching prefix in dir, keeping at most maxBackups (0 = unlimited) and dropping any older than retentionDays.
@@ -181,11 +524,7 @@ func pruneBackups(dir, prefix string, retentionDays, minBackups, maxBackups int)
 		return 0, fmt.Errorf("failed to read backup directory: %w", err)
 	}
 
-	type backupFile struct {
-		name    string
-		modTime time.Time
-	}
-	var backups []backupFile
+	var backups []BackupFile
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || !strings.HasSuffix(entry.Name(), ".zip") {
 			continue
@@ -194,34 +533,12 @@ func pruneBackups(dir, prefix string, retentionDays, minBackups, maxBackups int)
 		if err != nil {
 			continue
 		}
-		backups = append(backups, backupFile{name: entry.Name(), modTime: info.ModTime()})
-	}
-
-	// Newest first
-	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].modTime.After(backups[j].modTime)
-	})
-
-	toDelete := make(map[string]bool)
-	if maxBackups > 0 {
-		for _, b := range backups[min(maxBackups, len(backups)):] {
-			toDelete[b.name] = true
-		}
-	}
-	if retentionDays > 0 {
-		cutoff := time.Now().AddDate(0, 0, -retentionDays)
-		// Keep the newest minBackups regardless of age (always at least the most recent backup)
-		protected := max(minBackups, 1)
-		for _, b := range backups[min(protected, len(backups)):] {
-			if b.modTime.Before(cutoff) {
-				toDelete[b.name] = true
-			}
-		}
+		backups = append(backups, BackupFile{Name: entry.Name(), CreatedAt: info.ModTime()})
 	}
 
 	pruned := 0
 	var firstErr error
-	for name := range toDelete {
+	for _, name := range expiredBackups(backups, retentionDays, minBackups, maxBackups) {
 		if err := os.Remove(filepath.Join(dir, name)); err != nil {
 			if firstErr == nil {
 				firstErr = err
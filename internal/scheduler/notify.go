@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/webhook"
+)
+
+// Retries for a result post, backing off from one second
+const notifyRetries = 3
+
+// Output beyond this many bytes is cut from result posts, the execution keeps all of it
+const maxNotifyOutput = 4096
+
+// Posts a finished execution to the task's notify URL, or tasks.notify_url when it has none.
+// Delivery runs in the background so retries don't hold up the next task.
+func (s *Scheduler) notifyResult(task *storage.ScheduledTask, server *storage.Server, execution *storage.TaskExecution) {
+	var event string
+	switch execution.Status {
+	case storage.ExecutionStatusCompleted:
+		if !task.SuccessNotify {
+			return
+		}
+		event = "task_completed"
+	case storage.ExecutionStatusSkipped:
+		if !task.FailureNotify {
+			return
+		}
+		event = "task_skipped"
+	default:
+		if !task.FailureNotify {
+			return
+		}
+		event = "task_failed"
+	}
+
+	cfg := webhook.Config{URL: task.NotifyURL, MaxRetries: notifyRetries}
+	if cfg.URL == "" && s.appConfig != nil {
+		cfg.URL = s.appConfig.Tasks.NotifyURL
+		cfg.Secret = s.appConfig.Tasks.NotifySecret
+	}
+	if cfg.URL == "" {
+		return
+	}
+
+	output := execution.Output
+	if len(output) > maxNotifyOutput {
+		output = output[:maxNotifyOutput]
+	}
+	data := map[string]any{
+		"task_id":      task.ID,
+		"task_name":    task.Name,
+		"task_type":    string(task.TaskType),
+		"execution_id": execution.ID,
+		"status":       string(execution.Status),
+		"trigger":      execution.Trigger,
+		"success":      execution.Status == storage.ExecutionStatusCompleted,
+		"output":       output,
+		"duration_ms":  execution.Duration,
+	}
+	if execution.Error != "" {
+		data["error"] = execution.Error
+	}
+	payload := webhook.BuildPayload(event, server, data)
+
+	go func() {
+		result := webhook.Deliver(context.Background(), cfg, payload)
+		if !result.Success {
+			s.log.Warn("Task %s: failed to post result after %d attempts: %s", task.Name, result.Attempts, result.ErrorMessage)
+		}
+	}()
+}
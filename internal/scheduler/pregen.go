@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+)
+
+// How often a running pre-generation is asked for its progress
+const pregenPollInterval = 10 * time.Second
+
+// Chunky's progress line carries the share of chunks done as "(12.34%)"
+var pregenPercent = regexp.MustCompile(`\(([\d.]+)%\)`)
+
+// PregenerateTaskConfig represents configuration for chunk pre-generation tasks
+type PregenerateTaskConfig struct {
+	World   string `json:"world"` // World as Chunky names it, the default world when empty
+	CenterX int    `json:"center_x"`
+	CenterZ int    `json:"center_z"`
+	Radius  int    `json:"radius"` // In blocks
+}
+
+// HasChunky reports whether a Chunky mod or plugin jar is in the server's data directory
+func HasChunky(dataPath string) bool {
+	for _, dir := range []string{"mods", "plugins"} {
+		entries, err := os.ReadDir(filepath.Join(dataPath, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := strings.ToLower(entry.Name())
+			if !entry.IsDir() && strings.HasSuffix(name, ".jar") && strings.Contains(name, "chunky") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) executePregenerateTask(ctx context.Context, server *storage.Server, task *storage.ScheduledTask) (string, error) {
+	var config PregenerateTaskConfig
+	if task.Config != "" {
+		if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+			return "", fmt.Errorf("invalid pregenerate config: %w", err)
+		}
+	}
+	if config.Radius <= 0 {
+		return "", fmt.Errorf("radius must be greater than zero")
+	}
+
+	if server.DataPath == "" {
+		return "", fmt.Errorf("server files are in a Docker volume DiscoPanel can't see, Chunky can't be detected")
+	}
+	if !HasChunky(server.DataPath) {
+		return "", fmt.Errorf("pre-generation is not supported on this server, install the Chunky mod or plugin first")
+	}
+	if server.ContainerID == "" {
+		return "", fmt.Errorf("server has no container")
+	}
+
+	commands := []string{
+		fmt.Sprintf("chunky center %d %d", config.CenterX, config.CenterZ),
+		fmt.Sprintf("chunky radius %d", config.Radius),
+		"chunky start",
+	}
+	if config.World != "" {
+		commands = append([]string{"chunky world " + config.World}, commands...)
+	}
+
+	var output string
+	for _, cmd := range commands {
+		out, err := s.chunky(ctx, server, cmd)
+		if err != nil {
+			return output, err
+		}
+		output = out
+	}
+
+	// Chunky holds a start over an unfinished task of the world until it's confirmed
+	if strings.Contains(strings.ToLower(output), "confirm") {
+		if _, err := s.chunky(ctx, server, "chunky confirm"); err != nil {
+			return output, err
+		}
+	}
+
+	area := fmt.Sprintf("radius %d around %d, %d", config.Radius, config.CenterX, config.CenterZ)
+	s.log.Info("Task %s: pre-generating %s on server %s", task.Name, area, server.Name)
+
+	// The task keeps running in the server, so it's paused when this execution ends early
+	progress := ""
+	for {
+		select {
+		case <-ctx.Done():
+			if _, err := s.sender.SendCommand(context.Background(), server.ID, "chunky pause"); err != nil {
+				s.log.Warn("Task %s: failed to pause pre-generation: %v", task.Name, err)
+			}
+			return fmt.Sprintf("paused at %s", progressOrStart(progress)), ctx.Err()
+		case <-time.After(pregenPollInterval):
+		}
+
+		out, err := s.chunky(ctx, server, "chunky progress")
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return fmt.Sprintf("stopped at %s", progressOrStart(progress)), err
+		}
+		if strings.Contains(strings.ToLower(out), "no tasks running") {
+			return fmt.Sprintf("pre-generated %s", area), nil
+		}
+		if match := pregenPercent.FindStringSubmatch(out); match != nil {
+			progress = match[1] + "%"
+			s.reportProgress(ctx, fmt.Sprintf("pre-generating %s: %s", area, progress))
+		}
+	}
+}
+
+// Sends a Chunky command, failing when the server doesn't know it
+func (s *Scheduler) chunky(ctx context.Context, server *storage.Server, cmd string) (string, error) {
+	out, err := s.sender.SendCommand(ctx, server.ID, cmd)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(out, "Unknown or incomplete command") || strings.Contains(out, "Unknown command") {
+		return "", fmt.Errorf("pre-generation is not supported on this server, Chunky did not answer %q", cmd)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func progressOrStart(progress string) string {
+	if progress == "" {
+		return "the start"
+	}
+	return progress
+}
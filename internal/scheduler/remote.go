@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	appconfig "github.com/nickheyer/discopanel/internal/config"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/s3"
+)
+
+// NewRemoteBackupStore returns the configured off-site backup bucket, nil when remote backups are off
+func NewRemoteBackupStore(cfg *appconfig.Config) (*s3.Client, error) {
+	if cfg == nil || !cfg.Storage.Remote.Enabled {
+		return nil, nil
+	}
+	remote := cfg.Storage.Remote
+	return s3.New(s3.Options{
+		Endpoint:  remote.Endpoint,
+		Region:    remote.Region,
+		Bucket:    remote.Bucket,
+		AccessKey: remote.AccessKey,
+		SecretKey: remote.SecretKey,
+		PathStyle: remote.PathStyle,
+	})
+}
+
+// Remote keys mirror the local layout: <prefix>/<server data dir name>/<archive>
+func remoteBackupPrefix(cfg *appconfig.Config, server *storage.Server) string {
+	return path.Join(strings.Trim(cfg.Storage.Remote.Prefix, "/"), serverDataName(server)) + "/"
+}
+
+// ListRemoteBackups returns the server's backups in the remote store, newest first
+func ListRemoteBackups(ctx context.Context, cfg *appconfig.Config, server *storage.Server) ([]BackupFile, error) {
+	store, err := NewRemoteBackupStore(cfg)
+	if err != nil || store == nil {
+		return nil, err
+	}
+
+	prefix := remoteBackupPrefix(cfg, server)
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote backups: %w", err)
+	}
+
+	var backups []BackupFile
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.Key, prefix)
+		if strings.Contains(name, "/") || !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		backups = append(backups, BackupFile{Name: name, Size: obj.Size, CreatedAt: obj.LastModified, Remote: true})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// MergeBackups combines local and remote listings, marking local archives that also exist remotely
+func MergeBackups(local, remote []BackupFile) []BackupFile {
+	byName := make(map[string]int, len(local))
+	merged := append([]BackupFile(nil), local...)
+	for i, backup := range merged {
+		byName[backup.Name] = i
+	}
+	for _, backup := range remote {
+		if i, ok := byName[backup.Name]; ok {
+			merged[i].Remote = true
+			continue
+		}
+		merged = append(merged, backup)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.After(merged[j].CreatedAt)
+	})
+	return merged
+}
+
+// FetchRemoteBackup downloads a remote-only backup into dir so it can be restored.
+// The caller removes the returned file when done.
+func FetchRemoteBackup(ctx context.Context, cfg *appconfig.Config, server *storage.Server, name, dir string) (*BackupFile, error) {
+	if name == "" || name != filepath.Base(name) || !strings.HasSuffix(name, ".zip") {
+		return nil, fmt.Errorf("invalid backup name %q", name)
+	}
+
+	store, err := NewRemoteBackupStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, fmt.Errorf("backup %s not found", name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	dest, err := os.CreateTemp(dir, "remote-backup-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	dest.Close()
+
+	if err := store.GetFile(ctx, remoteBackupPrefix(cfg, server)+name, dest.Name()); err != nil {
+		os.Remove(dest.Name())
+		if err == s3.ErrNotFound {
+			return nil, fmt.Errorf("backup %s not found", name)
+		}
+		return nil, fmt.Errorf("failed to download backup %s: %w", name, err)
+	}
+
+	info, err := os.Stat(dest.Name())
+	if err != nil {
+		os.Remove(dest.Name())
+		return nil, err
+	}
+	return &BackupFile{Name: name, Path: dest.Name(), Size: info.Size(), CreatedAt: info.ModTime(), Remote: true}, nil
+}
+
+// Ships a freshly created archive off-site, then applies the task's retention to the remote copies
+func (s *Scheduler) uploadBackup(ctx context.Context, server *storage.Server, archivePath, namePrefix string, config *BackupTaskConfig) (string, error) {
+	store, err := NewRemoteBackupStore(s.appConfig)
+	if err != nil {
+		return "", err
+	}
+	if store == nil {
+		return "", fmt.Errorf("remote backup storage is not enabled")
+	}
+
+	key := remoteBackupPrefix(s.appConfig, server) + filepath.Base(archivePath)
+	if err := store.PutFile(ctx, key, archivePath); err != nil {
+		return "", err
+	}
+	output := fmt.Sprintf("uploaded to s3://%s/%s", s.appConfig.Storage.Remote.Bucket, key)
+
+	if config.RetentionDays <= 0 && config.MaxBackups <= 0 {
+		return output, nil
+	}
+
+	remote, err := ListRemoteBackups(ctx, s.appConfig, server)
+	if err != nil {
+		return output + fmt.Sprintf("; remote prune warning: %v", err), nil
+	}
+	var matching []BackupFile
+	for _, backup := range remote {
+		if strings.HasPrefix(backup.Name, namePrefix) {
+			matching = append(matching, backup)
+		}
+	}
+
+	pruned := 0
+	for _, name := range expiredBackups(matching, config.RetentionDays, config.MinBackups, config.MaxBackups) {
+		if err := store.Delete(ctx, remoteBackupPrefix(s.appConfig, server)+name); err != nil {
+			s.log.Warn("Failed to prune remote backup %s: %v", name, err)
+			continue
+		}
+		pruned++
+	}
+	if pruned > 0 {
+		output += fmt.Sprintf("; pruned %d remote backup(s)", pruned)
+	}
+	return output, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ type Scheduler struct {
 	sender        *command.Sender
 	appConfig     *appconfig.Config
 	metrics       *metrics.Collector
+	bus           *events.Bus
 	log           *logger.Logger
 	checkInterval time.Duration
 
@@ -39,6 +41,7 @@ type Scheduler struct {
 
 	// Execution tracking
 	runningExecutions map[string]context.CancelFunc // executionID -> cancel func
+	runningTasks      map[string]struct{}           // taskID of every running execution
 	executionMu       sync.RWMutex
 
 	// Cron parser
@@ -62,7 +65,7 @@ func DefaultConfig() Config {
 }
 
 // NewScheduler creates a new task scheduler
-func NewScheduler(store *storage.Store, docker *docker.Client, sender *command.Sender, appCfg *appconfig.Config, metricsCollector *metrics.Collector, log *logger.Logger, config ...Config) *Scheduler {
+func NewScheduler(store *storage.Store, docker *docker.Client, sender *command.Sender, appCfg *appconfig.Config, metricsCollector *metrics.Collector, bus *events.Bus, log *logger.Logger, config ...Config) *Scheduler {
 	cfg := DefaultConfig()
 	if len(config) > 0 {
 		cfg = config[0]
@@ -74,10 +77,12 @@ func NewScheduler(store *storage.Store, docker *docker.Client, sender *command.S
 		sender:            sender,
 		appConfig:         appCfg,
 		metrics:           metricsCollector,
+		bus:               bus,
 		log:               log,
 		checkInterval:     cfg.CheckInterval,
 		stopChan:          make(chan struct{}),
 		runningExecutions: make(map[string]context.CancelFunc),
+		runningTasks:      make(map[string]struct{}),
 		cronParser:        cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow),
 	}
 }
@@ -208,6 +213,11 @@ func (s *Scheduler) checkAndRunDueTasks() {
 	}
 
 	for _, task := range tasks {
+		// Next run is only moved on once an execution ends, a long one stays due meanwhile
+		if s.isTaskRunning(task.ID) {
+			continue
+		}
+
 		// Execute task asynchronously
 		s.wg.Add(1)
 		go func(t *storage.ScheduledTask) {
@@ -282,6 +292,7 @@ func (s *Scheduler) executeTask(task *storage.ScheduledTask, trigger string, eve
 		now := time.Now()
 		execution.EndedAt = &now
 		s.store.CreateTaskExecution(ctx, execution)
+		s.notifyResult(task, server, execution)
 
 		// Update next run time
 		s.updateNextRun(task)
@@ -308,16 +319,19 @@ func (s *Scheduler) executeTask(task *storage.ScheduledTask, trigger string, eve
 		timeout = 5 * time.Minute // Default timeout
 	}
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	execCtx = context.WithValue(execCtx, executionKey{}, execution)
 
 	// Track running execution
 	s.executionMu.Lock()
 	s.runningExecutions[execution.ID] = cancel
+	s.runningTasks[task.ID] = struct{}{}
 	s.executionMu.Unlock()
 
 	defer func() {
 		cancel()
 		s.executionMu.Lock()
 		delete(s.runningExecutions, execution.ID)
+		delete(s.runningTasks, task.ID)
 		s.executionMu.Unlock()
 	}()
 
@@ -373,6 +387,7 @@ func (s *Scheduler) executeTask(task *storage.ScheduledTask, trigger string, eve
 	}
 
 	s.store.UpdateTaskExecution(ctx, execution)
+	s.notifyResult(task, server, execution)
 
 	// Update next run time
 	s.updateNextRun(task)
@@ -397,11 +412,42 @@ func (s *Scheduler) runTaskType(ctx context.Context, server *storage.Server, tas
 		return s.executeScriptTask(ctx, server, task)
 	case storage.TaskTypeWebhook:
 		return s.executeWebhookTask(ctx, server, task, eventType, eventData)
+	case storage.TaskTypeImageUpdate:
+		return s.executeImageUpdateTask(ctx, server, task)
+	case storage.TaskTypeBackupVerify:
+		return s.executeBackupVerifyTask(ctx, server, task)
+	case storage.TaskTypeBroadcast:
+		return s.executeBroadcastTask(ctx, server, task)
+	case storage.TaskTypePregenerate:
+		return s.executePregenerateTask(ctx, server, task)
 	default:
 		return "", fmt.Errorf("unknown task type: %s", task.TaskType)
 	}
 }
 
+// Context key of the execution an executor runs for
+type executionKey struct{}
+
+// Reports whether an execution of the task is running
+func (s *Scheduler) isTaskRunning(taskID string) bool {
+	s.executionMu.RLock()
+	defer s.executionMu.RUnlock()
+	_, running := s.runningTasks[taskID]
+	return running
+}
+
+// Stores output of a running execution, so long tasks show how far they got
+func (s *Scheduler) reportProgress(ctx context.Context, output string) {
+	execution, ok := ctx.Value(executionKey{}).(*storage.TaskExecution)
+	if !ok {
+		return
+	}
+	execution.Output = output
+	if err := s.store.UpdateTaskExecution(context.Background(), execution); err != nil {
+		s.log.Warn("Failed to update progress of execution %s: %v", execution.ID, err)
+	}
+}
+
 // CancelExecution cancels a running execution
 func (s *Scheduler) CancelExecution(executionID string) error {
 	s.executionMu.RLock()
@@ -475,6 +521,41 @@ func (s *Scheduler) executeCommandTask(ctx context.Context, server *storage.Serv
 	return output, err
 }
 
+// BroadcastTaskConfig represents configuration for broadcast tasks
+type BroadcastTaskConfig struct {
+	Message string `json:"message"` // Each line is sent as its own say
+}
+
+func (s *Scheduler) executeBroadcastTask(ctx context.Context, server *storage.Server, task *storage.ScheduledTask) (string, error) {
+	var config BroadcastTaskConfig
+	if task.Config != "" {
+		if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+			return "", fmt.Errorf("invalid broadcast config: %w", err)
+		}
+	}
+
+	var lines []string
+	for line := range strings.Lines(config.Message) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no message specified")
+	}
+
+	if server.ContainerID == "" {
+		return "", fmt.Errorf("server has no container")
+	}
+
+	for _, line := range lines {
+		if _, err := s.sender.SendCommand(ctx, server.ID, "say "+line); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("broadcast %d line(s)", len(lines)), nil
+}
+
 func (s *Scheduler) executeRestartTask(ctx context.Context, server *storage.Server, _ *storage.ScheduledTask) (string, error) {
 	if server.ContainerID == "" {
 		return "", fmt.Errorf("server has no container")
@@ -680,6 +761,12 @@ func webhookEventName(t v1.TriggeredEventType) string {
 		return "player_join"
 	case v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_PLAYER_LEAVE:
 		return "player_leave"
+	case v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_IMAGE_UPDATED:
+		return "image_updated"
+	case v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_BACKUP_VERIFY_FAILED:
+		return "backup_verify_failed"
+	case v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_SERVER_UNHEALTHY_RESTART:
+		return "server_unhealthy_restart"
 	default:
 		return "manual"
 	}
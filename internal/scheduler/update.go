@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/docker"
+	"github.com/nickheyer/discopanel/internal/events"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// ImageUpdateTaskConfig represents configuration for image update tasks
+type ImageUpdateTaskConfig struct {
+	SkipBackup  bool     `json:"skip_backup"`
+	BackupPaths []string `json:"backup_paths"`
+}
+
+// Recreates the server container when its image tag resolves to a newer digest.
+// The task schedule acts as the maintenance window. A world backup is taken
+// before the container is replaced unless disabled, and an image_updated event
+// is emitted afterwards so webhook tasks can announce it.
+func (s *Scheduler) executeImageUpdateTask(ctx context.Context, server *storage.Server, task *storage.ScheduledTask) (string, error) {
+	var config ImageUpdateTaskConfig
+	if task.Config != "" {
+		if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+			return "", fmt.Errorf("invalid image update config: %w", err)
+		}
+	}
+
+	if server.ContainerID == "" {
+		return "", fmt.Errorf("server has no container")
+	}
+	if server.Imported {
+		return "", fmt.Errorf("server runs an imported container, recreate it from DiscoPanel before scheduling image updates")
+	}
+
+	imageName := docker.ServerImage(server)
+	update, err := s.docker.CheckImageUpdate(ctx, server.ContainerID, imageName, false)
+	if err != nil {
+		return "", err
+	}
+	if !update.Available {
+		return fmt.Sprintf("%s is up to date (%s)", imageName, shortDigest(update.LatestDigest)), nil
+	}
+
+	var output []string
+	if !config.SkipBackup {
+		backupConfig, err := json.Marshal(BackupTaskConfig{
+			BackupName: "pre-update",
+			Paths:      config.BackupPaths,
+			Compress:   true,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to build backup config: %w", err)
+		}
+		backupTask := *task
+		backupTask.Config = string(backupConfig)
+		backupOutput, err := s.executeBackupTask(ctx, server, &backupTask)
+		if err != nil {
+			return "", fmt.Errorf("pre-update backup failed, image not updated: %w", err)
+		}
+		output = append(output, backupOutput)
+	}
+
+	serverConfig, err := s.store.GetServerConfig(ctx, server.ID)
+	if err != nil {
+		return strings.Join(output, "\n"), fmt.Errorf("failed to get server config: %w", err)
+	}
+
+	result, err := s.docker.RecreateContainer(ctx, server.ContainerID, server, serverConfig)
+	if err != nil {
+		if result != nil && result.NewContainerID != "" {
+			server.ContainerID = result.NewContainerID
+		}
+		server.Status = storage.StatusError
+		s.store.UpdateServer(ctx, server)
+		return strings.Join(output, "\n"), fmt.Errorf("failed to recreate container: %w", err)
+	}
+
+	server.ContainerID = result.NewContainerID
+	server.PortBindingWarning = result.PortBindingWarning
+	server.RestartRequired = false
+	if result.WasRunning {
+		server.Status = storage.StatusStarting
+		now := time.Now()
+		server.LastStarted = &now
+	} else {
+		server.Status = storage.StatusStopped
+	}
+	s.store.UpdateServer(ctx, server)
+
+	if s.bus != nil {
+		s.bus.Emit(ctx, events.Event{
+			Type:     v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_IMAGE_UPDATED,
+			ServerID: server.ID,
+			Data: map[string]any{
+				"image":           imageName,
+				"previous_digest": update.CurrentDigest,
+				"digest":          update.LatestDigest,
+			},
+		})
+	}
+
+	s.log.Info("Server %s updated to %s (%s)", server.Name, imageName, shortDigest(update.LatestDigest))
+	output = append(output, fmt.Sprintf("updated %s from %s to %s", imageName, shortDigest(update.CurrentDigest), shortDigest(update.LatestDigest)))
+	return strings.Join(output, "\n"), nil
+}
+
+// Trims a sha256 digest to its first 12 hex characters for display
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if digest == "" {
+		return "unknown"
+	}
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
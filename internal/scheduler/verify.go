@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/events"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// BackupVerifyTaskConfig represents configuration for backup verification tasks
+type BackupVerifyTaskConfig struct {
+	Count int `json:"count"` // Most recent backups to verify (0 = 1)
+}
+
+// BackupVerification is the integrity report for a single backup archive
+type BackupVerification struct {
+	Name         string
+	OK           bool
+	FilesChecked int
+	Bytes        int64
+	Worlds       []string // Directories holding a level.dat, "." for the archive root
+	Problems     []string
+	VerifiedAt   time.Time
+}
+
+// Problems reported per archive before the rest are summarised
+const maxVerifyProblems = 20
+
+// VerifyBackup reads every entry of a backup archive without extracting it.
+// archive/zip checks each entry's CRC-32 once it is read to the end, so a
+// truncated or bit-rotted archive shows up as a failed entry. The archive
+// must also contain at least one world (a directory with a level.dat).
+func VerifyBackup(ctx context.Context, backup *BackupFile) *BackupVerification {
+	result := &BackupVerification{Name: backup.Name, VerifiedAt: time.Now()}
+
+	zr, err := zip.OpenReader(backup.Path)
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("archive is not readable: %v", err))
+		return result
+	}
+	defer zr.Close()
+
+	failed := 0
+	for _, f := range zr.File {
+		if ctx.Err() != nil {
+			result.Problems = append(result.Problems, "verification cancelled")
+			return result
+		}
+
+		name := strings.TrimPrefix(f.Name, "/")
+		if path.Base(name) == "level.dat" && !f.FileInfo().IsDir() {
+			result.Worlds = append(result.Worlds, path.Dir(name))
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		n, err := verifyZipEntry(f)
+		result.Bytes += n
+		result.FilesChecked++
+		if err != nil {
+			failed++
+			if failed <= maxVerifyProblems {
+				result.Problems = append(result.Problems, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+	if failed > maxVerifyProblems {
+		result.Problems = append(result.Problems, fmt.Sprintf("%d more damaged entries", failed-maxVerifyProblems))
+	}
+
+	if len(result.Worlds) == 0 {
+		result.Problems = append(result.Problems, "no level.dat found, archive contains no world")
+	}
+	sort.Strings(result.Worlds)
+
+	result.OK = len(result.Problems) == 0
+	return result
+}
+
+func verifyZipEntry(f *zip.File) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(io.Discard, rc)
+}
+
+// Verifies the newest backups of the server. Any failure fails the execution and
+// emits a backup_verify_failed event so webhook tasks can raise an alert.
+func (s *Scheduler) executeBackupVerifyTask(ctx context.Context, server *storage.Server, task *storage.ScheduledTask) (string, error) {
+	var config BackupVerifyTaskConfig
+	if task.Config != "" {
+		if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+			return "", fmt.Errorf("invalid backup verify config: %w", err)
+		}
+	}
+	if config.Count <= 0 {
+		config.Count = 1
+	}
+
+	backups, err := ListServerBackups(s.appConfig.Storage.BackupDir, server)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("no backups to verify")
+	}
+	backups = backups[:min(config.Count, len(backups))]
+
+	var output []string
+	var failed []string
+	for i := range backups {
+		result := VerifyBackup(ctx, &backups[i])
+		if result.OK {
+			output = append(output, fmt.Sprintf("%s: ok, %d files (%s), worlds: %s",
+				result.Name, result.FilesChecked, formatBytes(result.Bytes), strings.Join(result.Worlds, ", ")))
+			continue
+		}
+
+		failed = append(failed, result.Name)
+		output = append(output, fmt.Sprintf("%s: FAILED", result.Name))
+		for _, problem := range result.Problems {
+			output = append(output, "  "+problem)
+		}
+	}
+
+	if len(failed) == 0 {
+		return strings.Join(output, "\n"), nil
+	}
+
+	if s.bus != nil {
+		s.bus.Emit(ctx, events.Event{
+			Type:     v1.TriggeredEventType_TRIGGERED_EVENT_TYPE_BACKUP_VERIFY_FAILED,
+			ServerID: server.ID,
+			Data: map[string]any{
+				"backups": failed,
+				"task":    task.Name,
+			},
+		})
+	}
+
+	s.log.Warn("Backup verification failed for server %s: %s", server.Name, strings.Join(failed, ", "))
+	return strings.Join(output, "\n"), fmt.Errorf("%d of %d backups failed verification", len(failed), len(backups))
+}
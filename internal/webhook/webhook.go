@@ -177,12 +177,18 @@ func templateData(p *Payload) map[string]any {
 		"server_start":   "Server Started",
 		"server_stop":    "Server Stopped",
 		"server_restart": "Server Restarted",
+		"task_completed": "Task Completed",
+		"task_failed":    "Task Failed",
+		"task_skipped":   "Task Skipped",
 	}
 	colors := map[string]int{
 		"test":           0x5865F2,
 		"server_start":   0x57F287,
 		"server_stop":    0xED4245,
 		"server_restart": 0xFEE75C,
+		"task_completed": 0x57F287,
+		"task_failed":    0xED4245,
+		"task_skipped":   0xFEE75C,
 	}
 
 	title := titles[p.Event]
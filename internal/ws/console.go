@@ -0,0 +1,331 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/internal/rbac"
+	"github.com/nickheyer/discopanel/internal/rcon"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// Number of history entries persisted per server
+	consoleHistoryKeep = 500
+
+	// Number of history entries replayed to a new console
+	consoleHistoryReplay = 100
+
+	// How often the console checks server state and re-establishes RCON
+	consoleWatchInterval = 5 * time.Second
+
+	// Time allowed for a single RCON command round trip
+	consoleCommandTimeout = 10 * time.Second
+)
+
+// consoleSession holds the RCON state for a client attached to a single server console
+type consoleSession struct {
+	serverID string
+
+	started bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mu        sync.Mutex
+	rcon      *rcon.Session
+	target    string
+	reported  bool
+	connected bool
+	lastError string
+}
+
+// ServeConsole handles WebSocket upgrades for the interactive RCON console of a server
+//
+//	GET /api/v1/servers/{id}/rcon/ws
+//
+// The client authenticates with an AUTH message, after which the console
+// sends the persisted command history and the current RCON connection state.
+// The server is only looked up once the client is authenticated, so unauthenticated
+// clients can't tell which server IDs exist.
+func (h *Hub) ServeConsole(w http.ResponseWriter, r *http.Request) {
+	serverID := r.PathValue("id")
+	if serverID == "" {
+		http.Error(w, "missing server id", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.log.Error("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	client := &Client{
+		hub:                 h,
+		conn:                conn,
+		send:                make(chan []byte, 256),
+		subscriptions:       make(map[string]chan *v1.LogEntry),
+		moduleSubscriptions: make(map[string]chan *v1.LogEntry),
+		console: &consoleSession{
+			serverID: serverID,
+			done:     make(chan struct{}),
+		},
+	}
+
+	h.register <- client
+
+	go client.writePump()
+	go client.readPump()
+}
+
+// startConsole verifies command permission and begins watching the server's RCON state
+func (c *Client) startConsole() {
+	cs := c.console
+	if cs.started {
+		return
+	}
+
+	// Fail closed, every successful AUTH sets a user
+	if c.user == nil || !c.user.Allows(rbac.ResourceServers, rbac.ActionCommand) {
+		c.sendError("permission denied")
+		c.authenticated = false
+		return
+	}
+	if c.hub.enforcer != nil {
+		allowed, err := c.hub.enforcer.EnforceUser(context.Background(), c.user.ID, c.user.Roles, rbac.ResourceServers, rbac.ActionCommand, cs.serverID)
+		if err != nil || !allowed {
+			c.sendError("permission denied")
+			c.authenticated = false
+			return
+		}
+	}
+	if _, err := c.hub.store.GetServer(context.Background(), cs.serverID); err != nil {
+		c.sendError("server not found")
+		c.authenticated = false
+		return
+	}
+	cs.started = true
+
+	c.sendConsoleHistory()
+	c.syncConsole()
+
+	cs.wg.Add(1)
+	go c.watchConsole()
+}
+
+// watchConsole keeps the RCON connection in step with the server lifecycle
+func (c *Client) watchConsole() {
+	defer c.console.wg.Done()
+
+	ticker := time.NewTicker(consoleWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.console.done:
+			return
+		case <-ticker.C:
+			c.syncConsole()
+		}
+	}
+}
+
+// syncConsole connects or drops RCON based on the container state, reporting any change to the client
+func (c *Client) syncConsole() (*rcon.Session, string) {
+	cs := c.console
+	ctx, cancel := context.WithTimeout(context.Background(), consoleCommandTimeout)
+	defer cancel()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	connected, errMsg := c.connectConsoleLocked(ctx)
+	if !cs.reported || connected != cs.connected || errMsg != cs.lastError {
+		cs.reported = true
+		cs.connected = connected
+		cs.lastError = errMsg
+		c.sendRconStatus(cs.serverID, connected, errMsg)
+	}
+
+	if !connected {
+		return nil, errMsg
+	}
+	return cs.rcon, ""
+}
+
+// connectConsoleLocked (re)establishes RCON, redialing if the container address or credentials changed
+func (c *Client) connectConsoleLocked(ctx context.Context) (bool, string) {
+	cs := c.console
+
+	server, err := c.hub.store.GetServer(ctx, cs.serverID)
+	if err != nil {
+		c.closeConsoleLocked()
+		return false, "server not found"
+	}
+
+	if server.ContainerID == "" {
+		c.closeConsoleLocked()
+		return false, ""
+	}
+
+	status, err := c.hub.docker.GetContainerStatus(ctx, server.ContainerID)
+	if err != nil || status != storage.StatusRunning {
+		c.closeConsoleLocked()
+		return false, ""
+	}
+
+	host, port, password, err := c.hub.sender.ResolveRCON(ctx, server)
+	if err != nil {
+		c.closeConsoleLocked()
+		return false, err.Error()
+	}
+
+	target := server.ContainerID + "|" + host + "|" + password
+	if cs.rcon == nil || cs.target != target {
+		c.closeConsoleLocked()
+		cs.rcon = rcon.NewSession(host, port, password)
+		cs.target = target
+	}
+
+	if err := cs.rcon.Connect(ctx); err != nil {
+		// RCON may not be listening yet while the server is still starting up
+		return false, ""
+	}
+	return true, ""
+}
+
+func (c *Client) closeConsoleLocked() {
+	cs := c.console
+	if cs.rcon != nil {
+		cs.rcon.Close()
+		cs.rcon = nil
+	}
+	cs.target = ""
+}
+
+// handleConsoleCommand sends a command over the console's persistent RCON connection
+func (c *Client) handleConsoleCommand(msg *v1.CommandMessage) {
+	cs := c.console
+	if !c.authenticated || !cs.started {
+		c.sendError("not authenticated")
+		return
+	}
+
+	if msg == nil || msg.Command == "" {
+		c.sendError("missing command")
+		return
+	}
+
+	if msg.ServerId != "" && msg.ServerId != cs.serverID {
+		c.sendError("command targets a different server")
+		return
+	}
+
+	session, errMsg := c.syncConsole()
+	if session == nil {
+		if errMsg == "" {
+			errMsg = "server is not running"
+		}
+		c.sendError(errMsg)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), consoleCommandTimeout)
+	defer cancel()
+
+	server, err := c.hub.store.GetServer(ctx, cs.serverID)
+	if err != nil {
+		c.sendError("server not found")
+		return
+	}
+
+	silent := msg.Silent != nil && *msg.Silent
+
+	commandTime := time.Now()
+	if !silent {
+		c.hub.logStreamer.AddCommandEntry(server.ContainerID, msg.Command, commandTime)
+	}
+
+	entry := &storage.ConsoleHistory{
+		ServerID: cs.serverID,
+		Command:  msg.Command,
+	}
+	if c.user != nil {
+		entry.UserID = c.user.ID
+		entry.Username = c.user.Username
+	}
+	if err := c.hub.store.AddConsoleHistory(ctx, entry, consoleHistoryKeep); err != nil {
+		c.hub.log.Warn("Failed to persist console history for server %s: %v", cs.serverID, err)
+	}
+
+	output, err := session.Send(ctx, msg.Command)
+	success := err == nil
+
+	if !silent && (output != "" || !success) {
+		c.hub.logStreamer.AddCommandOutput(server.ContainerID, output, success, commandTime)
+	}
+
+	if err != nil {
+		c.sendCommandResult(cs.serverID, false, "", err.Error())
+		// Report the dropped connection now rather than on the next watcher pass
+		c.syncConsole()
+		return
+	}
+
+	c.sendCommandResult(cs.serverID, true, output, "")
+}
+
+// stopConsole releases the console's RCON connection
+func (c *Client) stopConsole() {
+	cs := c.console
+	close(cs.done)
+	cs.wg.Wait()
+
+	cs.mu.Lock()
+	c.closeConsoleLocked()
+	cs.mu.Unlock()
+}
+
+func (c *Client) sendConsoleHistory() {
+	serverID := c.console.serverID
+	entries, err := c.hub.store.ListConsoleHistory(context.Background(), serverID, consoleHistoryReplay)
+	if err != nil {
+		c.hub.log.Warn("Failed to load console history for server %s: %v", serverID, err)
+	}
+
+	history := make([]*v1.ConsoleHistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		history = append(history, &v1.ConsoleHistoryEntry{
+			Command:   e.Command,
+			Username:  e.Username,
+			Timestamp: timestamppb.New(e.CreatedAt),
+		})
+	}
+
+	c.sendMessage(&v1.WebSocketServerMessage{
+		Type: v1.WSMessageType_WS_MESSAGE_TYPE_HISTORY,
+		Payload: &v1.WebSocketServerMessage_History{
+			History: &v1.HistoryMessage{
+				ServerId: serverID,
+				Entries:  history,
+			},
+		},
+	})
+}
+
+func (c *Client) sendRconStatus(serverId string, connected bool, errMsg string) {
+	c.sendMessage(&v1.WebSocketServerMessage{
+		Type: v1.WSMessageType_WS_MESSAGE_TYPE_RCON_STATUS,
+		Payload: &v1.WebSocketServerMessage_RconStatus{
+			RconStatus: &v1.RconStatusMessage{
+				ServerId:  serverId,
+				Connected: connected,
+				Error:     errMsg,
+			},
+		},
+	})
+}
@@ -0,0 +1,136 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/gorilla/websocket"
+	"github.com/nickheyer/discopanel/internal/auth"
+	"github.com/nickheyer/discopanel/internal/config"
+	storage "github.com/nickheyer/discopanel/internal/db"
+	"github.com/nickheyer/discopanel/pkg/logger"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Dials the console of a server with no container, on a panel with auth turned off and a
+// module whose container the log streamer can't reach
+func dialTestConsole(t *testing.T) (*websocket.Conn, *storage.Module) {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "discopanel.db")
+	cfg.Database.AutoMigrate = true
+	store, err := storage.NewStore(cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	authManager, err := auth.NewManager(store, nil, &config.AuthConfig{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	server := &storage.Server{ID: "console-test", Name: "Console Test", ModLoader: storage.ModLoaderVanilla, MCVersion: "1.21.1", Port: 25565, DataPath: t.TempDir()}
+	if err := store.CreateServer(ctx, server); err != nil {
+		t.Fatalf("CreateServer: %v", err)
+	}
+	template := &storage.ModuleTemplate{Name: "Console Test Module", DockerImage: "busybox"}
+	if err := store.CreateModuleTemplate(ctx, template); err != nil {
+		t.Fatalf("CreateModuleTemplate: %v", err)
+	}
+	module := &storage.Module{Name: "sidecar", ServerID: server.ID, TemplateID: template.ID, ContainerID: "module-container"}
+	if err := store.CreateModule(ctx, module); err != nil {
+		t.Fatalf("CreateModule: %v", err)
+	}
+
+	daemon := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(daemon.Close)
+	dockerClient, err := client.NewClientWithOpts(client.WithHost("tcp://"+daemon.Listener.Addr().String()), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatalf("NewClientWithOpts: %v", err)
+	}
+	t.Cleanup(func() { dockerClient.Close() })
+
+	log := logger.New()
+	hub := NewHub(logger.NewLogStreamer(dockerClient, log, 0), authManager, nil, store, nil, nil, log)
+	go hub.Run()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/console/{id}", hub.ServeConsole)
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws/console/"+server.ID, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, module
+}
+
+func sendTestMessage(t *testing.T, conn *websocket.Conn, msg *v1.WebSocketClientMessage) {
+	t.Helper()
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+}
+
+// Reads until a message of the given type arrives, failing on errors along the way
+func awaitTestMessage(t *testing.T, conn *websocket.Conn, want v1.WSMessageType) *v1.WebSocketServerMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", want, err)
+		}
+		msg := &v1.WebSocketServerMessage{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if msg.Type == v1.WSMessageType_WS_MESSAGE_TYPE_ERROR {
+			t.Fatalf("waiting for %s: got error %q", want, msg.GetError().GetError())
+		}
+		if msg.Type == want {
+			return msg
+		}
+	}
+}
+
+func TestConsoleModuleSubscribe(t *testing.T) {
+	conn, module := dialTestConsole(t)
+
+	sendTestMessage(t, conn, &v1.WebSocketClientMessage{
+		Type:    v1.WSMessageType_WS_MESSAGE_TYPE_AUTH,
+		Payload: &v1.WebSocketClientMessage_Auth{Auth: &v1.AuthMessage{}},
+	})
+	awaitTestMessage(t, conn, v1.WSMessageType_WS_MESSAGE_TYPE_AUTH_OK)
+
+	sendTestMessage(t, conn, &v1.WebSocketClientMessage{
+		Type:    v1.WSMessageType_WS_MESSAGE_TYPE_SUBSCRIBE,
+		Payload: &v1.WebSocketClientMessage_Subscribe{Subscribe: &v1.SubscribeMessage{ModuleId: module.ID}},
+	})
+	subscribed := awaitTestMessage(t, conn, v1.WSMessageType_WS_MESSAGE_TYPE_SUBSCRIBED)
+	if got := subscribed.GetSubscribed().GetModuleId(); got != module.ID {
+		t.Errorf("subscribed module_id = %q, want %q", got, module.ID)
+	}
+
+	sendTestMessage(t, conn, &v1.WebSocketClientMessage{
+		Type:    v1.WSMessageType_WS_MESSAGE_TYPE_UNSUBSCRIBE,
+		Payload: &v1.WebSocketClientMessage_Unsubscribe{Unsubscribe: &v1.UnsubscribeMessage{ModuleId: module.ID}},
+	})
+	awaitTestMessage(t, conn, v1.WSMessageType_WS_MESSAGE_TYPE_UNSUBSCRIBED)
+}
@@ -63,9 +63,13 @@ type Client struct {
 	user          *auth.AuthenticatedUser
 	authenticated bool
 
-	// Subscriptions: serverId -> log channel
-	subscriptions   map[string]chan *v1.LogEntry
-	subscriptionsMu sync.RWMutex
+	// Subscriptions: serverId -> log channel, moduleId -> log channel
+	subscriptions       map[string]chan *v1.LogEntry
+	moduleSubscriptions map[string]chan *v1.LogEntry
+	subscriptionsMu     sync.RWMutex
+
+	// RCON console state, only set for clients attached via ServeConsole
+	console *consoleSession
 }
 
 // NewHub creates a new WebSocket hub
@@ -120,10 +124,11 @@ func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		hub:           h,
-		conn:          conn,
-		send:          make(chan []byte, 256),
-		subscriptions: make(map[string]chan *v1.LogEntry),
+		hub:                 h,
+		conn:                conn,
+		send:                make(chan []byte, 256),
+		subscriptions:       make(map[string]chan *v1.LogEntry),
+		moduleSubscriptions: make(map[string]chan *v1.LogEntry),
 	}
 
 	h.register <- client
@@ -203,12 +208,19 @@ func (c *Client) handleMessage(data []byte) {
 	switch msg.Type {
 	case v1.WSMessageType_WS_MESSAGE_TYPE_AUTH:
 		c.handleAuth(msg.GetAuth())
+		if c.console != nil && c.authenticated {
+			c.startConsole()
+		}
 	case v1.WSMessageType_WS_MESSAGE_TYPE_SUBSCRIBE:
 		c.handleSubscribe(msg.GetSubscribe())
 	case v1.WSMessageType_WS_MESSAGE_TYPE_UNSUBSCRIBE:
 		c.handleUnsubscribe(msg.GetUnsubscribe())
 	case v1.WSMessageType_WS_MESSAGE_TYPE_COMMAND:
-		c.handleCommand(msg.GetCommand())
+		if c.console != nil {
+			c.handleConsoleCommand(msg.GetCommand())
+		} else {
+			c.handleCommand(msg.GetCommand())
+		}
 	case v1.WSMessageType_WS_MESSAGE_TYPE_PING:
 		c.sendPong()
 	default:
@@ -276,14 +288,23 @@ func (c *Client) handleSubscribe(msg *v1.SubscribeMessage) {
 		return
 	}
 
+	if msg != nil && msg.ModuleId != "" {
+		c.handleModuleSubscribe(msg)
+		return
+	}
+
 	if msg == nil || msg.ServerId == "" {
 		c.sendError("missing server_id")
 		return
 	}
 
 	// Check permission
+	if c.user != nil && !c.user.Allows(rbac.ResourceServers, rbac.ActionRead) {
+		c.sendError("permission denied")
+		return
+	}
 	if c.hub.enforcer != nil && c.user != nil {
-		allowed, err := c.hub.enforcer.Enforce(c.user.Roles, rbac.ResourceServers, rbac.ActionRead, msg.ServerId)
+		allowed, err := c.hub.enforcer.EnforceUser(context.Background(), c.user.ID, c.user.Roles, rbac.ResourceServers, rbac.ActionRead, msg.ServerId)
 		if err != nil || !allowed {
 			c.sendError("permission denied")
 			return
@@ -344,6 +365,11 @@ func (c *Client) forwardLogs(serverId string, ch chan *v1.LogEntry) {
 
 // handleUnsubscribe unsubscribes from server logs
 func (c *Client) handleUnsubscribe(msg *v1.UnsubscribeMessage) {
+	if msg != nil && msg.ModuleId != "" {
+		c.handleModuleUnsubscribe(msg)
+		return
+	}
+
 	if msg == nil || msg.ServerId == "" {
 		c.sendError("missing server_id")
 		return
@@ -386,8 +412,12 @@ func (c *Client) handleCommand(msg *v1.CommandMessage) {
 	}
 
 	// Check command permission
+	if c.user != nil && !c.user.Allows(rbac.ResourceServers, rbac.ActionCommand) {
+		c.sendCommandResult(msg.ServerId, false, "", "permission denied")
+		return
+	}
 	if c.hub.enforcer != nil && c.user != nil {
-		allowed, err := c.hub.enforcer.Enforce(c.user.Roles, rbac.ResourceServers, rbac.ActionCommand, msg.ServerId)
+		allowed, err := c.hub.enforcer.EnforceUser(context.Background(), c.user.ID, c.user.Roles, rbac.ResourceServers, rbac.ActionCommand, msg.ServerId)
 		if err != nil || !allowed {
 			c.sendCommandResult(msg.ServerId, false, "", "permission denied")
 			return
@@ -437,6 +467,10 @@ func (c *Client) handleCommand(msg *v1.CommandMessage) {
 
 // cleanup removes all subscriptions when client disconnects
 func (c *Client) cleanup() {
+	if c.console != nil {
+		c.stopConsole()
+	}
+
 	c.subscriptionsMu.Lock()
 	defer c.subscriptionsMu.Unlock()
 
@@ -448,6 +482,14 @@ func (c *Client) cleanup() {
 		}
 	}
 	c.subscriptions = make(map[string]chan *v1.LogEntry)
+
+	for moduleId, ch := range c.moduleSubscriptions {
+		module, err := c.hub.store.GetModule(ctx, moduleId)
+		if err == nil && module.ContainerID != "" {
+			c.hub.logStreamer.Unsubscribe(module.ContainerID, ch)
+		}
+	}
+	c.moduleSubscriptions = make(map[string]chan *v1.LogEntry)
 }
 
 // sendMessage marshals and sends a server message
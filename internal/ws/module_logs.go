@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/nickheyer/discopanel/internal/rbac"
+	v1 "github.com/nickheyer/discopanel/pkg/proto/discopanel/v1"
+)
+
+// handleModuleSubscribe subscribes to a module container's logs
+func (c *Client) handleModuleSubscribe(msg *v1.SubscribeMessage) {
+	if c.user != nil && !c.user.Allows(rbac.ResourceModules, rbac.ActionRead) {
+		c.sendError("permission denied")
+		return
+	}
+	if c.hub.enforcer != nil && c.user != nil {
+		allowed, err := c.hub.enforcer.EnforceUser(context.Background(), c.user.ID, c.user.Roles, rbac.ResourceModules, rbac.ActionRead, msg.ModuleId)
+		if err != nil || !allowed {
+			c.sendError("permission denied")
+			return
+		}
+	}
+
+	ctx := context.Background()
+	module, err := c.hub.store.GetModule(ctx, msg.ModuleId)
+	if err != nil {
+		c.sendError("module not found")
+		return
+	}
+
+	tail := int(msg.Tail)
+	if tail <= 0 {
+		tail = 500
+	}
+
+	// Same as servers, the client re-subscribes once the module has a container
+	if module.ContainerID == "" {
+		c.sendModuleLogs(msg.ModuleId, nil)
+		c.sendModuleSubscribed(msg.ModuleId)
+		return
+	}
+
+	if err := c.hub.logStreamer.StartStreaming(module.ContainerID); err != nil {
+		c.hub.log.Warn("Failed to start log streaming for container %s: %v", module.ContainerID, err)
+	}
+
+	c.subscriptionsMu.Lock()
+	if _, exists := c.moduleSubscriptions[msg.ModuleId]; !exists {
+		ch := c.hub.logStreamer.Subscribe(module.ContainerID)
+		c.moduleSubscriptions[msg.ModuleId] = ch
+		go c.forwardModuleLogs(msg.ModuleId, ch)
+	}
+	c.subscriptionsMu.Unlock()
+
+	c.sendModuleLogs(msg.ModuleId, c.hub.logStreamer.GetLogs(module.ContainerID, tail))
+	c.sendModuleSubscribed(msg.ModuleId)
+}
+
+func (c *Client) forwardModuleLogs(moduleId string, ch chan *v1.LogEntry) {
+	for entry := range ch {
+		c.sendMessage(&v1.WebSocketServerMessage{
+			Type: v1.WSMessageType_WS_MESSAGE_TYPE_LOG,
+			Payload: &v1.WebSocketServerMessage_Log{
+				Log: &v1.LogMessage{
+					ModuleId: moduleId,
+					Log:      entry,
+				},
+			},
+		})
+	}
+}
+
+// handleModuleUnsubscribe unsubscribes from a module's logs
+func (c *Client) handleModuleUnsubscribe(msg *v1.UnsubscribeMessage) {
+	module, err := c.hub.store.GetModule(context.Background(), msg.ModuleId)
+
+	c.subscriptionsMu.Lock()
+	if ch, exists := c.moduleSubscriptions[msg.ModuleId]; exists {
+		delete(c.moduleSubscriptions, msg.ModuleId)
+		if err == nil && module.ContainerID != "" {
+			c.hub.logStreamer.Unsubscribe(module.ContainerID, ch)
+		} else {
+			close(ch)
+		}
+	}
+	c.subscriptionsMu.Unlock()
+
+	c.sendMessage(&v1.WebSocketServerMessage{
+		Type: v1.WSMessageType_WS_MESSAGE_TYPE_UNSUBSCRIBED,
+		Payload: &v1.WebSocketServerMessage_Unsubscribed{
+			Unsubscribed: &v1.UnsubscribedMessage{
+				ModuleId: msg.ModuleId,
+			},
+		},
+	})
+}
+
+func (c *Client) sendModuleLogs(moduleId string, logs []*v1.LogEntry) {
+	c.sendMessage(&v1.WebSocketServerMessage{
+		Type: v1.WSMessageType_WS_MESSAGE_TYPE_LOGS,
+		Payload: &v1.WebSocketServerMessage_Logs{
+			Logs: &v1.LogsMessage{
+				ModuleId: moduleId,
+				Logs:     logs,
+			},
+		},
+	})
+}
+
+func (c *Client) sendModuleSubscribed(moduleId string) {
+	c.sendMessage(&v1.WebSocketServerMessage{
+		Type: v1.WSMessageType_WS_MESSAGE_TYPE_SUBSCRIBED,
+		Payload: &v1.WebSocketServerMessage_Subscribed{
+			Subscribed: &v1.SubscribedMessage{
+				ModuleId: moduleId,
+			},
+		},
+	})
+}
@@ -4,15 +4,19 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/mholt/archives"
 )
@@ -119,6 +123,86 @@ func SanitizePathName(name string) string {
 	return safe
 }
 
+// Longest name prefix kept in a server directory name, the ID suffix keeps it unique
+const maxDirNamePrefix = 48
+
+// ServerDirName is the data directory name of a server. The "id" naming uses the ID alone,
+// otherwise the sanitized name goes in front, trimmed of separators and capped in length.
+func ServerDirName(naming, name, id string) string {
+	if naming == "id" {
+		return id
+	}
+	prefix := strings.Trim(SanitizePathName(name), "_-")
+	if len(prefix) > maxDirNamePrefix {
+		prefix = strings.TrimRight(prefix[:maxDirNamePrefix], "_-")
+	}
+	if prefix == "" {
+		return id
+	}
+	return prefix + "_" + id
+}
+
+// ErrPathOutsideRoot is returned when a path resolves outside the directory it was joined to
+var ErrPathOutsideRoot = errors.New("path is outside the root directory")
+
+// ErrNoRoot is returned when there is no root directory to resolve against
+var ErrNoRoot = errors.New("no root directory")
+
+// ResolvePath joins a client supplied relative path onto root, refusing anything
+// that leaves root through ".." or a symlink. Components that don't exist yet are
+// allowed so the result can be used to create files.
+func ResolvePath(root, rel string) (string, error) {
+	// An empty root would resolve against the working directory
+	if root == "" {
+		return "", ErrNoRoot
+	}
+	root = filepath.Clean(root)
+	fullPath := filepath.Join(root, rel)
+	if !isWithin(root, fullPath) {
+		return "", ErrPathOutsideRoot
+	}
+
+	// Compare real locations, the root itself may sit behind a link
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return "", err
+	}
+
+	// Resolve the deepest existing component, anything below it can't be a link yet
+	existing := filepath.Join(absRoot, rel)
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !isWithin(realRoot, resolved) {
+				return "", ErrPathOutsideRoot
+			}
+			return fullPath, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		// A dangling link would be followed on write
+		if info, err := os.Lstat(existing); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", ErrPathOutsideRoot
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fullPath, nil
+		}
+		existing = parent
+	}
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // Extract archive to destPath
 func ExtractArchive(ctx context.Context, archivePath string, destPath string, counter *atomic.Int32) (int, error) {
 	archiveFile, err := os.Open(archivePath)
@@ -217,19 +301,27 @@ func IsTextFile(path string) bool {
 		return false
 	}
 
-	if n == 0 {
+	return IsTextContent(buffer[:n])
+}
+
+// IsTextContent reports whether data looks like text, judging by its first 512 bytes
+func IsTextContent(data []byte) bool {
+	if len(data) > 512 {
+		data = data[:512]
+	}
+
+	if len(data) == 0 {
 		// Empty files are considered text
 		return true
 	}
 
 	// Check for null bytes (binary indicator)
-	if bytes.Contains(buffer[:n], []byte{0}) {
+	if bytes.Contains(data, []byte{0}) {
 		return false
 	}
 
 	// Check if it's valid UTF-8 with printable characters
-	for i := range n {
-		b := buffer[i]
+	for _, b := range data {
 		// Allow printable ASCII, tabs, newlines, carriage returns
 		if b < 32 && b != 9 && b != 10 && b != 13 {
 			return false
@@ -272,12 +364,26 @@ func zipMethod(name string) uint16 {
 	return zip.Deflate
 }
 
+// ZipFilter reports whether a path (relative to the archive base) should be left out.
+// Skipping a directory skips everything beneath it.
+type ZipFilter func(rel string, isDir bool) bool
+
 // CreateZipToWriter writes a zip archive of the given paths to the writer.
 // basePath is the root directory used to calculate relative paths in the archive.
 // When compress is false all entries are stored uncompressed.
+// An optional filter excludes matching files and directories found beneath the given paths.
 // Returns the number of files archived.
-func CreateZipToWriter(paths []string, basePath string, w io.Writer, compress bool) (int, error) {
+func CreateZipToWriter(paths []string, basePath string, w io.Writer, compress bool, filter ...ZipFilter) (int, error) {
 	zw := zip.NewWriter(w)
+
+	skip := func(rel string, isDir bool) bool {
+		for _, f := range filter {
+			if f != nil && f(filepath.ToSlash(rel), isDir) {
+				return true
+			}
+		}
+		return false
+	}
 	defer zw.Close()
 
 	method := func(name string) uint16 {
@@ -301,7 +407,17 @@ func CreateZipToWriter(paths []string, basePath string, w io.Writer, compress bo
 					return err
 				}
 				rel, _ := filepath.Rel(basePath, path)
+				// Requested paths themselves are never filtered, only their contents
+				if path != fullPath && skip(rel, d.IsDir()) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
 				if d.IsDir() {
+					if rel == "." {
+						return nil
+					}
 					// Add directory entry with trailing slash
 					_, err := zw.Create(rel + "/")
 					return err
@@ -358,15 +474,136 @@ func CreateZipToWriter(paths []string, basePath string, w io.Writer, compress bo
 	return count, nil
 }
 
+// ListZipTopLevel returns the distinct top-level names (files or directories) in a zip archive.
+func ListZipTopLevel(archivePath string) ([]string, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, f := range zr.File {
+		top, _, _ := strings.Cut(strings.TrimPrefix(filepath.ToSlash(f.Name), "/"), "/")
+		if top == "" || top == "." || top == ".." || seen[top] {
+			continue
+		}
+		seen[top] = true
+		names = append(names, top)
+	}
+	return names, nil
+}
+
+// FindZipWorld returns the directory of the shallowest level.dat in a zip archive, "" when
+// the world sits at the archive root.
+func FindZipWorld(archivePath string) (string, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	world, depth := "", -1
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(filepath.ToSlash(f.Name), "/")
+		if f.FileInfo().IsDir() || path.Base(name) != "level.dat" {
+			continue
+		}
+		// level.dat at the root has depth zero
+		if d := strings.Count(name, "/"); depth < 0 || d < depth {
+			world, depth = path.Dir(name), d
+		}
+	}
+	if depth < 0 {
+		return "", errors.New("archive contains no level.dat")
+	}
+	if world == "." {
+		return "", nil
+	}
+	return world, nil
+}
+
+// ExtractZipDir extracts the entries under prefix of a zip archive into destPath, with
+// prefix stripped from their names. Entries that would land outside destPath and links
+// are refused. Returns the number of files extracted.
+func ExtractZipDir(ctx context.Context, archivePath, prefix, destPath string) (int, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	if prefix != "" {
+		prefix = strings.TrimSuffix(prefix, "/") + "/"
+	}
+
+	count := 0
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(f.Name), "/")
+		rel, ok := strings.CutPrefix(name, prefix)
+		if !ok || rel == "" {
+			continue
+		}
+		target := filepath.Join(destPath, filepath.FromSlash(rel))
+		if !isWithin(destPath, target) {
+			return count, fmt.Errorf("illegal file path in archive: %s", f.Name)
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			return count, fmt.Errorf("archive contains a link: %s", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return count, err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return count, fmt.Errorf("failed to create parent directory: %w", err)
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open file in archive: %w", err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", target, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, rc); err != nil {
+		return fmt.Errorf("failed to extract file %s: %w", target, err)
+	}
+	return nil
+}
+
 // CreateZipArchive creates a zip archive file on disk from the given paths.
-func CreateZipArchive(paths []string, basePath string, destPath string, compress bool) (int, error) {
+func CreateZipArchive(paths []string, basePath string, destPath string, compress bool, filter ...ZipFilter) (int, error) {
 	f, err := os.Create(destPath)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create archive file: %w", err)
 	}
 	defer f.Close()
 
-	count, err := CreateZipToWriter(paths, basePath, f, compress)
+	count, err := CreateZipToWriter(paths, basePath, f, compress, filter...)
 	if err != nil {
 		os.Remove(destPath)
 		return 0, err
@@ -398,6 +635,71 @@ func CopyDir(src, dst string) error {
 	})
 }
 
+// ChownLike gives path the owner of ref, a no-op unless running as root
+func ChownLike(path, ref string) error {
+	info, err := os.Lstat(ref)
+	if err != nil {
+		return err
+	}
+	return copyOwner(info, path)
+}
+
+// MoveDir moves a directory tree to dst, which must not exist yet. Across filesystems it
+// copies modes, symlinks and owners, and only removes src once the copy is complete.
+func MoveDir(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("destination %s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create destination parent: %w", err)
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := CopyFile(path, target); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			return nil // Sockets, pipes and devices don't survive a move
+		}
+		return copyOwner(info, target)
+	})
+	if err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return os.RemoveAll(src)
+}
+
 func CopyFile(src, dst string) error {
 	// Prevent copying a file onto itself — os.Create truncates before io.Copy reads.
 	if filepath.Clean(src) == filepath.Clean(dst) {
@@ -423,3 +725,77 @@ func CopyFile(src, dst string) error {
 
 	return dstFile.Sync()
 }
+
+// Verifies that a file is a readable jar archive with a manifest
+func ValidateJarFile(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("not a valid jar archive: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if strings.EqualFold(f.Name, "META-INF/MANIFEST.MF") {
+			return nil
+		}
+	}
+	return fmt.Errorf("jar archive is missing META-INF/MANIFEST.MF")
+}
+
+// Directory under the server data path where JVM dumps are written
+const CrashDumpDir = "dumps"
+
+// Kinds of JVM diagnostics files
+const (
+	CrashDumpTypeErrorLog = "hs_err"
+	CrashDumpTypeHeapDump = "heap_dump"
+)
+
+// CrashDump describes a JVM fatal error log or heap dump in a server data dir
+type CrashDump struct {
+	Path     string // Relative to the data dir
+	Type     string
+	Size     int64
+	Modified time.Time
+}
+
+// Finds hs_err_pid*.log and *.hprof files in the data dir root and dumps dir, newest first
+func FindCrashDumps(dataDir string) ([]CrashDump, error) {
+	var dumps []CrashDump
+	for _, dir := range []string{"", CrashDumpDir} {
+		entries, err := os.ReadDir(filepath.Join(dataDir, dir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			var dumpType string
+			switch {
+			case strings.HasPrefix(name, "hs_err_pid") && strings.HasSuffix(name, ".log"):
+				dumpType = CrashDumpTypeErrorLog
+			case strings.HasSuffix(name, ".hprof"):
+				dumpType = CrashDumpTypeHeapDump
+			default:
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			dumps = append(dumps, CrashDump{
+				Path:     filepath.ToSlash(filepath.Join(dir, name)),
+				Type:     dumpType,
+				Size:     info.Size(),
+				Modified: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].Modified.After(dumps[j].Modified) })
+	return dumps, nil
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package files
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Gives dst the owner of the file src was read from. Only root may change owners, for
+// anyone else it is a no-op and the copy keeps the caller as owner.
+func copyOwner(info fs.FileInfo, dst string) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || os.Geteuid() != 0 {
+		return nil
+	}
+	return os.Lchown(dst, int(stat.Uid), int(stat.Gid))
+}
+
+// ChownTree gives everything under root the uid and gid, a no-op unless running as root
+func ChownTree(root string, uid, gid int) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
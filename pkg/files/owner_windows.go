@@ -0,0 +1,15 @@
+//go:build windows
+
+package files
+
+import "io/fs"
+
+// Windows has no uid/gid to carry over
+func copyOwner(info fs.FileInfo, dst string) error {
+	return nil
+}
+
+// ChownTree is a no-op, Windows has no uid/gid to set
+func ChownTree(root string, uid, gid int) error {
+	return nil
+}
@@ -0,0 +1,134 @@
+package files
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTar extracts a tar stream into destPath, dropping the first strip components of
+// each entry's name. Entries that would land outside destPath are refused and links are
+// skipped. Returns the number of files extracted.
+func ExtractTar(r io.Reader, destPath string, strip int) (int, error) {
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	count := 0
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		parts := strings.Split(strings.Trim(path.Clean("/"+hdr.Name), "/"), "/")
+		if len(parts) <= strip {
+			continue
+		}
+		target := filepath.Join(destPath, filepath.FromSlash(strings.Join(parts[strip:], "/")))
+		if !isWithin(destPath, target) {
+			return count, fmt.Errorf("illegal file path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return count, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return count, fmt.Errorf("failed to create parent directory: %w", err)
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(hdr.Mode)&0777|0600)
+			if err != nil {
+				return count, fmt.Errorf("failed to create file %s: %w", target, err)
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return count, fmt.Errorf("failed to extract file %s: %w", target, err)
+			}
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+			count++
+		}
+	}
+}
+
+// WriteTar writes the tree under srcPath to w as a tar stream with its entries under
+// prefix, owned by uid and gid. The prefix directories get entries of their own, so the
+// stream can be extracted where they don't exist yet. Links are left out.
+func WriteTar(w io.Writer, srcPath, prefix string, uid, gid int) error {
+	tw := tar.NewWriter(w)
+	prefix = strings.Trim(path.Clean("/"+filepath.ToSlash(prefix)), "/")
+
+	if prefix != "" {
+		parts := strings.Split(prefix, "/")
+		for i := range parts {
+			hdr := &tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     strings.Join(parts[:i+1], "/") + "/",
+				Mode:     0755,
+				Uid:      uid,
+				Gid:      gid,
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := filepath.WalkDir(srcPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcPath, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid = uid, gid
+		hdr.Uname, hdr.Gname = "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -34,6 +34,7 @@ type LogStreamer struct {
 	maxEntries  int
 	subscribers map[string]map[chan *v1.LogEntry]bool // containerID -> set of subscriber channels
 	subMu       sync.RWMutex                          // mutex for subscribers
+	lineHooks   []func(containerID, line string)      // called for every streamed log line
 }
 
 // NewLogStreamer creates a new log streamer
@@ -50,6 +51,14 @@ func NewLogStreamer(dockerClient *client.Client, log *Logger, maxEntriesPerConta
 	}
 }
 
+// OnLine registers a hook that sees every container log line as it streams
+// NOTE: Hooks run on the streaming goroutine and must return promptly!
+func (ls *LogStreamer) OnLine(hook func(containerID, line string)) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.lineHooks = append(ls.lineHooks, hook)
+}
+
 // StartStreaming starts streaming logs for a container
 func (ls *LogStreamer) StartStreaming(containerID string) error {
 	ls.mu.Lock()
@@ -138,6 +147,10 @@ func (ls *LogStreamer) streamLogs(ctx context.Context, stream *ContainerLogStrea
 		logReader = reader
 	}
 
+	ls.mu.RLock()
+	hooks := ls.lineHooks
+	ls.mu.RUnlock()
+
 	scanner := bufio.NewScanner(logReader)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for long lines
 
@@ -159,6 +172,10 @@ func (ls *LogStreamer) streamLogs(ctx context.Context, stream *ContainerLogStrea
 			}
 
 			if line != "" {
+				for _, hook := range hooks {
+					hook(stream.containerID, line)
+				}
+
 				entry := &v1.LogEntry{
 					Timestamp: timestamppb.New(time.Now()),
 					Message:   line,
@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// The SHA-1 seed of RFC 6238 appendix B, "12345678901234567890", base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B, SHA-1 rows. The RFC lists 8 digit codes, 6 digit codes are their last six.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		code, err := Code(rfc6238Secret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", v.unix, err)
+		}
+		if code != v.code {
+			t.Errorf("Code at %d = %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestValidateRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		at := time.Unix(v.unix, 0)
+		step, ok, err := Validate(rfc6238Secret, v.code, at)
+		if err != nil || !ok {
+			t.Fatalf("Validate at %d = %v, %v", v.unix, ok, err)
+		}
+		if step != Step(at) {
+			t.Errorf("Validate at %d matched step %d, want %d", v.unix, step, Step(at))
+		}
+	}
+
+	// Lowercase secrets and spaced codes are what people type in
+	if _, ok, _ := Validate("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287 082", time.Unix(59, 0)); !ok {
+		t.Error("Validate rejected a lowercase secret and a spaced code")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	now := Step(at)
+
+	for offset := int64(-1); offset <= 1; offset++ {
+		code, _ := Code(rfc6238Secret, now+offset)
+		step, ok, err := Validate(rfc6238Secret, code, at)
+		if err != nil || !ok || step != now+offset {
+			t.Errorf("code for step %+d: Validate = %d, %v, %v, want %d, true", offset, step, ok, err, now+offset)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		code, _ := Code(rfc6238Secret, now+offset)
+		if _, ok, _ := Validate(rfc6238Secret, code, at); ok {
+			t.Errorf("code for step %+d accepted outside the skew", offset)
+		}
+	}
+}
+
+func TestValidateRejectsMalformed(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082", "abcdef"} {
+		if _, ok, _ := Validate(rfc6238Secret, code, at); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+	if _, _, err := Validate("not base32!", "287082", at); err == nil {
+		t.Error("Validate accepted an invalid secret")
+	}
+}
@@ -0,0 +1,115 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers accepted for credentials, in order of preference
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms is advertised as pubKeyCredParams when registering
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE_Key map labels (RFC 9052)
+const (
+	coseKty = 1
+	coseAlg = 3
+	coseCrv = -1 // Also the RSA modulus
+	coseX   = -2 // Also the RSA exponent
+	coseY   = -3
+)
+
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// Parses a COSE_Key as stored in a credential
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	item, _, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := item.(map[any]any)
+	if !ok {
+		return nil, errors.New("public key is not a COSE map")
+	}
+
+	alg, _ := m[int64(coseAlg)].(int64)
+	kty, _ := m[int64(coseKty)].(int64)
+	switch {
+	case kty == 2 && alg == AlgES256:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		// ecdh rejects points that aren't on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid P-256 public key: %w", err)
+		}
+		return &publicKey{alg: alg, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case kty == 1 && alg == AlgEdDSA:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+
+	case kty == 3 && alg == AlgRS256:
+		n, _ := m[int64(coseCrv)].([]byte)
+		e, _ := m[int64(coseX)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA public key")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %d with algorithm %d", kty, alg)
+}
+
+// Checks sig over data with the key's algorithm
+func (k *publicKey) verify(data, sig []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return ErrInvalidSignature
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return ErrInvalidSignature
+		}
+	default:
+		return errors.New("unsupported public key")
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package webauthn verifies passkey registrations and assertions for a single
+// relying party. Attestation statements are not verified: credentials are
+// trusted on first use, the same as requesting "none" attestation.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrChallengeMismatch = errors.New("challenge mismatch")
+	ErrOriginMismatch    = errors.New("origin not allowed")
+	ErrRPIDMismatch      = errors.New("relying party id mismatch")
+	ErrUserNotPresent    = errors.New("user presence not asserted")
+	ErrUserNotVerified   = errors.New("user verification required")
+	ErrCloned            = errors.New("signature counter went backwards, credential may be cloned")
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// Size of the fixed authenticator data header: rpIdHash, flags and signCount
+const authDataHeaderSize = 37
+
+// RelyingParty is the site passkeys are registered with
+type RelyingParty struct {
+	ID                      string   // Domain passkeys are scoped to, e.g. panel.example.com
+	Name                    string   // Shown by the authenticator
+	Origins                 []string // Page origins allowed to use them, e.g. https://panel.example.com
+	RequireUserVerification bool     // Require PIN/biometric, not just a touch
+}
+
+// Credential is a registered passkey
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+	AAGUID    []byte // Authenticator model, all zero when not disclosed
+}
+
+// NewChallenge returns a random challenge for a registration or login ceremony
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	aaguid    []byte
+	credID    []byte
+	publicKey []byte
+}
+
+// VerifyRegistration checks the response to navigator.credentials.create and returns the new credential
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	item, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	attestation, ok := item.(map[any]any)
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object has no authenticator data")
+	}
+
+	authData, err := rp.verifyAuthData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.credID == nil {
+		return nil, errors.New("authenticator data has no credential")
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.credID,
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+		AAGUID:    authData.aaguid,
+	}, nil
+}
+
+// VerifyAssertion checks the response to navigator.credentials.get against a stored
+// credential and returns the authenticator's new signature counter
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, cred *Credential, clientDataJSON, rawAuthData, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.verifyAuthData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := key.verify(signed, signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators that keep a counter must increase it on every use
+	if (authData.signCount != 0 || cred.SignCount != 0) && authData.signCount <= cred.SignCount {
+		return 0, ErrCloned
+	}
+	return authData.signCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid client data: %w", err)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("unexpected client data type %q", data.Type)
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return ErrOriginMismatch
+	}
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthData(raw []byte) (*authenticatorData, error) {
+	authData, err := parseAuthData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return nil, ErrRPIDMismatch
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+	if rp.RequireUserVerification && authData.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+	return authData, nil
+}
+
+func parseAuthData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < authDataHeaderSize {
+		return nil, errors.New("authenticator data too short")
+	}
+	authData := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if authData.flags&flagAttested == 0 {
+		return authData, nil
+	}
+
+	// Attested credential data: aaguid, credential ID length and ID, then the COSE key
+	rest := raw[authDataHeaderSize:]
+	if len(rest) < 18 {
+		return nil, errors.New("attested credential data too short")
+	}
+	authData.aaguid = rest[:16]
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || len(rest) < idLen {
+		return nil, errors.New("invalid credential id")
+	}
+	authData.credID = rest[:idLen]
+	rest = rest[idLen:]
+
+	_, keyLen, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	authData.publicKey = rest[:keyLen]
+	return authData, nil
+}
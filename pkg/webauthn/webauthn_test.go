@@ -0,0 +1,341 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var testRP = &RelyingParty{
+	ID:      "panel.example.com",
+	Name:    "DiscoPanel",
+	Origins: []string{"https://panel.example.com"},
+}
+
+var (
+	testChallenge = bytes.Repeat([]byte{0xc7}, 32)
+	testCredID    = []byte("test-credential-id")
+	testAAGUID    = []byte("0123456789abcdef")
+)
+
+// Encodes the CBOR subset authenticators use: ints, byte and text strings, arrays and maps.
+// Map entries are given as key, value pairs to keep their order.
+func encodeCBOR(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		}
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case []any:
+		out := head(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborMap:
+		out := head(5, uint64(len(v)/2))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("encodeCBOR: unsupported %T", v))
+}
+
+type cborMap []any
+
+// A software authenticator holding one credential
+type testAuthenticator struct {
+	cose []byte
+	sign func(data []byte) []byte
+}
+
+// Ed25519 signatures are deterministic, so this authenticator always produces the same bytes
+func newEd25519Authenticator() *testAuthenticator {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+	return &testAuthenticator{
+		cose: encodeCBOR(cborMap{coseKty, 1, coseAlg, AlgEdDSA, coseCrv, 6, coseX, []byte(key.Public().(ed25519.PublicKey))}),
+		sign: func(data []byte) []byte { return ed25519.Sign(key, data) },
+	}
+}
+
+func newES256Authenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return &testAuthenticator{
+		cose: encodeCBOR(cborMap{coseKty, 2, coseAlg, AlgES256, coseCrv, 1, coseX, x, coseY, y}),
+		sign: func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+			if err != nil {
+				t.Fatalf("SignASN1: %v", err)
+			}
+			return sig
+		},
+	}
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) []byte {
+	raw, _ := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return raw
+}
+
+func authDataHeader(rpID string, flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	return binary.BigEndian.AppendUint32(append(rpIDHash[:], flags), signCount)
+}
+
+func (a *testAuthenticator) registrationAuthData(rpID string, flags byte) []byte {
+	authData := authDataHeader(rpID, flags|flagAttested, 0)
+	authData = append(authData, testAAGUID...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(testCredID)))
+	authData = append(authData, testCredID...)
+	return append(authData, a.cose...)
+}
+
+func attestationObject(authData []byte) []byte {
+	return encodeCBOR(cborMap{"fmt", "none", "attStmt", cborMap{}, "authData", authData})
+}
+
+func (a *testAuthenticator) credential(signCount uint32) *Credential {
+	return &Credential{ID: testCredID, PublicKey: a.cose, SignCount: signCount, AAGUID: testAAGUID}
+}
+
+// Signs an assertion over authData and the client data, as navigator.credentials.get returns it
+func (a *testAuthenticator) assert(authData, clientData []byte) []byte {
+	clientDataHash := sha256.Sum256(clientData)
+	return a.sign(append(append([]byte(nil), authData...), clientDataHash[:]...))
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	for name, authenticator := range map[string]*testAuthenticator{
+		"ES256": newES256Authenticator(t),
+		"EdDSA": newEd25519Authenticator(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			clientData := clientDataJSON("webauthn.create", testChallenge, "https://panel.example.com")
+			authData := authenticator.registrationAuthData(testRP.ID, flagUserPresent|flagUserVerified)
+
+			cred, err := testRP.VerifyRegistration(testChallenge, clientData, attestationObject(authData))
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if !bytes.Equal(cred.ID, testCredID) || !bytes.Equal(cred.PublicKey, authenticator.cose) || !bytes.Equal(cred.AAGUID, testAAGUID) || cred.SignCount != 0 {
+				t.Errorf("credential = %+v", cred)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	for name, authenticator := range map[string]*testAuthenticator{
+		"ES256": newES256Authenticator(t),
+		"EdDSA": newEd25519Authenticator(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			clientData := clientDataJSON("webauthn.get", testChallenge, "https://panel.example.com")
+			authData := authDataHeader(testRP.ID, flagUserPresent, 8)
+
+			count, err := testRP.VerifyAssertion(testChallenge, authenticator.credential(7), clientData, authData, authenticator.assert(authData, clientData))
+			if err != nil {
+				t.Fatalf("VerifyAssertion: %v", err)
+			}
+			if count != 8 {
+				t.Errorf("sign count = %d, want 8", count)
+			}
+
+			// Authenticators without a counter always report zero
+			authData = authDataHeader(testRP.ID, flagUserPresent, 0)
+			if _, err := testRP.VerifyAssertion(testChallenge, authenticator.credential(0), clientData, authData, authenticator.assert(authData, clientData)); err != nil {
+				t.Errorf("VerifyAssertion without a counter: %v", err)
+			}
+		})
+	}
+}
+
+// A fixed assertion by the Ed25519 authenticator, whose signatures are deterministic
+func TestEd25519AssertionVector(t *testing.T) {
+	authenticator := newEd25519Authenticator()
+	clientData := []byte(`{"challenge":"x8fHx8fHx8fHx8fHx8fHx8fHx8fHx8fHx8fHx8fHx8c","crossOrigin":false,"origin":"https://panel.example.com","type":"webauthn.get"}`)
+	authData := authDataHeader(testRP.ID, flagUserPresent, 1)
+	signature, _ := hex.DecodeString("e376a7111c850fb6957127a26f3621d8079ada0288675b2e258c54fb1c788c8c74170439a04e831c9d30be2ba7365375a246cf69b3020854af8ac9f2e6efef03")
+	tampered := append([]byte(nil), signature...)
+	tampered[0] ^= 0x01
+
+	if _, err := testRP.VerifyAssertion(testChallenge, authenticator.credential(0), clientData, authData, signature); err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if _, err := testRP.VerifyAssertion(testChallenge, authenticator.credential(0), clientData, authData, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered signature: err = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+// RFC 8032 section 7.1, test 1, checked through a COSE key as a credential stores it
+func TestEd25519RFC8032Vector(t *testing.T) {
+	publicKey, _ := hex.DecodeString("d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+	signature, _ := hex.DecodeString("e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b")
+
+	key, err := parsePublicKey(encodeCBOR(cborMap{coseKty, 1, coseAlg, AlgEdDSA, coseCrv, 6, coseX, publicKey}))
+	if err != nil {
+		t.Fatalf("parsePublicKey: %v", err)
+	}
+	if err := key.verify(nil, signature); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	if err := key.verify([]byte{0x00}, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verify of another message: err = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	authenticator := newES256Authenticator(t)
+	goodClientData := clientDataJSON("webauthn.create", testChallenge, "https://panel.example.com")
+	goodAuthData := authenticator.registrationAuthData(testRP.ID, flagUserPresent)
+
+	tests := []struct {
+		name       string
+		rp         *RelyingParty
+		clientData []byte
+		authData   []byte
+		want       error
+	}{
+		{"rpIdHash of another site", testRP, goodClientData, authenticator.registrationAuthData("evil.example.com", flagUserPresent), ErrRPIDMismatch},
+		{"origin of another site", testRP, clientDataJSON("webauthn.create", testChallenge, "https://evil.example.com"), goodAuthData, ErrOriginMismatch},
+		{"origin over http", testRP, clientDataJSON("webauthn.create", testChallenge, "http://panel.example.com"), goodAuthData, ErrOriginMismatch},
+		{"other challenge", testRP, clientDataJSON("webauthn.create", bytes.Repeat([]byte{0x01}, 32), "https://panel.example.com"), goodAuthData, ErrChallengeMismatch},
+		{"user not present", testRP, goodClientData, authenticator.registrationAuthData(testRP.ID, 0), ErrUserNotPresent},
+		{"user not verified", &RelyingParty{ID: testRP.ID, Origins: testRP.Origins, RequireUserVerification: true}, goodClientData, goodAuthData, ErrUserNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.rp.VerifyRegistration(testChallenge, tt.clientData, attestationObject(tt.authData)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("assertion client data", func(t *testing.T) {
+		clientData := clientDataJSON("webauthn.get", testChallenge, "https://panel.example.com")
+		if _, err := testRP.VerifyRegistration(testChallenge, clientData, attestationObject(goodAuthData)); err == nil {
+			t.Error("registration accepted webauthn.get client data")
+		}
+	})
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	authenticator := newES256Authenticator(t)
+	goodClientData := clientDataJSON("webauthn.get", testChallenge, "https://panel.example.com")
+
+	tests := []struct {
+		name       string
+		clientData []byte
+		authData   []byte
+		storedSign uint32
+		want       error
+	}{
+		{"rpIdHash of another site", goodClientData, authDataHeader("evil.example.com", flagUserPresent, 8), 7, ErrRPIDMismatch},
+		{"origin of another site", clientDataJSON("webauthn.get", testChallenge, "https://evil.example.com"), authDataHeader(testRP.ID, flagUserPresent, 8), 7, ErrOriginMismatch},
+		{"other challenge", clientDataJSON("webauthn.get", bytes.Repeat([]byte{0x01}, 32), "https://panel.example.com"), authDataHeader(testRP.ID, flagUserPresent, 8), 7, ErrChallengeMismatch},
+		{"user not present", goodClientData, authDataHeader(testRP.ID, flagUserVerified, 8), 7, ErrUserNotPresent},
+		{"counter went backwards", goodClientData, authDataHeader(testRP.ID, flagUserPresent, 5), 7, ErrCloned},
+		{"counter didn't move", goodClientData, authDataHeader(testRP.ID, flagUserPresent, 7), 7, ErrCloned},
+		{"counter reset to zero", goodClientData, authDataHeader(testRP.ID, flagUserPresent, 0), 7, ErrCloned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature := authenticator.assert(tt.authData, tt.clientData)
+			if _, err := testRP.VerifyAssertion(testChallenge, authenticator.credential(tt.storedSign), tt.clientData, tt.authData, signature); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("signature by another key", func(t *testing.T) {
+		authData := authDataHeader(testRP.ID, flagUserPresent, 8)
+		signature := newES256Authenticator(t).assert(authData, goodClientData)
+		if _, err := testRP.VerifyAssertion(testChallenge, authenticator.credential(7), goodClientData, authData, signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("err = %v, want %v", err, ErrInvalidSignature)
+		}
+	})
+}
+
+func TestDecodeCBORRejectsDeepNesting(t *testing.T) {
+	nested := func(depth int) []byte {
+		return append(bytes.Repeat([]byte{0x81}, depth), 0x00) // [[[...0...]]]
+	}
+
+	if _, _, err := decodeCBOR(nested(maxCBORDepth)); err != nil {
+		t.Fatalf("decodeCBOR at the nesting limit: %v", err)
+	}
+	for _, depth := range []int{maxCBORDepth + 1, 100000} {
+		if _, _, err := decodeCBOR(nested(depth)); err == nil {
+			t.Errorf("decodeCBOR accepted %d nested arrays", depth)
+		}
+	}
+
+	// Maps and tags count towards the limit too
+	deepMap := append(bytes.Repeat([]byte{0xa1, 0x00}, maxCBORDepth+1), 0x00)
+	if _, _, err := decodeCBOR(deepMap); err == nil {
+		t.Error("decodeCBOR accepted deeply nested maps")
+	}
+	deepTags := append(bytes.Repeat([]byte{0xc0}, maxCBORDepth+1), 0x00)
+	if _, _, err := decodeCBOR(deepTags); err == nil {
+		t.Error("decodeCBOR accepted deeply nested tags")
+	}
+
+	clientData := clientDataJSON("webauthn.create", testChallenge, "https://panel.example.com")
+	if _, err := testRP.VerifyRegistration(testChallenge, clientData, nested(100000)); err == nil {
+		t.Error("VerifyRegistration accepted a deeply nested attestation object")
+	}
+}
+
+func TestDecodeCBORRejectsBadLengths(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"byte string past the end": {0x5a, 0xff, 0xff, 0xff, 0xff, 0x00},
+		"huge array":               {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"huge map":                 {0xbb, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite array":         {0x9f, 0x00, 0xff},
+		"truncated argument":       {0x19, 0x01},
+		"empty":                    {},
+	} {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("%s: decodeCBOR accepted %x", name, data)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import {
 	Code
 } from '@connectrpc/connect';
 import { createConnectTransport } from '@connectrpc/connect-web';
+import { base } from '$app/paths';
 import { authStore } from '$lib/stores/auth';
 import { toast } from 'svelte-sonner';
 import { loadingStore } from '$lib/stores/loading.svelte';
@@ -58,7 +59,7 @@ const authInterceptor: Interceptor = (next) => async (req) => {
 		const res = await next(req);
 		return res;
 	} catch (error) {
-		const onLoginPage = typeof window !== 'undefined' && window.location.pathname === '/login';
+		const onLoginPage = typeof window !== 'undefined' && window.location.pathname === `${base}/login`;
 
 		// Log out on expired/invalid session
 		if (error instanceof ConnectError && error.code === Code.Unauthenticated) {
@@ -86,7 +87,7 @@ const authInterceptor: Interceptor = (next) => async (req) => {
 
 // Transport w/ auth
 const transport = createConnectTransport({
-	baseUrl: '',
+	baseUrl: base,
 	interceptors: [authInterceptor]
 });
 
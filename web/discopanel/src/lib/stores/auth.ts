@@ -11,6 +11,7 @@ import {
 	ChangePasswordRequestSchema,
 	UseRecoveryKeyRequestSchema
 } from '$lib/proto/discopanel/v1/auth_pb';
+import { signInWithPasskey } from '$lib/utils/passkey';
 import * as _ from 'lodash-es';
 
 interface AuthState {
@@ -24,6 +25,7 @@ interface AuthState {
 	firstUserSetup: boolean;
 	allowRegistration: boolean;
 	anonymousAccessEnabled: boolean;
+	passkeyEnabled: boolean;
 }
 
 /** Check if a permission list grants access for the given resource/action/objectId. */
@@ -52,7 +54,8 @@ function createAuthStore() {
 		oidcEnabled: false,
 		firstUserSetup: false,
 		allowRegistration: false,
-		anonymousAccessEnabled: false
+		anonymousAccessEnabled: false,
+		passkeyEnabled: false
 	});
 
 	// Load token from localStorage on init
@@ -78,7 +81,8 @@ function createAuthStore() {
 					oidcEnabled: response.oidcEnabled,
 					firstUserSetup: response.firstUserSetup,
 					allowRegistration: response.allowRegistration,
-					anonymousAccessEnabled: response.anonymousAccessEnabled
+					anonymousAccessEnabled: response.anonymousAccessEnabled,
+					passkeyEnabled: response.passkeyEnabled
 				}));
 
 				// If auth is enabled and we have a token, validate it
@@ -130,9 +134,9 @@ function createAuthStore() {
 			}
 		},
 
-		async login(username: string, password: string) {
+		async login(username: string, password: string, totpCode = '') {
 			try {
-				const request = create(LoginRequestSchema, { username, password });
+				const request = create(LoginRequestSchema, { username, password, totpCode });
 				const response = await rpcClient.auth.login(request);
 
 				// Store token
@@ -158,12 +162,39 @@ function createAuthStore() {
 			}
 		},
 
+		async loginWithPasskey(username?: string) {
+			try {
+				const response = await signInWithPasskey(username);
+
+				if (browser && response.token) {
+					localStorage.setItem('auth_token', response.token);
+				}
+
+				update((state) => ({
+					...state,
+					user: response.user || null,
+					token: response.token,
+					isAuthenticated: true,
+					isLoading: false
+				}));
+
+				await this.validateSession();
+
+				return response;
+			} catch (error) {
+				update((state) => ({ ...state, isLoading: false }));
+				throw error;
+			}
+		},
+
 		async logout() {
 			const currentState: AuthState = get({ subscribe });
+			let providerLogoutUrl = '';
 
 			try {
 				if (currentState.token) {
-					await rpcClient.auth.logout({});
+					const response = await rpcClient.auth.logout({});
+					providerLogoutUrl = response.logoutUrl;
 				}
 			} catch (error) {
 				console.error('Logout error:', error);
@@ -185,9 +216,16 @@ function createAuthStore() {
 				oidcEnabled: currentState.oidcEnabled,
 				firstUserSetup: currentState.firstUserSetup,
 				allowRegistration: currentState.allowRegistration,
-				anonymousAccessEnabled: currentState.anonymousAccessEnabled
+				anonymousAccessEnabled: currentState.anonymousAccessEnabled,
+				passkeyEnabled: currentState.passkeyEnabled
 			});
 
+			// End the OIDC provider session too, it sends the user back afterwards
+			if (browser && providerLogoutUrl) {
+				window.location.href = providerLogoutUrl;
+				return;
+			}
+
 			// Redirect to login
 			goto(resolve('/login'));
 		},
@@ -1,4 +1,5 @@
 import { browser } from '$app/environment';
+import { base } from '$app/paths';
 import { get } from 'svelte/store';
 import { create, toBinary, fromBinary } from '@bufbuild/protobuf';
 import type { LogEntry } from '$lib/proto/discopanel/v1/server_pb';
@@ -28,6 +29,8 @@ type MessageHandler = (message: WebSocketServerMessage) => void;
 type LogHandler = (serverId: string, logs: LogEntry[]) => void;
 type LogEntryHandler = (serverId: string, logs: LogEntry[]) => void;
 type CommandResultHandler = (result: CommandResultMessage) => void;
+// replace is set for the initial batch sent on subscribe
+type ModuleLogHandler = (moduleId: string, logs: LogEntry[], replace: boolean) => void;
 
 class WebSocketClient {
 	private socket: WebSocket | null = null;
@@ -53,9 +56,11 @@ class WebSocketClient {
 	private logHandlers = new Set<LogHandler>();
 	private logEntryHandlers = new Set<LogEntryHandler>();
 	private commandResultHandlers = new Set<CommandResultHandler>();
+	private moduleLogHandlers = new Set<ModuleLogHandler>();
 
 	// Active subscriptions (serverId -> true)
 	private subscriptions = new Map<string, boolean>();
+	private moduleSubscriptions = new Map<string, boolean>();
 
 	connect(): void {
 		if (!browser) return;
@@ -66,7 +71,7 @@ class WebSocketClient {
 		this.state.error = null;
 
 		const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-		const wsUrl = `${protocol}//${window.location.host}/ws`;
+		const wsUrl = `${protocol}//${window.location.host}${base}/ws`;
 
 		try {
 			this.socket = new WebSocket(wsUrl);
@@ -121,6 +126,7 @@ class WebSocketClient {
 		this.cleanup();
 		this.state.connectionState = 'disconnected';
 		this.subscriptions.clear();
+		this.moduleSubscriptions.clear();
 	}
 
 	private cleanup(): void {
@@ -215,6 +221,12 @@ class WebSocketClient {
 				case WSMessageType.WS_MESSAGE_TYPE_LOGS:
 					if (msg.payload.case === 'logs') {
 						const logsMsg = msg.payload.value as LogsMessage;
+						if (logsMsg.moduleId) {
+							this.moduleLogHandlers.forEach((handler) =>
+								handler(logsMsg.moduleId, logsMsg.logs, true)
+							);
+							break;
+						}
 						this.logHandlers.forEach((handler) => handler(logsMsg.serverId, logsMsg.logs));
 					}
 					break;
@@ -222,7 +234,12 @@ class WebSocketClient {
 				case WSMessageType.WS_MESSAGE_TYPE_LOG:
 					if (msg.payload.case === 'log') {
 						const logMsg = msg.payload.value as LogMessage;
-						if (logMsg.log) {
+						if (logMsg.log && logMsg.moduleId) {
+							const entry = logMsg.log;
+							this.moduleLogHandlers.forEach((handler) =>
+								handler(logMsg.moduleId, [entry], false)
+							);
+						} else if (logMsg.log) {
 							// Buffer log entries for batched dispatch
 							const buffer = this.logBuffer.get(logMsg.serverId) || [];
 							buffer.push(logMsg.log);
@@ -308,6 +325,40 @@ class WebSocketClient {
 		this.send(toBinary(WebSocketClientMessageSchema, msg));
 	}
 
+	subscribeModule(moduleId: string, tail: number = 500): void {
+		this.moduleSubscriptions.set(moduleId, true);
+
+		if (this.state.connectionState !== 'authenticated') {
+			return;
+		}
+
+		const msg = create(WebSocketClientMessageSchema, {
+			type: WSMessageType.WS_MESSAGE_TYPE_SUBSCRIBE,
+			payload: {
+				case: 'subscribe',
+				value: create(SubscribeMessageSchema, { moduleId, tail })
+			}
+		});
+		this.send(toBinary(WebSocketClientMessageSchema, msg));
+	}
+
+	unsubscribeModule(moduleId: string): void {
+		this.moduleSubscriptions.delete(moduleId);
+
+		if (this.state.connectionState !== 'authenticated') {
+			return;
+		}
+
+		const msg = create(WebSocketClientMessageSchema, {
+			type: WSMessageType.WS_MESSAGE_TYPE_UNSUBSCRIBE,
+			payload: {
+				case: 'unsubscribe',
+				value: create(UnsubscribeMessageSchema, { moduleId })
+			}
+		});
+		this.send(toBinary(WebSocketClientMessageSchema, msg));
+	}
+
 	sendCommand(serverId: string, command: string): void {
 		if (this.state.connectionState !== 'authenticated') {
 			return;
@@ -349,6 +400,16 @@ class WebSocketClient {
 			});
 			this.send(toBinary(WebSocketClientMessageSchema, msg));
 		}
+		for (const moduleId of this.moduleSubscriptions.keys()) {
+			const msg = create(WebSocketClientMessageSchema, {
+				type: WSMessageType.WS_MESSAGE_TYPE_SUBSCRIBE,
+				payload: {
+					case: 'subscribe',
+					value: create(SubscribeMessageSchema, { moduleId, tail: 500 })
+				}
+			});
+			this.send(toBinary(WebSocketClientMessageSchema, msg));
+		}
 	}
 
 	// Event handler registration
@@ -367,6 +428,11 @@ class WebSocketClient {
 		return () => this.logEntryHandlers.delete(handler);
 	}
 
+	onModuleLogs(handler: ModuleLogHandler): () => void {
+		this.moduleLogHandlers.add(handler);
+		return () => this.moduleLogHandlers.delete(handler);
+	}
+
 	onCommandResult(handler: CommandResultHandler): () => void {
 		this.commandResultHandlers.add(handler);
 		return () => this.commandResultHandlers.delete(handler);
@@ -1,5 +1,6 @@
 import { rpcClient, silentCallOptions } from '$lib/api/rpc-client';
 import { authStore } from '$lib/stores/auth';
+import { base } from '$app/paths';
 
 export interface UploadProgress {
 	sessionId: string;
@@ -148,7 +149,7 @@ function streamUpload(
 ): Promise<UploadResult> {
 	return new Promise((resolve, reject) => {
 		const xhr = new XMLHttpRequest();
-		xhr.open('PUT', `/api/v1/upload/${sessionId}`);
+		xhr.open('PUT', `${base}/api/v1/upload/${sessionId}`);
 		xhr.setRequestHeader('Content-Type', 'application/octet-stream');
 
 		// Auth headers
@@ -37,6 +37,21 @@ export const SERVER_EVENT_TYPES: EventTypeMeta[] = [
 		type: TriggeredEventType.PLAYER_LEAVE,
 		label: 'Player Leave',
 		description: 'When a player leaves (the player name is available as {{.player}})'
+	},
+	{
+		type: TriggeredEventType.IMAGE_UPDATED,
+		label: 'Image Updated',
+		description: 'When the server is recreated from a newer image (the new digest is available as {{.digest}})'
+	},
+	{
+		type: TriggeredEventType.BACKUP_VERIFY_FAILED,
+		label: 'Backup Verification Failed',
+		description: 'When a verify task finds a damaged backup (the failed archives are available as {{.backups}})'
+	},
+	{
+		type: TriggeredEventType.SERVER_UNHEALTHY_RESTART,
+		label: 'Unhealthy Restart',
+		description: 'When the server is restarted for staying unhealthy (the time it was unhealthy is available as {{.unhealthy_for}})'
 	}
 ];
 
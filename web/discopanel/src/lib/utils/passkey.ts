@@ -0,0 +1,97 @@
+import { create } from '@bufbuild/protobuf';
+import { rpcClient } from '$lib/api/rpc-client';
+import {
+	BeginPasskeyLoginRequestSchema,
+	FinishPasskeyLoginRequestSchema,
+	FinishPasskeyRegistrationRequestSchema,
+	type FinishPasskeyLoginResponse,
+	type Passkey
+} from '$lib/proto/discopanel/v1/auth_pb';
+
+/**
+ * Passkeys need a secure context (HTTPS or localhost) and a WebAuthn capable browser.
+ */
+export function passkeysSupported(): boolean {
+	return typeof window !== 'undefined' && window.isSecureContext && !!window.PublicKeyCredential;
+}
+
+// WebAuthn wants ArrayBuffers, proto bytes arrive as Uint8Arrays
+function buffer(bytes: Uint8Array): ArrayBuffer {
+	return bytes.slice().buffer as ArrayBuffer;
+}
+
+/**
+ * Creates a passkey on this device and registers it with the authenticated account.
+ */
+export async function registerPasskey(name: string): Promise<Passkey | undefined> {
+	const options = await rpcClient.auth.beginPasskeyRegistration({});
+
+	const credential = (await navigator.credentials.create({
+		publicKey: {
+			challenge: buffer(options.challenge),
+			rp: { id: options.rpId, name: options.rpName },
+			user: { id: buffer(options.userId), name: options.userName, displayName: options.userName },
+			pubKeyCredParams: options.algorithms.map((alg) => ({ type: 'public-key' as const, alg })),
+			excludeCredentials: options.excludeCredentialIds.map((id) => ({
+				type: 'public-key' as const,
+				id: buffer(id)
+			})),
+			authenticatorSelection: {
+				residentKey: 'preferred',
+				userVerification: options.userVerification as UserVerificationRequirement
+			},
+			attestation: 'none',
+			timeout: options.timeoutMs
+		}
+	})) as PublicKeyCredential | null;
+	if (!credential) {
+		throw new Error('Passkey creation was cancelled');
+	}
+
+	const response = credential.response as AuthenticatorAttestationResponse;
+	const result = await rpcClient.auth.finishPasskeyRegistration(
+		create(FinishPasskeyRegistrationRequestSchema, {
+			challengeId: options.challengeId,
+			name,
+			clientDataJson: new Uint8Array(response.clientDataJSON),
+			attestationObject: new Uint8Array(response.attestationObject)
+		})
+	);
+	return result.passkey;
+}
+
+/**
+ * Signs in with a passkey. Without a username the browser offers every passkey saved for this site.
+ */
+export async function signInWithPasskey(username?: string): Promise<FinishPasskeyLoginResponse> {
+	const options = await rpcClient.auth.beginPasskeyLogin(
+		create(BeginPasskeyLoginRequestSchema, { username: username ?? '' })
+	);
+
+	const credential = (await navigator.credentials.get({
+		publicKey: {
+			challenge: buffer(options.challenge),
+			rpId: options.rpId,
+			allowCredentials: options.allowCredentialIds.map((id) => ({
+				type: 'public-key' as const,
+				id: buffer(id)
+			})),
+			userVerification: options.userVerification as UserVerificationRequirement,
+			timeout: options.timeoutMs
+		}
+	})) as PublicKeyCredential | null;
+	if (!credential) {
+		throw new Error('Passkey sign-in was cancelled');
+	}
+
+	const response = credential.response as AuthenticatorAssertionResponse;
+	return await rpcClient.auth.finishPasskeyLogin(
+		create(FinishPasskeyLoginRequestSchema, {
+			challengeId: options.challengeId,
+			credentialId: new Uint8Array(credential.rawId),
+			clientDataJson: new Uint8Array(response.clientDataJSON),
+			authenticatorData: new Uint8Array(response.authenticatorData),
+			signature: new Uint8Array(response.signature)
+		})
+	);
+}
@@ -12,7 +12,11 @@ const config = {
 		}
 		handler(warning);
 	},
-	kit: { adapter: adapter({ fallback: 'index.html' }) },
+	kit: {
+		adapter: adapter({ fallback: 'index.html' }),
+		// Must match server.base_path when DiscoPanel is hosted under a subpath
+		paths: { base: process.env.BASE_PATH ?? '' }
+	},
 	compilerOptions: {
 		// THE FACT THAT THIS TOOK SO FUCKING LONG TO FIND... I VOW TO NEVER MAKE ACCESSIBLE APPS AGAIN
 		// THIS IS WHAT YOU DID SVELTE TEAM. THIS ONE IS ENTIRELY ON YOU, STUPID IDIOTS